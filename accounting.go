@@ -0,0 +1,76 @@
+package uniai
+
+import (
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/tokens"
+)
+
+// accountStream makes the Done event of a streamed reply carry its usage
+// and cost. Usage the provider did not report is estimated from the
+// request and the streamed output.
+func (c *Client) accountStream(providerName string, req *chat.Request) *chat.Request {
+	next := req.Options.OnStream
+	if next == nil {
+		return req
+	}
+	out := *req
+	model := c.defaultModel(providerName, req)
+	var output strings.Builder
+	out.Options.OnStream = func(ev chat.StreamEvent) error {
+		output.WriteString(ev.Delta)
+		if ev.ToolCallDelta != nil {
+			output.WriteString(ev.ToolCallDelta.Name + ev.ToolCallDelta.ArgsChunk)
+		}
+		if ev.Done {
+			if ev.Usage == nil || *ev.Usage == (chat.Usage{}) {
+				u := estimateUsage(model, req, output.String())
+				ev.Usage = &u
+			}
+			if c.cfg.PriceFunc != nil {
+				ev.Cost = c.cfg.PriceFunc(providerName, model, *ev.Usage)
+			}
+		}
+		return next(ev)
+	}
+	return &out
+}
+
+// estimateUsage counts the tokens of the request messages and tools and of
+// the output, with the model's encoder when one is registered. It is used
+// when a provider reports no usage, so the result is marked Estimated.
+func estimateUsage(model string, req *chat.Request, output string) chat.Usage {
+	count := func(text string) int {
+		n, _, err := tokens.Count(model, text)
+		if err != nil {
+			return tokens.Estimate(text)
+		}
+		return n
+	}
+	var u chat.Usage
+	for _, m := range req.Messages {
+		u.InputTokens += count(m.Content)
+		for _, tc := range m.ToolCalls {
+			u.InputTokens += count(tc.Function.Name + tc.Function.Arguments)
+		}
+	}
+	for _, t := range req.Tools {
+		u.InputTokens += count(t.Function.Name + t.Function.Description + string(t.Function.ParametersJSONSchema))
+	}
+	u.OutputTokens = count(output)
+	u.TotalTokens = u.InputTokens + u.OutputTokens
+	u.Estimated = true
+	return u
+}
+
+// resultOutput is the generated text of resp that estimateUsage counts.
+func resultOutput(resp *chat.Result) string {
+	var b strings.Builder
+	b.WriteString(resp.ReasoningText)
+	b.WriteString(resp.Text)
+	for _, tc := range resp.ToolCalls {
+		b.WriteString(tc.Function.Name + tc.Function.Arguments)
+	}
+	return b.String()
+}
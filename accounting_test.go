@@ -0,0 +1,42 @@
+package uniai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestStreamDoneEventCarriesUsageAndCost(t *testing.T) {
+	client := New(Config{PriceFunc: func(provider, model string, u chat.Usage) float64 {
+		return float64(u.TotalTokens) / 1000
+	}})
+	reply := fake.Text("Twelve bytes of output", 4, 0)
+	reply.Usage = &chat.Usage{} // the provider reports nothing
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{reply}}))
+
+	var done *chat.StreamEvent
+	resp, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User("Write twelve bytes of output, please.")),
+		WithOnStream(func(ev chat.StreamEvent) error {
+			if ev.Done {
+				done = &ev
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done == nil || done.Usage == nil || done.Usage.InputTokens == 0 || done.Usage.OutputTokens != 6 || !done.Usage.Estimated {
+		t.Fatalf("usage not estimated: %+v", done)
+	}
+	if resp.Usage != *done.Usage {
+		t.Fatalf("result usage %+v, stream usage %+v", resp.Usage, *done.Usage)
+	}
+	if done.Cost != float64(done.Usage.TotalTokens)/1000 {
+		t.Fatalf("cost %v for %+v", done.Cost, done.Usage)
+	}
+}
@@ -0,0 +1,335 @@
+// Package admission limits the provider calls in flight and decides who
+// goes next when the limit is reached. Interactive calls are admitted
+// before background ones, and within a class tenants take turns, so one
+// tenant's bulk work cannot starve the others.
+//
+// The class and tenant of a call travel in its context:
+//
+//	ctx = admission.WithClass(ctx, admission.Background)
+//	ctx = admission.WithTenant(ctx, "acme")
+package admission
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Class is the priority class of a call. Lower classes are admitted first.
+type Class int
+
+const (
+	// Interactive is the default class, for calls a user waits on.
+	Interactive Class = iota
+	// Background is for bulk and offline work.
+	Background
+
+	numClasses = int(Background) + 1
+)
+
+func (c Class) String() string {
+	if c == Background {
+		return "background"
+	}
+	return "interactive"
+}
+
+type classKey struct{}
+type tenantKey struct{}
+
+// WithClass returns a context whose calls are admitted as class.
+func WithClass(ctx context.Context, class Class) context.Context {
+	return context.WithValue(ctx, classKey{}, class)
+}
+
+// WithTenant returns a context whose calls count against tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// ClassFrom returns the class set with WithClass, or Interactive.
+func ClassFrom(ctx context.Context) Class {
+	if c, ok := ctx.Value(classKey{}).(Class); ok && c >= 0 && int(c) < numClasses {
+		return c
+	}
+	return Interactive
+}
+
+// HasClass reports whether ctx carries a class set with WithClass.
+func HasClass(ctx context.Context) bool {
+	_, ok := ctx.Value(classKey{}).(Class)
+	return ok
+}
+
+// TenantFrom returns the tenant set with WithTenant, or "".
+func TenantFrom(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey{}).(string)
+	return t
+}
+
+// DefaultMaxConcurrent is used when Config.MaxConcurrent is not set.
+const DefaultMaxConcurrent = 8
+
+// Config configures a Controller.
+type Config struct {
+	// MaxConcurrent is the number of calls in flight (default 8).
+	MaxConcurrent int
+	// MaxBackground caps the Background calls in flight, keeping the other
+	// slots free for Interactive calls. It defaults to MaxConcurrent-1,
+	// or 1 when MaxConcurrent is 1.
+	MaxBackground int
+	// Adaptive adjusts the limit from the outcomes passed to Report
+	// (AIMD): a rate-limited call halves it, down to MinConcurrent, and
+	// each successful call raises it by 1/limit, up to MaxConcurrent. New
+	// calls are also held back until the Retry-After or reset time of a
+	// rate limit or an exhausted budget.
+	Adaptive bool
+	// MinConcurrent is the lowest adaptive limit (default 1).
+	MinConcurrent int
+}
+
+// Stats is a snapshot of a Controller.
+type Stats struct {
+	// Limit is the current concurrency limit.
+	Limit    int
+	InFlight [numClasses]int
+	Waiting  [numClasses]int
+	// PausedUntil is set while calls are held back after a rate limit.
+	PausedUntil time.Time
+}
+
+// Controller admits calls. It is safe for concurrent use.
+type Controller struct {
+	cfg Config
+
+	mu           sync.Mutex
+	inFlight     [numClasses]int
+	queues       [numClasses]tenantQueue
+	limit        float64
+	pausedUntil  time.Time
+	lastDecrease time.Time
+}
+
+func New(cfg Config) *Controller {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if cfg.MaxBackground <= 0 || cfg.MaxBackground > cfg.MaxConcurrent {
+		cfg.MaxBackground = max(cfg.MaxConcurrent-1, 1)
+	}
+	if cfg.MinConcurrent <= 0 || cfg.MinConcurrent > cfg.MaxConcurrent {
+		cfg.MinConcurrent = 1
+	}
+	return &Controller{cfg: cfg, limit: float64(cfg.MaxConcurrent)}
+}
+
+// decreaseInterval keeps the calls that were in flight when a rate limit
+// hit from halving the limit once each.
+const decreaseInterval = time.Second
+
+// Report feeds the outcome of an admitted call back to an Adaptive
+// controller: the rate-limit state the provider reported, if any, and the
+// call's error. It is a no-op for other controllers.
+func (c *Controller) Report(rl *chat.RateLimit, err error) {
+	if !c.cfg.Adaptive {
+		return
+	}
+	limited := chat.ClassifyError(err) == chat.ErrorClassRateLimit
+	wait, exhausted := rl.Exhausted()
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case limited:
+		if now.Sub(c.lastDecrease) >= decreaseInterval {
+			c.limit = max(c.limit/2, float64(c.cfg.MinConcurrent))
+			c.lastDecrease = now
+		}
+		if wait <= 0 {
+			wait = time.Second
+		}
+		c.pause(now, wait)
+	case exhausted:
+		c.pause(now, wait)
+	case err == nil:
+		c.limit = min(c.limit+1/c.limit, float64(c.cfg.MaxConcurrent))
+	}
+	c.dispatch()
+}
+
+// pause holds back new calls for d.
+func (c *Controller) pause(now time.Time, d time.Duration) {
+	until := now.Add(d)
+	if d <= 0 || !until.After(c.pausedUntil) {
+		return
+	}
+	c.pausedUntil = until
+	time.AfterFunc(d, func() {
+		c.mu.Lock()
+		c.dispatch()
+		c.mu.Unlock()
+	})
+}
+
+// Acquire waits until the call described by ctx may start and returns a
+// function that must be called when it ends. It fails only when ctx ends
+// first.
+func (c *Controller) Acquire(ctx context.Context) (release func(), err error) {
+	class := ClassFrom(ctx)
+	w := &waiter{class: class, tenant: TenantFrom(ctx), ready: make(chan struct{})}
+	c.mu.Lock()
+	c.queues[class].push(w)
+	c.dispatch()
+	c.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return c.releaseFunc(class), nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if w.admitted {
+			// admitted while giving up: hand the slot on
+			c.inFlight[class]--
+			c.dispatch()
+		} else {
+			c.queues[class].remove(w)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Stats returns the calls in flight and waiting, by class.
+func (c *Controller) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := Stats{Limit: int(c.limit), InFlight: c.inFlight}
+	if c.pausedUntil.After(time.Now()) {
+		s.PausedUntil = c.pausedUntil
+	}
+	for i := range c.queues {
+		s.Waiting[i] = c.queues[i].len
+	}
+	return s
+}
+
+func (c *Controller) releaseFunc(class Class) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			c.inFlight[class]--
+			c.dispatch()
+			c.mu.Unlock()
+		})
+	}
+}
+
+// dispatch admits waiters while there is room, interactive first.
+func (c *Controller) dispatch() {
+	if time.Now().Before(c.pausedUntil) {
+		return
+	}
+	limit := int(c.limit)
+	maxBackground := min(c.cfg.MaxBackground, max(limit-1, 1))
+	for {
+		total := 0
+		for _, n := range c.inFlight {
+			total += n
+		}
+		if total >= limit {
+			return
+		}
+		admitted := false
+		for class := range Class(numClasses) {
+			if class == Background && c.inFlight[Background] >= maxBackground {
+				continue
+			}
+			if w := c.queues[class].pop(); w != nil {
+				c.inFlight[class]++
+				w.admitted = true
+				close(w.ready)
+				admitted = true
+				break
+			}
+		}
+		if !admitted {
+			return
+		}
+	}
+}
+
+type waiter struct {
+	class    Class
+	tenant   string
+	ready    chan struct{}
+	admitted bool
+}
+
+// tenantQueue holds the waiters of one class: FIFO per tenant, with the
+// tenants served round-robin.
+type tenantQueue struct {
+	tenants []string
+	next    int
+	waiting map[string][]*waiter
+	len     int
+}
+
+func (q *tenantQueue) push(w *waiter) {
+	if q.waiting == nil {
+		q.waiting = map[string][]*waiter{}
+	}
+	if len(q.waiting[w.tenant]) == 0 {
+		q.tenants = append(q.tenants, w.tenant)
+	}
+	q.waiting[w.tenant] = append(q.waiting[w.tenant], w)
+	q.len++
+}
+
+func (q *tenantQueue) pop() *waiter {
+	if q.len == 0 {
+		return nil
+	}
+	if q.next >= len(q.tenants) {
+		q.next = 0
+	}
+	tenant := q.tenants[q.next]
+	list := q.waiting[tenant]
+	w := list[0]
+	if len(list) == 1 {
+		delete(q.waiting, tenant)
+		q.tenants = append(q.tenants[:q.next], q.tenants[q.next+1:]...)
+	} else {
+		q.waiting[tenant] = list[1:]
+		q.next++
+	}
+	q.len--
+	return w
+}
+
+func (q *tenantQueue) remove(w *waiter) {
+	list := q.waiting[w.tenant]
+	for i, x := range list {
+		if x != w {
+			continue
+		}
+		q.len--
+		if len(list) > 1 {
+			q.waiting[w.tenant] = append(list[:i:i], list[i+1:]...)
+			return
+		}
+		delete(q.waiting, w.tenant)
+		for j, t := range q.tenants {
+			if t == w.tenant {
+				q.tenants = append(q.tenants[:j], q.tenants[j+1:]...)
+				if q.next > j {
+					q.next--
+				}
+				break
+			}
+		}
+		return
+	}
+}
@@ -0,0 +1,107 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestInteractiveFirstAndTenantsTakeTurns(t *testing.T) {
+	c := New(Config{MaxConcurrent: 1})
+	ctx := context.Background()
+	hold, err := c.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 4)
+	queued := 0
+	start := func(name string, class Class, tenant string) {
+		go func() {
+			release, err := c.Acquire(WithTenant(WithClass(ctx, class), tenant))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- name
+			release()
+		}()
+		// wait until it is queued, so the calls queue in a known order
+		queued++
+		for waiting(c) < queued {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	start("bulk-a1", Background, "a")
+	start("bulk-a2", Background, "a")
+	start("bulk-b1", Background, "b")
+	start("chat-c", Interactive, "c")
+	hold()
+
+	var got []string
+	for range 4 {
+		select {
+		case name := <-order:
+			got = append(got, name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("calls not admitted, got %v", got)
+		}
+	}
+	want := []string{"chat-c", "bulk-a1", "bulk-b1", "bulk-a2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("admission order %v, want %v", got, want)
+		}
+	}
+}
+
+func waiting(c *Controller) int {
+	s := c.Stats()
+	return s.Waiting[Interactive] + s.Waiting[Background]
+}
+
+func TestAcquireCanceled(t *testing.T) {
+	c := New(Config{MaxConcurrent: 1})
+	hold, _ := c.Acquire(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Acquire(ctx); err == nil {
+		t.Fatal("expected the context error")
+	}
+	hold()
+	if s := c.Stats(); s.Waiting[Interactive] != 0 || s.InFlight[Interactive] != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestAdaptiveLimit(t *testing.T) {
+	c := New(Config{MaxConcurrent: 8, Adaptive: true})
+	rateLimited := errors.New("status 429: slow down")
+	rl := &chat.RateLimit{RetryAfter: 30 * time.Millisecond}
+	c.Report(rl, rateLimited)
+	c.Report(rl, rateLimited) // same burst: no second decrease
+	if s := c.Stats(); s.Limit != 4 || s.PausedUntil.IsZero() {
+		t.Fatalf("unexpected stats after a rate limit %+v", s)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := c.Acquire(ctx); err == nil {
+		t.Fatal("expected calls to be held back during the pause")
+	}
+	release, err := c.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	for range 30 {
+		c.Report(nil, nil)
+	}
+	if s := c.Stats(); s.Limit != 8 {
+		t.Fatalf("expected the limit to recover, got %+v", s)
+	}
+}
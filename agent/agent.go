@@ -0,0 +1,187 @@
+// Package agent runs the tool-calling loop: it sends a conversation to a
+// chat client, executes the tool calls in the reply, appends the results and
+// repeats until the model answers without calling a tool.
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Chatter is the subset of uniai.Client the runner needs.
+type Chatter interface {
+	Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error)
+}
+
+// Tool is a tool definition plus the function that executes it. Run
+// receives the raw JSON arguments; its result is sent back to the model as
+// the tool message content.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  []byte
+	Run         func(ctx context.Context, args string) (string, error)
+}
+
+// ToolSource supplies tools that can change while the program runs, such
+// as the tools of an MCP server, a plugin directory or a database. The
+// runner lists the tools before every model call, so tools added or
+// removed by the source take effect on the next step.
+type ToolSource interface {
+	// ListTools returns the tools currently offered.
+	ListTools(ctx context.Context) ([]chat.Tool, error)
+	// Execute runs a call to one of the listed tools and returns the
+	// output sent back to the model.
+	Execute(ctx context.Context, call chat.ToolCall) (string, error)
+}
+
+// DefaultMaxSteps bounds the number of model calls per Run.
+const DefaultMaxSteps = 8
+
+// Runner executes tool calls on behalf of the model.
+type Runner struct {
+	Client Chatter
+	Tools  []Tool
+	// Sources add tools discovered at run time. A tool in Tools takes
+	// precedence over a source tool of the same name, and an earlier
+	// source over a later one.
+	Sources []ToolSource
+	// MaxSteps bounds the number of model calls (default DefaultMaxSteps).
+	MaxSteps int
+	// Approve, if set, is asked before each tool call; denied calls are
+	// reported to the model instead of run.
+	Approve func(ctx context.Context, call chat.ToolCall) bool
+	// OnToolResult, if set, observes each executed call and its output.
+	OnToolResult func(call chat.ToolCall, output string, err error)
+}
+
+// Result is the outcome of Run.
+type Result struct {
+	// Final is the last model reply, which has no tool calls unless the
+	// step limit was reached.
+	Final *chat.Result
+	// Messages is the conversation passed to Run followed by the assistant
+	// and tool messages produced during the run.
+	Messages []chat.Message
+	// Usage is summed across all steps.
+	Usage chat.Usage
+	Steps int
+}
+
+// Run continues messages until the model replies without tool calls. opts
+// are applied to every model call; the runner sets messages and tools.
+func (r *Runner) Run(ctx context.Context, messages []chat.Message, opts ...chat.Option) (*Result, error) {
+	maxSteps := r.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+	static := make([]chat.Tool, 0, len(r.Tools))
+	for _, t := range r.Tools {
+		static = append(static, chat.Tool{
+			Type: "function",
+			Function: chat.ToolFunction{
+				Name:                 t.Name,
+				Description:          t.Description,
+				ParametersJSONSchema: t.Parameters,
+			},
+		})
+	}
+
+	out := &Result{Messages: append([]chat.Message{}, messages...)}
+	for out.Steps < maxSteps {
+		defs, sources, err := r.listTools(ctx, static)
+		if err != nil {
+			return out, err
+		}
+		callOpts := append(append([]chat.Option{}, opts...), chat.WithReplaceMessages(out.Messages...))
+		if len(defs) > 0 {
+			callOpts = append(callOpts, chat.WithTools(defs))
+		}
+		resp, err := r.Client.Chat(ctx, callOpts...)
+		if err != nil {
+			return out, err
+		}
+		out.Steps++
+		out.Final = resp
+		out.Usage.Add(resp.Usage)
+		if len(resp.ToolCalls) == 0 {
+			if resp.Text != "" {
+				out.Messages = append(out.Messages, chat.Assistant(resp.Text))
+			}
+			return out, nil
+		}
+
+		out.Messages = append(out.Messages, chat.Message{
+			Role:      chat.RoleAssistant,
+			Content:   resp.Text,
+			ToolCalls: resp.ToolCalls,
+		})
+		for _, call := range resp.ToolCalls {
+			output := r.runTool(ctx, call, sources[call.Function.Name])
+			out.Messages = append(out.Messages, chat.ToolResult(call.ID, output))
+		}
+	}
+	return out, fmt.Errorf("agent stopped after %d steps without a final answer", maxSteps)
+}
+
+// listTools returns the static tools followed by the tools of the sources,
+// and the source of each source tool by name.
+func (r *Runner) listTools(ctx context.Context, static []chat.Tool) ([]chat.Tool, map[string]ToolSource, error) {
+	if len(r.Sources) == 0 {
+		return static, nil, nil
+	}
+	defs := append([]chat.Tool{}, static...)
+	seen := map[string]bool{}
+	for _, t := range static {
+		seen[t.Function.Name] = true
+	}
+	sources := map[string]ToolSource{}
+	for _, src := range r.Sources {
+		tools, err := src.ListTools(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("list tools: %w", err)
+		}
+		for _, t := range tools {
+			if seen[t.Function.Name] {
+				continue
+			}
+			seen[t.Function.Name] = true
+			sources[t.Function.Name] = src
+			defs = append(defs, t)
+		}
+	}
+	return defs, sources, nil
+}
+
+func (r *Runner) runTool(ctx context.Context, call chat.ToolCall, source ToolSource) string {
+	var tool *Tool
+	for i := range r.Tools {
+		if r.Tools[i].Name == call.Function.Name {
+			tool = &r.Tools[i]
+			break
+		}
+	}
+	var (
+		output string
+		err    error
+	)
+	switch {
+	case tool == nil && source == nil:
+		err = fmt.Errorf("unknown tool %q", call.Function.Name)
+	case r.Approve != nil && !r.Approve(ctx, call):
+		err = fmt.Errorf("tool call denied by user")
+	case tool != nil:
+		output, err = tool.Run(ctx, call.Function.Arguments)
+	default:
+		output, err = source.Execute(ctx, call)
+	}
+	if r.OnToolResult != nil {
+		r.OnToolResult(call, output, err)
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return output
+}
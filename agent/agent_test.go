@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+type scriptedChatter struct {
+	replies []*chat.Result
+	seen    [][]chat.Message
+	tools   [][]chat.Tool
+}
+
+func (s *scriptedChatter) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.seen = append(s.seen, req.Messages)
+	s.tools = append(s.tools, req.Tools)
+	resp := s.replies[0]
+	s.replies = s.replies[1:]
+	return resp, nil
+}
+
+func TestRunnerExecutesTools(t *testing.T) {
+	client := &scriptedChatter{replies: []*chat.Result{
+		{ToolCalls: []chat.ToolCall{
+			{ID: "1", Function: chat.ToolCallFunction{Name: "echo", Arguments: `{"s":"hi"}`}},
+			{ID: "2", Function: chat.ToolCallFunction{Name: "rm", Arguments: `{}`}},
+		}, Usage: chat.Usage{TotalTokens: 10}},
+		{Text: "done", Usage: chat.Usage{TotalTokens: 5}},
+	}}
+	runner := &Runner{
+		Client: client,
+		Tools: []Tool{
+			{Name: "echo", Run: func(ctx context.Context, args string) (string, error) { return args, nil }},
+			{Name: "rm", Run: func(ctx context.Context, args string) (string, error) { return "removed", nil }},
+		},
+		Approve: func(ctx context.Context, call chat.ToolCall) bool { return call.Function.Name != "rm" },
+	}
+	res, err := runner.Run(context.Background(), []chat.Message{chat.User("go")})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if res.Steps != 2 || res.Final.Text != "done" || res.Usage.TotalTokens != 15 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	second := client.seen[1]
+	if len(second) != 4 || second[2].Content != `{"s":"hi"}` || !strings.Contains(second[3].Content, "denied") {
+		t.Fatalf("unexpected follow-up messages: %+v", second)
+	}
+	if last := res.Messages[len(res.Messages)-1]; last.Role != chat.RoleAssistant || last.Content != "done" {
+		t.Fatalf("final answer missing from history: %+v", last)
+	}
+}
+
+// pluginSource offers a tool that is installed by its first call.
+type pluginSource struct {
+	installed bool
+}
+
+func (p *pluginSource) ListTools(ctx context.Context) ([]chat.Tool, error) {
+	tools := []chat.Tool{chat.FunctionTool("install", "", nil)}
+	if p.installed {
+		tools = append(tools, chat.FunctionTool("plugin", "", nil))
+	}
+	return tools, nil
+}
+
+func (p *pluginSource) Execute(ctx context.Context, call chat.ToolCall) (string, error) {
+	switch call.Function.Name {
+	case "install":
+		p.installed = true
+		return "installed", nil
+	case "plugin":
+		return "plugin output", nil
+	}
+	return "", fmt.Errorf("no tool %q", call.Function.Name)
+}
+
+func TestRunnerToolSource(t *testing.T) {
+	client := &scriptedChatter{replies: []*chat.Result{
+		{ToolCalls: []chat.ToolCall{{ID: "1", Function: chat.ToolCallFunction{Name: "install", Arguments: `{}`}}}},
+		{ToolCalls: []chat.ToolCall{{ID: "2", Function: chat.ToolCallFunction{Name: "plugin", Arguments: `{}`}}}},
+		{Text: "done"},
+	}}
+	runner := &Runner{Client: client, Sources: []ToolSource{&pluginSource{}}}
+	res, err := runner.Run(context.Background(), []chat.Message{chat.User("go")})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(client.tools[0]) != 1 || len(client.tools[1]) != 2 {
+		t.Fatalf("expected the plugin tool from the second step, got %d then %d tools", len(client.tools[0]), len(client.tools[1]))
+	}
+	if out := res.Messages[len(res.Messages)-2]; out.Content != "plugin output" {
+		t.Fatalf("unexpected tool output: %+v", out)
+	}
+}
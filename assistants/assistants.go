@@ -0,0 +1,249 @@
+// Package assistants offers the thread and run model of the OpenAI
+// Assistants API behind the Threads interface. OpenAI implements it with
+// the hosted API, and Native implements it with the agent runner on any
+// chat provider, so code written against Threads can move off Assistants
+// by swapping the implementation.
+package assistants
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/quailyquaily/uniai/agent"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Threads stores conversations and runs assistants on them.
+type Threads interface {
+	// CreateThread starts a thread with optional initial messages.
+	CreateThread(ctx context.Context, messages ...chat.Message) (string, error)
+	// AddMessages appends user or assistant messages to a thread.
+	AddMessages(ctx context.Context, threadID string, messages ...chat.Message) error
+	// Messages returns the messages of a thread, oldest first.
+	Messages(ctx context.Context, threadID string) ([]chat.Message, error)
+	DeleteThread(ctx context.Context, threadID string) error
+	// Run lets the assistant answer the thread, executing its tools until
+	// it replies without calling one, and adds the reply to the thread.
+	Run(ctx context.Context, threadID string, a Assistant) (*Run, error)
+}
+
+// Assistant describes who answers on a thread.
+type Assistant struct {
+	// ID is the OpenAI assistant the run uses; see OpenAI.CreateAssistant.
+	// Native ignores it.
+	ID string
+	// Model and Instructions override those of the assistant when set.
+	Model        string
+	Instructions string
+	// Tools are executed locally when the model calls them. With OpenAI
+	// they are sent as the function tools of the run.
+	Tools []agent.Tool
+}
+
+// Run states, as reported by the Assistants API.
+const (
+	StatusQueued         = "queued"
+	StatusInProgress     = "in_progress"
+	StatusRequiresAction = "requires_action"
+	StatusCancelling     = "cancelling"
+	StatusCancelled      = "cancelled"
+	StatusFailed         = "failed"
+	StatusCompleted      = "completed"
+	StatusIncomplete     = "incomplete"
+	StatusExpired        = "expired"
+)
+
+// Run is one answer of an assistant on a thread.
+type Run struct {
+	ID       string `json:"id"`
+	ThreadID string `json:"thread_id"`
+	Status   string `json:"status"`
+	// ToolCalls are the calls waiting for SubmitToolOutputs when Status is
+	// StatusRequiresAction.
+	ToolCalls []chat.ToolCall `json:"tool_calls,omitempty"`
+	// Text is the final reply of a completed run.
+	Text string `json:"text,omitempty"`
+	// Messages are the messages the run added to the thread.
+	Messages []chat.Message `json:"messages,omitempty"`
+	Usage    chat.Usage     `json:"usage"`
+	// Error describes why the run failed, expired or is incomplete.
+	Error string `json:"error,omitempty"`
+}
+
+// ToolOutput answers one tool call of a run.
+type ToolOutput struct {
+	ToolCallID string `json:"tool_call_id"`
+	Output     string `json:"output"`
+}
+
+// Native keeps threads in memory and runs assistants with agent.Runner.
+type Native struct {
+	// Client is usually a *uniai.Client.
+	Client agent.Chatter
+	// MaxSteps bounds the model calls of a run (default
+	// agent.DefaultMaxSteps).
+	MaxSteps int
+	// Options are applied to every model call, before the model of the
+	// assistant.
+	Options []chat.Option
+
+	mu      sync.Mutex
+	threads map[string]*nativeThread
+}
+
+type nativeThread struct {
+	messages []chat.Message
+	running  bool
+}
+
+var _ Threads = (*Native)(nil)
+
+func NewNative(client agent.Chatter, opts ...chat.Option) *Native {
+	return &Native{Client: client, Options: opts}
+}
+
+func (n *Native) CreateThread(ctx context.Context, messages ...chat.Message) (string, error) {
+	if err := checkMessages(messages); err != nil {
+		return "", err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.threads == nil {
+		n.threads = map[string]*nativeThread{}
+	}
+	id := newID("thread_")
+	n.threads[id] = &nativeThread{messages: append([]chat.Message{}, messages...)}
+	return id, nil
+}
+
+func (n *Native) AddMessages(ctx context.Context, threadID string, messages ...chat.Message) error {
+	if err := checkMessages(messages); err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	t, err := n.thread(threadID)
+	if err != nil {
+		return err
+	}
+	if t.running {
+		return fmt.Errorf("thread %s has an active run", threadID)
+	}
+	t.messages = append(t.messages, messages...)
+	return nil
+}
+
+func (n *Native) Messages(ctx context.Context, threadID string) ([]chat.Message, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	t, err := n.thread(threadID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]chat.Message{}, t.messages...), nil
+}
+
+func (n *Native) DeleteThread(ctx context.Context, threadID string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, err := n.thread(threadID); err != nil {
+		return err
+	}
+	delete(n.threads, threadID)
+	return nil
+}
+
+// Run answers the thread with the agent runner. Tool calls and results
+// stay in the thread, so later runs see them as the model produced them.
+func (n *Native) Run(ctx context.Context, threadID string, a Assistant) (*Run, error) {
+	n.mu.Lock()
+	t, err := n.thread(threadID)
+	if err != nil {
+		n.mu.Unlock()
+		return nil, err
+	}
+	if t.running {
+		n.mu.Unlock()
+		return nil, fmt.Errorf("thread %s has an active run", threadID)
+	}
+	t.running = true
+	history := append([]chat.Message{}, t.messages...)
+	n.mu.Unlock()
+
+	var messages []chat.Message
+	if a.Instructions != "" {
+		messages = append(messages, chat.System(a.Instructions))
+	}
+	messages = append(messages, history...)
+	opts := append([]chat.Option{}, n.Options...)
+	if a.Model != "" {
+		opts = append(opts, chat.WithModel(a.Model))
+	}
+	runner := &agent.Runner{Client: n.Client, Tools: a.Tools, MaxSteps: n.MaxSteps}
+	res, runErr := runner.Run(ctx, messages, opts...)
+
+	run := &Run{ID: newID("run_"), ThreadID: threadID, Status: StatusCompleted}
+	if res != nil {
+		run.Messages = res.Messages[len(messages):]
+		run.Usage = res.Usage
+		if res.Final != nil && runErr == nil {
+			run.Text = res.Final.Text
+		}
+	}
+	n.mu.Lock()
+	t.running = false
+	if runErr == nil {
+		// the thread may have been deleted meanwhile; t is then dropped
+		t.messages = append(t.messages, run.Messages...)
+	}
+	n.mu.Unlock()
+	if runErr != nil {
+		run.Status, run.Error = StatusFailed, runErr.Error()
+		return run, fmt.Errorf("run %s failed: %w", run.ID, runErr)
+	}
+	return run, nil
+}
+
+func (n *Native) thread(id string) (*nativeThread, error) {
+	t := n.threads[id]
+	if t == nil {
+		return nil, fmt.Errorf("thread %s not found", id)
+	}
+	return t, nil
+}
+
+// checkMessages rejects messages a thread cannot hold. Assistants threads
+// take user and assistant text only; instructions belong to the assistant.
+func checkMessages(messages []chat.Message) error {
+	for _, m := range messages {
+		if m.Role != chat.RoleUser && m.Role != chat.RoleAssistant {
+			return fmt.Errorf("threads only hold user and assistant messages, got %s", m.Role)
+		}
+	}
+	return nil
+}
+
+// runTool executes a tool call the way agent.Runner does, reporting errors
+// to the model as the output.
+func runTool(ctx context.Context, tools []agent.Tool, call chat.ToolCall) string {
+	for _, t := range tools {
+		if t.Name != call.Function.Name {
+			continue
+		}
+		out, err := t.Run(ctx, call.Function.Arguments)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return out
+	}
+	return fmt.Sprintf("error: unknown tool %q", call.Function.Name)
+}
+
+func newID(prefix string) string {
+	var b [12]byte
+	rand.Read(b[:])
+	return prefix + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,131 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/agent"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+type scriptedChatter struct {
+	replies []*chat.Result
+	seen    [][]chat.Message
+}
+
+func (s *scriptedChatter) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.seen = append(s.seen, req.Messages)
+	resp := s.replies[0]
+	s.replies = s.replies[1:]
+	return resp, nil
+}
+
+var weather = agent.Tool{
+	Name: "weather",
+	Run: func(ctx context.Context, args string) (string, error) {
+		return "sunny", nil
+	},
+}
+
+// ask runs the same conversation on any Threads implementation.
+func ask(t *testing.T, threads Threads, a Assistant) *Run {
+	t.Helper()
+	ctx := context.Background()
+	id, err := threads.CreateThread(ctx, chat.User("weather in Oslo?"))
+	if err != nil {
+		t.Fatalf("create thread: %v", err)
+	}
+	run, err := threads.Run(ctx, id, a)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if run.Status != StatusCompleted || run.Text != "It is sunny." {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	messages, err := threads.Messages(ctx, id)
+	if err != nil {
+		t.Fatalf("messages: %v", err)
+	}
+	if last := messages[len(messages)-1]; last.Role != chat.RoleAssistant || last.Content != "It is sunny." {
+		t.Fatalf("reply missing from thread: %+v", messages)
+	}
+	return run
+}
+
+func TestNative(t *testing.T) {
+	client := &scriptedChatter{replies: []*chat.Result{
+		{ToolCalls: []chat.ToolCall{{ID: "call_1", Function: chat.ToolCallFunction{Name: "weather", Arguments: `{}`}}}},
+		{Text: "It is sunny."},
+	}}
+	threads := NewNative(client)
+	run := ask(t, threads, Assistant{Instructions: "be brief", Tools: []agent.Tool{weather}})
+	if len(run.Messages) != 3 || run.Messages[1].Content != "sunny" {
+		t.Fatalf("unexpected run messages: %+v", run.Messages)
+	}
+	if first := client.seen[0]; first[0].Role != chat.RoleSystem || first[1].Content != "weather in Oslo?" {
+		t.Fatalf("unexpected model input: %+v", first)
+	}
+	if _, err := threads.CreateThread(context.Background(), chat.System("no")); err == nil {
+		t.Fatalf("expected error for system message")
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	var submitted []ToolOutput
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("OpenAI-Beta") != "assistants=v2" {
+			t.Errorf("missing beta header")
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/threads":
+			w.Write([]byte(`{"id":"thread_1"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/threads/thread_1/runs":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["assistant_id"] != "asst_1" || body["tools"] == nil {
+				t.Errorf("unexpected run body: %v", body)
+			}
+			w.Write([]byte(`{"id":"run_1","thread_id":"thread_1","status":"queued"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/threads/thread_1/runs/run_1":
+			w.Write([]byte(`{"id":"run_1","thread_id":"thread_1","status":"requires_action","required_action":{"type":"submit_tool_outputs","submit_tool_outputs":{"tool_calls":[{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{}"}}]}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/threads/thread_1/runs/run_1/submit_tool_outputs":
+			var body struct {
+				ToolOutputs []ToolOutput `json:"tool_outputs"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			submitted = body.ToolOutputs
+			w.Write([]byte(`{"id":"run_1","thread_id":"thread_1","status":"completed","usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/threads/thread_1/messages":
+			reply := `{"role":"assistant","content":[{"type":"text","text":{"value":"It is sunny."}}]}`
+			if r.URL.Query().Get("run_id") == "run_1" {
+				w.Write([]byte(`{"data":[` + reply + `],"has_more":false}`))
+				return
+			}
+			w.Write([]byte(`{"data":[{"role":"user","content":[{"type":"text","text":{"value":"weather in Oslo?"}}]},` + reply + `],"has_more":false}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	threads := NewOpenAI(OpenAIConfig{APIKey: "k", APIBase: srv.URL, PollInterval: time.Millisecond})
+	run := ask(t, threads, Assistant{ID: "asst_1", Tools: []agent.Tool{weather}})
+	if len(submitted) != 1 || submitted[0].ToolCallID != "call_1" || submitted[0].Output != "sunny" {
+		t.Fatalf("unexpected tool outputs: %+v", submitted)
+	}
+	if run.Usage.TotalTokens != 15 {
+		t.Fatalf("unexpected usage: %+v", run.Usage)
+	}
+	if _, err := threads.Run(context.Background(), "thread_1", Assistant{}); err == nil || !strings.Contains(err.Error(), "assistant id") {
+		t.Fatalf("expected missing assistant id error, got %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package assistants
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/agent"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/internal/httputil"
+	"github.com/quailyquaily/uniai/job"
+)
+
+const defaultOpenAIAPIBase = "https://api.openai.com/v1"
+
+type OpenAIConfig struct {
+	APIKey  string
+	APIBase string
+	// PollInterval is the delay between run status checks; it defaults to
+	// one second.
+	PollInterval time.Duration
+}
+
+// OpenAI implements Threads with the OpenAI Assistants API (v2). Besides
+// Run, it exposes the individual run calls for code that drives runs
+// itself.
+type OpenAI struct {
+	cfg OpenAIConfig
+}
+
+var _ Threads = (*OpenAI)(nil)
+
+func NewOpenAI(cfg OpenAIConfig) *OpenAI {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	return &OpenAI{cfg: cfg}
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+func openAITools(tools []agent.Tool) []openAITool {
+	var out []openAITool
+	for _, t := range tools {
+		tool := openAITool{Type: "function"}
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.Parameters
+		if len(tool.Function.Parameters) == 0 {
+			tool.Function.Parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		out = append(out, tool)
+	}
+	return out
+}
+
+// CreateAssistant stores a as an OpenAI assistant and returns its ID.
+func (o *OpenAI) CreateAssistant(ctx context.Context, a Assistant) (string, error) {
+	if a.Model == "" {
+		return "", fmt.Errorf("assistant model is required")
+	}
+	body := map[string]any{"model": a.Model}
+	if a.Instructions != "" {
+		body["instructions"] = a.Instructions
+	}
+	if tools := openAITools(a.Tools); len(tools) > 0 {
+		body["tools"] = tools
+	}
+	var out struct {
+		ID string `json:"id"`
+	}
+	if err := o.do(ctx, http.MethodPost, "/assistants", nil, body, &out); err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+func (o *OpenAI) DeleteAssistant(ctx context.Context, id string) error {
+	return o.do(ctx, http.MethodDelete, "/assistants/"+url.PathEscape(id), nil, nil, nil)
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content []struct {
+		Type string `json:"type"`
+		Text struct {
+			Value string `json:"value"`
+		} `json:"text"`
+	} `json:"content"`
+}
+
+func (m openAIMessage) message() chat.Message {
+	var parts []string
+	for _, c := range m.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text.Value)
+		}
+	}
+	return chat.Message{Role: m.Role, Content: strings.Join(parts, "\n")}
+}
+
+func (o *OpenAI) CreateThread(ctx context.Context, messages ...chat.Message) (string, error) {
+	if err := checkMessages(messages); err != nil {
+		return "", err
+	}
+	wire := make([]map[string]string, len(messages))
+	for i, m := range messages {
+		wire[i] = map[string]string{"role": m.Role, "content": m.Content}
+	}
+	var out struct {
+		ID string `json:"id"`
+	}
+	if err := o.do(ctx, http.MethodPost, "/threads", nil, map[string]any{"messages": wire}, &out); err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+func (o *OpenAI) AddMessages(ctx context.Context, threadID string, messages ...chat.Message) error {
+	if err := checkMessages(messages); err != nil {
+		return err
+	}
+	for _, m := range messages {
+		body := map[string]string{"role": m.Role, "content": m.Content}
+		if err := o.do(ctx, http.MethodPost, "/threads/"+url.PathEscape(threadID)+"/messages", nil, body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *OpenAI) Messages(ctx context.Context, threadID string) ([]chat.Message, error) {
+	return o.listMessages(ctx, threadID, "")
+}
+
+// listMessages pages through the messages of a thread, or of one run of
+// it when runID is set.
+func (o *OpenAI) listMessages(ctx context.Context, threadID, runID string) ([]chat.Message, error) {
+	var out []chat.Message
+	query := url.Values{"order": {"asc"}, "limit": {"100"}}
+	if runID != "" {
+		query.Set("run_id", runID)
+	}
+	for {
+		var page struct {
+			Data    []openAIMessage `json:"data"`
+			LastID  string          `json:"last_id"`
+			HasMore bool            `json:"has_more"`
+		}
+		if err := o.do(ctx, http.MethodGet, "/threads/"+url.PathEscape(threadID)+"/messages", query, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, m := range page.Data {
+			out = append(out, m.message())
+		}
+		if !page.HasMore || page.LastID == "" {
+			return out, nil
+		}
+		query.Set("after", page.LastID)
+	}
+}
+
+func (o *OpenAI) DeleteThread(ctx context.Context, threadID string) error {
+	return o.do(ctx, http.MethodDelete, "/threads/"+url.PathEscape(threadID), nil, nil, nil)
+}
+
+type openAIRun struct {
+	ID             string `json:"id"`
+	ThreadID       string `json:"thread_id"`
+	Status         string `json:"status"`
+	RequiredAction *struct {
+		SubmitToolOutputs struct {
+			ToolCalls []chat.ToolCall `json:"tool_calls"`
+		} `json:"submit_tool_outputs"`
+	} `json:"required_action"`
+	LastError *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"last_error"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func (r openAIRun) run() *Run {
+	out := &Run{ID: r.ID, ThreadID: r.ThreadID, Status: r.Status}
+	if r.RequiredAction != nil {
+		out.ToolCalls = r.RequiredAction.SubmitToolOutputs.ToolCalls
+	}
+	switch {
+	case r.LastError != nil:
+		out.Error = strings.TrimSpace(r.LastError.Code + ": " + r.LastError.Message)
+	case r.IncompleteDetails != nil:
+		out.Error = r.IncompleteDetails.Reason
+	case r.Status == StatusExpired:
+		out.Error = "run expired"
+	}
+	if r.Usage != nil {
+		out.Usage = chat.Usage{
+			InputTokens:  r.Usage.PromptTokens,
+			OutputTokens: r.Usage.CompletionTokens,
+			TotalTokens:  r.Usage.TotalTokens,
+		}
+	}
+	return out
+}
+
+// CreateRun starts a run of assistant a.ID on the thread and returns it
+// without waiting. Model, Instructions and Tools of a override the
+// assistant's for this run.
+func (o *OpenAI) CreateRun(ctx context.Context, threadID string, a Assistant) (*Run, error) {
+	if a.ID == "" {
+		return nil, fmt.Errorf("openai runs need an assistant id; create one with CreateAssistant")
+	}
+	body := map[string]any{"assistant_id": a.ID}
+	if a.Model != "" {
+		body["model"] = a.Model
+	}
+	if a.Instructions != "" {
+		body["instructions"] = a.Instructions
+	}
+	if tools := openAITools(a.Tools); len(tools) > 0 {
+		body["tools"] = tools
+	}
+	var out openAIRun
+	if err := o.do(ctx, http.MethodPost, "/threads/"+url.PathEscape(threadID)+"/runs", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out.run(), nil
+}
+
+func (o *OpenAI) GetRun(ctx context.Context, threadID, runID string) (*Run, error) {
+	var out openAIRun
+	if err := o.do(ctx, http.MethodGet, "/threads/"+url.PathEscape(threadID)+"/runs/"+url.PathEscape(runID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.run(), nil
+}
+
+func (o *OpenAI) CancelRun(ctx context.Context, threadID, runID string) (*Run, error) {
+	var out openAIRun
+	if err := o.do(ctx, http.MethodPost, "/threads/"+url.PathEscape(threadID)+"/runs/"+url.PathEscape(runID)+"/cancel", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.run(), nil
+}
+
+// SubmitToolOutputs answers the tool calls of a run in StatusRequiresAction,
+// which resumes it.
+func (o *OpenAI) SubmitToolOutputs(ctx context.Context, threadID, runID string, outputs []ToolOutput) (*Run, error) {
+	var out openAIRun
+	path := "/threads/" + url.PathEscape(threadID) + "/runs/" + url.PathEscape(runID) + "/submit_tool_outputs"
+	if err := o.do(ctx, http.MethodPost, path, nil, map[string]any{"tool_outputs": outputs}, &out); err != nil {
+		return nil, err
+	}
+	return out.run(), nil
+}
+
+// WaitRun polls a run until it needs tool outputs or has ended.
+func (o *OpenAI) WaitRun(ctx context.Context, run *Run) (*Run, error) {
+	if !pending(run.Status) {
+		return run, nil
+	}
+	return job.Wait(ctx, o.cfg.PollInterval, func(ctx context.Context) (*Run, job.Status, error) {
+		cur, err := o.GetRun(ctx, run.ThreadID, run.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		if pending(cur.Status) {
+			return cur, job.StatusRunning, nil
+		}
+		// a run waiting for tool outputs is done as far as polling goes
+		return cur, job.StatusSucceeded, nil
+	})
+}
+
+// pending reports whether a run in status will change without input.
+func pending(status string) bool {
+	return status == StatusQueued || status == StatusInProgress || status == StatusCancelling
+}
+
+// Run creates a run, executes the tools it asks for until it ends, and
+// returns it with the messages it added. A run that does not complete is
+// returned with an error.
+func (o *OpenAI) Run(ctx context.Context, threadID string, a Assistant) (*Run, error) {
+	run, err := o.CreateRun(ctx, threadID, a)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if run, err = o.WaitRun(ctx, run); err != nil {
+			return run, err
+		}
+		if run.Status != StatusRequiresAction {
+			break
+		}
+		outputs := make([]ToolOutput, len(run.ToolCalls))
+		for i, call := range run.ToolCalls {
+			outputs[i] = ToolOutput{ToolCallID: call.ID, Output: runTool(ctx, a.Tools, call)}
+		}
+		if run, err = o.SubmitToolOutputs(ctx, threadID, run.ID, outputs); err != nil {
+			return nil, err
+		}
+	}
+	if run.Status != StatusCompleted {
+		return run, fmt.Errorf("run %s %s: %s", run.ID, run.Status, run.Error)
+	}
+	if run.Messages, err = o.listMessages(ctx, threadID, run.ID); err != nil {
+		return run, err
+	}
+	for i := len(run.Messages) - 1; i >= 0; i-- {
+		if run.Messages[i].Role == chat.RoleAssistant {
+			run.Text = run.Messages[i].Content
+			break
+		}
+	}
+	return run, nil
+}
+
+func (o *OpenAI) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	if o.cfg.APIKey == "" {
+		return fmt.Errorf("openai api key is required")
+	}
+	base := o.cfg.APIBase
+	if base == "" {
+		base = defaultOpenAIAPIBase
+	}
+	endpoint := strings.TrimRight(base, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+o.cfg.APIKey)
+	req.Header.Set("OpenAI-Beta", "assistants=v2")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("assistants API request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
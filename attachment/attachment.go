@@ -0,0 +1,397 @@
+// Package attachment stores images and documents by the SHA-256 digest of
+// their content, so sessions, audit logs and exports can refer to an
+// attachment with a small Ref instead of repeating its base64 data.
+// Identical content is stored once.
+//
+// Offload moves the inline images of chat messages into a Store, leaving
+// attachment URLs in their place, and Resolve turns those URLs back into
+// data URLs before the messages are sent.
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/encryption"
+)
+
+var (
+	// ErrNotFound is returned by Get for unknown digests.
+	ErrNotFound = errors.New("attachment not found")
+	// ErrTooLarge is returned by Put for content over the store's limit.
+	ErrTooLarge = errors.New("attachment too large")
+)
+
+// DefaultMaxSize is the size limit of stores created without one.
+const DefaultMaxSize = 20 << 20
+
+// Ref identifies stored content.
+type Ref struct {
+	// Digest is "sha256:" followed by the hex digest of the content.
+	Digest   string `json:"digest"`
+	MIMEType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size"`
+	Name     string `json:"name,omitempty"`
+}
+
+// URLScheme prefixes the image URLs that refer to stored attachments.
+const URLScheme = "attachment:"
+
+// URL returns the image URL referring to r, such as
+// "attachment:sha256:9f86…".
+func (r Ref) URL() string {
+	return URLScheme + r.Digest
+}
+
+// Digest returns the digest of data in the form used by Ref.
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Store keeps attachment content. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Put stores data and returns its Ref. Storing the same content again
+	// returns the same digest without writing it twice.
+	Put(ctx context.Context, data []byte, mimeType string) (Ref, error)
+	// Get returns the content and metadata of digest.
+	Get(ctx context.Context, digest string) ([]byte, Ref, error)
+	// Stat returns the metadata of digest without reading its content.
+	Stat(ctx context.Context, digest string) (Ref, error)
+}
+
+// newRef checks data against maxSize and builds its Ref, sniffing the MIME
+// type when none is given.
+func newRef(data []byte, mimeType string, maxSize int64) (Ref, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if int64(len(data)) > maxSize {
+		return Ref{}, fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, len(data), maxSize)
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return Ref{Digest: Digest(data), MIMEType: mimeType, Size: int64(len(data))}, nil
+}
+
+// MemoryStore is an in-process Store.
+type MemoryStore struct {
+	// MaxSize bounds each attachment (default DefaultMaxSize).
+	MaxSize int64
+
+	mu    sync.RWMutex
+	items map[string]memoryItem
+}
+
+type memoryItem struct {
+	ref  Ref
+	data []byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: map[string]memoryItem{}}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, data []byte, mimeType string) (Ref, error) {
+	ref, err := newRef(data, mimeType, s.MaxSize)
+	if err != nil {
+		return Ref{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.items == nil {
+		s.items = map[string]memoryItem{}
+	}
+	if item, ok := s.items[ref.Digest]; ok {
+		return item.ref, nil
+	}
+	s.items[ref.Digest] = memoryItem{ref: ref, data: append([]byte(nil), data...)}
+	return ref, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, digest string) ([]byte, Ref, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[digest]
+	if !ok {
+		return nil, Ref{}, fmt.Errorf("%w: %s", ErrNotFound, digest)
+	}
+	return append([]byte(nil), item.data...), item.ref, nil
+}
+
+func (s *MemoryStore) Stat(ctx context.Context, digest string) (Ref, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[digest]
+	if !ok {
+		return Ref{}, fmt.Errorf("%w: %s", ErrNotFound, digest)
+	}
+	return item.ref, nil
+}
+
+// DirStore is a persistent Store keeping each attachment in a file named by
+// its digest, with its metadata alongside in a .json file.
+type DirStore struct {
+	// MaxSize bounds each attachment (default DefaultMaxSize).
+	MaxSize int64
+	// Sealer, if set, encrypts the content and metadata files. Set it
+	// before the first call; files written without it cannot be read with
+	// it, and vice versa.
+	Sealer *encryption.Sealer
+
+	dir string
+}
+
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+func (s *DirStore) path(digest string) (string, error) {
+	hexDigest, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok || len(hexDigest) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid attachment digest %q", digest)
+	}
+	if _, err := hex.DecodeString(hexDigest); err != nil {
+		return "", fmt.Errorf("invalid attachment digest %q", digest)
+	}
+	return filepath.Join(s.dir, hexDigest[:2], hexDigest), nil
+}
+
+func (s *DirStore) Put(ctx context.Context, data []byte, mimeType string) (Ref, error) {
+	ref, err := newRef(data, mimeType, s.MaxSize)
+	if err != nil {
+		return Ref{}, err
+	}
+	path, err := s.path(ref.Digest)
+	if err != nil {
+		return Ref{}, err
+	}
+	if existing, err := s.Stat(ctx, ref.Digest); err == nil {
+		return existing, nil
+	}
+	meta, err := json.Marshal(ref)
+	if err != nil {
+		return Ref{}, err
+	}
+	if s.Sealer != nil {
+		if data, err = s.Sealer.Seal(ctx, data, []byte(ref.Digest)); err != nil {
+			return Ref{}, err
+		}
+		if meta, err = s.Sealer.Seal(ctx, meta, []byte(ref.Digest+".json")); err != nil {
+			return Ref{}, err
+		}
+	}
+	// content first, so a readable .json always has its data
+	if err := writeFile(path, data); err != nil {
+		return Ref{}, err
+	}
+	if err := writeFile(path+".json", meta); err != nil {
+		return Ref{}, err
+	}
+	return ref, nil
+}
+
+func (s *DirStore) Get(ctx context.Context, digest string) ([]byte, Ref, error) {
+	ref, err := s.Stat(ctx, digest)
+	if err != nil {
+		return nil, Ref{}, err
+	}
+	path, _ := s.path(digest)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, Ref{}, err
+	}
+	if s.Sealer != nil {
+		if data, err = s.Sealer.Open(ctx, data, []byte(digest)); err != nil {
+			return nil, Ref{}, fmt.Errorf("read attachment %s: %w", digest, err)
+		}
+	}
+	return data, ref, nil
+}
+
+func (s *DirStore) Stat(ctx context.Context, digest string) (Ref, error) {
+	path, err := s.path(digest)
+	if err != nil {
+		return Ref{}, err
+	}
+	meta, err := os.ReadFile(path + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return Ref{}, fmt.Errorf("%w: %s", ErrNotFound, digest)
+	}
+	if err != nil {
+		return Ref{}, err
+	}
+	if s.Sealer != nil {
+		if meta, err = s.Sealer.Open(ctx, meta, []byte(digest+".json")); err != nil {
+			return Ref{}, fmt.Errorf("read attachment %s: %w", digest, err)
+		}
+	}
+	var ref Ref
+	if err := json.Unmarshal(meta, &ref); err != nil {
+		return Ref{}, fmt.Errorf("read attachment %s: %w", digest, err)
+	}
+	return ref, nil
+}
+
+// writeFile writes data to path atomically.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		os.Remove(tmp.Name())
+		return errors.Join(werr, cerr)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// PutBase64 decodes a base64 payload, optionally in data URL form
+// ("data:image/png;base64,..."), and stores it.
+func PutBase64(ctx context.Context, s Store, payload string) (Ref, error) {
+	mimeType := ""
+	if rest, ok := strings.CutPrefix(payload, "data:"); ok {
+		header, data, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(header, ";base64") {
+			return Ref{}, fmt.Errorf("unsupported data URL")
+		}
+		mimeType, payload = strings.TrimSuffix(header, ";base64"), data
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+	if err != nil {
+		return Ref{}, fmt.Errorf("decode attachment: %w", err)
+	}
+	return s.Put(ctx, data, mimeType)
+}
+
+// Lazy loads the content of a Ref from a Store on first use and keeps it.
+type Lazy struct {
+	Ref   Ref
+	Store Store
+
+	mu   sync.Mutex
+	data []byte
+}
+
+// Bytes returns the content, loading it on the first successful call. The
+// digest of the loaded content is verified against Ref.
+func (l *Lazy) Bytes(ctx context.Context) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.data != nil {
+		return l.data, nil
+	}
+	data, _, err := l.Store.Get(ctx, l.Ref.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if Digest(data) != l.Ref.Digest {
+		return nil, fmt.Errorf("attachment %s is corrupt", l.Ref.Digest)
+	}
+	l.data = data
+	return data, nil
+}
+
+// Reader returns the content as a reader.
+func (l *Lazy) Reader(ctx context.Context) (io.Reader, error) {
+	data, err := l.Bytes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// DataURL returns the content as a base64 data URL, for provider APIs that
+// take inline images.
+func (l *Lazy) DataURL(ctx context.Context) (string, error) {
+	data, err := l.Bytes(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + l.Ref.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Offload stores the inline images of msgs in s and replaces them with
+// attachment URLs, so transcripts kept in sessions or logs hold digests
+// instead of base64 data. Other images are kept. msgs is not modified.
+func Offload(ctx context.Context, s Store, msgs []chat.Message) ([]chat.Message, error) {
+	return mapImages(msgs, func(img chat.Image) (chat.Image, error) {
+		if !strings.HasPrefix(img.URL, "data:") {
+			return img, nil
+		}
+		ref, err := PutBase64(ctx, s, img.URL)
+		if err != nil {
+			return img, err
+		}
+		img.URL = ref.URL()
+		return img, nil
+	})
+}
+
+// Resolve replaces the attachment URLs in msgs with data URLs of the
+// content stored in s, verified against the digest. msgs is not modified.
+func Resolve(ctx context.Context, s Store, msgs []chat.Message) ([]chat.Message, error) {
+	return mapImages(msgs, func(img chat.Image) (chat.Image, error) {
+		digest, ok := strings.CutPrefix(img.URL, URLScheme)
+		if !ok {
+			return img, nil
+		}
+		ref, err := s.Stat(ctx, digest)
+		if err != nil {
+			return img, err
+		}
+		img.URL, err = (&Lazy{Ref: ref, Store: s}).DataURL(ctx)
+		return img, err
+	})
+}
+
+// mapImages returns msgs with fn applied to every image, copying only the
+// messages that have images.
+func mapImages(msgs []chat.Message, fn func(chat.Image) (chat.Image, error)) ([]chat.Message, error) {
+	out := msgs
+	copied := false
+	for i, m := range msgs {
+		if len(m.Images) == 0 {
+			continue
+		}
+		if !copied {
+			out, copied = append([]chat.Message(nil), msgs...), true
+		}
+		images := make([]chat.Image, len(m.Images))
+		for j, img := range m.Images {
+			var err error
+			if images[j], err = fn(img); err != nil {
+				return nil, err
+			}
+		}
+		out[i].Images = images
+	}
+	return out, nil
+}
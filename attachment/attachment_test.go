@@ -0,0 +1,74 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestDirStore(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	s.MaxSize = 16
+	ref, err := s.Put(ctx, []byte("hello"), "")
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	again, err := PutBase64(ctx, s, "data:text/plain;base64,aGVsbG8=")
+	if err != nil || again.Digest != ref.Digest {
+		t.Fatalf("dedup failed: %+v, %v", again, err)
+	}
+	if ref.MIMEType != "text/plain; charset=utf-8" || ref.Size != 5 {
+		t.Fatalf("unexpected ref %+v", ref)
+	}
+
+	lazy := &Lazy{Ref: ref, Store: s}
+	url, err := lazy.DataURL(ctx)
+	if err != nil || url != "data:text/plain; charset=utf-8;base64,aGVsbG8=" {
+		t.Fatalf("data url: %q, %v", url, err)
+	}
+
+	if _, err := s.Put(ctx, make([]byte, 17), ""); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+	if _, _, err := s.Get(ctx, Digest([]byte("other"))); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.Stat(ctx, "sha256:../../etc"); err == nil {
+		t.Fatalf("expected invalid digest error")
+	}
+}
+
+func TestOffloadResolve(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	inline := chat.ImageData("image/png", []byte("png"))
+	remote := chat.Image{URL: "https://example.com/cat.png"}
+	msgs := []chat.Message{chat.System("s"), chat.UserWithImages("what is this?", inline, remote)}
+
+	stored, err := Offload(ctx, s, msgs)
+	if err != nil {
+		t.Fatalf("offload: %v", err)
+	}
+	if got := stored[1].Images; got[0].URL != "attachment:"+Digest([]byte("png")) || got[1] != remote {
+		t.Fatalf("unexpected offloaded images %+v", got)
+	}
+	if msgs[1].Images[0] != inline {
+		t.Fatalf("input modified")
+	}
+	resolved, err := Resolve(ctx, s, stored)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if resolved[1].Images[0] != inline || resolved[1].Images[1] != remote {
+		t.Fatalf("unexpected resolved images %+v", resolved[1].Images)
+	}
+	if _, err := Resolve(ctx, NewMemoryStore(), stored); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package uniai
+
+import (
+	"context"
+	"sync"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// DefaultChatBatchConcurrency is the number of ChatBatch requests in flight
+// when ChatBatchConfig.Concurrency is not set.
+const DefaultChatBatchConcurrency = 4
+
+// ChatBatchConfig configures ChatBatch.
+type ChatBatchConfig struct {
+	// Concurrency is the number of requests in flight (default 4).
+	Concurrency int
+}
+
+// ChatBatch runs each element of requests as a separate Chat call, with
+// cfg.Concurrency calls in flight. A failed request does not fail the
+// batch: its Results entry is nil and the error is listed in ItemErrors
+// under the request's index. The error is non-nil only when ctx ends
+// before every request has run.
+func (c *Client) ChatBatch(ctx context.Context, cfg ChatBatchConfig, requests ...[]chat.Option) (*chat.BatchResult, error) {
+	return runBatch(ctx, cfg.Concurrency, len(requests), func(ctx context.Context, i int) (*chat.Result, error) {
+		return c.Chat(ctx, requests[i]...)
+	})
+}
+
+// runBatch calls run for 0..n-1 with concurrency calls in flight and
+// collects the results as ChatBatch does.
+func runBatch(ctx context.Context, concurrency, n int, run func(ctx context.Context, i int) (*chat.Result, error)) (*chat.BatchResult, error) {
+	if concurrency <= 0 {
+		concurrency = DefaultChatBatchConcurrency
+	}
+	out := &chat.BatchResult{Results: make([]*chat.Result, n)}
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range n {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			out.Results[i], errs[i] = run(ctx, i)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			out.Results[i] = nil
+			out.ItemErrors = append(out.ItemErrors, chat.ItemError{
+				Index:      i,
+				ErrorClass: chat.ClassifyError(err),
+				Error:      err.Error(),
+			})
+			continue
+		}
+		res := out.Results[i]
+		out.Usage.Add(res.Usage)
+		out.Cost += res.Cost
+	}
+	return out, nil
+}
@@ -0,0 +1,29 @@
+package uniai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestChatBatchPartialFailure(t *testing.T) {
+	client := New(Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{
+		fake.Text("one", 0, 0),
+		{Err: errors.New("status 500: boom")},
+		fake.Text("three", 0, 0),
+	}}))
+	item := []ChatOption{WithProvider("fake"), WithMessages(User("hi"))}
+	out, err := client.ChatBatch(context.Background(), ChatBatchConfig{Concurrency: 1}, item, item, item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Results[0].Text != "one" || out.Results[1] != nil || out.Results[2].Text != "three" {
+		t.Fatalf("unexpected results: %#v", out.Results)
+	}
+	if len(out.ItemErrors) != 1 || out.ItemErrors[0].Index != 1 || out.ItemErrors[0].ErrorClass != "server" {
+		t.Fatalf("unexpected item errors: %#v", out.ItemErrors)
+	}
+}
@@ -0,0 +1,268 @@
+// Package bench fires synthetic or recorded chat workloads at providers with
+// increasing concurrency and reports latency percentiles, time to first
+// token, throughput and error rates per provider and model.
+package bench
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Chatter is the subset of uniai.Client the harness needs.
+type Chatter interface {
+	Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error)
+}
+
+// Target is a provider and model under test.
+type Target struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+}
+
+func (t Target) String() string {
+	if t.Model == "" {
+		return t.Provider
+	}
+	return t.Provider + "/" + t.Model
+}
+
+// Workload is a set of conversations sent round-robin.
+type Workload [][]chat.Message
+
+// Synthetic returns n single-turn prompts of roughly words words each.
+func Synthetic(n, words int) Workload {
+	if n <= 0 {
+		n = 1
+	}
+	if words <= 0 {
+		words = 50
+	}
+	filler := strings.TrimSpace(strings.Repeat("lorem ipsum dolor sit amet ", (words+4)/5))
+	w := make(Workload, n)
+	for i := range w {
+		w[i] = []chat.Message{chat.User(fmt.Sprintf("Request %d. Summarize in one sentence: %s", i, filler))}
+	}
+	return w
+}
+
+// ReadWorkload reads recorded requests as JSON lines, each either a
+// chat.Request or an object with a "messages" array.
+func ReadWorkload(r io.Reader) (Workload, error) {
+	var w Workload
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var rec struct {
+			Messages []chat.Message `json:"messages"`
+		}
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("workload line %d: %w", line, err)
+		}
+		if len(rec.Messages) == 0 {
+			return nil, fmt.Errorf("workload line %d: no messages", line)
+		}
+		w = append(w, rec.Messages)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(w) == 0 {
+		return nil, fmt.Errorf("workload is empty")
+	}
+	return w, nil
+}
+
+// Config describes a run. Each target is driven through every concurrency
+// level in Ramp in turn, sending RequestsPerStage requests at each level.
+type Config struct {
+	Targets          []Target
+	Workload         Workload
+	Ramp             []int
+	RequestsPerStage int
+	// Stream measures time to first token by streaming responses.
+	Stream bool
+	// Timeout bounds each request (default 60s).
+	Timeout time.Duration
+	// Options are applied to every request.
+	Options []chat.Option
+}
+
+// Stage is the result of one target at one concurrency level.
+type Stage struct {
+	Target       Target        `json:"target"`
+	Concurrency  int           `json:"concurrency"`
+	Requests     int           `json:"requests"`
+	Errors       int           `json:"errors"`
+	ErrorRate    float64       `json:"error_rate"`
+	Duration     time.Duration `json:"duration"`
+	Throughput   float64       `json:"throughput_rps"`
+	OutputTokens int           `json:"output_tokens"`
+	OutputTPS    float64       `json:"output_tokens_per_sec"`
+	LatencyP50   time.Duration `json:"latency_p50"`
+	LatencyP90   time.Duration `json:"latency_p90"`
+	LatencyP99   time.Duration `json:"latency_p99"`
+	TTFTP50      time.Duration `json:"ttft_p50,omitempty"`
+	TTFTP90      time.Duration `json:"ttft_p90,omitempty"`
+	TTFTP99      time.Duration `json:"ttft_p99,omitempty"`
+	FirstErrors  []string      `json:"first_errors,omitempty"`
+}
+
+// Report holds every stage in run order.
+type Report struct {
+	Stages []Stage `json:"stages"`
+}
+
+// maxErrorSamples caps the error messages kept per stage.
+const maxErrorSamples = 3
+
+// Run executes cfg against client.
+func Run(ctx context.Context, client Chatter, cfg Config) (*Report, error) {
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("at least one target is required")
+	}
+	if len(cfg.Workload) == 0 {
+		return nil, fmt.Errorf("workload is empty")
+	}
+	ramp := cfg.Ramp
+	if len(ramp) == 0 {
+		ramp = []int{1}
+	}
+	perStage := cfg.RequestsPerStage
+	if perStage <= 0 {
+		perStage = 10
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+
+	report := &Report{}
+	for _, target := range cfg.Targets {
+		for _, conc := range ramp {
+			if conc <= 0 {
+				return nil, fmt.Errorf("concurrency must be positive, got %d", conc)
+			}
+			stage := runStage(ctx, client, cfg, target, conc, perStage, timeout)
+			report.Stages = append(report.Stages, stage)
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+		}
+	}
+	return report, nil
+}
+
+type sample struct {
+	latency time.Duration
+	ttft    time.Duration
+	tokens  int
+	err     error
+}
+
+func runStage(ctx context.Context, client Chatter, cfg Config, target Target, conc, n int, timeout time.Duration) Stage {
+	samples := make([]sample, n)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < conc; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= n || ctx.Err() != nil {
+					return
+				}
+				samples[i] = runOne(ctx, client, cfg, target, cfg.Workload[i%len(cfg.Workload)], timeout)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	stage := Stage{Target: target, Concurrency: conc, Duration: elapsed}
+	var latencies, ttfts []time.Duration
+	for _, s := range samples {
+		if s.latency == 0 && s.err == nil {
+			continue // not run: context cancelled
+		}
+		stage.Requests++
+		if s.err != nil {
+			stage.Errors++
+			if len(stage.FirstErrors) < maxErrorSamples {
+				stage.FirstErrors = append(stage.FirstErrors, s.err.Error())
+			}
+			continue
+		}
+		latencies = append(latencies, s.latency)
+		if s.ttft > 0 {
+			ttfts = append(ttfts, s.ttft)
+		}
+		stage.OutputTokens += s.tokens
+	}
+	if stage.Requests > 0 {
+		stage.ErrorRate = float64(stage.Errors) / float64(stage.Requests)
+	}
+	if secs := elapsed.Seconds(); secs > 0 {
+		stage.Throughput = float64(stage.Requests-stage.Errors) / secs
+		stage.OutputTPS = float64(stage.OutputTokens) / secs
+	}
+	stage.LatencyP50, stage.LatencyP90, stage.LatencyP99 = percentiles(latencies)
+	stage.TTFTP50, stage.TTFTP90, stage.TTFTP99 = percentiles(ttfts)
+	return stage
+}
+
+func runOne(ctx context.Context, client Chatter, cfg Config, target Target, msgs []chat.Message, timeout time.Duration) sample {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	opts := append([]chat.Option{}, cfg.Options...)
+	opts = append(opts, chat.WithReplaceMessages(msgs...), chat.WithProvider(target.Provider))
+	if target.Model != "" {
+		opts = append(opts, chat.WithModel(target.Model))
+	}
+	start := time.Now()
+	var first atomic.Int64
+	if cfg.Stream {
+		opts = append(opts, chat.WithOnToken(func(string) {
+			first.CompareAndSwap(0, int64(time.Since(start)))
+		}))
+	}
+	resp, err := client.Chat(ctx, opts...)
+	s := sample{latency: time.Since(start), ttft: time.Duration(first.Load()), err: err}
+	if err == nil {
+		s.tokens = resp.Usage.OutputTokens
+	}
+	return s
+}
+
+// percentiles returns the p50, p90 and p99 of d (nearest rank).
+func percentiles(d []time.Duration) (p50, p90, p99 time.Duration) {
+	if len(d) == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	at := func(p float64) time.Duration {
+		idx := int(math.Ceil(p*float64(len(d)))) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return d[idx]
+	}
+	return at(0.50), at(0.90), at(0.99)
+}
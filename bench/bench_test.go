@@ -0,0 +1,63 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+type fakeChatter struct{ calls atomic.Int64 }
+
+func (f *fakeChatter) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if f.calls.Add(1)%5 == 0 {
+		return nil, errors.New("rate limited")
+	}
+	time.Sleep(time.Millisecond)
+	if req.Options.OnToken != nil {
+		req.Options.OnToken("hi")
+	}
+	return &chat.Result{Text: "hi", Usage: chat.Usage{OutputTokens: 2}}, nil
+}
+
+func TestRun(t *testing.T) {
+	report, err := Run(context.Background(), &fakeChatter{}, Config{
+		Targets:          []Target{{Provider: "openai", Model: "gpt-4o"}},
+		Workload:         Synthetic(3, 10),
+		Ramp:             []int{1, 4},
+		RequestsPerStage: 10,
+		Stream:           true,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(report.Stages) != 2 {
+		t.Fatalf("expected 2 stages, got %d", len(report.Stages))
+	}
+	for _, s := range report.Stages {
+		if s.Requests != 10 || s.Errors != 2 || s.ErrorRate != 0.2 || s.OutputTokens != 16 {
+			t.Fatalf("unexpected stage: %+v", s)
+		}
+		if s.LatencyP50 <= 0 || s.TTFTP50 <= 0 || s.LatencyP99 < s.LatencyP50 {
+			t.Fatalf("unexpected percentiles: %+v", s)
+		}
+	}
+}
+
+func TestReadWorkload(t *testing.T) {
+	w, err := ReadWorkload(strings.NewReader(`{"messages":[{"role":"user","content":"a"}]}` + "\n\n" + `{"model":"x","messages":[{"role":"user","content":"b"}]}`))
+	if err != nil || len(w) != 2 || w[1][0].Content != "b" {
+		t.Fatalf("unexpected workload %+v %v", w, err)
+	}
+	if _, err := ReadWorkload(strings.NewReader(`{"messages":[]}`)); err == nil {
+		t.Fatalf("expected error for empty messages")
+	}
+}
@@ -0,0 +1,45 @@
+package uniai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// betaFamily returns the provider family whose preview flags providerName
+// accepts, or "" if it has none.
+func betaFamily(providerName string) string {
+	switch providerName {
+	case "anthropic":
+		return chat.BetaProviderAnthropic
+	case "openai", "openai_custom":
+		return chat.BetaProviderOpenAI
+	}
+	return ""
+}
+
+// applyBetas keeps the req.Options.Betas that providerName supports and
+// reports the others as warnings.
+func applyBetas(providerName string, req *chat.Request) (*chat.Request, []string) {
+	if len(req.Options.Betas) == 0 {
+		return req, nil
+	}
+	family := betaFamily(providerName)
+	var keep, dropped []string
+	for _, b := range req.Options.Betas {
+		if family != "" {
+			if _, unsupported := chat.ResolveBetas(family, []string{b}); len(unsupported) == 0 {
+				keep = append(keep, b)
+				continue
+			}
+		}
+		dropped = append(dropped, b)
+	}
+	if len(dropped) == 0 {
+		return req, nil
+	}
+	r := *req
+	r.Options.Betas = keep
+	return &r, []string{fmt.Sprintf("betas %s are not supported by %s; ignored", strings.Join(dropped, ", "), providerName)}
+}
@@ -0,0 +1,73 @@
+package uniai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestResolveBetas(t *testing.T) {
+	betas := []string{chat.BetaContext1M, "anthropic:files-api-2025-04-14", "openai:assistants=v2", chat.BetaContext1M}
+	flags, unsupported := chat.ResolveBetas(chat.BetaProviderAnthropic, betas)
+	if len(flags) != 2 || flags[0] != "context-1m-2025-08-07" || flags[1] != "files-api-2025-04-14" {
+		t.Fatalf("unexpected anthropic flags: %v", flags)
+	}
+	if len(unsupported) != 1 || unsupported[0] != "openai:assistants=v2" {
+		t.Fatalf("unexpected unsupported: %v", unsupported)
+	}
+	flags, unsupported = chat.ResolveBetas(chat.BetaProviderOpenAI, betas)
+	if len(flags) != 1 || flags[0] != "assistants=v2" || len(unsupported) != 3 {
+		t.Fatalf("unexpected openai flags: %v %v", flags, unsupported)
+	}
+}
+
+func TestApplyBetas(t *testing.T) {
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("hi")),
+		chat.WithBetas(chat.BetaInterleavedThinking, "openai:assistants=v2"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, warnings := applyBetas("anthropic", req)
+	if len(out.Options.Betas) != 1 || out.Options.Betas[0] != chat.BetaInterleavedThinking || len(warnings) != 1 {
+		t.Fatalf("unexpected anthropic betas: %v %v", out.Options.Betas, warnings)
+	}
+	if len(req.Options.Betas) != 2 {
+		t.Fatalf("request was modified: %v", req.Options.Betas)
+	}
+	out, warnings = applyBetas("gemini", req)
+	if len(out.Options.Betas) != 0 || len(warnings) != 1 {
+		t.Fatalf("unexpected gemini betas: %v %v", out.Options.Betas, warnings)
+	}
+}
+
+func TestBetasOpenAIHeader(t *testing.T) {
+	var header string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		header = r.Header.Get("OpenAI-Beta")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"c1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	defer srv.Close()
+
+	client := New(Config{Provider: "openai_custom", OpenAIAPIKey: "sk-test", OpenAIAPIBase: srv.URL, OpenAIModel: "gpt-4o"})
+	resp, err := client.Chat(context.Background(),
+		chat.WithMessages(chat.User("hi")),
+		chat.WithBetas("openai:assistants=v2", chat.BetaContext1M),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if header != "assistants=v2" {
+		t.Fatalf("unexpected OpenAI-Beta header %q", header)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Fatalf("expected one warning, got %v", resp.Warnings)
+	}
+}
@@ -0,0 +1,122 @@
+package uniai
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// ErrBreakerOpen is returned for chat calls to a provider whose circuit
+// breaker is open.
+var ErrBreakerOpen = errors.New("circuit breaker open")
+
+// Circuit-breaker states reported by Client.BreakerState.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// BreakerConfig enables a circuit breaker per provider. After Threshold
+// consecutive chat calls fail with a server, network or timeout error, the
+// breaker opens and calls to the provider fail fast with ErrBreakerOpen.
+// After Cooldown one trial call goes through (half open); it closes the
+// breaker if it succeeds and opens it again if it fails.
+type BreakerConfig struct {
+	// Threshold is the number of consecutive failures that opens the
+	// breaker (default 5).
+	Threshold int
+	// Cooldown is how long the breaker stays open (default 30s).
+	Cooldown time.Duration
+}
+
+type breaker struct {
+	state    string
+	failures int
+	openedAt time.Time
+	// trial is set while the half-open trial call is in flight.
+	trial bool
+}
+
+// BreakerState returns the circuit-breaker state of provider: one of
+// BreakerClosed, BreakerOpen or BreakerHalfOpen. It is always BreakerClosed
+// when Config.Breaker is not set.
+func (c *Client) BreakerState(provider string) string {
+	cfg := c.cfg.Breaker
+	if cfg == nil {
+		return BreakerClosed
+	}
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+	b := c.breakers[provider]
+	switch {
+	case b == nil:
+		return BreakerClosed
+	case b.state == BreakerOpen && time.Since(b.openedAt) >= breakerCooldown(cfg):
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allowCall fails fast when the breaker of provider is open. Otherwise the
+// returned function reports the outcome of the call.
+func (c *Client) allowCall(provider string) (func(error), error) {
+	cfg := c.cfg.Breaker
+	if cfg == nil {
+		return func(error) {}, nil
+	}
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+	if c.breakers == nil {
+		c.breakers = map[string]*breaker{}
+	}
+	b := c.breakers[provider]
+	if b == nil {
+		b = &breaker{state: BreakerClosed}
+		c.breakers[provider] = b
+	}
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= breakerCooldown(cfg) {
+		b.state = BreakerHalfOpen
+	}
+	switch {
+	case b.state == BreakerOpen, b.state == BreakerHalfOpen && b.trial:
+		return nil, fmt.Errorf("%s: %w", provider, ErrBreakerOpen)
+	case b.state == BreakerHalfOpen:
+		b.trial = true
+	}
+	return func(err error) { c.reportCall(b, err) }, nil
+}
+
+func (c *Client) reportCall(b *breaker, err error) {
+	threshold := c.cfg.Breaker.Threshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+	b.trial = false
+	switch chat.ClassifyError(err) {
+	case chat.ErrorClassServer, chat.ErrorClassNetwork, chat.ErrorClassTimeout:
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= threshold {
+			b.state = BreakerOpen
+			b.openedAt = time.Now()
+		}
+	case chat.ErrorClassCanceled:
+		// says nothing about the provider; a half-open breaker waits for
+		// the next trial
+	default:
+		// the provider answered, even if with an error
+		b.state = BreakerClosed
+		b.failures = 0
+	}
+}
+
+func breakerCooldown(cfg *BreakerConfig) time.Duration {
+	if cfg.Cooldown <= 0 {
+		return 30 * time.Second
+	}
+	return cfg.Cooldown
+}
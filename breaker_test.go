@@ -0,0 +1,48 @@
+package uniai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	client := New(Config{Breaker: &BreakerConfig{Threshold: 2, Cooldown: 20 * time.Millisecond}})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{
+		{Err: errors.New("status 503: overloaded")},
+		{Err: errors.New("status 503: overloaded")},
+		fake.Text("ok", 0, 0),
+	}}))
+	call := func() (string, error) {
+		resp, err := client.Chat(context.Background(), WithProvider("fake"), WithMessages(User("hi")))
+		if err != nil {
+			return "", err
+		}
+		return resp.Text, nil
+	}
+	for range 2 {
+		if _, err := call(); err == nil || errors.Is(err, ErrBreakerOpen) {
+			t.Fatalf("expected provider error, got %v", err)
+		}
+	}
+	if state := client.BreakerState("fake"); state != BreakerOpen {
+		t.Fatalf("expected open breaker, got %q", state)
+	}
+	if _, err := call(); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("expected ErrBreakerOpen, got %v", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if state := client.BreakerState("fake"); state != BreakerHalfOpen {
+		t.Fatalf("expected half-open breaker, got %q", state)
+	}
+	if text, err := call(); err != nil || text != "ok" {
+		t.Fatalf("trial call: %q, %v", text, err)
+	}
+	if state := client.BreakerState("fake"); state != BreakerClosed {
+		t.Fatalf("expected closed breaker, got %q", state)
+	}
+}
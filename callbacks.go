@@ -0,0 +1,51 @@
+package uniai
+
+import "github.com/quailyquaily/uniai/chat"
+
+// wrapCallbacks folds OnToken and OnEvent into req.Options.OnStream so that
+// streaming providers feed them directly. The returned finish func replays
+// the result through the callbacks when the provider did not stream.
+func wrapCallbacks(req *chat.Request) func(*chat.Result) {
+	onToken := req.Options.OnToken
+	onEvent := req.Options.OnEvent
+	if onToken == nil && onEvent == nil {
+		return func(*chat.Result) {}
+	}
+
+	streamed := false
+	next := req.Options.OnStream
+	req.Options.OnStream = func(ev chat.StreamEvent) error {
+		streamed = true
+		if onToken != nil && ev.Delta != "" {
+			onToken(ev.Delta)
+		}
+		if onEvent != nil {
+			onEvent(ev)
+		}
+		if next != nil {
+			return next(ev)
+		}
+		return nil
+	}
+
+	return func(resp *chat.Result) {
+		if streamed || resp == nil {
+			return
+		}
+		if onToken != nil && resp.Text != "" {
+			onToken(resp.Text)
+		}
+		if onEvent == nil {
+			return
+		}
+		if resp.Text != "" {
+			onEvent(chat.StreamEvent{Delta: resp.Text})
+		}
+		for i := range resp.ToolCalls {
+			call := resp.ToolCalls[i]
+			onEvent(chat.StreamEvent{ToolCall: &call})
+		}
+		usage := resp.Usage
+		onEvent(chat.StreamEvent{Usage: &usage, Cost: resp.Cost, Done: true})
+	}
+}
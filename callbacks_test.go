@@ -0,0 +1,47 @@
+package uniai
+
+import (
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestWrapCallbacksBlockingFallback(t *testing.T) {
+	var tokens []string
+	var events []chat.StreamEvent
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("hi")),
+		chat.WithOnToken(func(delta string) { tokens = append(tokens, delta) }),
+		chat.WithOnEvent(func(ev chat.StreamEvent) { events = append(events, ev) }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	finish := wrapCallbacks(req)
+	if req.Options.OnStream == nil {
+		t.Fatalf("expected OnStream to be set")
+	}
+	finish(&chat.Result{Text: "hello"})
+	if len(tokens) != 1 || tokens[0] != "hello" {
+		t.Fatalf("unexpected tokens: %v", tokens)
+	}
+	if len(events) != 2 || events[0].Delta != "hello" || !events[1].Done {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
+
+func TestWrapCallbacksStreamed(t *testing.T) {
+	var tokens []string
+	req, err := chat.BuildRequest(chat.WithMessages(chat.User("hi")), chat.WithOnToken(func(delta string) { tokens = append(tokens, delta) }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	finish := wrapCallbacks(req)
+	_ = req.Options.OnStream(chat.StreamEvent{Delta: "he"})
+	_ = req.Options.OnStream(chat.StreamEvent{Delta: "llo"})
+	_ = req.Options.OnStream(chat.StreamEvent{Done: true})
+	finish(&chat.Result{Text: "hello"})
+	if len(tokens) != 2 {
+		t.Fatalf("expected streamed tokens only, got %v", tokens)
+	}
+}
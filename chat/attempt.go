@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Values of AttemptInfo.Status.
+const (
+	AttemptOK    = "ok"
+	AttemptError = "error"
+)
+
+// AttemptInfo describes one provider call.
+type AttemptInfo struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+	Status   string `json:"status"`
+	// ErrorClass and Error are set for failed attempts; see ClassifyError.
+	ErrorClass string        `json:"error_class,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Usage      Usage         `json:"usage"`
+	Cost       float64       `json:"cost,omitempty"`
+}
+
+// Values returned by ClassifyError.
+const (
+	ErrorClassContextLength  = "context_length"
+	ErrorClassRateLimit      = "rate_limit"
+	ErrorClassTimeout        = "timeout"
+	ErrorClassCanceled       = "canceled"
+	ErrorClassAuth           = "auth"
+	ErrorClassInvalidRequest = "invalid_request"
+	ErrorClassServer         = "server"
+	ErrorClassNetwork        = "network"
+	ErrorClassOther          = "other"
+	// ErrorClassContentFilter marks an ItemError for a choice the
+	// provider's content filter blocked; ClassifyError does not return it.
+	ErrorClassContentFilter = "content_filter"
+)
+
+var statusPattern = regexp.MustCompile(`status(?: code)?:? (\d{3})`)
+
+// ClassifyError returns a coarse, provider-independent class for err, or ""
+// for nil. The HTTP status is taken from OpenAI SDK errors or from the
+// "status NNN" the other providers put in their error messages.
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if IsContextLengthError(err) {
+		return ErrorClassContextLength
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	status := 0
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		status = apiErr.StatusCode
+	} else if m := statusPattern.FindStringSubmatch(strings.ToLower(err.Error())); m != nil {
+		status, _ = strconv.Atoi(m[1])
+	}
+	switch {
+	case status == 429:
+		return ErrorClassRateLimit
+	case status == 401 || status == 403:
+		return ErrorClassAuth
+	case status == 408:
+		return ErrorClassTimeout
+	case status >= 500:
+		return ErrorClassServer
+	case status >= 400:
+		return ErrorClassInvalidRequest
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassNetwork
+	}
+	return ErrorClassOther
+}
+
+// IsRetryableClass reports whether an error of the given class is likely
+// to go away on retry: rate limits, timeouts, server and network errors,
+// and unclassified errors.
+func IsRetryableClass(class string) bool {
+	switch class {
+	case ErrorClassRateLimit, ErrorClassTimeout, ErrorClassServer, ErrorClassNetwork, ErrorClassOther:
+		return true
+	}
+	return false
+}
+
+// AttemptsError is returned by a failed Client.Chat call that reached at
+// least one provider. Its message is that of Err.
+type AttemptsError struct {
+	Err      error
+	Attempts []AttemptInfo
+}
+
+func (e *AttemptsError) Error() string { return e.Err.Error() }
+func (e *AttemptsError) Unwrap() error { return e.Err }
+
+// AttemptsFromError returns the attempts recorded in err, if any.
+func AttemptsFromError(err error) ([]AttemptInfo, bool) {
+	var ae *AttemptsError
+	if !errors.As(err, &ae) {
+		return nil, false
+	}
+	return ae.Attempts, true
+}
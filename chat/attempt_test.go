@@ -0,0 +1,36 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := map[error]string{
+		nil: "",
+		fmt.Errorf("anthropic api error: status 429: overloaded"): ErrorClassRateLimit,
+		fmt.Errorf("anthropic api error: status 401: bad key"):    ErrorClassAuth,
+		fmt.Errorf("api request failed with status 503: down"):    ErrorClassServer,
+		fmt.Errorf("status 400: prompt is too long"):              ErrorClassContextLength,
+		fmt.Errorf("call: %w", context.DeadlineExceeded):          ErrorClassTimeout,
+		errors.New("boom"): ErrorClassOther,
+	}
+	for err, want := range cases {
+		if got := ClassifyError(err); got != want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestAttemptsFromError(t *testing.T) {
+	err := fmt.Errorf("chat: %w", &AttemptsError{Err: errors.New("boom"), Attempts: []AttemptInfo{{Status: AttemptError}}})
+	attempts, ok := AttemptsFromError(err)
+	if !ok || len(attempts) != 1 {
+		t.Fatalf("attempts not found in %v", err)
+	}
+	if _, ok := AttemptsFromError(errors.New("plain")); ok {
+		t.Fatalf("unexpected attempts")
+	}
+}
@@ -0,0 +1,63 @@
+package chat
+
+import "strings"
+
+// Portable names for provider preview features, for Options.Betas.
+const (
+	BetaContext1M                = "context-1m"
+	BetaInterleavedThinking      = "interleaved-thinking"
+	BetaFineGrainedToolStreaming = "fine-grained-tool-streaming"
+	BetaTokenEfficientTools      = "token-efficient-tools"
+)
+
+// Provider families that accept preview flags.
+const (
+	BetaProviderAnthropic = "anthropic"
+	BetaProviderOpenAI    = "openai"
+)
+
+// betaFlags maps portable names to the flag each provider family expects:
+// an anthropic-beta header value, or an OpenAI-Beta header value.
+var betaFlags = map[string]map[string]string{
+	BetaContext1M:                {BetaProviderAnthropic: "context-1m-2025-08-07"},
+	BetaInterleavedThinking:      {BetaProviderAnthropic: "interleaved-thinking-2025-05-14"},
+	BetaFineGrainedToolStreaming: {BetaProviderAnthropic: "fine-grained-tool-streaming-2025-05-14"},
+	BetaTokenEfficientTools:      {BetaProviderAnthropic: "token-efficient-tools-2025-02-19"},
+}
+
+// ResolveBetas returns the flags of family ("anthropic" or "openai") for
+// betas, in order and without duplicates, and the betas that family does
+// not support. A beta is a portable name such as BetaContext1M, or a raw
+// flag qualified by its family, such as "anthropic:files-api-2025-04-14"
+// or "openai:assistants=v2", which is passed through to that family only.
+func ResolveBetas(family string, betas []string) (flags, unsupported []string) {
+	seen := map[string]bool{}
+	for _, b := range betas {
+		b = strings.TrimSpace(b)
+		if b == "" {
+			continue
+		}
+		var flag string
+		if prefix, raw, ok := strings.Cut(b, ":"); ok {
+			if prefix == family {
+				flag = raw
+			}
+		} else {
+			flag = betaFlags[b][family]
+		}
+		if flag == "" {
+			unsupported = append(unsupported, b)
+			continue
+		}
+		if !seen[flag] {
+			seen[flag] = true
+			flags = append(flags, flag)
+		}
+	}
+	return flags, unsupported
+}
+
+// WithBetas opts into provider preview features; see Options.Betas.
+func WithBetas(betas ...string) Option {
+	return func(r *Request) { r.Options.Betas = append(r.Options.Betas, betas...) }
+}
@@ -0,0 +1,111 @@
+package chat
+
+import (
+	"context"
+	"iter"
+)
+
+// StreamChunk is an element of the iterators returned by Stream and
+// ChatStream: a text, reasoning or tool call delta, a completed tool call,
+// or, last, the Done chunk carrying the final usage and the whole Result.
+type StreamChunk struct {
+	StreamEvent
+	// Result is set on the Done chunk.
+	Result *Result `json:"result,omitempty"`
+}
+
+// Stream runs call, which must deliver its events to onStream, and returns
+// them as an iterator for callers that prefer pulling chunks to callbacks:
+//
+//	for chunk, err := range chat.ChatStream(ctx, p, req) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Print(chunk.Delta)
+//	}
+//
+// The last chunk has Done and Result set. If call fails, the error is
+// yielded instead and the iteration ends. Breaking out of the loop cancels
+// the call. A call that replies without streaming yields its text in the
+// Done chunk.
+func Stream(ctx context.Context, call func(ctx context.Context, onStream OnStreamFunc) (*Result, error)) iter.Seq2[StreamChunk, error] {
+	return func(yield func(StreamChunk, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		chunks := make(chan StreamChunk)
+		var res *Result
+		var err error
+		go func() {
+			defer close(chunks)
+			res, err = call(ctx, func(ev StreamEvent) error {
+				select {
+				case chunks <- StreamChunk{StreamEvent: ev}:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}()
+
+		// the provider's Done event is held back and completed with the
+		// Result once call returns
+		var done *StreamChunk
+		streamed := false
+		for chunk := range chunks {
+			if chunk.Done {
+				done = &chunk
+				continue
+			}
+			streamed = true
+			if !yield(chunk, nil) {
+				cancel()
+				for range chunks {
+				}
+				return
+			}
+		}
+		if err != nil {
+			yield(StreamChunk{}, err)
+			return
+		}
+		if done == nil {
+			done = &StreamChunk{StreamEvent: StreamEvent{Done: true}}
+		}
+		if res != nil {
+			if done.Usage == nil {
+				usage := res.Usage
+				done.Usage = &usage
+			}
+			if !streamed {
+				done.Delta = res.Text
+			}
+		}
+		done.Result = res
+		yield(*done, nil)
+	}
+}
+
+// ChatStream streams the reply of p to req as an iterator; see Stream.
+// It works with every Provider, since providers stream through
+// req.Options.OnStream, which still receives the events when set.
+func ChatStream(ctx context.Context, p Provider, req *Request) iter.Seq2[StreamChunk, error] {
+	return Stream(ctx, func(ctx context.Context, onStream OnStreamFunc) (*Result, error) {
+		r := *req
+		r.Options.OnStream = ChainOnStream(req.Options.OnStream, onStream)
+		return p.Chat(ctx, &r)
+	})
+}
+
+// ChainOnStream returns a callback passing each event to first, if set,
+// and then to next. An error from first stops the event there.
+func ChainOnStream(first, next OnStreamFunc) OnStreamFunc {
+	if first == nil {
+		return next
+	}
+	return func(ev StreamEvent) error {
+		if err := first(ev); err != nil {
+			return err
+		}
+		return next(ev)
+	}
+}
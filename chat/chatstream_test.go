@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type streamFunc func(ctx context.Context, req *Request) (*Result, error)
+
+func (f streamFunc) Chat(ctx context.Context, req *Request) (*Result, error) { return f(ctx, req) }
+
+func TestChatStream(t *testing.T) {
+	p := streamFunc(func(ctx context.Context, req *Request) (*Result, error) {
+		for _, ev := range []StreamEvent{
+			{Delta: "Hel"},
+			{Delta: "lo"},
+			{ToolCallDelta: &ToolCallDelta{Index: 0, ID: "call_1", Name: "f"}},
+			{Done: true, Usage: &Usage{OutputTokens: 2}},
+		} {
+			if err := req.Options.OnStream(ev); err != nil {
+				return nil, err
+			}
+		}
+		return &Result{Text: "Hello", Usage: Usage{OutputTokens: 2}}, nil
+	})
+	var seen int
+	req := &Request{Options: Options{OnStream: func(StreamEvent) error { seen++; return nil }}}
+	var text string
+	var last StreamChunk
+	n := 0
+	for chunk, err := range ChatStream(context.Background(), p, req) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text += chunk.Delta
+		last = chunk
+		n++
+	}
+	if text != "Hello" || n != 4 || seen != 4 {
+		t.Fatalf("unexpected stream: text %q, %d chunks, %d callbacks", text, n, seen)
+	}
+	if !last.Done || last.Result == nil || last.Usage.OutputTokens != 2 {
+		t.Fatalf("unexpected last chunk: %+v", last)
+	}
+}
+
+func TestChatStreamBreakAndErrors(t *testing.T) {
+	var callErr error
+	p := streamFunc(func(ctx context.Context, req *Request) (*Result, error) {
+		for {
+			if err := req.Options.OnStream(StreamEvent{Delta: "x"}); err != nil {
+				callErr = err
+				return nil, err
+			}
+		}
+	})
+	for range ChatStream(context.Background(), p, &Request{}) {
+		break
+	}
+	if !errors.Is(callErr, context.Canceled) {
+		t.Fatalf("expected the call to be canceled, got %v", callErr)
+	}
+
+	boom := errors.New("boom")
+	failing := streamFunc(func(ctx context.Context, req *Request) (*Result, error) { return nil, boom })
+	for _, err := range ChatStream(context.Background(), failing, &Request{}) {
+		if !errors.Is(err, boom) {
+			t.Fatalf("expected boom, got %v", err)
+		}
+	}
+
+	quiet := streamFunc(func(ctx context.Context, req *Request) (*Result, error) { return &Result{Text: "all at once"}, nil })
+	for chunk, err := range ChatStream(context.Background(), quiet, &Request{}) {
+		if err != nil || !chunk.Done || chunk.Delta != "all at once" {
+			t.Fatalf("unexpected chunk %+v, %v", chunk, err)
+		}
+	}
+}
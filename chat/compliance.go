@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNonCompliant is returned (wrapped) when a request's requirements are
+// not met by the provider it would be sent to.
+var ErrNonCompliant = errors.New("provider does not meet request requirements")
+
+// Common compliance tags. Tags are free-form; these only fix the spelling
+// of the usual ones.
+const (
+	TagEU         = "region:eu"
+	TagUS         = "region:us"
+	TagNoTraining = "no-training"
+	TagHIPAA      = "hipaa"
+	TagZDR        = "zdr"
+)
+
+// MissingRequirements returns the requirements not present in tags,
+// compared case-insensitively.
+func MissingRequirements(tags, requirements []string) []string {
+	var missing []string
+	for _, req := range requirements {
+		found := false
+		for _, tag := range tags {
+			if strings.EqualFold(strings.TrimSpace(tag), strings.TrimSpace(req)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, req)
+		}
+	}
+	return missing
+}
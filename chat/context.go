@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrContextLengthExceeded is returned (wrapped) when the provider rejects a
+// request because the prompt does not fit the model's context window.
+var ErrContextLengthExceeded = errors.New("context length exceeded")
+
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context window",
+	"prompt is too long",
+	"input is too long",
+	"exceed context limit",
+	"reduce the length of the messages",
+}
+
+// IsContextLengthError reports whether err is, or looks like, a provider
+// context-length error.
+func IsContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrContextLengthExceeded) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	ContextStrategyDropOldest = "drop_oldest"
+	ContextStrategySummarize  = "summarize"
+)
+
+// ContextRecovery retries a request that failed with ErrContextLengthExceeded
+// after truncating its history. Each retry removes DropRatio of the
+// non-system messages (default 0.25, at least one); with the summarize
+// strategy the removed messages are replaced by a model-written summary.
+type ContextRecovery struct {
+	Strategy   string  `json:"strategy,omitempty"`
+	MaxRetries int     `json:"max_retries,omitempty"`
+	DropRatio  float64 `json:"drop_ratio,omitempty"`
+}
+
+// DropOldest removes the n oldest non-system messages. System messages and
+// the last message are always kept. Assistant messages and tool results
+// left at the head of the history are removed too, so the kept history
+// starts with a user message and has no tool results whose call was
+// dropped.
+func DropOldest(msgs []Message, n int) (kept, dropped []Message) {
+	if n <= 0 || len(msgs) == 0 {
+		return append([]Message{}, msgs...), nil
+	}
+	last := len(msgs) - 1
+	for i, msg := range msgs {
+		switch {
+		case msg.Role == RoleSystem || i == last:
+			kept = append(kept, msg)
+		case n > 0:
+			dropped = append(dropped, msg)
+			n--
+		case msg.Role != RoleUser && len(dropped) > 0 && len(kept) == countSystem(kept):
+			// assistant turn or orphaned tool result at the new head of
+			// the history
+			dropped = append(dropped, msg)
+		default:
+			kept = append(kept, msg)
+		}
+	}
+	return kept, dropped
+}
+
+func countSystem(msgs []Message) int {
+	count := 0
+	for _, msg := range msgs {
+		if msg.Role == RoleSystem {
+			count++
+		}
+	}
+	return count
+}
+
+const (
+	CompressionTrim      = "trim"
+	CompressionSummarize = "summarize"
+)
+
+// PromptCompression shrinks a long prompt to about TargetTokens before it
+// is sent. The trim strategy (default) drops the sentences least related
+// to the last message, in the way of LLMLingua; the summarize strategy has
+// the model condense the longest messages first. System messages and the
+// last message are never changed. What was removed is reported in
+// Result.Compression.
+type PromptCompression struct {
+	Strategy     string `json:"strategy,omitempty"`
+	TargetTokens int    `json:"target_tokens"`
+}
+
+// CompressionReport describes what prompt compression removed.
+type CompressionReport struct {
+	Strategy       string `json:"strategy"`
+	OriginalTokens int    `json:"original_tokens"`
+	Tokens         int    `json:"tokens"`
+	// Elided lists the removed sentences (trim) or the summarized messages
+	// (summarize), with the index of their message.
+	Elided []Elision `json:"elided,omitempty"`
+}
+
+// Elision is one piece of the prompt removed by compression.
+type Elision struct {
+	Message int    `json:"message"`
+	Text    string `json:"text"`
+	// Summary replaces Text in the summarize strategy.
+	Summary string `json:"summary,omitempty"`
+}
@@ -0,0 +1,223 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DumpVersion is the version of the dump file format.
+const DumpVersion = 1
+
+// Dump records the lifecycle of one Client.Chat call for offline
+// debugging: the request as built from the options, the decisions taken
+// before it was sent, the provider payloads, every attempt, the stream
+// events and the outcome. Pass a new Dump with WithDump, then Save it; a
+// loaded dump can be replayed with Replay.
+type Dump struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// Request is the request as built from the options, before the client
+	// changed it.
+	Request *Request `json:"request"`
+	// Provider and Model are where the request was sent.
+	Provider  string         `json:"provider,omitempty"`
+	Model     string         `json:"model,omitempty"`
+	Decisions []DumpDecision `json:"decisions,omitempty"`
+	// Payloads are the provider requests and responses, as DebugFn
+	// receives them.
+	Payloads []DumpPayload `json:"payloads,omitempty"`
+	Attempts []AttemptInfo `json:"attempts,omitempty"`
+	// Events are the stream events of the call, before stream pacing and
+	// transformers, so a replay through a client applies them again.
+	Events []DumpEvent `json:"events,omitempty"`
+	Result *Result     `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+
+	mu    sync.Mutex
+	start time.Time
+}
+
+// DumpDecision is a change the client made to the request, such as a
+// policy reroute or prompt compression.
+type DumpDecision struct {
+	Stage  string `json:"stage"`
+	Detail string `json:"detail"`
+}
+
+// DumpPayload is a provider request or response body.
+type DumpPayload struct {
+	// Elapsed is the time since the call started.
+	Elapsed time.Duration `json:"elapsed"`
+	Label   string        `json:"label"`
+	Body    string        `json:"body"`
+}
+
+// DumpEvent is a stream event with the time since the call started.
+type DumpEvent struct {
+	Elapsed time.Duration `json:"elapsed"`
+	Event   StreamEvent   `json:"event"`
+}
+
+// WithDump records the call into d.
+func WithDump(d *Dump) Option {
+	return func(r *Request) { r.Options.Dump = d }
+}
+
+// Begin starts recording req. The client calls it before changing req.
+func (d *Dump) Begin(req *Request) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Version = DumpVersion
+	d.start = time.Now()
+	d.CreatedAt = d.start.UTC()
+	saved := *req
+	saved.Messages = append([]Message{}, req.Messages...)
+	saved.Options.Dump = nil
+	d.Request = &saved
+}
+
+// Decide records a decision. It is a no-op on a nil Dump.
+func (d *Dump) Decide(stage, format string, args ...any) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Decisions = append(d.Decisions, DumpDecision{Stage: stage, Detail: fmt.Sprintf(format, args...)})
+}
+
+// DebugFn returns a DebugFn recording payloads and passing them on to
+// next, if set.
+func (d *Dump) DebugFn(next DebugFn) DebugFn {
+	return func(label, payload string) {
+		d.mu.Lock()
+		d.Payloads = append(d.Payloads, DumpPayload{Elapsed: time.Since(d.start), Label: label, Body: payload})
+		d.mu.Unlock()
+		if next != nil {
+			next(label, payload)
+		}
+	}
+}
+
+// OnStream returns an OnStreamFunc recording events before passing them
+// on to next.
+func (d *Dump) OnStream(next OnStreamFunc) OnStreamFunc {
+	return func(ev StreamEvent) error {
+		d.mu.Lock()
+		d.Events = append(d.Events, DumpEvent{Elapsed: time.Since(d.start), Event: ev})
+		d.mu.Unlock()
+		return next(ev)
+	}
+}
+
+// Route records where the request is sent.
+func (d *Dump) Route(provider, model string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Provider, d.Model = provider, model
+}
+
+// End records the outcome of the call.
+func (d *Dump) End(res *Result, err error) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if res != nil {
+		saved := *res
+		saved.Raw = nil
+		d.Result = &saved
+		d.Attempts = res.Attempts
+	}
+	if err != nil {
+		d.Error = err.Error()
+		if attempts, ok := AttemptsFromError(err); ok {
+			d.Attempts = attempts
+		}
+	}
+}
+
+// Save writes the dump as indented JSON.
+func (d *Dump) Save(path string) error {
+	d.mu.Lock()
+	data, err := json.MarshalIndent(d, "", "  ")
+	d.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode dump: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// LoadDump reads a dump written by Save.
+func LoadDump(path string) (*Dump, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var d Dump
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("parse dump %s: %w", path, err)
+	}
+	if d.Version != DumpVersion {
+		return nil, fmt.Errorf("dump %s has unsupported version %d", path, d.Version)
+	}
+	return &d, nil
+}
+
+// Options rebuild the recorded request. Callbacks are not recorded, so
+// add WithOnStream and the like after them.
+func (d *Dump) Options() []Option {
+	return []Option{func(r *Request) {
+		if d.Request == nil {
+			return
+		}
+		*r = *d.Request
+		r.Messages = append([]Message{}, d.Request.Messages...)
+	}}
+}
+
+// Replay returns a provider that answers every request with the recorded
+// outcome: the stream events are sent to OnStream in order, without
+// delays, and then the result or error is returned. Register it under
+// d.Provider and send d.Options() to step through the call offline.
+func (d *Dump) Replay() Provider {
+	return dumpReplay{d}
+}
+
+type dumpReplay struct {
+	d *Dump
+}
+
+func (p dumpReplay) Chat(ctx context.Context, req *Request) (*Result, error) {
+	if emit := req.Options.OnStream; emit != nil {
+		for _, ev := range p.d.Events {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err := emit(ev.Event); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if p.d.Error != "" {
+		return nil, errors.New(p.d.Error)
+	}
+	if p.d.Result == nil {
+		return nil, fmt.Errorf("dump has no result")
+	}
+	res := *p.d.Result
+	res.Attempts = nil
+	return &res, nil
+}
@@ -0,0 +1,240 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Handoff adapts a conversation recorded with one provider so it can be
+// continued on provider. Tool call IDs are rewritten to the target's
+// format, tool calls without results and results without calls are
+// resolved, and features the target cannot represent are dropped or
+// flattened to text. Images and videos are kept on user messages when the
+// target takes them. Each lossy change is described in the returned
+// warnings. The input is not modified.
+func Handoff(messages []Message, provider string) ([]Message, []string) {
+	h := &handoff{
+		target:  handoffTargetFor(provider),
+		ids:     map[string]string{},
+		results: map[string]bool{},
+		calls:   map[string]string{},
+	}
+	for _, m := range messages {
+		switch m.Role {
+		case RoleTool:
+			h.results[m.ToolCallID] = true
+		case RoleAssistant:
+			for _, tc := range m.ToolCalls {
+				h.calls[tc.ID] = tc.Function.Name
+			}
+		}
+	}
+	out := make([]Message, 0, len(messages))
+	seenTurn := false
+	for _, m := range messages {
+		m.ToolCalls = append([]ToolCall(nil), m.ToolCalls...)
+		if len(m.Images) > 0 {
+			m.Images = h.images(m)
+		}
+		if len(m.Videos) > 0 {
+			m.Videos = h.videos(m)
+		}
+		if m.Name != "" && !h.target.names {
+			h.warn("message names dropped")
+			m.Name = ""
+		}
+		switch m.Role {
+		case RoleSystem:
+			if seenTurn && !h.target.inlineSystem {
+				h.warn("system messages after the first turn are merged into the system prompt")
+			}
+		case RoleAssistant:
+			seenTurn = true
+			m = h.assistant(m)
+			if m.Content == "" && len(m.ToolCalls) == 0 && h.target.nonEmpty {
+				h.warn("empty assistant messages dropped")
+				continue
+			}
+		case RoleTool:
+			seenTurn = true
+			if name, ok := h.calls[m.ToolCallID]; !ok || m.ToolCallID == "" {
+				h.warn("tool results without a matching call converted to user messages")
+				m = Message{Role: RoleUser, Content: "Tool result: " + m.Content}
+			} else if !h.target.tools {
+				h.warn("tool calls and results flattened to text")
+				m = Message{Role: RoleUser, Content: fmt.Sprintf("Tool %s returned: %s", name, m.Content)}
+			} else {
+				m.ToolCallID = h.id(m.ToolCallID)
+			}
+		default:
+			seenTurn = true
+		}
+		out = append(out, m)
+	}
+	if h.target.userFirst {
+		out = h.userFirst(out)
+	}
+	return out, h.warnings
+}
+
+type handoffTarget struct {
+	tools        bool // tool calls and results are sent natively
+	names        bool // Message.Name is supported
+	inlineSystem bool // system messages keep their position
+	nonEmpty     bool // messages must have content
+	userFirst    bool // the first turn must be a user message
+	images       bool // images are sent on user messages
+	imageURLs    bool // images may be URLs rather than data URLs
+	videos       bool // videos are sent on user messages
+	maxIDLen     int
+	idChars      func(r rune) bool
+}
+
+func handoffTargetFor(provider string) handoffTarget {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "anthropic":
+		return handoffTarget{tools: true, nonEmpty: true, userFirst: true, images: true, imageURLs: true, maxIDLen: 64, idChars: isIDChar}
+	case "bedrock":
+		return handoffTarget{nonEmpty: true, userFirst: true, images: true}
+	case "deepseek":
+		return handoffTarget{tools: true, names: true, inlineSystem: true, maxIDLen: 40}
+	case "gemini":
+		return handoffTarget{tools: true, names: true, inlineSystem: true, images: true, imageURLs: true, videos: true, maxIDLen: 40}
+	case "openai", "openai_custom", "xai", "vllm", "azure":
+		return handoffTarget{tools: true, names: true, inlineSystem: true, images: true, imageURLs: true, maxIDLen: 40}
+	case "susanoo":
+		return handoffTarget{tools: true, names: true, inlineSystem: true}
+	default:
+		return handoffTarget{tools: true, names: true, inlineSystem: true, images: true, imageURLs: true, videos: true}
+	}
+}
+
+func isIDChar(r rune) bool {
+	return r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+type handoff struct {
+	target   handoffTarget
+	ids      map[string]string // original tool call ID to rewritten ID
+	results  map[string]bool   // tool call IDs that have a result
+	calls    map[string]string // tool call ID to tool name
+	warnings []string
+}
+
+func (h *handoff) warn(msg string) {
+	for _, w := range h.warnings {
+		if w == msg {
+			return
+		}
+	}
+	h.warnings = append(h.warnings, msg)
+}
+
+func (h *handoff) assistant(m Message) Message {
+	calls := m.ToolCalls[:0]
+	var flattened []string
+	for _, tc := range m.ToolCalls {
+		if tc.ID == "" || !h.results[tc.ID] {
+			h.warn("tool calls without a result dropped")
+			continue
+		}
+		tc.Metadata = nil
+		if !json.Valid([]byte(tc.Function.Arguments)) {
+			if strings.TrimSpace(tc.Function.Arguments) != "" {
+				h.warn("invalid tool call arguments replaced with {}")
+			}
+			tc.Function.Arguments = "{}"
+		}
+		if !h.target.tools {
+			flattened = append(flattened, fmt.Sprintf("Called tool %s with %s", tc.Function.Name, tc.Function.Arguments))
+			continue
+		}
+		tc.ID = h.id(tc.ID)
+		calls = append(calls, tc)
+	}
+	if len(flattened) > 0 {
+		h.warn("tool calls and results flattened to text")
+		m.Content = strings.TrimSpace(m.Content + "\n" + strings.Join(flattened, "\n"))
+	}
+	if len(calls) == 0 {
+		calls = nil
+	}
+	m.ToolCalls = calls
+	return m
+}
+
+// images returns the images of m the target can take.
+func (h *handoff) images(m Message) []Image {
+	switch {
+	case m.Role != RoleUser:
+		h.warn("images on non-user messages dropped")
+		return nil
+	case !h.target.images:
+		h.warn("images dropped")
+		return nil
+	case h.target.imageURLs:
+		return append([]Image(nil), m.Images...)
+	}
+	var out []Image
+	for _, img := range m.Images {
+		if _, _, ok := img.Data(); ok {
+			out = append(out, img)
+		} else {
+			h.warn("image URLs dropped; only inline images are supported")
+		}
+	}
+	return out
+}
+
+// videos returns the videos of m the target can take.
+func (h *handoff) videos(m Message) []Video {
+	switch {
+	case m.Role != RoleUser:
+		h.warn("videos on non-user messages dropped")
+		return nil
+	case !h.target.videos:
+		h.warn("videos dropped")
+		return nil
+	}
+	return append([]Video(nil), m.Videos...)
+}
+
+// id returns the rewritten form of a tool call ID, keeping valid IDs.
+func (h *handoff) id(orig string) string {
+	if id, ok := h.ids[orig]; ok {
+		return id
+	}
+	id := orig
+	valid := h.target.maxIDLen == 0 || len(id) <= h.target.maxIDLen
+	if valid && h.target.idChars != nil {
+		valid = strings.IndexFunc(id, func(r rune) bool { return !h.target.idChars(r) }) < 0
+	}
+	if !valid {
+		id = fmt.Sprintf("call_%d", len(h.ids))
+		for _, taken := h.calls[id]; taken; _, taken = h.calls[id] {
+			id += "_"
+		}
+	}
+	h.ids[orig] = id
+	return id
+}
+
+// userFirst prepends a placeholder user message when the first non-system
+// message is not from the user.
+func (h *handoff) userFirst(msgs []Message) []Message {
+	for i, m := range msgs {
+		if m.Role == RoleSystem {
+			continue
+		}
+		if m.Role == RoleUser {
+			return msgs
+		}
+		h.warn("placeholder user message inserted before the first assistant message")
+		out := make([]Message, 0, len(msgs)+1)
+		out = append(out, msgs[:i]...)
+		out = append(out, User("(conversation continued)"))
+		return append(out, msgs[i:]...)
+	}
+	return msgs
+}
@@ -0,0 +1,99 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+func handoffConversation() []Message {
+	return []Message{
+		System("be brief"),
+		User("weather in Oslo and Rome?"),
+		{Role: RoleAssistant, ToolCalls: []ToolCall{
+			{ID: "call.oslo|1", Type: "function", Function: ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Oslo"}`}},
+			{ID: "call_rome", Type: "function", Function: ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Rome"}`}},
+		}},
+		ToolResult("call.oslo|1", "rain"),
+		ToolResult("call_paris", "sun"),
+		Assistant("Rain in Oslo."),
+	}
+}
+
+func TestHandoffAnthropic(t *testing.T) {
+	in := handoffConversation()
+	out, warnings := Handoff(in, "anthropic")
+	if in[2].ToolCalls[0].ID != "call.oslo|1" {
+		t.Fatalf("input modified")
+	}
+	calls := out[2].ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_0" || out[3].ToolCallID != "call_0" {
+		t.Fatalf("unexpected tool calls %+v / %+v", calls, out[3])
+	}
+	if out[4].Role != RoleUser || !strings.Contains(out[4].Content, "sun") {
+		t.Fatalf("orphan result not converted: %+v", out[4])
+	}
+	want := []string{"tool calls without a result dropped", "tool results without a matching call converted to user messages"}
+	if strings.Join(warnings, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected warnings %q", warnings)
+	}
+}
+
+func TestHandoffBedrockFlattensTools(t *testing.T) {
+	out, warnings := Handoff(handoffConversation(), "bedrock")
+	for _, m := range out {
+		if m.Role == RoleTool || len(m.ToolCalls) > 0 {
+			t.Fatalf("tool message left for bedrock: %+v", m)
+		}
+	}
+	if out[2].Content != `Called tool get_weather with {"city":"Oslo"}` || out[3].Content != "Tool get_weather returned: rain" {
+		t.Fatalf("unexpected flattening %+v", out[2:4])
+	}
+	if len(warnings) == 0 {
+		t.Fatalf("expected warnings")
+	}
+}
+
+func TestHandoffUserFirst(t *testing.T) {
+	out, _ := Handoff([]Message{System("s"), Assistant("hello"), User("hi")}, "anthropic")
+	if len(out) != 4 || out[1].Role != RoleUser {
+		t.Fatalf("expected placeholder user message, got %+v", out)
+	}
+	out, warnings := Handoff([]Message{Assistant("hello"), User("hi")}, "openai")
+	if len(out) != 2 || len(warnings) != 0 {
+		t.Fatalf("openai conversation changed: %+v %q", out, warnings)
+	}
+}
+
+func TestHandoffImages(t *testing.T) {
+	inline, remote := ImageData("image/png", []byte("png")), Image{URL: "https://example.com/cat.png"}
+	in := []Message{UserWithImages("what is this?", inline, remote), Assistant("a cat")}
+
+	out, warnings := Handoff(in, "openai")
+	if len(out[0].Images) != 2 || len(warnings) != 0 {
+		t.Fatalf("images not kept: %+v %q", out[0], warnings)
+	}
+	out, warnings = Handoff(in, "bedrock")
+	if len(out[0].Images) != 1 || out[0].Images[0] != inline || len(warnings) != 1 {
+		t.Fatalf("expected inline image only: %+v %q", out[0], warnings)
+	}
+	out, warnings = Handoff(in, "deepseek")
+	if len(out[0].Images) != 0 || strings.Join(warnings, "") != "images dropped" {
+		t.Fatalf("expected images dropped: %+v %q", out[0], warnings)
+	}
+	if len(in[0].Images) != 2 {
+		t.Fatalf("input modified")
+	}
+}
+
+func TestHandoffVideos(t *testing.T) {
+	in := []Message{UserWithVideos("summarize", Video{URL: "https://www.youtube.com/watch?v=abc"}), Assistant("a talk")}
+
+	out, warnings := Handoff(in, "gemini")
+	if len(out[0].Videos) != 1 || len(warnings) != 0 {
+		t.Fatalf("videos not kept: %+v %q", out[0], warnings)
+	}
+	out, warnings = Handoff(in, "openai")
+	if len(out[0].Videos) != 0 || strings.Join(warnings, "") != "videos dropped" {
+		t.Fatalf("expected videos dropped: %+v %q", out[0], warnings)
+	}
+}
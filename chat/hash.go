@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Hash returns a hex SHA-256 digest of the canonical form of the request
+// (see Canonical). Requests that differ only in map ordering, stop word
+// order, JSON formatting of schemas and arguments, or surrounding
+// whitespace in message content hash the same. Callbacks are not part of
+// the hash.
+func (r *Request) Hash() string {
+	data, err := r.Canonical()
+	if err != nil {
+		// only reachable with unmarshalable values in provider options
+		data = []byte(fmt.Sprintf("%#v", r))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Canonical returns the normalized JSON encoding Hash is computed over.
+func (r *Request) Canonical() ([]byte, error) {
+	c := *r
+	c.Provider = strings.ToLower(strings.TrimSpace(c.Provider))
+	c.Model = strings.TrimSpace(c.Model)
+
+	c.Messages = make([]Message, len(r.Messages))
+	for i, m := range r.Messages {
+		m.Content = canonicalText(m.Content)
+		if len(m.ToolCalls) > 0 {
+			calls := make([]ToolCall, len(m.ToolCalls))
+			for j, tc := range m.ToolCalls {
+				tc.Function.Arguments = string(canonicalJSON([]byte(tc.Function.Arguments)))
+				tc.Metadata = nil
+				calls[j] = tc
+			}
+			m.ToolCalls = calls
+		}
+		c.Messages[i] = m
+	}
+
+	if len(r.Tools) > 0 {
+		c.Tools = make([]Tool, len(r.Tools))
+		for i, t := range r.Tools {
+			t.Function.Description = canonicalText(t.Function.Description)
+			t.Function.ParametersJSONSchema = canonicalJSON(t.Function.ParametersJSONSchema)
+			c.Tools[i] = t
+		}
+	}
+
+	if len(r.Options.Stop) > 0 {
+		c.Options.Stop = append([]string(nil), r.Options.Stop...)
+		sort.Strings(c.Options.Stop)
+	}
+	// encoding/json writes map keys in sorted order, so provider option
+	// maps need no further work.
+	return json.Marshal(&c)
+}
+
+// canonicalText normalizes line endings and trims surrounding whitespace.
+func canonicalText(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+}
+
+// canonicalJSON re-encodes data with sorted keys and no insignificant
+// whitespace. Invalid JSON is returned trimmed but otherwise unchanged.
+func canonicalJSON(data []byte) []byte {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return data
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+	return out
+}
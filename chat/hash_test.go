@@ -0,0 +1,42 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/lyricat/goutils/structs"
+)
+
+func TestRequestHash(t *testing.T) {
+	build := func(opts ...Option) *Request {
+		req, err := BuildRequest(opts...)
+		if err != nil {
+			t.Fatalf("build: %v", err)
+		}
+		return req
+	}
+	a := build(
+		WithModel("gpt-4o"),
+		WithMessages(User("  hello\r\nworld ")),
+		WithStopWords("b", "a"),
+		WithOpenAIOptions(structs.JSONMap{"x": 1, "y": map[string]any{"b": 2, "a": 1}}),
+		WithTools([]Tool{{Type: "function", Function: ToolFunction{Name: "f", ParametersJSONSchema: []byte(`{ "type": "object", "properties": {} }`)}}}),
+		WithOnToken(func(string) {}),
+	)
+	b := build(
+		WithModel("gpt-4o "),
+		WithMessages(User("hello\nworld")),
+		WithStopWords("a", "b"),
+		WithOpenAIOptions(structs.JSONMap{"y": map[string]any{"a": 1, "b": 2}, "x": 1}),
+		WithTools([]Tool{{Type: "function", Function: ToolFunction{Name: "f", ParametersJSONSchema: []byte(`{"properties":{},"type":"object"}`)}}}),
+	)
+	if a.Hash() != b.Hash() {
+		t.Fatalf("equivalent requests hash differently")
+	}
+	c := build(WithModel("gpt-4o"), WithMessages(User("hello world")))
+	if a.Hash() == c.Hash() {
+		t.Fatalf("different requests hash the same")
+	}
+	if a.Messages[0].Content != "  hello\r\nworld " {
+		t.Fatalf("Hash modified the request")
+	}
+}
@@ -77,6 +77,12 @@ func toChatOptions(req openai.ChatCompletionNewParams) ([]chat.Option, error) {
 	if req.User.Valid() {
 		opts = append(opts, chat.WithUser(req.User.Value))
 	}
+	if req.Store.Valid() && !req.Store.Value {
+		opts = append(opts, chat.WithNoStore())
+	}
+	if req.ParallelToolCalls.Valid() {
+		opts = append(opts, chat.WithParallelToolCalls(req.ParallelToolCalls.Value))
+	}
 
 	if len(req.Tools) > 0 {
 		tools, err := toTools(req.Tools)
@@ -114,11 +120,11 @@ func toChatMessage(m openai.ChatCompletionMessageParamUnion) (chat.Message, erro
 		}
 		return chat.Message{Role: chat.RoleSystem, Content: content, Name: m.OfSystem.Name.Or("")}, nil
 	case m.OfUser != nil:
-		content, err := readTextFromUser(m.OfUser.Content)
+		content, images, err := readUserContent(m.OfUser.Content)
 		if err != nil {
 			return chat.Message{}, err
 		}
-		return chat.Message{Role: chat.RoleUser, Content: content, Name: m.OfUser.Name.Or("")}, nil
+		return chat.Message{Role: chat.RoleUser, Content: content, Name: m.OfUser.Name.Or(""), Images: images}, nil
 	case m.OfAssistant != nil:
 		content, err := readTextFromAssistant(m.OfAssistant.Content)
 		if err != nil {
@@ -182,26 +188,30 @@ func readTextFromDeveloper(content openai.ChatCompletionDeveloperMessageParamCon
 	return strings.Join(parts, "\n"), nil
 }
 
-func readTextFromUser(content openai.ChatCompletionUserMessageParamContentUnion) (string, error) {
+func readUserContent(content openai.ChatCompletionUserMessageParamContentUnion) (string, []chat.Image, error) {
 	if content.OfString.Valid() {
-		return content.OfString.Value, nil
+		return content.OfString.Value, nil, nil
 	}
 	if len(content.OfArrayOfContentParts) == 0 {
-		return "", nil
+		return "", nil, nil
 	}
 	parts := make([]string, 0, len(content.OfArrayOfContentParts))
+	var images []chat.Image
 	for _, part := range content.OfArrayOfContentParts {
-		if part.OfText != nil {
+		switch {
+		case part.OfText != nil:
 			text := strings.TrimSpace(part.OfText.Text)
 			if text != "" {
 				parts = append(parts, text)
 			}
+		case part.OfImageURL != nil:
+			images = append(images, chat.Image{URL: part.OfImageURL.ImageURL.URL, Detail: part.OfImageURL.ImageURL.Detail})
 		}
 	}
-	if len(parts) == 0 {
-		return "", fmt.Errorf("unsupported user content parts")
+	if len(parts) == 0 && len(images) == 0 {
+		return "", nil, fmt.Errorf("unsupported user content parts")
 	}
-	return strings.Join(parts, "\n"), nil
+	return strings.Join(parts, "\n"), images, nil
 }
 
 func readTextFromAssistant(content openai.ChatCompletionAssistantMessageParamContentUnion) (string, error) {
@@ -363,6 +373,9 @@ func finishReason(result *chat.Result) string {
 	if result != nil && len(result.ToolCalls) > 0 {
 		return "tool_calls"
 	}
+	if result != nil && result.FinishReason != "" {
+		return result.FinishReason
+	}
 	return "stop"
 }
 
@@ -380,9 +393,6 @@ func toOpenAIOptions(req openai.ChatCompletionNewParams) structs.JSONMap {
 	if req.TopLogprobs.Valid() {
 		opts["top_logprobs"] = req.TopLogprobs.Value
 	}
-	if req.ParallelToolCalls.Valid() {
-		opts["parallel_tool_calls"] = req.ParallelToolCalls.Value
-	}
 	if req.Store.Valid() {
 		opts["store"] = req.Store.Value
 	}
@@ -21,6 +21,7 @@ func TestToChatOptions(t *testing.T) {
 		PresencePenalty:  openai.Float(0.1),
 		FrequencyPenalty: openai.Float(0.2),
 		User:             openai.String("u1"),
+		Store:            openai.Bool(false),
 		Tools: []openai.ChatCompletionToolUnionParam{
 			openai.ChatCompletionFunctionTool(shared.FunctionDefinitionParam{
 				Name:        "get_weather",
@@ -56,4 +57,7 @@ func TestToChatOptions(t *testing.T) {
 	if len(chatReq.Tools) != 1 {
 		t.Fatalf("tools mismatch")
 	}
+	if !chatReq.Options.NoStore {
+		t.Fatalf("store=false not mapped to NoStore")
+	}
 }
@@ -0,0 +1,137 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PostProcessor rewrites the final text of a reply. arg is the part of the
+// requested name after a colon, as in "max_sentences:2", or "" without one.
+type PostProcessor func(text, arg string) (string, error)
+
+// Names of the built-in post-processors.
+const (
+	PostStripFences         = "strip_fences"
+	PostNormalizeWhitespace = "normalize_whitespace"
+	PostPlainText           = "plain_text"
+	// PostMaxSentences takes the number of sentences to keep as argument,
+	// e.g. "max_sentences:3".
+	PostMaxSentences = "max_sentences"
+)
+
+// BuiltinPostProcessors are available on every client under their names.
+var BuiltinPostProcessors = map[string]PostProcessor{
+	PostStripFences: func(text, _ string) (string, error) {
+		return StripCodeFences(text), nil
+	},
+	PostNormalizeWhitespace: func(text, _ string) (string, error) {
+		return NormalizeWhitespace(text), nil
+	},
+	PostPlainText: func(text, _ string) (string, error) {
+		return PlainText(text), nil
+	},
+	PostSanitize: func(text, _ string) (string, error) {
+		return SanitizeMarkup(text), nil
+	},
+	PostMaxSentences: func(text, arg string) (string, error) {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("max_sentences needs a positive count, got %q", arg)
+		}
+		return MaxSentences(text, n), nil
+	},
+}
+
+// StripCodeFences removes the ``` and ~~~ lines of fenced code blocks and
+// keeps their content.
+func StripCodeFences(text string) string {
+	lines := strings.Split(text, "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+var (
+	spaceRun    = regexp.MustCompile(`[ \t\f\v]+`)
+	blankLines  = regexp.MustCompile(`\n{3,}`)
+	headingMark = regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+`)
+	quoteMark   = regexp.MustCompile(`(?m)^[ \t]*>[ \t]?`)
+	bulletMark  = regexp.MustCompile(`(?m)^([ \t]*)[*+][ \t]+`)
+	ruleLine    = regexp.MustCompile(`(?m)^[ \t]*([-*_][ \t]*){3,}$`)
+	imageLink   = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	textLink    = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	strongMark  = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	emphMark    = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:[^*_\n]*?\S)?)[*_]($|[^\w*])`)
+	strikeMark  = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	inlineCode  = regexp.MustCompile("`([^`\n]+)`")
+)
+
+// NormalizeWhitespace collapses runs of spaces and tabs, strips trailing
+// spaces, keeps at most one blank line between paragraphs and trims the
+// text.
+func NormalizeWhitespace(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(spaceRun.ReplaceAllString(line, " "), " ")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// PlainText converts Markdown to plain text: headings, emphasis, inline
+// code, quotes, rules and fences lose their markup, links and images become
+// their text, and "*" or "+" bullets become "-".
+func PlainText(text string) string {
+	text = StripCodeFences(text)
+	text = ruleLine.ReplaceAllString(text, "")
+	text = headingMark.ReplaceAllString(text, "")
+	text = quoteMark.ReplaceAllString(text, "")
+	text = bulletMark.ReplaceAllString(text, "$1- ")
+	text = imageLink.ReplaceAllString(text, "$1")
+	text = textLink.ReplaceAllString(text, "$1")
+	text = inlineCode.ReplaceAllString(text, "$1")
+	text = strongMark.ReplaceAllString(text, "$2")
+	text = strikeMark.ReplaceAllString(text, "$1")
+	text = emphMark.ReplaceAllString(text, "$1$2$3")
+	return NormalizeWhitespace(text)
+}
+
+// MaxSentences keeps the first n sentences of text. A sentence ends with
+// ".", "!" or "?" followed by whitespace, or with a blank line.
+func MaxSentences(text string, n int) string {
+	text = strings.TrimSpace(text)
+	count, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		end := -1
+		switch {
+		case strings.ContainsRune(".!?", rune(text[i])):
+			j := i + 1
+			for j < len(text) && strings.ContainsRune(".!?\"')]", rune(text[j])) {
+				j++
+			}
+			if j == len(text) || text[j] == ' ' || text[j] == '\n' || text[j] == '\t' {
+				end = j
+			}
+			i = j - 1
+		case strings.HasPrefix(text[i:], "\n\n"):
+			end = i
+		}
+		if end < 0 || strings.TrimSpace(text[start:end]) == "" {
+			continue
+		}
+		start = end
+		count++
+		if count == n {
+			return strings.TrimSpace(text[:end])
+		}
+	}
+	return text
+}
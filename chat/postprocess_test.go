@@ -0,0 +1,41 @@
+package chat
+
+import "testing"
+
+func TestPostProcessorFunctions(t *testing.T) {
+	if got := StripCodeFences("```json\n{\"a\":1}\n```"); got != `{"a":1}` {
+		t.Fatalf("unexpected stripped text %q", got)
+	}
+	if got := NormalizeWhitespace("a  \t b  \n\n\n\nc "); got != "a b\n\nc" {
+		t.Fatalf("unexpected normalized text %q", got)
+	}
+	if got := PlainText("# Title\n\n* **bold** and _em_ with `code`\n> quoted [link](https://x.y) ![img](a.png)"); got != "Title\n\n- bold and em with code\nquoted link img" {
+		t.Fatalf("unexpected plain text %q", got)
+	}
+	if got := MaxSentences("One. Two? Three! Four.", 3); got != "One. Two? Three!" {
+		t.Fatalf("unexpected sentences %q", got)
+	}
+	if got := MaxSentences("v1.2 is out. Upgrade now.", 1); got != "v1.2 is out." {
+		t.Fatalf("unexpected sentences %q", got)
+	}
+}
+
+func TestSanitizeMarkup(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"Hi <script>alert(1)</script>there", "Hi there"},
+		{`<a href="javascript:alert(1)" title="t">x</a>`, `<a title="t">x</a>`},
+		{`<a HREF=" jav&#x09;ascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<img src="data:image/png;base64,AAAA" onerror="alert(1)">`, `<img src="data:image/png;base64,AAAA">`},
+		{`<iframe src="https://evil"><p>x</p></iframe><b>ok</b>`, `<b>ok</b>`},
+		{"[click](javascript:alert(1)) and [docs](https://go.dev)", "click) and [docs](https://go.dev)"},
+		{"[x]: javascript:alert(1)\nsee <https://go.dev>", "\nsee <https://go.dev>"},
+		{"<!-- hidden --><title><script>x</script></title>text", "text"},
+		{"```html\n<script>demo()</script>\n```\n<script>bad()</script>", "```html\n<script>demo()</script>\n```\n"},
+		{"a < b and **bold** <em>em</em>", "a < b and **bold** <em>em</em>"},
+	}
+	for _, c := range cases {
+		if got := SanitizeMarkup(c.in); got != c.want {
+			t.Errorf("SanitizeMarkup(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package chat
+
+import "context"
+
+// Provider is implemented by chat backends. Built-in providers are selected
+// by name; custom ones are added with uniai.Client.RegisterProvider.
+// Providers stream through req.Options.OnStream when it is set.
+type Provider interface {
+	Chat(ctx context.Context, req *Request) (*Result, error)
+}
@@ -0,0 +1,142 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// RateLimit is the rate-limit state a provider reported with a response.
+// Counts are -1 when the provider did not report them.
+type RateLimit struct {
+	LimitRequests     int `json:"limit_requests"`
+	RemainingRequests int `json:"remaining_requests"`
+	LimitTokens       int `json:"limit_tokens"`
+	RemainingTokens   int `json:"remaining_tokens"`
+	// ResetRequests and ResetTokens are how long until the budgets refill.
+	ResetRequests time.Duration `json:"reset_requests,omitempty"`
+	ResetTokens   time.Duration `json:"reset_tokens,omitempty"`
+	// RetryAfter is set on rate-limited responses.
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+}
+
+// Exhausted reports whether the request or token budget is used up, and
+// how long until it refills.
+func (r *RateLimit) Exhausted() (time.Duration, bool) {
+	if r == nil {
+		return 0, false
+	}
+	wait, exhausted := r.RetryAfter, r.RetryAfter > 0
+	if r.RemainingRequests == 0 {
+		wait, exhausted = max(wait, r.ResetRequests), true
+	}
+	if r.RemainingTokens == 0 {
+		wait, exhausted = max(wait, r.ResetTokens), true
+	}
+	return wait, exhausted
+}
+
+// ParseRateLimit reads the rate-limit headers of OpenAI-style
+// (x-ratelimit-*) and Anthropic (anthropic-ratelimit-*) responses and
+// Retry-After. It returns nil when h has none of them.
+func ParseRateLimit(h http.Header) *RateLimit {
+	return parseRateLimit(h, time.Now())
+}
+
+func parseRateLimit(h http.Header, now time.Time) *RateLimit {
+	if h == nil {
+		return nil
+	}
+	r := &RateLimit{LimitRequests: -1, RemainingRequests: -1, LimitTokens: -1, RemainingTokens: -1}
+	found := false
+	count := func(dst *int, names ...string) {
+		for _, name := range names {
+			if v, err := strconv.Atoi(strings.TrimSpace(h.Get(name))); err == nil {
+				*dst, found = v, true
+				return
+			}
+		}
+	}
+	reset := func(dst *time.Duration, names ...string) {
+		for _, name := range names {
+			if d, ok := parseReset(h.Get(name), now); ok {
+				*dst, found = d, true
+				return
+			}
+		}
+	}
+	count(&r.LimitRequests, "x-ratelimit-limit-requests", "anthropic-ratelimit-requests-limit")
+	count(&r.RemainingRequests, "x-ratelimit-remaining-requests", "anthropic-ratelimit-requests-remaining")
+	count(&r.LimitTokens, "x-ratelimit-limit-tokens", "anthropic-ratelimit-tokens-limit")
+	count(&r.RemainingTokens, "x-ratelimit-remaining-tokens", "anthropic-ratelimit-tokens-remaining")
+	reset(&r.ResetRequests, "x-ratelimit-reset-requests", "anthropic-ratelimit-requests-reset")
+	reset(&r.ResetTokens, "x-ratelimit-reset-tokens", "anthropic-ratelimit-tokens-reset")
+	if ms, err := strconv.Atoi(strings.TrimSpace(h.Get("retry-after-ms"))); err == nil && ms > 0 {
+		r.RetryAfter, found = time.Duration(ms)*time.Millisecond, true
+	} else {
+		reset(&r.RetryAfter, "retry-after")
+	}
+	if !found {
+		return nil
+	}
+	return r
+}
+
+// parseReset accepts seconds ("20"), Go-style durations as OpenAI sends
+// them ("6m0s", "120ms"), RFC 3339 times as Anthropic sends them, and HTTP
+// dates.
+func parseReset(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d, true
+	}
+	for _, layout := range []string{time.RFC3339, http.TimeFormat} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return max(t.Sub(now), 0), true
+		}
+	}
+	return 0, false
+}
+
+// RateLimitError is returned by providers for a response that carried
+// rate-limit headers, such as a 429.
+type RateLimitError struct {
+	Err       error
+	RateLimit *RateLimit
+}
+
+func (e *RateLimitError) Error() string { return e.Err.Error() }
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+// WrapRateLimit attaches the rate-limit headers of a failed response to
+// err. It returns err unchanged when h has none.
+func WrapRateLimit(err error, h http.Header) error {
+	if rl := ParseRateLimit(h); rl != nil {
+		return &RateLimitError{Err: err, RateLimit: rl}
+	}
+	return err
+}
+
+// RateLimitFromError returns the rate-limit state reported with a failed
+// call: from a RateLimitError, or from the response of an OpenAI SDK error.
+func RateLimitFromError(err error) *RateLimit {
+	var rle *RateLimitError
+	if errors.As(err, &rle) {
+		return rle.RateLimit
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		return ParseRateLimit(apiErr.Response.Header)
+	}
+	return nil
+}
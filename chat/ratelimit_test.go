@@ -0,0 +1,36 @@
+package chat
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	openaiHeaders := http.Header{}
+	openaiHeaders.Set("x-ratelimit-limit-requests", "500")
+	openaiHeaders.Set("x-ratelimit-remaining-requests", "0")
+	openaiHeaders.Set("x-ratelimit-reset-requests", "1m30s")
+	openaiHeaders.Set("x-ratelimit-remaining-tokens", "1200")
+	rl := parseRateLimit(openaiHeaders, now)
+	if rl == nil || rl.LimitRequests != 500 || rl.RemainingRequests != 0 || rl.ResetRequests != 90*time.Second || rl.RemainingTokens != 1200 || rl.LimitTokens != -1 {
+		t.Fatalf("unexpected openai rate limit %+v", rl)
+	}
+	if wait, ok := rl.Exhausted(); !ok || wait != 90*time.Second {
+		t.Fatalf("expected exhausted for 90s, got %v %v", wait, ok)
+	}
+
+	anthropicHeaders := http.Header{}
+	anthropicHeaders.Set("anthropic-ratelimit-tokens-remaining", "10")
+	anthropicHeaders.Set("anthropic-ratelimit-tokens-reset", "2025-01-01T12:00:20Z")
+	anthropicHeaders.Set("retry-after", "5")
+	rl = parseRateLimit(anthropicHeaders, now)
+	if rl == nil || rl.RemainingTokens != 10 || rl.ResetTokens != 20*time.Second || rl.RetryAfter != 5*time.Second {
+		t.Fatalf("unexpected anthropic rate limit %+v", rl)
+	}
+
+	if parseRateLimit(http.Header{"Content-Type": {"application/json"}}, now) != nil {
+		t.Fatal("expected nil without rate-limit headers")
+	}
+}
@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"encoding/json"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// RawOpenAI returns the completion retained in Raw by the OpenAI-compatible
+// providers (openai, openai_custom, deepseek, xai, gemini, azure, vllm).
+// Typed access for the other providers is in their packages, e.g.
+// anthropic.RawResponse.
+func (r *Result) RawOpenAI() (*openai.ChatCompletion, bool) {
+	if r == nil {
+		return nil, false
+	}
+	raw, ok := r.Raw.(*openai.ChatCompletion)
+	return raw, ok
+}
+
+// RawJSON returns Raw encoded as JSON, or nil when no raw response was
+// retained.
+func (r *Result) RawJSON() ([]byte, error) {
+	if r == nil || r.Raw == nil {
+		return nil, nil
+	}
+	return json.Marshal(r.Raw)
+}
+
+// WithLeanResult trims results for high-throughput callers: Raw and
+// Messages are not retained, and OpenAI-compatible providers reuse pooled
+// message buffers across requests.
+func WithLeanResult() Option {
+	return func(r *Request) { r.Options.LeanResult = true }
+}
+
+// WithDropRaw discards provider responses instead of retaining them in
+// Result.Raw, which reduces memory held by long-lived results.
+func WithDropRaw() Option {
+	return func(r *Request) { r.Options.DropRaw = true }
+}
@@ -0,0 +1,220 @@
+package chat
+
+import "strings"
+
+// Reasoning markers recognized in reply text: <think> blocks used by
+// DeepSeek-R1 and Qwen models, and the Harmony channels of gpt-oss models.
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+
+	harmonyChannel  = "<|channel|>"
+	harmonyMessage  = "<|message|>"
+	harmonyStart    = "<|start|>"
+	harmonyEnd      = "<|end|>"
+	harmonyCall     = "<|call|>"
+	harmonyAnalysis = "analysis"
+)
+
+// SplitReasoning separates the chain-of-thought in text from the answer.
+// It removes <think> blocks and Harmony analysis channels, along with
+// Harmony tool calls and the control tokens around the final channel. A reply that only
+// closes a think block, because the chat template opened it in the prompt,
+// is treated as reasoning up to the closing tag.
+func SplitReasoning(text string) (answer, reasoning string) {
+	openAt, closeAt := strings.Index(text, thinkOpen), strings.Index(text, thinkClose)
+	if closeAt >= 0 && (openAt < 0 || openAt > closeAt) {
+		reasoning = text[:closeAt]
+		text = text[closeAt+len(thinkClose):]
+	}
+	var s ReasoningSplitter
+	a, r := s.Write(text)
+	fa, fr := s.Flush()
+	return strings.TrimSpace(a + fa), strings.TrimSpace(reasoning + r + fr)
+}
+
+// ReasoningSplitter separates reasoning from answer text as it streams in.
+// Text that may be the start of a marker is held back until the next Write
+// or Flush. The zero value is ready to use.
+type ReasoningSplitter struct {
+	// Open starts the splitter inside a think block, for models whose chat
+	// template opens it in the prompt so that the reply only closes it. A
+	// <think> the model repeats at the start is dropped. See PreopensThink.
+	Open bool
+
+	buf     string
+	started bool
+	// leading is set until the first text of a block opened by Open.
+	leading bool
+	// closing is the marker that ends the current reasoning block, or
+	// empty outside one.
+	closing string
+	// discard drops the current block instead of reporting it as
+	// reasoning; set for Harmony tool calls.
+	discard bool
+	// recipient is set when a Harmony role header addressed the next
+	// message to a tool.
+	recipient bool
+}
+
+// Write adds delta and returns the answer and reasoning text that can be
+// released.
+func (s *ReasoningSplitter) Write(delta string) (answer, reasoning string) {
+	s.buf += delta
+	if !s.started {
+		s.started = true
+		if s.Open {
+			s.closing, s.leading = thinkClose, true
+		}
+	}
+	var a, r strings.Builder
+	for s.buf != "" {
+		if s.leading {
+			rest := strings.TrimLeft(s.buf, " \t\r\n")
+			if rest == "" || len(rest) < len(thinkOpen) && strings.HasPrefix(thinkOpen, rest) {
+				break
+			}
+			if strings.HasPrefix(rest, thinkOpen) {
+				s.buf = rest[len(thinkOpen):]
+			}
+			s.leading = false
+			continue
+		}
+		if s.closing != "" {
+			i := strings.Index(s.buf, s.closing)
+			if i < 0 {
+				keep := partialSuffix(s.buf, s.closing)
+				if !s.discard {
+					r.WriteString(s.buf[:len(s.buf)-keep])
+				}
+				s.buf = s.buf[len(s.buf)-keep:]
+				break
+			}
+			if !s.discard {
+				r.WriteString(s.buf[:i])
+			}
+			s.buf = s.buf[i+len(s.closing):]
+			s.closing, s.discard = "", false
+			continue
+		}
+
+		i := indexAny(s.buf, thinkOpen, thinkClose, "<|")
+		if i < 0 {
+			keep := max(partialSuffix(s.buf, thinkOpen), partialSuffix(s.buf, thinkClose), partialSuffix(s.buf, "<|"))
+			a.WriteString(s.buf[:len(s.buf)-keep])
+			s.buf = s.buf[len(s.buf)-keep:]
+			break
+		}
+		a.WriteString(s.buf[:i])
+		s.buf = s.buf[i:]
+		switch {
+		case strings.HasPrefix(s.buf, thinkOpen):
+			s.buf = s.buf[len(thinkOpen):]
+			s.closing = thinkClose
+			continue
+		case strings.HasPrefix(s.buf, thinkClose):
+			s.buf = s.buf[len(thinkClose):]
+			continue
+		}
+		// a Harmony token
+		end := strings.Index(s.buf, "|>")
+		if end < 0 {
+			return a.String(), r.String()
+		}
+		token := s.buf[:end+2]
+		switch token {
+		case harmonyChannel:
+			// <|channel|>name[ to=...]<|message|>
+			msg := strings.Index(s.buf, harmonyMessage)
+			if msg < 0 {
+				return a.String(), r.String()
+			}
+			header := strings.TrimSpace(s.buf[len(harmonyChannel):msg])
+			s.buf = s.buf[msg+len(harmonyMessage):]
+			switch {
+			case s.recipient || strings.Contains(header, "to="):
+				// a tool call, ended by <|call|>
+				s.closing, s.discard = harmonyCall, true
+			case strings.HasPrefix(header, harmonyAnalysis):
+				s.closing = harmonyEnd
+			}
+			s.recipient = false
+		case harmonyStart:
+			// <|start|>role, followed by the next token
+			next := strings.Index(s.buf[len(token):], "<|")
+			if next < 0 {
+				return a.String(), r.String()
+			}
+			s.recipient = strings.Contains(s.buf[len(token):len(token)+next], "to=")
+			s.buf = s.buf[len(token)+next:]
+		default:
+			if isHarmonyToken(token) {
+				s.buf = s.buf[len(token):]
+			} else {
+				a.WriteString(token)
+				s.buf = s.buf[len(token):]
+			}
+		}
+	}
+	return a.String(), r.String()
+}
+
+// Flush releases any held back text.
+func (s *ReasoningSplitter) Flush() (answer, reasoning string) {
+	rest := s.buf
+	s.buf = ""
+	if s.leading {
+		s.leading = false
+		return "", ""
+	}
+	if s.discard {
+		return "", ""
+	}
+	if s.closing != "" {
+		return "", rest
+	}
+	if strings.HasPrefix(rest, harmonyStart) {
+		// a trailing <|start|>role with nothing after it
+		return "", ""
+	}
+	return rest, ""
+}
+
+// PreopensThink reports whether the chat template of model is known to open
+// a <think> block in the prompt, so that replies only close it: DeepSeek-R1,
+// QwQ and the Qwen3 thinking models.
+func PreopensThink(model string) bool {
+	model = strings.ToLower(model)
+	return strings.Contains(model, "deepseek-r1") || strings.Contains(model, "qwq") ||
+		strings.Contains(model, "qwen3") && strings.Contains(model, "thinking")
+}
+
+func isHarmonyToken(token string) bool {
+	switch token {
+	case harmonyEnd, harmonyMessage, harmonyCall, "<|return|>", "<|constrain|>":
+		return true
+	}
+	return false
+}
+
+// indexAny returns the first index in s of any of the markers, or -1.
+func indexAny(s string, markers ...string) int {
+	first := -1
+	for _, m := range markers {
+		if i := strings.Index(s, m); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	return first
+}
+
+// partialSuffix returns the length of the longest suffix of s that is a
+// proper prefix of marker.
+func partialSuffix(s, marker string) int {
+	for n := min(len(s), len(marker)-1); n > 0; n-- {
+		if strings.HasSuffix(s, marker[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitReasoning(t *testing.T) {
+	cases := []struct {
+		text, answer, reasoning string
+	}{
+		{"<think>add them</think>\n4", "4", "add them"},
+		{"add them</think>4", "4", "add them"},
+		{"<|channel|>analysis<|message|>add them<|end|><|start|>assistant<|channel|>final<|message|>4<|return|>", "4", "add them"},
+		{"plain 2 < 3", "plain 2 < 3", ""},
+	}
+	for _, c := range cases {
+		answer, reasoning := SplitReasoning(c.text)
+		if answer != c.answer || reasoning != c.reasoning {
+			t.Errorf("SplitReasoning(%q) = %q, %q", c.text, answer, reasoning)
+		}
+	}
+}
+
+func TestReasoningSplitterChunks(t *testing.T) {
+	text := "<|channel|>analysis<|message|>think<|end|><|start|>assistant<|channel|>final<|message|>done <b>"
+	var s ReasoningSplitter
+	var answer, reasoning string
+	for i := 0; i < len(text); i++ {
+		a, r := s.Write(text[i : i+1])
+		answer, reasoning = answer+a, reasoning+r
+	}
+	a, r := s.Flush()
+	answer, reasoning = answer+a, reasoning+r
+	if answer != "done <b>" || reasoning != "think" {
+		t.Fatalf("got %q, %q", answer, reasoning)
+	}
+}
+
+func TestReasoningSplitterOpen(t *testing.T) {
+	for _, text := range []string{"add them</think>4", "<think>\nadd them</think>4"} {
+		s := ReasoningSplitter{Open: true}
+		var answer, reasoning string
+		for i := 0; i < len(text); i++ {
+			a, r := s.Write(text[i : i+1])
+			answer, reasoning = answer+a, reasoning+r
+		}
+		a, r := s.Flush()
+		answer, reasoning = answer+a, reasoning+r
+		if answer != "4" || strings.TrimSpace(reasoning) != "add them" {
+			t.Errorf("%q: got %q, %q", text, answer, reasoning)
+		}
+	}
+}
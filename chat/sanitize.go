@@ -0,0 +1,202 @@
+package chat
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// PostSanitize names the built-in post-processor that applies
+// SanitizeMarkup.
+const PostSanitize = "sanitize"
+
+// droppedElements are removed with their content. This includes the
+// elements whose content is parsed as raw text, which could otherwise
+// smuggle markup past the tokenizer.
+var droppedElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "noscript": true, "template": true,
+	"svg": true, "math": true, "title": true, "textarea": true, "xmp": true,
+	"plaintext": true, "noembed": true, "noframes": true,
+}
+
+// droppedTags are removed while their content is kept.
+var droppedTags = map[string]bool{
+	"base": true, "meta": true, "link": true, "form": true, "input": true,
+	"button": true, "select": true, "option": true, "html": true, "head": true,
+	"body": true,
+}
+
+// urlAttributes hold URLs that a browser may load or navigate to.
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "xlink:href": true,
+	"background": true, "poster": true, "srcset": true, "data": true, "cite": true,
+}
+
+var (
+	// markdownLink matches the target of inline links and images.
+	markdownLink = regexp.MustCompile(`(!?\[[^\]]*\])\(\s*<?([^)\s>]*)>?((?:\s+"[^"]*")?\s*)\)`)
+	// referenceLink matches link reference definitions.
+	referenceLink = regexp.MustCompile(`(?m)^([ \t]{0,3}\[[^\]]+\]:[ \t]*)<?(\S+?)>?([ \t]+.*)?$`)
+	fenceLine     = regexp.MustCompile("^[ \t]*(```|~~~)")
+)
+
+// SanitizeMarkup makes model-written Markdown or HTML safe to render. It
+// removes script, style, iframe, object and similar elements with their
+// content, form and document-level tags, comments, event handler
+// attributes and style attributes, and drops javascript:, vbscript: and
+// data: URLs from attributes and Markdown links (data: images are kept in
+// img src). Other markup is left as written, and fenced code blocks are not
+// touched, since renderers escape them.
+func SanitizeMarkup(text string) string {
+	var b strings.Builder
+	var prose []string
+	flush := func() {
+		if len(prose) > 0 {
+			b.WriteString(sanitizeProse(strings.Join(prose, "")))
+			prose = prose[:0]
+		}
+	}
+	inFence := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		m := fenceLine.FindStringSubmatch(line)
+		switch {
+		case inFence != "":
+			b.WriteString(line)
+			if m != nil && m[1] == inFence {
+				inFence = ""
+			}
+		case m != nil:
+			flush()
+			inFence = m[1]
+			b.WriteString(line)
+		default:
+			prose = append(prose, line)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+func sanitizeProse(text string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(text))
+	skip := "" // element whose content is being dropped
+	depth := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				// unreadable rest; drop it rather than pass it through
+				return b.String()
+			}
+			break
+		}
+		raw := string(z.Raw())
+		tok := z.Token()
+		if skip != "" {
+			switch {
+			case tt == html.StartTagToken && tok.Data == skip:
+				depth++
+			case tt == html.EndTagToken && tok.Data == skip:
+				depth--
+				if depth == 0 {
+					skip = ""
+				}
+			}
+			continue
+		}
+		switch tt {
+		case html.TextToken:
+			b.WriteString(sanitizeLinks(raw))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name := tok.Data
+			switch {
+			case droppedElements[name]:
+				if tt == html.StartTagToken {
+					skip, depth = name, 1
+				}
+			case strings.Contains(name, ":"):
+				// a Markdown autolink such as <https://go.dev>
+				if !unsafeURL(strings.Trim(raw, "<>"), false) {
+					b.WriteString(raw)
+				}
+			case droppedTags[name]:
+			default:
+				writeTag(&b, tok, raw, tt == html.SelfClosingTagToken)
+			}
+		case html.EndTagToken:
+			if !droppedElements[tok.Data] && !droppedTags[tok.Data] && !strings.Contains(tok.Data, ":") {
+				b.WriteString(raw)
+			}
+		case html.CommentToken, html.DoctypeToken:
+		}
+	}
+	return b.String()
+}
+
+// writeTag writes a start tag without unsafe attributes, as written when
+// it had none.
+func writeTag(b *strings.Builder, tok html.Token, raw string, selfClosing bool) {
+	var attrs strings.Builder
+	dropped := false
+	for _, a := range tok.Attr {
+		key := strings.ToLower(a.Key)
+		if strings.HasPrefix(key, "on") || key == "style" || a.Namespace != "" ||
+			urlAttributes[key] && unsafeURL(a.Val, tok.Data == "img" && key == "src") {
+			dropped = true
+			continue
+		}
+		attrs.WriteString(" " + key + `="` + html.EscapeString(a.Val) + `"`)
+	}
+	if !dropped {
+		b.WriteString(raw)
+		return
+	}
+	b.WriteString("<" + tok.Data + attrs.String())
+	if selfClosing {
+		b.WriteString(" /")
+	}
+	b.WriteString(">")
+}
+
+// sanitizeLinks removes unsafe targets from Markdown links, keeping the
+// link text.
+func sanitizeLinks(text string) string {
+	text = markdownLink.ReplaceAllStringFunc(text, func(m string) string {
+		parts := markdownLink.FindStringSubmatch(m)
+		if !unsafeURL(html.UnescapeString(parts[2]), strings.HasPrefix(parts[1], "!")) {
+			return m
+		}
+		label := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(parts[1], "!"), "["), "]")
+		return label
+	})
+	return referenceLink.ReplaceAllStringFunc(text, func(m string) string {
+		parts := referenceLink.FindStringSubmatch(m)
+		if !unsafeURL(html.UnescapeString(parts[2]), false) {
+			return m
+		}
+		return ""
+	})
+}
+
+// unsafeURL reports whether u uses a scheme that runs script or embeds
+// content. Browsers ignore whitespace and control characters in schemes,
+// so they are removed first.
+func unsafeURL(u string, image bool) bool {
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.ToLower(u))
+	switch {
+	case strings.HasPrefix(u, "javascript:"), strings.HasPrefix(u, "vbscript:"):
+		return true
+	case strings.HasPrefix(u, "data:"):
+		return !image || !strings.HasPrefix(u, "data:image/") || strings.HasPrefix(u, "data:image/svg")
+	}
+	return false
+}
@@ -0,0 +1,385 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ToolCallAccumulator merges ToolCallDelta chunks into complete tool calls.
+// Providers stream tool calls one at a time, so a call is considered complete
+// once a delta for a different index arrives or the stream is flushed.
+type ToolCallAccumulator struct {
+	calls   []ToolCall
+	indexes map[int]int
+	current int
+	open    bool
+}
+
+// Add merges delta into the call with the same index. It returns the previous
+// call when delta starts a new one.
+func (a *ToolCallAccumulator) Add(delta ToolCallDelta) *ToolCall {
+	if a.indexes == nil {
+		a.indexes = map[int]int{}
+	}
+	var finished *ToolCall
+	if a.open && delta.Index != a.current {
+		finished = a.Flush()
+	}
+	pos, ok := a.indexes[delta.Index]
+	if !ok {
+		pos = len(a.calls)
+		a.indexes[delta.Index] = pos
+		a.calls = append(a.calls, ToolCall{Type: "function"})
+	}
+	call := &a.calls[pos]
+	if delta.ID != "" {
+		call.ID = delta.ID
+	}
+	if delta.Name != "" {
+		call.Function.Name += delta.Name
+	}
+	call.Function.Arguments += delta.ArgsChunk
+	a.current = delta.Index
+	a.open = true
+	return finished
+}
+
+// Flush completes the call currently being assembled, if any, and returns it.
+func (a *ToolCallAccumulator) Flush() *ToolCall {
+	if !a.open {
+		return nil
+	}
+	a.open = false
+	pos, ok := a.indexes[a.current]
+	if !ok {
+		return nil
+	}
+	call := a.calls[pos]
+	if call.Function.Arguments == "" {
+		call.Function.Arguments = "{}"
+		a.calls[pos].Function.Arguments = "{}"
+	}
+	return &call
+}
+
+// Calls returns all tool calls seen so far, in the order they started.
+func (a *ToolCallAccumulator) Calls() []ToolCall {
+	if len(a.calls) == 0 {
+		return nil
+	}
+	return append([]ToolCall{}, a.calls...)
+}
+
+// DefaultTeeBuffer is the number of events queued per StreamTee consumer
+// before the consumer is considered lagging and detached.
+const DefaultTeeBuffer = 1024
+
+// ErrStreamConsumerLagging is reported by StreamTee.Wait for consumers that
+// fell more than the buffer size behind the provider and were detached.
+var ErrStreamConsumerLagging = errors.New("stream consumer lagging; detached")
+
+// StreamTee fans a single stream out to several consumers. Each consumer
+// runs in its own goroutine behind a bounded queue, so a slow consumer never
+// stalls the provider read loop; a consumer whose queue overflows, or that
+// returns an error, is detached while the others keep receiving events.
+// OnStream, Close and Wait are safe for concurrent use.
+type StreamTee struct {
+	consumers []*teeConsumer
+	wg        sync.WaitGroup
+
+	// mu guards closed and the sends on the consumer queues, so no event
+	// is sent on a closed queue.
+	mu     sync.Mutex
+	closed bool
+}
+
+type teeConsumer struct {
+	fn     OnStreamFunc
+	events chan StreamEvent
+	failed atomic.Bool
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *teeConsumer) detach(err error) {
+	if c.failed.CompareAndSwap(false, true) {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+	}
+}
+
+// NewStreamTee starts a tee with DefaultTeeBuffer queued events per consumer.
+func NewStreamTee(consumers ...OnStreamFunc) *StreamTee {
+	return NewStreamTeeWithBuffer(DefaultTeeBuffer, consumers...)
+}
+
+// NewStreamTeeWithBuffer starts a tee with the given per-consumer queue size.
+func NewStreamTeeWithBuffer(buffer int, consumers ...OnStreamFunc) *StreamTee {
+	if buffer <= 0 {
+		buffer = DefaultTeeBuffer
+	}
+	t := &StreamTee{}
+	for _, fn := range consumers {
+		if fn == nil {
+			continue
+		}
+		c := &teeConsumer{fn: fn, events: make(chan StreamEvent, buffer)}
+		t.consumers = append(t.consumers, c)
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			for ev := range c.events {
+				if c.failed.Load() {
+					continue
+				}
+				if err := c.fn(ev); err != nil {
+					c.detach(err)
+				}
+			}
+		}()
+	}
+	return t
+}
+
+// OnStream queues ev for every attached consumer without blocking. It can be
+// passed directly to WithOnStream. The tee closes after the Done event.
+func (t *StreamTee) OnStream(ev StreamEvent) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	for _, c := range t.consumers {
+		if c.failed.Load() {
+			continue
+		}
+		select {
+		case c.events <- ev:
+		default:
+			c.detach(ErrStreamConsumerLagging)
+		}
+	}
+	if ev.Done {
+		t.close()
+	}
+	return nil
+}
+
+// Close stops accepting events. Queued events are still delivered.
+func (t *StreamTee) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.close()
+}
+
+// close is Close with t.mu held.
+func (t *StreamTee) close() {
+	if t.closed {
+		return
+	}
+	t.closed = true
+	for _, c := range t.consumers {
+		close(c.events)
+	}
+}
+
+// Wait closes the tee, waits for all consumers to drain their queues and
+// returns the errors of detached consumers, if any.
+func (t *StreamTee) Wait() error {
+	t.Close()
+	t.wg.Wait()
+	var errs []error
+	for _, c := range t.consumers {
+		c.mu.Lock()
+		if c.err != nil {
+			errs = append(errs, c.err)
+		}
+		c.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// RecordedEvent is a stream event with its offset from the start of the stream.
+type RecordedEvent struct {
+	Offset time.Duration `json:"offset"`
+	Event  StreamEvent   `json:"event"`
+}
+
+// StreamRecorder captures the full event sequence of a stream for later replay.
+type StreamRecorder struct {
+	mu     sync.Mutex
+	start  time.Time
+	events []RecordedEvent
+}
+
+// OnStream records ev. It can be passed directly to WithOnStream or to a StreamTee.
+func (r *StreamRecorder) OnStream(ev StreamEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if r.start.IsZero() {
+		r.start = now
+	}
+	r.events = append(r.events, RecordedEvent{Offset: now.Sub(r.start), Event: ev})
+	return nil
+}
+
+// Events returns a copy of the recorded events.
+func (r *StreamRecorder) Events() []RecordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedEvent{}, r.events...)
+}
+
+// ReplayStream delivers recorded events to fn in order. With realtime set the
+// original inter-event timing is reproduced; otherwise events are delivered
+// back to back.
+func ReplayStream(ctx context.Context, events []RecordedEvent, fn OnStreamFunc, realtime bool) error {
+	start := time.Now()
+	for _, rec := range events {
+		if realtime {
+			if wait := rec.Offset - time.Since(start); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(rec.Event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Boundaries for StreamPacing.Coalesce.
+const (
+	CoalesceWord     = "word"
+	CoalesceSentence = "sentence"
+)
+
+// StreamPacing configures PaceStream.
+type StreamPacing struct {
+	// MaxTokensPerSecond caps the rate at which text is forwarded. Tokens
+	// are approximated as words. Zero means no cap.
+	MaxTokensPerSecond float64 `json:"max_tokens_per_second,omitempty"`
+	// Coalesce holds text back until a word (CoalesceWord) or sentence
+	// (CoalesceSentence) boundary. Empty forwards deltas as they arrive.
+	Coalesce string `json:"coalesce,omitempty"`
+}
+
+// PaceStream returns an OnStreamFunc that forwards events to next with their
+// text smoothed according to pacing. Held back text is released before any
+// event that carries more than text, so event order is kept. Pacing waits
+// in the calling goroutine and stops the stream with ctx's error when ctx is
+// canceled.
+func PaceStream(ctx context.Context, pacing StreamPacing, next OnStreamFunc) OnStreamFunc {
+	p := &streamPacer{ctx: ctx, cfg: pacing, next: next}
+	return p.onStream
+}
+
+type streamPacer struct {
+	ctx     context.Context
+	cfg     StreamPacing
+	next    OnStreamFunc
+	pending string
+	nextAt  time.Time
+}
+
+func (p *streamPacer) onStream(ev StreamEvent) error {
+	p.pending += ev.Delta
+	rest := ev
+	rest.Delta = ""
+	ready := len(p.pending)
+	if rest == (StreamEvent{}) {
+		ready = coalesceCut(p.pending, p.cfg.Coalesce)
+	}
+	text := p.pending[:ready]
+	p.pending = p.pending[ready:]
+	if err := p.emit(text); err != nil {
+		return err
+	}
+	if rest == (StreamEvent{}) {
+		return nil
+	}
+	return p.next(rest)
+}
+
+// emit forwards text, one word at a time when the rate is capped.
+func (p *streamPacer) emit(text string) error {
+	if text == "" {
+		return nil
+	}
+	if p.cfg.MaxTokensPerSecond <= 0 {
+		return p.next(StreamEvent{Delta: text})
+	}
+	interval := time.Duration(float64(time.Second) / p.cfg.MaxTokensPerSecond)
+	for _, word := range splitWords(text) {
+		if wait := time.Until(p.nextAt); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-p.ctx.Done():
+				timer.Stop()
+				return p.ctx.Err()
+			case <-timer.C:
+			}
+		}
+		p.nextAt = time.Now().Add(interval)
+		if err := p.next(StreamEvent{Delta: word}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// coalesceCut returns how much of text can be released under the coalesce
+// mode: everything, or up to the last word or sentence boundary.
+func coalesceCut(text, mode string) int {
+	switch mode {
+	case CoalesceWord:
+		return strings.LastIndexAny(text, " \t\n") + 1
+	case CoalesceSentence:
+		for i := len(text) - 1; i >= 0; i-- {
+			switch text[i] {
+			case '\n':
+				return i + 1
+			case ' ', '\t':
+				if i > 0 && strings.ContainsRune(".!?", rune(text[i-1])) {
+					return i + 1
+				}
+			}
+		}
+		return 0
+	}
+	return len(text)
+}
+
+// splitWords splits text into words, each keeping the whitespace after it.
+func splitWords(text string) []string {
+	var words []string
+	for text != "" {
+		i := strings.IndexAny(text, " \t\n")
+		if i < 0 {
+			return append(words, text)
+		}
+		end := i
+		for end < len(text) && strings.ContainsRune(" \t\n", rune(text[end])) {
+			end++
+		}
+		words = append(words, text[:end])
+		text = text[end:]
+	}
+	return words
+}
@@ -0,0 +1,139 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestToolCallAccumulator(t *testing.T) {
+	var acc ToolCallAccumulator
+	if done := acc.Add(ToolCallDelta{Index: 0, ID: "call_1", Name: "a", ArgsChunk: `{"x":`}); done != nil {
+		t.Fatalf("unexpected completed call: %+v", done)
+	}
+	if done := acc.Add(ToolCallDelta{Index: 0, ArgsChunk: `1}`}); done != nil {
+		t.Fatalf("unexpected completed call: %+v", done)
+	}
+	done := acc.Add(ToolCallDelta{Index: 1, ID: "call_2", Name: "b"})
+	if done == nil || done.ID != "call_1" || done.Function.Arguments != `{"x":1}` {
+		t.Fatalf("expected call_1 to complete, got %+v", done)
+	}
+	done = acc.Flush()
+	if done == nil || done.ID != "call_2" || done.Function.Arguments != "{}" {
+		t.Fatalf("expected call_2 to complete on flush, got %+v", done)
+	}
+	if acc.Flush() != nil {
+		t.Fatalf("expected nothing left to flush")
+	}
+	calls := acc.Calls()
+	if len(calls) != 2 || calls[0].Function.Name != "a" || calls[1].Function.Name != "b" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+}
+
+func TestStreamTeeAndReplay(t *testing.T) {
+	var rec StreamRecorder
+	var text string
+	tee := NewStreamTee(rec.OnStream, func(ev StreamEvent) error {
+		text += ev.Delta
+		return nil
+	})
+	for _, ev := range []StreamEvent{{Delta: "a"}, {Delta: "b"}, {Delta: "c"}, {Done: true}} {
+		if err := tee.OnStream(ev); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := tee.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "abc" {
+		t.Fatalf("unexpected text: %q", text)
+	}
+
+	events := rec.Events()
+	if len(events) != 4 || !events[3].Event.Done {
+		t.Fatalf("unexpected recording: %+v", events)
+	}
+	var replayed string
+	err := ReplayStream(context.Background(), events, func(ev StreamEvent) error {
+		replayed += ev.Delta
+		return nil
+	}, false)
+	if err != nil || replayed != "abc" {
+		t.Fatalf("unexpected replay: %q (%v)", replayed, err)
+	}
+}
+
+func TestStreamTeeDetachesSlowConsumer(t *testing.T) {
+	block := make(chan struct{})
+	tee := NewStreamTeeWithBuffer(1, func(ev StreamEvent) error {
+		<-block
+		return nil
+	})
+	for _, d := range []string{"a", "b", "c"} {
+		if err := tee.OnStream(StreamEvent{Delta: d}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	close(block)
+	if err := tee.Wait(); !errors.Is(err, ErrStreamConsumerLagging) {
+		t.Fatalf("expected lagging consumer error, got %v", err)
+	}
+}
+
+func TestStreamTeeConcurrentClose(t *testing.T) {
+	tee := NewStreamTee(func(ev StreamEvent) error { return nil })
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				tee.OnStream(StreamEvent{Delta: "x"})
+			}
+		}()
+	}
+	tee.Close()
+	wg.Wait()
+	if err := tee.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPaceStream(t *testing.T) {
+	var got []string
+	on := PaceStream(context.Background(), StreamPacing{Coalesce: CoalesceSentence}, func(ev StreamEvent) error {
+		if ev.Delta != "" {
+			got = append(got, ev.Delta)
+		}
+		return nil
+	})
+	for _, d := range []string{"Hel", "lo there. How", " are", " you? Fi", "ne"} {
+		if err := on(StreamEvent{Delta: d}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := on(StreamEvent{Done: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Hello there. ", "How are you? ", "Fine"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	got = nil
+	start := time.Now()
+	on = PaceStream(context.Background(), StreamPacing{MaxTokensPerSecond: 100}, func(ev StreamEvent) error {
+		got = append(got, ev.Delta)
+		return nil
+	})
+	if err := on(StreamEvent{Delta: "one two three four five"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 || time.Since(start) < 40*time.Millisecond {
+		t.Fatalf("expected 5 paced words, got %q in %s", got, time.Since(start))
+	}
+}
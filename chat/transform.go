@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StreamTransformer rewrites a stream on its way from the provider to the
+// consumer. It wraps next the way HTTP middleware wraps a handler, and may
+// change, hold back, drop or add events before passing them on.
+// Transformers only affect the stream, not the final Result.
+type StreamTransformer func(next OnStreamFunc) OnStreamFunc
+
+// ChainStream wraps fn with the transformers. The first transformer sees
+// the provider's events first.
+func ChainStream(fn OnStreamFunc, transformers ...StreamTransformer) OnStreamFunc {
+	for i := len(transformers) - 1; i >= 0; i-- {
+		if transformers[i] != nil {
+			fn = transformers[i](fn)
+		}
+	}
+	return fn
+}
+
+// TransformText returns a StreamTransformer that rewrites the text of a
+// stream with fn. fn gets all text not yet released and returns the
+// rewritten text to release and how many bytes of the input it consumed;
+// the rest is held until more text arrives. Before any event that carries
+// more than text, flush is true and fn must consume everything.
+func TransformText(fn func(text string, flush bool) (out string, consumed int)) StreamTransformer {
+	return func(next OnStreamFunc) OnStreamFunc {
+		pending := ""
+		return func(ev StreamEvent) error {
+			pending += ev.Delta
+			rest := ev
+			rest.Delta = ""
+			flush := rest != (StreamEvent{})
+			out, n := fn(pending, flush)
+			if flush {
+				n = len(pending)
+			}
+			pending = pending[n:]
+			if out != "" {
+				if err := next(StreamEvent{Delta: out}); err != nil {
+					return err
+				}
+			}
+			if !flush {
+				return nil
+			}
+			return next(rest)
+		}
+	}
+}
+
+// CoalesceText holds deltas back until a word (CoalesceWord) or sentence
+// (CoalesceSentence) boundary.
+func CoalesceText(mode string) StreamTransformer {
+	return TransformText(func(text string, flush bool) (string, int) {
+		n := len(text)
+		if !flush {
+			n = coalesceCut(text, mode)
+		}
+		return text[:n], n
+	})
+}
+
+// MaskWords replaces the given words, matched case-insensitively as whole
+// words, with mask. An empty mask masks each letter with "*". A word that
+// may still be growing is held until the next delta.
+func MaskWords(words []string, mask string) StreamTransformer {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return func(next OnStreamFunc) OnStreamFunc { return next }
+	}
+	re := regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	replace := func(m string) string {
+		if mask != "" {
+			return mask
+		}
+		return strings.Repeat("*", utf8.RuneCountInString(m))
+	}
+	return TransformText(func(text string, flush bool) (string, int) {
+		n := len(text)
+		if !flush {
+			// hold back a trailing word that may continue
+			n = strings.LastIndexFunc(text, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			}) + 1
+		}
+		return re.ReplaceAllStringFunc(text[:n], replace), n
+	})
+}
+
+// CutAtStop ends the text of a stream at the first of the stop sequences.
+// The stop sequence and all text after it are dropped; other events, such
+// as the final Done event, still pass.
+func CutAtStop(stops ...string) StreamTransformer {
+	return func(next OnStreamFunc) OnStreamFunc {
+		stopped := false
+		return TransformText(func(text string, flush bool) (string, int) {
+			if stopped {
+				return "", len(text)
+			}
+			cut, hold := -1, 0
+			for _, s := range stops {
+				if s == "" {
+					continue
+				}
+				if i := strings.Index(text, s); i >= 0 && (cut < 0 || i < cut) {
+					cut = i
+				}
+				hold = max(hold, partialSuffix(text, s))
+			}
+			if cut >= 0 {
+				stopped = true
+				return text[:cut], len(text)
+			}
+			if flush {
+				hold = 0
+			}
+			return text[:len(text)-hold], len(text) - hold
+		})(next)
+	}
+}
@@ -0,0 +1,28 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChainStream(t *testing.T) {
+	var text strings.Builder
+	done := false
+	on := ChainStream(func(ev StreamEvent) error {
+		text.WriteString(ev.Delta)
+		done = done || ev.Done
+		return nil
+	}, MaskWords([]string{"darn"}, ""), CutAtStop("END"))
+
+	for _, d := range []string{"Well da", "rn it, darned", " thing. E", "ND and more"} {
+		if err := on(StreamEvent{Delta: d}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := on(StreamEvent{Done: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := text.String(); got != "Well **** it, darned thing. " || !done {
+		t.Fatalf("unexpected stream %q (done %v)", got, done)
+	}
+}
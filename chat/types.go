@@ -1,7 +1,11 @@
 package chat
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/lyricat/goutils/structs"
 )
@@ -19,12 +23,96 @@ type Message struct {
 	Name       string     `json:"name,omitempty"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Refusal is set on assistant messages when the model declined to
+	// answer and the provider reports the refusal separately from Content.
+	Refusal string `json:"refusal,omitempty"`
+	// Images are attached to user messages. Providers that take no images
+	// fail the request rather than dropping them.
+	Images []Image `json:"images,omitempty"`
+	// Videos are attached to user messages. Only gemini takes them; the
+	// other providers fail the request.
+	Videos []Video `json:"videos,omitempty"`
+}
+
+// Image is a picture attached to a user message. URL is an http(s) URL or
+// a data URL carrying the image itself; see ImageData.
+type Image struct {
+	URL string `json:"url"`
+	// Detail is the resolution hint of OpenAI-compatible providers: "low",
+	// "high" or "auto". Other providers ignore it.
+	Detail string `json:"detail,omitempty"`
+}
+
+// ImageData returns an Image carrying data inline as a data URL.
+func ImageData(mediaType string, data []byte) Image {
+	return Image{URL: "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)}
+}
+
+// Data returns the media type and base64 content of an image given as a
+// data URL.
+func (i Image) Data() (mediaType, data string, ok bool) {
+	rest, ok := strings.CutPrefix(i.URL, "data:")
+	if !ok {
+		return "", "", false
+	}
+	header, data, ok := strings.Cut(rest, ",")
+	mediaType, base64Encoded := strings.CutSuffix(header, ";base64")
+	if !ok || !base64Encoded {
+		return "", "", false
+	}
+	return mediaType, data, true
+}
+
+// Video is a video attached to a user message. URL is a YouTube URL, a
+// file URI from the Gemini Files API, or a data URL carrying the video
+// itself; see VideoData.
+type Video struct {
+	URL string `json:"url"`
+	// MIMEType is the media type of a file URI, such as "video/mp4".
+	// YouTube and data URLs leave it empty.
+	MIMEType string `json:"mime_type,omitempty"`
+	// StartOffset and EndOffset clip the video; zero means its start and
+	// its end.
+	StartOffset time.Duration `json:"start_offset,omitempty"`
+	EndOffset   time.Duration `json:"end_offset,omitempty"`
+	// FPS is the number of frames sampled per second; zero keeps the
+	// provider's default of one.
+	FPS float64 `json:"fps,omitempty"`
+}
+
+// VideoData returns a Video carrying data inline as a data URL. Inline
+// videos must keep the request under 20 MB; upload larger ones.
+func VideoData(mediaType string, data []byte) Video {
+	return Video{URL: ImageData(mediaType, data).URL}
+}
+
+// Data returns the media type and base64 content of a video given as a
+// data URL.
+func (v Video) Data() (mediaType, data string, ok bool) {
+	return Image{URL: v.URL}.Data()
 }
 
 type ToolCall struct {
 	ID       string           `json:"id,omitempty"`
 	Type     string           `json:"type,omitempty"`
 	Function ToolCallFunction `json:"function,omitempty"`
+	// Metadata holds annotations added by uniai rather than the provider,
+	// such as MetadataConfidence on emulated calls. It is never sent to a
+	// provider or written to fine-tuning records.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// Keys of ToolCall.Metadata set by tool emulation when the model reports
+// them; see WithToolsEmulationConfidence.
+const (
+	MetadataConfidence = "confidence" // float64 in [0, 1]
+	MetadataReasoning  = "reasoning"  // string
+)
+
+// Confidence returns the MetadataConfidence of the call, if any.
+func (c ToolCall) Confidence() (float64, bool) {
+	v, ok := c.Metadata[MetadataConfidence].(float64)
+	return v, ok
 }
 
 type ToolCallFunction struct {
@@ -42,6 +130,16 @@ type ToolFunction struct {
 	Description          string `json:"description,omitempty"`
 	ParametersJSONSchema []byte `json:"parameters,omitempty"`
 	Strict               *bool  `json:"strict,omitempty"`
+	// Examples are rendered into the tool emulation prompt as few-shot
+	// samples. They are not sent to providers with native tool calling.
+	Examples []ToolExample `json:"examples,omitempty"`
+}
+
+// ToolExample is a sample invocation of a tool.
+type ToolExample struct {
+	// Request is the user message the call answers; optional.
+	Request   string          `json:"request,omitempty"`
+	Arguments json.RawMessage `json:"arguments"`
 }
 
 type ToolChoice struct {
@@ -74,14 +172,248 @@ type Options struct {
 	PresencePenalty    *float64           `json:"presence_penalty,omitempty"`
 	FrequencyPenalty   *float64           `json:"frequency_penalty,omitempty"`
 	User               *string            `json:"user,omitempty"`
+	LogitBias          map[string]float64 `json:"logit_bias,omitempty"`
+	Grammar            *Grammar           `json:"grammar,omitempty"`
 	OpenAI             structs.JSONMap    `json:"openai_options,omitempty"`
 	Azure              structs.JSONMap    `json:"azure_options,omitempty"`
 	Anthropic          structs.JSONMap    `json:"anthropic_options,omitempty"`
 	Bedrock            structs.JSONMap    `json:"bedrock_options,omitempty"`
 	Susanoo            structs.JSONMap    `json:"susanoo_options,omitempty"`
+	VLLM               structs.JSONMap    `json:"vllm_options,omitempty"`
 	ToolsEmulationMode ToolsEmulationMode `json:"tools_emulation_mode,omitempty"`
+	AutoContinue       *AutoContinue      `json:"auto_continue,omitempty"`
+	ContextRecovery    *ContextRecovery   `json:"context_recovery,omitempty"`
+	ParamNormalization ParamNormalization `json:"param_normalization,omitempty"`
+	DropRaw            bool               `json:"drop_raw,omitempty"`
+	LeanResult         bool               `json:"lean_result,omitempty"`
 	OnStream           OnStreamFunc       `json:"-"`
+	OnToken            OnTokenFunc        `json:"-"`
+	OnEvent            OnEventFunc        `json:"-"`
 	DebugFn            DebugFn            `json:"-"`
+
+	// ToolsEmulationConfidence asks the emulated model for a confidence and
+	// a short reasoning with each call.
+	ToolsEmulationConfidence bool `json:"tools_emulation_confidence,omitempty"`
+	// Requirements are compliance tags, such as TagEU or TagHIPAA, that the
+	// provider serving the request must carry.
+	Requirements []string `json:"requirements,omitempty"`
+	// ProviderTags adds compliance tags to those of the client's
+	// Config.ProviderTags, keyed the same way, for checking Requirements.
+	// Routers pass their Tags this way.
+	ProviderTags map[string][]string `json:"-"`
+	// NoStore asks for zero data retention: providers with a per-request
+	// retention control are told not to store the exchange, and debug
+	// logging of request and response bodies is skipped. An explicit
+	// DebugFn still receives them.
+	NoStore bool `json:"no_store,omitempty"`
+	// Prefill is the start of the assistant reply, which the model must
+	// continue. Result.Text includes it.
+	Prefill string `json:"prefill,omitempty"`
+	// SeparateReasoning moves chain-of-thought written into the reply text
+	// (<think> blocks, Harmony analysis channels) to Result.ReasoningText
+	// and StreamEvent.ReasoningDelta.
+	SeparateReasoning bool `json:"separate_reasoning,omitempty"`
+	// WebSearch lets the model search the web before answering. The
+	// sources it used are returned in Result.Citations.
+	WebSearch *WebSearch `json:"web_search,omitempty"`
+	// Sources are documents, such as retrieved passages, the model must
+	// answer from. They are numbered in the prompt, the model cites them as
+	// [n], and each citation is returned in Result.Citations.
+	Sources []Source `json:"sources,omitempty"`
+	// StreamPacing smooths the stream before it reaches OnStream, OnToken
+	// and OnEvent.
+	StreamPacing *StreamPacing `json:"stream_pacing,omitempty"`
+	// StreamTransformers rewrite the stream of this request, after the
+	// client's Config.StreamTransformers.
+	StreamTransformers []StreamTransformer `json:"-"`
+	// ParallelToolCalls, when set, allows or forbids several tool calls in
+	// one reply. It maps to OpenAI parallel_tool_calls and Anthropic
+	// disable_parallel_tool_use, and limits emulated tool calls to one.
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+	// ToolTags adds the tools registered on the client with any of these
+	// tags to Request.Tools.
+	ToolTags []string `json:"tool_tags,omitempty"`
+	// Compression shrinks long prompts to a token budget before sending.
+	Compression *PromptCompression `json:"compression,omitempty"`
+	// CodeExecution lets the model run code in a sandbox hosted by the
+	// provider. Like WebSearch it is a server tool: the provider runs it
+	// within the request, and the runs are returned in
+	// Result.CodeExecutions.
+	CodeExecution *CodeExecution `json:"code_execution,omitempty"`
+	// PostProcessors name the post-processors, registered on the client or
+	// built in, that rewrite Result.Text in order once the reply is
+	// complete. The stream is not affected.
+	PostProcessors []string `json:"post_processors,omitempty"`
+	// Glossary enforces terminology: its terms are added to the prompt,
+	// and replies that break them are retried.
+	Glossary *Glossary `json:"glossary,omitempty"`
+	// NoSystemPrompt skips the system prompt configured on the client.
+	NoSystemPrompt bool `json:"no_system_prompt,omitempty"`
+	// Dump, if set, records the call; see WithDump.
+	Dump *Dump `json:"-"`
+	// Betas opt into provider preview features by portable name, such as
+	// BetaContext1M, or as raw flags qualified by provider, such as
+	// "anthropic:files-api-2025-04-14". Anthropic sends them as
+	// anthropic-beta and OpenAI as OpenAI-Beta; betas the provider does not
+	// support are reported as warnings. See ResolveBetas.
+	Betas []string `json:"betas,omitempty"`
+}
+
+// Source is a document given to the model with Options.Sources.
+type Source struct {
+	URL   string `json:"url,omitempty"`
+	Title string `json:"title,omitempty"`
+	Text  string `json:"text"`
+}
+
+// WebSearch configures the provider's built-in web search. MaxResults and
+// AllowedDomains are applied where the provider supports them and reported
+// as warnings otherwise.
+type WebSearch struct {
+	Enabled        bool     `json:"enabled"`
+	MaxResults     int      `json:"max_results,omitempty"`
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+}
+
+// CodeExecution configures the provider's code execution server tool.
+type CodeExecution struct {
+	Enabled bool `json:"enabled"`
+	// FileIDs are files uploaded with the provider's Files API (see the
+	// files package) to place in the sandbox, for example data to analyze.
+	FileIDs []string `json:"file_ids,omitempty"`
+}
+
+// CodeExecutionResult is one run of the code execution server tool.
+type CodeExecutionResult struct {
+	Code       string `json:"code"`
+	Stdout     string `json:"stdout,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	ReturnCode int    `json:"return_code"`
+	// FileIDs are files the code created, which can be downloaded with
+	// the files package.
+	FileIDs []string `json:"file_ids,omitempty"`
+	// Error is the provider's error code when the code could not be run.
+	Error string `json:"error,omitempty"`
+}
+
+// Citation is a source the reply is based on. Span, when set, is the part
+// of Result.Text the source supports.
+type Citation struct {
+	URL     string `json:"url"`
+	Title   string `json:"title,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+	Span    *Span  `json:"span,omitempty"`
+}
+
+// Span is a byte range [Start, End) of Result.Text.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// AddCitation appends c to list unless a citation of the same source and
+// span is already there. Sources are compared by URL, or by title when they
+// have no URL.
+func AddCitation(list []Citation, c Citation) []Citation {
+	for _, existing := range list {
+		sameSource := existing.URL == c.URL && (c.URL != "" || existing.Title == c.Title)
+		if sameSource && sameSpan(existing.Span, c.Span) {
+			return list
+		}
+	}
+	return append(list, c)
+}
+
+func sameSpan(a, b *Span) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// DefaultAutoContinueRounds is used when AutoContinue.MaxRounds is not set.
+const DefaultAutoContinueRounds = 3
+
+// AutoContinue re-prompts the model when a response stops with
+// FinishReasonLength and stitches the parts into a single result.
+// Continuation stops after MaxRounds extra calls (default 3), or earlier
+// once the total token usage or cost of all rounds reaches MaxTotalTokens
+// or MaxCost.
+type AutoContinue struct {
+	MaxRounds      int     `json:"max_rounds"`
+	MaxTotalTokens int     `json:"max_total_tokens,omitempty"`
+	MaxCost        float64 `json:"max_cost,omitempty"`
+	// CostFn prices the usage of one round; MaxCost is ignored without it.
+	CostFn func(model string, usage Usage) float64 `json:"-"`
+	// Prompt is the user message sent to request the rest of the output.
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// ParamNormalization controls how portable sampling options are adapted to
+// each provider's supported ranges before a request is sent.
+type ParamNormalization string
+
+const (
+	// ParamNormalizationClamp clamps out-of-range values and drops options
+	// the provider does not support. This is the default.
+	ParamNormalizationClamp ParamNormalization = "clamp"
+	// ParamNormalizationScale interprets Temperature on the OpenAI 0–2 scale
+	// and rescales it to the provider's range, then clamps like
+	// ParamNormalizationClamp.
+	ParamNormalizationScale ParamNormalization = "scale"
+	// ParamNormalizationOff sends options unchanged.
+	ParamNormalizationOff ParamNormalization = "off"
+)
+
+const (
+	GrammarGBNF       = "gbnf"
+	GrammarEBNF       = "ebnf"
+	GrammarRegex      = "regex"
+	GrammarJSONSchema = "json_schema"
+)
+
+const (
+	GrammarBackendLlamaCPP = "llamacpp"
+	GrammarBackendTGI      = "tgi"
+	GrammarBackendVLLM     = "vllm"
+)
+
+// Grammar constrains the output of a request. When Backend names the
+// inference server behind an OpenAI-compatible provider, the grammar is sent
+// in that server's native format. Otherwise it is emulated: the constraint is
+// added to the prompt and replies are validated (regex and json_schema only)
+// and retried up to MaxRetries times.
+type Grammar struct {
+	Syntax     string `json:"syntax"`
+	Value      string `json:"value"`
+	Backend    string `json:"backend,omitempty"`
+	MaxRetries int    `json:"max_retries,omitempty"`
+}
+
+// GlossaryTerm is a term replies must use as given.
+type GlossaryTerm struct {
+	// Term is the required spelling and capitalization, e.g. "iPhone".
+	Term string `json:"term"`
+	// Avoid lists spellings or synonyms that must not be used instead.
+	Avoid []string `json:"avoid,omitempty"`
+	// Source, when set, is a term of the input that must be translated as
+	// Term. A reply is only checked for Term when the input contains
+	// Source.
+	Source string `json:"source,omitempty"`
+	// Note is passed to the model, e.g. "product name, never translated".
+	Note string `json:"note,omitempty"`
+}
+
+// Glossary lists terminology that replies must follow. Terms are matched
+// as whole words, ignoring case, so a different capitalization of Term
+// also counts as a violation. A reply that breaks the glossary is sent
+// back with the violations up to MaxRetries times (default 2); if it still
+// does, the last reply is returned with a warning, or an error when
+// Strict is set.
+type Glossary struct {
+	Terms      []GlossaryTerm `json:"terms"`
+	MaxRetries int            `json:"max_retries,omitempty"`
+	Strict     bool           `json:"strict,omitempty"`
 }
 
 type Request struct {
@@ -97,36 +429,159 @@ type Usage struct {
 	InputTokens  int `json:"input_tokens"`
 	OutputTokens int `json:"output_tokens"`
 	TotalTokens  int `json:"total_tokens"`
+	// Estimated is set when the provider reported no token counts and
+	// they were estimated from the request and reply text.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// Add adds the counts of o to u. The sum is estimated if either is.
+func (u *Usage) Add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.TotalTokens += o.TotalTokens
+	u.Estimated = u.Estimated || o.Estimated
 }
 
 type Result struct {
-	Text      string     `json:"text,omitempty"`
-	Model     string     `json:"model,omitempty"`
-	Messages  []Message  `json:"messages,omitempty"`
-	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
-	Usage     Usage      `json:"usage,omitempty"`
-	Raw       any        `json:"raw,omitempty"`
-	Warnings  []string   `json:"warnings,omitempty"`
+	Text  string `json:"text,omitempty"`
+	Model string `json:"model,omitempty"`
+	// Messages is the assistant turn, ready to append to the conversation
+	// history for the next request.
+	Messages     []Message  `json:"messages,omitempty"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+	Usage        Usage      `json:"usage,omitempty"`
+	Raw          any        `json:"raw,omitempty"`
+	Warnings     []string   `json:"warnings,omitempty"`
+	// Attempts lists every provider call made for the request, such as
+	// tool emulation decisions, grammar retries and auto-continue rounds,
+	// including failed calls. Usage and Cost are the totals over all
+	// attempts.
+	Attempts []AttemptInfo `json:"attempts,omitempty"`
+	// Cost is set when the client is configured with a price function.
+	Cost float64 `json:"cost,omitempty"`
+	// ReasoningText is the model's chain-of-thought when the provider
+	// returns it separately (reasoning_content, Anthropic thinking blocks)
+	// or Options.SeparateReasoning extracted it from the reply. It is not
+	// part of Text or Messages.
+	ReasoningText string `json:"reasoning_text,omitempty"`
+	// Citations are the sources of a reply, from provider web search or
+	// from Options.Sources.
+	Citations []Citation `json:"citations,omitempty"`
+	// Choices are the replies of an n>1 request, in the provider's order.
+	// Text, ToolCalls and Messages are those of the first choice the
+	// content filter did not block.
+	Choices []Choice `json:"choices,omitempty"`
+	// ItemErrors lists the choices of an n>1 request that failed, such as
+	// choices blocked by a content filter; Index is the Choice.Index. When
+	// every choice failed, FinishReason says why.
+	ItemErrors []ItemError `json:"item_errors,omitempty"`
+	// RateLimit is the rate-limit state reported with the response, for
+	// providers that send rate-limit headers.
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+	// Compression reports what Options.Compression removed from the
+	// prompt.
+	Compression *CompressionReport `json:"compression,omitempty"`
+	// CodeExecutions are the runs of the code execution server tool.
+	CodeExecutions []CodeExecutionResult `json:"code_executions,omitempty"`
+}
+
+// Choice is one reply of an n>1 request.
+type Choice struct {
+	Index        int        `json:"index"`
+	Text         string     `json:"text,omitempty"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+	Refusal      string     `json:"refusal,omitempty"`
+}
+
+// ItemError reports one failed item of a call that otherwise succeeded: a
+// choice of an n>1 request, or a request of a batch.
+type ItemError struct {
+	Index int `json:"index"`
+	// ErrorClass is one of the ErrorClass constants.
+	ErrorClass string `json:"error_class"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BatchResult is the result of a batch of chat requests. Results has one
+// entry per request, nil for the requests listed in ItemErrors. Usage and
+// Cost are the totals of the successful requests.
+type BatchResult struct {
+	Results    []*Result   `json:"results"`
+	ItemErrors []ItemError `json:"item_errors,omitempty"`
+	Usage      Usage       `json:"usage"`
+	Cost       float64     `json:"cost,omitempty"`
+}
+
+// AssistantTurn returns the Result.Messages of a reply made of text and
+// tool calls.
+func AssistantTurn(text string, calls []ToolCall) []Message {
+	return []Message{{Role: RoleAssistant, Content: text, ToolCalls: calls}}
+}
+
+// Normalized values of Result.FinishReason. Providers that do not report a
+// reason leave it empty.
+const (
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonToolCalls     = "tool_calls"
+	FinishReasonContentFilter = "content_filter"
+)
+
+// NormalizeFinishReason maps provider-specific stop reasons onto the
+// FinishReason constants. Unknown values are returned unchanged.
+func NormalizeFinishReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence", "stop", "pause_turn":
+		return FinishReasonStop
+	case "max_tokens", "length", "model_context_window_exceeded":
+		return FinishReasonLength
+	case "tool_use", "tool_calls", "function_call":
+		return FinishReasonToolCalls
+	case "refusal", "content_filter", "guardrail_intervened":
+		return FinishReasonContentFilter
+	}
+	return reason
 }
 
 // OnStreamFunc is called for each streaming event.
 // Returning a non-nil error cancels the stream.
 type OnStreamFunc func(event StreamEvent) error
 
+// OnTokenFunc is called with each text delta. Unlike OnStreamFunc it cannot
+// cancel the stream. For providers that only return blocking responses it is
+// called once with the full text.
+type OnTokenFunc func(delta string)
+
+// OnEventFunc is called with each stream event. For providers that only
+// return blocking responses it is called once per tool call and text, and
+// then with the final Done event.
+type OnEventFunc func(event StreamEvent)
+
 // StreamEvent represents a single streaming event from an LLM provider.
 type StreamEvent struct {
-	Delta         string
-	ToolCallDelta *ToolCallDelta
-	Usage         *Usage
-	Done          bool
+	Delta         string         `json:"delta,omitempty"`
+	ToolCallDelta *ToolCallDelta `json:"tool_call_delta,omitempty"`
+	// ToolCall is set once a tool call has been fully received,
+	// after all of its ToolCallDelta events.
+	ToolCall *ToolCall `json:"tool_call,omitempty"`
+	Usage    *Usage    `json:"usage,omitempty"`
+	Done     bool      `json:"done,omitempty"`
+	// ReasoningDelta is a piece of chain-of-thought; see
+	// Result.ReasoningText.
+	ReasoningDelta string `json:"reasoning_delta,omitempty"`
+	// Cost is the price of Usage, set on the Done event when the client
+	// has a price function.
+	Cost float64 `json:"cost,omitempty"`
 }
 
 // ToolCallDelta represents an incremental update to a tool call during streaming.
 type ToolCallDelta struct {
-	Index     int
-	ID        string
-	Name      string
-	ArgsChunk string
+	Index     int    `json:"index"`
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	ArgsChunk string `json:"args_chunk,omitempty"`
 }
 
 type Option func(*Request)
@@ -192,6 +647,13 @@ func WithFrequencyPenalty(v float64) Option {
 	return func(r *Request) { r.Options.FrequencyPenalty = &v }
 }
 
+// WithLogitBias biases token strings (not IDs) by the given amount, on the
+// OpenAI -100..100 scale. Strings are tokenized per model via the tokens
+// package.
+func WithLogitBias(bias map[string]float64) Option {
+	return func(r *Request) { r.Options.LogitBias = bias }
+}
+
 func WithUser(user string) Option {
 	return func(r *Request) { r.Options.User = &user }
 }
@@ -200,10 +662,128 @@ func WithToolsEmulationMode(mode ToolsEmulationMode) Option {
 	return func(r *Request) { r.Options.ToolsEmulationMode = mode }
 }
 
+// WithToolsEmulationConfidence makes emulated tool calls carry the model's
+// confidence and reasoning in ToolCall.Metadata, so callers can ask for
+// confirmation before running low-confidence calls.
+func WithToolsEmulationConfidence() Option {
+	return func(r *Request) { r.Options.ToolsEmulationConfidence = true }
+}
+
+// WithRequirements adds compliance tags the serving provider must carry.
+// Providers are tagged with Config.ProviderTags on the client and with
+// Router.Tags on routers.
+func WithRequirements(tags ...string) Option {
+	return func(r *Request) { r.Options.Requirements = append(r.Options.Requirements, tags...) }
+}
+
+// WithProviderTags sets Options.ProviderTags.
+func WithProviderTags(tags map[string][]string) Option {
+	return func(r *Request) { r.Options.ProviderTags = tags }
+}
+
+// WithParallelToolCalls sets Options.ParallelToolCalls.
+func WithParallelToolCalls(parallel bool) Option {
+	return func(r *Request) { r.Options.ParallelToolCalls = &parallel }
+}
+
+// WithToolTags selects tools registered on the client by tag.
+func WithToolTags(tags ...string) Option {
+	return func(r *Request) { r.Options.ToolTags = append(r.Options.ToolTags, tags...) }
+}
+
+// WithNoStore sets Options.NoStore.
+func WithNoStore() Option {
+	return func(r *Request) { r.Options.NoStore = true }
+}
+
+// WithoutSystemPrompt sets Options.NoSystemPrompt.
+func WithoutSystemPrompt() Option {
+	return func(r *Request) { r.Options.NoSystemPrompt = true }
+}
+
+// WithPrefill makes the reply start with text, for example "{" to force a
+// JSON object. Anthropic continues the partial assistant turn natively;
+// other providers are instructed to begin their reply with text.
+func WithPrefill(text string) Option {
+	return func(r *Request) { r.Options.Prefill = text }
+}
+
+// WithSeparateReasoning sets Options.SeparateReasoning.
+func WithSeparateReasoning() Option {
+	return func(r *Request) { r.Options.SeparateReasoning = true }
+}
+
+// WithSources adds documents the model must answer from and cite.
+func WithSources(sources ...Source) Option {
+	return func(r *Request) { r.Options.Sources = append(r.Options.Sources, sources...) }
+}
+
+// WithStreamPacing smooths the stream of this request; see PaceStream.
+func WithStreamPacing(pacing StreamPacing) Option {
+	return func(r *Request) { r.Options.StreamPacing = &pacing }
+}
+
+// WithStreamTransformers adds transformers to the stream of this request.
+func WithStreamTransformers(transformers ...StreamTransformer) Option {
+	return func(r *Request) {
+		r.Options.StreamTransformers = append(r.Options.StreamTransformers, transformers...)
+	}
+}
+
+// WithWebSearch enables the provider's built-in web search.
+func WithWebSearch(ws WebSearch) Option {
+	ws.Enabled = true
+	return func(r *Request) { r.Options.WebSearch = &ws }
+}
+
+// WithCodeExecution enables the provider's code execution server tool.
+func WithCodeExecution(ce CodeExecution) Option {
+	ce.Enabled = true
+	return func(r *Request) { r.Options.CodeExecution = &ce }
+}
+
+// WithPostProcessors appends post-processors by name, e.g.
+// WithPostProcessors(PostStripFences, "max_sentences:2").
+func WithPostProcessors(names ...string) Option {
+	return func(r *Request) { r.Options.PostProcessors = append(r.Options.PostProcessors, names...) }
+}
+
+func WithAutoContinue(cfg AutoContinue) Option {
+	return func(r *Request) { r.Options.AutoContinue = &cfg }
+}
+
+func WithContextRecovery(cfg ContextRecovery) Option {
+	return func(r *Request) { r.Options.ContextRecovery = &cfg }
+}
+
+func WithPromptCompression(cfg PromptCompression) Option {
+	return func(r *Request) { r.Options.Compression = &cfg }
+}
+
+func WithParamNormalization(mode ParamNormalization) Option {
+	return func(r *Request) { r.Options.ParamNormalization = mode }
+}
+
+func WithGrammar(g Grammar) Option {
+	return func(r *Request) { r.Options.Grammar = &g }
+}
+
+func WithGlossary(g Glossary) Option {
+	return func(r *Request) { r.Options.Glossary = &g }
+}
+
 func WithOnStream(fn OnStreamFunc) Option {
 	return func(r *Request) { r.Options.OnStream = fn }
 }
 
+func WithOnToken(fn OnTokenFunc) Option {
+	return func(r *Request) { r.Options.OnToken = fn }
+}
+
+func WithOnEvent(fn OnEventFunc) Option {
+	return func(r *Request) { r.Options.OnEvent = fn }
+}
+
 func WithDebugFn(fn DebugFn) Option {
 	return func(r *Request) { r.Options.DebugFn = fn }
 }
@@ -228,6 +808,10 @@ func WithSusanooOptions(opts structs.JSONMap) Option {
 	return func(r *Request) { r.Options.Susanoo = opts }
 }
 
+func WithVLLMOptions(opts structs.JSONMap) Option {
+	return func(r *Request) { r.Options.VLLM = opts }
+}
+
 func WithTools(tools []Tool) Option {
 	return func(r *Request) { r.Tools = append([]Tool{}, tools...) }
 }
@@ -244,6 +828,16 @@ func User(text string) Message {
 	return Message{Role: RoleUser, Content: text}
 }
 
+// UserWithImages returns a user message with text followed by images.
+func UserWithImages(text string, images ...Image) Message {
+	return Message{Role: RoleUser, Content: text, Images: images}
+}
+
+// UserWithVideos returns a user message with text followed by videos.
+func UserWithVideos(text string, videos ...Video) Message {
+	return Message{Role: RoleUser, Content: text, Videos: videos}
+}
+
 func Assistant(text string) Message {
 	return Message{Role: RoleAssistant, Content: text}
 }
@@ -1,6 +1,11 @@
 package chat
 
-import "testing"
+import (
+	"errors"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
 
 func TestBuildRequestRequiresMessages(t *testing.T) {
 	_, err := BuildRequest(WithModel("gpt-4.1-mini"))
@@ -74,3 +79,49 @@ func TestOptions(t *testing.T) {
 		t.Fatalf("user not set")
 	}
 }
+
+func TestDropOldest(t *testing.T) {
+	msgs := []Message{
+		System("sys"),
+		User("q1"),
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "c1"}}},
+		ToolResult("c1", "r1"),
+		Assistant("a1"),
+		User("q2"),
+	}
+	kept, dropped := DropOldest(msgs, 2)
+	if len(dropped) != 4 || dropped[2].Role != RoleTool || dropped[3].Content != "a1" {
+		t.Fatalf("expected orphaned tool result and leading assistant to be dropped, got %+v", dropped)
+	}
+	if len(kept) != 2 || kept[0].Role != RoleSystem || kept[1].Content != "q2" {
+		t.Fatalf("unexpected kept messages: %+v", kept)
+	}
+	kept, dropped = DropOldest([]Message{User("q1"), Assistant("a1"), User("q2"), Assistant("a2"), User("q3")}, 1)
+	if len(dropped) != 2 || len(kept) != 3 || kept[0].Content != "q2" {
+		t.Fatalf("expected history to start with a user message, got %+v", kept)
+	}
+	if !IsContextLengthError(errors.New("This model's maximum context length is 8192 tokens")) {
+		t.Fatalf("expected context length error to be detected")
+	}
+}
+
+func TestResultRawOpenAI(t *testing.T) {
+	res := &Result{Raw: &openai.ChatCompletion{ID: "c1"}}
+	if raw, ok := res.RawOpenAI(); !ok || raw.ID != "c1" {
+		t.Fatalf("expected typed raw completion")
+	}
+	if _, ok := (&Result{Raw: map[string]any{}}).RawOpenAI(); ok {
+		t.Fatalf("non-OpenAI raw should not match")
+	}
+	if data, err := (&Result{}).RawJSON(); err != nil || data != nil {
+		t.Fatalf("expected nil raw json, got %s %v", data, err)
+	}
+}
+
+func TestUsageAdd(t *testing.T) {
+	u := Usage{InputTokens: 1, OutputTokens: 2, TotalTokens: 3}
+	u.Add(Usage{InputTokens: 10, OutputTokens: 20, TotalTokens: 30, Estimated: true})
+	if u != (Usage{InputTokens: 11, OutputTokens: 22, TotalTokens: 33, Estimated: true}) {
+		t.Fatalf("unexpected sum: %+v", u)
+	}
+}
@@ -0,0 +1,20 @@
+package uniai
+
+import (
+	"context"
+	"iter"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// ChatStream is Chat returning the reply as an iterator of chunks instead
+// of through an OnStream callback; see chat.Stream. An OnStream callback
+// set in opts still receives every event first.
+func (c *Client) ChatStream(ctx context.Context, opts ...chat.Option) iter.Seq2[chat.StreamChunk, error] {
+	return chat.Stream(ctx, func(ctx context.Context, onStream chat.OnStreamFunc) (*chat.Result, error) {
+		opts := append(append([]chat.Option{}, opts...), func(r *chat.Request) {
+			r.Options.OnStream = chat.ChainOnStream(r.Options.OnStream, onStream)
+		})
+		return c.Chat(ctx, opts...)
+	})
+}
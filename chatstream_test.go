@@ -0,0 +1,31 @@
+package uniai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestClientChatStream(t *testing.T) {
+	client := New(Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{fake.Text("hello world", 3, 0)}}))
+	var text string
+	var deltas int
+	for chunk, err := range client.ChatStream(context.Background(), WithProvider("fake"), WithMessages(User("hi"))) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if chunk.Done {
+			if chunk.Result == nil || chunk.Result.Text != "hello world" || chunk.Usage == nil {
+				t.Fatalf("unexpected done chunk: %+v", chunk)
+			}
+			continue
+		}
+		text += chunk.Delta
+		deltas++
+	}
+	if text != "hello world" || deltas != 4 {
+		t.Fatalf("unexpected stream: %q in %d deltas", text, deltas)
+	}
+}
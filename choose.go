@@ -0,0 +1,196 @@
+package uniai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/tokens"
+)
+
+// Ways Choose constrains the reply, reported in Choice.Method.
+const (
+	ChooseSchema    = "json_schema"
+	ChooseLogitBias = "logit_bias"
+	ChoosePrompt    = "prompt"
+)
+
+// chooseRetries is the number of times Choose asks again after a reply
+// that is not one of the options.
+const chooseRetries = 2
+
+// Choice is the answer of Choose.
+type Choice struct {
+	// Index is the position of the chosen option.
+	Index int
+	// Text is the raw reply the choice was read from.
+	Text string
+	// Method is how the reply was constrained: ChooseSchema,
+	// ChooseLogitBias or ChoosePrompt.
+	Method string
+	Result *chat.Result
+}
+
+// Choose asks the model to pick one of options in answer to prompt, and
+// guarantees that it does. Where the provider supports it, the reply is
+// constrained by a json_schema response format with the options as an
+// enum (openai, azure, gemini), or by logit bias when every option is a
+// single token of the model's tokenizer (other OpenAI-compatible
+// providers). Every reply is validated, and one that is not an option is
+// sent back up to twice before Choose fails. opts, such as the provider,
+// model or earlier messages, are applied before the prompt. The usage and
+// cost of the result cover every attempt.
+func Choose(ctx context.Context, c *Client, prompt string, options []string, opts ...chat.Option) (*Choice, error) {
+	if len(options) == 0 {
+		return nil, fmt.Errorf("choose: no options")
+	}
+	seen := map[string]bool{}
+	for _, o := range options {
+		key := strings.ToLower(strings.TrimSpace(o))
+		if key == "" {
+			return nil, fmt.Errorf("choose: empty option")
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("choose: duplicate option %q", o)
+		}
+		seen[key] = true
+	}
+	req, err := chat.BuildRequest(append(append([]chat.Option{}, opts...), chat.WithMessages(chat.User(prompt)))...)
+	if err != nil {
+		return nil, err
+	}
+	providerName := req.Provider
+	if providerName == "" {
+		providerName = c.cfg.Provider
+	}
+	if providerName == "" {
+		providerName = "openai"
+	}
+	method, constrain := c.chooseConstraint(providerName, req, options)
+
+	messages := withInstruction(req.Messages, chat.System(chooseInstruction(options, method)))
+	var (
+		usage chat.Usage
+		cost  float64
+	)
+	for attempt := 0; ; attempt++ {
+		call := append(append([]chat.Option{}, opts...), chat.WithReplaceMessages(messages...))
+		call = append(call, constrain...)
+		res, err := c.Chat(ctx, call...)
+		if err != nil {
+			return nil, err
+		}
+		// rejected replies are paid for too
+		usage.Add(res.Usage)
+		cost += res.Cost
+		if i := matchOption(options, res.Text); i >= 0 {
+			res.Usage, res.Cost = usage, cost
+			return &Choice{Index: i, Text: res.Text, Method: method, Result: res}, nil
+		}
+		if attempt >= chooseRetries {
+			return nil, fmt.Errorf("choose: reply %q is not one of the options after %d attempts", res.Text, attempt+1)
+		}
+		messages = append(messages,
+			chat.Assistant(res.Text),
+			chat.User("That is not one of the options. Reply with exactly one of them, and nothing else."),
+		)
+	}
+}
+
+// withInstruction returns msgs with instruction inserted after the leading
+// system messages.
+func withInstruction(msgs []chat.Message, instruction chat.Message) []chat.Message {
+	i := 0
+	for i < len(msgs) && msgs[i].Role == chat.RoleSystem {
+		i++
+	}
+	out := append([]chat.Message{}, msgs[:i]...)
+	out = append(out, instruction)
+	return append(out, msgs[i:]...)
+}
+
+// chooseConstraint returns the options that constrain the reply of
+// providerName to options, and how.
+func (c *Client) chooseConstraint(providerName string, req *chat.Request, options []string) (string, []chat.Option) {
+	switch providerName {
+	case "openai", "azure", "gemini":
+		format := map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "choice",
+				"strict": true,
+				"schema": map[string]any{
+					"type":                 "object",
+					"properties":           map[string]any{"choice": map[string]any{"type": "string", "enum": options}},
+					"required":             []any{"choice"},
+					"additionalProperties": false,
+				},
+			},
+		}
+		return ChooseSchema, []chat.Option{func(r *chat.Request) {
+			opts := cloneJSONMap(r.Options.OpenAI)
+			if opts == nil {
+				opts = structs.NewJSONMap()
+			}
+			opts["response_format"] = format
+			r.Options.OpenAI = opts
+		}}
+	case "openai_custom", "deepseek", "xai", "vllm":
+		enc := tokens.ForModel(c.defaultModel(providerName, req))
+		if enc == nil {
+			break
+		}
+		bias := map[string]float64{}
+		for _, o := range options {
+			ids, err := enc.Encode(o)
+			if err != nil || len(ids) != 1 {
+				return ChoosePrompt, nil
+			}
+			bias[o] = 100
+		}
+		return ChooseLogitBias, []chat.Option{chat.WithLogitBias(bias), chat.WithMaxTokens(1)}
+	}
+	return ChoosePrompt, nil
+}
+
+func chooseInstruction(options []string, method string) string {
+	var b strings.Builder
+	b.WriteString("Answer by choosing exactly one of these options:\n")
+	for _, o := range options {
+		b.WriteString("- " + o + "\n")
+	}
+	if method == ChooseSchema {
+		b.WriteString(`Reply with a JSON object of the form {"choice": "<option>"}.`)
+	} else {
+		b.WriteString("Reply with the option exactly as written, and nothing else.")
+	}
+	return b.String()
+}
+
+// matchOption returns the index of the option text names, or -1. It
+// accepts a {"choice": ...} object, surrounding quotes and punctuation,
+// and a different case.
+func matchOption(options []string, text string) int {
+	text = strings.TrimSpace(text)
+	var obj struct {
+		Choice string `json:"choice"`
+	}
+	if strings.HasPrefix(text, "{") && json.Unmarshal([]byte(text), &obj) == nil && obj.Choice != "" {
+		text = obj.Choice
+	}
+	text = strings.TrimSpace(strings.Trim(text, "\"'`*.!\n\t "))
+	for i, o := range options {
+		if text == strings.TrimSpace(o) {
+			return i
+		}
+	}
+	for i, o := range options {
+		if strings.EqualFold(text, strings.TrimSpace(o)) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,63 @@
+package uniai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestChooseSchema(t *testing.T) {
+	client := New(Config{})
+	p := fake.New(fake.Config{Responses: []fake.Response{fake.Text(`{"choice":"negative"}`, 0, 0)}})
+	client.RegisterProvider("openai", p)
+	choice, err := Choose(context.Background(), client, "I hated it.", []string{"positive", "negative", "neutral"}, WithProvider("openai"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if choice.Index != 1 || choice.Method != ChooseSchema || choice.Text != `{"choice":"negative"}` {
+		t.Fatalf("unexpected choice: %+v", choice)
+	}
+	format, _ := p.Requests()[0].Options.OpenAI["response_format"].(map[string]any)
+	if format["type"] != "json_schema" {
+		t.Fatalf("expected a json_schema response format, got %#v", p.Requests()[0].Options.OpenAI)
+	}
+}
+
+func TestChoosePromptRetries(t *testing.T) {
+	client := New(Config{})
+	p := fake.New(fake.Config{Responses: []fake.Response{
+		fake.Text("I think it is mostly positive", 0, 0),
+		fake.Text(`"Positive."`, 0, 0),
+	}})
+	client.RegisterProvider("fake", p)
+	choice, err := Choose(context.Background(), client, "I loved it.", []string{"positive", "negative"},
+		WithProvider("fake"), WithMessages(User("Classify the review."), Assistant("Send it.")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if choice.Index != 0 || choice.Method != ChoosePrompt {
+		t.Fatalf("unexpected choice: %+v", choice)
+	}
+	if choice.Result.Usage.OutputTokens != 2 {
+		t.Fatalf("expected usage of both attempts, got %+v", choice.Result.Usage)
+	}
+	reqs := p.Requests()
+	if len(reqs) != 2 || len(reqs[1].Messages) != 6 {
+		t.Fatalf("expected a retry with feedback, got %d requests", len(reqs))
+	}
+	if msgs := reqs[0].Messages; msgs[0].Role != chat.RoleSystem || msgs[3].Content != "I loved it." {
+		t.Fatalf("expected the instruction first and the prompt last, got %+v", msgs)
+	}
+
+	p = fake.New(fake.Config{Responses: []fake.Response{fake.Text("maybe", 0, 0)}})
+	client.RegisterProvider("fake", p)
+	if _, err := Choose(context.Background(), client, "?", []string{"yes", "no"}, WithProvider("fake")); err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Fatalf("expected failure after retries, got %v", err)
+	}
+	if _, err := Choose(context.Background(), client, "?", []string{"yes", "Yes"}); err == nil {
+		t.Fatalf("expected duplicate option error")
+	}
+}
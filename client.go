@@ -2,19 +2,29 @@ package uniai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/quailyquaily/uniai/chat"
 	"github.com/quailyquaily/uniai/classify"
 	"github.com/quailyquaily/uniai/embedding"
+	"github.com/quailyquaily/uniai/files"
+	"github.com/quailyquaily/uniai/finetune"
 	"github.com/quailyquaily/uniai/image"
 	"github.com/quailyquaily/uniai/providers/anthropic"
 	"github.com/quailyquaily/uniai/providers/azure"
 	"github.com/quailyquaily/uniai/providers/bedrock"
+	"github.com/quailyquaily/uniai/providers/gemini"
 	"github.com/quailyquaily/uniai/providers/openai"
 	"github.com/quailyquaily/uniai/providers/susanoo"
+	"github.com/quailyquaily/uniai/providers/vllm"
 	"github.com/quailyquaily/uniai/rerank"
+	"github.com/quailyquaily/uniai/speech"
+	"github.com/quailyquaily/uniai/usage"
+	"github.com/quailyquaily/uniai/video"
 )
 
 type Client struct {
@@ -24,6 +34,22 @@ type Client struct {
 	imageClient     *image.Client
 	rerankClient    *rerank.Client
 	classifyClient  *classify.Client
+	finetuneClient  *finetune.Client
+	videoClient     *video.Client
+	speechClient    *speech.Client
+	filesClient     *files.Client
+
+	providersMu sync.RWMutex
+	providers   map[string]chat.Provider
+
+	toolsMu sync.RWMutex
+	tools   []registeredTool
+
+	postMu         sync.RWMutex
+	postProcessors map[string]chat.PostProcessor
+
+	breakersMu sync.Mutex
+	breakers   map[string]*breaker
 }
 
 func New(cfg Config) *Client {
@@ -37,11 +63,21 @@ func New(cfg Config) *Client {
 			OpenAIAPIBase: cfg.OpenAIAPIBase,
 			GeminiAPIKey:  cfg.GeminiAPIKey,
 			GeminiAPIBase: cfg.GeminiAPIBase,
+			Admit:         admitFunc(cfg),
 		}),
 		imageClient: image.New(image.Config{
-			OpenAIAPIKey:  cfg.OpenAIAPIKey,
-			OpenAIAPIBase: cfg.OpenAIAPIBase,
-			GeminiAPIKey:  cfg.GeminiAPIKey,
+			OpenAIAPIKey:           cfg.OpenAIAPIKey,
+			OpenAIAPIBase:          cfg.OpenAIAPIBase,
+			GeminiAPIKey:           cfg.GeminiAPIKey,
+			AzureOpenAIAPIKey:      cfg.AzureOpenAIAPIKey,
+			AzureOpenAIEndpoint:    cfg.AzureOpenAIEndpoint,
+			AzureOpenAIAPIVersion:  cfg.AzureOpenAIAPIVersion,
+			AzureOpenAIDeployment:  cfg.AzureOpenAIImageDeployment,
+			AzureOpenAIDeployments: cfg.AzureOpenAIImageDeployments,
+			StabilityAPIKey:        cfg.StabilityAPIKey,
+			StabilityAPIBase:       cfg.StabilityAPIBase,
+			BFLAPIKey:              cfg.BFLAPIKey,
+			BFLAPIBase:             cfg.BFLAPIBase,
 		}),
 		rerankClient: rerank.New(rerank.Config{
 			JinaAPIKey:  cfg.JinaAPIKey,
@@ -51,6 +87,34 @@ func New(cfg Config) *Client {
 			JinaAPIKey:  cfg.JinaAPIKey,
 			JinaAPIBase: cfg.JinaAPIBase,
 		}),
+		finetuneClient: finetune.New(finetune.Config{
+			Provider:              cfg.FineTuneProvider,
+			OpenAIAPIKey:          cfg.OpenAIAPIKey,
+			OpenAIAPIBase:         cfg.OpenAIAPIBase,
+			AzureOpenAIAPIKey:     cfg.AzureOpenAIAPIKey,
+			AzureOpenAIEndpoint:   cfg.AzureOpenAIEndpoint,
+			AzureOpenAIAPIVersion: cfg.AzureOpenAIAPIVersion,
+		}),
+		videoClient: video.New(video.Config{
+			OpenAIAPIKey:  cfg.OpenAIAPIKey,
+			OpenAIAPIBase: cfg.OpenAIAPIBase,
+			RunwayAPIKey:  cfg.RunwayAPIKey,
+			RunwayAPIBase: cfg.RunwayAPIBase,
+			LumaAPIKey:    cfg.LumaAPIKey,
+			LumaAPIBase:   cfg.LumaAPIBase,
+		}),
+		speechClient: speech.New(speech.Config{
+			OpenAIAPIKey:    cfg.OpenAIAPIKey,
+			OpenAIAPIBase:   cfg.OpenAIAPIBase,
+			DeepgramAPIKey:  cfg.DeepgramAPIKey,
+			DeepgramAPIBase: cfg.DeepgramAPIBase,
+		}),
+		filesClient: files.New(files.Config{
+			Provider:        cfg.FilesProvider,
+			OpenAIAPIKey:    cfg.OpenAIAPIKey,
+			OpenAIAPIBase:   cfg.OpenAIAPIBase,
+			AnthropicAPIKey: cfg.AnthropicAPIKey,
+		}),
 	}
 }
 
@@ -59,6 +123,28 @@ func (c *Client) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, e
 	if err != nil {
 		return nil, err
 	}
+	d := req.Options.Dump
+	if d == nil {
+		return c.chat(ctx, req)
+	}
+	d.Begin(req)
+	req.Options.DebugFn = d.DebugFn(req.Options.DebugFn)
+	resp, err := c.chat(ctx, req)
+	d.End(resp, err)
+	return resp, err
+}
+
+func (c *Client) chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	dump := req.Options.Dump
+	if len(req.Options.ToolTags) > 0 {
+		n := len(req.Tools)
+		req.Tools = c.withRegisteredTools(req.Tools, req.Options.ToolTags)
+		dump.Decide("tools", "added %d registered tools tagged %s", len(req.Tools)-n, strings.Join(req.Options.ToolTags, ", "))
+	}
+	processors, err := c.resolvePostProcessors(req.Options.PostProcessors)
+	if err != nil {
+		return nil, err
+	}
 
 	providerName := req.Provider
 	if providerName == "" {
@@ -67,6 +153,162 @@ func (c *Client) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, e
 	if providerName == "" {
 		providerName = "openai"
 	}
+	var policyWarning string
+	if c.cfg.Policy != nil {
+		decision, err := c.cfg.Policy.Evaluate(ctx, providerName, req)
+		if err != nil {
+			return nil, err
+		}
+		if decision.Rule != nil {
+			dump.Decide("policy", "rule %s (%s) sends the request to %s/%s", decision.Rule.Name, decision.Rule.Action, decision.Provider, decision.Model)
+		}
+		providerName, req.Model, policyWarning = decision.Provider, decision.Model, decision.Warning
+		if decision.MaxTokens > 0 {
+			req.Options.MaxTokens = &decision.MaxTokens
+		}
+		if decision.DisableTools {
+			req.Tools, req.ToolChoice = nil, nil
+		}
+	}
+	if missing := chat.MissingRequirements(c.providerTags(providerName, req.Model, req), req.Options.Requirements); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s lacks %s", chat.ErrNonCompliant, providerName, strings.Join(missing, ", "))
+	}
+	req = c.injectSystemPrompt(ctx, providerName, req)
+	dump.Route(providerName, c.defaultModel(providerName, req))
+	finish := wrapCallbacks(req)
+	if pacing := req.Options.StreamPacing; pacing != nil && req.Options.OnStream != nil {
+		req.Options.OnStream = chat.PaceStream(ctx, *pacing, req.Options.OnStream)
+	}
+	if req.Options.OnStream != nil {
+		transformers := append(append([]chat.StreamTransformer{}, c.cfg.StreamTransformers...), req.Options.StreamTransformers...)
+		req.Options.OnStream = chat.ChainStream(req.Options.OnStream, transformers...)
+		if dump != nil {
+			req.Options.OnStream = dump.OnStream(req.Options.OnStream)
+		}
+	}
+	attempts := &attemptLog{}
+	ctx = context.WithValue(ctx, attemptLogKey{}, attempts)
+	req, compression, compressionWarning := c.compressPrompt(ctx, providerName, req)
+	if compression != nil {
+		dump.Decide("compression", "%s compressed the prompt from %d to %d tokens", compression.Strategy, compression.OriginalTokens, compression.Tokens)
+	}
+	resp, err := c.chatWithTools(ctx, providerName, req)
+	if err != nil && chat.IsContextLengthError(err) {
+		if !errors.Is(err, chat.ErrContextLengthExceeded) {
+			err = fmt.Errorf("%w: %w", chat.ErrContextLengthExceeded, err)
+		}
+		if req.Options.ContextRecovery != nil {
+			resp, err = c.recoverContext(ctx, providerName, req, err)
+		}
+	}
+	if err != nil {
+		return nil, attempts.wrap(err)
+	}
+	if req.Options.AutoContinue != nil {
+		resp, err = autoContinue(req, resp, func(next *chat.Request) (*chat.Result, error) {
+			return c.chatOnce(ctx, providerName, next)
+		})
+		if err != nil {
+			return nil, attempts.wrap(err)
+		}
+	}
+	attempts.apply(resp)
+	if policyWarning != "" {
+		resp.Warnings = append(resp.Warnings, policyWarning)
+	}
+	resp.Compression = compression
+	if compressionWarning != "" {
+		resp.Warnings = append(resp.Warnings, compressionWarning)
+	}
+	if resp.Messages == nil && !req.Options.LeanResult {
+		// registered providers and tool emulation may not set it
+		resp.Messages = chat.AssistantTurn(resp.Text, resp.ToolCalls)
+	}
+	if err := applyPostProcessors(resp, processors); err != nil {
+		return nil, err
+	}
+	finish(resp)
+	var budget *usage.Budget
+	if c.cfg.Policy != nil {
+		budget = c.cfg.Policy.Budget
+	}
+	if c.cfg.UsageRecorder != nil || budget != nil {
+		model := resp.Model
+		if model == "" {
+			model = c.defaultModel(providerName, req)
+		}
+		if c.cfg.UsageRecorder != nil {
+			c.cfg.UsageRecorder.RecordUsage(ctx, providerName, model, resp.Usage)
+		}
+		if budget != nil {
+			budget.RecordUsage(ctx, providerName, model, resp.Usage)
+		}
+	}
+	return resp, nil
+}
+
+// admit waits for the admission controller of the provider, if any, to
+// let a call start. The returned function ends the call and reports its
+// outcome.
+func (c *Client) admit(ctx context.Context, providerName string) (func(*chat.Result, error), error) {
+	a := c.cfg.admission(providerName)
+	if a == nil {
+		return func(*chat.Result, error) {}, nil
+	}
+	release, err := a.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(resp *chat.Result, err error) {
+		release()
+		rl := chat.RateLimitFromError(err)
+		if resp != nil {
+			rl = resp.RateLimit
+		}
+		a.Report(rl, err)
+	}, nil
+}
+
+func admitFunc(cfg Config) func(context.Context, string) (func(error), error) {
+	if cfg.Admission == nil && len(cfg.ProviderAdmission) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, provider string) (func(error), error) {
+		a := cfg.admission(provider)
+		if a == nil {
+			return func(error) {}, nil
+		}
+		release, err := a.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func(err error) {
+			release()
+			a.Report(chat.RateLimitFromError(err), err)
+		}, nil
+	}
+}
+
+// providerTags returns the compliance tags configured for the provider and
+// for the provider/model pair, on the client and on the request.
+func (c *Client) providerTags(providerName, model string, req *chat.Request) []string {
+	var tags []string
+	for _, m := range []map[string][]string{c.cfg.ProviderTags, req.Options.ProviderTags} {
+		tags = append(tags, m[providerName]...)
+		if model != "" {
+			tags = append(tags, m[providerName+"/"+model]...)
+		}
+	}
+	return tags
+}
+
+func (c *Client) chatWithTools(ctx context.Context, providerName string, req *chat.Request) (*chat.Result, error) {
+	if req.Options.Glossary != nil && len(req.Options.Glossary.Terms) > 0 {
+		return c.chatWithGlossary(ctx, providerName, req)
+	}
+	if g := req.Options.Grammar; g != nil && !grammarNative(providerName, g) {
+		return c.chatWithGrammar(ctx, providerName, req)
+	}
 	mode := req.Options.ToolsEmulationMode
 	if mode == "" {
 		mode = chat.ToolsEmulationOff
@@ -91,14 +333,218 @@ func (c *Client) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, e
 }
 
 func (c *Client) chatOnce(ctx context.Context, providerName string, req *chat.Request) (*chat.Result, error) {
+	normalized, warnings := normalizeParams(providerName, req)
+	normalized, biasWarnings := applyLogitBias(providerName, c.defaultModel(providerName, normalized), normalized)
+	warnings = append(warnings, biasWarnings...)
+	normalized = applyNativeGrammar(providerName, normalized)
+	normalized, searchWarnings := applyWebSearch(providerName, normalized)
+	warnings = append(warnings, searchWarnings...)
+	normalized, codeWarnings := applyCodeExecution(providerName, normalized)
+	warnings = append(warnings, codeWarnings...)
+	normalized, betaWarnings := applyBetas(providerName, normalized)
+	warnings = append(warnings, betaWarnings...)
+	normalized, schemaWarnings := applySchemaDialect(providerName, normalized)
+	warnings = append(warnings, schemaWarnings...)
+	normalized, finishPrefill := applyPrefill(providerName, normalized)
+	normalized, finishReasoning := applyReasoningSeparation(c.defaultModel(providerName, normalized), normalized)
+	normalized, finishSources := applySources(normalized)
+	normalized = c.accountStream(providerName, normalized)
+	report, err := c.allowCall(providerName)
+	if err != nil {
+		return nil, err
+	}
+	release, err := c.admit(ctx, providerName)
+	if err != nil {
+		report(err)
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := c.chatProvider(ctx, providerName, normalized)
+	if err == nil && resp.Usage == (chat.Usage{}) {
+		resp.Usage = estimateUsage(c.defaultModel(providerName, normalized), normalized, resultOutput(resp))
+	}
+	release(resp, err)
+	report(err)
+	if log, ok := ctx.Value(attemptLogKey{}).(*attemptLog); ok {
+		log.add(c.attempt(providerName, normalized, resp, err, time.Since(start)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	finishReasoning(resp)
+	finishPrefill(resp)
+	finishSources(resp)
+	if req.Options.DropRaw || req.Options.LeanResult {
+		resp.Raw = nil
+	}
+	if req.Options.LeanResult {
+		resp.Messages = nil
+	}
+	if len(warnings) > 0 {
+		resp.Warnings = append(warnings, resp.Warnings...)
+	}
+	return resp, nil
+}
+
+type attemptLogKey struct{}
+
+// attemptLog collects the provider calls made by chatOnce for one Chat
+// call.
+type attemptLog struct {
+	mu       sync.Mutex
+	attempts []chat.AttemptInfo
+}
+
+func (c *Client) attempt(providerName string, req *chat.Request, resp *chat.Result, err error, d time.Duration) chat.AttemptInfo {
+	a := chat.AttemptInfo{
+		Provider: providerName,
+		Model:    c.defaultModel(providerName, req),
+		Status:   chat.AttemptOK,
+		Duration: d,
+	}
+	if err != nil {
+		a.Status = chat.AttemptError
+		a.ErrorClass = chat.ClassifyError(err)
+		a.Error = err.Error()
+		return a
+	}
+	if resp.Model != "" {
+		a.Model = resp.Model
+	}
+	a.Usage = resp.Usage
+	if c.cfg.PriceFunc != nil {
+		a.Cost = c.cfg.PriceFunc(providerName, a.Model, resp.Usage)
+	}
+	return a
+}
+
+func (l *attemptLog) add(a chat.AttemptInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.attempts = append(l.attempts, a)
+}
+
+// apply sets the attempts on resp and replaces its usage and cost with
+// the totals, so callers are billed for every call, not just the last.
+func (l *attemptLog) apply(resp *chat.Result) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.attempts) == 0 {
+		return
+	}
+	resp.Attempts = append([]chat.AttemptInfo(nil), l.attempts...)
+	var total chat.Usage
+	var cost float64
+	for _, a := range l.attempts {
+		total.Add(a.Usage)
+		cost += a.Cost
+	}
+	resp.Usage = total
+	resp.Cost = cost
+}
+
+// wrap attaches the attempts to err as a chat.AttemptsError.
+func (l *attemptLog) wrap(err error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.attempts) == 0 {
+		return err
+	}
+	return &chat.AttemptsError{Err: err, Attempts: append([]chat.AttemptInfo(nil), l.attempts...)}
+}
+
+// RegisterProvider makes p available as providerName, taking precedence
+// over a built-in provider of the same name. A nil p removes it.
+func (c *Client) RegisterProvider(providerName string, p chat.Provider) {
+	c.providersMu.Lock()
+	defer c.providersMu.Unlock()
+	if p == nil {
+		delete(c.providers, providerName)
+		return
+	}
+	if c.providers == nil {
+		c.providers = map[string]chat.Provider{}
+	}
+	c.providers[providerName] = p
+}
+
+// geminiNative reports whether req needs the native Gemini API rather than
+// its OpenAI-compatible endpoint: for videos and Google Search grounding.
+func geminiNative(req *chat.Request) bool {
+	if ws := req.Options.WebSearch; ws != nil && ws.Enabled {
+		return true
+	}
+	for _, m := range req.Messages {
+		if len(m.Videos) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// geminiAPIBase returns Config.GeminiAPIBase, or the default.
+func (c *Client) geminiAPIBase() string {
+	base := strings.TrimRight(c.cfg.GeminiAPIBase, "/")
+	if base == "" {
+		base = DefaultGeminiAPIBase
+	}
+	return base
+}
+
+// geminiOpenAIBase returns Gemini's OpenAI-compatible endpoint. The
+// configured base may be the API root, its /v1beta version or the endpoint
+// itself.
+func (c *Client) geminiOpenAIBase() string {
+	base := c.geminiAPIBase()
+	if strings.HasSuffix(base, "/v1beta") {
+		return base + "/openai"
+	}
+	if !strings.Contains(base, "/openai") {
+		return base + "/v1beta/openai"
+	}
+	return base
+}
+
+func (c *Client) geminiAPIKey() string {
+	if c.cfg.GeminiAPIKey != "" {
+		return c.cfg.GeminiAPIKey
+	}
+	return c.cfg.OpenAIAPIKey
+}
+
+func (c *Client) anthropicConfig() anthropic.Config {
+	return anthropic.Config{
+		APIKey:            c.cfg.AnthropicAPIKey,
+		DefaultModel:      c.cfg.AnthropicModel,
+		Debug:             c.cfg.Debug,
+		Backend:           c.cfg.AnthropicBackend,
+		VertexProjectID:   c.cfg.AnthropicVertexProjectID,
+		VertexRegion:      c.cfg.AnthropicVertexRegion,
+		VertexAccessToken: c.cfg.AnthropicVertexAccessToken,
+		AwsKey:            c.cfg.AwsKey,
+		AwsSecret:         c.cfg.AwsSecret,
+		AwsRegion:         c.cfg.AwsRegion,
+	}
+}
+
+func (c *Client) chatProvider(ctx context.Context, providerName string, req *chat.Request) (*chat.Result, error) {
+	c.providersMu.RLock()
+	custom := c.providers[providerName]
+	c.providersMu.RUnlock()
+	if custom != nil {
+		return custom.Chat(ctx, req)
+	}
+
 	switch providerName {
-	case "openai", "openai_custom", "deepseek", "xai":
+	case "openai", "openai_custom", "deepseek", "xai", "perplexity":
 		base := c.cfg.OpenAIAPIBase
 		switch providerName {
 		case "deepseek":
 			base = "https://api.deepseek.com"
 		case "xai":
 			base = "https://api.x.ai/v1"
+		case "perplexity":
+			base = "https://api.perplexity.ai"
 		case "openai_custom":
 			// keep cfg.OpenAIAPIBase
 		}
@@ -108,6 +554,8 @@ func (c *Client) chatOnce(ctx context.Context, providerName string, req *chat.Re
 			BaseURL:      base,
 			DefaultModel: c.cfg.OpenAIModel,
 			Debug:        c.cfg.Debug,
+			// store is documented by OpenAI only
+			OmitStore: providerName != "openai",
 		})
 		if err != nil {
 			return nil, err
@@ -115,26 +563,25 @@ func (c *Client) chatOnce(ctx context.Context, providerName string, req *chat.Re
 		return p.Chat(ctx, req)
 
 	case "gemini":
-		base := strings.TrimRight(c.cfg.GeminiAPIBase, "/")
-		if base == "" {
-			base = DefaultGeminiAPIBase
-		}
-		if strings.HasSuffix(base, "/v1beta") {
-			base += "/openai"
-		} else if !strings.Contains(base, "/openai") {
-			base += "/v1beta/openai"
-		}
-		apiKey := c.cfg.GeminiAPIKey
-		if apiKey == "" {
-			apiKey = c.cfg.OpenAIAPIKey
-		}
 		geminiModel := c.cfg.GeminiModel
 		if geminiModel == "" {
 			geminiModel = c.cfg.OpenAIModel
 		}
+		if geminiNative(req) {
+			p, err := gemini.New(gemini.Config{
+				APIKey:       c.geminiAPIKey(),
+				BaseURL:      strings.TrimSuffix(strings.TrimSuffix(c.geminiAPIBase(), "/openai"), "/v1beta"),
+				DefaultModel: geminiModel,
+				Debug:        c.cfg.Debug,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return p.Chat(ctx, req)
+		}
 		p, err := openai.New(openai.Config{
-			APIKey:       apiKey,
-			BaseURL:      base,
+			APIKey:       c.geminiAPIKey(),
+			BaseURL:      c.geminiOpenAIBase(),
 			DefaultModel: geminiModel,
 			Debug:        c.cfg.Debug,
 		})
@@ -143,6 +590,18 @@ func (c *Client) chatOnce(ctx context.Context, providerName string, req *chat.Re
 		}
 		return p.Chat(ctx, req)
 
+	case "vllm":
+		p, err := vllm.New(vllm.Config{
+			APIKey:       c.cfg.VLLMAPIKey,
+			BaseURL:      c.cfg.VLLMAPIBase,
+			DefaultModel: c.cfg.VLLMModel,
+			Debug:        c.cfg.Debug,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return p.Chat(ctx, req)
+
 	case "azure":
 		p, err := azure.New(azure.Config{
 			APIKey:     c.cfg.AzureOpenAIAPIKey,
@@ -157,11 +616,10 @@ func (c *Client) chatOnce(ctx context.Context, providerName string, req *chat.Re
 		return p.Chat(ctx, req)
 
 	case "anthropic":
-		p := anthropic.New(anthropic.Config{
-			APIKey:       c.cfg.AnthropicAPIKey,
-			DefaultModel: c.cfg.AnthropicModel,
-			Debug:        c.cfg.Debug,
-		})
+		p, err := anthropic.New(c.anthropicConfig())
+		if err != nil {
+			return nil, err
+		}
 		return p.Chat(ctx, req)
 
 	case "bedrock":
@@ -194,6 +652,35 @@ func (c *Client) Embedding(ctx context.Context, opts ...embedding.Option) (*embe
 	return c.embeddingClient.Create(ctx, opts...)
 }
 
+// EmbeddingBatch embeds a large number of inputs with batching, caching and
+// rate limiting; see embedding.Client.CreateBatch.
+func (c *Client) EmbeddingBatch(ctx context.Context, cfg embedding.BatchConfig, opts ...embedding.Option) (*embedding.Result, error) {
+	if c.embeddingClient == nil {
+		return nil, fmt.Errorf("embedding client not configured")
+	}
+	return c.embeddingClient.CreateBatch(ctx, cfg, opts...)
+}
+
+// FineTune returns the client for fine-tuning files and jobs.
+func (c *Client) FineTune() *finetune.Client {
+	return c.finetuneClient
+}
+
+// Video returns the client for asynchronous video generation jobs.
+func (c *Client) Video() *video.Client {
+	return c.videoClient
+}
+
+// Speech returns the client for streaming transcription.
+func (c *Client) Speech() *speech.Client {
+	return c.speechClient
+}
+
+// Files returns the client for provider file storage.
+func (c *Client) Files() *files.Client {
+	return c.filesClient
+}
+
 func (c *Client) Image(ctx context.Context, opts ...image.Option) (*image.Result, error) {
 	if c.imageClient == nil {
 		return nil, fmt.Errorf("image client not configured")
@@ -201,6 +688,23 @@ func (c *Client) Image(ctx context.Context, opts ...image.Option) (*image.Result
 	return c.imageClient.Create(ctx, opts...)
 }
 
+// ImageEdit edits an input image; see image.Client.Edit.
+func (c *Client) ImageEdit(ctx context.Context, opts ...image.Option) (*image.Result, error) {
+	if c.imageClient == nil {
+		return nil, fmt.Errorf("image client not configured")
+	}
+	return c.imageClient.Edit(ctx, opts...)
+}
+
+// ImageVariations returns variations of an input image; see
+// image.Client.Variations.
+func (c *Client) ImageVariations(ctx context.Context, opts ...image.Option) (*image.Result, error) {
+	if c.imageClient == nil {
+		return nil, fmt.Errorf("image client not configured")
+	}
+	return c.imageClient.Variations(ctx, opts...)
+}
+
 func (c *Client) Rerank(ctx context.Context, opts ...rerank.Option) (*rerank.Result, error) {
 	if c.rerankClient == nil {
 		return nil, fmt.Errorf("rerank client not configured")
@@ -0,0 +1,164 @@
+package uniai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/admission"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/policy"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestRegisterProvider(t *testing.T) {
+	client := New(Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{
+		Responses: []fake.Response{fake.Text("streamed reply", 4, 0)},
+	}))
+
+	var got strings.Builder
+	resp, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User("hi")),
+		WithOnToken(func(delta string) { got.WriteString(delta) }),
+	)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Text != "streamed reply" || got.String() != "streamed reply" {
+		t.Fatalf("unexpected result %q / %q", resp.Text, got.String())
+	}
+
+	client.RegisterProvider("fake", nil)
+	if _, err := client.Chat(context.Background(), WithProvider("fake"), WithMessages(User("hi"))); err == nil {
+		t.Fatalf("expected error after unregistering")
+	}
+}
+
+func TestChatAggregatesAttempts(t *testing.T) {
+	decision := fake.Text(`{"tools":[]}`, 0, 0)
+	decision.Usage = &chat.Usage{InputTokens: 100, OutputTokens: 5, TotalTokens: 105}
+	final := fake.Text("no tool needed", 0, 0)
+	final.Usage = &chat.Usage{InputTokens: 20, OutputTokens: 4, TotalTokens: 24}
+
+	client := New(Config{PriceFunc: func(provider, model string, u chat.Usage) float64 {
+		return float64(u.TotalTokens) / 1000
+	}})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{decision, final}}))
+
+	resp, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithModel("m"),
+		WithMessages(User("hi")),
+		WithTools([]Tool{FunctionTool("noop", "", nil)}),
+		WithToolsEmulationMode(ToolsEmulationForce),
+	)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if len(resp.Attempts) != 2 || resp.Attempts[0].Usage.TotalTokens != 105 {
+		t.Fatalf("unexpected attempts %+v", resp.Attempts)
+	}
+	if resp.Usage.TotalTokens != 129 || resp.Usage.InputTokens != 120 {
+		t.Fatalf("usage not aggregated: %+v", resp.Usage)
+	}
+	if resp.Cost < 0.1289 || resp.Cost > 0.1291 {
+		t.Fatalf("unexpected cost %v", resp.Cost)
+	}
+}
+
+func TestChatAttemptsOnError(t *testing.T) {
+	client := New(Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{
+		{Err: errors.New("status 429: slow down")},
+	}}))
+	_, err := client.Chat(context.Background(), WithProvider("fake"), WithMessages(User("hi")))
+	attempts, ok := chat.AttemptsFromError(err)
+	if !ok || len(attempts) != 1 {
+		t.Fatalf("expected one attempt in %v", err)
+	}
+	if a := attempts[0]; a.Status != chat.AttemptError || a.ErrorClass != chat.ErrorClassRateLimit || a.Provider != "fake" {
+		t.Fatalf("unexpected attempt %+v", a)
+	}
+}
+
+func TestChatPolicyDowngrade(t *testing.T) {
+	p, err := policy.Parse([]byte(`{"rules":[{"name":"cheap","match":{"models":["big"]},"action":"downgrade","provider":"fake","model":"small"}]}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	client := New(Config{Policy: p})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{fake.Text("ok", 0, 0)}}))
+
+	resp, err := client.Chat(context.Background(), WithProvider("openai"), WithModel("big"), WithMessages(User("hi")))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Text != "ok" || len(resp.Warnings) != 1 {
+		t.Fatalf("unexpected result %+v", resp)
+	}
+	if len(resp.Attempts) != 1 || resp.Attempts[0].Provider != "fake" || resp.Attempts[0].Model != "small" {
+		t.Fatalf("request not rerouted: %+v", resp.Attempts)
+	}
+}
+
+func TestChatRequirements(t *testing.T) {
+	client := New(Config{ProviderTags: map[string][]string{"fake": {TagEU}}})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{fake.Text("ok", 0, 0)}}))
+
+	if _, err := client.Chat(context.Background(), WithProvider("fake"), WithMessages(User("hi")), WithRequirements(TagEU)); err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	_, err := client.Chat(context.Background(), WithProvider("fake"), WithMessages(User("hi")), WithRequirements(TagEU, TagHIPAA))
+	if !errors.Is(err, ErrNonCompliant) {
+		t.Fatalf("expected ErrNonCompliant, got %v", err)
+	}
+}
+
+func TestContextRecoverySummaryKeepsNoStore(t *testing.T) {
+	p := fake.New(fake.Config{Responses: []fake.Response{
+		{Err: chat.ErrContextLengthExceeded},
+		fake.Text("earlier: greetings", 0, 0),
+		fake.Text("ok", 0, 0),
+	}})
+	client := New(Config{ProviderTags: map[string][]string{"fake": {TagEU}}})
+	client.RegisterProvider("fake", p)
+
+	_, err := client.Chat(context.Background(), WithProvider("fake"),
+		WithMessages(User("hi"), Assistant("hello"), User("how are you?")),
+		WithContextRecovery(ContextRecovery{Strategy: chat.ContextStrategySummarize}),
+		WithNoStore(), WithRequirements(TagEU))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	reqs := p.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("expected the request, a summary and the retry, got %d calls", len(reqs))
+	}
+	if opts := reqs[1].Options; !opts.NoStore || len(opts.Requirements) != 1 {
+		t.Fatalf("summary call lost NoStore or requirements: %+v", opts)
+	}
+}
+
+func TestProviderAdmission(t *testing.T) {
+	shared := admission.New(admission.Config{Adaptive: true})
+	limited := admission.New(admission.Config{Adaptive: true})
+	client := New(Config{Admission: shared, ProviderAdmission: map[string]*admission.Controller{"a": limited}})
+	client.RegisterProvider("a", fake.New(fake.Config{Responses: []fake.Response{{Err: errors.New("status 429: slow down")}}}))
+	client.RegisterProvider("b", fake.New(fake.Config{Responses: []fake.Response{fake.Text("ok", 0, 0)}}))
+
+	if _, err := client.Chat(context.Background(), WithProvider("a"), WithMessages(User("hi"))); err == nil {
+		t.Fatalf("expected rate limit error")
+	}
+	if limited.Stats().PausedUntil.IsZero() {
+		t.Fatalf("expected provider a's controller to pause")
+	}
+	if !shared.Stats().PausedUntil.IsZero() {
+		t.Fatalf("rate limit from a paused the shared controller")
+	}
+	if resp, err := client.Chat(context.Background(), WithProvider("b"), WithMessages(User("hi"))); err != nil || resp.Text != "ok" {
+		t.Fatalf("provider b: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/bench"
+)
+
+func runBench(ctx context.Context, cfg uniai.Config, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var targets []bench.Target
+	fs.Func("t", "target as provider or provider/model (repeatable)", func(s string) error {
+		provider, model, _ := strings.Cut(s, "/")
+		targets = append(targets, bench.Target{Provider: provider, Model: model})
+		return nil
+	})
+	ramp := fs.String("c", "1,4,16", "comma-separated concurrency levels")
+	requests := fs.Int("n", 20, "requests per concurrency level")
+	workloadPath := fs.String("workload", "", "JSONL file of recorded requests (default: synthetic prompts)")
+	words := fs.Int("words", 50, "approximate words per synthetic prompt")
+	stream := fs.Bool("stream", true, "stream responses to measure time to first token")
+	timeout := fs.Duration("timeout", time.Minute, "per-request timeout")
+	maxTokens := fs.Int("max-tokens", 64, "maximum output tokens per request")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		targets = []bench.Target{{Provider: providerOrDefault("", cfg)}}
+	}
+
+	var levels []int
+	for _, part := range strings.Split(*ramp, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid concurrency level %q", part)
+		}
+		levels = append(levels, n)
+	}
+
+	workload := bench.Synthetic(*requests, *words)
+	if *workloadPath != "" {
+		f, err := os.Open(*workloadPath)
+		if err != nil {
+			return err
+		}
+		workload, err = bench.ReadWorkload(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	report, err := bench.Run(ctx, uniai.New(cfg), bench.Config{
+		Targets:          targets,
+		Workload:         workload,
+		Ramp:             levels,
+		RequestsPerStage: *requests,
+		Stream:           *stream,
+		Timeout:          *timeout,
+		Options:          []uniai.ChatOption{uniai.WithMaxTokens(*maxTokens)},
+	})
+	if report == nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(report); encErr != nil {
+			return encErr
+		}
+		return err
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tCONC\tREQ\tERR%\tRPS\tTOK/S\tP50\tP90\tP99\tTTFT P50\tTTFT P90")
+	for _, s := range report.Stages {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.2f\t%.1f\t%s\t%s\t%s\t%s\t%s\n",
+			s.Target, s.Concurrency, s.Requests, s.ErrorRate*100, s.Throughput, s.OutputTPS,
+			ms(s.LatencyP50), ms(s.LatencyP90), ms(s.LatencyP99), ms(s.TTFTP50), ms(s.TTFTP90))
+	}
+	if flushErr := tw.Flush(); flushErr != nil {
+		return flushErr
+	}
+	for _, s := range report.Stages {
+		for _, e := range s.FirstErrors {
+			fmt.Fprintf(stderr, "%s c=%d: %s\n", s.Target, s.Concurrency, e)
+		}
+	}
+	return err
+}
+
+func ms(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func runChat(ctx context.Context, cfg uniai.Config, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("chat", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	provider := fs.String("p", "", "provider (defaults to the config provider)")
+	model := fs.String("m", "", "model")
+	system := fs.String("s", "", "system prompt")
+	stream := fs.Bool("stream", true, "stream the reply as it is generated")
+	emulation := fs.String("tools-emulation", "", "tool emulation mode: off, fallback or force")
+	toolsPath := fs.String("tools", "", "JSON file with tool definitions")
+	asJSON := fs.Bool("json", false, "print the full result as JSON")
+	maxTokens := fs.Int("max-tokens", 0, "maximum output tokens")
+	var temperature *float64
+	fs.Func("t", "sampling temperature", func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		temperature = &v
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	prompt, err := readPrompt(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	var msgs []chat.Message
+	if *system != "" {
+		msgs = append(msgs, chat.System(*system))
+	}
+	msgs = append(msgs, chat.User(prompt))
+	opts := []chat.Option{chat.WithMessages(msgs...)}
+	if *provider != "" {
+		opts = append(opts, chat.WithProvider(*provider))
+	}
+	if *model != "" {
+		opts = append(opts, chat.WithModel(*model))
+	}
+	if temperature != nil {
+		opts = append(opts, chat.WithTemperature(*temperature))
+	}
+	if *maxTokens > 0 {
+		opts = append(opts, chat.WithMaxTokens(*maxTokens))
+	}
+	if *emulation != "" {
+		opts = append(opts, chat.WithToolsEmulationMode(chat.ToolsEmulationMode(*emulation)))
+	}
+	if *toolsPath != "" {
+		tools, err := loadTools(*toolsPath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, chat.WithTools(tools))
+	}
+	streamed := *stream && !*asJSON
+	if streamed {
+		opts = append(opts, chat.WithOnToken(func(delta string) {
+			fmt.Fprint(stdout, delta)
+		}))
+	}
+
+	resp, err := uniai.New(cfg).Chat(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		resp.Raw = nil
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resp)
+	}
+	if streamed {
+		fmt.Fprintln(stdout)
+	} else {
+		fmt.Fprintln(stdout, resp.Text)
+	}
+	printToolCalls(stdout, resp.ToolCalls)
+	for _, w := range resp.Warnings {
+		fmt.Fprintln(stderr, "warning:", w)
+	}
+	return nil
+}
+
+func printToolCalls(w io.Writer, calls []chat.ToolCall) {
+	for _, tc := range calls {
+		fmt.Fprintf(w, "tool call: %s(%s)\n", tc.Function.Name, tc.Function.Arguments)
+	}
+}
+
+// loadTools reads a JSON array of {"name", "description", "parameters"}
+// objects, where parameters is a JSON schema.
+func loadTools(path string) ([]chat.Tool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tools: %w", err)
+	}
+	return parseTools(data)
+}
+
+func parseTools(data []byte) ([]chat.Tool, error) {
+	var defs []struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("parse tools: %w", err)
+	}
+	tools := make([]chat.Tool, 0, len(defs))
+	for _, d := range defs {
+		if d.Name == "" {
+			return nil, fmt.Errorf("tool name is required")
+		}
+		tools = append(tools, uniai.FunctionTool(d.Name, d.Description, d.Parameters))
+	}
+	return tools, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/quailyquaily/uniai"
+)
+
+// envOverrides maps environment variables onto config fields.
+var envOverrides = []struct {
+	name string
+	dst  func(*uniai.Config) *string
+}{
+	{"UNIAI_PROVIDER", func(c *uniai.Config) *string { return &c.Provider }},
+	{"OPENAI_API_KEY", func(c *uniai.Config) *string { return &c.OpenAIAPIKey }},
+	{"OPENAI_API_BASE", func(c *uniai.Config) *string { return &c.OpenAIAPIBase }},
+	{"ANTHROPIC_API_KEY", func(c *uniai.Config) *string { return &c.AnthropicAPIKey }},
+	{"GEMINI_API_KEY", func(c *uniai.Config) *string { return &c.GeminiAPIKey }},
+	{"AZURE_OPENAI_API_KEY", func(c *uniai.Config) *string { return &c.AzureOpenAIAPIKey }},
+	{"AZURE_OPENAI_ENDPOINT", func(c *uniai.Config) *string { return &c.AzureOpenAIEndpoint }},
+	{"JINA_API_KEY", func(c *uniai.Config) *string { return &c.JinaAPIKey }},
+	{"OPENROUTER_API_KEY", func(c *uniai.Config) *string { return &c.OpenRouterAPIKey }},
+	{"VLLM_API_BASE", func(c *uniai.Config) *string { return &c.VLLMAPIBase }},
+	{"AWS_ACCESS_KEY_ID", func(c *uniai.Config) *string { return &c.AwsKey }},
+	{"AWS_SECRET_ACCESS_KEY", func(c *uniai.Config) *string { return &c.AwsSecret }},
+	{"AWS_REGION", func(c *uniai.Config) *string { return &c.AwsRegion }},
+}
+
+// loadConfig reads path, falling back to $UNIAI_CONFIG and then the default
+// location. A missing default file is not an error.
+func loadConfig(path string) (uniai.Config, error) {
+	var cfg uniai.Config
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("UNIAI_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "uniai", "config.json")
+		}
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&cfg); err != nil {
+				return cfg, fmt.Errorf("parse config %s: %w", path, err)
+			}
+		case explicit || !os.IsNotExist(err):
+			return cfg, fmt.Errorf("read config: %w", err)
+		}
+	}
+	for _, o := range envOverrides {
+		if v := os.Getenv(o.name); v != "" {
+			*o.dst(&cfg) = v
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/lint"
+)
+
+// runLint checks config, policy, prompt template and tool files, printing
+// one line per issue.
+func runLint(ctx context.Context, cfg uniai.Config, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	catalog := fs.Bool("catalog", false, "check model names against the providers' model lists (needs credentials)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("files are required")
+	}
+	l := &lint.Linter{}
+	if *catalog {
+		l.Catalog = lint.ClientCatalog(uniai.New(cfg))
+	}
+	count := 0
+	for _, path := range fs.Args() {
+		issues, err := l.File(ctx, path)
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			fmt.Fprintln(stdout, issue)
+		}
+		count += len(issues)
+	}
+	if count > 0 {
+		return fmt.Errorf("%d issues found", count)
+	}
+	return nil
+}
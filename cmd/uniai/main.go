@@ -0,0 +1,105 @@
+// Command uniai is a small CLI for ad-hoc chats and for checking provider
+// configuration.
+//
+// Usage:
+//
+//	uniai chat [-p provider] [-m model] [flags] prompt...
+//	uniai repl [-p provider] [-m model] [flags]
+//	uniai models [-p provider]
+//	uniai ping [-p provider]...
+//	uniai tokens [-m model] text...
+//	uniai bench [-t provider/model]... [-c 1,4,16] [-n requests]
+//	uniai lint [-catalog] file...
+//	uniai record [-o fixtures.json] [-stream] [-redact] scenario.json
+//
+// Configuration is read from the JSON file named by -config, $UNIAI_CONFIG,
+// or ~/.config/uniai/config.json; keys are uniai.Config field names.
+// Provider API keys in the usual environment variables override the file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "uniai:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	global := flag.NewFlagSet("uniai", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	configPath := global.String("config", "", "path to the JSON config file")
+	debug := global.Bool("debug", false, "log provider requests and responses")
+	global.Usage = func() {
+		fmt.Fprintln(stderr, "usage: uniai [-config file] [-debug] <chat|repl|models|ping|tokens|bench|lint|record> [flags] [args]")
+		global.PrintDefaults()
+	}
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return flag.ErrHelp
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *debug {
+		cfg.Debug = true
+	}
+
+	cmd, rest := global.Arg(0), global.Args()[1:]
+	switch cmd {
+	case "chat":
+		return runChat(ctx, cfg, rest, stdin, stdout, stderr)
+	case "repl":
+		return runREPL(ctx, cfg, rest, stdin, stdout, stderr)
+	case "models":
+		return runModels(ctx, cfg, rest, stdout, stderr)
+	case "ping":
+		return runPing(ctx, cfg, rest, stdout, stderr)
+	case "bench":
+		return runBench(ctx, cfg, rest, stdout, stderr)
+	case "tokens":
+		return runTokens(rest, stdin, stdout, stderr)
+	case "lint":
+		return runLint(ctx, cfg, rest, stdout, stderr)
+	case "record":
+		return runRecord(ctx, cfg, rest, stdout, stderr)
+	default:
+		global.Usage()
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+// readPrompt joins args, or reads stdin when there are none.
+func readPrompt(args []string, stdin io.Reader) (string, error) {
+	if len(args) > 0 {
+		return strings.Join(args, " "), nil
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return "", fmt.Errorf("prompt is required")
+	}
+	return text, nil
+}
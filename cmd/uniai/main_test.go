@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestRunChat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"c1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"pong"}}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"Provider":"openai","OpenAIAPIKey":"sk-test","OpenAIAPIBase":"` + srv.URL + `","OpenAIModel":"gpt-4o"}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPENAI_API_KEY", "")
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"-config", path, "chat", "-stream=false", "ping"}, strings.NewReader(""), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("run: %v (%s)", err, stderr.String())
+	}
+	if strings.TrimSpace(stdout.String()) != "pong" {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+
+	if err := os.WriteFile(path, []byte(`{"OpenAIKey":"typo"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(context.Background(), []string{"-config", path, "models"}, nil, &stdout, &stderr); err == nil {
+		t.Fatalf("expected error for unknown config field")
+	}
+}
+
+func TestREPLCommands(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	r := &repl{provider: "openai", stdout: &stdout, stderr: &stderr}
+	r.history = []chat.Message{chat.User("hi")}
+	for _, line := range []string{"/provider anthropic", "/model claude-3", "/tools off", "/emulation force", "/reset"} {
+		if r.command(line) {
+			t.Fatalf("%s should not quit", line)
+		}
+	}
+	if r.label() != "anthropic/claude-3" || r.tools || r.emulation != "force" || r.history != nil {
+		t.Fatalf("unexpected state: %+v", r)
+	}
+	r.command("/emulation sometimes")
+	if r.emulation != "force" || !strings.Contains(stderr.String(), "must be") {
+		t.Fatalf("invalid mode should be rejected")
+	}
+	if !r.command("/exit") {
+		t.Fatalf("/exit should quit")
+	}
+}
+
+func TestRunRecord(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"c1","object":"chat.completion","model":"gpt-4o-2024","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"Mailing ada@example.com, key sk-secret","tool_calls":[{"id":"call_x7Rq","type":"function","function":{"name":"send","arguments":"{\"to\":\"ada@example.com\"}"}}]}}],"usage":{"prompt_tokens":3,"completion_tokens":5,"total_tokens":8}}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.json")
+	scenario := filepath.Join(dir, "scenario.json")
+	out := filepath.Join(dir, "fixtures.json")
+	if err := os.WriteFile(config, []byte(`{"Provider":"openai","OpenAIAPIKey":"sk-secret","OpenAIAPIBase":"`+srv.URL+`"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(scenario, []byte(`{"model":"gpt-4o","steps":[{"name":"mail","messages":[{"role":"user","content":"Mail ada@example.com"}],"tools":[{"name":"send","parameters":{"type":"object"}}]}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPENAI_API_KEY", "")
+
+	var stdout, stderr bytes.Buffer
+	if err := run(context.Background(), []string{"-config", config, "record", "-stream=false", "-o", out, scenario}, nil, &stdout, &stderr); err != nil {
+		t.Fatalf("run: %v (%s)", err, stderr.String())
+	}
+	fixtures, err := fake.LoadFixtures(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 1 || fixtures[0].Name != "mail" || fixtures[0].Usage.TotalTokens != 8 {
+		t.Fatalf("fixtures: %+v", fixtures)
+	}
+	fx := fixtures[0]
+	if fx.Messages[0].Content != "Mail [EMAIL_1]" || len(fx.Chunks) != 2 || fx.Chunks[0].Text != "Mailing [EMAIL_1], key [REDACTED]" {
+		t.Fatalf("not sanitized: %+v", fx)
+	}
+	if tc := fx.Chunks[1].ToolCall; tc == nil || tc.ID != "call_1" || tc.Function.Arguments != `{"to":"[EMAIL_1]"}` {
+		t.Fatalf("tool call: %+v", tc)
+	}
+
+	res, err := fake.Replay(fixtures...).Chat(context.Background(), &chat.Request{})
+	if err != nil || res.Text != fx.Chunks[0].Text || len(res.ToolCalls) != 1 || res.FinishReason != chat.FinishReasonToolCalls {
+		t.Fatalf("replay: %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/tokens"
+)
+
+func runModels(ctx context.Context, cfg uniai.Config, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("models", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	provider := fs.String("p", "", "provider (defaults to the config provider)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	name := providerOrDefault(*provider, cfg)
+	models, err := uniai.New(cfg).ListModels(ctx, name)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tOWNED BY\tCONTEXT")
+	for _, m := range models {
+		ctxLen := ""
+		if m.ContextLength > 0 {
+			ctxLen = fmt.Sprint(m.ContextLength)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", m.ID, m.OwnedBy, ctxLen)
+	}
+	return tw.Flush()
+}
+
+// runPing checks each provider given with -p (repeatable).
+func runPing(ctx context.Context, cfg uniai.Config, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("ping", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var providers []string
+	fs.Func("p", "provider to check (repeatable)", func(s string) error {
+		providers = append(providers, s)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(providers) == 0 {
+		providers = []string{providerOrDefault("", cfg)}
+	}
+	client := uniai.New(cfg)
+	failed := 0
+	for _, p := range providers {
+		latency, err := client.Ping(ctx, p)
+		if err != nil {
+			failed++
+			fmt.Fprintf(stdout, "%s\tFAIL\t%v\n", p, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s\tOK\t%s\n", p, latency.Round(time.Millisecond))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d providers failed", failed, len(providers))
+	}
+	return nil
+}
+
+func runTokens(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	model := fs.String("m", "", "model whose tokenizer to use")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	text, err := readPrompt(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	n, exact, err := tokens.Count(*model, text)
+	if err != nil {
+		return err
+	}
+	if exact {
+		fmt.Fprintln(stdout, n)
+	} else {
+		fmt.Fprintf(stdout, "%d (estimate, no tokenizer registered for %q)\n", n, *model)
+	}
+	return nil
+}
+
+func providerOrDefault(provider string, cfg uniai.Config) string {
+	if provider = strings.TrimSpace(provider); provider != "" {
+		return provider
+	}
+	if cfg.Provider != "" {
+		return cfg.Provider
+	}
+	return "openai"
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+	"github.com/quailyquaily/uniai/redact"
+)
+
+// scenario is the file uniai record runs. Provider and Model are defaults
+// for the steps.
+type scenario struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Steps    []step `json:"steps"`
+}
+
+type step struct {
+	Name        string          `json:"name"`
+	Provider    string          `json:"provider,omitempty"`
+	Model       string          `json:"model,omitempty"`
+	Messages    []chat.Message  `json:"messages"`
+	Tools       json.RawMessage `json:"tools,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+}
+
+// runRecord runs each step of a scenario against the real providers and
+// writes the sanitized replies as fake.Fixture values.
+func runRecord(ctx context.Context, cfg uniai.Config, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("o", "", "fixtures file to write (default stdout)")
+	stream := fs.Bool("stream", true, "record the reply chunks as streamed")
+	pii := fs.Bool("redact", true, "replace emails, phone numbers and similar personal data with placeholders")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("one scenario file is required")
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var sc scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return fmt.Errorf("parse scenario: %w", err)
+	}
+
+	client := uniai.New(cfg)
+	s := newSanitizer(cfg, *pii)
+	fixtures := make([]fake.Fixture, 0, len(sc.Steps))
+	for i, st := range sc.Steps {
+		name := st.Name
+		if name == "" {
+			name = "step-" + strconv.Itoa(i+1)
+		}
+		fx, err := recordStep(ctx, client, sc, st, *stream)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fx.Name = name
+		fixtures = append(fixtures, s.fixture(fx))
+		fmt.Fprintf(stderr, "recorded %s\n", name)
+	}
+
+	if *out != "" {
+		return fake.SaveFixtures(*out, fixtures)
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fixtures)
+}
+
+func recordStep(ctx context.Context, client *uniai.Client, sc scenario, st step, stream bool) (fake.Fixture, error) {
+	provider, model := st.Provider, st.Model
+	if provider == "" {
+		provider = sc.Provider
+	}
+	if model == "" {
+		model = sc.Model
+	}
+	opts := []chat.Option{chat.WithMessages(st.Messages...)}
+	if provider != "" {
+		opts = append(opts, chat.WithProvider(provider))
+	}
+	if model != "" {
+		opts = append(opts, chat.WithModel(model))
+	}
+	if st.Temperature != nil {
+		opts = append(opts, chat.WithTemperature(*st.Temperature))
+	}
+	if st.MaxTokens > 0 {
+		opts = append(opts, chat.WithMaxTokens(st.MaxTokens))
+	}
+	if len(st.Tools) > 0 {
+		tools, err := parseTools(st.Tools)
+		if err != nil {
+			return fake.Fixture{}, err
+		}
+		opts = append(opts, chat.WithTools(tools))
+	}
+	var chunks []fake.FixtureChunk
+	if stream {
+		opts = append(opts, chat.WithOnStream(func(ev chat.StreamEvent) error {
+			switch {
+			case ev.Delta != "":
+				chunks = append(chunks, fake.FixtureChunk{Text: ev.Delta})
+			case ev.ToolCall != nil:
+				chunks = append(chunks, fake.FixtureChunk{ToolCall: ev.ToolCall})
+			}
+			return nil
+		}))
+	}
+	resp, err := client.Chat(ctx, opts...)
+	if err != nil {
+		return fake.Fixture{}, err
+	}
+	if len(chunks) == 0 {
+		// not streamed, or the provider answered without streaming
+		if resp.Text != "" {
+			chunks = append(chunks, fake.FixtureChunk{Text: resp.Text})
+		}
+		for _, tc := range resp.ToolCalls {
+			chunks = append(chunks, fake.FixtureChunk{ToolCall: &tc})
+		}
+	}
+	return fake.Fixture{
+		Provider:     provider,
+		Messages:     st.Messages,
+		Model:        resp.Model,
+		Chunks:       chunks,
+		FinishReason: resp.FinishReason,
+		Usage:        resp.Usage,
+	}, nil
+}
+
+// sanitizer removes credentials, personal data and random tool call IDs
+// from fixtures, so they can be committed and re-recording them gives
+// stable diffs.
+type sanitizer struct {
+	secrets []string
+	pii     *redact.Mapping
+}
+
+func newSanitizer(cfg uniai.Config, pii bool) *sanitizer {
+	s := &sanitizer{}
+	for _, v := range []string{
+		cfg.OpenAIAPIKey, cfg.AzureOpenAIAPIKey, cfg.AnthropicAPIKey, cfg.AnthropicVertexAccessToken,
+		cfg.AwsKey, cfg.AwsSecret, cfg.VLLMAPIKey, cfg.OpenRouterAPIKey, cfg.SusanooAPIKey,
+		cfg.StabilityAPIKey, cfg.BFLAPIKey, cfg.RunwayAPIKey, cfg.LumaAPIKey, cfg.DeepgramAPIKey,
+		cfg.JinaAPIKey, cfg.GeminiAPIKey,
+	} {
+		if v != "" {
+			s.secrets = append(s.secrets, v)
+		}
+	}
+	if pii {
+		s.pii = redact.NewMapping(redact.DefaultDetectors()...)
+	}
+	return s
+}
+
+func (s *sanitizer) text(text string) string {
+	for _, secret := range s.secrets {
+		text = strings.ReplaceAll(text, secret, "[REDACTED]")
+	}
+	if s.pii != nil {
+		text = s.pii.Redact(text)
+	}
+	return text
+}
+
+func (s *sanitizer) call(tc chat.ToolCall, ids map[string]string) chat.ToolCall {
+	if tc.ID != "" {
+		id, ok := ids[tc.ID]
+		if !ok {
+			id = "call_" + strconv.Itoa(len(ids)+1)
+			ids[tc.ID] = id
+		}
+		tc.ID = id
+	}
+	tc.Function.Arguments = s.text(tc.Function.Arguments)
+	return tc
+}
+
+func (s *sanitizer) fixture(fx fake.Fixture) fake.Fixture {
+	ids := map[string]string{}
+	msgs := make([]chat.Message, len(fx.Messages))
+	for i, m := range fx.Messages {
+		m.Content = s.text(m.Content)
+		if m.ToolCallID != "" {
+			m.ToolCallID = s.call(chat.ToolCall{ID: m.ToolCallID}, ids).ID
+		}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]chat.ToolCall, len(m.ToolCalls))
+			for j, tc := range m.ToolCalls {
+				calls[j] = s.call(tc, ids)
+			}
+			m.ToolCalls = calls
+		}
+		msgs[i] = m
+	}
+	fx.Messages = msgs
+
+	// Redacting chunk by chunk would miss values split across chunks, so
+	// text that changes as a whole is kept as a single chunk.
+	var text strings.Builder
+	var calls []fake.FixtureChunk
+	chunks := make([]fake.FixtureChunk, 0, len(fx.Chunks))
+	for _, c := range fx.Chunks {
+		text.WriteString(c.Text)
+		if c.ToolCall != nil {
+			tc := s.call(*c.ToolCall, ids)
+			c.ToolCall = &tc
+			calls = append(calls, c)
+		}
+		chunks = append(chunks, c)
+	}
+	if clean := s.text(text.String()); clean != text.String() {
+		chunks = append([]fake.FixtureChunk{{Text: clean}}, calls...)
+	}
+	fx.Chunks = chunks
+	return fx
+}
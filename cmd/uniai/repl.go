@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/agent"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+const replHelp = `commands:
+  /provider NAME    switch provider
+  /model NAME       switch model
+  /system TEXT      set the system prompt (empty clears it)
+  /tools on|off     enable or disable local tools
+  /emulation MODE   tool emulation mode: off, fallback or force
+  /history          print the conversation
+  /reset            clear the conversation
+  /help             show this help
+  /exit             quit`
+
+// repl holds the state of an interactive session.
+type repl struct {
+	client      *uniai.Client
+	provider    string
+	model       string
+	system      string
+	emulation   string
+	tools       bool
+	autoApprove bool
+	history     []chat.Message
+
+	in     *bufio.Scanner
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func runREPL(ctx context.Context, cfg uniai.Config, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	r := &repl{stdout: stdout, stderr: stderr}
+	fs.StringVar(&r.provider, "p", "", "provider (defaults to the config provider)")
+	fs.StringVar(&r.model, "m", "", "model")
+	fs.StringVar(&r.system, "s", "", "system prompt")
+	fs.StringVar(&r.emulation, "tools-emulation", "", "tool emulation mode: off, fallback or force")
+	fs.BoolVar(&r.tools, "tools", true, "let the model use local shell and file tools")
+	fs.BoolVar(&r.autoApprove, "yes", false, "run shell and write_file calls without asking")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	r.provider = providerOrDefault(r.provider, cfg)
+	r.client = uniai.New(cfg)
+	r.in = bufio.NewScanner(stdin)
+	r.in.Buffer(make([]byte, 0, 64<<10), 1<<20)
+
+	fmt.Fprintln(stdout, "uniai repl — /help for commands")
+	for {
+		fmt.Fprintf(stdout, "%s> ", r.label())
+		if !r.in.Scan() {
+			fmt.Fprintln(stdout)
+			return r.in.Err()
+		}
+		line := strings.TrimSpace(r.in.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "/"):
+			if quit := r.command(line); quit {
+				return nil
+			}
+		default:
+			if err := r.send(ctx, line); err != nil {
+				fmt.Fprintln(stderr, "error:", err)
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *repl) label() string {
+	if r.model != "" {
+		return r.provider + "/" + r.model
+	}
+	return r.provider
+}
+
+// command handles a slash command and reports whether to quit.
+func (r *repl) command(line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "/exit", "/quit":
+		return true
+	case "/help":
+		fmt.Fprintln(r.stdout, replHelp)
+	case "/provider":
+		if arg == "" {
+			fmt.Fprintln(r.stdout, r.provider)
+			break
+		}
+		// a model name rarely carries over between providers
+		r.provider, r.model = arg, ""
+	case "/model":
+		if arg == "" {
+			fmt.Fprintln(r.stdout, r.model)
+			break
+		}
+		r.model = arg
+	case "/system":
+		r.system = arg
+	case "/tools":
+		switch arg {
+		case "on":
+			r.tools = true
+		case "off":
+			r.tools = false
+		default:
+			fmt.Fprintf(r.stdout, "tools: %v\n", r.tools)
+		}
+	case "/emulation":
+		switch mode := chat.ToolsEmulationMode(arg); mode {
+		case chat.ToolsEmulationOff, chat.ToolsEmulationFallback, chat.ToolsEmulationForce:
+			r.emulation = arg
+		default:
+			fmt.Fprintln(r.stderr, "emulation mode must be off, fallback or force")
+		}
+	case "/history":
+		for _, m := range r.history {
+			content := m.Content
+			for _, tc := range m.ToolCalls {
+				content += fmt.Sprintf(" [%s(%s)]", tc.Function.Name, tc.Function.Arguments)
+			}
+			fmt.Fprintf(r.stdout, "%s: %s\n", m.Role, content)
+		}
+	case "/reset":
+		r.history = nil
+	default:
+		fmt.Fprintf(r.stderr, "unknown command %s; /help lists commands\n", name)
+	}
+	return false
+}
+
+func (r *repl) send(ctx context.Context, text string) error {
+	messages := append([]chat.Message{}, r.history...)
+	messages = append(messages, chat.User(text))
+	if r.system != "" {
+		messages = append([]chat.Message{chat.System(r.system)}, messages...)
+	}
+
+	opts := []chat.Option{
+		chat.WithProvider(r.provider),
+		chat.WithOnToken(func(delta string) { fmt.Fprint(r.stdout, delta) }),
+	}
+	if r.model != "" {
+		opts = append(opts, chat.WithModel(r.model))
+	}
+	if r.emulation != "" {
+		opts = append(opts, chat.WithToolsEmulationMode(chat.ToolsEmulationMode(r.emulation)))
+	}
+	runner := &agent.Runner{
+		Client:  r.client,
+		Approve: r.approve,
+		OnToolResult: func(call chat.ToolCall, output string, err error) {
+			status := "ok"
+			if err != nil {
+				status = err.Error()
+			}
+			fmt.Fprintf(r.stderr, "\n[%s %s: %s]\n", call.Function.Name, call.Function.Arguments, firstLine(status))
+		},
+	}
+	if r.tools {
+		runner.Tools = localTools()
+	}
+	res, err := runner.Run(ctx, messages, opts...)
+	fmt.Fprintln(r.stdout)
+	if res != nil {
+		// keep the system prompt out of the history so /system applies to
+		// later turns
+		if r.system != "" && len(res.Messages) > 0 {
+			res.Messages = res.Messages[1:]
+		}
+		r.history = res.Messages
+		if res.Final != nil {
+			for _, w := range res.Final.Warnings {
+				fmt.Fprintln(r.stderr, "warning:", w)
+			}
+		}
+	}
+	return err
+}
+
+// approve asks before running tools with side effects.
+func (r *repl) approve(ctx context.Context, call chat.ToolCall) bool {
+	if r.autoApprove {
+		return true
+	}
+	switch call.Function.Name {
+	case "shell", "write_file":
+	default:
+		return true
+	}
+	fmt.Fprintf(r.stdout, "\nrun %s %s? [y/N] ", call.Function.Name, call.Function.Arguments)
+	if !r.in.Scan() {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(r.in.Text()))
+	return answer == "y" || answer == "yes"
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
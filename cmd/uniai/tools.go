@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/agent"
+)
+
+// maxToolOutput caps what a local tool sends back to the model.
+const maxToolOutput = 16 << 10
+
+// localTools returns shell and file tools that run on this machine.
+func localTools() []agent.Tool {
+	return []agent.Tool{
+		{
+			Name:        "shell",
+			Description: "Run a shell command and return its combined output.",
+			Parameters:  []byte(`{"type":"object","properties":{"command":{"type":"string"}},"required":["command"]}`),
+			Run: func(ctx context.Context, args string) (string, error) {
+				var in struct {
+					Command string `json:"command"`
+				}
+				if err := json.Unmarshal([]byte(args), &in); err != nil || in.Command == "" {
+					return "", fmt.Errorf("command is required")
+				}
+				ctx, cancel := context.WithTimeout(ctx, time.Minute)
+				defer cancel()
+				out, err := exec.CommandContext(ctx, "sh", "-c", in.Command).CombinedOutput()
+				text := truncate(string(out))
+				if err != nil {
+					return text, fmt.Errorf("%w\n%s", err, text)
+				}
+				return text, nil
+			},
+		},
+		{
+			Name:        "read_file",
+			Description: "Read a text file.",
+			Parameters:  []byte(`{"type":"object","properties":{"path":{"type":"string"}},"required":["path"]}`),
+			Run: func(ctx context.Context, args string) (string, error) {
+				var in struct {
+					Path string `json:"path"`
+				}
+				if err := json.Unmarshal([]byte(args), &in); err != nil || in.Path == "" {
+					return "", fmt.Errorf("path is required")
+				}
+				data, err := os.ReadFile(in.Path)
+				if err != nil {
+					return "", err
+				}
+				return truncate(string(data)), nil
+			},
+		},
+		{
+			Name:        "write_file",
+			Description: "Create or overwrite a text file.",
+			Parameters:  []byte(`{"type":"object","properties":{"path":{"type":"string"},"content":{"type":"string"}},"required":["path","content"]}`),
+			Run: func(ctx context.Context, args string) (string, error) {
+				var in struct {
+					Path    string `json:"path"`
+					Content string `json:"content"`
+				}
+				if err := json.Unmarshal([]byte(args), &in); err != nil || in.Path == "" {
+					return "", fmt.Errorf("path is required")
+				}
+				if err := os.WriteFile(in.Path, []byte(in.Content), 0o644); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("wrote %d bytes to %s", len(in.Content), in.Path), nil
+			},
+		},
+		{
+			Name:        "list_dir",
+			Description: "List the entries of a directory.",
+			Parameters:  []byte(`{"type":"object","properties":{"path":{"type":"string"}}}`),
+			Run: func(ctx context.Context, args string) (string, error) {
+				var in struct {
+					Path string `json:"path"`
+				}
+				_ = json.Unmarshal([]byte(args), &in)
+				if in.Path == "" {
+					in.Path = "."
+				}
+				entries, err := os.ReadDir(in.Path)
+				if err != nil {
+					return "", err
+				}
+				var b strings.Builder
+				for _, e := range entries {
+					name := e.Name()
+					if e.IsDir() {
+						name += "/"
+					}
+					b.WriteString(name + "\n")
+				}
+				return truncate(b.String()), nil
+			},
+		},
+	}
+}
+
+func truncate(s string) string {
+	if len(s) <= maxToolOutput {
+		return s
+	}
+	return s[:maxToolOutput] + "\n[output truncated]"
+}
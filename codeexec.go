@@ -0,0 +1,20 @@
+package uniai
+
+import (
+	"fmt"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// applyCodeExecution drops req.Options.CodeExecution, with a warning, for
+// providers without a code execution server tool. Anthropic adds the tool
+// in the provider.
+func applyCodeExecution(providerName string, req *chat.Request) (*chat.Request, []string) {
+	ce := req.Options.CodeExecution
+	if ce == nil || !ce.Enabled || providerName == "anthropic" {
+		return req, nil
+	}
+	r := *req
+	r.Options.CodeExecution = nil
+	return &r, []string{fmt.Sprintf("code execution is not supported by %s; ignored", providerName)}
+}
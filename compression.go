@@ -0,0 +1,266 @@
+package uniai
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/tokens"
+)
+
+const compressionSummaryPrompt = "Condense the text below to at most %d words. Keep the facts, figures and names that matter for this request: %q. Reply with the condensed text only."
+
+// compressPrompt implements req.Options.Compression. It returns the
+// request to send, the report, and a warning when the target could not be
+// reached.
+func (c *Client) compressPrompt(ctx context.Context, providerName string, req *chat.Request) (*chat.Request, *chat.CompressionReport, string) {
+	cfg := req.Options.Compression
+	if cfg == nil || cfg.TargetTokens <= 0 || len(req.Messages) < 2 {
+		return req, nil, ""
+	}
+	model := c.defaultModel(providerName, req)
+	count := func(text string) int {
+		n, _, err := tokens.Count(model, text)
+		if err != nil {
+			return tokens.Estimate(text)
+		}
+		return n
+	}
+	msgs := slices.Clone(req.Messages)
+	total := 0
+	for _, msg := range msgs {
+		total += count(msg.Content)
+	}
+	if total <= cfg.TargetTokens {
+		return req, nil, ""
+	}
+	report := &chat.CompressionReport{Strategy: cfg.Strategy, OriginalTokens: total}
+	if report.Strategy == "" {
+		report.Strategy = chat.CompressionTrim
+	}
+	query := msgs[len(msgs)-1].Content
+	var warning string
+	if report.Strategy == chat.CompressionSummarize {
+		total, warning = c.summarizeForBudget(ctx, providerName, req, msgs, total, cfg.TargetTokens, query, count, report)
+	} else {
+		total = trimSentences(msgs, total, cfg.TargetTokens, query, count, report)
+	}
+	report.Tokens = total
+	if total > cfg.TargetTokens && warning == "" {
+		warning = fmt.Sprintf("prompt compression: %d tokens left, above the target of %d", total, cfg.TargetTokens)
+	}
+	out := *req
+	out.Messages = msgs
+	return &out, report, warning
+}
+
+// compressible reports whether compression may change msgs[i]: system
+// messages and the last message are kept as they are.
+func compressible(msgs []chat.Message, i int) bool {
+	return i < len(msgs)-1 && msgs[i].Role != chat.RoleSystem && strings.TrimSpace(msgs[i].Content) != ""
+}
+
+type scoredSentence struct {
+	msg     int
+	text    string
+	tokens  int
+	score   float64
+	dropped bool
+}
+
+// trimSentences drops the lowest-scoring sentences until the prompt fits.
+// A sentence scores higher the more of the query's words it shares, the
+// denser it is in content words, and the later its message.
+func trimSentences(msgs []chat.Message, total, target int, query string, count func(string) int, report *chat.CompressionReport) int {
+	queryWords := map[string]bool{}
+	for _, w := range contentWords(query) {
+		queryWords[w] = true
+	}
+	var sentences []*scoredSentence
+	byMessage := map[int][]*scoredSentence{}
+	for i := range msgs {
+		if !compressible(msgs, i) {
+			continue
+		}
+		for pos, text := range splitSentences(msgs[i].Content) {
+			s := &scoredSentence{msg: i, text: text, tokens: count(text)}
+			words := contentWords(text)
+			allWords := max(len(strings.Fields(text)), 1)
+			overlap, unique := 0, map[string]bool{}
+			for _, w := range words {
+				if queryWords[w] {
+					overlap++
+				}
+				unique[w] = true
+			}
+			s.score = 2*float64(overlap)/float64(len(words)+1) +
+				float64(len(unique))/float64(allWords) +
+				0.3*float64(i)/float64(len(msgs))
+			if pos == 0 {
+				s.score += 0.2
+			}
+			sentences = append(sentences, s)
+			byMessage[i] = append(byMessage[i], s)
+		}
+	}
+	order := slices.Clone(sentences)
+	slices.SortStableFunc(order, func(a, b *scoredSentence) int {
+		switch {
+		case a.score < b.score:
+			return -1
+		case a.score > b.score:
+			return 1
+		}
+		return 0
+	})
+	// sentence counts need not add up to message counts, so recount after
+	// each pass and keep dropping while the prompt is over the target
+	next := 0
+	for total > target && next < len(order) {
+		for ; next < len(order) && total > target; next++ {
+			s := order[next]
+			s.dropped = true
+			total -= s.tokens
+			report.Elided = append(report.Elided, chat.Elision{Message: s.msg, Text: strings.TrimSpace(s.text)})
+		}
+		for i, list := range byMessage {
+			var b strings.Builder
+			changed := false
+			for _, s := range list {
+				if s.dropped {
+					changed = true
+				} else {
+					b.WriteString(s.text)
+				}
+			}
+			if !changed {
+				continue
+			}
+			content := strings.TrimSpace(b.String())
+			if content == "" {
+				content = "[omitted]"
+			}
+			msgs[i].Content = content
+		}
+		total = 0
+		for _, msg := range msgs {
+			total += count(msg.Content)
+		}
+	}
+	slices.SortStableFunc(report.Elided, func(a, b chat.Elision) int { return a.Message - b.Message })
+	return total
+}
+
+// summarizeForBudget has the model condense the longest messages, largest
+// first, until the prompt fits.
+func (c *Client) summarizeForBudget(ctx context.Context, providerName string, req *chat.Request, msgs []chat.Message, total, target int, query string, count func(string) int, report *chat.CompressionReport) (int, string) {
+	var candidates []int
+	for i := range msgs {
+		if compressible(msgs, i) {
+			candidates = append(candidates, i)
+		}
+	}
+	size := make(map[int]int, len(candidates))
+	for _, i := range candidates {
+		size[i] = count(msgs[i].Content)
+	}
+	slices.SortStableFunc(candidates, func(a, b int) int { return size[b] - size[a] })
+	for _, i := range candidates {
+		if total <= target {
+			break
+		}
+		// shrink this message by the excess, but to no less than a quarter
+		goal := max(size[i]-(total-target), size[i]/4, 32)
+		if goal >= size[i] {
+			continue
+		}
+		summaryReq := &chat.Request{
+			Provider: req.Provider,
+			Model:    req.Model,
+			Messages: []chat.Message{
+				chat.System(fmt.Sprintf(compressionSummaryPrompt, goal*3/4, truncateRunes(query, 300))),
+				chat.User(msgs[i].Content),
+			},
+			Options: chat.Options{DebugFn: req.Options.DebugFn, NoStore: req.Options.NoStore, Requirements: req.Options.Requirements},
+		}
+		resp, err := c.chatOnce(ctx, providerName, summaryReq)
+		if err != nil {
+			return total, fmt.Sprintf("prompt compression: summary failed: %v", err)
+		}
+		summary := strings.TrimSpace(resp.Text)
+		if summary == "" {
+			continue
+		}
+		report.Elided = append(report.Elided, chat.Elision{Message: i, Text: msgs[i].Content, Summary: summary})
+		total += count(summary) - size[i]
+		msgs[i].Content = summary
+	}
+	return total, ""
+}
+
+// splitSentences splits text after sentence punctuation and line breaks.
+// Each sentence keeps its trailing whitespace, so joining them gives back
+// text.
+func splitSentences(text string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		end := -1
+		switch {
+		case ch == '\n':
+			end = i + 1
+		case (ch == '.' || ch == '!' || ch == '?') && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\n'):
+			end = i + 1
+		}
+		if end < 0 {
+			continue
+		}
+		for end < len(text) && (text[end] == ' ' || text[end] == '\n') {
+			end++
+		}
+		out = append(out, text[start:end])
+		start, i = end, end-1
+	}
+	if start < len(text) {
+		out = append(out, text[start:])
+	}
+	return out
+}
+
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "any": true, "can": true, "had": true, "her": true,
+	"was": true, "one": true, "our": true, "out": true, "has": true, "his": true,
+	"how": true, "its": true, "who": true, "did": true, "yes": true, "she": true,
+	"him": true, "they": true, "this": true, "that": true, "with": true, "have": true,
+	"from": true, "were": true, "been": true, "will": true, "what": true, "when": true,
+	"which": true, "their": true, "there": true, "would": true, "about": true, "into": true,
+	"than": true, "then": true, "them": true, "these": true, "those": true, "also": true,
+	"some": true, "such": true, "only": true, "very": true, "just": true, "more": true,
+}
+
+// contentWords returns the lower-cased words of text without stop words
+// and words shorter than three letters.
+func contentWords(text string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) >= 3 && !stopWords[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
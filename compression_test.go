@@ -0,0 +1,58 @@
+package uniai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestPromptCompressionTrim(t *testing.T) {
+	client := New(Config{})
+	p := fake.New(fake.Config{Responses: []fake.Response{fake.Text("ok", 0, 0)}})
+	client.RegisterProvider("fake", p)
+	filler := strings.Repeat("Weather was mild and people walked around the park all afternoon. ", 20)
+	doc := filler + "The invoice total for March was 4210 euros. " + filler
+	res, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(System("Answer briefly."), User(doc), User("What was the invoice total for March?")),
+		WithPromptCompression(PromptCompression{TargetTokens: 200}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := res.Compression
+	if report == nil || report.Strategy != CompressionTrim || report.Tokens > 200 || report.OriginalTokens <= 200 || len(report.Elided) == 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	sent := p.Requests()[0].Messages
+	if sent[0].Content != "Answer briefly." || !strings.Contains(sent[1].Content, "4210 euros") || sent[2].Content != "What was the invoice total for March?" {
+		t.Fatalf("unexpected messages %+v", sent)
+	}
+}
+
+func TestPromptCompressionSummarize(t *testing.T) {
+	client := New(Config{})
+	p := fake.New(fake.Config{Responses: []fake.Response{
+		fake.Text("March invoice: 4210 euros.", 0, 0),
+		fake.Text("4210 euros", 0, 0),
+	}})
+	client.RegisterProvider("fake", p)
+	doc := strings.Repeat("Long notes about the invoice. ", 100)
+	res, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User(doc), User("What was the total?")),
+		WithPromptCompression(PromptCompression{Strategy: chat.CompressionSummarize, TargetTokens: 100}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Text != "4210 euros" || res.Compression == nil || len(res.Compression.Elided) != 1 || res.Compression.Elided[0].Summary != "March invoice: 4210 euros." {
+		t.Fatalf("unexpected result %q, report %+v", res.Text, res.Compression)
+	}
+	if got := p.Requests()[1].Messages[0].Content; got != "March invoice: 4210 euros." {
+		t.Fatalf("unexpected message %q", got)
+	}
+}
@@ -1,5 +1,12 @@
 package uniai
 
+import (
+	"github.com/quailyquaily/uniai/admission"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/policy"
+	"github.com/quailyquaily/uniai/usage"
+)
+
 // Config provides shared configuration for uniai clients.
 // Fields are optional and used by specific providers/features.
 type Config struct {
@@ -20,6 +27,13 @@ type Config struct {
 	// Anthropic
 	AnthropicAPIKey string
 	AnthropicModel  string
+	// AnthropicBackend selects the transport for the anthropic provider:
+	// "anthropic" (default), "vertex" or "bedrock". The bedrock backend
+	// reuses the AWS credentials below.
+	AnthropicBackend           string
+	AnthropicVertexProjectID   string
+	AnthropicVertexRegion      string
+	AnthropicVertexAccessToken string
 
 	// AWS Bedrock
 	AwsKey             string
@@ -27,14 +41,88 @@ type Config struct {
 	AwsRegion          string
 	AwsBedrockModelArn string
 
+	// vLLM (OpenAI-compatible server)
+	VLLMAPIBase string
+	VLLMAPIKey  string
+	VLLMModel   string
+
+	// Ollama and OpenRouter are used for model listing; chat goes through
+	// openai_custom.
+	OllamaAPIBase     string
+	OpenRouterAPIBase string
+	OpenRouterAPIKey  string
+
 	// Susanoo
 	SusanooAPIBase string
 	SusanooAPIKey  string
 
+	// UsageRecorder, if set, receives the usage of every successful Chat
+	// call; see usage.Aggregator.
+	UsageRecorder usage.Recorder
+	// PriceFunc, if set, prices each provider call into
+	// chat.Result.Cost and chat.AttemptInfo.Cost.
+	PriceFunc usage.PriceFunc
+
+	// Policy, if set, is evaluated before each Chat call and may reroute,
+	// downgrade or reject it; see the policy package.
+	Policy *policy.Policy
+	// ProviderTags lists the compliance tags of each provider, keyed by
+	// provider name or by "provider/model" for model-specific tags.
+	// Requests with chat.WithRequirements are rejected with
+	// chat.ErrNonCompliant unless the serving provider has every tag.
+	ProviderTags map[string][]string
+	// StreamTransformers rewrite the stream of every Chat call, before
+	// the request's own chat.WithStreamTransformers.
+	StreamTransformers []chat.StreamTransformer
+	// Admission, if set, limits the chat and embedding calls in flight and
+	// admits interactive calls before background ones, taking turns among
+	// tenants; see the admission package.
+	Admission *admission.Controller
+	// ProviderAdmission replaces Admission for the providers it names.
+	// Give providers with separate quotas their own controllers, so a
+	// rate limit from one does not hold back calls to the others.
+	ProviderAdmission map[string]*admission.Controller
+	// Breaker, if set, opens a circuit breaker for providers that keep
+	// failing; see BreakerConfig.
+	Breaker *BreakerConfig
+	// SystemPrompt, if set, is added to the system prompt of every Chat
+	// call. ProviderSystemPrompts replace it for the providers they name.
+	SystemPrompt          *SystemPrompt
+	ProviderSystemPrompts map[string]SystemPrompt
+
+	// FineTuneProvider selects "openai" (default) or "azure" for fine-tuning.
+	FineTuneProvider string
+
+	// FilesProvider selects "openai" (default) or "anthropic" for the
+	// Files API.
+	FilesProvider string
+
 	// Embeddings / Images / Rerank / Classify
 	OpenAIEmbeddingModel      string
 	AzureOpenAIEmbeddingModel string
 	AwsBedrockEmbeddingModel  string
+	// AzureOpenAIImageDeployment is the default Azure image deployment;
+	// AzureOpenAIImageDeployments maps image model names to deployments.
+	AzureOpenAIImageDeployment  string
+	AzureOpenAIImageDeployments map[string]string
+
+	// Stability AI and Black Forest Labs (Flux) image generation
+	StabilityAPIKey  string
+	StabilityAPIBase string
+	BFLAPIKey        string
+	BFLAPIBase       string
+
+	// Runway and Luma video generation; OpenAI (Sora) reuses the OpenAI
+	// settings.
+	RunwayAPIKey  string
+	RunwayAPIBase string
+	LumaAPIKey    string
+	LumaAPIBase   string
+
+	// Deepgram streaming transcription; OpenAI realtime transcription
+	// reuses the OpenAI settings.
+	DeepgramAPIKey  string
+	DeepgramAPIBase string
 
 	JinaAPIKey    string
 	JinaAPIBase   string
@@ -61,3 +149,11 @@ func (cfg Config) withDefaults() Config {
 	}
 	return cfg
 }
+
+// admission returns the admission controller for provider, if any.
+func (cfg Config) admission(provider string) *admission.Controller {
+	if a, ok := cfg.ProviderAdmission[provider]; ok {
+		return a
+	}
+	return cfg.Admission
+}
@@ -0,0 +1,123 @@
+package uniai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+const contextSummaryPrompt = "Summarize the following conversation so it can replace the original messages. Keep facts, decisions, names and open questions. Reply with the summary only."
+
+// recoverContext retries req with a truncated history after a context-length
+// error, following req.Options.ContextRecovery. What was removed is recorded in
+// the result warnings.
+func (c *Client) recoverContext(ctx context.Context, providerName string, req *chat.Request, cause error) (*chat.Result, error) {
+	cfg := req.Options.ContextRecovery
+	retries := cfg.MaxRetries
+	if retries <= 0 {
+		retries = 1
+	}
+	ratio := cfg.DropRatio
+	if ratio <= 0 || ratio >= 1 {
+		ratio = 0.25
+	}
+
+	msgs := req.Messages
+	var warnings []string
+	for attempt := 0; attempt < retries; attempt++ {
+		n := int(math.Ceil(float64(len(msgs)-countRole(msgs, chat.RoleSystem)) * ratio))
+		kept, dropped := chat.DropOldest(msgs, n)
+		if len(dropped) == 0 {
+			break
+		}
+		warning := fmt.Sprintf("context recovery: dropped %d oldest messages (%s)", len(dropped), messageRoles(dropped))
+		if cfg.Strategy == chat.ContextStrategySummarize {
+			summary, err := c.summarizeMessages(ctx, providerName, req, dropped)
+			if err != nil {
+				warning += fmt.Sprintf("; summary failed: %v", err)
+			} else {
+				kept = insertAfterSystem(kept, chat.System("Summary of the earlier conversation:\n"+summary))
+				warning = fmt.Sprintf("context recovery: summarized %d oldest messages (%s)", len(dropped), messageRoles(dropped))
+			}
+		}
+		warnings = append(warnings, warning)
+		msgs = kept
+
+		next := *req
+		next.Messages = msgs
+		next.Options.ContextRecovery = nil
+		resp, err := c.chatWithTools(ctx, providerName, &next)
+		if err == nil {
+			resp.Warnings = append(warnings, resp.Warnings...)
+			return resp, nil
+		}
+		if !chat.IsContextLengthError(err) {
+			return nil, err
+		}
+	}
+	return nil, errors.Join(cause, fmt.Errorf("context recovery failed: %s", strings.Join(warnings, "; ")))
+}
+
+func (c *Client) summarizeMessages(ctx context.Context, providerName string, req *chat.Request, msgs []chat.Message) (string, error) {
+	var transcript strings.Builder
+	for _, msg := range msgs {
+		transcript.WriteString(msg.Role)
+		transcript.WriteString(": ")
+		transcript.WriteString(msg.Content)
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&transcript, " [call %s(%s)]", call.Function.Name, call.Function.Arguments)
+		}
+		transcript.WriteString("\n")
+	}
+	summaryReq := &chat.Request{
+		Provider: req.Provider,
+		Model:    req.Model,
+		Messages: []chat.Message{
+			chat.System(contextSummaryPrompt),
+			chat.User(transcript.String()),
+		},
+		Options: chat.Options{DebugFn: req.Options.DebugFn, NoStore: req.Options.NoStore, Requirements: req.Options.Requirements},
+	}
+	resp, err := c.chatOnce(ctx, providerName, summaryReq)
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(resp.Text)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
+
+func insertAfterSystem(msgs []chat.Message, msg chat.Message) []chat.Message {
+	pos := 0
+	for pos < len(msgs) && msgs[pos].Role == chat.RoleSystem {
+		pos++
+	}
+	out := make([]chat.Message, 0, len(msgs)+1)
+	out = append(out, msgs[:pos]...)
+	out = append(out, msg)
+	return append(out, msgs[pos:]...)
+}
+
+func countRole(msgs []chat.Message, role string) int {
+	count := 0
+	for _, msg := range msgs {
+		if msg.Role == role {
+			count++
+		}
+	}
+	return count
+}
+
+func messageRoles(msgs []chat.Message) string {
+	roles := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		roles = append(roles, msg.Role)
+	}
+	return strings.Join(roles, ", ")
+}
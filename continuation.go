@@ -0,0 +1,75 @@
+package uniai
+
+import (
+	"fmt"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+const defaultContinuePrompt = "Continue exactly where you left off. Do not repeat anything you have already written."
+
+// autoContinue re-issues req while resp was cut off by the output limit and
+// stitches the continuation into resp. Tool calls end the loop since they are
+// not truncated text.
+func autoContinue(req *chat.Request, resp *chat.Result, call func(*chat.Request) (*chat.Result, error)) (*chat.Result, error) {
+	cfg := req.Options.AutoContinue
+	if cfg == nil || resp == nil {
+		return resp, nil
+	}
+	prompt := cfg.Prompt
+	if prompt == "" {
+		prompt = defaultContinuePrompt
+	}
+	maxRounds := cfg.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = chat.DefaultAutoContinueRounds
+	}
+
+	out := *resp
+	cost := 0.0
+	if cfg.CostFn != nil {
+		cost = cfg.CostFn(out.Model, out.Usage)
+	}
+	rounds := 0
+	for out.FinishReason == chat.FinishReasonLength && len(out.ToolCalls) == 0 {
+		if rounds >= maxRounds {
+			out.Warnings = append(out.Warnings, fmt.Sprintf("auto-continue: output still truncated after %d rounds", rounds))
+			break
+		}
+		if cfg.MaxTotalTokens > 0 && out.Usage.TotalTokens >= cfg.MaxTotalTokens {
+			out.Warnings = append(out.Warnings, fmt.Sprintf("auto-continue: stopped at token cap %d", cfg.MaxTotalTokens))
+			break
+		}
+		if cfg.MaxCost > 0 && cfg.CostFn != nil && cost >= cfg.MaxCost {
+			out.Warnings = append(out.Warnings, fmt.Sprintf("auto-continue: stopped at cost cap %g", cfg.MaxCost))
+			break
+		}
+		rounds++
+
+		next := *req
+		next.Messages = append(append([]chat.Message{}, req.Messages...), chat.Assistant(out.Text), chat.User(prompt))
+		next.Tools = nil
+		next.ToolChoice = nil
+		next.Options.AutoContinue = nil
+		if cfg.MaxTotalTokens > 0 {
+			remaining := cfg.MaxTotalTokens - out.Usage.TotalTokens
+			if next.Options.MaxTokens == nil || *next.Options.MaxTokens > remaining {
+				next.Options.MaxTokens = &remaining
+			}
+		}
+
+		part, err := call(&next)
+		if err != nil {
+			return nil, fmt.Errorf("auto-continue round %d: %w", rounds, err)
+		}
+		out.Text += part.Text
+		out.Messages = nil // rebuilt from the stitched text by Client.Chat
+		out.FinishReason = part.FinishReason
+		out.Usage.Add(part.Usage)
+		out.Warnings = append(out.Warnings, part.Warnings...)
+		if cfg.CostFn != nil {
+			cost += cfg.CostFn(part.Model, part.Usage)
+		}
+	}
+	return &out, nil
+}
@@ -0,0 +1,83 @@
+package uniai
+
+import (
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestAutoContinueStitchesOutput(t *testing.T) {
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("write a long story")),
+		chat.WithAutoContinue(chat.AutoContinue{MaxRounds: 3}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parts := []*chat.Result{
+		{Text: " middle", FinishReason: chat.FinishReasonLength, Usage: chat.Usage{TotalTokens: 10}},
+		{Text: " end", FinishReason: chat.FinishReasonStop, Usage: chat.Usage{TotalTokens: 10}},
+	}
+	calls := 0
+	first := &chat.Result{Text: "start", FinishReason: chat.FinishReasonLength, Usage: chat.Usage{TotalTokens: 10}}
+	out, err := autoContinue(req, first, func(next *chat.Request) (*chat.Result, error) {
+		if last := next.Messages[len(next.Messages)-2]; last.Role != chat.RoleAssistant {
+			t.Fatalf("expected assistant prefix before continue prompt, got %+v", last)
+		}
+		calls++
+		return parts[calls-1], nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Text != "start middle end" || out.FinishReason != chat.FinishReasonStop || out.Usage.TotalTokens != 30 {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+}
+
+func TestAutoContinueTokenCap(t *testing.T) {
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("hi")),
+		chat.WithAutoContinue(chat.AutoContinue{MaxRounds: 5, MaxTotalTokens: 15}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := &chat.Result{Text: "a", FinishReason: chat.FinishReasonLength, Usage: chat.Usage{TotalTokens: 10}}
+	calls := 0
+	out, err := autoContinue(req, first, func(next *chat.Request) (*chat.Result, error) {
+		calls++
+		if next.Options.MaxTokens == nil || *next.Options.MaxTokens != 5 {
+			t.Fatalf("expected max tokens clamped to remaining budget")
+		}
+		return &chat.Result{Text: "b", FinishReason: chat.FinishReasonLength, Usage: chat.Usage{TotalTokens: 10}}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 || out.Text != "ab" || len(out.Warnings) != 1 {
+		t.Fatalf("unexpected result after cap: calls=%d %+v", calls, out)
+	}
+}
+
+func TestAutoContinueDefaultRounds(t *testing.T) {
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("hi")),
+		chat.WithAutoContinue(chat.AutoContinue{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	calls := 0
+	first := &chat.Result{Text: "a", FinishReason: chat.FinishReasonLength}
+	out, err := autoContinue(req, first, func(next *chat.Request) (*chat.Result, error) {
+		calls++
+		return &chat.Result{Text: "a", FinishReason: chat.FinishReasonLength}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != chat.DefaultAutoContinueRounds || len(out.Warnings) != 1 {
+		t.Fatalf("expected %d rounds and a warning, got %d rounds: %+v", chat.DefaultAutoContinueRounds, calls, out)
+	}
+}
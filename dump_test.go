@@ -0,0 +1,58 @@
+package uniai
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestDumpSaveAndReplay(t *testing.T) {
+	client := New(Config{SystemPrompt: &SystemPrompt{Prefix: "Be brief."}})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{fake.Text("hello there", 3, 0)}}))
+
+	var d chat.Dump
+	resp, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithModel("m1"),
+		WithMessages(User("hi")),
+		WithOnStream(func(chat.StreamEvent) error { return nil }),
+		WithDebugFn(func(label, payload string) {}),
+		WithDump(&d),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "dump.json")
+	if err := d.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := chat.LoadDump(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Provider != "fake" || loaded.Model != "m1" || len(loaded.Request.Messages) != 1 || loaded.Result.Text != resp.Text || len(loaded.Attempts) != 1 {
+		t.Fatalf("dump: %+v", loaded)
+	}
+	if len(loaded.Decisions) != 1 || loaded.Decisions[0].Stage != "system_prompt" {
+		t.Fatalf("decisions: %+v", loaded.Decisions)
+	}
+	if n := len(loaded.Events); n != 5 || !loaded.Events[n-1].Event.Done {
+		t.Fatalf("events: %+v", loaded.Events)
+	}
+
+	offline := New(Config{})
+	offline.RegisterProvider(loaded.Provider, loaded.Replay())
+	var streamed strings.Builder
+	replayed, err := offline.Chat(context.Background(), append(loaded.Options(), WithOnToken(func(delta string) { streamed.WriteString(delta) }))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Text != "hello there" || streamed.String() != "hello there" {
+		t.Fatalf("replayed %q, streamed %q", replayed.Text, streamed.String())
+	}
+}
@@ -0,0 +1,204 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// providerBatchLimits is the maximum number of inputs per request.
+var providerBatchLimits = map[string]int{
+	"openai": 2048,
+	"jina":   2048,
+	"gemini": 100,
+}
+
+// BatchConfig controls CreateBatch.
+type BatchConfig struct {
+	// BatchSize caps inputs per request; it defaults to the provider limit.
+	BatchSize int
+	// Concurrency is the number of requests in flight (default 4).
+	Concurrency int
+	// RequestsPerMinute throttles request starts; 0 disables throttling.
+	RequestsPerMinute int
+	// Cache, if set, is consulted before and filled after each request.
+	Cache Cache
+}
+
+// CreateBatch embeds any number of inputs by splitting them into provider
+// sized batches submitted concurrently under the rate limit. Cached inputs
+// are not sent. Result.Data is ordered and indexed by input position, and
+// usage is summed over all requests.
+func (c *Client) CreateBatch(ctx context.Context, cfg BatchConfig, opts ...Option) (*Result, error) {
+	req := BuildRequest(opts...)
+	provider := req.Provider
+	if provider == "" {
+		provider = pickProviderByModel(req.Model)
+	}
+	return createBatch(ctx, provider, req, cfg, func(ctx context.Context, batch *Request) (*Result, error) {
+		batch.Provider = provider
+		return c.Create(ctx, withRequest(batch))
+	})
+}
+
+func withRequest(src *Request) Option {
+	return func(r *Request) { *r = *src }
+}
+
+type createFunc func(ctx context.Context, req *Request) (*Result, error)
+
+func createBatch(ctx context.Context, provider string, req *Request, cfg BatchConfig, create createFunc) (*Result, error) {
+	for i, in := range req.Input {
+		if in.Text == "" && in.Image == "" {
+			return nil, fmt.Errorf("embedding input %d is empty", i)
+		}
+	}
+	size := cfg.BatchSize
+	if limit := providerBatchLimits[provider]; limit > 0 && (size <= 0 || size > limit) {
+		size = limit
+	}
+	if size <= 0 {
+		size = 100
+	}
+	workers := cfg.Concurrency
+	if workers <= 0 {
+		workers = 4
+	}
+
+	out := &Result{Object: "list", Model: req.Model, Data: make([]Item, len(req.Input))}
+	keys := make([]string, len(req.Input))
+	var pending []int
+	for i, in := range req.Input {
+		out.Data[i] = Item{Object: "embedding", Index: i}
+		if cfg.Cache != nil {
+			keys[i] = CacheKey(provider, req.Model, req.Options, in)
+			if emb, ok := cfg.Cache.Get(keys[i]); ok {
+				out.Data[i].Embedding = emb
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+
+	var batches [][]int
+	for start := 0; start < len(pending); start += size {
+		end := min(start+size, len(pending))
+		batches = append(batches, pending[start:end])
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	limiter := newRateLimiter(cfg.RequestsPerMinute)
+	jobs := make(chan []int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				if err := limiter.wait(ctx); err != nil {
+					fail(err)
+					continue
+				}
+				sub := *req
+				sub.Input = make([]Input, len(batch))
+				for j, idx := range batch {
+					sub.Input[j] = req.Input[idx]
+				}
+				res, err := create(ctx, &sub)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				if len(res.Data) != len(batch) {
+					fail(fmt.Errorf("embedding batch returned %d items for %d inputs", len(res.Data), len(batch)))
+					continue
+				}
+				mu.Lock()
+				for j, item := range res.Data {
+					pos := j
+					if item.Index >= 0 && item.Index < len(batch) {
+						pos = item.Index
+					}
+					idx := batch[pos]
+					out.Data[idx].Embedding = item.Embedding
+					if cfg.Cache != nil {
+						cfg.Cache.Set(keys[idx], item.Embedding)
+					}
+				}
+				if res.Model != "" {
+					out.Model = res.Model
+				}
+				out.Usage.PromptTokens += res.Usage.PromptTokens
+				out.Usage.TotalTokens += res.Usage.TotalTokens
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, batch := range batches {
+		select {
+		case jobs <- batch:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return out, nil
+}
+
+// rateLimiter spaces request starts evenly to stay under a per-minute budget.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	if perMinute <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	slot := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
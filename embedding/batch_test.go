@@ -0,0 +1,50 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCreateBatchSplitsAndCaches(t *testing.T) {
+	var calls atomic.Int32
+	create := func(ctx context.Context, req *Request) (*Result, error) {
+		calls.Add(1)
+		if len(req.Input) > 2 {
+			return nil, fmt.Errorf("batch too large: %d", len(req.Input))
+		}
+		res := &Result{Model: req.Model}
+		for i, in := range req.Input {
+			res.Data = append(res.Data, Item{Embedding: "emb-" + in.Text, Index: i})
+		}
+		res.Usage.TotalTokens = len(req.Input)
+		return res, nil
+	}
+	req := BuildRequest(Embedding("m", "a", "b", "c", "d", "e"))
+	cache := NewLRUCache(10)
+	cfg := BatchConfig{BatchSize: 2, Concurrency: 2, Cache: cache}
+
+	out, err := createBatch(context.Background(), "openai", req, cfg, create)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 3 || out.Usage.TotalTokens != 5 {
+		t.Fatalf("unexpected calls=%d usage=%d", calls.Load(), out.Usage.TotalTokens)
+	}
+	for i, want := range []string{"a", "b", "c", "d", "e"} {
+		if out.Data[i].Embedding != "emb-"+want || out.Data[i].Index != i {
+			t.Fatalf("item %d mismatch: %+v", i, out.Data[i])
+		}
+	}
+
+	calls.Store(0)
+	req = BuildRequest(Embedding("m", "a", "f"))
+	out, err = createBatch(context.Background(), "openai", req, cfg, create)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 1 || out.Data[0].Embedding != "emb-a" || out.Data[1].Embedding != "emb-f" {
+		t.Fatalf("expected cache hit for a: calls=%d %+v", calls.Load(), out.Data)
+	}
+}
@@ -0,0 +1,145 @@
+package embedding
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/quailyquaily/uniai/encryption"
+)
+
+// Cache stores base64-encoded embeddings by key. Implementations must be safe
+// for concurrent use.
+type Cache interface {
+	Get(key string) (string, bool)
+	Set(key, embedding string)
+}
+
+// CacheKey identifies the embedding of input under model and provider options.
+func CacheKey(provider, model string, options Options, input Input) string {
+	opts, _ := json.Marshal(options)
+	h := sha256.New()
+	for _, part := range []string{provider, model, string(opts), input.Text, input.Image} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LRUCache is an in-memory Cache that evicts the least recently used entry
+// once it holds Size entries.
+type LRUCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value string
+}
+
+func NewLRUCache(size int) *LRUCache {
+	if size <= 0 {
+		size = 10000
+	}
+	return &LRUCache{size: size, order: list.New(), items: map[string]*list.Element{}}
+}
+
+func (c *LRUCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+func (c *LRUCache) Set(key, embedding string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry).value = embedding
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: embedding})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// FileCache is a persistent Cache storing one file per key under Dir.
+type FileCache struct {
+	// Sealer, if set, encrypts the entries. Entries that fail to decrypt,
+	// such as ones written without it, are misses.
+	Sealer *encryption.Sealer
+
+	dir string
+}
+
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+func (c *FileCache) path(key string) string {
+	if len(key) > 2 {
+		return filepath.Join(c.dir, key[:2], key)
+	}
+	return filepath.Join(c.dir, key)
+}
+
+func (c *FileCache) Get(key string) (string, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return "", false
+	}
+	if c.Sealer != nil {
+		if data, err = c.Sealer.Open(context.Background(), data, []byte(key)); err != nil {
+			return "", false
+		}
+	}
+	return string(data), true
+}
+
+// Set writes the entry atomically; write errors are ignored since the cache
+// is best effort.
+func (c *FileCache) Set(key, embedding string) {
+	data := []byte(embedding)
+	if c.Sealer != nil {
+		var err error
+		if data, err = c.Sealer.Seal(context.Background(), data, []byte(key)); err != nil {
+			return
+		}
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".tmp*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -18,6 +18,9 @@ type Config struct {
 	OpenAIAPIBase string
 	GeminiAPIKey  string
 	GeminiAPIBase string
+	// Admit, if set, is called before each provider request and must
+	// return a function to call with the request's error when it ends.
+	Admit func(ctx context.Context, provider string) (release func(err error), err error)
 }
 
 type Client struct {
@@ -37,11 +40,18 @@ func (c *Client) Create(ctx context.Context, opts ...Option) (*Result, error) {
 	if provider == "" {
 		return nil, fmt.Errorf("provider not set")
 	}
-
 	var (
 		respData []byte
 		err      error
 	)
+	if c.cfg.Admit != nil {
+		release, admitErr := c.cfg.Admit(ctx, provider)
+		if admitErr != nil {
+			return nil, admitErr
+		}
+		// err is the provider error assigned below
+		defer func() { release(err) }()
+	}
 	switch provider {
 	case "jina":
 		respData, err = jina.CreateEmbeddings(ctx, c.cfg.JinaAPIKey, c.cfg.JinaAPIBase, req.Model, toJinaInputs(req.Input), req.Options.Jina)
@@ -23,17 +23,21 @@ type Request struct {
 type Result struct {
 	Model  string `json:"model"`
 	Object string `json:"object"`
-	Data   []struct {
-		Object    string `json:"object"`
-		Embedding string `json:"embedding"`
-		Index     int    `json:"index"`
-	} `json:"data"`
-	Usage struct {
+	Data   []Item `json:"data"`
+	Usage  struct {
 		PromptTokens int `json:"prompt_tokens"`
 		TotalTokens  int `json:"total_tokens"`
 	} `json:"usage"`
 }
 
+// Item is a single embedding. Embedding holds the base64-encoded vector and
+// Index the position of the input it belongs to.
+type Item struct {
+	Object    string `json:"object"`
+	Embedding string `json:"embedding"`
+	Index     int    `json:"index"`
+}
+
 type Option func(*Request)
 
 func BuildRequest(opts ...Option) *Request {
@@ -0,0 +1,217 @@
+// Package encryption seals data at rest with AES-GCM for the persistent
+// stores (jobs.DirStore, attachment.DirStore and embedding.FileCache), since
+// transcripts and documents often hold sensitive data.
+//
+// The key is either given directly or managed by a KMS: with a KMS each
+// Sealer generates a data key, keeps it in memory, and stores it wrapped by
+// the KMS next to the data it encrypts (envelope encryption), so the master
+// key never leaves the KMS.
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNotSealed is returned by Open for data not sealed by a Sealer.
+var ErrNotSealed = errors.New("data is not encrypted")
+
+// KMS wraps and unwraps data keys. Adapters for cloud key management
+// services implement it.
+type KMS interface {
+	// GenerateDataKey returns a new 256-bit key and its wrapped form.
+	GenerateDataKey(ctx context.Context) (key, wrapped []byte, err error)
+	// DecryptDataKey unwraps a key returned by GenerateDataKey.
+	DecryptDataKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// magic starts sealed data, followed by a mode byte.
+var magic = []byte("uenc")
+
+const (
+	modeKey byte = 1
+	modeKMS byte = 2
+)
+
+// Sealer encrypts and decrypts data. It is safe for concurrent use.
+type Sealer struct {
+	aead cipher.AEAD
+	kms  KMS
+
+	mu      sync.Mutex
+	wrapped []byte
+	dataKey cipher.AEAD
+	// keys caches unwrapped data keys by their wrapped form.
+	keys map[string]cipher.AEAD
+}
+
+// New returns a Sealer using key, which must be 16, 24 or 32 bytes long for
+// AES-128, AES-192 or AES-256.
+func New(key []byte) (*Sealer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// NewKMS returns a Sealer using data keys from kms.
+func NewKMS(kms KMS) *Sealer {
+	return &Sealer{kms: kms, keys: map[string]cipher.AEAD{}}
+}
+
+// Seal encrypts plaintext. additional is authenticated but not stored;
+// stores pass the record's ID, so a sealed record cannot be swapped for
+// another, and the same value must be passed to Open.
+func (s *Sealer) Seal(ctx context.Context, plaintext, additional []byte) ([]byte, error) {
+	out := append([]byte{}, magic...)
+	aead := s.aead
+	if s.kms != nil {
+		var wrapped []byte
+		var err error
+		aead, wrapped, err = s.currentKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, modeKMS)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(wrapped)))
+		out = append(out, wrapped...)
+	} else {
+		out = append(out, modeKey)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, additional), nil
+}
+
+// Open decrypts data returned by Seal.
+func (s *Sealer) Open(ctx context.Context, data, additional []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return nil, ErrNotSealed
+	}
+	mode, rest := data[len(magic)], data[len(magic)+1:]
+	var aead cipher.AEAD
+	switch {
+	case mode == modeKey && s.kms == nil:
+		aead = s.aead
+	case mode == modeKMS && s.kms != nil:
+		if len(rest) < 2 || len(rest) < 2+int(binary.BigEndian.Uint16(rest)) {
+			return nil, fmt.Errorf("encrypted data is truncated")
+		}
+		n := int(binary.BigEndian.Uint16(rest))
+		var err error
+		aead, err = s.key(ctx, rest[2:2+n])
+		if err != nil {
+			return nil, err
+		}
+		rest = rest[2+n:]
+	default:
+		return nil, fmt.Errorf("data was encrypted with a different kind of key")
+	}
+	if len(rest) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], additional)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// IsSealed reports whether data looks like the output of Seal.
+func IsSealed(data []byte) bool {
+	return len(data) > len(magic) && bytes.HasPrefix(data, magic)
+}
+
+// currentKey returns the data key new data is sealed with, generating it on
+// first use.
+func (s *Sealer) currentKey(ctx context.Context) (cipher.AEAD, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dataKey == nil {
+		key, wrapped, err := s.kms.GenerateDataKey(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("generate data key: %w", err)
+		}
+		if len(wrapped) > 0xffff {
+			return nil, nil, fmt.Errorf("wrapped data key is too long")
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.dataKey, s.wrapped = aead, wrapped
+		s.keys[string(wrapped)] = aead
+	}
+	return s.dataKey, s.wrapped, nil
+}
+
+// key unwraps a stored data key, once per key.
+func (s *Sealer) key(ctx context.Context, wrapped []byte) (cipher.AEAD, error) {
+	s.mu.Lock()
+	aead, ok := s.keys[string(wrapped)]
+	s.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+	key, err := s.kms.DecryptDataKey(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data key: %w", err)
+	}
+	aead, err = newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.keys[string(wrapped)] = aead
+	s.mu.Unlock()
+	return aead, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// LocalKMS is a KMS wrapping data keys with a master key held in process,
+// for tests and deployments without a key management service.
+type LocalKMS struct {
+	master *Sealer
+}
+
+func NewLocalKMS(master []byte) (*LocalKMS, error) {
+	s, err := New(master)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKMS{master: s}, nil
+}
+
+func (k *LocalKMS) GenerateDataKey(ctx context.Context) (key, wrapped []byte, err error) {
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err = k.master.Seal(ctx, key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, wrapped, nil
+}
+
+func (k *LocalKMS) DecryptDataKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return k.master.Open(ctx, wrapped, nil)
+}
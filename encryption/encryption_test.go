@@ -0,0 +1,81 @@
+package encryption_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quailyquaily/uniai/attachment"
+	"github.com/quailyquaily/uniai/encryption"
+)
+
+func TestSealer(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{7}, 32)
+	kms, err := encryption.NewLocalKMS(key)
+	if err != nil {
+		t.Fatalf("kms: %v", err)
+	}
+	withKey, err := encryption.New(key)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	for name, s := range map[string]*encryption.Sealer{"key": withKey, "kms": encryption.NewKMS(kms)} {
+		sealed, err := s.Seal(ctx, []byte("secret transcript"), []byte("job-1"))
+		if err != nil {
+			t.Fatalf("%s: seal: %v", name, err)
+		}
+		if bytes.Contains(sealed, []byte("secret")) || !encryption.IsSealed(sealed) {
+			t.Fatalf("%s: not encrypted: %q", name, sealed)
+		}
+		plain, err := s.Open(ctx, sealed, []byte("job-1"))
+		if err != nil || string(plain) != "secret transcript" {
+			t.Fatalf("%s: open: %q, %v", name, plain, err)
+		}
+		if _, err := s.Open(ctx, sealed, []byte("job-2")); err == nil {
+			t.Fatalf("%s: opened with the wrong id", name)
+		}
+		if _, err := s.Open(ctx, []byte("{}"), nil); !errors.Is(err, encryption.ErrNotSealed) {
+			t.Fatalf("%s: expected ErrNotSealed, got %v", name, err)
+		}
+	}
+	// a new sealer with the same KMS reads data sealed under another data key
+	sealed, _ := encryption.NewKMS(kms).Seal(ctx, []byte("x"), nil)
+	if plain, err := encryption.NewKMS(kms).Open(ctx, sealed, nil); err != nil || string(plain) != "x" {
+		t.Fatalf("open with new sealer: %q, %v", plain, err)
+	}
+	if _, err := encryption.New([]byte("short")); err == nil {
+		t.Fatalf("expected key length error")
+	}
+}
+
+func TestSealedDirStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := attachment.NewDirStore(dir)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	s.Sealer, _ = encryption.New(bytes.Repeat([]byte{1}, 16))
+	ref, err := s.Put(ctx, []byte("patient notes"), "text/plain")
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	data, got, err := s.Get(ctx, ref.Digest)
+	if err != nil || string(data) != "patient notes" || got.MIMEType != "text/plain" {
+		t.Fatalf("get: %q %+v, %v", data, got, err)
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if len(paths) != 2 {
+		t.Fatalf("expected content and metadata files, got %v", paths)
+	}
+	for _, path := range paths {
+		raw, _ := os.ReadFile(path)
+		if bytes.Contains(raw, []byte("patient")) || bytes.Contains(raw, []byte("text/plain")) {
+			t.Fatalf("%s stored in the clear", path)
+		}
+	}
+}
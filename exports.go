@@ -11,21 +11,52 @@ import (
 
 // Chat re-exports
 type (
-	ChatOption         = chat.Option
-	ChatRequest        = chat.Request
-	ChatResult         = chat.Result
-	ChatOptions        = chat.Options
-	Message            = chat.Message
-	Tool               = chat.Tool
-	ToolFunction       = chat.ToolFunction
-	ToolChoice         = chat.ToolChoice
-	ToolCall           = chat.ToolCall
-	ToolCallFunction   = chat.ToolCallFunction
-	DebugFn            = chat.DebugFn
-	ToolsEmulationMode = chat.ToolsEmulationMode
-	OnStreamFunc       = chat.OnStreamFunc
-	StreamEvent        = chat.StreamEvent
-	ToolCallDelta      = chat.ToolCallDelta
+	ChatOption          = chat.Option
+	ChatRequest         = chat.Request
+	ChatResult          = chat.Result
+	ChatOptions         = chat.Options
+	AttemptInfo         = chat.AttemptInfo
+	Message             = chat.Message
+	MessageImage        = chat.Image
+	MessageVideo        = chat.Video
+	Tool                = chat.Tool
+	ToolFunction        = chat.ToolFunction
+	ToolExample         = chat.ToolExample
+	ToolChoice          = chat.ToolChoice
+	ToolCall            = chat.ToolCall
+	ToolCallFunction    = chat.ToolCallFunction
+	DebugFn             = chat.DebugFn
+	ToolsEmulationMode  = chat.ToolsEmulationMode
+	AutoContinue        = chat.AutoContinue
+	ContextRecovery     = chat.ContextRecovery
+	PromptCompression   = chat.PromptCompression
+	CompressionReport   = chat.CompressionReport
+	ParamNormalization  = chat.ParamNormalization
+	Grammar             = chat.Grammar
+	Glossary            = chat.Glossary
+	GlossaryTerm        = chat.GlossaryTerm
+	OnStreamFunc        = chat.OnStreamFunc
+	OnTokenFunc         = chat.OnTokenFunc
+	OnEventFunc         = chat.OnEventFunc
+	StreamEvent         = chat.StreamEvent
+	StreamChunk         = chat.StreamChunk
+	ToolCallDelta       = chat.ToolCallDelta
+	ToolCallAccumulator = chat.ToolCallAccumulator
+	StreamTee           = chat.StreamTee
+	StreamRecorder      = chat.StreamRecorder
+	RecordedEvent       = chat.RecordedEvent
+	WebSearch           = chat.WebSearch
+	CodeExecution       = chat.CodeExecution
+	CodeExecutionResult = chat.CodeExecutionResult
+	PostProcessor       = chat.PostProcessor
+	Citation            = chat.Citation
+	Span                = chat.Span
+	Source              = chat.Source
+	StreamPacing        = chat.StreamPacing
+	StreamTransformer   = chat.StreamTransformer
+	ItemError           = chat.ItemError
+	BatchResult         = chat.BatchResult
+	Dump                = chat.Dump
 )
 
 const (
@@ -35,6 +66,32 @@ const (
 	RoleTool      = chat.RoleTool
 )
 
+const (
+	MetadataConfidence = chat.MetadataConfidence
+	MetadataReasoning  = chat.MetadataReasoning
+)
+
+const (
+	TagEU         = chat.TagEU
+	TagUS         = chat.TagUS
+	TagNoTraining = chat.TagNoTraining
+	TagHIPAA      = chat.TagHIPAA
+	TagZDR        = chat.TagZDR
+)
+
+var ErrNonCompliant = chat.ErrNonCompliant
+
+const (
+	ParamNormalizationClamp = chat.ParamNormalizationClamp
+	ParamNormalizationScale = chat.ParamNormalizationScale
+	ParamNormalizationOff   = chat.ParamNormalizationOff
+)
+
+const (
+	CompressionTrim      = chat.CompressionTrim
+	CompressionSummarize = chat.CompressionSummarize
+)
+
 const (
 	ToolsEmulationOff      = chat.ToolsEmulationOff
 	ToolsEmulationFallback = chat.ToolsEmulationFallback
@@ -54,11 +111,78 @@ func WithStopWords(stops ...string) ChatOption       { return chat.WithStopWords
 func WithPresencePenalty(v float64) ChatOption       { return chat.WithPresencePenalty(v) }
 func WithFrequencyPenalty(v float64) ChatOption      { return chat.WithFrequencyPenalty(v) }
 func WithUser(user string) ChatOption                { return chat.WithUser(user) }
+func WithLogitBias(bias map[string]float64) ChatOption {
+	return chat.WithLogitBias(bias)
+}
 func WithToolsEmulationMode(mode ToolsEmulationMode) ChatOption {
 	return chat.WithToolsEmulationMode(mode)
 }
+func WithToolsEmulationConfidence() ChatOption { return chat.WithToolsEmulationConfidence() }
+func WithRequirements(tags ...string) ChatOption {
+	return chat.WithRequirements(tags...)
+}
+
+func WithProviderTags(tags map[string][]string) ChatOption {
+	return chat.WithProviderTags(tags)
+}
+func WithNoStore() ChatOption         { return chat.WithNoStore() }
+func WithoutSystemPrompt() ChatOption { return chat.WithoutSystemPrompt() }
+func WithDump(d *Dump) ChatOption     { return chat.WithDump(d) }
+func WithBetas(betas ...string) ChatOption {
+	return chat.WithBetas(betas...)
+}
+func WithPrefill(text string) ChatOption {
+	return chat.WithPrefill(text)
+}
+func WithSeparateReasoning() ChatOption {
+	return chat.WithSeparateReasoning()
+}
+func WithSources(sources ...Source) ChatOption {
+	return chat.WithSources(sources...)
+}
+func WithStreamPacing(pacing StreamPacing) ChatOption {
+	return chat.WithStreamPacing(pacing)
+}
+func WithStreamTransformers(transformers ...StreamTransformer) ChatOption {
+	return chat.WithStreamTransformers(transformers...)
+}
+func WithParallelToolCalls(parallel bool) ChatOption {
+	return chat.WithParallelToolCalls(parallel)
+}
+func WithToolTags(tags ...string) ChatOption {
+	return chat.WithToolTags(tags...)
+}
+func WithWebSearch(ws WebSearch) ChatOption {
+	return chat.WithWebSearch(ws)
+}
+func WithCodeExecution(ce CodeExecution) ChatOption {
+	return chat.WithCodeExecution(ce)
+}
+
+func WithPostProcessors(names ...string) ChatOption {
+	return chat.WithPostProcessors(names...)
+}
+func WithAutoContinue(cfg AutoContinue) ChatOption {
+	return chat.WithAutoContinue(cfg)
+}
+func WithContextRecovery(cfg ContextRecovery) ChatOption {
+	return chat.WithContextRecovery(cfg)
+}
+
+func WithPromptCompression(cfg PromptCompression) ChatOption {
+	return chat.WithPromptCompression(cfg)
+}
+func WithParamNormalization(mode ParamNormalization) ChatOption {
+	return chat.WithParamNormalization(mode)
+}
+func WithGrammar(g Grammar) ChatOption        { return chat.WithGrammar(g) }
+func WithGlossary(g Glossary) ChatOption      { return chat.WithGlossary(g) }
 func WithOnStream(fn OnStreamFunc) ChatOption { return chat.WithOnStream(fn) }
+func WithOnToken(fn OnTokenFunc) ChatOption   { return chat.WithOnToken(fn) }
+func WithOnEvent(fn OnEventFunc) ChatOption   { return chat.WithOnEvent(fn) }
 func WithDebugFn(fn DebugFn) ChatOption       { return chat.WithDebugFn(fn) }
+func WithDropRaw() ChatOption                 { return chat.WithDropRaw() }
+func WithLeanResult() ChatOption              { return chat.WithLeanResult() }
 func WithOpenAIOptions(opts structs.JSONMap) ChatOption {
 	return chat.WithOpenAIOptions(opts)
 }
@@ -74,6 +198,9 @@ func WithBedrockOptions(opts structs.JSONMap) ChatOption {
 func WithSusanooOptions(opts structs.JSONMap) ChatOption {
 	return chat.WithSusanooOptions(opts)
 }
+func WithVLLMOptions(opts structs.JSONMap) ChatOption {
+	return chat.WithVLLMOptions(opts)
+}
 func WithTools(tools []Tool) ChatOption           { return chat.WithTools(tools) }
 func WithToolChoice(choice ToolChoice) ChatOption { return chat.WithToolChoice(choice) }
 
@@ -81,6 +208,17 @@ func System(text string) Message                    { return chat.System(text) }
 func User(text string) Message                      { return chat.User(text) }
 func Assistant(text string) Message                 { return chat.Assistant(text) }
 func ToolResult(toolCallID, content string) Message { return chat.ToolResult(toolCallID, content) }
+func UserWithImages(text string, images ...MessageImage) Message {
+	return chat.UserWithImages(text, images...)
+}
+func UserWithVideos(text string, videos ...MessageVideo) Message {
+	return chat.UserWithVideos(text, videos...)
+}
+
+// Handoff adapts messages for a different provider; see chat.Handoff.
+func Handoff(messages []Message, provider string) ([]Message, []string) {
+	return chat.Handoff(messages, provider)
+}
 
 func ToolChoiceAuto() ToolChoice                { return chat.ToolChoiceAuto() }
 func ToolChoiceNone() ToolChoice                { return chat.ToolChoiceNone() }
@@ -93,10 +231,13 @@ func FunctionTool(name, description string, paramsJSON []byte) Tool {
 
 // Embedding re-exports
 type (
-	EmbeddingOption  = embedding.Option
-	EmbeddingRequest = embedding.Request
-	EmbeddingInput   = embedding.Input
-	EmbeddingResult  = embedding.Result
+	EmbeddingOption      = embedding.Option
+	EmbeddingRequest     = embedding.Request
+	EmbeddingInput       = embedding.Input
+	EmbeddingResult      = embedding.Result
+	EmbeddingItem        = embedding.Item
+	EmbeddingBatchConfig = embedding.BatchConfig
+	EmbeddingCache       = embedding.Cache
 )
 
 func Embedding(model string, texts ...string) EmbeddingOption {
@@ -119,6 +260,12 @@ func Image(model, prompt string) ImageOption          { return image.Image(model
 func WithImageProvider(provider string) ImageOption   { return image.WithProvider(provider) }
 func WithCount(count int) ImageOption                 { return image.WithCount(count) }
 func WithImageOptions(opts image.Options) ImageOption { return image.WithOptions(opts) }
+func WithImageInput(data []byte) ImageOption {
+	return image.WithImage(data)
+}
+func WithImageMask(mask []byte) ImageOption {
+	return image.WithMask(mask)
+}
 
 // Rerank re-exports
 type (
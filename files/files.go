@@ -0,0 +1,334 @@
+// Package files manages files stored with a provider's Files API, so they
+// can be referenced by ID in later requests instead of being sent again.
+// Anthropic files can be placed in the code execution sandbox with
+// chat.CodeExecution.FileIDs, and files the sandbox creates are downloaded
+// with Content.
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+const (
+	DefaultAnthropicAPIBase = "https://api.anthropic.com"
+	defaultOpenAIAPIBase    = "https://api.openai.com/v1"
+
+	anthropicAPIVersion = "2023-06-01"
+	anthropicFilesBeta  = "files-api-2025-04-14"
+)
+
+type Config struct {
+	// Provider is used when a call does not set one: "openai" (default)
+	// or "anthropic".
+	Provider string
+
+	OpenAIAPIKey  string
+	OpenAIAPIBase string
+
+	AnthropicAPIKey  string
+	AnthropicAPIBase string
+}
+
+// File describes a stored file.
+type File struct {
+	ID        string    `json:"id"`
+	Provider  string    `json:"provider"`
+	Name      string    `json:"name"`
+	MIMEType  string    `json:"mime_type,omitempty"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	// Purpose is the OpenAI file purpose.
+	Purpose string `json:"purpose,omitempty"`
+	// Downloadable reports whether Content may fetch the file. Anthropic
+	// only allows downloading files created by tools such as code
+	// execution.
+	Downloadable bool `json:"downloadable"`
+}
+
+// List is a page of files.
+type List struct {
+	Files []File `json:"files"`
+	// HasMore is set when there are more files after the last one; pass
+	// its ID to WithAfter for the next page.
+	HasMore bool `json:"has_more"`
+}
+
+type Request struct {
+	Provider string `json:"provider,omitempty"`
+	// MIMEType of an upload; it is guessed from the content when empty.
+	MIMEType string `json:"mime_type,omitempty"`
+	// Purpose of an OpenAI upload (default "user_data").
+	Purpose string `json:"purpose,omitempty"`
+	Limit   int    `json:"limit,omitempty"`
+	After   string `json:"after,omitempty"`
+}
+
+type Option func(*Request)
+
+func BuildRequest(opts ...Option) *Request {
+	req := &Request{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(req)
+		}
+	}
+	return req
+}
+
+func WithProvider(provider string) Option {
+	return func(r *Request) { r.Provider = provider }
+}
+
+func WithMIMEType(mimeType string) Option {
+	return func(r *Request) { r.MIMEType = mimeType }
+}
+
+func WithPurpose(purpose string) Option {
+	return func(r *Request) { r.Purpose = purpose }
+}
+
+// WithLimit sets the page size of List.
+func WithLimit(limit int) Option {
+	return func(r *Request) { r.Limit = limit }
+}
+
+// WithAfter makes List start after the file with id.
+func WithAfter(id string) Option {
+	return func(r *Request) { r.After = id }
+}
+
+type Client struct {
+	cfg Config
+}
+
+func New(cfg Config) *Client {
+	return &Client{cfg: cfg}
+}
+
+// Upload stores data as a file called name.
+func (c *Client) Upload(ctx context.Context, name string, data []byte, opts ...Option) (*File, error) {
+	req := BuildRequest(opts...)
+	provider := c.provider(req)
+	mimeType := req.MIMEType
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if provider == "openai" {
+		purpose := req.Purpose
+		if purpose == "" {
+			purpose = "user_data"
+		}
+		if err := w.WriteField("purpose", purpose); err != nil {
+			return nil, err
+		}
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	header.Set("Content-Type", mimeType)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	var out wireFile
+	if err := c.do(ctx, provider, http.MethodPost, "/files", nil, w.FormDataContentType(), &body, &out); err != nil {
+		return nil, err
+	}
+	f := out.file(provider)
+	if f.MIMEType == "" {
+		f.MIMEType = mimeType
+	}
+	return &f, nil
+}
+
+// Get returns the metadata of the file with id.
+func (c *Client) Get(ctx context.Context, id string, opts ...Option) (*File, error) {
+	provider := c.provider(BuildRequest(opts...))
+	var out wireFile
+	if err := c.do(ctx, provider, http.MethodGet, "/files/"+url.PathEscape(id), nil, "", nil, &out); err != nil {
+		return nil, err
+	}
+	f := out.file(provider)
+	return &f, nil
+}
+
+// List returns a page of files, newest first.
+func (c *Client) List(ctx context.Context, opts ...Option) (*List, error) {
+	req := BuildRequest(opts...)
+	provider := c.provider(req)
+	query := url.Values{}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+	if req.After != "" {
+		if provider == "anthropic" {
+			query.Set("after_id", req.After)
+		} else {
+			query.Set("after", req.After)
+		}
+	}
+	if req.Purpose != "" && provider == "openai" {
+		query.Set("purpose", req.Purpose)
+	}
+	var out struct {
+		Data    []wireFile `json:"data"`
+		HasMore bool       `json:"has_more"`
+	}
+	if err := c.do(ctx, provider, http.MethodGet, "/files", query, "", nil, &out); err != nil {
+		return nil, err
+	}
+	list := &List{HasMore: out.HasMore, Files: make([]File, len(out.Data))}
+	for i, f := range out.Data {
+		list.Files[i] = f.file(provider)
+	}
+	return list, nil
+}
+
+// Delete removes the file with id.
+func (c *Client) Delete(ctx context.Context, id string, opts ...Option) error {
+	provider := c.provider(BuildRequest(opts...))
+	var out json.RawMessage
+	return c.do(ctx, provider, http.MethodDelete, "/files/"+url.PathEscape(id), nil, "", nil, &out)
+}
+
+// Content downloads the file with id.
+func (c *Client) Content(ctx context.Context, id string, opts ...Option) ([]byte, error) {
+	provider := c.provider(BuildRequest(opts...))
+	var out []byte
+	if err := c.do(ctx, provider, http.MethodGet, "/files/"+url.PathEscape(id)+"/content", nil, "", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) provider(req *Request) string {
+	if req.Provider != "" {
+		return req.Provider
+	}
+	if c.cfg.Provider != "" {
+		return c.cfg.Provider
+	}
+	return "openai"
+}
+
+// wireFile is a file object of either API.
+type wireFile struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	// anthropic
+	MIMEType     string `json:"mime_type"`
+	SizeBytes    int64  `json:"size_bytes"`
+	Downloadable bool   `json:"downloadable"`
+	// openai
+	Bytes   int64  `json:"bytes"`
+	Purpose string `json:"purpose"`
+	// CreatedAt is RFC 3339 (anthropic) or Unix seconds (openai).
+	CreatedAt json.RawMessage `json:"created_at"`
+}
+
+func (w wireFile) file(provider string) File {
+	f := File{
+		ID:           w.ID,
+		Provider:     provider,
+		Name:         w.Filename,
+		MIMEType:     w.MIMEType,
+		Size:         max(w.SizeBytes, w.Bytes),
+		Purpose:      w.Purpose,
+		Downloadable: w.Downloadable,
+	}
+	if provider == "openai" {
+		// OpenAI serves the content of every file
+		f.Downloadable = true
+	}
+	var unix int64
+	var stamp string
+	if json.Unmarshal(w.CreatedAt, &unix) == nil && unix > 0 {
+		f.CreatedAt = time.Unix(unix, 0).UTC()
+	} else if json.Unmarshal(w.CreatedAt, &stamp) == nil {
+		f.CreatedAt, _ = time.Parse(time.RFC3339, stamp)
+	}
+	return f
+}
+
+// do calls the Files API of provider. A *[]byte out receives the raw body.
+func (c *Client) do(ctx context.Context, provider, method, path string, query url.Values, contentType string, body io.Reader, out any) error {
+	var base string
+	header := http.Header{}
+	switch provider {
+	case "openai":
+		if c.cfg.OpenAIAPIKey == "" {
+			return fmt.Errorf("openai api key is required")
+		}
+		base = c.cfg.OpenAIAPIBase
+		if base == "" {
+			base = defaultOpenAIAPIBase
+		}
+		header.Set("Authorization", "Bearer "+c.cfg.OpenAIAPIKey)
+	case "anthropic":
+		if c.cfg.AnthropicAPIKey == "" {
+			return fmt.Errorf("anthropic api key is required")
+		}
+		base = c.cfg.AnthropicAPIBase
+		if base == "" {
+			base = DefaultAnthropicAPIBase
+		}
+		base = strings.TrimRight(base, "/") + "/v1"
+		header.Set("x-api-key", c.cfg.AnthropicAPIKey)
+		header.Set("anthropic-version", anthropicAPIVersion)
+		header.Set("anthropic-beta", anthropicFilesBeta)
+	default:
+		return fmt.Errorf("files not supported for provider %s", provider)
+	}
+	endpoint := strings.TrimRight(base, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s files API request failed with status %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw = data
+		return nil
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
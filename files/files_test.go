@@ -0,0 +1,69 @@
+package files
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnthropicFiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "key" || r.Header.Get("anthropic-beta") != anthropicFilesBeta {
+			t.Errorf("missing headers: %v", r.Header)
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+			f, h, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("read upload: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(f)
+			if h.Filename != "data.csv" || string(data) != "a,b\n1,2\n" {
+				t.Errorf("unexpected upload %s: %q", h.Filename, data)
+			}
+			w.Write([]byte(`{"id":"file_1","type":"file","filename":"data.csv","mime_type":"text/csv","size_bytes":8,"created_at":"2025-05-22T10:00:00Z","downloadable":false}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files":
+			if r.URL.Query().Get("after_id") != "file_0" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"data":[{"id":"file_1","filename":"data.csv","size_bytes":8,"created_at":"2025-05-22T10:00:00Z"}],"has_more":true}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file_out/content":
+			w.Write([]byte("PNG"))
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/files/file_1":
+			w.Write([]byte(`{"id":"file_1","type":"file_deleted"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(Config{Provider: "anthropic", AnthropicAPIKey: "key", AnthropicAPIBase: srv.URL})
+	ctx := context.Background()
+	f, err := c.Upload(ctx, "data.csv", []byte("a,b\n1,2\n"), WithMIMEType("text/csv"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.ID != "file_1" || f.Size != 8 || f.CreatedAt.IsZero() || f.Downloadable {
+		t.Fatalf("unexpected file: %+v", f)
+	}
+	list, err := c.List(ctx, WithAfter("file_0"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Files) != 1 || !list.HasMore || list.Files[0].Name != "data.csv" {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	data, err := c.Content(ctx, "file_out")
+	if err != nil || string(data) != "PNG" {
+		t.Fatalf("unexpected content %q: %v", data, err)
+	}
+	if err := c.Delete(ctx, "file_1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Get(ctx, "missing"); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
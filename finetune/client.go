@@ -0,0 +1,195 @@
+package finetune
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/internal/httputil"
+	"github.com/quailyquaily/uniai/job"
+)
+
+const (
+	defaultOpenAIAPIBase   = "https://api.openai.com/v1"
+	defaultAzureAPIVersion = "2024-10-21"
+)
+
+type Config struct {
+	// Provider is "openai" (default) or "azure".
+	Provider string
+
+	OpenAIAPIKey  string
+	OpenAIAPIBase string
+
+	AzureOpenAIAPIKey     string
+	AzureOpenAIEndpoint   string
+	AzureOpenAIAPIVersion string
+
+	// PollInterval is how often WaitJob checks the job (default
+	// job.DefaultPollInterval).
+	PollInterval time.Duration
+}
+
+// Client manages fine-tuning files and jobs on OpenAI or Azure OpenAI.
+type Client struct {
+	cfg Config
+}
+
+func New(cfg Config) *Client {
+	return &Client{cfg: cfg}
+}
+
+// UploadFile uploads JSONL training data with purpose "fine-tune".
+func (c *Client) UploadFile(ctx context.Context, filename string, r io.Reader) (*File, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("purpose", "fine-tune"); err != nil {
+		return nil, err
+	}
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	var out File
+	if err := c.do(ctx, http.MethodPost, "/files", nil, w.FormDataContentType(), &body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) CreateJob(ctx context.Context, req JobRequest) (*Job, error) {
+	if req.Model == "" || req.TrainingFile == "" {
+		return nil, fmt.Errorf("fine-tuning model and training file are required")
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var out Job
+	if err := c.do(ctx, http.MethodPost, "/fine_tuning/jobs", nil, "application/json", bytes.NewReader(data), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListJobs returns up to limit jobs created before the job with ID after.
+func (c *Client) ListJobs(ctx context.Context, limit int, after string) (*JobList, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", fmt.Sprint(limit))
+	}
+	if after != "" {
+		query.Set("after", after)
+	}
+	var out JobList
+	if err := c.do(ctx, http.MethodGet, "/fine_tuning/jobs", query, "", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+	var out Job
+	if err := c.do(ctx, http.MethodGet, "/fine_tuning/jobs/"+url.PathEscape(id), nil, "", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// WaitJob polls a job until it succeeds, fails or is canceled.
+func (c *Client) WaitJob(ctx context.Context, id string) (*Job, error) {
+	return job.Wait(ctx, c.cfg.PollInterval, func(ctx context.Context) (*Job, job.Status, error) {
+		j, err := c.GetJob(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		return j, j.JobStatus(), nil
+	})
+}
+
+func (c *Client) CancelJob(ctx context.Context, id string) (*Job, error) {
+	var out Job
+	if err := c.do(ctx, http.MethodPost, "/fine_tuning/jobs/"+url.PathEscape(id)+"/cancel", nil, "", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) endpoint(path string, query url.Values) (string, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	switch c.cfg.Provider {
+	case "azure":
+		if c.cfg.AzureOpenAIAPIKey == "" || c.cfg.AzureOpenAIEndpoint == "" {
+			return "", fmt.Errorf("azure openai api key and endpoint are required")
+		}
+		version := c.cfg.AzureOpenAIAPIVersion
+		if version == "" {
+			version = defaultAzureAPIVersion
+		}
+		query.Set("api-version", version)
+		return strings.TrimRight(c.cfg.AzureOpenAIEndpoint, "/") + "/openai" + path + "?" + query.Encode(), nil
+	case "", "openai":
+		if c.cfg.OpenAIAPIKey == "" {
+			return "", fmt.Errorf("openai api key is required")
+		}
+		base := strings.TrimRight(c.cfg.OpenAIAPIBase, "/")
+		if base == "" {
+			base = defaultOpenAIAPIBase
+		}
+		endpoint := base + path
+		if len(query) > 0 {
+			endpoint += "?" + query.Encode()
+		}
+		return endpoint, nil
+	default:
+		return "", fmt.Errorf("fine-tuning not supported for provider %s", c.cfg.Provider)
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader, out any) error {
+	endpoint, err := c.endpoint(path, query)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.cfg.Provider == "azure" {
+		req.Header.Set("api-key", c.cfg.AzureOpenAIAPIKey)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.OpenAIAPIKey)
+	}
+
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("fine-tuning API request failed with status %d: %s", resp.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, out)
+}
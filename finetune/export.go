@@ -0,0 +1,142 @@
+package finetune
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/redact"
+	"github.com/quailyquaily/uniai/session"
+)
+
+// Conversation is an audited transcript to be exported as training data.
+// Stores that keep conversations convert their entries into this type.
+type Conversation struct {
+	ID       string
+	Messages []chat.Message
+	Tools    []chat.Tool
+	// Rating is the reviewer score, if any.
+	Rating *float64
+}
+
+// ExportConfig filters and splits conversations for Export.
+type ExportConfig struct {
+	// MinRating drops conversations rated below it. Unrated conversations
+	// are dropped too unless IncludeUnrated is set.
+	MinRating      *float64
+	IncludeUnrated bool
+	// Dedup drops conversations whose messages duplicate an earlier one.
+	Dedup bool
+	// ScrubPII replaces e-mail addresses, phone numbers, card numbers and
+	// similar identifiers in message content and in the string values of
+	// tool call arguments; Scrubber overrides the default.
+	ScrubPII bool
+	Scrubber func(string) string
+	// ValidationRatio is the share of conversations (0–1) placed in the
+	// validation set. The split is deterministic per conversation.
+	ValidationRatio float64
+}
+
+// ExportResult holds the split records and the number of conversations
+// skipped per reason.
+type ExportResult struct {
+	Train      []Record
+	Validation []Record
+	Skipped    map[string]int
+}
+
+// Export converts conversations into fine-tuning records.
+func Export(convs []Conversation, cfg ExportConfig) *ExportResult {
+	out := &ExportResult{Skipped: map[string]int{}}
+	scrub := cfg.Scrubber
+	if scrub == nil && cfg.ScrubPII {
+		scrub = ScrubPII
+	}
+	seen := map[[32]byte]bool{}
+	for _, conv := range convs {
+		if cfg.MinRating != nil {
+			if conv.Rating == nil {
+				if !cfg.IncludeUnrated {
+					out.Skipped["unrated"]++
+					continue
+				}
+			} else if *conv.Rating < *cfg.MinRating {
+				out.Skipped["low_rating"]++
+				continue
+			}
+		}
+		msgs := conv.Messages
+		if scrub != nil {
+			msgs = scrubMessages(conv.Messages, scrub)
+		}
+		digest := conversationDigest(msgs)
+		if cfg.Dedup {
+			if seen[digest] {
+				out.Skipped["duplicate"]++
+				continue
+			}
+			seen[digest] = true
+		}
+		rec, err := FromMessages(msgs, conv.Tools)
+		if err != nil {
+			out.Skipped["invalid"]++
+			continue
+		}
+		if inValidationSplit(conv.ID, digest, cfg.ValidationRatio) {
+			out.Validation = append(out.Validation, rec)
+		} else {
+			out.Train = append(out.Train, rec)
+		}
+	}
+	return out
+}
+
+func conversationDigest(msgs []chat.Message) [32]byte {
+	data, _ := json.Marshal(msgs)
+	return sha256.Sum256(data)
+}
+
+func inValidationSplit(id string, digest [32]byte, ratio float64) bool {
+	if ratio <= 0 {
+		return false
+	}
+	if id != "" {
+		digest = sha256.Sum256([]byte(id))
+	}
+	bucket := binary.BigEndian.Uint64(digest[:8]) % 10000
+	return float64(bucket) < ratio*10000
+}
+
+func scrubMessages(msgs []chat.Message, scrub func(string) string) []chat.Message {
+	out := make([]chat.Message, len(msgs))
+	for i, msg := range msgs {
+		msg.Content = scrub(msg.Content)
+		msg.Refusal = scrub(msg.Refusal)
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]chat.ToolCall, len(msg.ToolCalls))
+			for j, tc := range msg.ToolCalls {
+				tc.Function.Arguments = redact.MapJSON(tc.Function.Arguments, scrub)
+				calls[j] = tc
+			}
+			msg.ToolCalls = calls
+		}
+		out[i] = msg
+	}
+	return out
+}
+
+// ScrubPII replaces the personal data found by redact.DefaultDetectors in
+// text with placeholders such as [EMAIL]. It is pattern based and does not
+// catch names or free-form addresses.
+func ScrubPII(text string) string {
+	return redact.Replace(text, redact.DefaultDetectors(), func(kind, _ string) string {
+		return "[" + kind + "]"
+	})
+}
+
+// FromSession returns the active branch of s as a Conversation with the
+// given ID. Set Tools and Rating before exporting it if they are known.
+func FromSession(id string, s *session.Session) Conversation {
+	return Conversation{ID: id, Messages: s.Messages()}
+}
@@ -0,0 +1,168 @@
+package finetune
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Record is one line of chat fine-tuning JSONL.
+type Record struct {
+	Messages []RecordMessage `json:"messages"`
+	Tools    []RecordTool    `json:"tools,omitempty"`
+}
+
+type RecordMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content,omitempty"`
+	// Images turn the content into an array of text and image_url parts,
+	// as in OpenAI's vision fine-tuning format.
+	Images     []chat.Image    `json:"-"`
+	Name       string          `json:"name,omitempty"`
+	ToolCalls  []chat.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	// Weight set to 0 excludes an assistant message from training.
+	Weight *int `json:"weight,omitempty"`
+}
+
+// recordPart is an element of the content array of a message with images.
+type recordPart struct {
+	Type     string      `json:"type"`
+	Text     string      `json:"text,omitempty"`
+	ImageURL *chat.Image `json:"image_url,omitempty"`
+}
+
+func (m RecordMessage) MarshalJSON() ([]byte, error) {
+	type plain RecordMessage
+	if len(m.Images) == 0 {
+		return json.Marshal(plain(m))
+	}
+	var parts []recordPart
+	if m.Content != "" {
+		parts = append(parts, recordPart{Type: "text", Text: m.Content})
+	}
+	for _, img := range m.Images {
+		parts = append(parts, recordPart{Type: "image_url", ImageURL: &img})
+	}
+	return json.Marshal(struct {
+		plain
+		Content []recordPart `json:"content"`
+	}{plain(m), parts})
+}
+
+func (m *RecordMessage) UnmarshalJSON(data []byte) error {
+	type plain RecordMessage
+	var wire struct {
+		plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*m = RecordMessage(wire.plain)
+	content := bytes.TrimSpace(wire.Content)
+	switch {
+	case len(content) == 0 || string(content) == "null":
+		return nil
+	case content[0] != '[':
+		return json.Unmarshal(content, &m.Content)
+	}
+	var parts []recordPart
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return err
+	}
+	var texts []string
+	for _, p := range parts {
+		switch {
+		case p.Type == "text":
+			texts = append(texts, p.Text)
+		case p.Type == "image_url" && p.ImageURL != nil:
+			m.Images = append(m.Images, *p.ImageURL)
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
+}
+
+type RecordTool struct {
+	Type     string             `json:"type"`
+	Function RecordToolFunction `json:"function"`
+}
+
+type RecordToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// FromMessages converts a transcript into a training record. The transcript
+// must contain at least one assistant message, which is what the model learns.
+// Images are kept as content parts; videos have no place in the format and
+// are an error.
+func FromMessages(msgs []chat.Message, tools []chat.Tool) (Record, error) {
+	rec := Record{Messages: make([]RecordMessage, 0, len(msgs))}
+	hasAssistant := false
+	for i, msg := range msgs {
+		if msg.Role == chat.RoleAssistant {
+			hasAssistant = true
+		}
+		if len(msg.Videos) > 0 {
+			return Record{}, fmt.Errorf("message %d has videos, which fine-tuning records cannot hold", i)
+		}
+		rec.Messages = append(rec.Messages, RecordMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			Images:     msg.Images,
+			Name:       msg.Name,
+			ToolCalls:  wireToolCalls(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
+		})
+	}
+	if !hasAssistant {
+		return Record{}, fmt.Errorf("transcript has no assistant message")
+	}
+	for _, tool := range tools {
+		params := json.RawMessage(tool.Function.ParametersJSONSchema)
+		if len(params) > 0 && !json.Valid(params) {
+			return Record{}, fmt.Errorf("tool %s has invalid parameters schema", tool.Function.Name)
+		}
+		rec.Tools = append(rec.Tools, RecordTool{
+			Type: "function",
+			Function: RecordToolFunction{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  params,
+			},
+		})
+	}
+	return rec, nil
+}
+
+// wireToolCalls returns calls without the Metadata uniai adds, which is not
+// part of the fine-tuning format.
+func wireToolCalls(calls []chat.ToolCall) []chat.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]chat.ToolCall, len(calls))
+	for i, tc := range calls {
+		tc.Metadata = nil
+		out[i] = tc
+	}
+	return out
+}
+
+// WriteJSONL writes records one per line.
+func WriteJSONL(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package finetune
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/session"
+)
+
+func TestFromMessagesJSONL(t *testing.T) {
+	rec, err := FromMessages([]chat.Message{
+		chat.System("be brief"),
+		chat.User("hi"),
+		chat.Assistant("hello"),
+	}, []chat.Tool{chat.FunctionTool("noop", "", []byte(`{"type":"object"}`))})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, []Record{rec, rec}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"role":"assistant","content":"hello"`) || !strings.Contains(lines[0], `"parameters":{"type":"object"}`) {
+		t.Fatalf("unexpected jsonl: %s", buf.String())
+	}
+	if _, err := FromMessages([]chat.Message{chat.User("hi")}, nil); err == nil {
+		t.Fatalf("expected error without assistant message")
+	}
+}
+
+func TestFromMessagesDropsMetadata(t *testing.T) {
+	call := chat.ToolCall{ID: "c1", Type: "function", Function: chat.ToolCallFunction{Name: "noop", Arguments: "{}"},
+		Metadata: map[string]any{chat.MetadataConfidence: 0.4}}
+	msgs := []chat.Message{chat.User("hi"), {Role: chat.RoleAssistant, ToolCalls: []chat.ToolCall{call}}}
+	rec, err := FromMessages(msgs, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, []Record{rec}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "metadata") || strings.Contains(buf.String(), "confidence") {
+		t.Fatalf("metadata leaked into the record: %s", buf.String())
+	}
+	if msgs[1].ToolCalls[0].Metadata == nil {
+		t.Fatalf("transcript modified")
+	}
+}
+
+func TestFromMessagesImages(t *testing.T) {
+	msgs := []chat.Message{
+		chat.UserWithImages("what is this?", chat.Image{URL: "https://example.com/cat.png", Detail: "low"}),
+		chat.Assistant("a cat"),
+	}
+	rec, err := FromMessages(msgs, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, []Record{rec}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png","detail":"low"}}]}`
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("unexpected jsonl: %s", buf.String())
+	}
+	var back Record
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if m := back.Messages[0]; m.Content != "what is this?" || len(m.Images) != 1 || m.Images[0].Detail != "low" || back.Messages[1].Content != "a cat" {
+		t.Fatalf("unexpected round trip: %+v", back)
+	}
+
+	msgs[0].Videos = []chat.Video{{URL: "https://example.com/cat.mp4"}}
+	if _, err := FromMessages(msgs, nil); err == nil {
+		t.Fatalf("expected an error for videos")
+	}
+}
+
+func TestExportFilters(t *testing.T) {
+	good, bad := 5.0, 1.0
+	minRating := 3.0
+	transcript := []chat.Message{chat.User("mail me at a@b.com"), chat.Assistant("ok")}
+	res := Export([]Conversation{
+		{ID: "1", Messages: transcript, Rating: &good},
+		{ID: "2", Messages: transcript, Rating: &good},
+		{ID: "3", Messages: transcript, Rating: &bad},
+		{ID: "4", Messages: transcript},
+	}, ExportConfig{MinRating: &minRating, Dedup: true, ScrubPII: true})
+	if len(res.Train) != 1 || res.Skipped["duplicate"] != 1 || res.Skipped["low_rating"] != 1 || res.Skipped["unrated"] != 1 {
+		t.Fatalf("unexpected export: %+v", res)
+	}
+	if got := res.Train[0].Messages[0].Content; got != "mail me at [EMAIL]" {
+		t.Fatalf("expected scrubbed content, got %q", got)
+	}
+}
+
+func TestExportScrubsSessionToolCalls(t *testing.T) {
+	s := session.New(nil)
+	s.Append(chat.User("book a table for ann@example.com"))
+	s.Append(chat.Message{Role: chat.RoleAssistant, ToolCalls: []chat.ToolCall{{
+		ID: "call_1", Type: "function",
+		Function: chat.ToolCallFunction{Name: "book", Arguments: `{"email":"ann@example.com","seats":4}`},
+	}}})
+	s.Append(chat.ToolResult("call_1", "booked"))
+	s.Append(chat.Assistant("done"))
+	res := Export([]Conversation{FromSession("s1", s)}, ExportConfig{ScrubPII: true})
+	if len(res.Train) != 1 {
+		t.Fatalf("unexpected export: %+v", res)
+	}
+	msgs := res.Train[0].Messages
+	if got := msgs[1].ToolCalls[0].Function.Arguments; got != `{"email":"[EMAIL]","seats":4}` {
+		t.Fatalf("expected scrubbed arguments, got %q", got)
+	}
+	if msgs[0].Content != "book a table for [EMAIL]" {
+		t.Fatalf("expected scrubbed content, got %q", msgs[0].Content)
+	}
+}
@@ -0,0 +1,71 @@
+package finetune
+
+import (
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/job"
+)
+
+// File is an uploaded training or validation file.
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status,omitempty"`
+}
+
+// JobRequest creates a fine-tuning job. For Azure, Model is the base model
+// name and the resulting model must be deployed before use.
+type JobRequest struct {
+	Model           string          `json:"model"`
+	TrainingFile    string          `json:"training_file"`
+	ValidationFile  string          `json:"validation_file,omitempty"`
+	Suffix          string          `json:"suffix,omitempty"`
+	Seed            *int64          `json:"seed,omitempty"`
+	Hyperparameters structs.JSONMap `json:"hyperparameters,omitempty"`
+}
+
+// Job is a fine-tuning job as reported by the provider.
+type Job struct {
+	ID              string          `json:"id"`
+	Object          string          `json:"object"`
+	Model           string          `json:"model"`
+	FineTunedModel  string          `json:"fine_tuned_model,omitempty"`
+	Status          string          `json:"status"`
+	TrainingFile    string          `json:"training_file"`
+	ValidationFile  string          `json:"validation_file,omitempty"`
+	CreatedAt       int64           `json:"created_at"`
+	FinishedAt      int64           `json:"finished_at,omitempty"`
+	TrainedTokens   int64           `json:"trained_tokens,omitempty"`
+	Hyperparameters structs.JSONMap `json:"hyperparameters,omitempty"`
+	Error           *JobError       `json:"error,omitempty"`
+}
+
+// JobStatus maps Status to the normalized job status.
+func (j *Job) JobStatus() job.Status {
+	switch j.Status {
+	case "succeeded":
+		return job.StatusSucceeded
+	case "failed":
+		return job.StatusFailed
+	case "cancelled":
+		return job.StatusCanceled
+	case "running":
+		return job.StatusRunning
+	}
+	return job.StatusQueued
+}
+
+type JobError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+}
+
+// JobList is one page of jobs.
+type JobList struct {
+	Data    []Job `json:"data"`
+	HasMore bool  `json:"has_more"`
+}
@@ -0,0 +1,144 @@
+package uniai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// chatWithGlossary implements req.Options.Glossary: the terms are added to
+// the prompt, and replies that break them are sent back with the
+// violations listed.
+func (c *Client) chatWithGlossary(ctx context.Context, providerName string, req *chat.Request) (*chat.Result, error) {
+	g := req.Options.Glossary
+	retries := g.MaxRetries
+	if retries <= 0 {
+		retries = 2
+	}
+	terms, err := compileGlossary(g)
+	if err != nil {
+		return nil, err
+	}
+	var input strings.Builder
+	for _, m := range req.Messages {
+		if m.Role != chat.RoleAssistant {
+			input.WriteString(m.Content + "\n")
+		}
+	}
+
+	next := *req
+	next.Options.Glossary = nil
+	next.Messages = append([]chat.Message{chat.System(glossaryInstruction(g))}, req.Messages...)
+	var warnings []string
+	for attempt := 0; ; attempt++ {
+		resp, err := c.chatWithTools(ctx, providerName, &next)
+		if err != nil {
+			return nil, err
+		}
+		violations := glossaryViolations(terms, input.String(), resp.Text)
+		if len(violations) == 0 || len(resp.ToolCalls) > 0 {
+			resp.Warnings = append(warnings, resp.Warnings...)
+			return resp, nil
+		}
+		summary := strings.Join(violations, "; ")
+		if attempt >= retries {
+			if g.Strict {
+				return nil, fmt.Errorf("glossary: reply breaks terminology after %d attempts: %s", attempt+1, summary)
+			}
+			resp.Warnings = append(append(warnings, "glossary: reply breaks terminology: "+summary), resp.Warnings...)
+			return resp, nil
+		}
+		warnings = append(warnings, fmt.Sprintf("glossary: attempt %d rejected: %s", attempt+1, summary))
+		next.Messages = append(append([]chat.Message{}, next.Messages...),
+			chat.Assistant(resp.Text),
+			chat.User("Your reply breaks the required terminology: "+summary+". Reply again in full with these fixed and nothing else changed."),
+		)
+	}
+}
+
+func glossaryInstruction(g *chat.Glossary) string {
+	var b strings.Builder
+	b.WriteString("Follow this terminology exactly:")
+	for _, t := range g.Terms {
+		if t.Source != "" {
+			fmt.Fprintf(&b, "\n- Translate %q as %q.", t.Source, t.Term)
+		} else {
+			fmt.Fprintf(&b, "\n- Write %q exactly with this spelling and capitalization.", t.Term)
+		}
+		if len(t.Avoid) > 0 {
+			b.WriteString(" Never write " + quoteList(t.Avoid) + ".")
+		}
+		if t.Note != "" {
+			b.WriteString(" (" + t.Note + ")")
+		}
+	}
+	return b.String()
+}
+
+type glossaryTerm struct {
+	chat.GlossaryTerm
+	term, source *regexp.Regexp
+	avoid        []*regexp.Regexp
+}
+
+func compileGlossary(g *chat.Glossary) ([]glossaryTerm, error) {
+	out := make([]glossaryTerm, 0, len(g.Terms))
+	for _, t := range g.Terms {
+		if strings.TrimSpace(t.Term) == "" {
+			return nil, fmt.Errorf("glossary: term is required")
+		}
+		gt := glossaryTerm{GlossaryTerm: t, term: wordPattern(t.Term)}
+		if t.Source != "" {
+			gt.source = wordPattern(t.Source)
+		}
+		for _, a := range t.Avoid {
+			gt.avoid = append(gt.avoid, wordPattern(a))
+		}
+		out = append(out, gt)
+	}
+	return out, nil
+}
+
+// wordPattern matches s as a whole word, ignoring case. The boundaries are
+// captured, since RE2 has no lookaround.
+func wordPattern(s string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(^|[^\p{L}\p{N}_])(` + regexp.QuoteMeta(s) + `)($|[^\p{L}\p{N}_])`)
+}
+
+// glossaryViolations describes how text breaks the terms. input is the
+// prompt, which decides whether translated terms are expected.
+func glossaryViolations(terms []glossaryTerm, input, text string) []string {
+	var out []string
+	for _, t := range terms {
+		for i, re := range t.avoid {
+			for _, m := range re.FindAllStringSubmatch(text, -1) {
+				// an avoided variant may differ from Term only in case
+				if m[2] != t.Term {
+					out = append(out, fmt.Sprintf("use %q instead of %q", t.Term, t.Avoid[i]))
+					break
+				}
+			}
+		}
+		for _, m := range t.term.FindAllStringSubmatch(text, -1) {
+			if m[2] != t.Term {
+				out = append(out, fmt.Sprintf("write %q, not %q", t.Term, m[2]))
+				break
+			}
+		}
+		if t.source != nil && t.source.MatchString(input) && !t.term.MatchString(text) {
+			out = append(out, fmt.Sprintf("translate %q as %q", t.Source, t.Term))
+		}
+	}
+	return out
+}
+
+func quoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, s := range items {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return strings.Join(quoted, " or ")
+}
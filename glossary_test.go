@@ -0,0 +1,56 @@
+package uniai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestGlossaryRetries(t *testing.T) {
+	client := New(Config{})
+	p := fake.New(fake.Config{Responses: []fake.Response{
+		fake.Text("Das neue Iphone schützt Ihr Benutzerkonto.", 0, 0),
+		fake.Text("Das neue iPhone schützt Ihr Kundenkonto.", 0, 0),
+	}})
+	client.RegisterProvider("fake", p)
+	res, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User("Translate to German: The new iPhone protects your customer account.")),
+		WithGlossary(Glossary{Terms: []GlossaryTerm{
+			{Term: "iPhone", Note: "brand name"},
+			{Term: "Kundenkonto", Source: "customer account", Avoid: []string{"Benutzerkonto"}},
+		}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Text != "Das neue iPhone schützt Ihr Kundenkonto." || len(res.Warnings) != 1 {
+		t.Fatalf("unexpected result %q %v", res.Text, res.Warnings)
+	}
+	reqs := p.Requests()
+	if len(reqs) != 2 || !strings.Contains(reqs[0].Messages[0].Content, `Translate "customer account" as "Kundenkonto"`) {
+		t.Fatalf("unexpected requests %+v", reqs)
+	}
+	feedback := reqs[1].Messages[len(reqs[1].Messages)-1].Content
+	if !strings.Contains(feedback, `write "iPhone", not "Iphone"`) || !strings.Contains(feedback, `use "Kundenkonto" instead of "Benutzerkonto"`) {
+		t.Fatalf("unexpected feedback %q", feedback)
+	}
+}
+
+func TestGlossaryStrict(t *testing.T) {
+	client := New(Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{
+		fake.Text("Buy the IPHONE.", 0, 0),
+		fake.Text("Buy the IPHONE.", 0, 0),
+	}}))
+	_, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User("Write an ad.")),
+		WithGlossary(Glossary{Terms: []GlossaryTerm{{Term: "iPhone"}}, MaxRetries: 1, Strict: true}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Fatalf("expected glossary error, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package uniai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// grammarNative reports whether g can be sent to the provider as-is.
+func grammarNative(providerName string, g *chat.Grammar) bool {
+	switch providerName {
+	case "vllm":
+		_, ok := grammarParams(vllmGrammar(g))
+		return ok
+	case "openai_custom":
+		_, ok := grammarParams(g)
+		return ok
+	}
+	return false
+}
+
+// grammarParams returns the request body fields that express g for its
+// backend, or false when the backend cannot express the syntax.
+func grammarParams(g *chat.Grammar) (map[string]any, bool) {
+	switch g.Backend {
+	case chat.GrammarBackendLlamaCPP:
+		switch g.Syntax {
+		case chat.GrammarGBNF:
+			return map[string]any{"grammar": g.Value}, true
+		case chat.GrammarJSONSchema:
+			var schema any
+			if err := json.Unmarshal([]byte(g.Value), &schema); err != nil {
+				return nil, false
+			}
+			return map[string]any{"json_schema": schema}, true
+		}
+	case chat.GrammarBackendTGI:
+		switch g.Syntax {
+		case chat.GrammarRegex:
+			return map[string]any{"response_format": map[string]any{"type": "regex", "value": g.Value}}, true
+		case chat.GrammarJSONSchema:
+			var schema any
+			if err := json.Unmarshal([]byte(g.Value), &schema); err != nil {
+				return nil, false
+			}
+			return map[string]any{"response_format": map[string]any{"type": "json", "value": schema}}, true
+		}
+	case chat.GrammarBackendVLLM:
+		switch g.Syntax {
+		case chat.GrammarRegex:
+			return map[string]any{"guided_regex": g.Value}, true
+		case chat.GrammarEBNF, chat.GrammarGBNF:
+			return map[string]any{"guided_grammar": g.Value}, true
+		case chat.GrammarJSONSchema:
+			var schema any
+			if err := json.Unmarshal([]byte(g.Value), &schema); err != nil {
+				return nil, false
+			}
+			return map[string]any{"guided_json": schema}, true
+		}
+	}
+	return nil, false
+}
+
+// vllmGrammar returns g targeted at vLLM, the only backend of the vllm provider.
+func vllmGrammar(g *chat.Grammar) *chat.Grammar {
+	out := *g
+	out.Backend = chat.GrammarBackendVLLM
+	return &out
+}
+
+// applyNativeGrammar merges the grammar fields into the OpenAI extra_body option.
+func applyNativeGrammar(providerName string, req *chat.Request) *chat.Request {
+	g := req.Options.Grammar
+	if g == nil || !grammarNative(providerName, g) {
+		return req
+	}
+	if providerName == "vllm" {
+		g = vllmGrammar(g)
+	}
+	fields, _ := grammarParams(g)
+	return withExtraBody(req, fields)
+}
+
+// withExtraBody returns a copy of req with fields merged into the OpenAI
+// extra_body option.
+func withExtraBody(req *chat.Request, fields map[string]any) *chat.Request {
+	out := *req
+	opts := cloneJSONMap(out.Options.OpenAI)
+	if opts == nil {
+		opts = structs.NewJSONMap()
+	}
+	extra := map[string]any{}
+	if existing, ok := opts["extra_body"].(map[string]any); ok {
+		for k, v := range existing {
+			extra[k] = v
+		}
+	}
+	for k, v := range fields {
+		extra[k] = v
+	}
+	opts["extra_body"] = extra
+	out.Options.OpenAI = opts
+	return &out
+}
+
+// chatWithGrammar emulates a grammar constraint by prompting for it and
+// validating the reply, retrying with the validation error as feedback.
+func (c *Client) chatWithGrammar(ctx context.Context, providerName string, req *chat.Request) (*chat.Result, error) {
+	g := req.Options.Grammar
+	retries := g.MaxRetries
+	if retries <= 0 {
+		retries = 2
+	}
+	validate, err := grammarValidator(g)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *req
+	next.Options.Grammar = nil
+	next.Messages = append([]chat.Message{chat.System(grammarInstruction(g))}, req.Messages...)
+
+	var warnings []string
+	if validate == nil {
+		warnings = append(warnings, fmt.Sprintf("grammar: %s cannot be enforced by %s; only prompted", g.Syntax, providerName))
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.chatWithTools(ctx, providerName, &next)
+		if err != nil {
+			return nil, err
+		}
+		if validate == nil || len(resp.ToolCalls) > 0 {
+			resp.Warnings = append(warnings, resp.Warnings...)
+			return resp, nil
+		}
+		verr := validate(resp.Text)
+		if verr == nil {
+			resp.Warnings = append(warnings, resp.Warnings...)
+			return resp, nil
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("grammar: reply does not match after %d attempts: %w", attempt+1, verr)
+		}
+		warnings = append(warnings, fmt.Sprintf("grammar: attempt %d rejected: %v", attempt+1, verr))
+		next.Messages = append(append([]chat.Message{}, next.Messages...),
+			chat.Assistant(resp.Text),
+			chat.User(fmt.Sprintf("Your reply does not satisfy the required format (%v). Reply again with only output that matches it.", verr)),
+		)
+	}
+}
+
+func grammarInstruction(g *chat.Grammar) string {
+	switch g.Syntax {
+	case chat.GrammarRegex:
+		return "Your entire reply must match this regular expression, with no other text:\n" + g.Value
+	case chat.GrammarJSONSchema:
+		return "Your entire reply must be a single JSON value that validates against this JSON Schema, with no other text:\n" + g.Value
+	default:
+		return fmt.Sprintf("Your entire reply must be derivable from this %s grammar, with no other text:\n%s", strings.ToUpper(g.Syntax), g.Value)
+	}
+}
+
+// grammarValidator returns a local validator for g, or nil when the syntax
+// cannot be checked locally.
+func grammarValidator(g *chat.Grammar) (func(string) error, error) {
+	switch g.Syntax {
+	case chat.GrammarRegex:
+		re, err := regexp.Compile(`^(?:` + g.Value + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("grammar: invalid regex: %w", err)
+		}
+		return func(text string) error {
+			if !re.MatchString(strings.TrimSpace(text)) {
+				return fmt.Errorf("reply does not match the regular expression")
+			}
+			return nil
+		}, nil
+	case chat.GrammarJSONSchema:
+		var schema map[string]any
+		if err := json.Unmarshal([]byte(g.Value), &schema); err != nil {
+			return nil, fmt.Errorf("grammar: invalid json schema: %w", err)
+		}
+		return func(text string) error {
+			var value any
+			if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &value); err != nil {
+				return fmt.Errorf("reply is not valid JSON: %v", err)
+			}
+			return checkSchema(schema, value, "$")
+		}, nil
+	}
+	return nil, nil
+}
+
+// checkSchema validates the type, required, properties, items and enum
+// keywords of a JSON Schema, which covers the structural constraints callers
+// typically rely on.
+func checkSchema(schema map[string]any, value any, path string) error {
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, candidate := range enum {
+			if fmt.Sprint(candidate) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value not in enum", path)
+		}
+	}
+	if typ, ok := schema["type"].(string); ok && !jsonTypeMatches(typ, value) {
+		return fmt.Errorf("%s: expected %s", path, typ)
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, key := range required {
+				name, _ := key.(string)
+				if _, ok := v[name]; !ok {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+		if props, ok := schema["properties"].(map[string]any); ok {
+			for name, sub := range props {
+				subSchema, ok := sub.(map[string]any)
+				field, present := v[name]
+				if !ok || !present {
+					continue
+				}
+				if err := checkSchema(subSchema, field, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := checkSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func jsonTypeMatches(typ string, value any) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package uniai
+
+import (
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestApplyNativeGrammar(t *testing.T) {
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("pick")),
+		chat.WithGrammar(chat.Grammar{Syntax: chat.GrammarRegex, Value: "yes|no", Backend: chat.GrammarBackendVLLM}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := applyNativeGrammar("openai_custom", req)
+	extra, _ := out.Options.OpenAI["extra_body"].(map[string]any)
+	if extra["guided_regex"] != "yes|no" {
+		t.Fatalf("unexpected extra body: %#v", out.Options.OpenAI)
+	}
+	if grammarNative("openai", req.Options.Grammar) {
+		t.Fatalf("official openai endpoint must fall back to emulation")
+	}
+}
+
+func TestGrammarValidator(t *testing.T) {
+	validate, err := grammarValidator(&chat.Grammar{
+		Syntax: chat.GrammarJSONSchema,
+		Value:  `{"type":"object","required":["answer"],"properties":{"answer":{"type":"string","enum":["yes","no"]}}}`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validate(`{"answer":"yes"}`); err != nil {
+		t.Fatalf("expected valid reply: %v", err)
+	}
+	if err := validate(`{"answer":"maybe"}`); err == nil {
+		t.Fatalf("expected enum violation")
+	}
+	if err := validate(`{}`); err == nil {
+		t.Fatalf("expected missing property")
+	}
+}
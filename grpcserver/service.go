@@ -0,0 +1,246 @@
+// Package grpcserver serves the Uniai gRPC service defined in uniai.proto
+// on top of uniai.Client. The generated code is in the uniaipb package;
+// non-Go callers generate theirs from the same file.
+//
+//	srv := grpc.NewServer()
+//	grpcserver.Register(srv, client)
+//	srv.Serve(lis)
+package grpcserver
+
+//go:generate protoc --go_out=uniaipb --go_opt=paths=source_relative --go-grpc_out=uniaipb --go-grpc_opt=paths=source_relative uniai.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/embedding"
+	"github.com/quailyquaily/uniai/grpcserver/uniaipb"
+)
+
+// Service implements uniaipb.UniaiServer with Client.
+type Service struct {
+	uniaipb.UnimplementedUniaiServer
+	Client *uniai.Client
+}
+
+// Register serves the Uniai service on s with client.
+func Register(s grpc.ServiceRegistrar, client *uniai.Client) *Service {
+	svc := &Service{Client: client}
+	uniaipb.RegisterUniaiServer(s, svc)
+	return svc
+}
+
+func (s *Service) Chat(ctx context.Context, req *uniaipb.ChatRequest) (*uniaipb.ChatResponse, error) {
+	opts, err := chatOptions(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp, err := s.Client.Chat(ctx, opts...)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toChatResponse(resp), nil
+}
+
+// ChatStream streams the reply. The final chunk has Done set and carries
+// the usage. Text and tool calls a provider did not stream, such as the
+// reply of a non-streaming provider or emulated tool calls, are sent from
+// the result before it.
+func (s *Service) ChatStream(req *uniaipb.ChatRequest, stream grpc.ServerStreamingServer[uniaipb.ChatChunk]) error {
+	opts, err := chatOptions(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	streamedText, streamedCalls := false, false
+	opts = append(opts, chat.WithOnStream(func(ev chat.StreamEvent) error {
+		switch {
+		case ev.Delta != "":
+			streamedText = true
+			return stream.Send(&uniaipb.ChatChunk{Delta: ev.Delta})
+		case ev.ToolCall != nil:
+			streamedCalls = true
+			return stream.Send(&uniaipb.ChatChunk{ToolCall: fromToolCall(*ev.ToolCall)})
+		}
+		return nil
+	}))
+	resp, err := s.Client.Chat(stream.Context(), opts...)
+	if err != nil {
+		return toStatus(err)
+	}
+	if !streamedText && resp.Text != "" {
+		if err := stream.Send(&uniaipb.ChatChunk{Delta: resp.Text}); err != nil {
+			return err
+		}
+	}
+	if !streamedCalls {
+		for _, call := range resp.ToolCalls {
+			if err := stream.Send(&uniaipb.ChatChunk{ToolCall: fromToolCall(call)}); err != nil {
+				return err
+			}
+		}
+	}
+	return stream.Send(&uniaipb.ChatChunk{Done: true, Usage: fromUsage(resp.Usage)})
+}
+
+func (s *Service) Embed(ctx context.Context, req *uniaipb.EmbedRequest) (*uniaipb.EmbedResponse, error) {
+	inputs := make([]embedding.Input, 0, len(req.Input))
+	for _, in := range req.Input {
+		inputs = append(inputs, embedding.Input{Text: in.Text, Image: in.Image})
+	}
+	resp, err := s.Client.Embedding(ctx,
+		embedding.Embedding(req.Model),
+		embedding.WithProvider(req.Provider),
+		embedding.WithInputs(inputs...),
+	)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	out := &uniaipb.EmbedResponse{
+		Model: resp.Model,
+		Usage: &uniaipb.Usage{
+			InputTokens: int32(resp.Usage.PromptTokens),
+			TotalTokens: int32(resp.Usage.TotalTokens),
+		},
+	}
+	for _, item := range resp.Data {
+		out.Data = append(out.Data, &uniaipb.Embedding{Index: int32(item.Index), Embedding: item.Embedding})
+	}
+	return out, nil
+}
+
+// toStatus gives err the gRPC code matching its chat.ClassifyError class.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, chat.ErrNonCompliant):
+		code = codes.FailedPrecondition
+	default:
+		switch chat.ClassifyError(err) {
+		case chat.ErrorClassCanceled:
+			code = codes.Canceled
+		case chat.ErrorClassTimeout:
+			code = codes.DeadlineExceeded
+		case chat.ErrorClassRateLimit:
+			code = codes.ResourceExhausted
+		case chat.ErrorClassAuth:
+			code = codes.PermissionDenied
+		case chat.ErrorClassInvalidRequest, chat.ErrorClassContextLength, chat.ErrorClassContentFilter:
+			code = codes.InvalidArgument
+		case chat.ErrorClassServer, chat.ErrorClassNetwork:
+			code = codes.Unavailable
+		}
+	}
+	return status.Error(code, err.Error())
+}
+
+func chatOptions(req *uniaipb.ChatRequest) ([]chat.Option, error) {
+	if req == nil || len(req.Messages) == 0 {
+		return nil, fmt.Errorf("messages are required")
+	}
+	msgs := make([]chat.Message, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		msg := chat.Message{Role: m.Role, Content: m.Content, Name: m.Name, ToolCallID: m.ToolCallId}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, chat.ToolCall{
+				ID:   tc.Id,
+				Type: tc.Type,
+				Function: chat.ToolCallFunction{
+					Name:      tc.Name,
+					Arguments: tc.Arguments,
+				},
+			})
+		}
+		for _, img := range m.Images {
+			msg.Images = append(msg.Images, chat.Image{URL: img.Url, Detail: img.Detail})
+		}
+		msgs = append(msgs, msg)
+	}
+	opts := []chat.Option{chat.WithMessages(msgs...)}
+	if req.Provider != "" {
+		opts = append(opts, chat.WithProvider(req.Provider))
+	}
+	if req.Model != "" {
+		opts = append(opts, chat.WithModel(req.Model))
+	}
+	if req.Temperature != nil {
+		opts = append(opts, chat.WithTemperature(*req.Temperature))
+	}
+	if req.TopP != nil {
+		opts = append(opts, chat.WithTopP(*req.TopP))
+	}
+	if req.MaxTokens != nil {
+		opts = append(opts, chat.WithMaxTokens(int(*req.MaxTokens)))
+	}
+	if len(req.Stop) > 0 {
+		opts = append(opts, chat.WithStopWords(req.Stop...))
+	}
+	if req.User != "" {
+		opts = append(opts, chat.WithUser(req.User))
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]chat.Tool, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			tools = append(tools, chat.Tool{
+				Type: "function",
+				Function: chat.ToolFunction{
+					Name:                 t.Name,
+					Description:          t.Description,
+					ParametersJSONSchema: []byte(t.Parameters),
+					Strict:               t.Strict,
+				},
+			})
+		}
+		opts = append(opts, chat.WithTools(tools))
+	}
+	switch req.ToolChoice {
+	case "":
+	case "auto", "none", "required":
+		opts = append(opts, chat.WithToolChoice(chat.ToolChoice{Mode: req.ToolChoice}))
+	default:
+		opts = append(opts, chat.WithToolChoice(chat.ToolChoice{Mode: "function", FunctionName: req.ToolChoice}))
+	}
+	switch mode := chat.ToolsEmulationMode(req.ToolsEmulationMode); mode {
+	case "":
+	case chat.ToolsEmulationOff, chat.ToolsEmulationFallback, chat.ToolsEmulationForce:
+		opts = append(opts, chat.WithToolsEmulationMode(mode))
+	default:
+		return nil, fmt.Errorf("unknown tools emulation mode %q", req.ToolsEmulationMode)
+	}
+	return opts, nil
+}
+
+func toChatResponse(resp *chat.Result) *uniaipb.ChatResponse {
+	out := &uniaipb.ChatResponse{
+		Text:         resp.Text,
+		Model:        resp.Model,
+		FinishReason: resp.FinishReason,
+		Usage:        fromUsage(resp.Usage),
+		Warnings:     resp.Warnings,
+	}
+	for _, tc := range resp.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, fromToolCall(tc))
+	}
+	return out
+}
+
+func fromToolCall(tc chat.ToolCall) *uniaipb.ToolCall {
+	return &uniaipb.ToolCall{Id: tc.ID, Type: tc.Type, Name: tc.Function.Name, Arguments: tc.Function.Arguments}
+}
+
+func fromUsage(u chat.Usage) *uniaipb.Usage {
+	return &uniaipb.Usage{
+		InputTokens:  int32(u.InputTokens),
+		OutputTokens: int32(u.OutputTokens),
+		TotalTokens:  int32(u.TotalTokens),
+	}
+}
@@ -0,0 +1,111 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/grpcserver/uniaipb"
+)
+
+func TestChatOptions(t *testing.T) {
+	temp := 0.2
+	opts, err := chatOptions(&uniaipb.ChatRequest{
+		Provider: "anthropic",
+		Messages: []*uniaipb.Message{{
+			Role:    chat.RoleUser,
+			Content: "what is this?",
+			Images:  []*uniaipb.Image{{Url: "https://example.com/cat.png", Detail: "low"}},
+		}},
+		Temperature: &temp,
+		Tools:       []*uniaipb.Tool{{Name: "lookup", Parameters: `{"type":"object"}`}},
+		ToolChoice:  "lookup",
+	})
+	if err != nil {
+		t.Fatalf("chatOptions: %v", err)
+	}
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if req.Provider != "anthropic" || *req.Options.Temperature != 0.2 || len(req.Tools) != 1 {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Mode != "function" || req.ToolChoice.FunctionName != "lookup" {
+		t.Fatalf("unexpected tool choice: %+v", req.ToolChoice)
+	}
+	if imgs := req.Messages[0].Images; len(imgs) != 1 || imgs[0].URL != "https://example.com/cat.png" || imgs[0].Detail != "low" {
+		t.Fatalf("unexpected images: %+v", imgs)
+	}
+
+	if _, err := chatOptions(&uniaipb.ChatRequest{Messages: []*uniaipb.Message{{Role: chat.RoleUser}}, ToolsEmulationMode: "always"}); err == nil {
+		t.Fatalf("expected error for unknown emulation mode")
+	}
+}
+
+// unstreamed answers without calling OnStream, like susanoo.
+type unstreamed struct{}
+
+func (unstreamed) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	return &chat.Result{
+		Text:      "checking",
+		ToolCalls: []chat.ToolCall{{ID: "call_1", Type: "function", Function: chat.ToolCallFunction{Name: "lookup", Arguments: `{}`}}},
+		Usage:     chat.Usage{InputTokens: 3, OutputTokens: 2, TotalTokens: 5},
+	}, nil
+}
+
+// dial serves client on an in-memory listener and returns a connected stub.
+func dial(t *testing.T, client *uniai.Client) uniaipb.UniaiClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	Register(srv, client)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return uniaipb.NewUniaiClient(conn)
+}
+
+func TestChatStreamWithoutProviderStreaming(t *testing.T) {
+	client := uniai.New(uniai.Config{})
+	client.RegisterProvider("plain", unstreamed{})
+	stub := dial(t, client)
+	stream, err := stub.ChatStream(context.Background(), &uniaipb.ChatRequest{
+		Provider: "plain",
+		Messages: []*uniaipb.Message{{Role: chat.RoleUser, Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	var chunks []*uniaipb.ChatChunk
+	for {
+		c, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		chunks = append(chunks, c)
+	}
+	if len(chunks) != 3 || chunks[0].Delta != "checking" || chunks[1].ToolCall == nil || chunks[1].ToolCall.Name != "lookup" {
+		t.Fatalf("unexpected chunks %+v", chunks)
+	}
+	if last := chunks[2]; !last.Done || last.Usage == nil || last.Usage.TotalTokens != 5 {
+		t.Fatalf("unexpected final chunk %+v", last)
+	}
+}
@@ -0,0 +1,32 @@
+package grpcserver
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/signature"
+)
+
+// SignatureMetadata is the metadata key carrying request signatures.
+var SignatureMetadata = strings.ToLower(signature.Header)
+
+// SignMetadata returns the metadata signing a call to method, the full
+// method name such as "/uniai.v1.Uniai/Chat", with payload, the serialized
+// request message.
+func SignMetadata(secret []byte, method string, payload []byte) map[string]string {
+	return map[string]string{
+		SignatureMetadata: signature.Sign(secret, time.Now(), signature.RequestPayload("POST", method, payload)),
+	}
+}
+
+// VerifyMetadata checks the signature in the incoming metadata of a call,
+// as set by SignMetadata. Call it from a unary interceptor with the
+// re-serialized request; streaming calls sign their first message.
+func VerifyMetadata(md map[string][]string, method string, payload []byte, tolerance time.Duration, secrets ...[]byte) error {
+	values := md[SignatureMetadata]
+	if len(values) == 0 {
+		return fmt.Errorf("%w: missing %s metadata", signature.ErrInvalid, SignatureMetadata)
+	}
+	return signature.Verify(values[0], signature.RequestPayload("POST", method, payload), tolerance, secrets...)
+}
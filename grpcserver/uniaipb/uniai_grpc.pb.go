@@ -0,0 +1,209 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: uniai.proto
+
+package uniaipb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Uniai_Chat_FullMethodName       = "/uniai.v1.Uniai/Chat"
+	Uniai_ChatStream_FullMethodName = "/uniai.v1.Uniai/ChatStream"
+	Uniai_Embed_FullMethodName      = "/uniai.v1.Uniai/Embed"
+)
+
+// UniaiClient is the client API for Uniai service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Uniai mirrors the Go client: Chat and ChatStream go through the same
+// provider routing and middleware as uniai.Client.Chat, Embed through
+// uniai.Client.Embedding.
+type UniaiClient interface {
+	Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error)
+	ChatStream(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChatChunk], error)
+	Embed(ctx context.Context, in *EmbedRequest, opts ...grpc.CallOption) (*EmbedResponse, error)
+}
+
+type uniaiClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUniaiClient(cc grpc.ClientConnInterface) UniaiClient {
+	return &uniaiClient{cc}
+}
+
+func (c *uniaiClient) Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChatResponse)
+	err := c.cc.Invoke(ctx, Uniai_Chat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uniaiClient) ChatStream(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChatChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Uniai_ServiceDesc.Streams[0], Uniai_ChatStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ChatRequest, ChatChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Uniai_ChatStreamClient = grpc.ServerStreamingClient[ChatChunk]
+
+func (c *uniaiClient) Embed(ctx context.Context, in *EmbedRequest, opts ...grpc.CallOption) (*EmbedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EmbedResponse)
+	err := c.cc.Invoke(ctx, Uniai_Embed_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UniaiServer is the server API for Uniai service.
+// All implementations must embed UnimplementedUniaiServer
+// for forward compatibility.
+//
+// Uniai mirrors the Go client: Chat and ChatStream go through the same
+// provider routing and middleware as uniai.Client.Chat, Embed through
+// uniai.Client.Embedding.
+type UniaiServer interface {
+	Chat(context.Context, *ChatRequest) (*ChatResponse, error)
+	ChatStream(*ChatRequest, grpc.ServerStreamingServer[ChatChunk]) error
+	Embed(context.Context, *EmbedRequest) (*EmbedResponse, error)
+	mustEmbedUnimplementedUniaiServer()
+}
+
+// UnimplementedUniaiServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedUniaiServer struct{}
+
+func (UnimplementedUniaiServer) Chat(context.Context, *ChatRequest) (*ChatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Chat not implemented")
+}
+func (UnimplementedUniaiServer) ChatStream(*ChatRequest, grpc.ServerStreamingServer[ChatChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method ChatStream not implemented")
+}
+func (UnimplementedUniaiServer) Embed(context.Context, *EmbedRequest) (*EmbedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Embed not implemented")
+}
+func (UnimplementedUniaiServer) mustEmbedUnimplementedUniaiServer() {}
+func (UnimplementedUniaiServer) testEmbeddedByValue()               {}
+
+// UnsafeUniaiServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UniaiServer will
+// result in compilation errors.
+type UnsafeUniaiServer interface {
+	mustEmbedUnimplementedUniaiServer()
+}
+
+func RegisterUniaiServer(s grpc.ServiceRegistrar, srv UniaiServer) {
+	// If the following call pancis, it indicates UnimplementedUniaiServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Uniai_ServiceDesc, srv)
+}
+
+func _Uniai_Chat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UniaiServer).Chat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uniai_Chat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UniaiServer).Chat(ctx, req.(*ChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Uniai_ChatStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChatRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UniaiServer).ChatStream(m, &grpc.GenericServerStream[ChatRequest, ChatChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Uniai_ChatStreamServer = grpc.ServerStreamingServer[ChatChunk]
+
+func _Uniai_Embed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmbedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UniaiServer).Embed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uniai_Embed_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UniaiServer).Embed(ctx, req.(*EmbedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Uniai_ServiceDesc is the grpc.ServiceDesc for Uniai service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Uniai_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "uniai.v1.Uniai",
+	HandlerType: (*UniaiServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Chat",
+			Handler:    _Uniai_Chat_Handler,
+		},
+		{
+			MethodName: "Embed",
+			Handler:    _Uniai_Embed_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ChatStream",
+			Handler:       _Uniai_ChatStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "uniai.proto",
+}
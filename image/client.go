@@ -6,14 +6,30 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/quailyquaily/uniai/internal/providers/bfl"
 	"github.com/quailyquaily/uniai/internal/providers/gemini"
 	"github.com/quailyquaily/uniai/internal/providers/openai"
+	"github.com/quailyquaily/uniai/internal/providers/stability"
 )
 
 type Config struct {
 	OpenAIAPIKey  string
 	OpenAIAPIBase string
 	GeminiAPIKey  string
+
+	// Azure OpenAI image deployments. AzureOpenAIDeployments maps model
+	// names to deployment names; requests for other models use
+	// AzureOpenAIDeployment, or the model name itself when that is empty.
+	AzureOpenAIAPIKey      string
+	AzureOpenAIEndpoint    string
+	AzureOpenAIAPIVersion  string
+	AzureOpenAIDeployment  string
+	AzureOpenAIDeployments map[string]string
+
+	StabilityAPIKey  string
+	StabilityAPIBase string
+	BFLAPIKey        string
+	BFLAPIBase       string
 }
 
 type Client struct {
@@ -41,15 +57,84 @@ func (c *Client) Create(ctx context.Context, opts ...Option) (*Result, error) {
 	switch provider {
 	case "openai", "openai_custom":
 		respData, err = openai.CreateImages(ctx, c.cfg.OpenAIAPIKey, c.cfg.OpenAIAPIBase, req.Model, req.Prompt, req.Count, req.Options.OpenAI)
+	case "azure":
+		opts := req.Options.Azure
+		if len(opts) == 0 {
+			opts = req.Options.OpenAI
+		}
+		respData, err = openai.CreateAzureImages(ctx, c.cfg.AzureOpenAIAPIKey, c.cfg.AzureOpenAIEndpoint, c.cfg.AzureOpenAIAPIVersion,
+			c.azureDeployment(req.Model), req.Model, req.Prompt, req.Count, opts)
 	case "gemini":
 		respData, err = gemini.CreateImages(ctx, c.cfg.GeminiAPIKey, req.Model, req.Prompt, req.Count, req.Options.Gemini)
+	case "stability":
+		respData, err = stability.CreateImages(ctx, c.cfg.StabilityAPIKey, c.cfg.StabilityAPIBase, req.Model, req.Prompt, req.Count, req.Options.Stability)
+	case "bfl", "flux":
+		respData, err = bfl.CreateImages(ctx, c.cfg.BFLAPIKey, c.cfg.BFLAPIBase, req.Model, req.Prompt, req.Count, req.Options.BFL)
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", provider)
 	}
+	return decodeResult(respData, err)
+}
+
+// Edit edits the request image following the prompt, repainting only the
+// masked areas when a mask is given. It is supported by openai, azure,
+// stability and bfl.
+func (c *Client) Edit(ctx context.Context, opts ...Option) (*Result, error) {
+	req := BuildRequest(opts...)
+	if len(req.Image) == 0 {
+		return nil, fmt.Errorf("input image is required")
+	}
+	provider := req.Provider
+	if provider == "" {
+		provider = pickProviderByModel(req.Model)
+	}
+
+	var (
+		respData []byte
+		err      error
+	)
+	switch provider {
+	case "openai", "openai_custom":
+		respData, err = openai.EditImages(ctx, c.cfg.OpenAIAPIKey, c.cfg.OpenAIAPIBase, req.Model, req.Prompt, req.Image, req.Mask, req.Count, req.Options.OpenAI)
+	case "azure":
+		opts := req.Options.Azure
+		if len(opts) == 0 {
+			opts = req.Options.OpenAI
+		}
+		respData, err = openai.EditAzureImages(ctx, c.cfg.AzureOpenAIAPIKey, c.cfg.AzureOpenAIEndpoint, c.cfg.AzureOpenAIAPIVersion,
+			c.azureDeployment(req.Model), req.Model, req.Prompt, req.Image, req.Mask, req.Count, opts)
+	case "stability":
+		respData, err = stability.EditImages(ctx, c.cfg.StabilityAPIKey, c.cfg.StabilityAPIBase, req.Prompt, req.Image, req.Mask, req.Count, req.Options.Stability)
+	case "bfl", "flux":
+		respData, err = bfl.EditImages(ctx, c.cfg.BFLAPIKey, c.cfg.BFLAPIBase, req.Model, req.Prompt, req.Image, req.Mask, req.Count, req.Options.BFL)
+	default:
+		return nil, fmt.Errorf("provider %s does not support image edits", provider)
+	}
+	return decodeResult(respData, err)
+}
+
+// Variations returns variations of the request image. Only openai
+// (dall-e-2) supports it.
+func (c *Client) Variations(ctx context.Context, opts ...Option) (*Result, error) {
+	req := BuildRequest(opts...)
+	if len(req.Image) == 0 {
+		return nil, fmt.Errorf("input image is required")
+	}
+	provider := req.Provider
+	if provider == "" {
+		provider = "openai"
+	}
+	if provider != "openai" && provider != "openai_custom" {
+		return nil, fmt.Errorf("provider %s does not support image variations", provider)
+	}
+	respData, err := openai.CreateVariations(ctx, c.cfg.OpenAIAPIKey, c.cfg.OpenAIAPIBase, req.Model, req.Image, req.Count, req.Options.OpenAI)
+	return decodeResult(respData, err)
+}
+
+func decodeResult(respData []byte, err error) (*Result, error) {
 	if err != nil {
 		return nil, err
 	}
-
 	var out Result
 	if err := json.Unmarshal(respData, &out); err != nil {
 		return nil, err
@@ -57,10 +142,26 @@ func (c *Client) Create(ctx context.Context, opts ...Option) (*Result, error) {
 	return &out, nil
 }
 
+func (c *Client) azureDeployment(model string) string {
+	if deployment, ok := c.cfg.AzureOpenAIDeployments[model]; ok {
+		return deployment
+	}
+	if c.cfg.AzureOpenAIDeployment != "" {
+		return c.cfg.AzureOpenAIDeployment
+	}
+	return model
+}
+
 func pickProviderByModel(model string) string {
 	if strings.HasPrefix(model, "gemini-") || strings.HasPrefix(model, "imagen-") {
 		return "gemini"
 	}
+	if strings.HasPrefix(model, "flux") {
+		return "bfl"
+	}
+	if strings.HasPrefix(model, "sd3") || strings.HasPrefix(model, "stable-") {
+		return "stability"
+	}
 	if strings.Contains(model, "gpt-") {
 		return "openai"
 	}
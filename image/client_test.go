@@ -0,0 +1,119 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/internal/providers/bfl"
+)
+
+func TestAzureDeployment(t *testing.T) {
+	var path, key string
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, key = r.URL.RequestURI(), r.Header.Get("api-key")
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		w.Write([]byte(`{"created":1,"data":[{"b64_json":"aGk="}]}`))
+	}))
+	defer srv.Close()
+
+	c := New(Config{
+		AzureOpenAIAPIKey:      "k",
+		AzureOpenAIEndpoint:    srv.URL,
+		AzureOpenAIDeployments: map[string]string{"dall-e-3": "art"},
+	})
+	res, err := c.Create(context.Background(), Image("dall-e-3", "a cat"), WithProvider("azure"),
+		WithOptions(Options{Azure: structs.JSONMap{"quality": "hd", "style": "natural"}}))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if path != "/openai/deployments/art/images/generations?api-version=2025-04-01-preview" || key != "k" {
+		t.Fatalf("unexpected request %s (key %q)", path, key)
+	}
+	if body["response_format"] != "b64_json" || body["quality"] != "hd" || body["model"] != nil {
+		t.Fatalf("unexpected body %v", body)
+	}
+	if len(res.Data) != 1 || res.MimeType != "image/png" || res.Usage.Quality != "hd" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestBFLPolling(t *testing.T) {
+	polls := 0
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/flux-dev":
+			if r.Header.Get("x-key") != "k" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"id":"t1","polling_url":"` + srv.URL + `/v1/get_result?id=t1"}`))
+		case "/v1/get_result":
+			polls++
+			if polls < 2 {
+				w.Write([]byte(`{"status":"Pending"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"Ready","result":{"sample":"` + srv.URL + `/sample.jpg"}}`))
+		case "/sample.jpg":
+			w.Write([]byte("img"))
+		}
+	}))
+	defer srv.Close()
+	bfl.PollInterval = time.Millisecond
+
+	c := New(Config{BFLAPIKey: "k", BFLAPIBase: srv.URL})
+	res, err := c.Create(context.Background(), Image("flux-dev", "a cat"),
+		WithOptions(Options{BFL: structs.JSONMap{"seed": 7, "steps": 20}}))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(res.Data) != 1 || res.Data[0].B64JSON != "aW1n" || res.MimeType != "image/jpeg" || len(res.Seeds) != 1 || res.Seeds[0] != 7 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestOpenAIEdit(t *testing.T) {
+	var fields map[string][]string
+	var files []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/images/edits" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fields = r.MultipartForm.Value
+		for name := range r.MultipartForm.File {
+			files = append(files, name)
+		}
+		w.Write([]byte(`{"created":1,"data":[{"b64_json":"aGk="}]}`))
+	}))
+	defer srv.Close()
+
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	c := New(Config{OpenAIAPIKey: "k", OpenAIAPIBase: srv.URL})
+	res, err := c.Edit(context.Background(), Image("dall-e-2", "add a hat"), WithImage(png), WithMask(png))
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if fields["prompt"][0] != "add a hat" || fields["size"][0] != "1024x1024" || len(files) != 2 {
+		t.Fatalf("unexpected form %v (files %v)", fields, files)
+	}
+	if len(res.Data) != 1 || res.MimeType != "image/png" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if _, err := c.Variations(context.Background(), Image("gpt-image-1", ""), WithImage(png)); err == nil {
+		t.Fatal("expected variations to reject gpt-image-1")
+	}
+}
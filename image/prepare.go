@@ -0,0 +1,248 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	stdimage "image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// Image detail levels for vision requests. Only OpenAI models distinguish
+// them; other providers ignore the detail.
+const (
+	DetailAuto = "auto"
+	DetailLow  = "low"
+	DetailHigh = "high"
+)
+
+// Limits describe what a provider accepts for an input image.
+type Limits struct {
+	// Formats are the accepted MIME types.
+	Formats []string
+	// MaxBytes bounds the encoded size of one image.
+	MaxBytes int
+	// MaxDimension bounds the longest side in pixels. Larger images are
+	// downscaled by the provider anyway, so sending them only costs
+	// bandwidth.
+	MaxDimension int
+}
+
+// LimitsFor returns the input image limits of provider.
+func LimitsFor(provider string) Limits {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "anthropic":
+		return Limits{Formats: []string{"image/jpeg", "image/png", "image/gif", "image/webp"}, MaxBytes: 5 << 20, MaxDimension: 1568}
+	case "bedrock":
+		return Limits{Formats: []string{"image/jpeg", "image/png", "image/gif", "image/webp"}, MaxBytes: 3750000, MaxDimension: 1568}
+	case "gemini":
+		return Limits{Formats: []string{"image/jpeg", "image/png", "image/webp"}, MaxBytes: 20 << 20, MaxDimension: 3072}
+	default:
+		return Limits{Formats: []string{"image/jpeg", "image/png", "image/gif", "image/webp"}, MaxBytes: 20 << 20, MaxDimension: 2048}
+	}
+}
+
+// Prepared is an image ready to send to a provider.
+type Prepared struct {
+	Data     []byte
+	MIMEType string
+	Width    int
+	Height   int
+	// Detail is the chosen detail level for OpenAI-style providers, and
+	// empty for the others.
+	Detail string
+	// Tokens is the estimated input token cost of the image.
+	Tokens int
+	// Resized and Reencoded report what Prepare changed.
+	Resized   bool
+	Reencoded bool
+}
+
+// Prepare fits an image to the limits of provider: it downscales images
+// larger than MaxDimension, converts unsupported formats to PNG, and
+// re-encodes images over MaxBytes as JPEG, shrinking them further if
+// needed. Images already within the limits are returned unchanged. detail
+// may be empty or DetailAuto to pick low detail for images no larger than
+// one 512px tile. WebP images can be passed through but not modified.
+func Prepare(data []byte, provider, detail string) (*Prepared, error) {
+	limits := LimitsFor(provider)
+	mimeType := http.DetectContentType(data)
+	cfg, _, err := stdimage.DecodeConfig(bytes.NewReader(data))
+	if err != nil && mimeType != "image/webp" {
+		return nil, fmt.Errorf("decode image config: %w", err)
+	}
+	out := &Prepared{Data: data, MIMEType: mimeType, Width: cfg.Width, Height: cfg.Height}
+
+	supported := containsString(limits.Formats, mimeType)
+	tooBig := max(cfg.Width, cfg.Height) > limits.MaxDimension
+	if supported && !tooBig && len(data) <= limits.MaxBytes {
+		out.finish(provider, detail)
+		return out, nil
+	}
+	if mimeType == "image/webp" {
+		return nil, fmt.Errorf("webp image exceeds %s limits and cannot be re-encoded", provider)
+	}
+
+	img, _, err := stdimage.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if tooBig {
+		img = fit(img, limits.MaxDimension)
+		out.Resized = true
+	}
+	// keep lossless formats lossless unless the size forces JPEG
+	format := "image/png"
+	if mimeType == "image/jpeg" {
+		format = "image/jpeg"
+	}
+	encoded, err := encode(img, format, 90)
+	if err != nil {
+		return nil, err
+	}
+	for quality := 85; len(encoded) > limits.MaxBytes; quality -= 15 {
+		if quality < 40 {
+			b := img.Bounds()
+			if max(b.Dx(), b.Dy()) < 64 {
+				return nil, fmt.Errorf("image cannot be reduced below %d bytes", limits.MaxBytes)
+			}
+			img = fit(img, max(b.Dx(), b.Dy())*3/4)
+			out.Resized = true
+			quality = 70
+		}
+		format = "image/jpeg"
+		if encoded, err = encode(img, format, quality); err != nil {
+			return nil, err
+		}
+	}
+	b := img.Bounds()
+	out.Data, out.MIMEType, out.Width, out.Height = encoded, format, b.Dx(), b.Dy()
+	out.Reencoded = true
+	out.finish(provider, detail)
+	return out, nil
+}
+
+func (p *Prepared) finish(provider, detail string) {
+	if usesDetail(provider) {
+		if detail == "" || detail == DetailAuto {
+			detail = DetailHigh
+			if p.Width <= 512 && p.Height <= 512 {
+				detail = DetailLow
+			}
+		}
+		p.Detail = detail
+	}
+	p.Tokens = EstimateTokens(provider, p.Width, p.Height, p.Detail)
+}
+
+func usesDetail(provider string) bool {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "anthropic", "bedrock", "gemini":
+		return false
+	}
+	return true
+}
+
+// EstimateTokens returns the approximate input tokens of a width x height
+// image on provider, following each provider's published formula.
+func EstimateTokens(provider string, width, height int, detail string) int {
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	w, h := float64(width), float64(height)
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "anthropic", "bedrock":
+		if long := math.Max(w, h); long > 1568 {
+			w, h = w*1568/long, h*1568/long
+		}
+		return int(math.Ceil(w * h / 750))
+	case "gemini":
+		if w <= 384 && h <= 384 {
+			return 258
+		}
+		return int(math.Ceil(w/768)*math.Ceil(h/768)) * 258
+	default:
+		if detail == DetailLow {
+			return 85
+		}
+		// fit in 2048x2048, then scale the short side down to 768
+		if long := math.Max(w, h); long > 2048 {
+			w, h = w*2048/long, h*2048/long
+		}
+		if short := math.Min(w, h); short > 768 {
+			w, h = w*768/short, h*768/short
+		}
+		tiles := math.Ceil(w/512) * math.Ceil(h/512)
+		return int(tiles)*170 + 85
+	}
+}
+
+// fit downscales img so its longest side is at most size, averaging the
+// source pixels covered by each destination pixel.
+func fit(img stdimage.Image, size int) stdimage.Image {
+	b := img.Bounds()
+	long := max(b.Dx(), b.Dy())
+	if long <= size || size <= 0 {
+		return img
+	}
+	dw := max(1, b.Dx()*size/long)
+	dh := max(1, b.Dy()*size/long)
+	src := stdimage.NewNRGBA(stdimage.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	dst := stdimage.NewNRGBA(stdimage.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*b.Dy()/dh, max((y+1)*b.Dy()/dh, y*b.Dy()/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*b.Dx()/dw, max((x+1)*b.Dx()/dw, x*b.Dx()/dw+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
+
+func encode(img stdimage.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if format == "image/png" {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("encode png: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+	// JPEG has no alpha: flatten onto white
+	b := img.Bounds()
+	flat := stdimage.NewRGBA(b)
+	draw.Draw(flat, b, stdimage.NewUniform(color.White), stdimage.Point{}, draw.Src)
+	draw.Draw(flat, b, img, b.Min, draw.Over)
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("encode jpeg: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
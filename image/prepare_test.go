@@ -0,0 +1,58 @@
+package image
+
+import (
+	"bytes"
+	stdimage "image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	cases := []struct {
+		provider string
+		w, h     int
+		detail   string
+		want     int
+	}{
+		{"openai", 1024, 1024, DetailHigh, 765},
+		{"openai", 2048, 4096, DetailHigh, 1105},
+		{"openai", 4096, 4096, DetailLow, 85},
+		{"anthropic", 1000, 1000, "", 1334},
+		{"gemini", 300, 300, "", 258},
+		{"gemini", 1000, 800, "", 1032},
+	}
+	for _, c := range cases {
+		if got := EstimateTokens(c.provider, c.w, c.h, c.detail); got != c.want {
+			t.Errorf("%s %dx%d %s: got %d, want %d", c.provider, c.w, c.h, c.detail, got, c.want)
+		}
+	}
+}
+
+func TestPrepareResizes(t *testing.T) {
+	img := stdimage.NewNRGBA(stdimage.Rect(0, 0, 3000, 1500))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	img.Set(0, 0, color.Black)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	p, err := Prepare(buf.Bytes(), "anthropic", "")
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if !p.Resized || p.Width != 1568 || p.Height != 784 || p.MIMEType != "image/png" || p.Detail != "" {
+		t.Fatalf("unexpected %+v", p)
+	}
+
+	small, err := Prepare(p.Data, "openai", DetailAuto)
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if small.Resized || small.Reencoded || small.Detail != DetailHigh || small.Tokens != EstimateTokens("openai", 1568, 784, DetailHigh) {
+		t.Fatalf("unexpected %+v", small)
+	}
+}
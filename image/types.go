@@ -5,6 +5,10 @@ import "github.com/lyricat/goutils/structs"
 type Options struct {
 	OpenAI structs.JSONMap `json:"openai_options,omitempty"`
 	Gemini structs.JSONMap `json:"gemini_options,omitempty"`
+	// Azure falls back to OpenAI when empty.
+	Azure     structs.JSONMap `json:"azure_options,omitempty"`
+	Stability structs.JSONMap `json:"stability_options,omitempty"`
+	BFL       structs.JSONMap `json:"bfl_options,omitempty"`
 }
 
 type Request struct {
@@ -13,6 +17,10 @@ type Request struct {
 	Prompt   string  `json:"prompt,omitempty"`
 	Count    int     `json:"count,omitempty"`
 	Options  Options `json:"options,omitempty"`
+
+	// Image and Mask are the inputs of Edit and Variations.
+	Image []byte `json:"image,omitempty"`
+	Mask  []byte `json:"mask,omitempty"`
 }
 
 type Result struct {
@@ -22,6 +30,9 @@ type Result struct {
 	} `json:"data"`
 	MimeType string           `json:"mime_type"`
 	Usage    CreateImageUsage `json:"usage"`
+	// Seeds holds the seed of each image for providers that report or
+	// accept one (stability, bfl).
+	Seeds []int64 `json:"seeds,omitempty"`
 }
 
 type CreateImageUsage struct {
@@ -63,3 +74,15 @@ func WithCount(count int) Option {
 func WithOptions(opts Options) Option {
 	return func(r *Request) { r.Options = opts }
 }
+
+// WithImage sets the input image of an edit or variation request.
+func WithImage(data []byte) Option {
+	return func(r *Request) { r.Image = data }
+}
+
+// WithMask sets the inpainting mask of an edit request. OpenAI repaints the
+// fully transparent areas of the mask; Stability and Flux repaint the white
+// areas.
+func WithMask(mask []byte) Option {
+	return func(r *Request) { r.Mask = mask }
+}
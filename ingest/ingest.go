@@ -0,0 +1,339 @@
+// Package ingest turns files into prompt text. Plain text is used as is;
+// images and PDFs go through an OCR backend, either a vision model (Vision)
+// or a local tesseract install (Tesseract). The resulting Document can be
+// sent as a message or split into chunks for retrieval.
+//
+//	in := ingest.New(ingest.Config{OCR: ingest.Tesseract{Languages: []string{"eng"}}})
+//	doc, err := in.File(ctx, "scan.pdf", data)
+//	chunks := doc.Chunks(ingest.ChunkConfig{MaxTokens: 400})
+package ingest
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/quailyquaily/uniai/attachment"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/tokens"
+)
+
+// Page is the text of one page. Images are a single page.
+type Page struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+}
+
+// OCR extracts the text of an image or PDF.
+type OCR interface {
+	Recognize(ctx context.Context, data []byte, mimeType string) ([]Page, error)
+}
+
+// Document is an ingested file.
+type Document struct {
+	Name     string `json:"name,omitempty"`
+	MIMEType string `json:"mime_type"`
+	Pages    []Page `json:"pages"`
+	// OCR reports whether the text was recognized rather than read.
+	OCR bool `json:"ocr"`
+}
+
+// Text returns the pages separated by blank lines.
+func (d *Document) Text() string {
+	texts := make([]string, 0, len(d.Pages))
+	for _, p := range d.Pages {
+		if t := strings.TrimSpace(p.Text); t != "" {
+			texts = append(texts, t)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// Message returns the document as a user message, wrapped in a <document>
+// element named after the file.
+func (d *Document) Message() chat.Message {
+	var b strings.Builder
+	b.WriteString("<document")
+	if d.Name != "" {
+		b.WriteString(" name=")
+		b.WriteString(strconv.Quote(d.Name))
+	}
+	b.WriteString(">\n")
+	b.WriteString(d.Text())
+	b.WriteString("\n</document>")
+	return chat.User(b.String())
+}
+
+// Config configures an Ingester.
+type Config struct {
+	// OCR handles images and PDFs. Without it only text files are accepted.
+	OCR OCR
+}
+
+// Ingester turns files into Documents.
+type Ingester struct {
+	cfg Config
+}
+
+func New(cfg Config) *Ingester {
+	return &Ingester{cfg: cfg}
+}
+
+// File ingests data. The MIME type is taken from the file name's extension,
+// or sniffed from the content.
+func (in *Ingester) File(ctx context.Context, name string, data []byte) (*Document, error) {
+	return in.ingest(ctx, name, mimeTypeOf(name, data), data)
+}
+
+func (in *Ingester) ingest(ctx context.Context, name, mimeType string, data []byte) (*Document, error) {
+	doc := &Document{Name: name, MIMEType: mimeType}
+	switch {
+	case isText(mimeType):
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("ingest %s: text is not valid UTF-8", name)
+		}
+		doc.Pages = []Page{{Number: 1, Text: string(data)}}
+		return doc, nil
+	case strings.HasPrefix(mimeType, "image/") || mimeType == "application/pdf":
+		if in.cfg.OCR == nil {
+			return nil, fmt.Errorf("ingest %s: %s needs OCR, but none is configured", name, mimeType)
+		}
+		pages, err := in.cfg.OCR.Recognize(ctx, data, mimeType)
+		if err != nil {
+			return nil, fmt.Errorf("ingest %s: %w", name, err)
+		}
+		doc.Pages, doc.OCR = pages, true
+		return doc, nil
+	}
+	return nil, fmt.Errorf("ingest %s: unsupported type %s", name, mimeType)
+}
+
+// Attachment ingests stored content.
+func (in *Ingester) Attachment(ctx context.Context, store attachment.Store, ref attachment.Ref) (*Document, error) {
+	data, stored, err := store.Get(ctx, ref.Digest)
+	if err != nil {
+		return nil, err
+	}
+	name := cmp.Or(ref.Name, stored.Name, ref.Digest)
+	mimeType, _, _ := strings.Cut(cmp.Or(ref.MIMEType, stored.MIMEType), ";")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = mimeTypeOf(name, data)
+	}
+	return in.ingest(ctx, name, strings.TrimSpace(mimeType), data)
+}
+
+var extensionTypes = map[string]string{
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".csv":  "text/csv",
+	".json": "application/json",
+	".html": "text/html",
+	".htm":  "text/html",
+	".pdf":  "application/pdf",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".bmp":  "image/bmp",
+}
+
+func mimeTypeOf(name string, data []byte) string {
+	if t, ok := extensionTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return t
+	}
+	t, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	return t
+}
+
+func isText(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "text/") || mimeType == "application/json"
+}
+
+// DefaultChunkTokens is used when ChunkConfig.MaxTokens is not set.
+const DefaultChunkTokens = 512
+
+// ChunkConfig configures Document.Chunks.
+type ChunkConfig struct {
+	// MaxTokens bounds the estimated tokens of a chunk (default 512). A
+	// single longer sentence becomes a chunk of its own.
+	MaxTokens int
+	// Overlap is the number of tokens from the end of a chunk repeated at
+	// the start of the next, in whole sentences.
+	Overlap int
+}
+
+// Chunk is a piece of a Document for retrieval.
+type Chunk struct {
+	Index  int    `json:"index"`
+	Page   int    `json:"page"`
+	Text   string `json:"text"`
+	Tokens int    `json:"tokens"`
+}
+
+// Chunks splits the document at sentence boundaries into chunks of at most
+// MaxTokens. Chunks do not span pages, so each can cite its page.
+func (d *Document) Chunks(cfg ChunkConfig) []Chunk {
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = DefaultChunkTokens
+	}
+	cfg.Overlap = min(max(cfg.Overlap, 0), cfg.MaxTokens/2)
+	var out []Chunk
+	for _, page := range d.Pages {
+		var current []string
+		size := 0
+		flush := func() {
+			text := strings.TrimSpace(strings.Join(current, " "))
+			if text == "" {
+				return
+			}
+			out = append(out, Chunk{Index: len(out), Page: page.Number, Text: text, Tokens: tokens.Estimate(text)})
+		}
+		for _, s := range sentences(page.Text) {
+			n := tokens.Estimate(s)
+			if size+n > cfg.MaxTokens && len(current) > 0 {
+				flush()
+				// carry whole sentences from the end for the overlap
+				keep, kept := len(current), 0
+				for keep > 0 {
+					m := tokens.Estimate(current[keep-1])
+					if kept+m > cfg.Overlap || kept+m+n > cfg.MaxTokens {
+						break
+					}
+					keep--
+					kept += m
+				}
+				current = slices.Clone(current[keep:])
+				size = kept
+			}
+			current = append(current, s)
+			size += n
+		}
+		flush()
+	}
+	return out
+}
+
+// sentences splits text into trimmed sentences. Paragraph breaks also end
+// a sentence.
+func sentences(text string) []string {
+	var out []string
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.Join(strings.Fields(para), " ")
+		start := 0
+		for i := 0; i < len(para); i++ {
+			switch para[i] {
+			case '.', '!', '?':
+				if i+1 == len(para) || para[i+1] == ' ' {
+					out = append(out, para[start:i+1])
+					start = i + 2
+					i++
+				}
+			}
+		}
+		if start < len(para) {
+			out = append(out, para[start:])
+		}
+	}
+	return out
+}
+
+// Tesseract runs a local tesseract install. PDFs are first rendered to
+// images with pdftoppm from poppler-utils.
+type Tesseract struct {
+	// Command is the tesseract binary (default "tesseract").
+	Command string
+	// Languages are tesseract language codes, such as "eng" or "deu".
+	Languages []string
+	// PDFToPPM is the pdftoppm binary (default "pdftoppm").
+	PDFToPPM string
+	// DPI is the PDF rendering resolution (default 300).
+	DPI int
+}
+
+func (t Tesseract) Recognize(ctx context.Context, data []byte, mimeType string) ([]Page, error) {
+	images := [][]byte{data}
+	if mimeType == "application/pdf" {
+		var err error
+		if images, err = RenderPDF(ctx, t.PDFToPPM, t.DPI, data); err != nil {
+			return nil, err
+		}
+	}
+	command := t.Command
+	if command == "" {
+		command = "tesseract"
+	}
+	args := []string{"stdin", "stdout"}
+	if len(t.Languages) > 0 {
+		args = append(args, "-l", strings.Join(t.Languages, "+"))
+	}
+	pages := make([]Page, 0, len(images))
+	for i, img := range images {
+		cmd := exec.CommandContext(ctx, command, args...)
+		cmd.Stdin = bytes.NewReader(img)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("tesseract page %d: %w: %s", i+1, err, strings.TrimSpace(stderr.String()))
+		}
+		pages = append(pages, Page{Number: i + 1, Text: strings.TrimSpace(string(out))})
+	}
+	return pages, nil
+}
+
+// RenderPDF renders each page of a PDF to PNG with pdftoppm. An empty
+// command means "pdftoppm"; dpi defaults to 300.
+func RenderPDF(ctx context.Context, command string, dpi int, pdf []byte) ([][]byte, error) {
+	if command == "" {
+		command = "pdftoppm"
+	}
+	if dpi <= 0 {
+		dpi = 300
+	}
+	dir, err := os.MkdirTemp("", "uniai-pdf-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in := filepath.Join(dir, "in.pdf")
+	if err := os.WriteFile(in, pdf, 0o600); err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, command, "-r", strconv.Itoa(dpi), "-png", in, filepath.Join(dir, "page"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	// pages are named page-1.png, or page-01.png and so on for longer
+	// documents, so order by the number
+	files, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return nil, err
+	}
+	number := func(path string) int {
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "page-"), ".png"))
+		return n
+	}
+	slices.SortFunc(files, func(a, b string) int { return number(a) - number(b) })
+	if len(files) == 0 {
+		return nil, fmt.Errorf("pdftoppm rendered no pages")
+	}
+	images := make([][]byte, len(files))
+	for i, f := range files {
+		if images[i], err = os.ReadFile(f); err != nil {
+			return nil, err
+		}
+	}
+	return images, nil
+}
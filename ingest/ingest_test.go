@@ -0,0 +1,102 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/attachment"
+)
+
+func TestIngestTesseractPDF(t *testing.T) {
+	dir := t.TempDir()
+	// stand-ins that render two pages and "recognize" the image bytes
+	pdftoppm := filepath.Join(dir, "pdftoppm")
+	script := "#!/bin/sh\nfor last; do :; done\nprintf 'page two' > \"$last-2.png\"\nprintf 'page one' > \"$last-1.png\"\n"
+	if err := os.WriteFile(pdftoppm, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tesseract := filepath.Join(dir, "tesseract")
+	if err := os.WriteFile(tesseract, []byte("#!/bin/sh\necho \"$4:\"; cat\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	in := New(Config{OCR: Tesseract{Command: tesseract, PDFToPPM: pdftoppm, Languages: []string{"eng", "deu"}}})
+	doc, err := in.File(context.Background(), "scan.pdf", []byte("%PDF-1.4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.OCR || len(doc.Pages) != 2 || doc.Pages[0].Text != "eng+deu:\npage one" || doc.Pages[1].Number != 2 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+
+	if _, err := New(Config{}).File(context.Background(), "photo.png", []byte("x")); err == nil {
+		t.Fatal("expected an error without OCR")
+	}
+}
+
+func TestIngestVision(t *testing.T) {
+	var sent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "c1", "object": "chat.completion", "model": "m",
+			"choices": []map[string]any{{"index": 0, "finish_reason": "stop", "message": map[string]any{"role": "assistant", "content": " INVOICE 42 "}}},
+		})
+	}))
+	defer srv.Close()
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)))
+
+	store := attachment.NewMemoryStore()
+	ref, err := store.Put(context.Background(), buf.Bytes(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := New(Config{OCR: NewVision(VisionConfig{APIKey: "k", BaseURL: srv.URL})})
+	doc, err := in.Attachment(context.Background(), store, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text() != "INVOICE 42" || doc.MIMEType != "image/png" || !strings.Contains(sent, "data:image/png;base64,") {
+		t.Fatalf("unexpected document %+v, request %s", doc, sent)
+	}
+	if msg := doc.Message(); !strings.Contains(msg.Content, "<document name=\"sha256:") {
+		t.Fatalf("unexpected message %q", msg.Content)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	doc := &Document{Pages: []Page{
+		{Number: 1, Text: "One two three four. Five six seven eight.\n\nNine ten eleven twelve. Thirteen."},
+		{Number: 2, Text: "Last page."},
+	}}
+	chunks := doc.Chunks(ChunkConfig{MaxTokens: 12, Overlap: 6})
+	want := []string{
+		"One two three four. Five six seven eight.",
+		"Five six seven eight. Nine ten eleven twelve.",
+		"Nine ten eleven twelve. Thirteen.",
+		"Last page.",
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("unexpected chunks %+v", chunks)
+	}
+	for i, c := range chunks {
+		if c.Text != want[i] || c.Tokens > 12 {
+			t.Fatalf("chunk %d: %+v, want %q", i, c, want[i])
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.Page != 2 || last.Text != "Last page." || last.Index != 3 {
+		t.Fatalf("unexpected last chunk %+v", last)
+	}
+}
@@ -0,0 +1,105 @@
+package ingest
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+
+	"github.com/quailyquaily/uniai/image"
+)
+
+// DefaultVisionPrompt is used when VisionConfig.Prompt is not set.
+const DefaultVisionPrompt = "Transcribe all text in this image exactly as written, in reading order. Render tables as Markdown tables. Reply with the transcription only; reply with nothing if there is no text."
+
+// VisionConfig configures Vision.
+type VisionConfig struct {
+	// APIKey and BaseURL select an OpenAI-compatible chat completions
+	// endpoint with image input, such as OpenAI, Gemini's OpenAI endpoint
+	// or a local server.
+	APIKey  string
+	BaseURL string
+	// Model defaults to "gpt-4o-mini".
+	Model string
+	// Provider picks the image limits applied before upload (see
+	// image.LimitsFor); it defaults to "openai".
+	Provider string
+	// Prompt is the transcription instruction.
+	Prompt string
+	// PDFToPPM and DPI are used to render PDF pages, as in Tesseract.
+	PDFToPPM string
+	DPI      int
+}
+
+// Vision recognizes text with a vision model, one call per page.
+type Vision struct {
+	cfg    VisionConfig
+	client openai.Client
+}
+
+func NewVision(cfg VisionConfig) *Vision {
+	if cfg.Model == "" {
+		cfg.Model = "gpt-4o-mini"
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = "openai"
+	}
+	if cfg.Prompt == "" {
+		cfg.Prompt = DefaultVisionPrompt
+	}
+	opts := []option.RequestOption{option.WithAPIKey(cfg.APIKey)}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+	}
+	return &Vision{cfg: cfg, client: openai.NewClient(opts...)}
+}
+
+func (v *Vision) Recognize(ctx context.Context, data []byte, mimeType string) ([]Page, error) {
+	images := [][]byte{data}
+	if mimeType == "application/pdf" {
+		var err error
+		if images, err = RenderPDF(ctx, v.cfg.PDFToPPM, v.cfg.DPI, data); err != nil {
+			return nil, err
+		}
+	}
+	pages := make([]Page, 0, len(images))
+	for i, img := range images {
+		text, err := v.transcribe(ctx, img)
+		if err != nil {
+			return nil, fmt.Errorf("vision page %d: %w", i+1, err)
+		}
+		pages = append(pages, Page{Number: i + 1, Text: text})
+	}
+	return pages, nil
+}
+
+func (v *Vision) transcribe(ctx context.Context, data []byte) (string, error) {
+	prepared, err := image.Prepare(data, v.cfg.Provider, image.DetailHigh)
+	if err != nil {
+		return "", err
+	}
+	url := "data:" + prepared.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(prepared.Data)
+	imageURL := openai.ChatCompletionContentPartImageImageURLParam{URL: url}
+	if prepared.Detail != "" {
+		imageURL.Detail = prepared.Detail
+	}
+	resp, err := v.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model: v.cfg.Model,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+				openai.TextContentPart(v.cfg.Prompt),
+				openai.ImageContentPart(imageURL),
+			}),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,110 @@
+package oaicompat
+
+import (
+	"encoding/json"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/respjson"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Citations returns the sources of a web search reply: the url_citation
+// annotations of OpenAI search models on the Primary choice, and the
+// search_results (or bare citations) field of Perplexity.
+func Citations(resp *openai.ChatCompletion) []chat.Citation {
+	if resp == nil {
+		return nil
+	}
+	var out []chat.Citation
+	if i := Primary(resp.Choices); i >= 0 {
+		msg := resp.Choices[i].Message
+		out = AnnotationCitations(msg.Content, 0, msg.Annotations)
+	}
+	return MergeCitations(out, CitationFields(resp.JSON.ExtraFields))
+}
+
+// AnnotationCitations converts url_citation annotations on text into
+// citations. Annotation indexes count characters; spans are byte offsets,
+// shifted by offset.
+func AnnotationCitations(text string, offset int, annotations []openai.ChatCompletionMessageAnnotation) []chat.Citation {
+	var out []chat.Citation
+	for _, a := range annotations {
+		u := a.URLCitation
+		if u.URL == "" {
+			continue
+		}
+		c := chat.Citation{URL: u.URL, Title: u.Title}
+		if u.EndIndex > u.StartIndex {
+			start, end := byteOffset(text, int(u.StartIndex)), byteOffset(text, int(u.EndIndex))
+			c.Span = &chat.Span{Start: offset + start, End: offset + end}
+		}
+		out = chat.AddCitation(out, c)
+	}
+	return out
+}
+
+// DeltaAnnotations returns the annotations of a stream delta, which the SDK
+// keeps among the extra fields.
+func DeltaAnnotations(fields map[string]respjson.Field) []openai.ChatCompletionMessageAnnotation {
+	f, ok := fields["annotations"]
+	if !ok {
+		return nil
+	}
+	var annotations []openai.ChatCompletionMessageAnnotation
+	if json.Unmarshal([]byte(f.Raw()), &annotations) != nil {
+		return nil
+	}
+	return annotations
+}
+
+// CitationFields parses the Perplexity search_results and citations fields
+// of a response or stream chunk.
+func CitationFields(fields map[string]respjson.Field) []chat.Citation {
+	var out []chat.Citation
+	if f, ok := fields["search_results"]; ok {
+		var results []struct {
+			URL     string `json:"url"`
+			Title   string `json:"title"`
+			Snippet string `json:"snippet"`
+		}
+		if json.Unmarshal([]byte(f.Raw()), &results) == nil {
+			for _, r := range results {
+				if r.URL != "" {
+					out = chat.AddCitation(out, chat.Citation{URL: r.URL, Title: r.Title, Snippet: r.Snippet})
+				}
+			}
+		}
+	}
+	if f, ok := fields["citations"]; ok {
+		var urls []string
+		if json.Unmarshal([]byte(f.Raw()), &urls) == nil {
+			for _, u := range urls {
+				if u != "" {
+					out = chat.AddCitation(out, chat.Citation{URL: u})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// MergeCitations appends the citations in add to list, skipping ones that
+// are already present.
+func MergeCitations(list, add []chat.Citation) []chat.Citation {
+	for _, c := range add {
+		list = chat.AddCitation(list, c)
+	}
+	return list
+}
+
+// byteOffset returns the byte offset of the n-th character of text.
+func byteOffset(text string, n int) int {
+	i := 0
+	for pos := range text {
+		if i == n {
+			return pos
+		}
+		i++
+	}
+	return len(text)
+}
@@ -7,17 +7,27 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/lyricat/goutils/structs"
 	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/respjson"
 	"github.com/openai/openai-go/v3/shared"
 	"github.com/quailyquaily/uniai/chat"
 	"github.com/quailyquaily/uniai/internal/toolschema"
 )
 
-// ToMessages converts chat.Message slice to OpenAI SDK message params.
+var messagePool sync.Pool
+
+// ToMessages converts chat.Message slice to OpenAI SDK message params. The
+// slice may come from a pool; see ReleaseMessages.
 func ToMessages(input []chat.Message) ([]openai.ChatCompletionMessageParamUnion, error) {
-	out := make([]openai.ChatCompletionMessageParamUnion, 0, len(input))
+	var out []openai.ChatCompletionMessageParamUnion
+	if p, ok := messagePool.Get().(*[]openai.ChatCompletionMessageParamUnion); ok && cap(*p) >= len(input) {
+		out = (*p)[:0]
+	} else {
+		out = make([]openai.ChatCompletionMessageParamUnion, 0, len(input))
+	}
 	for _, m := range input {
 		switch m.Role {
 		case chat.RoleSystem:
@@ -29,9 +39,15 @@ func ToMessages(input []chat.Message) ([]openai.ChatCompletionMessageParamUnion,
 			}
 			out = append(out, openai.ChatCompletionMessageParamUnion{OfSystem: &msg})
 		case chat.RoleUser:
+			if len(m.Videos) > 0 {
+				return nil, fmt.Errorf("videos are not supported by chat completions")
+			}
 			msg := openai.ChatCompletionUserMessageParam{
 				Content: openai.ChatCompletionUserMessageParamContentUnion{OfString: openai.String(m.Content)},
 			}
+			if len(m.Images) > 0 {
+				msg.Content = openai.ChatCompletionUserMessageParamContentUnion{OfArrayOfContentParts: toContentParts(m)}
+			}
 			if m.Name != "" {
 				msg.Name = openai.String(m.Name)
 			}
@@ -44,6 +60,9 @@ func ToMessages(input []chat.Message) ([]openai.ChatCompletionMessageParamUnion,
 			if m.Name != "" {
 				msg.Name = openai.String(m.Name)
 			}
+			if m.Refusal != "" {
+				msg.Refusal = openai.String(m.Refusal)
+			}
 			if len(m.ToolCalls) > 0 {
 				msg.ToolCalls = ToToolCallParams(m.ToolCalls)
 			}
@@ -60,6 +79,30 @@ func ToMessages(input []chat.Message) ([]openai.ChatCompletionMessageParamUnion,
 	return out, nil
 }
 
+// toContentParts returns the text and images of a user message as
+// content parts.
+func toContentParts(m chat.Message) []openai.ChatCompletionContentPartUnionParam {
+	parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(m.Images)+1)
+	if m.Content != "" {
+		parts = append(parts, openai.TextContentPart(m.Content))
+	}
+	for _, img := range m.Images {
+		parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: img.URL, Detail: img.Detail}))
+	}
+	return parts
+}
+
+// ReleaseMessages returns a slice built by ToMessages to the pool. Call it
+// only once the request has been sent and nothing references msgs.
+func ReleaseMessages(msgs []openai.ChatCompletionMessageParamUnion) {
+	if cap(msgs) == 0 {
+		return
+	}
+	clear(msgs)
+	msgs = msgs[:0]
+	messagePool.Put(&msgs)
+}
+
 // ToToolParams converts chat.Tool slice to OpenAI SDK tool params.
 func ToToolParams(tools []chat.Tool) ([]openai.ChatCompletionToolUnionParam, error) {
 	out := make([]openai.ChatCompletionToolUnionParam, 0, len(tools))
@@ -156,6 +199,125 @@ func ToToolCalls(calls []openai.ChatCompletionMessageToolCallUnion) []chat.ToolC
 	return out
 }
 
+// FinishReason returns the normalized finish reason of the first choice,
+// skipping choices blocked by the content filter when another one finished.
+func FinishReason(choices []openai.ChatCompletionChoice) string {
+	reason := ""
+	for _, choice := range choices {
+		if choice.FinishReason == "" {
+			continue
+		}
+		r := chat.NormalizeFinishReason(string(choice.FinishReason))
+		// a blocked choice is reported in ItemErrors when others succeeded
+		if r != chat.FinishReasonContentFilter {
+			return r
+		}
+		if reason == "" {
+			reason = r
+		}
+	}
+	return reason
+}
+
+// ItemErrors reports the choices of a multi-choice response that the
+// content filter blocked, whether or not another choice succeeded. A single
+// choice is described by FinishReason alone.
+func ItemErrors(choices []openai.ChatCompletionChoice) []chat.ItemError {
+	if len(choices) < 2 {
+		return nil
+	}
+	var out []chat.ItemError
+	for _, choice := range choices {
+		if chat.NormalizeFinishReason(string(choice.FinishReason)) == chat.FinishReasonContentFilter {
+			out = append(out, chat.ItemError{
+				Index:      int(choice.Index),
+				ErrorClass: chat.ErrorClassContentFilter,
+				Error:      "choice blocked by content filter",
+			})
+		}
+	}
+	return out
+}
+
+// Primary returns the position in choices of the choice a Result reports:
+// the first one the content filter did not block, or the first one when
+// all were. It is -1 when there are no choices.
+func Primary(choices []openai.ChatCompletionChoice) int {
+	for i, choice := range choices {
+		if chat.NormalizeFinishReason(string(choice.FinishReason)) != chat.FinishReasonContentFilter {
+			return i
+		}
+	}
+	if len(choices) == 0 {
+		return -1
+	}
+	return 0
+}
+
+// Choices returns every choice of a multi-choice response, for
+// Result.Choices. A single choice is the Result itself.
+func Choices(choices []openai.ChatCompletionChoice) []chat.Choice {
+	if len(choices) < 2 {
+		return nil
+	}
+	out := make([]chat.Choice, len(choices))
+	for i, choice := range choices {
+		out[i] = chat.Choice{
+			Index:        int(choice.Index),
+			Text:         choice.Message.Content,
+			FinishReason: chat.NormalizeFinishReason(string(choice.FinishReason)),
+			Refusal:      choice.Message.Refusal,
+		}
+		if len(choice.Message.ToolCalls) > 0 {
+			out[i].ToolCalls = ToToolCalls(choice.Message.ToolCalls)
+		}
+	}
+	return out
+}
+
+// reasoningFields are the non-standard fields in which OpenAI-compatible
+// servers (DeepSeek, vLLM, Ollama, OpenRouter) return chain-of-thought.
+var reasoningFields = []string{"reasoning_content", "reasoning"}
+
+// Reasoning returns the chain-of-thought of the first choice that has one.
+func Reasoning(choices []openai.ChatCompletionChoice) string {
+	for _, choice := range choices {
+		if text := ReasoningField(choice.Message.JSON.ExtraFields); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// ReasoningField returns the reasoning string among the extra fields of a
+// message or stream delta.
+func ReasoningField(fields map[string]respjson.Field) string {
+	for _, key := range reasoningFields {
+		field, ok := fields[key]
+		if !ok {
+			continue
+		}
+		var text string
+		if err := json.Unmarshal([]byte(field.Raw()), &text); err == nil && text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// AssistantTurn returns the Result.Messages of a response, keeping the
+// refusal the model may return in place of content.
+func AssistantTurn(choices []openai.ChatCompletionChoice, text string, calls []chat.ToolCall) []chat.Message {
+	msgs := chat.AssistantTurn(text, calls)
+	for _, choice := range choices {
+		if choice.Message.Refusal != "" {
+			msgs[0].Refusal = choice.Message.Refusal
+			break
+		}
+	}
+	return msgs
+}
+
 // ApplyOptions applies shared OpenAI-compatible option fields to params.
 func ApplyOptions(params *openai.ChatCompletionNewParams, opts structs.JSONMap) {
 	if params == nil || len(opts) == 0 {
@@ -227,6 +389,30 @@ func ApplyOptions(params *openai.ChatCompletionNewParams, opts structs.JSONMap)
 	if opt.HasKey("response_format") {
 		ApplyResponseFormat(params, (*opt)["response_format"])
 	}
+	if opt.HasKey("extra_body") {
+		if extra := ParseAnyMap((*opt)["extra_body"]); len(extra) > 0 {
+			params.SetExtraFields(extra)
+		}
+	}
+}
+
+// ApplyParallelToolCalls sets parallel_tool_calls from the portable option.
+// It is only sent with tools, since OpenAI rejects it otherwise.
+func ApplyParallelToolCalls(params *openai.ChatCompletionNewParams, parallel *bool) {
+	if parallel != nil && len(params.Tools) > 0 {
+		params.ParallelToolCalls = openai.Bool(*parallel)
+	}
+}
+
+// ParseAnyMap extracts a map[string]any from a raw option value.
+func ParseAnyMap(value any) map[string]any {
+	switch m := value.(type) {
+	case map[string]any:
+		return m
+	case structs.JSONMap:
+		return map[string]any(m)
+	}
+	return nil
 }
 
 // ApplyResponseFormat sets the response format on params from a raw option value.
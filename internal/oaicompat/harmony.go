@@ -0,0 +1,165 @@
+package oaicompat
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Local servers without a Harmony parser (llama.cpp without --jinja, older
+// Ollama and vLLM builds) return the raw Harmony response format of gpt-oss
+// models: messages of the form
+//
+//	<|start|>assistant<|channel|>analysis<|message|>...<|end|>
+//	<|start|>assistant<|channel|>commentary to=functions.get_weather <|constrain|>json<|message|>{...}<|call|>
+//	<|start|>assistant<|channel|>final<|message|>...<|return|>
+//
+// where the leading <|start|>assistant of the first message is part of the
+// prompt. Some servers also strip the special tokens, leaving
+// "analysis...assistantfinal...".
+
+var (
+	harmonyRecipient = regexp.MustCompile(`to=([^\s<]+)`)
+	harmonyStripped  = regexp.MustCompile(`(?s)^\s*analysis(.*?)assistantfinal(.*)$`)
+)
+
+// HarmonyModel reports whether model answers in the Harmony format.
+func HarmonyModel(model string) bool {
+	return strings.Contains(strings.ToLower(model), "gpt-oss")
+}
+
+// ParseHarmony splits raw Harmony output into the final answer, the
+// analysis channel and the function calls. ok is false when text is not in
+// the Harmony format.
+func ParseHarmony(text string) (answer, reasoning string, calls []chat.ToolCall, ok bool) {
+	if !strings.Contains(text, "<|channel|>") {
+		if m := harmonyStripped.FindStringSubmatch(text); m != nil {
+			return strings.TrimSpace(m[2]), strings.TrimSpace(m[1]), nil, true
+		}
+		return "", "", nil, false
+	}
+	var answers, analysis []string
+	rest := text
+	for {
+		i := strings.Index(rest, "<|channel|>")
+		if i < 0 {
+			break
+		}
+		// the role header, such as "assistant to=functions.x", sits
+		// between <|start|> and <|channel|>
+		role := ""
+		if start := strings.LastIndex(rest[:i], "<|start|>"); start >= 0 {
+			role = rest[start+len("<|start|>") : i]
+		}
+		rest = rest[i+len("<|channel|>"):]
+		m := strings.Index(rest, "<|message|>")
+		if m < 0 {
+			break
+		}
+		header := rest[:m]
+		rest = rest[m+len("<|message|>"):]
+		end := len(rest)
+		for _, token := range []string{"<|end|>", "<|call|>", "<|return|>", "<|start|>"} {
+			if j := strings.Index(rest, token); j >= 0 && j < end {
+				end = j
+			}
+		}
+		content := strings.TrimSpace(rest[:end])
+		rest = rest[end:]
+
+		channel := strings.Fields(header + " ")
+		name := ""
+		if len(channel) > 0 {
+			name = channel[0]
+		}
+		recipient := harmonyRecipient.FindStringSubmatch(role + " " + header)
+		switch {
+		case recipient != nil:
+			if fn, found := strings.CutPrefix(recipient[1], "functions."); found {
+				calls = append(calls, harmonyCall(len(calls), fn, content))
+			}
+		case name == "analysis":
+			analysis = append(analysis, content)
+		case content != "":
+			answers = append(answers, content)
+		}
+	}
+	return strings.Join(answers, "\n"), strings.Join(analysis, "\n"), calls, true
+}
+
+func harmonyCall(index int, name, args string) chat.ToolCall {
+	if !json.Valid([]byte(args)) {
+		// keep the call, but make its arguments valid JSON
+		data, _ := json.Marshal(map[string]string{"input": args})
+		args = string(data)
+	}
+	return chat.ToolCall{
+		ID:       fmt.Sprintf("call_%d", index),
+		Type:     "function",
+		Function: chat.ToolCallFunction{Name: name, Arguments: args},
+	}
+}
+
+// ApplyHarmony rewrites res when its text is raw Harmony output: the final
+// channel becomes the text, the analysis channel the reasoning, and
+// function calls become tool calls.
+func ApplyHarmony(res *chat.Result) {
+	answer, reasoning, calls, ok := ParseHarmony(res.Text)
+	if !ok {
+		return
+	}
+	res.Text = answer
+	if reasoning != "" {
+		res.ReasoningText = strings.TrimSpace(res.ReasoningText + "\n" + reasoning)
+	}
+	if len(calls) > 0 {
+		res.ToolCalls = append(res.ToolCalls, calls...)
+		res.FinishReason = chat.FinishReasonToolCalls
+	}
+	if len(res.Messages) > 0 {
+		res.Messages[0].Content = res.Text
+		res.Messages[0].ToolCalls = res.ToolCalls
+	} else {
+		res.Messages = chat.AssistantTurn(res.Text, res.ToolCalls)
+	}
+}
+
+// HarmonyStream wraps onStream for a Harmony model: text deltas are split
+// into answer and reasoning deltas, and function calls are reported as
+// ToolCall events before the final Done event.
+func HarmonyStream(onStream chat.OnStreamFunc) chat.OnStreamFunc {
+	var (
+		raw      strings.Builder
+		splitter chat.ReasoningSplitter
+	)
+	return func(ev chat.StreamEvent) error {
+		raw.WriteString(ev.Delta)
+		answer, reasoning := splitter.Write(ev.Delta)
+		if ev.Done {
+			a, r := splitter.Flush()
+			answer, reasoning = answer+a, reasoning+r
+		}
+		if answer != "" || reasoning != "" {
+			if err := onStream(chat.StreamEvent{Delta: answer, ReasoningDelta: reasoning}); err != nil {
+				return err
+			}
+		}
+		if ev.Done {
+			if _, _, calls, ok := ParseHarmony(raw.String()); ok {
+				for i := range calls {
+					if err := onStream(chat.StreamEvent{ToolCall: &calls[i]}); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		ev.Delta = ""
+		if ev == (chat.StreamEvent{}) {
+			return nil
+		}
+		return onStream(ev)
+	}
+}
@@ -0,0 +1,114 @@
+package oaicompat
+
+import (
+	"strings"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// BuildParams converts req into chat completion params for model, applying
+// the portable options, tools and the OpenAI provider options.
+func BuildParams(req *chat.Request, model string) (openai.ChatCompletionNewParams, error) {
+	messages, err := ToMessages(req.Messages)
+	if err != nil {
+		return openai.ChatCompletionNewParams{}, err
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(model),
+		Messages: messages,
+	}
+
+	if req.Options.Temperature != nil {
+		params.Temperature = openai.Float(*req.Options.Temperature)
+	}
+	if req.Options.TopP != nil {
+		params.TopP = openai.Float(*req.Options.TopP)
+	}
+	if req.Options.MaxTokens != nil {
+		maxTokens := int64(*req.Options.MaxTokens)
+		if UseMaxCompletionTokens(model) {
+			params.MaxCompletionTokens = openai.Int(maxTokens)
+		} else {
+			params.MaxTokens = openai.Int(maxTokens)
+		}
+	}
+	if len(req.Options.Stop) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{
+			OfStringArray: append([]string{}, req.Options.Stop...),
+		}
+	}
+	if req.Options.PresencePenalty != nil {
+		params.PresencePenalty = openai.Float(*req.Options.PresencePenalty)
+	}
+	if req.Options.FrequencyPenalty != nil {
+		params.FrequencyPenalty = openai.Float(*req.Options.FrequencyPenalty)
+	}
+	if req.Options.User != nil {
+		params.User = openai.String(*req.Options.User)
+	}
+
+	if len(req.Tools) > 0 {
+		tools, err := ToToolParams(req.Tools)
+		if err != nil {
+			return openai.ChatCompletionNewParams{}, err
+		}
+		params.Tools = tools
+	}
+
+	if req.ToolChoice != nil {
+		params.ToolChoice = ToToolChoice(req.ToolChoice)
+	}
+
+	ApplyOptions(&params, req.Options.OpenAI)
+	ApplyParallelToolCalls(&params, req.Options.ParallelToolCalls)
+	return params, nil
+}
+
+// UseMaxCompletionTokens reports whether model takes max_completion_tokens
+// rather than the deprecated max_tokens.
+func UseMaxCompletionTokens(model string) bool {
+	model = strings.ToLower(model)
+	return strings.HasPrefix(model, "gpt") ||
+		strings.HasPrefix(model, "o1") ||
+		strings.HasPrefix(model, "o3") ||
+		strings.HasPrefix(model, "o4")
+}
+
+// ToResult converts a chat completion into a chat.Result.
+func ToResult(resp *openai.ChatCompletion) *chat.Result {
+	if resp == nil {
+		return &chat.Result{Warnings: []string{"response is nil"}}
+	}
+	// the reply is the first choice that was not blocked; with n>1 every
+	// choice is also listed in Choices
+	text := ""
+	var toolCalls []chat.ToolCall
+	var primary []openai.ChatCompletionChoice
+	if i := Primary(resp.Choices); i >= 0 {
+		primary = resp.Choices[i : i+1]
+		text = primary[0].Message.Content
+		if len(primary[0].Message.ToolCalls) > 0 {
+			toolCalls = ToToolCalls(primary[0].Message.ToolCalls)
+		}
+	}
+
+	return &chat.Result{
+		Text:          text,
+		Model:         resp.Model,
+		Messages:      AssistantTurn(primary, text, toolCalls),
+		ToolCalls:     toolCalls,
+		FinishReason:  FinishReason(resp.Choices),
+		Choices:       Choices(resp.Choices),
+		ItemErrors:    ItemErrors(resp.Choices),
+		ReasoningText: Reasoning(primary),
+		Citations:     Citations(resp),
+		Usage: chat.Usage{
+			InputTokens:  int(resp.Usage.PromptTokens),
+			OutputTokens: int(resp.Usage.CompletionTokens),
+			TotalTokens:  int(resp.Usage.TotalTokens),
+		},
+		Raw: resp,
+	}
+}
@@ -2,60 +2,91 @@ package oaicompat
 
 import (
 	"context"
+	"strings"
 
 	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 	"github.com/quailyquaily/uniai/chat"
 )
 
 // ChatStream performs a streaming chat completion using the OpenAI SDK.
 // It invokes onStream for each chunk, accumulates the result, and returns
-// the final chat.Result.
+// the final chat.Result. opts are passed to the SDK request.
 func ChatStream(
 	ctx context.Context,
 	client *openai.Client,
 	params openai.ChatCompletionNewParams,
 	onStream chat.OnStreamFunc,
+	opts ...option.RequestOption,
 ) (*chat.Result, error) {
-	stream := client.Chat.Completions.NewStreaming(ctx, params)
+	if !params.StreamOptions.IncludeUsage.Valid() {
+		// usage arrives in a final chunk only when asked for
+		params.StreamOptions.IncludeUsage = openai.Bool(true)
+	}
+	stream := client.Chat.Completions.NewStreaming(ctx, params, opts...)
 	acc := openai.ChatCompletionAccumulator{}
+	var calls chat.ToolCallAccumulator
+	var reasoning strings.Builder
+	var citations []chat.Citation
+	var annotations []openai.ChatCompletionMessageAnnotation
+
+	emit := func(ev chat.StreamEvent) error {
+		if err := onStream(ev); err != nil {
+			stream.Close()
+			return err
+		}
+		return nil
+	}
 
 	for stream.Next() {
 		chunk := stream.Current()
 		acc.AddChunk(chunk)
+		citations = MergeCitations(citations, CitationFields(chunk.JSON.ExtraFields))
 
 		if len(chunk.Choices) == 0 {
 			continue
 		}
+		annotations = append(annotations, DeltaAnnotations(chunk.Choices[0].Delta.JSON.ExtraFields)...)
 
-		delta := chunk.Choices[0].Delta.Content
+		if delta := ReasoningField(chunk.Choices[0].Delta.JSON.ExtraFields); delta != "" {
+			reasoning.WriteString(delta)
+			if err := emit(chat.StreamEvent{ReasoningDelta: delta}); err != nil {
+				return nil, err
+			}
+		}
+		if delta := chunk.Choices[0].Delta.Content; delta != "" {
+			if err := emit(chat.StreamEvent{Delta: delta}); err != nil {
+				return nil, err
+			}
+		}
 
-		var toolDelta *chat.ToolCallDelta
-		if len(chunk.Choices[0].Delta.ToolCalls) > 0 {
-			tc := chunk.Choices[0].Delta.ToolCalls[0]
-			toolDelta = &chat.ToolCallDelta{
+		for _, tc := range chunk.Choices[0].Delta.ToolCalls {
+			toolDelta := chat.ToolCallDelta{
 				Index:     int(tc.Index),
 				ID:        tc.ID,
 				Name:      tc.Function.Name,
 				ArgsChunk: tc.Function.Arguments,
 			}
-		}
-
-		if delta == "" && toolDelta == nil {
-			continue
-		}
-
-		if err := onStream(chat.StreamEvent{
-			Delta:         delta,
-			ToolCallDelta: toolDelta,
-		}); err != nil {
-			stream.Close()
-			return nil, err
+			finished := calls.Add(toolDelta)
+			if finished != nil {
+				if err := emit(chat.StreamEvent{ToolCall: finished}); err != nil {
+					return nil, err
+				}
+			}
+			if err := emit(chat.StreamEvent{ToolCallDelta: &toolDelta}); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	if err := stream.Err(); err != nil {
 		return nil, err
 	}
+	if finished := calls.Flush(); finished != nil {
+		if err := emit(chat.StreamEvent{ToolCall: finished}); err != nil {
+			return nil, err
+		}
+	}
 
 	completion := acc.ChatCompletion
 
@@ -68,30 +99,9 @@ func ChatStream(
 		},
 	})
 
-	return accumulatedToResult(&completion), nil
-}
-
-func accumulatedToResult(resp *openai.ChatCompletion) *chat.Result {
-	if resp == nil {
-		return &chat.Result{Warnings: []string{"response is nil"}}
-	}
-	text := ""
-	var toolCalls []chat.ToolCall
-	for _, choice := range resp.Choices {
-		text += choice.Message.Content
-		if len(choice.Message.ToolCalls) > 0 && len(toolCalls) == 0 {
-			toolCalls = ToToolCalls(choice.Message.ToolCalls)
-		}
-	}
-	return &chat.Result{
-		Text:      text,
-		Model:     resp.Model,
-		ToolCalls: toolCalls,
-		Usage: chat.Usage{
-			InputTokens:  int(resp.Usage.PromptTokens),
-			OutputTokens: int(resp.Usage.CompletionTokens),
-			TotalTokens:  int(resp.Usage.TotalTokens),
-		},
-		Raw: resp,
-	}
+	res := ToResult(&completion)
+	// the accumulator drops non-standard delta fields
+	res.ReasoningText = reasoning.String()
+	res.Citations = MergeCitations(AnnotationCitations(res.Text, 0, annotations), citations)
+	return res, nil
 }
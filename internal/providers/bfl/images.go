@@ -0,0 +1,288 @@
+// Package bfl generates images with the Black Forest Labs (Flux) API.
+package bfl
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+const (
+	bflAPIBase = "https://api.bfl.ai"
+
+	ModelFluxPro11 = "flux-pro-1.1"
+	ModelFluxDev   = "flux-dev"
+
+	ModelFluxFill    = "flux-pro-1.0-fill"
+	ModelFluxKontext = "flux-kontext-pro"
+)
+
+// PollInterval is the delay between status checks of a generation task.
+var PollInterval = time.Second
+
+type createImagesOutput struct {
+	Created int         `json:"created"`
+	Data    []imageData `json:"data"`
+	// Seeds holds the requested seed of each image, when one was given.
+	Seeds    []int64          `json:"seeds,omitempty"`
+	MimeType string           `json:"mime_type"`
+	Usage    createImageUsage `json:"usage"`
+}
+
+type imageData struct {
+	B64JSON string `json:"b64_json"`
+}
+
+type createImageUsage struct {
+	Size    string `json:"size"`
+	Quality string `json:"quality"`
+}
+
+type taskResponse struct {
+	ID         string `json:"id"`
+	PollingURL string `json:"polling_url"`
+}
+
+type resultResponse struct {
+	Status string `json:"status"`
+	Result struct {
+		Sample string `json:"sample"`
+	} `json:"result"`
+}
+
+// CreateImages generates count images with a Flux model (default
+// flux-pro-1.1), submitting one task per image and polling until each is
+// ready.
+//
+// Options: aspect_ratio, width, height, seed, steps, guidance,
+// output_format (jpeg or png), prompt_upsampling and safety_tolerance.
+func CreateImages(ctx context.Context, token, base, model, prompt string, count int, options structs.JSONMap) ([]byte, error) {
+	if token == "" {
+		return nil, fmt.Errorf("bfl api key is required")
+	}
+	if base == "" {
+		base = bflAPIBase
+	}
+	base = strings.TrimRight(base, "/")
+	if model == "" {
+		model = ModelFluxPro11
+	}
+	if count <= 0 {
+		count = 1
+	}
+
+	payload := map[string]any{"prompt": prompt}
+	for _, key := range []string{"aspect_ratio", "output_format"} {
+		if v := options.GetString(key); v != "" {
+			payload[key] = v
+		}
+	}
+	for _, key := range []string{"width", "height", "steps", "safety_tolerance"} {
+		if options.HasKey(key) {
+			payload[key] = options.GetInt64(key)
+		}
+	}
+	if options.HasKey("guidance") {
+		payload["guidance"] = options.GetFloat64("guidance")
+	}
+	if options.HasKey("prompt_upsampling") {
+		payload["prompt_upsampling"] = options.GetBool("prompt_upsampling")
+	}
+	format := options.GetString("output_format")
+	if format == "" {
+		format = "jpeg"
+	}
+
+	size := options.GetString("aspect_ratio")
+	if options.HasKey("width") && options.HasKey("height") {
+		size = fmt.Sprintf("%dx%d", options.GetInt64("width"), options.GetInt64("height"))
+	}
+	out := &createImagesOutput{
+		Created:  int(time.Now().Unix()),
+		MimeType: "image/" + format,
+		Usage:    createImageUsage{Size: size, Quality: model},
+	}
+	if err := generate(ctx, token, base, model, payload, count, options, out); err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// EditImages edits image following prompt. With a mask the image is
+// inpainted with flux-pro-1.0-fill, repainting the white areas of the mask;
+// without one it is edited with flux-kontext-pro. model overrides the
+// default for either case.
+//
+// Options: seed, steps, guidance, output_format, prompt_upsampling and
+// safety_tolerance.
+func EditImages(ctx context.Context, token, base, model, prompt string, image, mask []byte, count int, options structs.JSONMap) ([]byte, error) {
+	if token == "" {
+		return nil, fmt.Errorf("bfl api key is required")
+	}
+	if len(image) == 0 {
+		return nil, fmt.Errorf("input image is required")
+	}
+	if base == "" {
+		base = bflAPIBase
+	}
+	base = strings.TrimRight(base, "/")
+	if count <= 0 {
+		count = 1
+	}
+
+	payload := map[string]any{"prompt": prompt}
+	if len(mask) > 0 {
+		if model == "" {
+			model = ModelFluxFill
+		}
+		payload["image"] = base64.StdEncoding.EncodeToString(image)
+		payload["mask"] = base64.StdEncoding.EncodeToString(mask)
+	} else {
+		if model == "" {
+			model = ModelFluxKontext
+		}
+		payload["input_image"] = base64.StdEncoding.EncodeToString(image)
+	}
+	if v := options.GetString("output_format"); v != "" {
+		payload["output_format"] = v
+	}
+	for _, key := range []string{"steps", "safety_tolerance"} {
+		if options.HasKey(key) {
+			payload[key] = options.GetInt64(key)
+		}
+	}
+	if options.HasKey("guidance") {
+		payload["guidance"] = options.GetFloat64("guidance")
+	}
+	if options.HasKey("prompt_upsampling") {
+		payload["prompt_upsampling"] = options.GetBool("prompt_upsampling")
+	}
+	format := options.GetString("output_format")
+	if format == "" {
+		format = "jpeg"
+	}
+	out := &createImagesOutput{
+		Created:  int(time.Now().Unix()),
+		MimeType: "image/" + format,
+		Usage:    createImageUsage{Quality: model},
+	}
+	if err := generate(ctx, token, base, model, payload, count, options, out); err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// generate submits payload count times and appends each image to out.
+func generate(ctx context.Context, token, base, model string, payload map[string]any, count int, options structs.JSONMap, out *createImagesOutput) error {
+	seed := options.GetInt64("seed")
+	for i := 0; i < count; i++ {
+		if options.HasKey("seed") {
+			payload["seed"] = seed + int64(i)
+			out.Seeds = append(out.Seeds, seed+int64(i))
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		respData, err := doRequest(ctx, token, http.MethodPost, base+"/v1/"+model, data)
+		if err != nil {
+			return err
+		}
+		var task taskResponse
+		if err := json.Unmarshal(respData, &task); err != nil {
+			return err
+		}
+		pollURL := task.PollingURL
+		if pollURL == "" {
+			pollURL = base + "/v1/get_result?id=" + task.ID
+		}
+		sample, err := poll(ctx, token, pollURL)
+		if err != nil {
+			return err
+		}
+		img, err := download(ctx, sample)
+		if err != nil {
+			return err
+		}
+		out.Data = append(out.Data, imageData{B64JSON: base64.StdEncoding.EncodeToString(img)})
+	}
+	return nil
+}
+
+// poll waits for a task and returns the URL of its image.
+func poll(ctx context.Context, token, url string) (string, error) {
+	for {
+		respData, err := doRequest(ctx, token, http.MethodGet, url, nil)
+		if err != nil {
+			return "", err
+		}
+		var res resultResponse
+		if err := json.Unmarshal(respData, &res); err != nil {
+			return "", err
+		}
+		switch res.Status {
+		case "Ready":
+			return res.Result.Sample, nil
+		case "Pending", "Queued", "":
+		default:
+			return "", fmt.Errorf("bfl task failed: %s", res.Status)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+// download fetches a result image; sample URLs are signed and need no key.
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bfl image download failed with status %d", resp.StatusCode)
+	}
+	return httputil.ReadBody(resp.Body)
+}
+
+func doRequest(ctx context.Context, token, method, url string, data []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-key", token)
+
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respData, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return respData, fmt.Errorf("bfl API request failed with status %d: %s", resp.StatusCode, string(respData))
+	}
+	return respData, nil
+}
@@ -0,0 +1,172 @@
+// Package luma generates videos with the Luma Dream Machine API.
+package luma
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+const (
+	lumaAPIBase = "https://api.lumalabs.ai/dream-machine/v1"
+
+	ModelRay2      = "ray-2"
+	ModelRayFlash2 = "ray-flash-2"
+)
+
+type videoJob struct {
+	ID        string   `json:"id"`
+	Model     string   `json:"model"`
+	Status    string   `json:"status"`
+	Progress  int      `json:"progress"`
+	Error     string   `json:"error,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+	URLs      []string `json:"urls,omitempty"`
+}
+
+type generation struct {
+	ID            string    `json:"id"`
+	Model         string    `json:"model"`
+	State         string    `json:"state"`
+	FailureReason string    `json:"failure_reason"`
+	CreatedAt     time.Time `json:"created_at"`
+	Assets        struct {
+		Video string `json:"video"`
+	} `json:"assets"`
+}
+
+// CreateVideo submits a Luma generation (default model ray-2). image, if
+// set, is the URL of the first frame; Luma does not accept inline images.
+//
+// Options: aspect_ratio, resolution, duration (e.g. "5s" or 5) and loop.
+func CreateVideo(ctx context.Context, token, base, model, prompt, image string, options structs.JSONMap) ([]byte, error) {
+	if token == "" {
+		return nil, fmt.Errorf("luma api key is required")
+	}
+	if strings.HasPrefix(image, "data:") {
+		return nil, fmt.Errorf("luma requires an image url, not inline data")
+	}
+	if model == "" {
+		model = ModelRay2
+	}
+	payload := map[string]any{"prompt": prompt, "model": model}
+	for _, key := range []string{"aspect_ratio", "resolution"} {
+		if v := options.GetString(key); v != "" {
+			payload[key] = v
+		}
+	}
+	if options.HasKey("duration") {
+		d := options.GetString("duration")
+		if d == "" {
+			d = strconv.FormatInt(options.GetInt64("duration"), 10) + "s"
+		}
+		payload["duration"] = d
+	}
+	if options.HasKey("loop") {
+		payload["loop"] = options.GetBool("loop")
+	}
+	if image != "" {
+		payload["keyframes"] = map[string]any{"frame0": map[string]any{"type": "image", "url": image}}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	respData, err := doRequest(ctx, token, http.MethodPost, apiBase(base)+"/generations", data)
+	if err != nil {
+		return nil, err
+	}
+	return toVideoJob(respData)
+}
+
+// GetVideo returns the state of a Luma generation.
+func GetVideo(ctx context.Context, token, base, id string) ([]byte, error) {
+	if token == "" {
+		return nil, fmt.Errorf("luma api key is required")
+	}
+	respData, err := doRequest(ctx, token, http.MethodGet, apiBase(base)+"/generations/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	return toVideoJob(respData)
+}
+
+// DeleteVideo deletes a Luma generation.
+func DeleteVideo(ctx context.Context, token, base, id string) error {
+	if token == "" {
+		return fmt.Errorf("luma api key is required")
+	}
+	_, err := doRequest(ctx, token, http.MethodDelete, apiBase(base)+"/generations/"+url.PathEscape(id), nil)
+	return err
+}
+
+func toVideoJob(respData []byte) ([]byte, error) {
+	var g generation
+	if err := json.Unmarshal(respData, &g); err != nil {
+		return nil, err
+	}
+	job := videoJob{ID: g.ID, Model: g.Model, Error: g.FailureReason}
+	if !g.CreatedAt.IsZero() {
+		job.CreatedAt = g.CreatedAt.Unix()
+	}
+	switch g.State {
+	case "queued":
+		job.Status = "queued"
+	case "dreaming":
+		job.Status = "running"
+	case "completed":
+		job.Status = "succeeded"
+		job.Progress = 100
+		if g.Assets.Video != "" {
+			job.URLs = []string{g.Assets.Video}
+		}
+	case "failed":
+		job.Status = "failed"
+	default:
+		job.Status = g.State
+	}
+	return json.Marshal(job)
+}
+
+func apiBase(base string) string {
+	if base == "" {
+		return lumaAPIBase
+	}
+	return strings.TrimRight(base, "/")
+}
+
+func doRequest(ctx context.Context, token, method, url string, data []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respData, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return respData, fmt.Errorf("luma API request failed with status %d: %s", resp.StatusCode, string(respData))
+	}
+	return respData, nil
+}
@@ -0,0 +1,142 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+// azureImagesAPIVersion is the default API version for image deployments;
+// gpt-image models need a 2025 preview version.
+const azureImagesAPIVersion = "2025-04-01-preview"
+
+type azureDallEInput struct {
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n"`
+	Size           string `json:"size"`
+	Quality        string `json:"quality"`
+	Style          string `json:"style,omitempty"`
+	ResponseFormat string `json:"response_format"`
+}
+
+// CreateAzureImages generates images with an Azure OpenAI image deployment.
+// DALL-E deployments, recognized by model or deployment name, take
+// DALL-E parameters; other deployments take the gpt-image parameters of
+// CreateImages.
+func CreateAzureImages(ctx context.Context, key, endpoint, apiVersion, deployment, model, prompt string, count int, options structs.JSONMap) ([]byte, error) {
+	if key == "" || endpoint == "" {
+		return nil, fmt.Errorf("azure openai api key and endpoint are required")
+	}
+	if deployment == "" {
+		return nil, fmt.Errorf("azure openai image deployment is required")
+	}
+	if v := options.GetString("api_version"); v != "" {
+		apiVersion = v
+	}
+	if apiVersion == "" {
+		apiVersion = azureImagesAPIVersion
+	}
+
+	var (
+		reqData                 []byte
+		size, quality, mimeType string
+		err                     error
+	)
+	if isDallE(model) || (model == "" && isDallE(deployment)) {
+		payload, verr := newAzureDallEInput(prompt, count, options)
+		if verr != nil {
+			return nil, verr
+		}
+		size, quality, mimeType = payload.Size, payload.Quality, "image/png"
+		reqData, err = json.Marshal(payload)
+	} else {
+		payload, verr := newImagesInput(model, prompt, count, options)
+		if verr != nil {
+			return nil, verr
+		}
+		// the deployment selects the model
+		payload.Model = ""
+		size, quality, mimeType = payload.Size, payload.Quality, getMimeType(payload.OutputFormat)
+		reqData, err = json.Marshal(payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/openai/deployments/%s/images/generations?api-version=%s", url.PathEscape(deployment), url.QueryEscape(apiVersion))
+	respData, err := doAzureRequest(ctx, key, endpoint, path, "application/json", reqData)
+	if err != nil {
+		return nil, err
+	}
+	return toImagesOutput(respData, size, quality, mimeType)
+}
+
+func isDallE(name string) bool {
+	return strings.Contains(strings.ToLower(name), "dall-e")
+}
+
+func newAzureDallEInput(prompt string, count int, options structs.JSONMap) (*azureDallEInput, error) {
+	payload := &azureDallEInput{
+		Prompt:         prompt,
+		N:              1,
+		Size:           options.GetString("size"),
+		Quality:        options.GetString("quality"),
+		Style:          options.GetString("style"),
+		ResponseFormat: "b64_json",
+	}
+	if count > 1 {
+		return nil, fmt.Errorf("dall-e-3 generates one image per request")
+	}
+	if payload.Size == "" {
+		payload.Size = "1024x1024"
+	}
+	if payload.Quality == "" {
+		payload.Quality = "standard"
+	}
+	sizes := []string{"1024x1024", "1792x1024", "1024x1792"}
+	qualities := []string{"standard", "hd"}
+	styles := []string{"", "vivid", "natural"}
+	if !slices.Contains(sizes, payload.Size) {
+		return nil, fmt.Errorf("size must be one of %v", sizes)
+	}
+	if !slices.Contains(qualities, payload.Quality) {
+		return nil, fmt.Errorf("quality must be one of %v", qualities)
+	}
+	if !slices.Contains(styles, payload.Style) {
+		return nil, fmt.Errorf("style must be one of %v", styles[1:])
+	}
+	return payload, nil
+}
+
+func doAzureRequest(ctx context.Context, key, endpoint, path, contentType string, data []byte) ([]byte, error) {
+	u := strings.TrimRight(endpoint, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("api-key", key)
+
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respData, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return respData, fmt.Errorf("azure openai API request failed with status %d: %s", resp.StatusCode, string(respData))
+	}
+	return respData, nil
+}
@@ -0,0 +1,196 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lyricat/goutils/structs"
+)
+
+var dallE2Sizes = []string{"256x256", "512x512", "1024x1024"}
+
+// editFields returns the form fields of an edit request and the size,
+// quality and MIME type of its output. dall-e-2 takes its own parameters;
+// other models take those of CreateImages.
+func editFields(model, prompt string, count int, options structs.JSONMap) (map[string]string, string, string, string, error) {
+	if strings.Contains(model, "dall-e-3") {
+		return nil, "", "", "", fmt.Errorf("dall-e-3 does not support image edits")
+	}
+	if isDallE(model) {
+		fields, err := dallE2Fields(model, count, options)
+		if err != nil {
+			return nil, "", "", "", err
+		}
+		fields["prompt"] = prompt
+		return fields, fields["size"], "", "image/png", nil
+	}
+	payload, err := newImagesInput(model, prompt, count, options)
+	if err != nil {
+		return nil, "", "", "", err
+	}
+	fields := map[string]string{
+		"model":         payload.Model,
+		"prompt":        payload.Prompt,
+		"n":             strconv.Itoa(payload.N),
+		"quality":       payload.Quality,
+		"size":          payload.Size,
+		"background":    payload.Background,
+		"output_format": payload.OutputFormat,
+	}
+	return fields, payload.Size, payload.Quality, getMimeType(payload.OutputFormat), nil
+}
+
+func dallE2Fields(model string, count int, options structs.JSONMap) (map[string]string, error) {
+	if count <= 0 {
+		count = 1
+	}
+	size := options.GetString("size")
+	if size == "" {
+		size = "1024x1024"
+	}
+	if !slices.Contains(dallE2Sizes, size) {
+		return nil, fmt.Errorf("size must be one of %v", dallE2Sizes)
+	}
+	return map[string]string{
+		"model":           model,
+		"n":               strconv.Itoa(count),
+		"size":            size,
+		"response_format": "b64_json",
+	}, nil
+}
+
+// EditImages edits image following prompt. With a mask, only the fully
+// transparent areas of the mask are repainted.
+func EditImages(ctx context.Context, token, base, model, prompt string, image, mask []byte, count int, options structs.JSONMap) ([]byte, error) {
+	fields, size, quality, mimeType, err := editFields(model, prompt, count, options)
+	if err != nil {
+		return nil, err
+	}
+	body, contentType, err := imageForm(fields, image, mask)
+	if err != nil {
+		return nil, err
+	}
+	respData, err := doRequestWithType(ctx, token, base, "POST", "/images/edits", contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	return toImagesOutput(respData, size, quality, mimeType)
+}
+
+// CreateVariations returns variations of image. Only dall-e-2 supports
+// variations.
+func CreateVariations(ctx context.Context, token, base, model string, image []byte, count int, options structs.JSONMap) ([]byte, error) {
+	if model == "" {
+		model = "dall-e-2"
+	}
+	if model != "dall-e-2" {
+		return nil, fmt.Errorf("image variations are only supported by dall-e-2")
+	}
+	fields, err := dallE2Fields(model, count, options)
+	if err != nil {
+		return nil, err
+	}
+	body, contentType, err := imageForm(fields, image, nil)
+	if err != nil {
+		return nil, err
+	}
+	respData, err := doRequestWithType(ctx, token, base, "POST", "/images/variations", contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	return toImagesOutput(respData, fields["size"], "", "image/png")
+}
+
+// EditAzureImages edits image with an Azure OpenAI image deployment; see
+// EditImages.
+func EditAzureImages(ctx context.Context, key, endpoint, apiVersion, deployment, model, prompt string, image, mask []byte, count int, options structs.JSONMap) ([]byte, error) {
+	if key == "" || endpoint == "" {
+		return nil, fmt.Errorf("azure openai api key and endpoint are required")
+	}
+	if deployment == "" {
+		return nil, fmt.Errorf("azure openai image deployment is required")
+	}
+	if v := options.GetString("api_version"); v != "" {
+		apiVersion = v
+	}
+	if apiVersion == "" {
+		apiVersion = azureImagesAPIVersion
+	}
+	fields, size, quality, mimeType, err := editFields(model, prompt, count, options)
+	if err != nil {
+		return nil, err
+	}
+	// the deployment selects the model
+	delete(fields, "model")
+	body, contentType, err := imageForm(fields, image, mask)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("/openai/deployments/%s/images/edits?api-version=%s", url.PathEscape(deployment), url.QueryEscape(apiVersion))
+	respData, err := doAzureRequest(ctx, key, endpoint, path, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	return toImagesOutput(respData, size, quality, mimeType)
+}
+
+// imageForm builds a multipart body with fields and the image and mask
+// files.
+func imageForm(fields map[string]string, image, mask []byte) ([]byte, string, error) {
+	if len(image) == 0 {
+		return nil, "", fmt.Errorf("input image is required")
+	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if v == "" {
+			continue
+		}
+		if err := w.WriteField(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{{"image", image}, {"mask", mask}}
+	for _, f := range files {
+		if len(f.data) == 0 {
+			continue
+		}
+		h := textproto.MIMEHeader{}
+		mimeType := http.DetectContentType(f.data)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename="%s.%s"`, f.name, f.name, extension(mimeType)))
+		h.Set("Content-Type", mimeType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(f.data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), w.FormDataContentType(), nil
+}
+
+func extension(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return "jpg"
+	case "image/webp":
+		return "webp"
+	default:
+		return "png"
+	}
+}
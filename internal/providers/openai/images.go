@@ -10,7 +10,7 @@ import (
 )
 
 type openAICreateImagesInput struct {
-	Model             string `json:"model"`
+	Model             string `json:"model,omitempty"`
 	Prompt            string `json:"prompt"`
 	Background        string `json:"background,omitempty"`
 	Moderation        string `json:"moderation,omitempty"`
@@ -62,6 +62,25 @@ type createImageUsage struct {
 }
 
 func CreateImages(ctx context.Context, token, base, model, prompt string, count int, options structs.JSONMap) ([]byte, error) {
+	payload, err := newImagesInput(model, prompt, count, options)
+	if err != nil {
+		return nil, err
+	}
+	reqData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respData, err := doRequest(ctx, token, base, "POST", "/images/generations", reqData)
+	if err != nil {
+		return nil, err
+	}
+	return toImagesOutput(respData, payload.Size, payload.Quality, getMimeType(payload.OutputFormat))
+}
+
+// newImagesInput builds and validates a gpt-image request, filling in
+// defaults for unset options.
+func newImagesInput(model, prompt string, count int, options structs.JSONMap) (*openAICreateImagesInput, error) {
 	payload := &openAICreateImagesInput{
 		Model:  model,
 		Prompt: prompt,
@@ -91,17 +110,10 @@ func CreateImages(ctx context.Context, token, base, model, prompt string, count
 	if err := verifyOpenAIImagesInput(payload); err != nil {
 		return nil, err
 	}
+	return payload, nil
+}
 
-	reqData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
-	}
-
-	respData, err := doRequest(ctx, token, base, "POST", "/images/generations", reqData)
-	if err != nil {
-		return nil, err
-	}
-
+func toImagesOutput(respData []byte, size, quality, mimeType string) ([]byte, error) {
 	var resp openAICreateImagesOutput
 	if err := json.Unmarshal(respData, &resp); err != nil {
 		return nil, err
@@ -111,13 +123,13 @@ func CreateImages(ctx context.Context, token, base, model, prompt string, count
 		Created: resp.Created,
 		Data:    resp.Data,
 		Usage: createImageUsage{
-			Size:         payload.Size,
-			Quality:      payload.Quality,
+			Size:         size,
+			Quality:      quality,
 			InputTokens:  resp.OpenAICreateImagesUsage.InputTokens,
 			OutputTokens: resp.OpenAICreateImagesUsage.OutputTokens,
 			TotalTokens:  resp.OpenAICreateImagesUsage.TotalTokens,
 		},
-		MimeType: getMimeType(payload.OutputFormat),
+		MimeType: mimeType,
 	}
 
 	return json.Marshal(out)
@@ -0,0 +1,137 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+
+	"github.com/lyricat/goutils/structs"
+)
+
+type videoJob struct {
+	ID        string   `json:"id"`
+	Model     string   `json:"model"`
+	Status    string   `json:"status"`
+	Progress  int      `json:"progress"`
+	Error     string   `json:"error,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+	URLs      []string `json:"urls,omitempty"`
+}
+
+type soraVideo struct {
+	ID        string `json:"id"`
+	Model     string `json:"model"`
+	Status    string `json:"status"`
+	Progress  int    `json:"progress"`
+	CreatedAt int64  `json:"created_at"`
+	Error     *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// CreateVideo submits a Sora video job (default model sora-2). image, if
+// set, is the first frame and must match the requested size.
+//
+// Options: seconds ("4", "8" or "12") and size (e.g. "1280x720").
+func CreateVideo(ctx context.Context, token, base, model, prompt string, image []byte, options structs.JSONMap) ([]byte, error) {
+	if model == "" {
+		model = "sora-2"
+	}
+	fields := map[string]string{
+		"model":  model,
+		"prompt": prompt,
+		"size":   options.GetString("size"),
+	}
+	if options.HasKey("seconds") {
+		// the API takes seconds as a string; accept numbers too
+		if s := options.GetString("seconds"); s != "" {
+			fields["seconds"] = s
+		} else {
+			fields["seconds"] = strconv.FormatInt(options.GetInt64("seconds"), 10)
+		}
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if v == "" {
+			continue
+		}
+		if err := w.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	if len(image) > 0 {
+		h := textproto.MIMEHeader{}
+		mimeType := http.DetectContentType(image)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="input_reference"; filename="reference.%s"`, extension(mimeType)))
+		h.Set("Content-Type", mimeType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(image); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	respData, err := doRequestWithType(ctx, token, base, http.MethodPost, "/videos", w.FormDataContentType(), body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return toVideoJob(respData)
+}
+
+// GetVideo returns the state of a Sora video job.
+func GetVideo(ctx context.Context, token, base, id string) ([]byte, error) {
+	respData, err := doRequest(ctx, token, base, http.MethodGet, "/videos/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	return toVideoJob(respData)
+}
+
+// DeleteVideo deletes a Sora video job and its content.
+func DeleteVideo(ctx context.Context, token, base, id string) error {
+	_, err := doRequest(ctx, token, base, http.MethodDelete, "/videos/"+url.PathEscape(id), nil)
+	return err
+}
+
+// DownloadVideo returns the MP4 of a completed Sora video job.
+func DownloadVideo(ctx context.Context, token, base, id string) ([]byte, error) {
+	return doRequest(ctx, token, base, http.MethodGet, "/videos/"+url.PathEscape(id)+"/content", nil)
+}
+
+func toVideoJob(respData []byte) ([]byte, error) {
+	var v soraVideo
+	if err := json.Unmarshal(respData, &v); err != nil {
+		return nil, err
+	}
+	job := videoJob{ID: v.ID, Model: v.Model, Progress: v.Progress, CreatedAt: v.CreatedAt}
+	switch v.Status {
+	case "queued":
+		job.Status = "queued"
+	case "in_progress":
+		job.Status = "running"
+	case "completed":
+		job.Status = "succeeded"
+		job.Progress = 100
+	case "failed":
+		job.Status = "failed"
+	default:
+		job.Status = v.Status
+	}
+	if v.Error != nil {
+		job.Error = v.Error.Message
+	}
+	return json.Marshal(job)
+}
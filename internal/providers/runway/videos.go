@@ -0,0 +1,166 @@
+// Package runway generates videos with the Runway API.
+package runway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+const (
+	runwayAPIBase = "https://api.dev.runwayml.com"
+	// runwayVersion is sent as X-Runway-Version, which the API requires.
+	runwayVersion = "2024-11-06"
+
+	ModelGen4Turbo = "gen4_turbo"
+	ModelVeo3      = "veo3"
+)
+
+type videoJob struct {
+	ID        string   `json:"id"`
+	Model     string   `json:"model"`
+	Status    string   `json:"status"`
+	Progress  int      `json:"progress"`
+	Error     string   `json:"error,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+	URLs      []string `json:"urls,omitempty"`
+}
+
+type task struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	Progress  float64   `json:"progress"`
+	Output    []string  `json:"output"`
+	Failure   string    `json:"failure"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateVideo submits a Runway generation task. With image (a URL or data
+// URL) it is an image-to-video task (default model gen4_turbo); without one
+// it is text-to-video (default model veo3).
+//
+// Options: ratio (e.g. "1280:720"), duration in seconds, and seed.
+func CreateVideo(ctx context.Context, token, base, model, prompt, image string, options structs.JSONMap) ([]byte, error) {
+	if token == "" {
+		return nil, fmt.Errorf("runway api key is required")
+	}
+	payload := map[string]any{"promptText": prompt}
+	path := "/v1/text_to_video"
+	if image != "" {
+		path = "/v1/image_to_video"
+		payload["promptImage"] = image
+		if model == "" {
+			model = ModelGen4Turbo
+		}
+	} else if model == "" {
+		model = ModelVeo3
+	}
+	payload["model"] = model
+	if v := options.GetString("ratio"); v != "" {
+		payload["ratio"] = v
+	}
+	for _, key := range []string{"duration", "seed"} {
+		if options.HasKey(key) {
+			payload[key] = options.GetInt64(key)
+		}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	respData, err := doRequest(ctx, token, http.MethodPost, apiBase(base)+path, data)
+	if err != nil {
+		return nil, err
+	}
+	var t task
+	if err := json.Unmarshal(respData, &t); err != nil {
+		return nil, err
+	}
+	return json.Marshal(videoJob{ID: t.ID, Model: model, Status: "queued", CreatedAt: time.Now().Unix()})
+}
+
+// GetVideo returns the state of a Runway task.
+func GetVideo(ctx context.Context, token, base, id string) ([]byte, error) {
+	if token == "" {
+		return nil, fmt.Errorf("runway api key is required")
+	}
+	respData, err := doRequest(ctx, token, http.MethodGet, apiBase(base)+"/v1/tasks/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	var t task
+	if err := json.Unmarshal(respData, &t); err != nil {
+		return nil, err
+	}
+	job := videoJob{ID: t.ID, Progress: int(t.Progress * 100), Error: t.Failure, URLs: t.Output}
+	if !t.CreatedAt.IsZero() {
+		job.CreatedAt = t.CreatedAt.Unix()
+	}
+	switch t.Status {
+	case "PENDING", "THROTTLED":
+		job.Status = "queued"
+	case "RUNNING":
+		job.Status = "running"
+	case "SUCCEEDED":
+		job.Status = "succeeded"
+		job.Progress = 100
+	case "FAILED":
+		job.Status = "failed"
+	case "CANCELLED":
+		job.Status = "canceled"
+	default:
+		job.Status = strings.ToLower(t.Status)
+	}
+	return json.Marshal(job)
+}
+
+// DeleteVideo cancels a running Runway task, or deletes a finished one.
+func DeleteVideo(ctx context.Context, token, base, id string) error {
+	if token == "" {
+		return fmt.Errorf("runway api key is required")
+	}
+	_, err := doRequest(ctx, token, http.MethodDelete, apiBase(base)+"/v1/tasks/"+url.PathEscape(id), nil)
+	return err
+}
+
+func apiBase(base string) string {
+	if base == "" {
+		return runwayAPIBase
+	}
+	return strings.TrimRight(base, "/")
+}
+
+func doRequest(ctx context.Context, token, method, url string, data []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Runway-Version", runwayVersion)
+
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respData, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return respData, fmt.Errorf("runway API request failed with status %d: %s", resp.StatusCode, string(respData))
+	}
+	return respData, nil
+}
@@ -0,0 +1,285 @@
+// Package stability generates images with the Stability AI API.
+package stability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+const (
+	stabilityAPIBase = "https://api.stability.ai"
+
+	ModelCore  = "core"
+	ModelUltra = "ultra"
+	ModelSD3   = "sd3.5-large"
+)
+
+var aspectRatios = []string{"16:9", "1:1", "21:9", "2:3", "3:2", "4:5", "5:4", "9:16", "9:21"}
+
+type createImagesOutput struct {
+	Created int         `json:"created"`
+	Data    []imageData `json:"data"`
+	// Seeds holds the seed of each image, for reproducing it.
+	Seeds    []int64          `json:"seeds,omitempty"`
+	MimeType string           `json:"mime_type"`
+	Usage    createImageUsage `json:"usage"`
+}
+
+type imageData struct {
+	B64JSON string `json:"b64_json"`
+}
+
+type createImageUsage struct {
+	Size    string `json:"size"`
+	Quality string `json:"quality"`
+}
+
+// CreateImages generates images with the Stable Image API. The model is
+// "core", "ultra" or an SD3 model such as "sd3.5-large" (the default is
+// "core"), which generate one image per call, or a v1 engine ID such as
+// "stable-diffusion-xl-1024-v1-0", which supports steps and cfg_scale.
+//
+// Options: aspect_ratio, seed, negative_prompt, output_format (png, jpeg
+// or webp), and for v1 engines steps, cfg_scale, width and height.
+func CreateImages(ctx context.Context, token, base, model, prompt string, count int, options structs.JSONMap) ([]byte, error) {
+	if token == "" {
+		return nil, fmt.Errorf("stability api key is required")
+	}
+	if base == "" {
+		base = stabilityAPIBase
+	}
+	base = strings.TrimRight(base, "/")
+	if model == "" {
+		model = ModelCore
+	}
+	if count <= 0 {
+		count = 1
+	}
+	if ratio := options.GetString("aspect_ratio"); ratio != "" && !slices.Contains(aspectRatios, ratio) {
+		return nil, fmt.Errorf("aspect_ratio must be one of %v", aspectRatios)
+	}
+
+	var (
+		out *createImagesOutput
+		err error
+	)
+	if strings.HasPrefix(model, "stable-diffusion") {
+		out, err = generateV1(ctx, token, base, model, prompt, count, options)
+	} else {
+		out, err = generateV2(ctx, token, base, model, prompt, count, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out.Created = int(time.Now().Unix())
+	return json.Marshal(out)
+}
+
+func generateV2(ctx context.Context, token, base, model, prompt string, count int, options structs.JSONMap) (*createImagesOutput, error) {
+	endpoint := model
+	if strings.HasPrefix(model, "sd3") {
+		endpoint = "sd3"
+	}
+	format := options.GetString("output_format")
+	if format == "" {
+		format = "png"
+	}
+	fields := map[string]string{
+		"prompt":          prompt,
+		"output_format":   format,
+		"aspect_ratio":    options.GetString("aspect_ratio"),
+		"negative_prompt": options.GetString("negative_prompt"),
+	}
+	if endpoint == "sd3" {
+		fields["model"] = model
+	}
+	out := &createImagesOutput{MimeType: "image/" + format, Usage: createImageUsage{Size: fields["aspect_ratio"], Quality: model}}
+	seed := options.GetInt64("seed")
+	for i := 0; i < count; i++ {
+		if options.HasKey("seed") {
+			// consecutive seeds keep a batch reproducible but distinct
+			fields["seed"] = strconv.FormatInt(seed+int64(i), 10)
+		}
+		img, gotSeed, err := postV2(ctx, token, base+"/v2beta/stable-image/generate/"+endpoint, fields, nil)
+		if err != nil {
+			return nil, err
+		}
+		out.Data = append(out.Data, imageData{B64JSON: img})
+		out.Seeds = append(out.Seeds, gotSeed)
+	}
+	return out, nil
+}
+
+// postV2 sends a multipart Stable Image request and returns the base64
+// image and its seed.
+func postV2(ctx context.Context, token, url string, fields map[string]string, files map[string][]byte) (string, int64, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if v != "" {
+			w.WriteField(k, v)
+		}
+	}
+	for name, data := range files {
+		if len(data) == 0 {
+			continue
+		}
+		part, err := w.CreateFormFile(name, name)
+		if err != nil {
+			return "", 0, err
+		}
+		part.Write(data)
+	}
+	if err := w.Close(); err != nil {
+		return "", 0, err
+	}
+	respData, err := doRequest(ctx, token, url, w.FormDataContentType(), body.Bytes())
+	if err != nil {
+		return "", 0, err
+	}
+	var resp struct {
+		Image        string `json:"image"`
+		FinishReason string `json:"finish_reason"`
+		Seed         int64  `json:"seed"`
+	}
+	if err := json.Unmarshal(respData, &resp); err != nil {
+		return "", 0, err
+	}
+	if resp.FinishReason == "CONTENT_FILTERED" {
+		return "", 0, fmt.Errorf("stability image was filtered")
+	}
+	return resp.Image, resp.Seed, nil
+}
+
+func generateV1(ctx context.Context, token, base, model, prompt string, count int, options structs.JSONMap) (*createImagesOutput, error) {
+	payload := map[string]any{
+		"text_prompts": []map[string]any{{"text": prompt, "weight": 1}},
+		"samples":      count,
+	}
+	if neg := options.GetString("negative_prompt"); neg != "" {
+		payload["text_prompts"] = append(payload["text_prompts"].([]map[string]any), map[string]any{"text": neg, "weight": -1})
+	}
+	for _, key := range []string{"seed", "steps", "width", "height"} {
+		if options.HasKey(key) {
+			payload[key] = options.GetInt64(key)
+		}
+	}
+	if options.HasKey("cfg_scale") {
+		payload["cfg_scale"] = options.GetFloat64("cfg_scale")
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	respData, err := doRequest(ctx, token, base+"/v1/generation/"+model+"/text-to-image", "application/json", data)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Artifacts []struct {
+			Base64       string `json:"base64"`
+			Seed         int64  `json:"seed"`
+			FinishReason string `json:"finishReason"`
+		} `json:"artifacts"`
+	}
+	if err := json.Unmarshal(respData, &resp); err != nil {
+		return nil, err
+	}
+	size := ""
+	if options.HasKey("width") && options.HasKey("height") {
+		size = fmt.Sprintf("%dx%d", options.GetInt64("width"), options.GetInt64("height"))
+	}
+	out := &createImagesOutput{MimeType: "image/png", Usage: createImageUsage{Size: size, Quality: model}}
+	for _, a := range resp.Artifacts {
+		if a.FinishReason == "CONTENT_FILTERED" {
+			continue
+		}
+		out.Data = append(out.Data, imageData{B64JSON: a.Base64})
+		out.Seeds = append(out.Seeds, a.Seed)
+	}
+	if len(out.Data) == 0 {
+		return nil, fmt.Errorf("stability returned no images")
+	}
+	return out, nil
+}
+
+// EditImages inpaints image with the Stable Image inpaint API. White areas
+// of mask are repainted; without a mask the image's alpha channel is used.
+// Options: seed, negative_prompt, output_format and grow_mask.
+func EditImages(ctx context.Context, token, base, prompt string, image, mask []byte, count int, options structs.JSONMap) ([]byte, error) {
+	if token == "" {
+		return nil, fmt.Errorf("stability api key is required")
+	}
+	if len(image) == 0 {
+		return nil, fmt.Errorf("input image is required")
+	}
+	if base == "" {
+		base = stabilityAPIBase
+	}
+	base = strings.TrimRight(base, "/")
+	if count <= 0 {
+		count = 1
+	}
+	format := options.GetString("output_format")
+	if format == "" {
+		format = "png"
+	}
+	fields := map[string]string{
+		"prompt":          prompt,
+		"output_format":   format,
+		"negative_prompt": options.GetString("negative_prompt"),
+	}
+	if options.HasKey("grow_mask") {
+		fields["grow_mask"] = strconv.FormatInt(options.GetInt64("grow_mask"), 10)
+	}
+	out := &createImagesOutput{Created: int(time.Now().Unix()), MimeType: "image/" + format, Usage: createImageUsage{Quality: "inpaint"}}
+	seed := options.GetInt64("seed")
+	for i := 0; i < count; i++ {
+		if options.HasKey("seed") {
+			fields["seed"] = strconv.FormatInt(seed+int64(i), 10)
+		}
+		img, gotSeed, err := postV2(ctx, token, base+"/v2beta/stable-image/edit/inpaint", fields, map[string][]byte{"image": image, "mask": mask})
+		if err != nil {
+			return nil, err
+		}
+		out.Data = append(out.Data, imageData{B64JSON: img})
+		out.Seeds = append(out.Seeds, gotSeed)
+	}
+	return json.Marshal(out)
+}
+
+func doRequest(ctx context.Context, token, url, contentType string, data []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respData, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return respData, fmt.Errorf("stability API request failed with status %d: %s", resp.StatusCode, string(respData))
+	}
+	return respData, nil
+}
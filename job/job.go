@@ -0,0 +1,46 @@
+// Package job defines the state shared by asynchronous provider jobs, such
+// as video generations, and a helper to wait for them.
+package job
+
+import (
+	"context"
+	"time"
+)
+
+// Status is the normalized state of an asynchronous job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Done reports whether s is a final state.
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// DefaultPollInterval is used by Wait when interval is not positive.
+var DefaultPollInterval = 5 * time.Second
+
+// Wait calls get every interval until it reports a final status, an error,
+// or ctx is done, and returns the last value.
+func Wait[T any](ctx context.Context, interval time.Duration, get func(ctx context.Context) (T, Status, error)) (T, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	for {
+		v, status, err := get(ctx)
+		if err != nil || status.Done() {
+			return v, err
+		}
+		select {
+		case <-ctx.Done():
+			return v, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,440 @@
+// Package jobs runs chat, chat batch and embedding requests in the
+// background. Jobs are persisted in a Store, retried with backoff on
+// transient errors, may be scheduled for later, and report completion
+// through a callback, so services can offload non-interactive generation
+// without building their own queue.
+//
+//	q := jobs.New(jobs.Config{Client: client, Store: store, OnComplete: notify})
+//	go q.Run(ctx)
+//	j, _ := jobs.Chat(uniai.WithModel("gpt-4o"), uniai.WithMessages(msgs...))
+//	j, err := q.Enqueue(ctx, j)
+//
+// A Queue assumes it is the only one working on its Store.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/admission"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/embedding"
+	"github.com/quailyquaily/uniai/job"
+	"github.com/quailyquaily/uniai/webhook"
+)
+
+// Kind is the type of work a Job does.
+type Kind string
+
+const (
+	KindChat      Kind = "chat"
+	KindChatBatch Kind = "chat_batch"
+	KindEmbedding Kind = "embedding"
+)
+
+// Job is a unit of background work and its outcome. Exactly one of Chat,
+// ChatBatch and Embedding is set, matching Kind.
+type Job struct {
+	ID     string     `json:"id"`
+	Kind   Kind       `json:"kind"`
+	Status job.Status `json:"status"`
+	// Meta is free-form data for the caller, such as the user to notify.
+	Meta map[string]string `json:"meta,omitempty"`
+
+	Chat      *chat.Request      `json:"chat,omitempty"`
+	ChatBatch []chat.Request     `json:"chat_batch,omitempty"`
+	Embedding *embedding.Request `json:"embedding,omitempty"`
+
+	// RunAt delays the first attempt; after a failed attempt it is the
+	// time of the next one.
+	RunAt time.Time `json:"run_at"`
+	// MaxAttempts overrides Config.MaxAttempts for this job.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	Attempts    int `json:"attempts"`
+	// Error and ErrorClass describe the last failed attempt.
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+
+	ChatResult      *chat.Result      `json:"chat_result,omitempty"`
+	ChatBatchResult *chat.BatchResult `json:"chat_batch_result,omitempty"`
+	EmbeddingResult *embedding.Result `json:"embedding_result,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Chat returns a chat job built from opts. Callbacks such as WithOnStream
+// are not kept, since jobs are persisted.
+func Chat(opts ...chat.Option) (*Job, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Job{Kind: KindChat, Chat: req}, nil
+}
+
+// ChatBatch returns a job that runs each element of requests as a separate
+// chat request, like Client.ChatBatch.
+func ChatBatch(requests ...[]chat.Option) (*Job, error) {
+	j := &Job{Kind: KindChatBatch}
+	for i, opts := range requests {
+		req, err := chat.BuildRequest(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", i, err)
+		}
+		j.ChatBatch = append(j.ChatBatch, *req)
+	}
+	return j, nil
+}
+
+// Embedding returns an embedding job built from opts.
+func Embedding(opts ...embedding.Option) *Job {
+	return &Job{Kind: KindEmbedding, Embedding: embedding.BuildRequest(opts...)}
+}
+
+// Client is the subset of uniai.Client the queue needs.
+type Client interface {
+	Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error)
+	ChatBatch(ctx context.Context, cfg uniai.ChatBatchConfig, requests ...[]chat.Option) (*chat.BatchResult, error)
+	Embedding(ctx context.Context, opts ...embedding.Option) (*embedding.Result, error)
+}
+
+// Defaults for Config.
+const (
+	DefaultWorkers      = 2
+	DefaultMaxAttempts  = 3
+	DefaultPollInterval = time.Second
+)
+
+// Config configures a Queue.
+type Config struct {
+	Client Client
+	// Store persists jobs (default an in-memory store).
+	Store Store
+	// Workers is the number of jobs run at once (default 2).
+	Workers int
+	// MaxAttempts is the number of attempts per job (default 3). Only
+	// rate limits, timeouts, server, network and unclassified errors are
+	// retried.
+	MaxAttempts int
+	// Backoff returns the delay before the next attempt after the given
+	// number of failed ones (default 2s, 4s, 8s... up to 5 minutes).
+	Backoff func(attempts int) time.Duration
+	// PollInterval is how often scheduled and retried jobs are checked
+	// for being due (default 1s).
+	PollInterval time.Duration
+	// BatchConcurrency is passed to ChatBatch for chat batch jobs.
+	BatchConcurrency int
+	// OnComplete is called after a job succeeds, fails for good or is
+	// canceled.
+	OnComplete func(*Job)
+	// Webhook, if set, is sent a webhook.TypeJob event with the job at
+	// the same points, in the background.
+	Webhook *webhook.Sender
+}
+
+// Queue runs jobs from its Store. Enqueue, Get and Cancel may be called
+// before and while Run is running.
+type Queue struct {
+	cfg  Config
+	wake chan struct{}
+
+	// mu serializes claiming and canceling jobs
+	mu     sync.Mutex
+	active map[string]*activeJob
+}
+
+type activeJob struct {
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+func New(cfg Config) *Queue {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	return &Queue{cfg: cfg, wake: make(chan struct{}, 1), active: map[string]*activeJob{}}
+}
+
+func defaultBackoff(attempts int) time.Duration {
+	d := 2 * time.Second << min(attempts-1, 10)
+	return min(d, 5*time.Minute)
+}
+
+// Enqueue validates j, stores it as queued and returns it with its ID set.
+func (q *Queue) Enqueue(ctx context.Context, j *Job) (*Job, error) {
+	switch {
+	case j.Kind == KindChat && j.Chat != nil:
+	case j.Kind == KindChatBatch && len(j.ChatBatch) > 0:
+	case j.Kind == KindEmbedding && j.Embedding != nil:
+	default:
+		return nil, fmt.Errorf("job of kind %q has no matching request", j.Kind)
+	}
+	now := time.Now()
+	out := *j
+	if out.ID == "" {
+		out.ID = newID()
+	}
+	out.Status = job.StatusQueued
+	out.Attempts = 0
+	out.CreatedAt, out.UpdatedAt = now, now
+	if out.RunAt.IsZero() {
+		out.RunAt = now
+	}
+	if err := q.cfg.Store.Save(ctx, &out); err != nil {
+		return nil, err
+	}
+	q.notify()
+	return &out, nil
+}
+
+// Get returns the current state of a job.
+func (q *Queue) Get(ctx context.Context, id string) (*Job, error) {
+	return q.cfg.Store.Get(ctx, id)
+}
+
+// Cancel cancels a queued or running job. Canceling a finished job is a
+// no-op.
+func (q *Queue) Cancel(ctx context.Context, id string) error {
+	q.mu.Lock()
+	if a, ok := q.active[id]; ok {
+		// the worker records the cancellation
+		a.canceled = true
+		a.cancel()
+		q.mu.Unlock()
+		return nil
+	}
+	j, err := q.cfg.Store.Get(ctx, id)
+	if err == nil && !j.Status.Done() {
+		j.Status = job.StatusCanceled
+		j.UpdatedAt = time.Now()
+		err = q.cfg.Store.Save(ctx, j)
+	} else {
+		j = nil
+	}
+	q.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if j != nil {
+		q.complete(j)
+	}
+	return nil
+}
+
+// Wait polls the job until it finishes or ctx is done.
+func (q *Queue) Wait(ctx context.Context, id string) (*Job, error) {
+	return job.Wait(ctx, q.cfg.PollInterval, func(ctx context.Context) (*Job, job.Status, error) {
+		j, err := q.cfg.Store.Get(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		return j, j.Status, nil
+	})
+}
+
+// Run works through due jobs until ctx is done, then waits for running
+// jobs to stop and returns ctx.Err(). Jobs left running by an earlier
+// process are queued again. Jobs are admitted as admission.Background
+// unless ctx sets another class.
+func (q *Queue) Run(ctx context.Context) error {
+	stale, err := q.cfg.Store.List(ctx, job.StatusRunning)
+	if err != nil {
+		return err
+	}
+	for _, j := range stale {
+		j.Status = job.StatusQueued
+		if err := q.cfg.Store.Save(ctx, j); err != nil {
+			return err
+		}
+	}
+
+	sem := make(chan struct{}, q.cfg.Workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		due, err := q.due(ctx)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		for _, j := range due {
+			select {
+			case sem <- struct{}{}:
+			default:
+				// all workers busy; pick the rest up later
+				continue
+			}
+			jobCtx, ok := q.claim(ctx, j.ID)
+			if !ok {
+				<-sem
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				q.run(ctx, jobCtx, j)
+			}()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// due returns the queued jobs whose RunAt has passed, oldest first.
+func (q *Queue) due(ctx context.Context) ([]*Job, error) {
+	queued, err := q.cfg.Store.List(ctx, job.StatusQueued)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	queued = slices.DeleteFunc(queued, func(j *Job) bool { return j.RunAt.After(now) })
+	slices.SortFunc(queued, func(a, b *Job) int { return a.RunAt.Compare(b.RunAt) })
+	return queued, nil
+}
+
+// claim marks the job as running, unless it was canceled or claimed since
+// it was listed, and returns the context of its attempt.
+func (q *Queue) claim(ctx context.Context, id string) (context.Context, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, busy := q.active[id]; busy {
+		return nil, false
+	}
+	j, err := q.cfg.Store.Get(ctx, id)
+	if err != nil || j.Status != job.StatusQueued {
+		return nil, false
+	}
+	j.Status = job.StatusRunning
+	j.UpdatedAt = time.Now()
+	if err := q.cfg.Store.Save(ctx, j); err != nil {
+		return nil, false
+	}
+	jobCtx, cancel := context.WithCancel(ctx)
+	q.active[id] = &activeJob{cancel: cancel}
+	return jobCtx, true
+}
+
+func (q *Queue) run(ctx, jobCtx context.Context, j *Job) {
+	err := q.execute(jobCtx, j)
+	q.mu.Lock()
+	a := q.active[j.ID]
+	delete(q.active, j.ID)
+	wasCanceled := a.canceled
+	q.mu.Unlock()
+	a.cancel()
+
+	// record the outcome even if ctx ended meanwhile
+	saveCtx := context.WithoutCancel(ctx)
+	j.Attempts++
+	j.UpdatedAt = time.Now()
+	switch {
+	case wasCanceled:
+		j.Status = job.StatusCanceled
+	case err == nil:
+		j.Status = job.StatusSucceeded
+		j.Error, j.ErrorClass = "", ""
+	case ctx.Err() != nil:
+		// shutting down: run the attempt again next time
+		j.Attempts--
+		j.Status = job.StatusQueued
+	default:
+		j.Error, j.ErrorClass = err.Error(), chat.ClassifyError(err)
+		maxAttempts := j.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = q.cfg.MaxAttempts
+		}
+		if j.Attempts < maxAttempts && chat.IsRetryableClass(j.ErrorClass) {
+			j.Status = job.StatusQueued
+			j.RunAt = time.Now().Add(q.cfg.Backoff(j.Attempts))
+		} else {
+			j.Status = job.StatusFailed
+		}
+	}
+	if err := q.cfg.Store.Save(saveCtx, j); err != nil {
+		return
+	}
+	if j.Status.Done() {
+		q.complete(j)
+	}
+}
+
+func (q *Queue) execute(ctx context.Context, j *Job) error {
+	if !admission.HasClass(ctx) {
+		ctx = admission.WithClass(ctx, admission.Background)
+	}
+	switch j.Kind {
+	case KindChat:
+		res, err := q.cfg.Client.Chat(ctx, replay(j.Chat))
+		j.ChatResult = res
+		return err
+	case KindChatBatch:
+		requests := make([][]chat.Option, len(j.ChatBatch))
+		for i := range j.ChatBatch {
+			requests[i] = []chat.Option{replay(&j.ChatBatch[i])}
+		}
+		res, err := q.cfg.Client.ChatBatch(ctx, uniai.ChatBatchConfig{Concurrency: q.cfg.BatchConcurrency}, requests...)
+		j.ChatBatchResult = res
+		return err
+	case KindEmbedding:
+		req := *j.Embedding
+		res, err := q.cfg.Client.Embedding(ctx, func(r *embedding.Request) { *r = req })
+		j.EmbeddingResult = res
+		return err
+	}
+	return fmt.Errorf("unknown job kind %q", j.Kind)
+}
+
+// replay returns an option that restores a stored request.
+func replay(req *chat.Request) chat.Option {
+	saved := *req
+	return func(r *chat.Request) { *r = saved }
+}
+
+func (q *Queue) complete(j *Job) {
+	if q.cfg.OnComplete != nil {
+		q.cfg.OnComplete(j)
+	}
+	if q.cfg.Webhook != nil {
+		q.cfg.Webhook.SendAsync(webhook.Event{Type: webhook.TypeJob, Subject: j.ID, Data: j})
+	}
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func newID() string {
+	var b [12]byte
+	rand.Read(b[:])
+	return "job_" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/job"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestQueueRetriesAndCompletes(t *testing.T) {
+	client := uniai.New(uniai.Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{
+		{Err: errors.New("status 503: overloaded")},
+		fake.Text("done", 0, 0),
+	}}))
+	store, err := NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	completed := make(chan *Job, 1)
+	q := New(Config{
+		Client:       client,
+		Store:        store,
+		Backoff:      func(int) time.Duration { return time.Millisecond },
+		PollInterval: 5 * time.Millisecond,
+		OnComplete:   func(j *Job) { completed <- j },
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	j, err := Chat(uniai.WithProvider("fake"), uniai.WithMessages(uniai.User("hi")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Meta = map[string]string{"user": "u1"}
+	j, err = q.Enqueue(ctx, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-completed:
+		if got.ID != j.ID || got.Status != job.StatusSucceeded || got.Attempts != 2 || got.ChatResult.Text != "done" || got.Meta["user"] != "u1" {
+			t.Fatalf("unexpected job %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not complete")
+	}
+	stored, err := q.Get(ctx, j.ID)
+	if err != nil || stored.Status != job.StatusSucceeded {
+		t.Fatalf("unexpected stored job %+v, %v", stored, err)
+	}
+}
+
+func TestQueueCancelScheduled(t *testing.T) {
+	q := New(Config{})
+	j, err := q.Enqueue(context.Background(), &Job{
+		Kind:  KindChat,
+		RunAt: time.Now().Add(time.Hour),
+	})
+	if err == nil {
+		t.Fatalf("expected an error for a job without a request, got %+v", j)
+	}
+	j, err = Chat(uniai.WithMessages(uniai.User("later")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.RunAt = time.Now().Add(time.Hour)
+	j, err = q.Enqueue(context.Background(), j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Cancel(context.Background(), j.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := q.Get(context.Background(), j.ID)
+	if err != nil || got.Status != job.StatusCanceled {
+		t.Fatalf("unexpected job %+v, %v", got, err)
+	}
+	if _, err := q.Get(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/quailyquaily/uniai/encryption"
+	"github.com/quailyquaily/uniai/job"
+)
+
+// ErrNotFound is returned by a Store for unknown job IDs.
+var ErrNotFound = errors.New("job not found")
+
+// Store persists jobs. Implementations must be safe for concurrent use and
+// return copies, so callers may modify the jobs they get.
+type Store interface {
+	// Save inserts or replaces the job with j.ID.
+	Save(ctx context.Context, j *Job) error
+	Get(ctx context.Context, id string) (*Job, error)
+	// List returns the jobs with one of the statuses, or all jobs when
+	// none are given.
+	List(ctx context.Context, statuses ...job.Status) ([]*Job, error)
+}
+
+// MemoryStore is an in-process Store. Jobs do not survive a restart.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	jobs map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: map[string][]byte{}}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, j *Job) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs == nil {
+		s.jobs = map[string][]byte{}
+	}
+	s.jobs[j.ID] = data
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.RLock()
+	data, ok := s.jobs[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return decodeJob(data)
+}
+
+func (s *MemoryStore) List(ctx context.Context, statuses ...job.Status) ([]*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Job
+	for _, data := range s.jobs {
+		j, err := decodeJob(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(statuses) == 0 || slices.Contains(statuses, j.Status) {
+			out = append(out, j)
+		}
+	}
+	return out, nil
+}
+
+// DirStore is a persistent Store keeping each job in a JSON file named by
+// its ID.
+type DirStore struct {
+	// Sealer, if set, encrypts the files, which hold the job's messages
+	// and result. Set it before the first call; files written without it
+	// cannot be read with it, and vice versa.
+	Sealer *encryption.Sealer
+
+	dir string
+	mu  sync.Mutex
+}
+
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+func (s *DirStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid job id %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *DirStore) Save(ctx context.Context, j *Job) error {
+	path, err := s.path(j.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	if s.Sealer != nil {
+		if data, err = s.Sealer.Seal(ctx, data, []byte(j.ID)); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// write and rename, so a crash never leaves a partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *DirStore) Get(ctx context.Context, id string) (*Job, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.decode(ctx, id, data)
+}
+
+func (s *DirStore) List(ctx context.Context, statuses ...job.Status) ([]*Job, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []*Job
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		j, err := s.decode(ctx, strings.TrimSuffix(filepath.Base(path), ".json"), data)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", filepath.Base(path), err)
+		}
+		if len(statuses) == 0 || slices.Contains(statuses, j.Status) {
+			out = append(out, j)
+		}
+	}
+	return out, nil
+}
+
+func (s *DirStore) decode(ctx context.Context, id string, data []byte) (*Job, error) {
+	if s.Sealer != nil {
+		var err error
+		if data, err = s.Sealer.Open(ctx, data, []byte(id)); err != nil {
+			return nil, err
+		}
+	}
+	return decodeJob(data)
+}
+
+func decodeJob(data []byte) (*Job, error) {
+	var j Job
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
@@ -0,0 +1,329 @@
+// Package lint checks declarative uniai files before they are deployed:
+// client configs, routing policies, prompt templates and tool definitions.
+// It catches typos such as unknown config fields and providers, template
+// syntax errors and invalid tool schemas, and with a Catalog, model names
+// the provider does not serve.
+//
+//	l := &lint.Linter{Catalog: lint.ClientCatalog(client)}
+//	issues, err := l.File(ctx, "policy.json")
+package lint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/policy"
+	"github.com/quailyquaily/uniai/prompt"
+)
+
+// Providers are the provider names built into uniai.Client.
+var Providers = []string{
+	"openai", "openai_custom", "deepseek", "xai", "perplexity", "gemini",
+	"vllm", "azure", "anthropic", "bedrock", "susanoo",
+}
+
+// Issue is a problem found in a file. Path locates it within the file, e.g.
+// "rules[2].model"; it is empty for problems with the file as a whole.
+type Issue struct {
+	File    string `json:"file"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return i.File + ": " + i.Message
+	}
+	return i.File + ": " + i.Path + ": " + i.Message
+}
+
+// Catalog lists the model IDs a provider serves.
+type Catalog func(ctx context.Context, provider string) ([]string, error)
+
+// ClientCatalog returns a Catalog listing models with client.ListModels.
+func ClientCatalog(client *uniai.Client) Catalog {
+	return func(ctx context.Context, provider string) ([]string, error) {
+		models, err := client.ListModels(ctx, provider)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(models))
+		for _, m := range models {
+			ids = append(ids, m.ID)
+		}
+		return ids, nil
+	}
+}
+
+// Linter checks files. The zero value checks everything but model names.
+type Linter struct {
+	// Catalog, if set, is used to check model references. Each provider's
+	// catalog is fetched once.
+	Catalog Catalog
+	// Providers are extra provider names, such as those registered with
+	// uniai.Client.RegisterProvider.
+	Providers []string
+
+	catalogs map[string]catalogResult
+}
+
+type catalogResult struct {
+	models map[string]bool
+	err    error
+}
+
+// File checks the file at path, telling its kind from its name and
+// content: .tmpl, .prompt, .txt and .md files are prompt templates; JSON
+// files are policies when they have "rules", tool definitions when they
+// hold a tool or a list of tools, and client configs otherwise.
+func (l *Linter) File(ctx context.Context, path string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tmpl", ".prompt", ".txt", ".md":
+		return l.Prompt(path, data), nil
+	case ".json":
+	default:
+		return nil, fmt.Errorf("%s: unknown file type", path)
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return l.Tools(path, data), nil
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return []Issue{{File: path, Message: "invalid JSON: " + err.Error()}}, nil
+	}
+	switch {
+	case probe["rules"] != nil:
+		return l.Policy(ctx, path, data), nil
+	case probe["function"] != nil:
+		return l.Tools(path, data), nil
+	default:
+		return l.Config(ctx, path, data), nil
+	}
+}
+
+// Config checks a client config in the JSON form read by the uniai
+// command, whose keys are uniai.Config field names.
+func (l *Linter) Config(ctx context.Context, file string, data []byte) []Issue {
+	var cfg uniai.Config
+	if err := decodeStrict(data, &cfg); err != nil {
+		return []Issue{{File: file, Message: err.Error()}}
+	}
+	var issues []Issue
+	add := func(path, msg string) { issues = append(issues, Issue{File: file, Path: path, Message: msg}) }
+	if cfg.Provider != "" && !l.knownProvider(cfg.Provider) {
+		add("Provider", fmt.Sprintf("unknown provider %q", cfg.Provider))
+	}
+	for _, m := range []struct{ field, provider, model string }{
+		{"OpenAIModel", "openai", cfg.OpenAIModel},
+		{"AzureOpenAIModel", "azure", cfg.AzureOpenAIModel},
+		{"AnthropicModel", "anthropic", cfg.AnthropicModel},
+		{"VLLMModel", "vllm", cfg.VLLMModel},
+		{"GeminiModel", "gemini", cfg.GeminiModel},
+	} {
+		if m.model != "" {
+			if msg := l.checkModel(ctx, m.provider, m.model); msg != "" {
+				add(m.field, msg)
+			}
+		}
+	}
+	for _, field := range []struct {
+		name string
+		keys []string
+	}{
+		{"ProviderTags", mapKeys(cfg.ProviderTags)},
+		{"ProviderSystemPrompts", mapKeys(cfg.ProviderSystemPrompts)},
+	} {
+		for _, key := range field.keys {
+			name, _, _ := strings.Cut(key, "/")
+			if !l.knownProvider(name) {
+				add(field.name+"."+key, fmt.Sprintf("unknown provider %q", name))
+			}
+		}
+	}
+	if cfg.Policy != nil {
+		issues = append(issues, l.policy(ctx, file, "Policy.", cfg.Policy)...)
+	}
+	return issues
+}
+
+// Policy checks a JSON routing policy.
+func (l *Linter) Policy(ctx context.Context, file string, data []byte) []Issue {
+	p, err := policy.Parse(data)
+	if err != nil {
+		return []Issue{{File: file, Message: err.Error()}}
+	}
+	return l.policy(ctx, file, "", p)
+}
+
+func (l *Linter) policy(ctx context.Context, file, prefix string, p *policy.Policy) []Issue {
+	if err := p.Validate(); err != nil {
+		return []Issue{{File: file, Path: strings.TrimSuffix(prefix, "."), Message: err.Error()}}
+	}
+	var issues []Issue
+	seen := map[string]bool{}
+	for i, r := range p.Rules {
+		path := fmt.Sprintf("%srules[%d]", prefix, i)
+		add := func(field, msg string) {
+			issues = append(issues, Issue{File: file, Path: path + field, Message: msg})
+		}
+		if r.Name != "" {
+			if seen[r.Name] {
+				add(".name", fmt.Sprintf("duplicate rule name %q", r.Name))
+			}
+			seen[r.Name] = true
+		}
+		if r.Provider != "" && !l.knownProvider(r.Provider) {
+			add(".provider", fmt.Sprintf("unknown provider %q", r.Provider))
+		} else if r.Provider != "" && r.Model != "" {
+			if msg := l.checkModel(ctx, r.Provider, r.Model); msg != "" {
+				add(".model", msg)
+			}
+		}
+		for j, pattern := range r.Match.Providers {
+			if !strings.HasSuffix(pattern, "*") && !l.knownProvider(pattern) {
+				add(fmt.Sprintf(".match.providers[%d]", j), fmt.Sprintf("unknown provider %q", pattern))
+			}
+		}
+		if r.Match.BudgetUsed < 0 || r.Match.BudgetUsed > 1 {
+			add(".match.budget_used", "must be between 0 and 1")
+		}
+		if r.Match.MaxTokens > 0 && r.Match.MinTokens > r.Match.MaxTokens {
+			add(".match", "min_tokens is above max_tokens, so the rule never matches")
+		}
+	}
+	return issues
+}
+
+// Prompt checks the syntax of a prompt template.
+func (l *Linter) Prompt(file string, data []byte) []Issue {
+	if _, err := prompt.Parse(filepath.Base(file), string(data)); err != nil {
+		return []Issue{{File: file, Message: err.Error()}}
+	}
+	return nil
+}
+
+var toolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// toolFunction is a function tool as the uniai command's -tools files
+// declare it.
+type toolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+	Strict      *bool           `json:"strict,omitempty"`
+}
+
+// toolDef is a tool in either the OpenAI format, with the function nested,
+// or the flat format of toolFunction.
+type toolDef struct {
+	Type     string        `json:"type,omitempty"`
+	Function *toolFunction `json:"function,omitempty"`
+	toolFunction
+}
+
+// Tools checks a tool definition or a list of them, in the OpenAI function
+// tool format or the flat {"name", "description", "parameters"} format read
+// by uniai chat -tools.
+func (l *Linter) Tools(file string, data []byte) []Issue {
+	var tools []toolDef
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		tools = make([]toolDef, 1)
+		if err := decodeStrict(data, &tools[0]); err != nil {
+			return []Issue{{File: file, Message: err.Error()}}
+		}
+	} else if err := decodeStrict(data, &tools); err != nil {
+		return []Issue{{File: file, Message: err.Error()}}
+	}
+	var issues []Issue
+	seen := map[string]bool{}
+	for i, t := range tools {
+		path := fmt.Sprintf("[%d]", i)
+		add := func(p, msg string) { issues = append(issues, Issue{File: file, Path: p, Message: msg}) }
+		if t.Type != "" && t.Type != "function" {
+			add(path+".type", fmt.Sprintf("unknown tool type %q", t.Type))
+		}
+		prefix := path
+		fn := t.toolFunction
+		if t.Function != nil {
+			prefix, fn = path+".function", *t.Function
+		}
+		name := fn.Name
+		switch {
+		case !toolName.MatchString(name):
+			add(prefix+".name", fmt.Sprintf("invalid tool name %q: use 1-64 letters, digits, _ or -", name))
+		case seen[name]:
+			add(prefix+".name", fmt.Sprintf("duplicate tool %q", name))
+		}
+		seen[name] = true
+		if len(fn.Parameters) == 0 {
+			continue
+		}
+		var schema any
+		if err := json.Unmarshal(fn.Parameters, &schema); err != nil {
+			add(prefix+".parameters", "invalid JSON: "+err.Error())
+			continue
+		}
+		for _, p := range CheckSchema(schema) {
+			add(prefix+".parameters"+p.Path, p.Message)
+		}
+	}
+	return issues
+}
+
+func (l *Linter) knownProvider(name string) bool {
+	return slices.Contains(Providers, name) || slices.Contains(l.Providers, name)
+}
+
+// checkModel returns a message when the catalog of provider lacks model.
+func (l *Linter) checkModel(ctx context.Context, provider, model string) string {
+	if l.Catalog == nil {
+		return ""
+	}
+	if l.catalogs == nil {
+		l.catalogs = map[string]catalogResult{}
+	}
+	res, ok := l.catalogs[provider]
+	if !ok {
+		ids, err := l.Catalog(ctx, provider)
+		res = catalogResult{models: map[string]bool{}, err: err}
+		for _, id := range ids {
+			res.models[id] = true
+		}
+		l.catalogs[provider] = res
+	}
+	switch {
+	case res.err != nil:
+		return fmt.Sprintf("cannot check model %q: %v", model, res.err)
+	case !res.models[model]:
+		return fmt.Sprintf("unknown %s model %q", provider, model)
+	}
+	return ""
+}
+
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,64 @@
+package lint
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{"Provider":"opneai","OpenAIModel":"gpt-4o","ProviderTags":{"azure/gpt-4o":["eu"]}}`,
+		"typo.json":   `{"OpenAIKey":"sk-test"}`,
+		"policy.json": `{"rules":[
+			{"name":"fast","match":{"models":["fast"]},"action":"route","provider":"openai","model":"gpt-5-mnii"},
+			{"name":"fast","match":{"providers":["antropic"]},"action":"deny"}
+		]}`,
+		"system.tmpl": `Today is {{.date}. Hi {{.user}}.`,
+		"tools.json": `[
+			{"type":"function","function":{"name":"get_weather","parameters":{"type":"object","properties":{"city":{"type":"sting"}},"required":["city","unit"],"additionalProperties":false}}},
+			{"name":"lookup","parameters":{"type":"object","properties":{"id":{"$ref":"#/$defs/id"}},"$defs":{"ID":{"type":"string"}},"requird":["id"]}}
+		]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := &Linter{Catalog: func(ctx context.Context, provider string) ([]string, error) {
+		return []string{"gpt-4o", "gpt-5-mini"}, nil
+	}}
+	want := map[string][]string{
+		"config.json": {`Provider: unknown provider "opneai"`},
+		"typo.json":   {`unknown field "OpenAIKey"`},
+		"policy.json": {
+			`rules[0].model: unknown openai model "gpt-5-mnii"`,
+			`rules[1].name: duplicate rule name "fast"`,
+			`rules[1].match.providers[0]: unknown provider "antropic"`,
+		},
+		"system.tmpl": {`bad character U+007D '}'`},
+		"tools.json": {
+			`[0].function.parameters.properties.city.type: unknown type "sting"`,
+			`[0].function.parameters.required[1]: required property "unit" is not defined in properties`,
+			`[1].parameters.requird: unknown schema keyword "requird"`,
+			`[1].parameters.properties.id.$ref: $ref "#/$defs/id" does not resolve`,
+		},
+	}
+	for name, msgs := range want {
+		issues, err := l.File(context.Background(), filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != len(msgs) {
+			t.Fatalf("%s: got %v, want %d issues", name, issues, len(msgs))
+		}
+		for i, msg := range msgs {
+			if !strings.Contains(issues[i].String(), msg) {
+				t.Errorf("%s: issue %d is %q, want %q", name, i, issues[i], msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var schemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// schemaKeywords are the JSON Schema keywords providers understand; any
+// other key in a schema is most likely a typo.
+var schemaKeywords = []string{
+	"$schema", "$id", "$ref", "$defs", "$comment", "definitions",
+	"type", "enum", "const", "title", "description", "default", "examples",
+	"format", "pattern", "minLength", "maxLength",
+	"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf",
+	"properties", "required", "additionalProperties", "patternProperties",
+	"propertyNames", "minProperties", "maxProperties",
+	"dependentRequired", "dependentSchemas", "unevaluatedProperties",
+	"items", "prefixItems", "additionalItems", "contains", "minItems",
+	"maxItems", "uniqueItems", "unevaluatedItems",
+	"anyOf", "oneOf", "allOf", "not", "if", "then", "else",
+	"nullable", "deprecated", "readOnly", "writeOnly",
+	"contentEncoding", "contentMediaType",
+}
+
+// CheckSchema checks that schema, a decoded JSON value, is a well-formed
+// JSON Schema: known keywords and types, required properties that exist,
+// and local $refs that resolve. Paths in the returned issues start with
+// "." and have no File.
+func CheckSchema(schema any) []Issue {
+	c := schemaChecker{root: schema}
+	c.check(schema, "")
+	return c.issues
+}
+
+type schemaChecker struct {
+	root   any
+	issues []Issue
+}
+
+func (c *schemaChecker) add(path, format string, args ...any) {
+	c.issues = append(c.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *schemaChecker) check(v any, path string) {
+	if _, ok := v.(bool); ok {
+		return
+	}
+	s, ok := v.(map[string]any)
+	if !ok {
+		c.add(path, "schema must be an object")
+		return
+	}
+	for _, k := range mapKeys(s) {
+		if !slices.Contains(schemaKeywords, k) {
+			c.add(path+"."+k, "unknown schema keyword %q", k)
+		}
+	}
+	switch t := s["type"].(type) {
+	case nil:
+	case string:
+		c.checkType(t, path+".type")
+	case []any:
+		for i, e := range t {
+			name, _ := e.(string)
+			c.checkType(name, fmt.Sprintf("%s.type[%d]", path, i))
+		}
+	default:
+		c.add(path+".type", "type must be a string or a list of strings")
+	}
+	props, isMap := s["properties"].(map[string]any)
+	if _, ok := s["properties"]; ok && !isMap {
+		c.add(path+".properties", "properties must be an object")
+	}
+	for _, name := range mapKeys(props) {
+		c.check(props[name], path+".properties."+name)
+	}
+	if req, ok := s["required"]; ok {
+		list, ok := req.([]any)
+		if !ok {
+			c.add(path+".required", "required must be a list of property names")
+		}
+		for i, e := range list {
+			name, ok := e.(string)
+			switch {
+			case !ok:
+				c.add(fmt.Sprintf("%s.required[%d]", path, i), "required entries must be strings")
+			case props != nil && props[name] == nil:
+				c.add(fmt.Sprintf("%s.required[%d]", path, i), "required property %q is not defined in properties", name)
+			}
+		}
+	}
+	if e, ok := s["enum"]; ok {
+		if list, ok := e.([]any); !ok || len(list) == 0 {
+			c.add(path+".enum", "enum must be a non-empty list")
+		}
+	}
+	for _, k := range []string{"items", "additionalProperties", "not", "if", "then", "else", "contains", "propertyNames", "additionalItems", "unevaluatedProperties", "unevaluatedItems"} {
+		if sub, ok := s[k]; ok {
+			c.check(sub, path+"."+k)
+		}
+	}
+	for _, k := range []string{"anyOf", "oneOf", "allOf", "prefixItems"} {
+		sub, ok := s[k]
+		if !ok {
+			continue
+		}
+		list, ok := sub.([]any)
+		if !ok || len(list) == 0 {
+			c.add(path+"."+k, "%s must be a non-empty list of schemas", k)
+			continue
+		}
+		for i, e := range list {
+			c.check(e, fmt.Sprintf("%s.%s[%d]", path, k, i))
+		}
+	}
+	for _, k := range []string{"$defs", "definitions", "patternProperties", "dependentSchemas"} {
+		defs, ok := s[k]
+		if !ok {
+			continue
+		}
+		m, ok := defs.(map[string]any)
+		if !ok {
+			c.add(path+"."+k, "%s must be an object", k)
+			continue
+		}
+		for _, name := range mapKeys(m) {
+			c.check(m[name], path+"."+k+"."+name)
+		}
+	}
+	if ref, ok := s["$ref"]; ok {
+		c.checkRef(ref, path+".$ref")
+	}
+}
+
+func (c *schemaChecker) checkType(name, path string) {
+	if !slices.Contains(schemaTypes, name) {
+		c.add(path, "unknown type %q", name)
+	}
+}
+
+// checkRef resolves local references ("#/$defs/address"). Remote
+// references are not followed.
+func (c *schemaChecker) checkRef(v any, path string) {
+	ref, ok := v.(string)
+	if !ok {
+		c.add(path, "$ref must be a string")
+		return
+	}
+	if !strings.HasPrefix(ref, "#") {
+		return
+	}
+	node := c.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		m, ok := node.(map[string]any)
+		if !ok || m[part] == nil {
+			c.add(path, "$ref %q does not resolve", ref)
+			return
+		}
+		node = m[part]
+	}
+}
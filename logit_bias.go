@@ -0,0 +1,111 @@
+package uniai
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/tokens"
+)
+
+// defaultModel returns the model a provider will use for req.
+func (c *Client) defaultModel(providerName string, req *chat.Request) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	switch providerName {
+	case "azure":
+		return c.cfg.AzureOpenAIModel
+	case "anthropic":
+		return c.cfg.AnthropicModel
+	case "bedrock":
+		return c.cfg.AwsBedrockModelArn
+	case "gemini":
+		if c.cfg.GeminiModel != "" {
+			return c.cfg.GeminiModel
+		}
+	case "vllm":
+		return c.cfg.VLLMModel
+	}
+	return c.cfg.OpenAIModel
+}
+
+// applyLogitBias converts Options.LogitBias from token strings to the
+// provider's token-ID logit_bias option. Explicit IDs already present in the
+// provider options take precedence.
+func applyLogitBias(providerName, model string, req *chat.Request) (*chat.Request, []string) {
+	if len(req.Options.LogitBias) == 0 {
+		return req, nil
+	}
+	target := ""
+	switch providerName {
+	case "openai", "openai_custom", "deepseek", "xai", "vllm":
+		target = "openai"
+	case "azure":
+		target = "azure"
+		if len(req.Options.Azure) == 0 {
+			target = "openai"
+		}
+	default:
+		return req, []string{fmt.Sprintf("logit_bias is not supported by %s; ignored", providerName)}
+	}
+	enc := tokens.ForModel(model)
+	if enc == nil {
+		return req, []string{fmt.Sprintf("logit_bias ignored: no tokenizer registered for model %q", model)}
+	}
+
+	var warnings []string
+	bias := map[string]any{}
+	keys := make([]string, 0, len(req.Options.LogitBias))
+	for text := range req.Options.LogitBias {
+		keys = append(keys, text)
+	}
+	sort.Strings(keys)
+	for _, text := range keys {
+		ids, err := enc.Encode(text)
+		if err != nil || len(ids) == 0 {
+			warnings = append(warnings, fmt.Sprintf("logit_bias: cannot tokenize %q; ignored", text))
+			continue
+		}
+		if len(ids) > 1 {
+			warnings = append(warnings, fmt.Sprintf("logit_bias: %q spans %d tokens; biasing each", text, len(ids)))
+		}
+		value := int64(clampFloat(req.Options.LogitBias[text], -100, 100))
+		for _, id := range ids {
+			bias[fmt.Sprint(id)] = value
+		}
+	}
+	if len(bias) == 0 {
+		return req, warnings
+	}
+
+	out := *req
+	opts := structs.NewJSONMap()
+	src := out.Options.OpenAI
+	if target == "azure" {
+		src = out.Options.Azure
+	}
+	for k, v := range src {
+		opts[k] = v
+	}
+	if existing, ok := opts["logit_bias"]; ok {
+		switch m := existing.(type) {
+		case map[string]any:
+			for k, v := range m {
+				bias[k] = v
+			}
+		case structs.JSONMap:
+			for k, v := range m {
+				bias[k] = v
+			}
+		}
+	}
+	opts["logit_bias"] = bias
+	if target == "azure" {
+		out.Options.Azure = opts
+	} else {
+		out.Options.OpenAI = opts
+	}
+	return &out, warnings
+}
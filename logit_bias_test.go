@@ -0,0 +1,37 @@
+package uniai
+
+import (
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/tokens"
+)
+
+func TestApplyLogitBias(t *testing.T) {
+	tokens.Register("test-model", tokens.EncoderFunc(func(text string) ([]int, error) {
+		return map[string][]int{"yes": {9891}, "no": {2201}}[text], nil
+	}))
+	defer tokens.Register("test-model", nil)
+
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("hi")),
+		chat.WithLogitBias(map[string]float64{"yes": 200, "no": -50}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, warnings := applyLogitBias("openai", "test-model-1", req)
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	bias, _ := out.Options.OpenAI["logit_bias"].(map[string]any)
+	if bias["9891"] != int64(100) || bias["2201"] != int64(-50) {
+		t.Fatalf("unexpected logit_bias: %#v", bias)
+	}
+
+	for _, provider := range []string{"anthropic", "gemini"} {
+		if _, warnings := applyLogitBias(provider, "test-model-1", req); len(warnings) != 1 {
+			t.Fatalf("expected unsupported warning for %s, got %v", provider, warnings)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package uniai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// MapConfig configures Map.
+type MapConfig struct {
+	// Concurrency is the number of prompts in flight (default 4).
+	Concurrency int
+	// MaxAttempts is the number of attempts per item (default 1). Only
+	// errors for which chat.IsRetryableClass holds are retried.
+	MaxAttempts int
+	// RetryDelay is the wait before the second attempt, doubled for each
+	// further one (default 1s).
+	RetryDelay time.Duration
+}
+
+// Map runs prompt for every item as a separate Chat call, with
+// cfg.Concurrency calls in flight, and retries failed items. Results and
+// ItemErrors are indexed like items; as with ChatBatch, a failed item does
+// not fail the call, and the error is non-nil only when ctx ends first.
+func Map[T any](ctx context.Context, c *Client, items []T, prompt func(item T) []chat.Option, cfg MapConfig) (*chat.BatchResult, error) {
+	return runBatch(ctx, cfg.Concurrency, len(items), func(ctx context.Context, i int) (*chat.Result, error) {
+		return c.chatRetry(ctx, cfg.MaxAttempts, cfg.RetryDelay, prompt(items[i]))
+	})
+}
+
+// chatRetry calls Chat up to maxAttempts times while it fails with a
+// retryable error.
+func (c *Client) chatRetry(ctx context.Context, maxAttempts int, delay time.Duration, opts []chat.Option) (*chat.Result, error) {
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		res, err := c.Chat(ctx, opts...)
+		if err == nil || attempt >= maxAttempts || !chat.IsRetryableClass(chat.ClassifyError(err)) {
+			return res, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// DefaultReduceFanIn is the number of texts combined per call when
+// ReduceConfig.FanIn is not set.
+const DefaultReduceFanIn = 8
+
+// ReduceConfig configures Reduce.
+type ReduceConfig struct {
+	// FanIn is the number of texts combined by one call (default 8).
+	FanIn int
+	// Concurrency, MaxAttempts and RetryDelay apply to the calls of each
+	// round as in MapConfig.
+	Concurrency int
+	MaxAttempts int
+	RetryDelay  time.Duration
+}
+
+// Reduce combines texts into one with the model. combine builds the
+// request for a group of at most cfg.FanIn texts, and its reply replaces
+// the group; rounds repeat until one text remains, so any number of texts
+// fits. A failed call fails the reduction. The result's Usage and Cost are
+// the totals over all calls.
+func Reduce(ctx context.Context, c *Client, texts []string, combine func(texts []string) []chat.Option, cfg ReduceConfig) (*chat.Result, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("nothing to reduce")
+	}
+	fanIn := cfg.FanIn
+	if fanIn < 2 {
+		fanIn = DefaultReduceFanIn
+	}
+	var total chat.Usage
+	var cost float64
+	for {
+		var groups [][]string
+		for start := 0; start < len(texts); start += fanIn {
+			groups = append(groups, texts[start:min(start+fanIn, len(texts))])
+		}
+		round, err := Map(ctx, c, groups, combine, MapConfig{
+			Concurrency: cfg.Concurrency,
+			MaxAttempts: cfg.MaxAttempts,
+			RetryDelay:  cfg.RetryDelay,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(round.ItemErrors) > 0 {
+			e := round.ItemErrors[0]
+			return nil, fmt.Errorf("reduce group %d: %s", e.Index, e.Error)
+		}
+		total.Add(round.Usage)
+		cost += round.Cost
+		if len(round.Results) == 1 {
+			res := round.Results[0]
+			res.Usage, res.Cost = total, cost
+			return res, nil
+		}
+		texts = make([]string, len(round.Results))
+		for i, res := range round.Results {
+			texts[i] = strings.TrimSpace(res.Text)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package uniai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestMapRetries(t *testing.T) {
+	client := New(Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{
+		{Err: errors.New("status 503: overloaded")},
+		fake.Text("A", 0, 0),
+		fake.Text("B", 0, 0),
+	}}))
+	out, err := Map(context.Background(), client, []string{"a", "b"}, func(item string) []ChatOption {
+		return []ChatOption{WithProvider("fake"), WithMessages(User("upper-case " + item))}
+	}, MapConfig{Concurrency: 1, MaxAttempts: 2, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.ItemErrors) != 0 || out.Results[0].Text != "A" || out.Results[1].Text != "B" {
+		t.Fatalf("unexpected result %+v", out)
+	}
+}
+
+func TestReduceRounds(t *testing.T) {
+	client := New(Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{fake.Text("merged", 0, 0)}}))
+	calls := 0
+	res, err := Reduce(context.Background(), client, []string{"1", "2", "3", "4", "5"}, func(texts []string) []ChatOption {
+		calls++
+		return []ChatOption{WithProvider("fake"), WithMessages(User("Merge:\n" + strings.Join(texts, "\n")))}
+	}, ReduceConfig{FanIn: 2, Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5 texts -> 3 -> 2 -> 1
+	if res.Text != "merged" || calls != 6 || res.Usage.OutputTokens != 6 {
+		t.Fatalf("unexpected result %q after %d calls, usage %+v", res.Text, calls, res.Usage)
+	}
+}
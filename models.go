@@ -0,0 +1,227 @@
+package uniai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/internal/httputil"
+	"github.com/quailyquaily/uniai/providers/anthropic"
+)
+
+const (
+	DefaultOllamaAPIBase       = "http://localhost:11434"
+	DefaultOpenRouterAPIBase   = "https://openrouter.ai/api/v1"
+	azureDeploymentsAPIVersion = "2022-12-01"
+)
+
+// ModelInfo is a model entry normalized across provider catalogs.
+type ModelInfo struct {
+	ID            string `json:"id"`
+	Provider      string `json:"provider"`
+	Name          string `json:"name,omitempty"`
+	OwnedBy       string `json:"owned_by,omitempty"`
+	Created       int64  `json:"created,omitempty"`
+	ContextLength int    `json:"context_length,omitempty"`
+}
+
+// ErrModelListUnsupported is returned by ListModels and Ping for providers
+// without a model list: perplexity, and registered providers that do not
+// implement ModelLister.
+var ErrModelListUnsupported = errors.New("listing models is not supported")
+
+// ModelLister is implemented by registered providers that can list their
+// models, for ListModels and Ping.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// ListModels returns the models available from providerName. Supported are
+// the OpenAI-compatible providers except perplexity, azure (deployments),
+// anthropic, ollama (local tags), openrouter (public catalog) and
+// registered providers implementing ModelLister.
+func (c *Client) ListModels(ctx context.Context, providerName string) ([]ModelInfo, error) {
+	c.providersMu.RLock()
+	custom := c.providers[providerName]
+	c.providersMu.RUnlock()
+	if custom != nil {
+		if l, ok := custom.(ModelLister); ok {
+			return l.ListModels(ctx)
+		}
+		return nil, fmt.Errorf("%w for provider %s", ErrModelListUnsupported, providerName)
+	}
+
+	switch providerName {
+	case "openai", "openai_custom":
+		return c.listOpenAIModels(ctx, providerName, c.cfg.OpenAIAPIBase, c.cfg.OpenAIAPIKey)
+	case "deepseek":
+		return c.listOpenAIModels(ctx, providerName, "https://api.deepseek.com", c.cfg.OpenAIAPIKey)
+	case "xai":
+		return c.listOpenAIModels(ctx, providerName, "https://api.x.ai/v1", c.cfg.OpenAIAPIKey)
+	case "vllm":
+		return c.listOpenAIModels(ctx, providerName, c.cfg.VLLMAPIBase, c.cfg.VLLMAPIKey)
+	case "gemini":
+		return c.listOpenAIModels(ctx, providerName, c.geminiOpenAIBase(), c.geminiAPIKey())
+	case "openrouter":
+		base := c.cfg.OpenRouterAPIBase
+		if base == "" {
+			base = DefaultOpenRouterAPIBase
+		}
+		return c.listOpenAIModels(ctx, providerName, base, c.cfg.OpenRouterAPIKey)
+	case "azure":
+		return c.listAzureDeployments(ctx)
+	case "anthropic":
+		return c.listAnthropicModels(ctx)
+	case "ollama":
+		return c.listOllamaModels(ctx)
+	default:
+		return nil, fmt.Errorf("%w for provider %s", ErrModelListUnsupported, providerName)
+	}
+}
+
+// Ping checks that providerName is reachable and the credentials are
+// accepted by listing its models. It returns the round-trip latency, or
+// ErrModelListUnsupported when the provider cannot be checked this way.
+func (c *Client) Ping(ctx context.Context, providerName string) (time.Duration, error) {
+	start := time.Now()
+	_, err := c.ListModels(ctx, providerName)
+	return time.Since(start), err
+}
+
+func (c *Client) listOpenAIModels(ctx context.Context, providerName, base, apiKey string) ([]ModelInfo, error) {
+	base = strings.TrimRight(base, "/")
+	if base == "" {
+		base = DefaultOpenAIAPIBase
+	}
+	headers := map[string]string{}
+	if apiKey != "" {
+		headers["Authorization"] = "Bearer " + apiKey
+	}
+	var out struct {
+		Data []struct {
+			ID            string `json:"id"`
+			Name          string `json:"name"`
+			OwnedBy       string `json:"owned_by"`
+			Created       int64  `json:"created"`
+			ContextLength int    `json:"context_length"`
+			MaxModelLen   int    `json:"max_model_len"`
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, base+"/models", headers, &out); err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(out.Data))
+	for _, m := range out.Data {
+		ctxLen := m.ContextLength
+		if ctxLen == 0 {
+			ctxLen = m.MaxModelLen
+		}
+		models = append(models, ModelInfo{
+			ID:            strings.TrimPrefix(m.ID, "models/"),
+			Provider:      providerName,
+			Name:          m.Name,
+			OwnedBy:       m.OwnedBy,
+			Created:       m.Created,
+			ContextLength: ctxLen,
+		})
+	}
+	return models, nil
+}
+
+func (c *Client) listAzureDeployments(ctx context.Context) ([]ModelInfo, error) {
+	if c.cfg.AzureOpenAIAPIKey == "" || c.cfg.AzureOpenAIEndpoint == "" {
+		return nil, fmt.Errorf("azure openai api key and endpoint are required")
+	}
+	url := strings.TrimRight(c.cfg.AzureOpenAIEndpoint, "/") + "/openai/deployments?api-version=" + azureDeploymentsAPIVersion
+	var out struct {
+		Data []struct {
+			ID        string `json:"id"`
+			Model     string `json:"model"`
+			Owner     string `json:"owner"`
+			CreatedAt int64  `json:"created_at"`
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, url, map[string]string{"api-key": c.cfg.AzureOpenAIAPIKey}, &out); err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(out.Data))
+	for _, d := range out.Data {
+		models = append(models, ModelInfo{ID: d.ID, Provider: "azure", Name: d.Model, OwnedBy: d.Owner, Created: d.CreatedAt})
+	}
+	return models, nil
+}
+
+// listAnthropicModels lists the models of the configured Anthropic
+// backend, by the IDs requests to that backend take.
+func (c *Client) listAnthropicModels(ctx context.Context) ([]ModelInfo, error) {
+	p, err := anthropic.New(c.anthropicConfig())
+	if err != nil {
+		return nil, err
+	}
+	list, err := p.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(list))
+	for _, m := range list {
+		info := ModelInfo{ID: m.ID, Provider: "anthropic", Name: m.Name, OwnedBy: "anthropic"}
+		if !m.Created.IsZero() {
+			info.Created = m.Created.Unix()
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+func (c *Client) listOllamaModels(ctx context.Context) ([]ModelInfo, error) {
+	base := strings.TrimRight(c.cfg.OllamaAPIBase, "/")
+	if base == "" {
+		base = DefaultOllamaAPIBase
+	}
+	var out struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			Model      string    `json:"model"`
+			ModifiedAt time.Time `json:"modified_at"`
+		} `json:"models"`
+	}
+	if err := getJSON(ctx, base+"/api/tags", nil, &out); err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(out.Models))
+	for _, m := range out.Models {
+		id := m.Model
+		if id == "" {
+			id = m.Name
+		}
+		models = append(models, ModelInfo{ID: id, Provider: "ollama", Name: m.Name, Created: m.ModifiedAt.Unix()})
+	}
+	return models, nil
+}
+
+func getJSON(ctx context.Context, url string, headers map[string]string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("model list request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, out)
+}
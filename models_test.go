@@ -0,0 +1,80 @@
+package uniai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestListModelsNormalizes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1beta/openai/models":
+			_, _ = w.Write([]byte(`{"data":[{"id":"models/gemini-2.5-flash","owned_by":"google"}]}`))
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"llama3:8b","model":"llama3:8b","modified_at":"2024-05-01T00:00:00Z"}]}`))
+		case "/v1/models":
+			if r.Header.Get("Authorization") != "Bearer key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":[{"id":"gpt-x","owned_by":"me","created":1},{"id":"qwen","max_model_len":32768}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(Config{OllamaAPIBase: srv.URL, OpenAIAPIBase: srv.URL + "/v1", OpenAIAPIKey: "key"})
+	models, err := c.ListModels(context.Background(), "ollama")
+	if err != nil || len(models) != 1 || models[0].ID != "llama3:8b" || models[0].Provider != "ollama" {
+		t.Fatalf("unexpected ollama models: %+v %v", models, err)
+	}
+	models, err = c.ListModels(context.Background(), "openai")
+	if err != nil || len(models) != 2 || models[1].ContextLength != 32768 {
+		t.Fatalf("unexpected openai models: %+v %v", models, err)
+	}
+	if _, err := c.Ping(context.Background(), "openai"); err != nil {
+		t.Fatalf("unexpected ping error: %v", err)
+	}
+	c = New(Config{GeminiAPIBase: srv.URL + "/v1beta", GeminiAPIKey: "key"})
+	models, err = c.ListModels(context.Background(), "gemini")
+	if err != nil || len(models) != 1 || models[0].ID != "gemini-2.5-flash" {
+		t.Fatalf("unexpected gemini models: %+v %v", models, err)
+	}
+	c = New(Config{OpenAIAPIBase: srv.URL + "/v1", OpenAIAPIKey: "wrong"})
+	if _, err := c.Ping(context.Background(), "openai"); err == nil {
+		t.Fatalf("expected ping to fail with bad credentials")
+	}
+}
+
+type echoProvider struct{}
+
+func (echoProvider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	return &chat.Result{Text: "ok"}, nil
+}
+
+type listingProvider struct{ echoProvider }
+
+func (listingProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	return []ModelInfo{{ID: "house-model", Provider: "house"}}, nil
+}
+
+func TestListModelsRegisteredProviders(t *testing.T) {
+	c := New(Config{})
+	c.RegisterProvider("plain", echoProvider{})
+	c.RegisterProvider("house", listingProvider{})
+	for _, provider := range []string{"plain", "perplexity"} {
+		if _, err := c.Ping(context.Background(), provider); !errors.Is(err, ErrModelListUnsupported) {
+			t.Fatalf("%s: expected ErrModelListUnsupported, got %v", provider, err)
+		}
+	}
+	models, err := c.ListModels(context.Background(), "house")
+	if err != nil || len(models) != 1 || models[0].ID != "house-model" {
+		t.Fatalf("unexpected models %+v: %v", models, err)
+	}
+}
@@ -0,0 +1,97 @@
+package uniai
+
+import (
+	"fmt"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// paramLimits describes the sampling options a provider accepts.
+type paramLimits struct {
+	maxTemperature float64
+	penalties      bool
+	maxStop        int
+}
+
+var openAIParamLimits = paramLimits{maxTemperature: 2, penalties: true, maxStop: 4}
+
+var providerParamLimits = map[string]paramLimits{
+	"openai":        openAIParamLimits,
+	"openai_custom": openAIParamLimits,
+	"azure":         openAIParamLimits,
+	"deepseek":      openAIParamLimits,
+	"xai":           openAIParamLimits,
+	"perplexity":    {maxTemperature: 2, penalties: true},
+	"gemini":        {maxTemperature: 2, penalties: true, maxStop: 5},
+	"vllm":          {maxTemperature: 2, penalties: true},
+	"anthropic":     {maxTemperature: 1, penalties: false},
+	"bedrock":       {maxTemperature: 1, penalties: false},
+}
+
+// normalizeParams returns a copy of req with sampling options adapted to the
+// provider, and warnings describing every change. Unknown providers and
+// ParamNormalizationOff leave the request untouched.
+func normalizeParams(providerName string, req *chat.Request) (*chat.Request, []string) {
+	mode := req.Options.ParamNormalization
+	if mode == "" {
+		mode = chat.ParamNormalizationClamp
+	}
+	limits, ok := providerParamLimits[providerName]
+	if !ok || mode == chat.ParamNormalizationOff {
+		return req, nil
+	}
+
+	out := *req
+	opts := &out.Options
+	var warnings []string
+
+	if opts.Temperature != nil {
+		v := *opts.Temperature
+		if mode == chat.ParamNormalizationScale && limits.maxTemperature != 2 {
+			v = v * limits.maxTemperature / 2
+		}
+		v = clampFloat(v, 0, limits.maxTemperature)
+		if v != *opts.Temperature {
+			warnings = append(warnings, fmt.Sprintf("temperature %g adjusted to %g for %s", *opts.Temperature, v, providerName))
+			opts.Temperature = &v
+		}
+	}
+	if opts.TopP != nil {
+		if v := clampFloat(*opts.TopP, 0, 1); v != *opts.TopP {
+			warnings = append(warnings, fmt.Sprintf("top_p %g clamped to %g for %s", *opts.TopP, v, providerName))
+			opts.TopP = &v
+		}
+	}
+	opts.PresencePenalty, warnings = normalizePenalty("presence_penalty", opts.PresencePenalty, limits, providerName, warnings)
+	opts.FrequencyPenalty, warnings = normalizePenalty("frequency_penalty", opts.FrequencyPenalty, limits, providerName, warnings)
+	if limits.maxStop > 0 && len(opts.Stop) > limits.maxStop {
+		warnings = append(warnings, fmt.Sprintf("%d stop sequences truncated to %d for %s", len(opts.Stop), limits.maxStop, providerName))
+		opts.Stop = opts.Stop[:limits.maxStop]
+	}
+	return &out, warnings
+}
+
+func normalizePenalty(name string, value *float64, limits paramLimits, providerName string, warnings []string) (*float64, []string) {
+	if value == nil {
+		return nil, warnings
+	}
+	if !limits.penalties {
+		return nil, append(warnings, fmt.Sprintf("%s is not supported by %s; ignored", name, providerName))
+	}
+	v := clampFloat(*value, -2, 2)
+	if v != *value {
+		warnings = append(warnings, fmt.Sprintf("%s %g clamped to %g for %s", name, *value, v, providerName))
+		return &v, warnings
+	}
+	return value, warnings
+}
+
+func clampFloat(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
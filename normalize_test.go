@@ -0,0 +1,37 @@
+package uniai
+
+import (
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestNormalizeParams(t *testing.T) {
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("hi")),
+		chat.WithTemperature(1.6),
+		chat.WithPresencePenalty(0.5),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, warnings := normalizeParams("anthropic", req)
+	if *out.Options.Temperature != 1 || out.Options.PresencePenalty != nil || len(warnings) != 2 {
+		t.Fatalf("unexpected clamp result: %+v %v", out.Options, warnings)
+	}
+	if *req.Options.Temperature != 1.6 {
+		t.Fatalf("original request must not be modified")
+	}
+
+	req.Options.ParamNormalization = chat.ParamNormalizationScale
+	out, _ = normalizeParams("anthropic", req)
+	if *out.Options.Temperature != 0.8 {
+		t.Fatalf("expected scaled temperature 0.8, got %v", *out.Options.Temperature)
+	}
+
+	out, warnings = normalizeParams("openai", req)
+	if *out.Options.Temperature != 1.6 || len(warnings) != 0 {
+		t.Fatalf("openai options should pass through: %+v %v", out.Options, warnings)
+	}
+}
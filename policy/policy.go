@@ -0,0 +1,295 @@
+// Package policy evaluates declarative routing rules against chat requests
+// before they are sent. A policy is usually loaded from JSON:
+//
+//	{"rules": [
+//	  {"name": "no-tools-for-trial", "match": {"tenants": ["trial-*"], "has_tools": true}, "action": "deny"},
+//	  {"name": "vision", "match": {"has_images": true}, "action": "route", "provider": "openai", "model": "gpt-4o"},
+//	  {"name": "big-prompts", "match": {"min_tokens": 100000}, "action": "route", "provider": "gemini", "model": "gemini-2.5-pro"},
+//	  {"name": "fast", "match": {"models": ["fast"]}, "action": "route", "provider": "openai", "model": "gpt-5-mini"}
+//	]}
+//
+// Rules are tried in order and the first match applies.
+//
+// With a Budget, rules can degrade requests as a tenant's budget runs out
+// instead of failing them outright:
+//
+//	{"rules": [
+//	  {"name": "over-budget", "match": {"budget_used": 1}, "action": "deny"},
+//	  {"name": "nearly-out", "match": {"budget_used": 0.9}, "action": "downgrade", "max_tokens": 512, "disable_tools": true},
+//	  {"name": "running-low", "match": {"budget_used": 0.75}, "action": "downgrade", "model": "cheap"}
+//	]}
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/tokens"
+	"github.com/quailyquaily/uniai/usage"
+)
+
+// Rule actions.
+const (
+	ActionRoute           = "route"
+	ActionDeny            = "deny"
+	ActionDowngrade       = "downgrade"
+	ActionRequireApproval = "require_approval"
+)
+
+var (
+	// ErrDenied is returned (wrapped) for requests matching a deny rule.
+	ErrDenied = errors.New("request denied by policy")
+	// ErrNotApproved is returned (wrapped) when a require_approval rule
+	// matches and the request is not approved.
+	ErrNotApproved = errors.New("request not approved")
+	// ErrBudgetExceeded is returned, wrapped together with ErrDenied, by
+	// deny rules with a budget_used condition.
+	ErrBudgetExceeded = errors.New("budget exceeded")
+)
+
+// Policy is an ordered list of rules.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+	// Approve decides require_approval rules. Without it such requests
+	// are rejected.
+	Approve func(ctx context.Context, req *chat.Request, rule Rule) (bool, error) `json:"-"`
+	// Budget provides the spending that budget_used conditions compare
+	// against. A client with this policy records the usage of its calls in
+	// it, so it should not also be the client's UsageRecorder.
+	Budget *usage.Budget `json:"-"`
+}
+
+// Rule applies Action to requests matching Match. Provider and Model are
+// the destination of route and downgrade rules; empty fields keep the
+// request's value. Downgrade rules may also lower max_tokens to MaxTokens
+// and drop the tools of the request.
+type Rule struct {
+	Name         string `json:"name"`
+	Match        Match  `json:"match"`
+	Action       string `json:"action"`
+	Provider     string `json:"provider,omitempty"`
+	Model        string `json:"model,omitempty"`
+	MaxTokens    int    `json:"max_tokens,omitempty"`
+	DisableTools bool   `json:"disable_tools,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// Match conditions are combined with AND; empty conditions match every
+// request. Model, provider and tenant patterns may end in "*" to match by
+// prefix.
+type Match struct {
+	// Models match the requested model name, which may be an alias that a
+	// route rule resolves.
+	Models    []string `json:"models,omitempty"`
+	Providers []string `json:"providers,omitempty"`
+	Tenants   []string `json:"tenants,omitempty"`
+	// MinTokens and MaxTokens bound the prompt size, counted with the
+	// tokens package (estimated for models without an encoder).
+	MinTokens int   `json:"min_tokens,omitempty"`
+	MaxTokens int   `json:"max_tokens,omitempty"`
+	HasTools  *bool `json:"has_tools,omitempty"`
+	// HasImages matches requests with or without images in their
+	// messages.
+	HasImages *bool `json:"has_images,omitempty"`
+	// BudgetUsed matches once the tenant has spent at least this fraction
+	// of its budget, e.g. 0.8; 1 means the budget is exhausted. It never
+	// matches without a Policy.Budget.
+	BudgetUsed float64 `json:"budget_used,omitempty"`
+}
+
+// Decision is the outcome of Evaluate.
+type Decision struct {
+	// Rule is the rule that matched, or nil.
+	Rule     *Rule
+	Provider string
+	Model    string
+	// MaxTokens, if positive, caps the request's max_tokens.
+	MaxTokens int
+	// DisableTools asks for the request to be sent without tools.
+	DisableTools bool
+	// Warning describes a downgrade, for chat.Result.Warnings.
+	Warning string
+}
+
+// Load reads a JSON policy file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a JSON policy.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate checks that every rule has a known action, that route rules
+// name a destination and that downgrade rules change something.
+func (p *Policy) Validate() error {
+	for i, r := range p.Rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		switch r.Action {
+		case ActionDeny, ActionRequireApproval:
+		case ActionRoute:
+			if r.Provider == "" && r.Model == "" {
+				return fmt.Errorf("policy rule %s: %s needs a provider or model", name, r.Action)
+			}
+		case ActionDowngrade:
+			if r.Provider == "" && r.Model == "" && r.MaxTokens <= 0 && !r.DisableTools {
+				return fmt.Errorf("policy rule %s: %s needs a provider, model, max_tokens or disable_tools", name, r.Action)
+			}
+		default:
+			return fmt.Errorf("policy rule %s: unknown action %q", name, r.Action)
+		}
+	}
+	return nil
+}
+
+// Evaluate applies the first rule matching a request bound for provider.
+// The tenant is read from usage.TenantFrom(ctx). Deny rules and
+// unapproved require_approval rules return an error.
+func (p *Policy) Evaluate(ctx context.Context, provider string, req *chat.Request) (Decision, error) {
+	d := Decision{Provider: provider, Model: req.Model}
+	if p == nil {
+		return d, nil
+	}
+	tenant := usage.TenantFrom(ctx)
+	promptTokens := -1
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		m := rule.Match
+		if !matchAny(m.Models, req.Model) || !matchAny(m.Providers, provider) || !matchAny(m.Tenants, tenant) {
+			continue
+		}
+		if m.HasTools != nil && *m.HasTools != (len(req.Tools) > 0) {
+			continue
+		}
+		if m.HasImages != nil && *m.HasImages != hasImages(req) {
+			continue
+		}
+		if m.BudgetUsed > 0 && (p.Budget == nil || p.Budget.Used(ctx) < m.BudgetUsed) {
+			continue
+		}
+		if m.MinTokens > 0 || m.MaxTokens > 0 {
+			if promptTokens < 0 {
+				promptTokens = countTokens(req)
+			}
+			if promptTokens < m.MinTokens || (m.MaxTokens > 0 && promptTokens > m.MaxTokens) {
+				continue
+			}
+		}
+		d.Rule = rule
+		return d, p.apply(ctx, req, rule, &d)
+	}
+	return d, nil
+}
+
+func hasImages(req *chat.Request) bool {
+	for _, m := range req.Messages {
+		if len(m.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) apply(ctx context.Context, req *chat.Request, rule *Rule, d *Decision) error {
+	switch rule.Action {
+	case ActionDeny:
+		if rule.Match.BudgetUsed > 0 {
+			return fmt.Errorf("%w: %w: %s", ErrDenied, ErrBudgetExceeded, ruleReason(rule))
+		}
+		return fmt.Errorf("%w: %s", ErrDenied, ruleReason(rule))
+	case ActionRequireApproval:
+		if p.Approve == nil {
+			return fmt.Errorf("%w: %s", ErrNotApproved, ruleReason(rule))
+		}
+		ok, err := p.Approve(ctx, req, *rule)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrNotApproved, ruleReason(rule))
+		}
+	case ActionRoute, ActionDowngrade:
+		from := d.Model
+		if rule.Provider != "" {
+			d.Provider = rule.Provider
+		}
+		if rule.Model != "" {
+			d.Model = rule.Model
+		}
+		if rule.Action == ActionDowngrade {
+			var changes []string
+			if rule.Provider != "" || rule.Model != "" {
+				changes = append(changes, fmt.Sprintf("downgraded %s to %s", from, d.Model))
+			}
+			if rule.MaxTokens > 0 && (req.Options.MaxTokens == nil || *req.Options.MaxTokens > rule.MaxTokens) {
+				d.MaxTokens = rule.MaxTokens
+				changes = append(changes, fmt.Sprintf("capped max_tokens at %d", rule.MaxTokens))
+			}
+			if rule.DisableTools && len(req.Tools) > 0 {
+				d.DisableTools = true
+				changes = append(changes, "disabled tools")
+			}
+			if len(changes) > 0 {
+				d.Warning = fmt.Sprintf("policy %s: %s", rule.Name, strings.Join(changes, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func ruleReason(rule *Rule) string {
+	if rule.Reason != "" {
+		return rule.Reason
+	}
+	return "rule " + rule.Name
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(value, prefix) {
+			return true
+		}
+		if pattern == value {
+			return true
+		}
+	}
+	return false
+}
+
+func countTokens(req *chat.Request) int {
+	total := 0
+	for _, m := range req.Messages {
+		n, _, err := tokens.Count(req.Model, m.Content)
+		if err != nil {
+			n = tokens.Estimate(m.Content)
+		}
+		total += n
+	}
+	return total
+}
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/usage"
+)
+
+const testPolicy = `{"rules": [
+  {"name": "trial-tools", "match": {"tenants": ["trial-*"], "has_tools": true}, "action": "deny", "reason": "tools need a paid plan"},
+  {"name": "vision", "match": {"has_images": true}, "action": "route", "provider": "openai", "model": "gpt-4o"},
+  {"name": "huge", "match": {"min_tokens": 1000}, "action": "downgrade", "model": "small"},
+  {"name": "fast", "match": {"models": ["fast"]}, "action": "route", "provider": "openai", "model": "gpt-5-mini"},
+  {"name": "review", "match": {"providers": ["anthropic"]}, "action": "require_approval"}
+]}`
+
+func TestEvaluate(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	ctx := context.Background()
+	req := func(model string, text string) *chat.Request {
+		return &chat.Request{Model: model, Messages: []chat.Message{chat.User(text)}}
+	}
+
+	d, err := p.Evaluate(ctx, "azure", req("fast", "hi"))
+	if err != nil || d.Provider != "openai" || d.Model != "gpt-5-mini" || d.Warning != "" {
+		t.Fatalf("route: %+v, %v", d, err)
+	}
+
+	d, err = p.Evaluate(ctx, "openai", req("big", strings.Repeat("word ", 2000)))
+	if err != nil || d.Model != "small" || d.Warning == "" {
+		t.Fatalf("downgrade: %+v, %v", d, err)
+	}
+
+	withImage := req("deepseek-chat", "what is this?")
+	withImage.Messages[0].Images = []chat.Image{{URL: "https://example.com/cat.png"}}
+	d, err = p.Evaluate(ctx, "deepseek", withImage)
+	if err != nil || d.Rule == nil || d.Rule.Name != "vision" || d.Provider != "openai" {
+		t.Fatalf("image route: %+v, %v", d, err)
+	}
+
+	withTools := req("gpt", "hi")
+	withTools.Tools = []chat.Tool{chat.FunctionTool("f", "", nil)}
+	if _, err := p.Evaluate(usage.WithTenant(ctx, "trial-42"), "openai", withTools); !errors.Is(err, ErrDenied) {
+		t.Fatalf("expected ErrDenied, got %v", err)
+	}
+	if _, err := p.Evaluate(usage.WithTenant(ctx, "acme"), "openai", withTools); err != nil {
+		t.Fatalf("paid tenant denied: %v", err)
+	}
+
+	if _, err := p.Evaluate(ctx, "anthropic", req("claude", "hi")); !errors.Is(err, ErrNotApproved) {
+		t.Fatalf("expected ErrNotApproved, got %v", err)
+	}
+	p.Approve = func(ctx context.Context, req *chat.Request, rule Rule) (bool, error) { return true, nil }
+	if _, err := p.Evaluate(ctx, "anthropic", req("claude", "hi")); err != nil {
+		t.Fatalf("approved request rejected: %v", err)
+	}
+}
+
+func TestParseRejectsUnknownAction(t *testing.T) {
+	if _, err := Parse([]byte(`{"rules":[{"name":"x","action":"explode"}]}`)); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestBudgetDegradation(t *testing.T) {
+	p, err := Parse([]byte(`{"rules": [
+  {"name": "over", "match": {"budget_used": 1}, "action": "deny"},
+  {"name": "low", "match": {"budget_used": 0.5}, "action": "downgrade", "model": "cheap", "max_tokens": 100, "disable_tools": true}
+]}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	p.Budget = usage.NewBudget(usage.Limit{Tokens: 1000}, nil)
+	ctx := usage.WithTenant(context.Background(), "acme")
+	req := &chat.Request{Model: "smart", Messages: []chat.Message{chat.User("hi")}, Tools: []chat.Tool{chat.FunctionTool("f", "", nil)}}
+
+	d, err := p.Evaluate(ctx, "openai", req)
+	if err != nil || d.Rule != nil || d.Model != "smart" {
+		t.Fatalf("fresh budget: %+v, %v", d, err)
+	}
+
+	p.Budget.RecordUsage(ctx, "openai", "smart", chat.Usage{TotalTokens: 600})
+	d, err = p.Evaluate(ctx, "openai", req)
+	if err != nil || d.Model != "cheap" || d.MaxTokens != 100 || !d.DisableTools {
+		t.Fatalf("degraded: %+v, %v", d, err)
+	}
+	if !strings.Contains(d.Warning, "capped max_tokens at 100") || !strings.Contains(d.Warning, "disabled tools") {
+		t.Fatalf("warning: %q", d.Warning)
+	}
+	if _, err := p.Evaluate(usage.WithTenant(context.Background(), "other"), "openai", req); err != nil {
+		t.Fatalf("other tenant affected: %v", err)
+	}
+
+	p.Budget.RecordUsage(ctx, "openai", "cheap", chat.Usage{TotalTokens: 400})
+	_, err = p.Evaluate(ctx, "openai", req)
+	if !errors.Is(err, ErrBudgetExceeded) || !errors.Is(err, ErrDenied) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package uniai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// RegisterPostProcessor makes p selectable by name with
+// chat.WithPostProcessors, taking precedence over a built-in processor of
+// the same name. A nil p removes it.
+func (c *Client) RegisterPostProcessor(name string, p chat.PostProcessor) {
+	c.postMu.Lock()
+	defer c.postMu.Unlock()
+	if p == nil {
+		delete(c.postProcessors, name)
+		return
+	}
+	if c.postProcessors == nil {
+		c.postProcessors = map[string]chat.PostProcessor{}
+	}
+	c.postProcessors[name] = p
+}
+
+type boundPostProcessor struct {
+	name string
+	arg  string
+	fn   chat.PostProcessor
+}
+
+// resolvePostProcessors resolves the requested names, so unknown names fail the
+// request before the provider is called.
+func (c *Client) resolvePostProcessors(names []string) ([]boundPostProcessor, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	c.postMu.RLock()
+	defer c.postMu.RUnlock()
+	out := make([]boundPostProcessor, 0, len(names))
+	for _, full := range names {
+		name, arg, _ := strings.Cut(full, ":")
+		fn := c.postProcessors[name]
+		if fn == nil {
+			fn = chat.BuiltinPostProcessors[name]
+		}
+		if fn == nil {
+			return nil, fmt.Errorf("unknown post-processor %q", name)
+		}
+		out = append(out, boundPostProcessor{name: name, arg: arg, fn: fn})
+	}
+	return out, nil
+}
+
+// applyPostProcessors rewrites resp.Text, and the assistant message that
+// carries it.
+func applyPostProcessors(resp *chat.Result, processors []boundPostProcessor) error {
+	if len(processors) == 0 {
+		return nil
+	}
+	text := resp.Text
+	for _, p := range processors {
+		out, err := p.fn(text, p.arg)
+		if err != nil {
+			return fmt.Errorf("post-processor %s: %w", p.name, err)
+		}
+		text = out
+	}
+	for i := len(resp.Messages) - 1; i >= 0; i-- {
+		if m := &resp.Messages[i]; m.Role == chat.RoleAssistant && m.Content == resp.Text {
+			m.Content = text
+			break
+		}
+	}
+	resp.Text = text
+	return nil
+}
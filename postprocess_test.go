@@ -0,0 +1,39 @@
+package uniai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestPostProcessors(t *testing.T) {
+	client := New(Config{})
+	reply := "```markdown\n## Answer\n\nThe **total** is   4210 euros.  It was paid in [March](https://example.com). Thanks!\n```"
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{fake.Text(reply, 0, 0)}}))
+	client.RegisterPostProcessor("shout", func(text, _ string) (string, error) {
+		return strings.ToUpper(text), nil
+	})
+	res, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User("total?")),
+		WithPostProcessors(chat.PostPlainText, "max_sentences:3", "shout"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ANSWER\n\nTHE TOTAL IS 4210 EUROS. IT WAS PAID IN MARCH."
+	if res.Text != want {
+		t.Fatalf("unexpected text %q", res.Text)
+	}
+	if last := res.Messages[len(res.Messages)-1]; last.Content != want {
+		t.Fatalf("assistant message not rewritten: %+v", res.Messages)
+	}
+
+	_, err = client.Chat(context.Background(), WithProvider("fake"), WithMessages(User("hi")), WithPostProcessors("nope"))
+	if err == nil || !strings.Contains(err.Error(), "unknown post-processor") {
+		t.Fatalf("expected unknown post-processor error, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package uniai
+
+import (
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+const prefillPrompt = "Begin your reply with exactly the following text, then continue it. Do not add anything before it:\n"
+
+// prefillNative reports whether the provider continues a trailing assistant
+// turn itself.
+func prefillNative(providerName string) bool {
+	return providerName == "anthropic"
+}
+
+// applyPrefill emulates req.Options.Prefill on providers without native
+// support. It asks the model to start its reply with the prefill and
+// returns a function that makes the reply start with it, whether or not the
+// model repeated it. Streamed deltas are corrected the same way.
+func applyPrefill(providerName string, req *chat.Request) (*chat.Request, func(*chat.Result)) {
+	prefill := req.Options.Prefill
+	if prefill == "" || prefillNative(providerName) {
+		return req, func(*chat.Result) {}
+	}
+	out := *req
+	out.Messages = append(append([]chat.Message{}, req.Messages...), chat.System(prefillPrompt+prefill))
+	if onStream := req.Options.OnStream; onStream != nil {
+		s := &prefillStream{prefill: prefill, next: onStream}
+		out.Options.OnStream = s.onStream
+	}
+	return &out, func(resp *chat.Result) {
+		if resp.Text == "" && len(resp.ToolCalls) > 0 {
+			return
+		}
+		setReplyText(resp, withPrefill(prefill, resp.Text))
+	}
+}
+
+// setReplyText replaces the reply text in resp and in the assistant turn of
+// resp.Messages, keeping the turn's other fields.
+func setReplyText(resp *chat.Result, text string) {
+	resp.Text = text
+	for i := len(resp.Messages) - 1; i >= 0; i-- {
+		if resp.Messages[i].Role == chat.RoleAssistant {
+			resp.Messages[i].Content = text
+			break
+		}
+	}
+}
+
+// withPrefill returns text starting with prefill. A copy of the prefill the
+// model already wrote is dropped along with any whitespace before it, as is
+// a reply that is only the start of one.
+func withPrefill(prefill, text string) string {
+	head := strings.TrimLeft(text, " \t\r\n")
+	if strings.HasPrefix(head, prefill) {
+		return head
+	}
+	if strings.HasPrefix(prefill, head) {
+		return prefill
+	}
+	return prefill + text
+}
+
+// prefillStream holds back the first deltas until it can tell whether the
+// model is repeating the prefill, then forwards the reply starting with it.
+type prefillStream struct {
+	prefill string
+	next    chat.OnStreamFunc
+	pending string
+	decided bool
+}
+
+func (s *prefillStream) onStream(ev chat.StreamEvent) error {
+	if s.decided {
+		return s.next(ev)
+	}
+	if ev.ReasoningDelta != "" {
+		// reasoning comes before the reply and needs no prefill
+		if err := s.next(chat.StreamEvent{ReasoningDelta: ev.ReasoningDelta}); err != nil {
+			return err
+		}
+		if ev.ReasoningDelta = ""; ev == (chat.StreamEvent{}) {
+			return nil
+		}
+	}
+	s.pending += ev.Delta
+	head := strings.TrimLeft(s.pending, " \t\r\n")
+	textOnly := ev.ToolCallDelta == nil && ev.ToolCall == nil && ev.Usage == nil && !ev.Done
+	if textOnly && len(head) < len(s.prefill) && strings.HasPrefix(s.prefill, head) {
+		return nil
+	}
+	s.decided = true
+	if s.pending == "" && (ev.ToolCallDelta != nil || ev.ToolCall != nil) {
+		// a reply of only tool calls has no text to prefill
+		return s.next(ev)
+	}
+	if err := s.next(chat.StreamEvent{Delta: withPrefill(s.prefill, s.pending)}); err != nil {
+		return err
+	}
+	if textOnly {
+		return nil
+	}
+	ev.Delta = ""
+	return s.next(ev)
+}
@@ -0,0 +1,36 @@
+package uniai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestPrefillEmulation(t *testing.T) {
+	for _, reply := range []string{`{"a":1}`, `a":1}`, ` {"a":1}`} {
+		p := fake.New(fake.Config{Responses: []fake.Response{fake.Text(reply, 1, 0)}})
+		client := New(Config{})
+		client.RegisterProvider("fake", p)
+
+		var streamed strings.Builder
+		resp, err := client.Chat(context.Background(),
+			WithProvider("fake"),
+			WithMessages(User("give me json")),
+			WithPrefill(`{"`),
+			WithOnToken(func(delta string) { streamed.WriteString(delta) }),
+		)
+		if err != nil {
+			t.Fatalf("chat: %v", err)
+		}
+		if resp.Text != `{"a":1}` || streamed.String() != `{"a":1}` {
+			t.Fatalf("reply %q: got %q, streamed %q", reply, resp.Text, streamed.String())
+		}
+		msgs := p.Requests()[0].Messages
+		if last := msgs[len(msgs)-1]; last.Role != chat.RoleSystem || !strings.HasSuffix(last.Content, `{"`) {
+			t.Fatalf("prefill instruction missing: %+v", msgs)
+		}
+	}
+}
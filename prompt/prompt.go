@@ -0,0 +1,199 @@
+// Package prompt renders prompts from text/template templates whose
+// variables are either passed by the caller or resolved at render time by
+// functions registered once, such as the current date, a profile lookup or
+// feature flags:
+//
+//	reg := prompt.NewRegistry()
+//	reg.Register("date", prompt.Date("Monday, 2 January 2006"))
+//	reg.Register("user", func(ctx context.Context) (any, error) { return profiles.Get(ctx, userID(ctx)) })
+//	tmpl, err := reg.Parse("support", "Today is {{.date}}. You are helping {{.user.Name}} with {{.topic}}.")
+//	text, err := tmpl.Render(ctx, map[string]any{"topic": "billing"})
+//
+// A resolver runs only when the template refers to its variable and the
+// caller did not pass it, at most once per render. Referring to a variable
+// that is neither passed nor registered, or to a missing map key, is an
+// error; use {{index .flags "beta"}} for optional keys.
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Resolver returns the value of a variable at render time. Request-scoped
+// inputs, such as the user ID, come from ctx.
+type Resolver func(ctx context.Context) (any, error)
+
+// Registry holds resolvers by variable name. It is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	resolvers map[string]Resolver
+}
+
+func NewRegistry() *Registry {
+	return &Registry{resolvers: map[string]Resolver{}}
+}
+
+// Register sets the resolver of a variable, replacing any previous one.
+// Templates parsed earlier see the change.
+func (r *Registry) Register(name string, fn Resolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resolvers[name] = fn
+}
+
+func (r *Registry) resolver(name string) Resolver {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolvers[name]
+}
+
+// Template is a parsed prompt.
+type Template struct {
+	tmpl *template.Template
+	reg  *Registry
+	// vars are the top-level variables the template refers to.
+	vars []string
+}
+
+// Parse parses a template using the resolvers of r.
+func (r *Registry) Parse(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt %s: %w", name, err)
+	}
+	seen := map[string]bool{}
+	var vars []string
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		walk(t.Tree.Root, func(name string) {
+			if !seen[name] {
+				seen[name] = true
+				vars = append(vars, name)
+			}
+		})
+	}
+	return &Template{tmpl: tmpl, reg: r, vars: vars}, nil
+}
+
+// Parse parses a template without resolvers; every variable must be passed
+// to Render.
+func Parse(name, text string) (*Template, error) {
+	return (*Registry)(nil).Parse(name, text)
+}
+
+// Render executes the template with vars, resolving the variables it
+// refers to that vars lacks.
+func (t *Template) Render(ctx context.Context, vars map[string]any) (string, error) {
+	data := make(map[string]any, len(vars)+len(t.vars))
+	for k, v := range vars {
+		data[k] = v
+	}
+	for _, name := range t.vars {
+		if _, ok := data[name]; ok {
+			continue
+		}
+		fn := t.reg.resolver(name)
+		if fn == nil {
+			continue
+		}
+		v, err := fn(ctx)
+		if err != nil {
+			return "", fmt.Errorf("prompt %s: resolve %s: %w", t.tmpl.Name(), name, err)
+		}
+		data[name] = v
+	}
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render prompt %s: %w", t.tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// Message renders the template into a message with the given role, e.g.
+// chat.RoleSystem.
+func (t *Template) Message(ctx context.Context, role string, vars map[string]any) (chat.Message, error) {
+	text, err := t.Render(ctx, vars)
+	if err != nil {
+		return chat.Message{}, err
+	}
+	return chat.Message{Role: role, Content: text}, nil
+}
+
+// Date returns a resolver for the current local time formatted with layout.
+func Date(layout string) Resolver {
+	return func(ctx context.Context) (any, error) {
+		return time.Now().Format(layout), nil
+	}
+}
+
+// Value returns a resolver for a value stored in ctx under key, such as a
+// locale set by middleware.
+func Value(key any) Resolver {
+	return func(ctx context.Context) (any, error) {
+		return ctx.Value(key), nil
+	}
+}
+
+// walk calls fn with the first field of every .field and $.field reference
+// in node. Inside range and with, dot is rebound, so this may name fields
+// that are not top-level variables; those are only resolved when a
+// resolver of that name exists.
+func walk(node parse.Node, fn func(string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walk(c, fn)
+		}
+	case *parse.ActionNode:
+		walk(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walk(c, fn)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			walk(a, fn)
+		}
+	case *parse.FieldNode:
+		fn(n.Ident[0])
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			fn(n.Ident[1])
+		}
+	case *parse.ChainNode:
+		walk(n.Node, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walk(n.Pipe, fn)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(string)) {
+	walk(n.Pipe, fn)
+	walk(n.List, fn)
+	walk(n.ElseList, fn)
+}
@@ -0,0 +1,51 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderResolvesReferencedVariables(t *testing.T) {
+	type user struct{ Name string }
+	calls := map[string]int{}
+	reg := NewRegistry()
+	reg.Register("user", func(ctx context.Context) (any, error) {
+		calls["user"]++
+		return user{Name: "Ada"}, nil
+	})
+	reg.Register("flags", func(ctx context.Context) (any, error) {
+		calls["flags"]++
+		return map[string]bool{"beta": true}, nil
+	})
+	reg.Register("unused", func(ctx context.Context) (any, error) {
+		calls["unused"]++
+		return nil, errors.New("should not run")
+	})
+
+	tmpl, err := reg.Parse("support", "Hi {{.user.Name}}{{if .flags.beta}} (beta){{end}}, about {{.topic}}. {{.user.Name}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := tmpl.Render(context.Background(), map[string]any{"topic": "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Hi Ada (beta), about billing. Ada" {
+		t.Fatalf("got %q", text)
+	}
+	if calls["user"] != 1 || calls["flags"] != 1 || calls["unused"] != 0 {
+		t.Fatalf("resolver calls: %v", calls)
+	}
+
+	// passed variables win over resolvers
+	text, err = tmpl.Render(context.Background(), map[string]any{"topic": "x", "user": user{Name: "Bob"}, "flags": map[string]bool{"beta": false}})
+	if err != nil || text != "Hi Bob, about x. Bob" {
+		t.Fatalf("got %q, %v", text, err)
+	}
+
+	if _, err := tmpl.Render(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "topic") {
+		t.Fatalf("missing variable: %v", err)
+	}
+}
@@ -2,7 +2,6 @@ package anthropic
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,6 +9,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/service/bedrock/bedrockiface"
+	"github.com/aws/aws-sdk-go/service/bedrockruntime/bedrockruntimeiface"
 	"github.com/lyricat/goutils/structs"
 	"github.com/quailyquaily/uniai/chat"
 	"github.com/quailyquaily/uniai/internal/diag"
@@ -20,14 +21,41 @@ type Config struct {
 	APIKey       string
 	DefaultModel string
 	Debug        bool
+
+	// Backend selects the transport used to reach Claude models:
+	// BackendAnthropic (default), BackendVertex or BackendBedrock.
+	Backend string
+
+	// Vertex AI backend
+	VertexProjectID   string
+	VertexRegion      string
+	VertexAccessToken string
+
+	// AWS Bedrock backend
+	AwsKey    string
+	AwsSecret string
+	AwsRegion string
 }
 
 type Provider struct {
-	cfg Config
+	cfg     Config
+	bedrock bedrockruntimeiface.BedrockRuntimeAPI
+	// bedrockModels lists the foundation models on the Bedrock backend.
+	bedrockModels bedrockiface.BedrockAPI
 }
 
-func New(cfg Config) *Provider {
-	return &Provider{cfg: cfg}
+// New returns a provider for cfg. It fails when the Bedrock backend is
+// selected and no AWS session can be created from cfg.
+func New(cfg Config) (*Provider, error) {
+	p := &Provider{cfg: cfg}
+	if p.backend() == BackendBedrock {
+		runtime, models, err := newBedrockClients(cfg)
+		if err != nil {
+			return nil, err
+		}
+		p.bedrock, p.bedrockModels = runtime, models
+	}
+	return p, nil
 }
 
 type anthropicMessage struct {
@@ -44,31 +72,102 @@ type anthropicContentPart struct {
 	ToolUseID string `json:"tool_use_id,omitempty"`
 	Content   any    `json:"content,omitempty"`
 	IsError   *bool  `json:"is_error,omitempty"`
+	// FileID is set on container_upload blocks.
+	FileID string `json:"file_id,omitempty"`
+	// Source is the content of image blocks.
+	Source *anthropicImageSource `json:"source,omitempty"`
+
+	// Thinking is the text of a thinking block in responses.
+	Thinking string `json:"thinking,omitempty"`
+	// Citations are the web search sources of a text block in responses.
+	Citations []anthropicCitation `json:"citations,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// imagePart converts img into an image block, from a base64 source for
+// data URLs and a url source otherwise.
+func imagePart(img chat.Image) anthropicContentPart {
+	if mediaType, data, ok := img.Data(); ok {
+		return anthropicContentPart{Type: "image", Source: &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}}
+	}
+	return anthropicContentPart{Type: "image", Source: &anthropicImageSource{Type: "url", URL: img.URL}}
+}
+
+type anthropicCitation struct {
+	Type      string `json:"type"`
+	URL       string `json:"url,omitempty"`
+	Title     string `json:"title,omitempty"`
+	CitedText string `json:"cited_text,omitempty"`
 }
 
 type anthropicRequest struct {
-	Model         string               `json:"model"`
-	System        string               `json:"system,omitempty"`
-	Messages      []anthropicMessage   `json:"messages"`
-	MaxTokens     int                  `json:"max_tokens"`
-	Temperature   *float64             `json:"temperature,omitempty"`
-	TopP          *float64             `json:"top_p,omitempty"`
-	TopK          *int                 `json:"top_k,omitempty"`
-	StopSequences []string             `json:"stop_sequences,omitempty"`
-	Metadata      *anthropicMetadata   `json:"metadata,omitempty"`
-	Tools         []anthropicTool      `json:"tools,omitempty"`
-	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
-	Stream        bool                 `json:"stream,omitempty"`
-}
-
-type anthropicResponse struct {
+	Model            string               `json:"model,omitempty"`
+	AnthropicVersion string               `json:"anthropic_version,omitempty"`
+	System           any                  `json:"system,omitempty"`
+	Messages         []anthropicMessage   `json:"messages"`
+	MaxTokens        int                  `json:"max_tokens"`
+	Temperature      *float64             `json:"temperature,omitempty"`
+	TopP             *float64             `json:"top_p,omitempty"`
+	TopK             *int                 `json:"top_k,omitempty"`
+	StopSequences    []string             `json:"stop_sequences,omitempty"`
+	Metadata         *anthropicMetadata   `json:"metadata,omitempty"`
+	Tools            []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice       *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Thinking         any                  `json:"thinking,omitempty"`
+	Stream           bool                 `json:"stream,omitempty"`
+	// AnthropicBeta carries the beta features on Bedrock, which has no
+	// anthropic-beta header.
+	AnthropicBeta []string `json:"anthropic_beta,omitempty"`
+}
+
+type anthropicSystemBlock struct {
+	Type         string `json:"type"`
+	Text         string `json:"text"`
+	CacheControl any    `json:"cache_control,omitempty"`
+}
+
+// Response is the Messages API response, available as chat.Result.Raw via
+// RawResponse.
+type Response struct {
 	Content    []anthropicContentPart `json:"content"`
 	Model      string                 `json:"model"`
 	StopReason string                 `json:"stop_reason,omitempty"`
-	Usage      struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	Usage      anthropicUsage         `json:"usage"`
+}
+
+// anthropicUsage is the usage of responses and stream events. Its
+// input_tokens leaves out the tokens read from or written to the prompt
+// cache, which chat.Usage counts as input like the other providers.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+func (u anthropicUsage) input() int {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// merge takes the counts of a message_delta. Output tokens are always
+// reported; each input count only when it changed.
+func (u *anthropicUsage) merge(d anthropicUsage) {
+	u.OutputTokens = d.OutputTokens
+	if d.InputTokens > 0 {
+		u.InputTokens = d.InputTokens
+	}
+	if d.CacheCreationInputTokens > 0 {
+		u.CacheCreationInputTokens = d.CacheCreationInputTokens
+	}
+	if d.CacheReadInputTokens > 0 {
+		u.CacheReadInputTokens = d.CacheReadInputTokens
+	}
 }
 
 type anthropicMetadata struct {
@@ -76,9 +175,26 @@ type anthropicMetadata struct {
 }
 
 type anthropicTool struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	InputSchema any    `json:"input_schema"`
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	InputSchema  any    `json:"input_schema,omitempty"`
+	CacheControl any    `json:"cache_control,omitempty"`
+
+	// Type, MaxUses and AllowedDomains describe server tools such as web
+	// search; client tools leave Type empty.
+	Type           string   `json:"type,omitempty"`
+	MaxUses        int      `json:"max_uses,omitempty"`
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+}
+
+// webSearchTool is the server tool that lets Claude search the web.
+func webSearchTool(ws *chat.WebSearch) anthropicTool {
+	return anthropicTool{
+		Type:           "web_search_20250305",
+		Name:           "web_search",
+		MaxUses:        ws.MaxResults,
+		AllowedDomains: ws.AllowedDomains,
+	}
 }
 
 type anthropicToolChoice struct {
@@ -87,10 +203,54 @@ type anthropicToolChoice struct {
 	DisableParallelToolUse *bool  `json:"disable_parallel_tool_use,omitempty"`
 }
 
+// Chat sends req to the Messages API. A prefill is sent as a trailing
+// assistant turn and prepended to the reply, and to the stream as its first
+// delta.
 func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	prefill := prefillText(req)
+	if prefill == "" {
+		return p.chat(ctx, req)
+	}
+	if onStream := req.Options.OnStream; onStream != nil {
+		r := *req
+		sent := false
+		r.Options.OnStream = func(ev chat.StreamEvent) error {
+			if !sent {
+				sent = true
+				if err := onStream(chat.StreamEvent{Delta: prefill}); err != nil {
+					return err
+				}
+			}
+			return onStream(ev)
+		}
+		req = &r
+	}
+	res, err := p.chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	res.Text = prefill + res.Text
+	res.Messages = chat.AssistantTurn(res.Text, res.ToolCalls)
+	for _, c := range res.Citations {
+		if c.Span != nil {
+			c.Span.Start += len(prefill)
+			c.Span.End += len(prefill)
+		}
+	}
+	return res, nil
+}
+
+// prefillText returns the prefill of req without trailing whitespace, which
+// the API rejects in a final assistant turn.
+func prefillText(req *chat.Request) string {
+	return strings.TrimRight(req.Options.Prefill, " \t\r\n")
+}
+
+func (p *Provider) chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
 	debugFn := req.Options.DebugFn
-	if p.cfg.APIKey == "" {
-		return nil, fmt.Errorf("anthropic api key is required")
+	debug := p.cfg.Debug && !req.Options.NoStore
+	if err := p.validate(); err != nil {
+		return nil, err
 	}
 	model := req.Model
 	if model == "" {
@@ -100,6 +260,86 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 		return nil, fmt.Errorf("model is required")
 	}
 
+	body, err := buildRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	betas := betas(req)
+	switch p.backend() {
+	case BackendVertex:
+		body.AnthropicVersion = vertexAnthropicVersion
+	case BackendBedrock:
+		body.AnthropicVersion = bedrockAnthropicVersion
+		body.AnthropicBeta = betas
+	default:
+		body.Model = model
+	}
+	// Bedrock selects streaming by API rather than by a body field.
+	if req.Options.OnStream != nil && p.backend() != BackendBedrock {
+		body.Stream = true
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	diag.LogText(debug, debugFn, "anthropic.chat.request", string(data))
+
+	if p.backend() == BackendBedrock {
+		if req.Options.OnStream != nil {
+			return p.chatStreamBedrock(ctx, model, data, req.Options.OnStream)
+		}
+		respData, err := p.invokeBedrock(ctx, model, data)
+		if err != nil {
+			return nil, err
+		}
+		diag.LogText(debug, debugFn, "anthropic.chat.response", string(respData))
+		return parseResponse(respData)
+	}
+
+	httpReq, err := p.newHTTPRequest(ctx, model, data, body.Stream, betas)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httputil.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if req.Options.OnStream != nil {
+		if resp.StatusCode != http.StatusOK {
+			respData, err := httputil.ReadBody(resp.Body)
+			if err != nil {
+				return nil, err
+			}
+			return nil, chat.WrapRateLimit(fmt.Errorf("anthropic api error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respData))), resp.Header)
+		}
+		res, err := p.chatStream(resp.Body, req.Options.OnStream)
+		if err == nil {
+			res.RateLimit = chat.ParseRateLimit(resp.Header)
+		}
+		return res, err
+	}
+
+	respData, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	diag.LogText(debug, debugFn, "anthropic.chat.response", string(respData))
+	if resp.StatusCode != http.StatusOK {
+		return nil, chat.WrapRateLimit(fmt.Errorf("anthropic api error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respData))), resp.Header)
+	}
+	res, err := parseResponse(respData)
+	if err == nil {
+		res.RateLimit = chat.ParseRateLimit(resp.Header)
+	}
+	return res, err
+}
+
+func buildRequest(req *chat.Request) (anthropicRequest, error) {
 	systemParts := make([]string, 0, 1)
 	messages := make([]anthropicMessage, 0, len(req.Messages))
 	for _, m := range req.Messages {
@@ -110,7 +350,13 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 			}
 			continue
 		case chat.RoleUser:
+			if len(m.Videos) > 0 {
+				return anthropicRequest{}, fmt.Errorf("anthropic provider does not support videos")
+			}
 			msg := anthropicMessage{Role: "user"}
+			for _, img := range m.Images {
+				msg.Content = append(msg.Content, imagePart(img))
+			}
 			if m.Content != "" {
 				msg.Content = append(msg.Content, anthropicContentPart{Type: "text", Text: m.Content})
 			}
@@ -125,7 +371,7 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 			if len(m.ToolCalls) > 0 {
 				toolParts, err := toAnthropicToolUses(m.ToolCalls)
 				if err != nil {
-					return nil, err
+					return anthropicRequest{}, err
 				}
 				msg.Content = append(msg.Content, toolParts...)
 			}
@@ -134,7 +380,7 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 			}
 		case chat.RoleTool:
 			if m.ToolCallID == "" {
-				return nil, fmt.Errorf("tool_call_id is required for tool messages")
+				return anthropicRequest{}, fmt.Errorf("tool_call_id is required for tool messages")
 			}
 			messages = append(messages, anthropicMessage{
 				Role: "user",
@@ -145,11 +391,17 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 				}},
 			})
 		default:
-			return nil, fmt.Errorf("anthropic provider does not support role %q", m.Role)
+			return anthropicRequest{}, fmt.Errorf("anthropic provider does not support role %q", m.Role)
 		}
 	}
 	if len(messages) == 0 {
-		return nil, fmt.Errorf("at least one non-system message is required")
+		return anthropicRequest{}, fmt.Errorf("at least one non-system message is required")
+	}
+	if prefill := prefillText(req); prefill != "" {
+		messages = append(messages, anthropicMessage{
+			Role:    "assistant",
+			Content: []anthropicContentPart{{Type: "text", Text: prefill}},
+		})
 	}
 
 	maxTokens := 8192
@@ -158,90 +410,93 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 	}
 
 	body := anthropicRequest{
-		Model:         model,
-		System:        strings.Join(systemParts, "\n"),
 		Messages:      messages,
 		MaxTokens:     maxTokens,
 		Temperature:   req.Options.Temperature,
 		TopP:          req.Options.TopP,
 		StopSequences: req.Options.Stop,
 	}
+	if len(systemParts) > 0 {
+		body.System = strings.Join(systemParts, "\n")
+	}
 	if len(req.Tools) > 0 {
 		tools, err := toAnthropicTools(req.Tools)
 		if err != nil {
-			return nil, err
+			return anthropicRequest{}, err
 		}
 		if len(tools) > 0 {
 			body.Tools = tools
 		}
 	}
+	if ws := req.Options.WebSearch; ws != nil && ws.Enabled {
+		body.Tools = append(body.Tools, webSearchTool(ws))
+	}
+	if ce := req.Options.CodeExecution; ce != nil && ce.Enabled {
+		body.Tools = append(body.Tools, codeExecutionTool())
+		addContainerUploads(body.Messages, ce.FileIDs)
+	}
 	if req.ToolChoice != nil {
 		choice, err := toAnthropicToolChoice(req.ToolChoice)
 		if err != nil {
-			return nil, err
+			return anthropicRequest{}, err
 		}
 		if choice != nil {
 			body.ToolChoice = choice
 		}
 	}
-	applyAnthropicOptions(&body, req.Options.Anthropic)
-
-	if req.Options.OnStream != nil {
-		body.Stream = true
-	}
-
-	data, err := json.Marshal(body)
-	if err != nil {
-		return nil, err
-	}
-	diag.LogText(p.cfg.Debug, debugFn, "anthropic.chat.request", string(data))
-
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", p.cfg.APIKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
-
-	resp, err := httputil.DefaultClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if req.Options.OnStream != nil {
-		if resp.StatusCode != http.StatusOK {
-			respData, err := httputil.ReadBody(resp.Body)
-			if err != nil {
-				return nil, err
-			}
-			return nil, fmt.Errorf("anthropic api error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respData)))
+	if p := req.Options.ParallelToolCalls; p != nil && !*p && len(body.Tools) > 0 {
+		if body.ToolChoice == nil {
+			body.ToolChoice = &anthropicToolChoice{Type: "auto"}
+		}
+		if body.ToolChoice.Type != "none" {
+			disable := true
+			body.ToolChoice.DisableParallelToolUse = &disable
 		}
-		return p.chatStream(resp.Body, req.Options.OnStream)
-	}
-
-	respData, err := httputil.ReadBody(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	diag.LogText(p.cfg.Debug, debugFn, "anthropic.chat.response", string(respData))
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("anthropic api error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respData)))
 	}
+	applyAnthropicOptions(&body, req.Options.Anthropic)
+	return body, nil
+}
 
-	var out anthropicResponse
+func parseResponse(respData []byte) (*chat.Result, error) {
+	var out Response
 	if err := json.Unmarshal(respData, &out); err != nil {
 		return nil, err
 	}
 
 	textParts := make([]string, 0, len(out.Content))
 	toolCalls := make([]chat.ToolCall, 0)
+	var thinking []string
+	var citations []chat.Citation
+	// a searched reply is split into text blocks at each citation
+	searched := false
+	for _, part := range out.Content {
+		if part.Type == "server_tool_use" && part.Name == "web_search" {
+			searched = true
+		}
+	}
+	codes := map[string]string{}
+	var runs []chat.CodeExecutionResult
+	sep := "\n"
+	if searched {
+		sep = ""
+	}
+	offset := 0
 	for _, part := range out.Content {
 		switch part.Type {
+		case "thinking":
+			thinking = append(thinking, part.Thinking)
 		case "text":
-			if strings.TrimSpace(part.Text) != "" {
-				textParts = append(textParts, part.Text)
+			if !searched && strings.TrimSpace(part.Text) == "" {
+				continue
+			}
+			if len(textParts) > 0 {
+				offset += len(sep)
+			}
+			textParts = append(textParts, part.Text)
+			span := &chat.Span{Start: offset, End: offset + len(part.Text)}
+			offset = span.End
+			for _, c := range part.Citations {
+				citations = appendCitation(citations, c, span)
 			}
 		case "tool_use":
 			call, err := fromAnthropicToolUse(part)
@@ -249,25 +504,48 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 				return nil, err
 			}
 			toolCalls = append(toolCalls, call)
+		case "server_tool_use":
+			if part.Name == "code_execution" {
+				codes[part.ID] = serverToolCode(part.Input)
+			}
+		case "code_execution_tool_result":
+			raw, err := json.Marshal(part.Content)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, codeExecutionResult(codes[part.ToolUseID], raw))
 		}
 	}
-	text := strings.Join(textParts, "\n")
+	text := strings.Join(textParts, sep)
 
 	result := &chat.Result{
-		Text:      text,
-		Model:     out.Model,
-		ToolCalls: toolCalls,
+		Text:         text,
+		Model:        out.Model,
+		Messages:     chat.AssistantTurn(text, toolCalls),
+		ToolCalls:    toolCalls,
+		FinishReason: chat.NormalizeFinishReason(out.StopReason),
 		Usage: chat.Usage{
-			InputTokens:  out.Usage.InputTokens,
+			InputTokens:  out.Usage.input(),
 			OutputTokens: out.Usage.OutputTokens,
-			TotalTokens:  out.Usage.InputTokens + out.Usage.OutputTokens,
+			TotalTokens:  out.Usage.input() + out.Usage.OutputTokens,
 		},
-		Raw: out,
+		ReasoningText:  strings.Join(thinking, "\n"),
+		Citations:      citations,
+		CodeExecutions: runs,
+		Raw:            &out,
 	}
 
 	return result, nil
 }
 
+// appendCitation adds a web search citation of the text at span to list.
+func appendCitation(list []chat.Citation, c anthropicCitation, span *chat.Span) []chat.Citation {
+	if c.URL == "" {
+		return list
+	}
+	return chat.AddCitation(list, chat.Citation{URL: c.URL, Title: c.Title, Snippet: c.CitedText, Span: span})
+}
+
 func applyAnthropicOptions(body *anthropicRequest, opts structs.JSONMap) {
 	if body == nil || len(opts) == 0 {
 		return
@@ -281,6 +559,42 @@ func applyAnthropicOptions(body *anthropicRequest, opts structs.JSONMap) {
 	if userID := readUserID(opt); userID != "" {
 		body.Metadata = &anthropicMetadata{UserID: userID}
 	}
+	if opt.HasKey("thinking") {
+		body.Thinking = (*opt)["thinking"]
+	}
+	if opt.HasKey("cache_control") {
+		applyCacheControl(body, (*opt)["cache_control"])
+	}
+}
+
+// applyCacheControl marks the system prompt and the tool definitions as a
+// cacheable prefix. The breakpoint is placed on the last system block and the
+// last tool, which caches everything before it.
+func applyCacheControl(body *anthropicRequest, value any) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case bool:
+		if !v {
+			return
+		}
+		value = map[string]any{"type": "ephemeral"}
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return
+		}
+		value = map[string]any{"type": v}
+	}
+	if system, ok := body.System.(string); ok && system != "" {
+		body.System = []anthropicSystemBlock{{
+			Type:         "text",
+			Text:         system,
+			CacheControl: value,
+		}}
+	}
+	if len(body.Tools) > 0 {
+		body.Tools[len(body.Tools)-1].CacheControl = value
+	}
 }
 
 func toAnthropicTools(tools []chat.Tool) ([]anthropicTool, error) {
@@ -383,72 +697,51 @@ func fromAnthropicToolUse(part anthropicContentPart) (chat.ToolCall, error) {
 
 // SSE event data types for streaming.
 
+type sseEvent struct {
+	Type string `json:"type"`
+}
+
 type sseMessageStart struct {
 	Message struct {
-		Model string `json:"model"`
-		Usage struct {
-			InputTokens int `json:"input_tokens"`
-		} `json:"usage"`
+		Model string         `json:"model"`
+		Usage anthropicUsage `json:"usage"`
 	} `json:"message"`
 }
 
 type sseContentBlockStart struct {
 	Index        int `json:"index"`
 	ContentBlock struct {
-		Type string `json:"type"`
-		ID   string `json:"id,omitempty"`
-		Name string `json:"name,omitempty"`
+		Type      string          `json:"type"`
+		ID        string          `json:"id,omitempty"`
+		Name      string          `json:"name,omitempty"`
+		ToolUseID string          `json:"tool_use_id,omitempty"`
+		Content   json.RawMessage `json:"content,omitempty"`
 	} `json:"content_block"`
 }
 
 type sseContentBlockDelta struct {
 	Index int `json:"index"`
 	Delta struct {
-		Type         string `json:"type"`
-		Text         string `json:"text,omitempty"`
-		PartialJSON  string `json:"partial_json,omitempty"`
+		Type        string             `json:"type"`
+		Text        string             `json:"text,omitempty"`
+		PartialJSON string             `json:"partial_json,omitempty"`
+		Thinking    string             `json:"thinking,omitempty"`
+		Citation    *anthropicCitation `json:"citation,omitempty"`
 	} `json:"delta"`
 }
 
 type sseMessageDelta struct {
-	Usage struct {
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	Delta struct {
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	// Usage is cumulative; input counts are only set when they changed
+	// since message_start.
+	Usage anthropicUsage `json:"usage"`
 }
 
 func (p *Provider) chatStream(body io.Reader, onStream chat.OnStreamFunc) (*chat.Result, error) {
 	scanner := bufio.NewScanner(body)
-
-	var (
-		model        string
-		inputTokens  int
-		outputTokens int
-		textParts    []string
-		toolCalls    []chat.ToolCall
-
-		// per-tool-call accumulator
-		currentToolIndex int    = -1
-		currentToolID    string
-		currentToolName  string
-		currentToolArgs  strings.Builder
-	)
-
-	flushToolCall := func() {
-		if currentToolIndex >= 0 && currentToolName != "" {
-			toolCalls = append(toolCalls, chat.ToolCall{
-				ID:   currentToolID,
-				Type: "function",
-				Function: chat.ToolCallFunction{
-					Name:      currentToolName,
-					Arguments: currentToolArgs.String(),
-				},
-			})
-		}
-		currentToolIndex = -1
-		currentToolID = ""
-		currentToolName = ""
-		currentToolArgs.Reset()
-	}
+	state := newStreamState(onStream)
 
 	var eventType string
 	for scanner.Scan() {
@@ -463,98 +756,240 @@ func (p *Provider) chatStream(body io.Reader, onStream chat.OnStreamFunc) (*chat
 			continue
 		}
 		data := strings.TrimPrefix(line, "data: ")
+		if err := state.handle(eventType, []byte(data)); err != nil {
+			return nil, err
+		}
+		eventType = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 
-		switch eventType {
-		case "message_start":
-			var ev sseMessageStart
-			if err := json.Unmarshal([]byte(data), &ev); err == nil {
-				model = ev.Message.Model
-				inputTokens = ev.Message.Usage.InputTokens
-			}
+	return state.finish()
+}
 
-		case "content_block_start":
-			var ev sseContentBlockStart
-			if err := json.Unmarshal([]byte(data), &ev); err == nil {
-				if ev.ContentBlock.Type == "tool_use" {
-					flushToolCall()
-					currentToolIndex = ev.Index
-					currentToolID = ev.ContentBlock.ID
-					currentToolName = ev.ContentBlock.Name
-					if err := onStream(chat.StreamEvent{
-						ToolCallDelta: &chat.ToolCallDelta{
-							Index: ev.Index,
-							ID:    ev.ContentBlock.ID,
-							Name:  ev.ContentBlock.Name,
-						},
-					}); err != nil {
-						return nil, err
-					}
+// streamState accumulates a Messages API event stream. The same event
+// payloads are delivered as SSE by the Anthropic and Vertex backends and as
+// event-stream chunks by Bedrock.
+type streamState struct {
+	onStream chat.OnStreamFunc
+
+	model      string
+	usage      anthropicUsage
+	stopReason string
+	textParts  []string
+	toolCalls  []chat.ToolCall
+	thinking   strings.Builder
+	citations  []chat.Citation
+	// the citations of the current text block, which starts at blockStart
+	blockStart     int
+	blockCitations []anthropicCitation
+	textLen        int
+
+	// the input of the code execution call being streamed, and the code of
+	// finished calls by ID
+	serverToolID   string
+	serverToolArgs strings.Builder
+	serverCodes    map[string]string
+	codeRuns       []chat.CodeExecutionResult
+
+	// per-tool-call accumulator
+	currentToolIndex int
+	currentToolID    string
+	currentToolName  string
+	currentToolArgs  strings.Builder
+}
+
+func newStreamState(onStream chat.OnStreamFunc) *streamState {
+	return &streamState{onStream: onStream, currentToolIndex: -1}
+}
+
+// flushToolCall completes the tool call being assembled and reports it.
+func (s *streamState) flushToolCall() error {
+	if s.currentToolIndex < 0 || s.currentToolName == "" {
+		s.resetToolCall()
+		return nil
+	}
+	args := s.currentToolArgs.String()
+	if args == "" {
+		args = "{}"
+	}
+	call := chat.ToolCall{
+		ID:   s.currentToolID,
+		Type: "function",
+		Function: chat.ToolCallFunction{
+			Name:      s.currentToolName,
+			Arguments: args,
+		},
+	}
+	s.toolCalls = append(s.toolCalls, call)
+	s.resetToolCall()
+	return s.onStream(chat.StreamEvent{ToolCall: &call})
+}
+
+// flushCitations records the citations of the text block that just ended.
+func (s *streamState) flushCitations() {
+	span := &chat.Span{Start: s.blockStart, End: s.textLen}
+	for _, c := range s.blockCitations {
+		s.citations = appendCitation(s.citations, c, span)
+	}
+	s.blockStart, s.blockCitations = s.textLen, nil
+}
+
+func (s *streamState) resetToolCall() {
+	s.currentToolIndex = -1
+	s.currentToolID = ""
+	s.currentToolName = ""
+	s.currentToolArgs.Reset()
+}
+
+// handle processes a single event. eventType may be empty, in which case the
+// type is read from the payload.
+func (s *streamState) handle(eventType string, data []byte) error {
+	if eventType == "" {
+		var ev sseEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return nil
+		}
+		eventType = ev.Type
+	}
+
+	switch eventType {
+	case "message_start":
+		var ev sseMessageStart
+		if err := json.Unmarshal(data, &ev); err == nil {
+			s.model = ev.Message.Model
+			s.usage = ev.Message.Usage
+		}
+
+	case "content_block_start":
+		var ev sseContentBlockStart
+		if err := json.Unmarshal(data, &ev); err == nil {
+			switch ev.ContentBlock.Type {
+			case "server_tool_use":
+				if ev.ContentBlock.Name == "code_execution" {
+					s.serverToolID = ev.ContentBlock.ID
+					s.serverToolArgs.Reset()
+				}
+			case "code_execution_tool_result":
+				s.codeRuns = append(s.codeRuns, codeExecutionResult(s.serverCodes[ev.ContentBlock.ToolUseID], ev.ContentBlock.Content))
+			}
+			if ev.ContentBlock.Type == "tool_use" {
+				if err := s.flushToolCall(); err != nil {
+					return err
+				}
+				s.currentToolIndex = ev.Index
+				s.currentToolID = ev.ContentBlock.ID
+				s.currentToolName = ev.ContentBlock.Name
+				if err := s.onStream(chat.StreamEvent{
+					ToolCallDelta: &chat.ToolCallDelta{
+						Index: ev.Index,
+						ID:    ev.ContentBlock.ID,
+						Name:  ev.ContentBlock.Name,
+					},
+				}); err != nil {
+					return err
 				}
 			}
+		}
 
-		case "content_block_delta":
-			var ev sseContentBlockDelta
-			if err := json.Unmarshal([]byte(data), &ev); err == nil {
-				switch ev.Delta.Type {
-				case "text_delta":
-					textParts = append(textParts, ev.Delta.Text)
-					if err := onStream(chat.StreamEvent{
-						Delta: ev.Delta.Text,
-					}); err != nil {
-						return nil, err
-					}
-				case "input_json_delta":
-					currentToolArgs.WriteString(ev.Delta.PartialJSON)
-					if err := onStream(chat.StreamEvent{
-						ToolCallDelta: &chat.ToolCallDelta{
-							Index:     currentToolIndex,
-							ArgsChunk: ev.Delta.PartialJSON,
-						},
-					}); err != nil {
-						return nil, err
+	case "content_block_delta":
+		var ev sseContentBlockDelta
+		if err := json.Unmarshal(data, &ev); err == nil {
+			switch ev.Delta.Type {
+			case "text_delta":
+				s.textParts = append(s.textParts, ev.Delta.Text)
+				s.textLen += len(ev.Delta.Text)
+				if err := s.onStream(chat.StreamEvent{
+					Delta: ev.Delta.Text,
+				}); err != nil {
+					return err
+				}
+			case "thinking_delta":
+				s.thinking.WriteString(ev.Delta.Thinking)
+				if err := s.onStream(chat.StreamEvent{
+					ReasoningDelta: ev.Delta.Thinking,
+				}); err != nil {
+					return err
+				}
+			case "citations_delta":
+				if ev.Delta.Citation != nil {
+					s.blockCitations = append(s.blockCitations, *ev.Delta.Citation)
+				}
+			case "input_json_delta":
+				if s.currentToolIndex < 0 {
+					// the input of a server tool such as web search
+					if s.serverToolID != "" {
+						s.serverToolArgs.WriteString(ev.Delta.PartialJSON)
 					}
+					break
+				}
+				s.currentToolArgs.WriteString(ev.Delta.PartialJSON)
+				if err := s.onStream(chat.StreamEvent{
+					ToolCallDelta: &chat.ToolCallDelta{
+						Index:     s.currentToolIndex,
+						ArgsChunk: ev.Delta.PartialJSON,
+					},
+				}); err != nil {
+					return err
 				}
 			}
+		}
 
-		case "content_block_stop":
-			flushToolCall()
-
-		case "message_delta":
-			var ev sseMessageDelta
-			if err := json.Unmarshal([]byte(data), &ev); err == nil {
-				outputTokens = ev.Usage.OutputTokens
+	case "content_block_stop":
+		s.flushCitations()
+		if s.serverToolID != "" {
+			var input any
+			_ = json.Unmarshal([]byte(s.serverToolArgs.String()), &input)
+			if s.serverCodes == nil {
+				s.serverCodes = map[string]string{}
 			}
+			s.serverCodes[s.serverToolID] = serverToolCode(input)
+			s.serverToolID = ""
+		}
+		if err := s.flushToolCall(); err != nil {
+			return err
+		}
 
-		case "message_stop":
-			// handled after the loop
+	case "message_delta":
+		var ev sseMessageDelta
+		if err := json.Unmarshal(data, &ev); err == nil {
+			s.usage.merge(ev.Usage)
+			if ev.Delta.StopReason != "" {
+				s.stopReason = ev.Delta.StopReason
+			}
 		}
-		eventType = ""
+
+	case "message_stop":
+		// handled in finish
 	}
-	if err := scanner.Err(); err != nil {
+	return nil
+}
+
+func (s *streamState) finish() (*chat.Result, error) {
+	if err := s.flushToolCall(); err != nil {
 		return nil, err
 	}
 
-	flushToolCall()
-
-	totalTokens := inputTokens + outputTokens
-	_ = onStream(chat.StreamEvent{
-		Done: true,
-		Usage: &chat.Usage{
-			InputTokens:  inputTokens,
-			OutputTokens: outputTokens,
-			TotalTokens:  totalTokens,
-		},
-	})
+	usage := chat.Usage{
+		InputTokens:  s.usage.input(),
+		OutputTokens: s.usage.OutputTokens,
+	}
+	usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	done := usage
+	_ = s.onStream(chat.StreamEvent{Done: true, Usage: &done})
 
+	text := strings.Join(s.textParts, "")
 	return &chat.Result{
-		Text:      strings.Join(textParts, ""),
-		Model:     model,
-		ToolCalls: toolCalls,
-		Usage: chat.Usage{
-			InputTokens:  inputTokens,
-			OutputTokens: outputTokens,
-			TotalTokens:  totalTokens,
-		},
+		Text:           text,
+		Model:          s.model,
+		Messages:       chat.AssistantTurn(text, s.toolCalls),
+		ToolCalls:      s.toolCalls,
+		FinishReason:   chat.NormalizeFinishReason(s.stopReason),
+		Usage:          usage,
+		ReasoningText:  s.thinking.String(),
+		Citations:      s.citations,
+		CodeExecutions: s.codeRuns,
 	}, nil
 }
 
@@ -581,3 +1016,12 @@ func readUserID(opt *structs.JSONMap) string {
 	}
 	return ""
 }
+
+// RawResponse returns the provider response retained in res.Raw.
+func RawResponse(res *chat.Result) (*Response, bool) {
+	if res == nil {
+		return nil, false
+	}
+	raw, ok := res.Raw.(*Response)
+	return raw, ok
+}
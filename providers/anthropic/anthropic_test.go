@@ -0,0 +1,291 @@
+package anthropic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestBuildRequestNativeOptions(t *testing.T) {
+	req := &chat.Request{
+		Messages: []chat.Message{
+			chat.System("be brief"),
+			chat.User("hello"),
+		},
+		Tools: []chat.Tool{
+			chat.FunctionTool("get_weather", "desc", []byte(`{"type":"object"}`)),
+		},
+		Options: chat.Options{
+			Anthropic: structs.JSONMap{
+				"thinking":      map[string]any{"type": "enabled", "budget_tokens": 1024},
+				"cache_control": true,
+			},
+		},
+	}
+
+	body, err := buildRequest(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.Thinking == nil {
+		t.Fatalf("thinking not mapped")
+	}
+	blocks, ok := body.System.([]anthropicSystemBlock)
+	if !ok || len(blocks) != 1 || blocks[0].Text != "be brief" || blocks[0].CacheControl == nil {
+		t.Fatalf("expected cached system block, got %#v", body.System)
+	}
+	if len(body.Tools) != 1 || body.Tools[0].CacheControl == nil {
+		t.Fatalf("expected cache_control on last tool")
+	}
+}
+
+func TestVertexEndpoint(t *testing.T) {
+	got := vertexEndpoint("proj", "us-east5", "claude-sonnet-4@20250514", true)
+	want := "https://us-east5-aiplatform.googleapis.com/v1/projects/proj/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict"
+	if got != want {
+		t.Fatalf("endpoint mismatch:\n got: %s\nwant: %s", got, want)
+	}
+	if got := vertexEndpoint("proj", "global", "m", false); !strings.HasPrefix(got, "https://aiplatform.googleapis.com/") || !strings.HasSuffix(got, ":rawPredict") {
+		t.Fatalf("unexpected global endpoint: %s", got)
+	}
+}
+
+func TestStreamStateTypedPayloads(t *testing.T) {
+	var (
+		deltas   []string
+		finished []chat.ToolCall
+	)
+	state := newStreamState(func(ev chat.StreamEvent) error {
+		if ev.Delta != "" {
+			deltas = append(deltas, ev.Delta)
+		}
+		if ev.ToolCall != nil {
+			finished = append(finished, *ev.ToolCall)
+		}
+		return nil
+	})
+	events := []string{
+		`{"type":"message_start","message":{"model":"claude","usage":{"input_tokens":3}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"tu_1","name":"get_weather"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Tokyo\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","usage":{"output_tokens":7}}`,
+	}
+	for _, ev := range events {
+		if err := state.handle("", []byte(ev)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	result, err := state.finish()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Text != "Hi" || len(deltas) != 1 {
+		t.Fatalf("unexpected text: %q (%v)", result.Text, deltas)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Function.Arguments != `{"city":"Tokyo"}` {
+		t.Fatalf("unexpected tool calls: %+v", result.ToolCalls)
+	}
+	if len(finished) != 1 || finished[0].ID != "tu_1" {
+		t.Fatalf("expected one completed tool call event, got %+v", finished)
+	}
+	if result.Usage.TotalTokens != 10 {
+		t.Fatalf("unexpected usage: %+v", result.Usage)
+	}
+}
+
+func TestStreamStateMergesDeltaUsage(t *testing.T) {
+	state := newStreamState(func(chat.StreamEvent) error { return nil })
+	events := []string{
+		`{"type":"message_start","message":{"model":"claude","usage":{"input_tokens":3,"cache_creation_input_tokens":100,"cache_read_input_tokens":2000,"output_tokens":1}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		// input_tokens changed, the cache counts did not
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"input_tokens":5,"output_tokens":7}}`,
+	}
+	for _, ev := range events {
+		if err := state.handle("", []byte(ev)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	result, err := state.finish()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := result.Usage; u.InputTokens != 2105 || u.OutputTokens != 7 || u.TotalTokens != 2112 {
+		t.Fatalf("unexpected usage: %+v", u)
+	}
+}
+
+func TestUsageCountsCachedInput(t *testing.T) {
+	res, err := parseResponse([]byte(`{"model":"claude","stop_reason":"end_turn","content":[{"type":"text","text":"ok"}],
+		"usage":{"input_tokens":5,"cache_creation_input_tokens":100,"cache_read_input_tokens":900,"output_tokens":2}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Usage.InputTokens != 1005 || res.Usage.TotalTokens != 1007 {
+		t.Fatalf("unexpected usage: %+v", res.Usage)
+	}
+
+	state := newStreamState(func(chat.StreamEvent) error { return nil })
+	for _, ev := range []string{
+		`{"type":"message_start","message":{"model":"claude","usage":{"input_tokens":5,"cache_read_input_tokens":900}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ok"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+	} {
+		if err := state.handle("", []byte(ev)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	res, err = state.finish()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Usage.InputTokens != 905 || res.Usage.OutputTokens != 2 {
+		t.Fatalf("unexpected stream usage: %+v", res.Usage)
+	}
+}
+
+func TestBuildRequestPrefill(t *testing.T) {
+	body, err := buildRequest(&chat.Request{
+		Messages: []chat.Message{chat.User("list three colors as json")},
+		Options:  chat.Options{Prefill: "[\n"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	last := body.Messages[len(body.Messages)-1]
+	if last.Role != "assistant" || len(last.Content) != 1 || last.Content[0].Text != "[" {
+		t.Fatalf("expected trimmed assistant prefill, got %#v", last)
+	}
+}
+
+func TestBuildRequestImages(t *testing.T) {
+	body, err := buildRequest(&chat.Request{
+		Messages: []chat.Message{chat.UserWithImages("compare",
+			chat.ImageData("image/png", []byte("png")),
+			chat.Image{URL: "https://example.com/cat.jpg"},
+		)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := body.Messages[0].Content
+	if len(content) != 3 || content[2].Text != "compare" {
+		t.Fatalf("unexpected content %#v", content)
+	}
+	if src := content[0].Source; src == nil || src.Type != "base64" || src.MediaType != "image/png" || src.Data != "cG5n" {
+		t.Fatalf("unexpected inline image %#v", content[0].Source)
+	}
+	if src := content[1].Source; src == nil || src.Type != "url" || src.URL != "https://example.com/cat.jpg" {
+		t.Fatalf("unexpected url image %#v", content[1].Source)
+	}
+}
+
+func TestWebSearch(t *testing.T) {
+	body, err := buildRequest(&chat.Request{
+		Messages: []chat.Message{chat.User("latest go release?")},
+		Options:  chat.Options{WebSearch: &chat.WebSearch{Enabled: true, MaxResults: 3, AllowedDomains: []string{"go.dev"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(body.Tools) != 1 || body.Tools[0].Type != "web_search_20250305" || body.Tools[0].MaxUses != 3 {
+		t.Fatalf("expected web search tool, got %#v", body.Tools)
+	}
+
+	res, err := parseResponse([]byte(`{"model":"claude","stop_reason":"end_turn","content":[
+		{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{"query":"go release"}},
+		{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[]},
+		{"type":"text","text":"The latest release is "},
+		{"type":"text","text":"Go 1.25","citations":[{"type":"web_search_result_location","url":"https://go.dev/doc/devel/release","title":"Release History","cited_text":"go1.25.0 (released 2025-08-12)"}]},
+		{"type":"text","text":"."}
+	]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Text != "The latest release is Go 1.25." || len(res.ToolCalls) != 0 {
+		t.Fatalf("unexpected result: %q %#v", res.Text, res.ToolCalls)
+	}
+	if len(res.Citations) != 1 || res.Citations[0].Title != "Release History" || res.Citations[0].Snippet == "" {
+		t.Fatalf("unexpected citations: %#v", res.Citations)
+	}
+	if span := res.Citations[0].Span; span == nil || res.Text[span.Start:span.End] != "Go 1.25" {
+		t.Fatalf("unexpected citation span: %#v", span)
+	}
+}
+
+func TestBuildRequestParallelToolCalls(t *testing.T) {
+	parallel := false
+	body, err := buildRequest(&chat.Request{
+		Messages: []chat.Message{chat.User("weather in Paris and Rome?")},
+		Tools:    []chat.Tool{chat.FunctionTool("get_weather", "", nil)},
+		Options:  chat.Options{ParallelToolCalls: &parallel},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	choice := body.ToolChoice
+	if choice == nil || choice.Type != "auto" || choice.DisableParallelToolUse == nil || !*choice.DisableParallelToolUse {
+		t.Fatalf("expected disable_parallel_tool_use, got %#v", choice)
+	}
+}
+
+func TestCodeExecution(t *testing.T) {
+	req := &chat.Request{
+		Messages: []chat.Message{chat.User("plot data.csv")},
+		Options:  chat.Options{CodeExecution: &chat.CodeExecution{Enabled: true, FileIDs: []string{"file_1"}}},
+	}
+	body, err := buildRequest(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(body.Tools) != 1 || body.Tools[0].Type != "code_execution_20250522" {
+		t.Fatalf("expected code execution tool, got %#v", body.Tools)
+	}
+	parts := body.Messages[0].Content
+	if last := parts[len(parts)-1]; last.Type != "container_upload" || last.FileID != "file_1" {
+		t.Fatalf("expected container upload, got %#v", parts)
+	}
+	if got := betas(req); len(got) != 2 || got[0] != betaCodeExecution || got[1] != betaFiles {
+		t.Fatalf("unexpected betas: %v", got)
+	}
+	req.Options.Betas = []string{chat.BetaContext1M, "anthropic:" + betaFiles}
+	if got := betas(req); len(got) != 3 || got[2] != "context-1m-2025-08-07" {
+		t.Fatalf("unexpected betas with options: %v", got)
+	}
+	req.Options.Betas = nil
+
+	res, err := parseResponse([]byte(`{"model":"claude","stop_reason":"end_turn","content":[
+		{"type":"server_tool_use","id":"srvtoolu_1","name":"code_execution","input":{"code":"print(1+1)"}},
+		{"type":"code_execution_tool_result","tool_use_id":"srvtoolu_1","content":{"type":"code_execution_result","stdout":"2\n","stderr":"","return_code":0,"content":[{"type":"code_execution_output","file_id":"file_out"}]}},
+		{"type":"text","text":"The answer is 2."}
+	]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Text != "The answer is 2." || len(res.ToolCalls) != 0 {
+		t.Fatalf("unexpected result: %q %#v", res.Text, res.ToolCalls)
+	}
+	if len(res.CodeExecutions) != 1 {
+		t.Fatalf("expected one code execution, got %#v", res.CodeExecutions)
+	}
+	run := res.CodeExecutions[0]
+	if run.Code != "print(1+1)" || run.Stdout != "2\n" || len(run.FileIDs) != 1 || run.FileIDs[0] != "file_out" {
+		t.Fatalf("unexpected code execution: %#v", run)
+	}
+}
+
+func TestNewBedrockSessionError(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", t.TempDir()+"/missing.pem")
+	if _, err := New(Config{Backend: BackendBedrock, AwsRegion: "us-east-1"}); err == nil {
+		t.Fatalf("expected the session error")
+	}
+	t.Setenv("AWS_CA_BUNDLE", "")
+	if _, err := New(Config{Backend: BackendBedrock, AwsRegion: "us-east-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
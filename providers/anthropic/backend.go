@@ -0,0 +1,167 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/bedrock"
+	"github.com/aws/aws-sdk-go/service/bedrock/bedrockiface"
+	"github.com/aws/aws-sdk-go/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go/service/bedrockruntime/bedrockruntimeiface"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+const (
+	BackendAnthropic = "anthropic"
+	BackendVertex    = "vertex"
+	BackendBedrock   = "bedrock"
+)
+
+const (
+	anthropicAPIURL         = "https://api.anthropic.com/v1/messages"
+	anthropicAPIVersion     = "2023-06-01"
+	vertexAnthropicVersion  = "vertex-2023-10-16"
+	bedrockAnthropicVersion = "bedrock-2023-05-31"
+)
+
+func (p *Provider) backend() string {
+	switch strings.ToLower(strings.TrimSpace(p.cfg.Backend)) {
+	case BackendVertex:
+		return BackendVertex
+	case BackendBedrock:
+		return BackendBedrock
+	default:
+		return BackendAnthropic
+	}
+}
+
+func (p *Provider) validate() error {
+	switch p.backend() {
+	case BackendVertex:
+		if p.cfg.VertexProjectID == "" || p.cfg.VertexRegion == "" {
+			return fmt.Errorf("anthropic vertex project id and region are required")
+		}
+		if p.cfg.VertexAccessToken == "" {
+			return fmt.Errorf("anthropic vertex access token is required")
+		}
+	case BackendBedrock:
+		if p.bedrock == nil {
+			return fmt.Errorf("anthropic bedrock client is not configured")
+		}
+	default:
+		if p.cfg.APIKey == "" {
+			return fmt.Errorf("anthropic api key is required")
+		}
+	}
+	return nil
+}
+
+// newHTTPRequest builds the HTTP request for the Anthropic and Vertex backends.
+// Vertex takes the model from the URL instead of the request body.
+func (p *Provider) newHTTPRequest(ctx context.Context, model string, data []byte, stream bool, betas []string) (*http.Request, error) {
+	endpoint := anthropicAPIURL
+	if p.backend() == BackendVertex {
+		endpoint = vertexEndpoint(p.cfg.VertexProjectID, p.cfg.VertexRegion, model, stream)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.backend() == BackendVertex {
+		httpReq.Header.Set("Authorization", "Bearer "+p.cfg.VertexAccessToken)
+	} else {
+		httpReq.Header.Set("x-api-key", p.cfg.APIKey)
+		httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+	}
+	if len(betas) > 0 {
+		httpReq.Header.Set("anthropic-beta", strings.Join(betas, ","))
+	}
+	return httpReq, nil
+}
+
+func vertexEndpoint(projectID, region, model string, stream bool) string {
+	host := vertexHost(region)
+	method := "rawPredict"
+	if stream {
+		method = "streamRawPredict"
+	}
+	return fmt.Sprintf("https://%s/v1/projects/%s/locations/%s/publishers/anthropic/models/%s:%s",
+		host, url.PathEscape(projectID), url.PathEscape(region), url.PathEscape(model), method)
+}
+
+// vertexHost returns the Vertex AI host of region.
+func vertexHost(region string) string {
+	if region == "global" {
+		return "aiplatform.googleapis.com"
+	}
+	return region + "-aiplatform.googleapis.com"
+}
+
+// newBedrockClients returns the runtime client that invokes models and the
+// control plane client that lists them.
+func newBedrockClients(cfg Config) (bedrockruntimeiface.BedrockRuntimeAPI, bedrockiface.BedrockAPI, error) {
+	region := cfg.AwsRegion
+	if region == "" {
+		region = "us-east-1"
+	}
+	awsCfg := &aws.Config{Region: aws.String(region)}
+	if cfg.AwsKey != "" || cfg.AwsSecret != "" {
+		awsCfg.Credentials = credentials.NewStaticCredentials(cfg.AwsKey, cfg.AwsSecret, "")
+	}
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("anthropic bedrock session: %w", err)
+	}
+	return bedrockruntime.New(sess), bedrock.New(sess), nil
+}
+
+func (p *Provider) invokeBedrock(ctx context.Context, model string, body []byte) ([]byte, error) {
+	resp, err := p.bedrock.InvokeModelWithContext(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(model),
+		Body:        body,
+		Accept:      aws.String("application/json"),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (p *Provider) chatStreamBedrock(ctx context.Context, model string, body []byte, onStream chat.OnStreamFunc) (*chat.Result, error) {
+	resp, err := p.bedrock.InvokeModelWithResponseStreamWithContext(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(model),
+		Body:        body,
+		Accept:      aws.String("application/json"),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	stream := resp.GetStream()
+	defer stream.Close()
+
+	state := newStreamState(onStream)
+	for event := range stream.Events() {
+		chunk, ok := event.(*bedrockruntime.PayloadPart)
+		if !ok || len(chunk.Bytes) == 0 {
+			continue
+		}
+		if err := state.handle("", chunk.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	return state.finish()
+}
@@ -0,0 +1,93 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Beta features used by the code execution server tool.
+const (
+	betaCodeExecution = "code-execution-2025-05-22"
+	betaFiles         = "files-api-2025-04-14"
+)
+
+// codeExecutionTool is the server tool that runs Python in a sandbox.
+func codeExecutionTool() anthropicTool {
+	return anthropicTool{Type: "code_execution_20250522", Name: "code_execution"}
+}
+
+// betas returns the beta features req needs, followed by those it opts
+// into with Options.Betas.
+func betas(req *chat.Request) []string {
+	var out []string
+	if ce := req.Options.CodeExecution; ce != nil && ce.Enabled {
+		out = append(out, betaCodeExecution)
+		if len(ce.FileIDs) > 0 {
+			out = append(out, betaFiles)
+		}
+	}
+	flags, _ := chat.ResolveBetas(chat.BetaProviderAnthropic, req.Options.Betas)
+	for _, f := range flags {
+		if !slices.Contains(out, f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// addContainerUploads attaches the files of ce to the last user message, which
+// places them in the sandbox.
+func addContainerUploads(messages []anthropicMessage, fileIDs []string) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+		for _, id := range fileIDs {
+			messages[i].Content = append(messages[i].Content, anthropicContentPart{Type: "container_upload", FileID: id})
+		}
+		return
+	}
+}
+
+type codeExecutionContent struct {
+	Type       string `json:"type"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	ReturnCode int    `json:"return_code"`
+	ErrorCode  string `json:"error_code"`
+	Content    []struct {
+		FileID string `json:"file_id"`
+	} `json:"content"`
+}
+
+// codeExecutionResult converts the content of a code_execution_tool_result
+// block for the code that was run.
+func codeExecutionResult(code string, raw []byte) chat.CodeExecutionResult {
+	res := chat.CodeExecutionResult{Code: code}
+	var content codeExecutionContent
+	if err := json.Unmarshal(raw, &content); err != nil {
+		res.Error = "invalid_result"
+		return res
+	}
+	res.Stdout, res.Stderr, res.ReturnCode = content.Stdout, content.Stderr, content.ReturnCode
+	if content.Type == "code_execution_tool_result_error" {
+		res.Error = content.ErrorCode
+	}
+	for _, f := range content.Content {
+		if f.FileID != "" {
+			res.FileIDs = append(res.FileIDs, f.FileID)
+		}
+	}
+	return res
+}
+
+// serverToolCode returns the code of a code_execution server tool input.
+func serverToolCode(input any) string {
+	if m, ok := input.(map[string]any); ok {
+		code, _ := m["code"].(string)
+		return code
+	}
+	return ""
+}
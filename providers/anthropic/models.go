@@ -0,0 +1,142 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/bedrock"
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+var (
+	anthropicModelsURL = "https://api.anthropic.com/v1/models"
+	// vertexModelsURL returns the Model Garden catalog of Anthropic models
+	// in region; a variable so tests can point it elsewhere.
+	vertexModelsURL = func(region string) string {
+		return "https://" + vertexHost(region) + "/v1beta1/publishers/anthropic/models"
+	}
+)
+
+// Model is a Claude model served by the configured backend.
+type Model struct {
+	// ID is the model as requests to the backend name it.
+	ID      string
+	Name    string
+	Created time.Time
+}
+
+// ListModels returns the Claude models of the backend: the Models API for
+// BackendAnthropic, the Anthropic publisher models of the Model Garden for
+// BackendVertex, and the Anthropic foundation models for BackendBedrock.
+func (p *Provider) ListModels(ctx context.Context) ([]Model, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	switch p.backend() {
+	case BackendVertex:
+		return p.listVertexModels(ctx)
+	case BackendBedrock:
+		return p.listBedrockModels(ctx)
+	}
+	var out struct {
+		Data []struct {
+			ID          string    `json:"id"`
+			DisplayName string    `json:"display_name"`
+			CreatedAt   time.Time `json:"created_at"`
+		} `json:"data"`
+	}
+	headers := map[string]string{
+		"x-api-key":         p.cfg.APIKey,
+		"anthropic-version": anthropicAPIVersion,
+	}
+	if err := getJSON(ctx, anthropicModelsURL+"?limit=1000", headers, &out); err != nil {
+		return nil, err
+	}
+	models := make([]Model, 0, len(out.Data))
+	for _, m := range out.Data {
+		models = append(models, Model{ID: m.ID, Name: m.DisplayName, Created: m.CreatedAt})
+	}
+	return models, nil
+}
+
+// listVertexModels pages through the publisher models. Vertex names a
+// model version as model@version.
+func (p *Provider) listVertexModels(ctx context.Context) ([]Model, error) {
+	var models []Model
+	token := ""
+	for {
+		u := vertexModelsURL(p.cfg.VertexRegion) + "?pageSize=100"
+		if token != "" {
+			u += "&pageToken=" + url.QueryEscape(token)
+		}
+		var out struct {
+			PublisherModels []struct {
+				Name      string `json:"name"`
+				VersionID string `json:"versionId"`
+			} `json:"publisherModels"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := getJSON(ctx, u, map[string]string{"Authorization": "Bearer " + p.cfg.VertexAccessToken}, &out); err != nil {
+			return nil, err
+		}
+		for _, m := range out.PublisherModels {
+			id := path.Base(m.Name)
+			if m.VersionID != "" {
+				id += "@" + m.VersionID
+			}
+			models = append(models, Model{ID: id, Name: path.Base(m.Name)})
+		}
+		if out.NextPageToken == "" {
+			return models, nil
+		}
+		token = out.NextPageToken
+	}
+}
+
+func (p *Provider) listBedrockModels(ctx context.Context) ([]Model, error) {
+	if p.bedrockModels == nil {
+		return nil, fmt.Errorf("anthropic bedrock client is not configured")
+	}
+	out, err := p.bedrockModels.ListFoundationModelsWithContext(ctx, &bedrock.ListFoundationModelsInput{
+		ByProvider:       aws.String("Anthropic"),
+		ByOutputModality: aws.String(bedrock.ModelModalityText),
+	})
+	if err != nil {
+		return nil, err
+	}
+	models := make([]Model, 0, len(out.ModelSummaries))
+	for _, m := range out.ModelSummaries {
+		models = append(models, Model{ID: aws.StringValue(m.ModelId), Name: aws.StringValue(m.ModelName)})
+	}
+	return models, nil
+}
+
+func getJSON(ctx context.Context, url string, headers map[string]string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("anthropic model list request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,66 @@
+package anthropic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/bedrock"
+	"github.com/aws/aws-sdk-go/service/bedrock/bedrockiface"
+)
+
+type stubBedrockModels struct {
+	bedrockiface.BedrockAPI
+	input *bedrock.ListFoundationModelsInput
+}
+
+func (s *stubBedrockModels) ListFoundationModelsWithContext(_ aws.Context, in *bedrock.ListFoundationModelsInput, _ ...request.Option) (*bedrock.ListFoundationModelsOutput, error) {
+	s.input = in
+	return &bedrock.ListFoundationModelsOutput{ModelSummaries: []*bedrock.FoundationModelSummary{
+		{ModelId: aws.String("anthropic.claude-sonnet-4-20250514-v1:0"), ModelName: aws.String("Claude Sonnet 4")},
+	}}, nil
+}
+
+func TestListModelsBackends(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = w.Write([]byte(`{"publisherModels":[{"name":"publishers/anthropic/models/claude-sonnet-4","versionId":"20250514"}],"nextPageToken":"next"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"publisherModels":[{"name":"publishers/anthropic/models/claude-opus-4"}]}`))
+	}))
+	defer srv.Close()
+	orig := vertexModelsURL
+	defer func() { vertexModelsURL = orig }()
+	vertexModelsURL = func(region string) string { return srv.URL + "/" + region }
+
+	p, err := New(Config{Backend: BackendVertex, VertexProjectID: "proj", VertexRegion: "us-east5", VertexAccessToken: "token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	models, err := p.ListModels(context.Background())
+	if err != nil || len(models) != 2 || models[0].ID != "claude-sonnet-4@20250514" || models[1].ID != "claude-opus-4" {
+		t.Fatalf("unexpected vertex models: %+v %v", models, err)
+	}
+
+	stub := &stubBedrockModels{}
+	p, err = New(Config{Backend: BackendBedrock, AwsRegion: "us-east-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.bedrockModels = stub
+	models, err = p.ListModels(context.Background())
+	if err != nil || len(models) != 1 || models[0].ID != "anthropic.claude-sonnet-4-20250514-v1:0" {
+		t.Fatalf("unexpected bedrock models: %+v %v", models, err)
+	}
+	if aws.StringValue(stub.input.ByProvider) != "Anthropic" {
+		t.Fatalf("expected the Anthropic provider filter, got %v", stub.input)
+	}
+}
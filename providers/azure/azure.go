@@ -24,10 +24,14 @@ type Provider struct {
 	client     openai.Client
 	deployment string
 	debug      bool
+	apiVersion string
 }
 
 const azureAPIVersion = "2024-08-01-preview"
 
+// storeAPIVersion is the first API version that accepts store.
+const storeAPIVersion = "2025-02-01-preview"
+
 func New(cfg Config) (*Provider, error) {
 	if cfg.APIKey == "" || cfg.Endpoint == "" {
 		return nil, fmt.Errorf("azure openai api key and endpoint are required")
@@ -47,11 +51,13 @@ func New(cfg Config) (*Provider, error) {
 		client:     client,
 		deployment: cfg.Deployment,
 		debug:      cfg.Debug,
+		apiVersion: apiVersion,
 	}, nil
 }
 
 func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
 	debugFn := req.Options.DebugFn
+	debug := p.debug && !req.Options.NoStore
 	messages, err := oaicompat.ToMessages(req.Messages)
 	if err != nil {
 		return nil, err
@@ -99,10 +105,27 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 	}
 
 	applyAzureOptions(&params, req.Options.Azure, req.Options.OpenAI)
-	diag.LogJSON(p.debug, debugFn, "azure.chat.request", params)
+	oaicompat.ApplyParallelToolCalls(&params, req.Options.ParallelToolCalls)
+	var warnings []string
+	if req.Options.NoStore {
+		// API versions are dates, so they order as strings
+		if p.apiVersion >= storeAPIVersion {
+			params.Store = openai.Bool(false)
+		} else {
+			warnings = append(warnings, fmt.Sprintf("no_store: azure api version %s does not take store, it was not sent", p.apiVersion))
+		}
+	}
+	if req.Options.LeanResult {
+		defer oaicompat.ReleaseMessages(params.Messages)
+	}
+	diag.LogJSON(debug, debugFn, "azure.chat.request", params)
 
 	if req.Options.OnStream != nil {
-		return oaicompat.ChatStream(ctx, &p.client, params, req.Options.OnStream)
+		res, err := oaicompat.ChatStream(ctx, &p.client, params, req.Options.OnStream)
+		if err == nil {
+			res.Warnings = append(warnings, res.Warnings...)
+		}
+		return res, err
 	}
 
 	resp, err := p.client.Chat.Completions.New(ctx, params)
@@ -110,31 +133,14 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 		return nil, err
 	}
 	if raw := resp.RawJSON(); raw != "" {
-		diag.LogText(p.debug, debugFn, "azure.chat.response", raw)
+		diag.LogText(debug, debugFn, "azure.chat.response", raw)
 	} else {
-		diag.LogJSON(p.debug, debugFn, "azure.chat.response", resp)
+		diag.LogJSON(debug, debugFn, "azure.chat.response", resp)
 	}
 
-	text := ""
-	var toolCalls []chat.ToolCall
-	for _, choice := range resp.Choices {
-		text += choice.Message.Content
-		if len(choice.Message.ToolCalls) > 0 && len(toolCalls) == 0 {
-			toolCalls = oaicompat.ToToolCalls(choice.Message.ToolCalls)
-		}
-	}
-
-	return &chat.Result{
-		Text:      text,
-		Model:     resp.Model,
-		ToolCalls: toolCalls,
-		Usage: chat.Usage{
-			InputTokens:  int(resp.Usage.PromptTokens),
-			OutputTokens: int(resp.Usage.CompletionTokens),
-			TotalTokens:  int(resp.Usage.TotalTokens),
-		},
-		Raw: resp,
-	}, nil
+	res := oaicompat.ToResult(resp)
+	res.Warnings = append(warnings, res.Warnings...)
+	return res, nil
 }
 
 func applyAzureOptions(params *openai.ChatCompletionNewParams, azureOpts, openaiOpts structs.JSONMap) {
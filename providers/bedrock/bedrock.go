@@ -52,13 +52,23 @@ type bedrockMessage struct {
 }
 
 type bedrockMsgContent struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type   string              `json:"type"`
+	Text   string              `json:"text,omitempty"`
+	Source *bedrockImageSource `json:"source,omitempty"`
 }
 
-type bedrockResponse struct {
-	Content []bedrockMsgContent `json:"content"`
-	Usage   struct {
+type bedrockImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// Response is the InvokeModel response body, available as chat.Result.Raw
+// via RawResponse.
+type Response struct {
+	Content    []bedrockMsgContent `json:"content"`
+	StopReason string              `json:"stop_reason,omitempty"`
+	Usage      struct {
 		InputTokens  int `json:"input_tokens"`
 		OutputTokens int `json:"output_tokens"`
 	} `json:"usage"`
@@ -66,6 +76,7 @@ type bedrockResponse struct {
 
 func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
 	debugFn := req.Options.DebugFn
+	debug := p.debug && !req.Options.NoStore
 	if p.modelArn == "" {
 		return nil, fmt.Errorf("bedrock model arn is required")
 	}
@@ -79,15 +90,25 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 				systemParts = append(systemParts, m.Content)
 			}
 		case chat.RoleUser, chat.RoleAssistant:
-			if m.Content == "" {
+			if len(m.Videos) > 0 {
+				return nil, fmt.Errorf("bedrock provider does not support videos")
+			}
+			var content []bedrockMsgContent
+			for _, img := range m.Images {
+				// Bedrock takes inline images only
+				mediaType, data, ok := img.Data()
+				if !ok {
+					return nil, fmt.Errorf("bedrock provider takes images as data URLs only")
+				}
+				content = append(content, bedrockMsgContent{Type: "image", Source: &bedrockImageSource{Type: "base64", MediaType: mediaType, Data: data}})
+			}
+			if m.Content != "" {
+				content = append(content, bedrockMsgContent{Type: "text", Text: m.Content})
+			}
+			if len(content) == 0 {
 				continue
 			}
-			messages = append(messages, bedrockMessage{
-				Role: m.Role,
-				Content: []bedrockMsgContent{
-					{Type: "text", Text: m.Content},
-				},
-			})
+			messages = append(messages, bedrockMessage{Role: m.Role, Content: content})
 		default:
 			return nil, fmt.Errorf("bedrock provider does not support role %q", m.Role)
 		}
@@ -115,7 +136,7 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 	if err != nil {
 		return nil, err
 	}
-	diag.LogText(p.debug, debugFn, "bedrock.chat.request", string(body))
+	diag.LogText(debug, debugFn, "bedrock.chat.request", string(body))
 
 	if req.Options.OnStream != nil {
 		return p.chatStream(ctx, body, req.Options.OnStream, req.Tools)
@@ -131,11 +152,11 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 		return nil, err
 	}
 
-	var out bedrockResponse
+	var out Response
 	if err := json.Unmarshal(resp.Body, &out); err != nil {
 		return nil, err
 	}
-	diag.LogText(p.debug, debugFn, "bedrock.chat.response", string(resp.Body))
+	diag.LogText(debug, debugFn, "bedrock.chat.response", string(resp.Body))
 
 	var textParts []string
 	for _, c := range out.Content {
@@ -146,13 +167,15 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 	text := strings.Join(textParts, "")
 
 	result := &chat.Result{
-		Text: text,
+		Text:         text,
+		Messages:     chat.AssistantTurn(text, nil),
+		FinishReason: chat.NormalizeFinishReason(out.StopReason),
 		Usage: chat.Usage{
 			InputTokens:  out.Usage.InputTokens,
 			OutputTokens: out.Usage.OutputTokens,
 			TotalTokens:  out.Usage.InputTokens + out.Usage.OutputTokens,
 		},
-		Raw: out,
+		Raw: &out,
 	}
 	if len(req.Tools) > 0 {
 		result.Warnings = append(result.Warnings, "tools not supported for bedrock provider yet")
@@ -183,6 +206,12 @@ type bedrockStreamEvent struct {
 	} `json:"usage,omitempty"`
 }
 
+type bedrockStreamDelta struct {
+	Delta *struct {
+		StopReason string `json:"stop_reason,omitempty"`
+	} `json:"delta,omitempty"`
+}
+
 func (p *Provider) chatStream(ctx context.Context, body []byte, onStream chat.OnStreamFunc, tools []chat.Tool) (*chat.Result, error) {
 	resp, err := p.client.InvokeModelWithResponseStreamWithContext(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
 		ModelId:     aws.String(p.modelArn),
@@ -201,6 +230,7 @@ func (p *Provider) chatStream(ctx context.Context, body []byte, onStream chat.On
 		model        string
 		inputTokens  int
 		outputTokens int
+		stopReason   string
 	)
 
 	for event := range stream.Events() {
@@ -235,6 +265,10 @@ func (p *Provider) chatStream(ctx context.Context, body []byte, onStream chat.On
 			if ev.Usage != nil {
 				outputTokens = ev.Usage.OutputTokens
 			}
+			var delta bedrockStreamDelta
+			if err := json.Unmarshal(chunk.Bytes, &delta); err == nil && delta.Delta != nil && delta.Delta.StopReason != "" {
+				stopReason = delta.Delta.StopReason
+			}
 		}
 	}
 
@@ -252,9 +286,12 @@ func (p *Provider) chatStream(ctx context.Context, body []byte, onStream chat.On
 		},
 	})
 
+	text := strings.Join(textParts, "")
 	result := &chat.Result{
-		Text:  strings.Join(textParts, ""),
-		Model: model,
+		Text:         text,
+		Model:        model,
+		Messages:     chat.AssistantTurn(text, nil),
+		FinishReason: chat.NormalizeFinishReason(stopReason),
 		Usage: chat.Usage{
 			InputTokens:  inputTokens,
 			OutputTokens: outputTokens,
@@ -278,3 +315,12 @@ func applyBedrockOptions(payload map[string]any, opts structs.JSONMap) {
 		}
 	}
 }
+
+// RawResponse returns the provider response retained in res.Raw.
+func RawResponse(res *chat.Result) (*Response, bool) {
+	if res == nil {
+		return nil, false
+	}
+	raw, ok := res.Raw.(*Response)
+	return raw, ok
+}
@@ -0,0 +1,229 @@
+// Package chaos wraps a chat provider and injects faults around it, for
+// testing retries, fallbacks and other resilience features end to end:
+//
+//	flaky := chaos.New(fake.New(fake.Config{...}), chaos.Config{
+//		Seed:          1,
+//		RateLimitRate: 0.2,
+//		TruncateRate:  0.1,
+//	})
+//	client.RegisterProvider("flaky", flaky)
+//
+// Faults are drawn per call from the rates, or taken in order from
+// Config.Sequence for the first calls, so tests can script an exact
+// scenario. Injected errors carry an HTTP status in their message, so
+// chat.ClassifyError classifies them like real provider errors.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Fault is a kind of injected failure.
+type Fault string
+
+const (
+	None Fault = ""
+	// Latency delays the call by Config.Latency before it is sent.
+	Latency Fault = "latency"
+	// RateLimit fails the call with a 429 before it is sent.
+	RateLimit Fault = "rate_limit"
+	// ServerError fails the call with a 503 before it is sent.
+	ServerError Fault = "server_error"
+	// Truncate cuts the reply: a stream fails with io.ErrUnexpectedEOF
+	// after some of its events, and a blocking call fails the same way
+	// after the provider answered.
+	Truncate Fault = "truncate"
+	// Malformed corrupts the JSON in the reply: tool call arguments and
+	// text that is a JSON object or array lose their last byte.
+	Malformed Fault = "malformed"
+)
+
+// Config sets the faults to inject. Rates are probabilities between 0 and
+// 1, tried in the order of the fields; at most one fault is injected per
+// call.
+type Config struct {
+	// Sequence lists the faults of the first calls, in order. Later calls
+	// use the rates.
+	Sequence []Fault
+	// Seed makes the faults drawn from the rates reproducible.
+	Seed uint64
+
+	LatencyRate float64
+	// Latency is the delay of a latency spike (default 2s).
+	Latency       time.Duration
+	RateLimitRate float64
+	// RetryAfter, if set, is reported with rate limit errors.
+	RetryAfter      time.Duration
+	ServerErrorRate float64
+	TruncateRate    float64
+	MalformedRate   float64
+}
+
+// Provider implements chat.Provider around another provider.
+type Provider struct {
+	next chat.Provider
+	cfg  Config
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	calls    int
+	injected []Fault
+}
+
+var _ chat.Provider = (*Provider)(nil)
+
+func New(next chat.Provider, cfg Config) *Provider {
+	if cfg.Latency <= 0 {
+		cfg.Latency = 2 * time.Second
+	}
+	return &Provider{next: next, cfg: cfg, rand: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))}
+}
+
+// Injected returns the fault of every call so far, None for calls that
+// were passed through unchanged.
+func (p *Provider) Injected() []Fault {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Fault(nil), p.injected...)
+}
+
+// draw picks the fault of the next call, and for truncation the number of
+// stream events to let through.
+func (p *Provider) draw() (Fault, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fault := None
+	if p.calls < len(p.cfg.Sequence) {
+		fault = p.cfg.Sequence[p.calls]
+	} else {
+		for _, f := range []struct {
+			fault Fault
+			rate  float64
+		}{
+			{Latency, p.cfg.LatencyRate},
+			{RateLimit, p.cfg.RateLimitRate},
+			{ServerError, p.cfg.ServerErrorRate},
+			{Truncate, p.cfg.TruncateRate},
+			{Malformed, p.cfg.MalformedRate},
+		} {
+			if f.rate > 0 && p.rand.Float64() < f.rate {
+				fault = f.fault
+				break
+			}
+		}
+	}
+	p.calls++
+	p.injected = append(p.injected, fault)
+	return fault, p.rand.IntN(4)
+}
+
+func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	fault, keep := p.draw()
+	switch fault {
+	case Latency:
+		t := time.NewTimer(p.cfg.Latency)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	case RateLimit:
+		err := fmt.Errorf("chaos: status 429: rate limit exceeded")
+		if p.cfg.RetryAfter > 0 {
+			return nil, &chat.RateLimitError{Err: err, RateLimit: &chat.RateLimit{RetryAfter: p.cfg.RetryAfter}}
+		}
+		return nil, err
+	case ServerError:
+		return nil, fmt.Errorf("chaos: status 503: service unavailable")
+	case Truncate:
+		return p.truncate(ctx, req, keep)
+	case Malformed:
+		return p.malformed(ctx, req)
+	}
+	return p.next.Chat(ctx, req)
+}
+
+var errTruncated = fmt.Errorf("chaos: connection closed mid-reply: %w", io.ErrUnexpectedEOF)
+
+func (p *Provider) truncate(ctx context.Context, req *chat.Request, keep int) (*chat.Result, error) {
+	emit := req.Options.OnStream
+	if emit == nil {
+		if _, err := p.next.Chat(ctx, req); err != nil {
+			return nil, err
+		}
+		return nil, errTruncated
+	}
+	out := *req
+	seen := 0
+	out.Options.OnStream = func(ev chat.StreamEvent) error {
+		if seen >= keep || ev.Done {
+			return errTruncated
+		}
+		seen++
+		return emit(ev)
+	}
+	if _, err := p.next.Chat(ctx, &out); err != nil {
+		return nil, err
+	}
+	// the provider did not stream
+	return nil, errTruncated
+}
+
+func (p *Provider) malformed(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	out := *req
+	var text []string
+	if emit := req.Options.OnStream; emit != nil {
+		// Text deltas are held back until Done, since only then is it
+		// known whether the text is JSON.
+		out.Options.OnStream = func(ev chat.StreamEvent) error {
+			switch {
+			case ev.Delta != "":
+				text = append(text, ev.Delta)
+				return nil
+			case ev.ToolCall != nil:
+				tc := corruptCall(*ev.ToolCall)
+				ev.ToolCall = &tc
+			case ev.Done:
+				if joined := corruptText(strings.Join(text, "")); joined != "" {
+					if err := emit(chat.StreamEvent{Delta: joined}); err != nil {
+						return err
+					}
+				}
+			}
+			return emit(ev)
+		}
+	}
+	res, err := p.next.Chat(ctx, &out)
+	if err != nil {
+		return nil, err
+	}
+	res.Text = corruptText(res.Text)
+	for i, tc := range res.ToolCalls {
+		res.ToolCalls[i] = corruptCall(tc)
+	}
+	res.Messages = nil
+	return res, nil
+}
+
+func corruptText(text string) string {
+	if n := len(text); n > 1 && (text[0] == '{' || text[0] == '[') {
+		return text[:n-1]
+	}
+	return text
+}
+
+func corruptCall(tc chat.ToolCall) chat.ToolCall {
+	if n := len(tc.Function.Arguments); n > 0 {
+		tc.Function.Arguments = tc.Function.Arguments[:n-1]
+	}
+	return tc
+}
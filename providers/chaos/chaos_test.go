@@ -0,0 +1,62 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+	"github.com/quailyquaily/uniai/router"
+)
+
+func TestInjectedFaults(t *testing.T) {
+	call := chat.ToolCall{ID: "c1", Type: "function", Function: chat.ToolCallFunction{Name: "f", Arguments: `{"x":1}`}}
+	reply := fake.Text(`{"a":1}`, 2, 0)
+	reply.Chunks = append(reply.Chunks, fake.Chunk{ToolCall: &call})
+	p := New(fake.New(fake.Config{Responses: []fake.Response{reply}}), Config{
+		Sequence: []Fault{RateLimit, ServerError, Truncate, Malformed, None},
+	})
+	ctx := context.Background()
+	var streamed string
+	req := func() *chat.Request {
+		streamed = ""
+		return &chat.Request{Options: chat.Options{OnStream: func(ev chat.StreamEvent) error {
+			streamed += ev.Delta
+			return nil
+		}}}
+	}
+
+	for _, want := range []string{chat.ErrorClassRateLimit, chat.ErrorClassServer} {
+		if _, err := p.Chat(ctx, req()); chat.ClassifyError(err) != want {
+			t.Fatalf("got %v, want a %s error", err, want)
+		}
+	}
+	if _, err := p.Chat(ctx, req()); !errors.Is(err, io.ErrUnexpectedEOF) || len(streamed) >= len(`{"a":1}`) {
+		t.Fatalf("truncate: %v after %q", err, streamed)
+	}
+	res, err := p.Chat(ctx, req())
+	if err != nil || res.Text != `{"a":1` || streamed != res.Text || res.ToolCalls[0].Function.Arguments != `{"x":1` {
+		t.Fatalf("malformed: %+v, streamed %q, %v", res, streamed, err)
+	}
+	if res, err := p.Chat(ctx, req()); err != nil || res.Text != `{"a":1}` {
+		t.Fatalf("pass-through: %+v, %v", res, err)
+	}
+	if got := p.Injected(); len(got) != 5 || got[3] != Malformed || got[4] != None {
+		t.Fatalf("injected %v", got)
+	}
+}
+
+func TestRouterFallsBackFromFaultyProvider(t *testing.T) {
+	client := uniai.New(uniai.Config{})
+	client.RegisterProvider("flaky", New(fake.New(fake.Config{Responses: []fake.Response{fake.Text("flaky", 0, 0)}}), Config{RateLimitRate: 1}))
+	client.RegisterProvider("backup", fake.New(fake.Config{Responses: []fake.Response{fake.Text("backup", 0, 0)}}))
+
+	r := router.New(client, router.Target{Provider: "flaky"}, router.Target{Provider: "backup"})
+	res, err := r.Chat(context.Background(), chat.WithMessages(chat.User("hi")))
+	if err != nil || res.Text != "backup" {
+		t.Fatalf("got %+v, %v", res, err)
+	}
+}
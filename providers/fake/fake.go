@@ -0,0 +1,180 @@
+// Package fake provides a scripted chat provider for tests. It replays
+// canned responses, streaming them chunk by chunk with configurable delays
+// and mid-stream errors, so code that consumes streams can be tested
+// without network access or timing flakiness.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Chunk is one streamed piece of a response. Delay is waited before the
+// chunk is emitted. A chunk with Err set ends the stream with that error
+// after any text or tool call it carries has been emitted.
+type Chunk struct {
+	Text     string
+	ToolCall *chat.ToolCall
+	Delay    time.Duration
+	Err      error
+}
+
+// Response is one scripted reply. Err fails the call before anything is
+// streamed.
+type Response struct {
+	Chunks       []Chunk
+	Model        string
+	FinishReason string
+	// Usage defaults to one output token per chunk.
+	Usage *chat.Usage
+	Err   error
+}
+
+// Text returns a response streaming text in pieces of chunkSize bytes
+// (the whole text when chunkSize <= 0), each preceded by delay.
+func Text(text string, chunkSize int, delay time.Duration) Response {
+	if chunkSize <= 0 || chunkSize > len(text) {
+		chunkSize = len(text)
+	}
+	var chunks []Chunk
+	for start := 0; start < len(text); start += chunkSize {
+		end := min(start+chunkSize, len(text))
+		chunks = append(chunks, Chunk{Text: text[start:end], Delay: delay})
+	}
+	return Response{Chunks: chunks, FinishReason: chat.FinishReasonStop}
+}
+
+// Config scripts the provider.
+type Config struct {
+	// Responses are returned in order, one per call. Once exhausted the
+	// last one is repeated; with none, calls fail.
+	Responses []Response
+	// Sleep waits between chunks. It defaults to a context-aware timer;
+	// tests that want zero wall-clock time can replace it.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Provider implements chat.Provider.
+type Provider struct {
+	cfg Config
+
+	mu       sync.Mutex
+	calls    int
+	requests []*chat.Request
+}
+
+var _ chat.Provider = (*Provider)(nil)
+
+func New(cfg Config) *Provider {
+	if cfg.Sleep == nil {
+		cfg.Sleep = sleep
+	}
+	return &Provider{cfg: cfg}
+}
+
+// Requests returns the requests received so far.
+func (p *Provider) Requests() []*chat.Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*chat.Request(nil), p.requests...)
+}
+
+func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	if err := ctx.Err(); err != nil {
+		// a canceled call does not use up a response
+		p.mu.Unlock()
+		return nil, err
+	}
+	idx := p.calls
+	p.calls++
+	p.mu.Unlock()
+
+	if len(p.cfg.Responses) == 0 {
+		return nil, fmt.Errorf("fake provider has no scripted responses")
+	}
+	resp := p.cfg.Responses[min(idx, len(p.cfg.Responses)-1)]
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	emit := req.Options.OnStream
+	var (
+		text  strings.Builder
+		calls []chat.ToolCall
+	)
+	for i, c := range resp.Chunks {
+		if c.Delay > 0 {
+			if err := p.cfg.Sleep(ctx, c.Delay); err != nil {
+				return nil, err
+			}
+		}
+		if c.Text != "" {
+			text.WriteString(c.Text)
+			if emit != nil {
+				if err := emit(chat.StreamEvent{Delta: c.Text}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if c.ToolCall != nil {
+			calls = append(calls, *c.ToolCall)
+			if emit != nil {
+				delta := chat.ToolCallDelta{Index: len(calls) - 1, ID: c.ToolCall.ID, Name: c.ToolCall.Function.Name, ArgsChunk: c.ToolCall.Function.Arguments}
+				if err := emit(chat.StreamEvent{ToolCallDelta: &delta}); err != nil {
+					return nil, err
+				}
+				if err := emit(chat.StreamEvent{ToolCall: c.ToolCall}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if c.Err != nil {
+			return nil, fmt.Errorf("fake stream chunk %d: %w", i, c.Err)
+		}
+	}
+
+	usage := chat.Usage{OutputTokens: len(resp.Chunks), TotalTokens: len(resp.Chunks)}
+	if resp.Usage != nil {
+		usage = *resp.Usage
+	}
+	if emit != nil {
+		_ = emit(chat.StreamEvent{Done: true, Usage: &usage})
+	}
+	model := resp.Model
+	if model == "" {
+		model = req.Model
+	}
+	finish := resp.FinishReason
+	if finish == "" {
+		finish = chat.FinishReasonStop
+		if len(calls) > 0 {
+			finish = chat.FinishReasonToolCalls
+		}
+	}
+	return &chat.Result{
+		Text:         text.String(),
+		Model:        model,
+		Messages:     chat.AssistantTurn(text.String(), calls),
+		ToolCalls:    calls,
+		FinishReason: finish,
+		Usage:        usage,
+	}, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestProviderStreamsScript(t *testing.T) {
+	var slept time.Duration
+	boom := errors.New("connection reset")
+	p := New(Config{
+		Responses: []Response{
+			Text("hello world", 5, 10*time.Millisecond),
+			{Chunks: []Chunk{{Text: "par"}, {Text: "tial", Err: boom}}},
+		},
+		Sleep: func(ctx context.Context, d time.Duration) error { slept += d; return nil },
+	})
+
+	var deltas []string
+	req := &chat.Request{Options: chat.Options{OnStream: func(ev chat.StreamEvent) error {
+		if ev.Delta != "" {
+			deltas = append(deltas, ev.Delta)
+		}
+		return nil
+	}}}
+	res, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if res.Text != "hello world" || len(deltas) != 3 || deltas[1] != " worl" || slept != 30*time.Millisecond {
+		t.Fatalf("unexpected stream: %q %q %v", res.Text, deltas, slept)
+	}
+
+	deltas = nil
+	if _, err := p.Chat(context.Background(), req); !errors.Is(err, boom) {
+		t.Fatalf("expected mid-stream error, got %v", err)
+	}
+	if len(deltas) != 2 {
+		t.Fatalf("chunks before the error should be emitted: %q", deltas)
+	}
+	if len(p.Requests()) != 2 {
+		t.Fatalf("requests not recorded")
+	}
+}
@@ -0,0 +1,71 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Fixture is a recorded reply of a real provider, as written by the uniai
+// record command, that the fake provider replays.
+type Fixture struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider,omitempty"`
+	// Messages are the request that produced the reply. They document the
+	// fixture; replay does not match on them.
+	Messages     []chat.Message `json:"messages,omitempty"`
+	Model        string         `json:"model,omitempty"`
+	Chunks       []FixtureChunk `json:"chunks"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	Usage        chat.Usage     `json:"usage"`
+}
+
+// FixtureChunk is one streamed piece of a recorded reply.
+type FixtureChunk struct {
+	Text     string         `json:"text,omitempty"`
+	ToolCall *chat.ToolCall `json:"tool_call,omitempty"`
+}
+
+// Response returns the reply as a scripted response, without delays.
+func (f Fixture) Response() Response {
+	chunks := make([]Chunk, 0, len(f.Chunks))
+	for _, c := range f.Chunks {
+		chunks = append(chunks, Chunk{Text: c.Text, ToolCall: c.ToolCall})
+	}
+	usage := f.Usage
+	return Response{Chunks: chunks, Model: f.Model, FinishReason: f.FinishReason, Usage: &usage}
+}
+
+// Replay returns a provider answering with the fixtures in order.
+func Replay(fixtures ...Fixture) *Provider {
+	responses := make([]Response, 0, len(fixtures))
+	for _, f := range fixtures {
+		responses = append(responses, f.Response())
+	}
+	return New(Config{Responses: responses})
+}
+
+// LoadFixtures reads a JSON array of fixtures.
+func LoadFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("parse fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// SaveFixtures writes fixtures as an indented JSON array, so changes to
+// recorded replies diff well.
+func SaveFixtures(path string, fixtures []Fixture) error {
+	data, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
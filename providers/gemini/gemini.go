@@ -0,0 +1,615 @@
+package gemini
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/internal/diag"
+	"github.com/quailyquaily/uniai/internal/httputil"
+	"github.com/quailyquaily/uniai/internal/oaicompat"
+)
+
+// DefaultBaseURL is the root of the Gemini API.
+const DefaultBaseURL = "https://generativelanguage.googleapis.com"
+
+type Config struct {
+	APIKey string
+	// BaseURL is the root of the API, without the version.
+	BaseURL      string
+	DefaultModel string
+	Debug        bool
+}
+
+// Provider talks to the native generateContent API, which unlike the
+// OpenAI-compatible endpoint takes video parts and grounds replies in
+// Google Search.
+type Provider struct {
+	cfg Config
+}
+
+func New(cfg Config) (*Provider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("gemini api key is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &Provider{cfg: cfg}, nil
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type part struct {
+	Text             string            `json:"text,omitempty"`
+	Thought          bool              `json:"thought,omitempty"`
+	InlineData       *blob             `json:"inlineData,omitempty"`
+	FileData         *fileData         `json:"fileData,omitempty"`
+	VideoMetadata    *videoMetadata    `json:"videoMetadata,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+}
+
+type blob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type fileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type videoMetadata struct {
+	StartOffset string  `json:"startOffset,omitempty"`
+	EndOffset   string  `json:"endOffset,omitempty"`
+	FPS         float64 `json:"fps,omitempty"`
+}
+
+type functionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type functionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type tool struct {
+	FunctionDeclarations []functionDeclaration `json:"functionDeclarations,omitempty"`
+	GoogleSearch         *struct{}             `json:"googleSearch,omitempty"`
+}
+
+type functionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type toolConfig struct {
+	FunctionCallingConfig functionCallingConfig `json:"functionCallingConfig"`
+}
+
+type functionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type generationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	MaxOutputTokens  *int     `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+	ResponseSchema   any      `json:"responseSchema,omitempty"`
+}
+
+type request struct {
+	Contents          []content         `json:"contents"`
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	Tools             []tool            `json:"tools,omitempty"`
+	ToolConfig        *toolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+}
+
+// Response is a generateContent response, available as chat.Result.Raw via
+// RawResponse. A streamed reply keeps its last chunk.
+type Response struct {
+	Candidates     []candidate    `json:"candidates"`
+	UsageMetadata  *usageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion   string         `json:"modelVersion,omitempty"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason,omitempty"`
+	} `json:"promptFeedback,omitempty"`
+}
+
+type candidate struct {
+	Content           content            `json:"content"`
+	FinishReason      string             `json:"finishReason,omitempty"`
+	GroundingMetadata *groundingMetadata `json:"groundingMetadata,omitempty"`
+}
+
+type usageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount,omitempty"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+type groundingMetadata struct {
+	GroundingChunks []struct {
+		Web *struct {
+			URI   string `json:"uri"`
+			Title string `json:"title"`
+		} `json:"web,omitempty"`
+	} `json:"groundingChunks,omitempty"`
+	GroundingSupports []struct {
+		Segment struct {
+			PartIndex  int    `json:"partIndex,omitempty"`
+			StartIndex int    `json:"startIndex,omitempty"`
+			EndIndex   int    `json:"endIndex"`
+			Text       string `json:"text,omitempty"`
+		} `json:"segment"`
+		GroundingChunkIndices []int `json:"groundingChunkIndices"`
+	} `json:"groundingSupports,omitempty"`
+	WebSearchQueries []string `json:"webSearchQueries,omitempty"`
+}
+
+// Chat sends req to generateContent, or to streamGenerateContent when it
+// has an OnStream callback.
+func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	debugFn := req.Options.DebugFn
+	debug := p.cfg.Debug && !req.Options.NoStore
+	model := req.Model
+	if model == "" {
+		model = p.cfg.DefaultModel
+	}
+	if model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	body, err := buildRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	diag.LogText(debug, debugFn, "gemini.chat.request", string(data))
+
+	url := p.cfg.BaseURL + "/v1beta/models/" + strings.TrimPrefix(model, "models/")
+	if req.Options.OnStream != nil {
+		url += ":streamGenerateContent?alt=sse"
+	} else {
+		url += ":generateContent"
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.cfg.APIKey)
+
+	resp, err := httputil.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respData, err := httputil.ReadBody(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, chat.WrapRateLimit(fmt.Errorf("gemini api error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respData))), resp.Header)
+	}
+
+	state := &streamState{onStream: req.Options.OnStream}
+	if req.Options.OnStream != nil {
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var chunk Response
+			if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+				return nil, fmt.Errorf("gemini stream: %w", err)
+			}
+			if err := state.add(&chunk); err != nil {
+				return nil, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else {
+		respData, err := httputil.ReadBody(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		diag.LogText(debug, debugFn, "gemini.chat.response", string(respData))
+		var out Response
+		if err := json.Unmarshal(respData, &out); err != nil {
+			return nil, err
+		}
+		if err := state.add(&out); err != nil {
+			return nil, err
+		}
+	}
+	res, err := state.finish()
+	if err == nil {
+		res.RateLimit = chat.ParseRateLimit(resp.Header)
+	}
+	return res, err
+}
+
+func buildRequest(req *chat.Request) (*request, error) {
+	body := &request{}
+	// tool results name the function they answer, which Gemini requires
+	names := map[string]string{}
+	var system []part
+	for _, m := range req.Messages {
+		switch m.Role {
+		case chat.RoleSystem:
+			if m.Content != "" {
+				system = append(system, part{Text: m.Content})
+			}
+		case chat.RoleUser:
+			c := content{Role: "user"}
+			for _, img := range m.Images {
+				c.Parts = append(c.Parts, imagePart(img))
+			}
+			for _, v := range m.Videos {
+				c.Parts = append(c.Parts, videoPart(v))
+			}
+			if m.Content != "" {
+				c.Parts = append(c.Parts, part{Text: m.Content})
+			}
+			body.Contents = appendContent(body.Contents, c)
+		case chat.RoleAssistant:
+			c := content{Role: "model"}
+			if m.Content != "" {
+				c.Parts = append(c.Parts, part{Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				if tc.Function.Name == "" {
+					continue
+				}
+				names[tc.ID] = tc.Function.Name
+				args := strings.TrimSpace(tc.Function.Arguments)
+				if args == "" {
+					args = "{}"
+				}
+				if !json.Valid([]byte(args)) {
+					return nil, fmt.Errorf("invalid tool call arguments for %s", tc.Function.Name)
+				}
+				c.Parts = append(c.Parts, part{FunctionCall: &functionCall{ID: tc.ID, Name: tc.Function.Name, Args: json.RawMessage(args)}})
+			}
+			body.Contents = appendContent(body.Contents, c)
+		case chat.RoleTool:
+			name, ok := names[m.ToolCallID]
+			if !ok {
+				return nil, fmt.Errorf("tool result %q has no matching tool call", m.ToolCallID)
+			}
+			body.Contents = appendContent(body.Contents, content{Role: "user", Parts: []part{{
+				FunctionResponse: &functionResponse{ID: m.ToolCallID, Name: name, Response: toolResponse(m.Content)},
+			}}})
+		default:
+			return nil, fmt.Errorf("gemini provider does not support role %q", m.Role)
+		}
+	}
+	if len(body.Contents) == 0 {
+		return nil, fmt.Errorf("at least one non-system message is required")
+	}
+	if len(system) > 0 {
+		body.SystemInstruction = &content{Parts: system}
+	}
+
+	var decls []functionDeclaration
+	for _, t := range req.Tools {
+		if t.Type != "function" || t.Function.Name == "" {
+			continue
+		}
+		decls = append(decls, functionDeclaration{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.ParametersJSONSchema,
+		})
+	}
+	if len(decls) > 0 {
+		body.Tools = append(body.Tools, tool{FunctionDeclarations: decls})
+		body.ToolConfig = toToolConfig(req.ToolChoice)
+	}
+	if ws := req.Options.WebSearch; ws != nil && ws.Enabled {
+		body.Tools = append(body.Tools, tool{GoogleSearch: &struct{}{}})
+	}
+
+	o := req.Options
+	gen := generationConfig{
+		Temperature:      o.Temperature,
+		TopP:             o.TopP,
+		MaxOutputTokens:  o.MaxTokens,
+		StopSequences:    o.Stop,
+		PresencePenalty:  o.PresencePenalty,
+		FrequencyPenalty: o.FrequencyPenalty,
+	}
+	format := oaicompat.ParseAnyMap(o.OpenAI["response_format"])
+	switch format["type"] {
+	case "json_object":
+		gen.ResponseMimeType = "application/json"
+	case "json_schema":
+		gen.ResponseMimeType = "application/json"
+		if js := oaicompat.ParseAnyMap(format["json_schema"]); js != nil {
+			gen.ResponseSchema = js["schema"]
+		}
+	}
+	body.GenerationConfig = &gen
+	return body, nil
+}
+
+// appendContent adds c to contents, merging it into the last content when
+// both have the same role, since Gemini expects the turns to alternate.
+func appendContent(contents []content, c content) []content {
+	if len(c.Parts) == 0 {
+		return contents
+	}
+	if n := len(contents); n > 0 && contents[n-1].Role == c.Role {
+		contents[n-1].Parts = append(contents[n-1].Parts, c.Parts...)
+		return contents
+	}
+	return append(contents, c)
+}
+
+// imagePart sends a data URL inline and any other URL as file data.
+func imagePart(img chat.Image) part {
+	if mediaType, data, ok := img.Data(); ok {
+		return part{InlineData: &blob{MimeType: mediaType, Data: data}}
+	}
+	return part{FileData: &fileData{FileURI: img.URL}}
+}
+
+// videoPart sends a data URL inline and a YouTube URL or file URI as file
+// data, with the clip and frame rate of v.
+func videoPart(v chat.Video) part {
+	var p part
+	if mediaType, data, ok := v.Data(); ok {
+		p.InlineData = &blob{MimeType: mediaType, Data: data}
+	} else {
+		p.FileData = &fileData{MimeType: v.MIMEType, FileURI: v.URL}
+	}
+	if v.StartOffset > 0 || v.EndOffset > 0 || v.FPS > 0 {
+		p.VideoMetadata = &videoMetadata{
+			StartOffset: offset(v.StartOffset),
+			EndOffset:   offset(v.EndOffset),
+			FPS:         v.FPS,
+		}
+	}
+	return p
+}
+
+// offset formats d as a protobuf Duration, such as "90s" or "1.5s".
+func offset(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// toolResponse wraps a tool result that is not a JSON object, since the
+// response of a function must be one.
+func toolResponse(result string) json.RawMessage {
+	trimmed := strings.TrimSpace(result)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	data, _ := json.Marshal(map[string]string{"result": result})
+	return data
+}
+
+func toToolConfig(choice *chat.ToolChoice) *toolConfig {
+	if choice == nil {
+		return nil
+	}
+	switch choice.Mode {
+	case "auto":
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{Mode: "AUTO"}}
+	case "none":
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{Mode: "NONE"}}
+	case "required":
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{Mode: "ANY"}}
+	case "function":
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{Mode: "ANY", AllowedFunctionNames: []string{choice.FunctionName}}}
+	}
+	return nil
+}
+
+// streamState accumulates the responses of a stream. A blocking call is a
+// stream of one response.
+type streamState struct {
+	onStream chat.OnStreamFunc
+
+	last      *Response
+	chunks    int
+	text      strings.Builder
+	thinking  strings.Builder
+	toolCalls []chat.ToolCall
+	reason    string
+	usage     chat.Usage
+	grounding *groundingMetadata
+	// the offset in the text of each part of the last response
+	partStarts []int
+}
+
+func (s *streamState) emit(ev chat.StreamEvent) error {
+	if s.onStream == nil {
+		return nil
+	}
+	return s.onStream(ev)
+}
+
+func (s *streamState) add(resp *Response) error {
+	s.last = resp
+	s.chunks++
+	if u := resp.UsageMetadata; u != nil {
+		out := u.CandidatesTokenCount + u.ThoughtsTokenCount
+		s.usage = chat.Usage{InputTokens: u.PromptTokenCount, OutputTokens: out, TotalTokens: u.PromptTokenCount + out}
+	}
+	if len(resp.Candidates) == 0 {
+		if fb := resp.PromptFeedback; fb != nil && fb.BlockReason != "" {
+			s.reason = chat.FinishReasonContentFilter
+		}
+		return nil
+	}
+	cand := resp.Candidates[0]
+	if cand.FinishReason != "" {
+		s.reason = finishReason(cand.FinishReason)
+	}
+	if cand.GroundingMetadata != nil {
+		s.grounding = cand.GroundingMetadata
+	}
+	s.partStarts = s.partStarts[:0]
+	for _, p := range cand.Content.Parts {
+		s.partStarts = append(s.partStarts, s.text.Len())
+		switch {
+		case p.FunctionCall != nil:
+			args := string(p.FunctionCall.Args)
+			if args == "" || args == "null" {
+				args = "{}"
+			}
+			id := p.FunctionCall.ID
+			if id == "" {
+				id = newCallID()
+			}
+			call := chat.ToolCall{ID: id, Type: "function", Function: chat.ToolCallFunction{Name: p.FunctionCall.Name, Arguments: args}}
+			index := len(s.toolCalls)
+			s.toolCalls = append(s.toolCalls, call)
+			if err := s.emit(chat.StreamEvent{ToolCallDelta: &chat.ToolCallDelta{Index: index, ID: id, Name: call.Function.Name, ArgsChunk: args}}); err != nil {
+				return err
+			}
+			if err := s.emit(chat.StreamEvent{ToolCall: &call}); err != nil {
+				return err
+			}
+		case p.Thought:
+			s.thinking.WriteString(p.Text)
+			if p.Text != "" {
+				if err := s.emit(chat.StreamEvent{ReasoningDelta: p.Text}); err != nil {
+					return err
+				}
+			}
+		case p.Text != "":
+			s.text.WriteString(p.Text)
+			if err := s.emit(chat.StreamEvent{Delta: p.Text}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *streamState) finish() (*chat.Result, error) {
+	if len(s.toolCalls) > 0 && (s.reason == "" || s.reason == chat.FinishReasonStop) {
+		s.reason = chat.FinishReasonToolCalls
+	}
+	usage := s.usage
+	if err := s.emit(chat.StreamEvent{Done: true, Usage: &usage}); err != nil {
+		return nil, err
+	}
+	text := s.text.String()
+	res := &chat.Result{
+		Text:          text,
+		Messages:      chat.AssistantTurn(text, s.toolCalls),
+		ToolCalls:     s.toolCalls,
+		FinishReason:  s.reason,
+		Usage:         s.usage,
+		ReasoningText: s.thinking.String(),
+		Citations:     s.citations(text),
+	}
+	if s.last != nil {
+		res.Model = s.last.ModelVersion
+		res.Raw = s.last
+	}
+	return res, nil
+}
+
+// citations returns the grounding sources, with the spans of the text they
+// support. Span offsets are bytes, as Gemini reports them; in a blocking
+// reply they count from the start of their part.
+func (s *streamState) citations(text string) []chat.Citation {
+	g := s.grounding
+	if g == nil {
+		return nil
+	}
+	var out []chat.Citation
+	cited := map[int]bool{}
+	for _, sup := range g.GroundingSupports {
+		start, end := sup.Segment.StartIndex, sup.Segment.EndIndex
+		if s.chunks == 1 && sup.Segment.PartIndex < len(s.partStarts) {
+			start += s.partStarts[sup.Segment.PartIndex]
+			end += s.partStarts[sup.Segment.PartIndex]
+		}
+		var span *chat.Span
+		if start >= 0 && start < end && end <= len(text) {
+			span = &chat.Span{Start: start, End: end}
+		}
+		for _, i := range sup.GroundingChunkIndices {
+			if i < 0 || i >= len(g.GroundingChunks) || g.GroundingChunks[i].Web == nil {
+				continue
+			}
+			web := g.GroundingChunks[i].Web
+			cited[i] = true
+			out = chat.AddCitation(out, chat.Citation{URL: web.URI, Title: web.Title, Snippet: sup.Segment.Text, Span: span})
+		}
+	}
+	for i, c := range g.GroundingChunks {
+		if c.Web != nil && !cited[i] {
+			out = chat.AddCitation(out, chat.Citation{URL: c.Web.URI, Title: c.Web.Title})
+		}
+	}
+	return out
+}
+
+func finishReason(reason string) string {
+	switch reason {
+	case "STOP":
+		return chat.FinishReasonStop
+	case "MAX_TOKENS":
+		return chat.FinishReasonLength
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return chat.FinishReasonContentFilter
+	}
+	return strings.ToLower(reason)
+}
+
+func newCallID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "call_" + hex.EncodeToString(b[:])
+}
+
+// RawResponse returns the generateContent response of a gemini result.
+func RawResponse(res *chat.Result) (*Response, bool) {
+	if res == nil {
+		return nil, false
+	}
+	raw, ok := res.Raw.(*Response)
+	return raw, ok
+}
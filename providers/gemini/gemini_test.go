@@ -0,0 +1,173 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestBuildRequestVideosAndTools(t *testing.T) {
+	req := &chat.Request{
+		Messages: []chat.Message{
+			chat.System("be brief"),
+			chat.UserWithVideos("summarize",
+				chat.Video{URL: "https://www.youtube.com/watch?v=abc", StartOffset: 10 * time.Second, EndOffset: 90500 * time.Millisecond, FPS: 2},
+				chat.VideoData("video/mp4", []byte("mp4")),
+			),
+			{Role: chat.RoleAssistant, ToolCalls: []chat.ToolCall{{ID: "call_1", Type: "function", Function: chat.ToolCallFunction{Name: "lookup", Arguments: `{"q":"x"}`}}}},
+			chat.ToolResult("call_1", "plain text"),
+		},
+		Tools:      []chat.Tool{chat.FunctionTool("lookup", "search", []byte(`{"type":"object"}`))},
+		ToolChoice: &chat.ToolChoice{Mode: "function", FunctionName: "lookup"},
+	}
+	body, err := buildRequest(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.SystemInstruction == nil || body.SystemInstruction.Parts[0].Text != "be brief" {
+		t.Fatalf("unexpected system instruction: %#v", body.SystemInstruction)
+	}
+	if len(body.Contents) != 3 {
+		t.Fatalf("expected user, model and tool result turns, got %#v", body.Contents)
+	}
+	parts := body.Contents[0].Parts
+	if len(parts) != 3 || parts[0].FileData == nil || parts[0].FileData.FileURI != "https://www.youtube.com/watch?v=abc" {
+		t.Fatalf("unexpected video parts: %#v", parts)
+	}
+	if meta := parts[0].VideoMetadata; meta == nil || meta.StartOffset != "10s" || meta.EndOffset != "90.5s" || meta.FPS != 2 {
+		t.Fatalf("unexpected video metadata: %#v", parts[0].VideoMetadata)
+	}
+	if parts[1].InlineData == nil || parts[1].InlineData.MimeType != "video/mp4" || parts[1].VideoMetadata != nil {
+		t.Fatalf("unexpected inline video: %#v", parts[1])
+	}
+	resp := body.Contents[2].Parts[0].FunctionResponse
+	if resp == nil || resp.Name != "lookup" || string(resp.Response) != `{"result":"plain text"}` {
+		t.Fatalf("unexpected function response: %#v", resp)
+	}
+	cfg := body.ToolConfig.FunctionCallingConfig
+	if cfg.Mode != "ANY" || len(cfg.AllowedFunctionNames) != 1 {
+		t.Fatalf("unexpected tool config: %#v", cfg)
+	}
+}
+
+func TestChatGrounding(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.5-flash:generateContent" || r.Header.Get("x-goog-api-key") != "key" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, r.Header.Get("x-goog-api-key"))
+		}
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &got)
+		_, _ = io.WriteString(w, `{
+			"candidates": [{
+				"content": {"role": "model", "parts": [{"text": "Go 1.25 is out."}]},
+				"finishReason": "STOP",
+				"groundingMetadata": {
+					"groundingChunks": [{"web": {"uri": "https://go.dev/blog", "title": "go.dev"}}, {"web": {"uri": "https://example.com", "title": "example"}}],
+					"groundingSupports": [{"segment": {"endIndex": 14, "text": "Go 1.25 is out"}, "groundingChunkIndices": [0]}]
+				}
+			}],
+			"usageMetadata": {"promptTokenCount": 5, "candidatesTokenCount": 4, "totalTokenCount": 9},
+			"modelVersion": "gemini-2.5-flash"
+		}`)
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{APIKey: "key", BaseURL: srv.URL, DefaultModel: "gemini-2.5-flash"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := p.Chat(context.Background(), &chat.Request{
+		Messages: []chat.Message{chat.User("what's new in go?")},
+		Options:  chat.Options{WebSearch: &chat.WebSearch{Enabled: true}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tools, _ := got["tools"].([]any)
+	if len(tools) != 1 || !strings.Contains(mustJSON(t, tools[0]), "googleSearch") {
+		t.Fatalf("expected googleSearch tool, got %v", got["tools"])
+	}
+	if res.Text != "Go 1.25 is out." || res.FinishReason != chat.FinishReasonStop || res.Usage.TotalTokens != 9 {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+	if len(res.Citations) != 2 {
+		t.Fatalf("expected two citations, got %#v", res.Citations)
+	}
+	if c := res.Citations[0]; c.URL != "https://go.dev/blog" || c.Span == nil || res.Text[c.Span.Start:c.Span.End] != "Go 1.25 is out" {
+		t.Fatalf("unexpected supported citation: %#v", c)
+	}
+	if c := res.Citations[1]; c.URL != "https://example.com" || c.Span != nil {
+		t.Fatalf("unexpected unsupported citation: %#v", c)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.5-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking","thought":true}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Let me "},{"text":"check."}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"lookup","args":{"q":"go"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":6,"thoughtsTokenCount":2,"totalTokenCount":11}}`,
+		} {
+			_, _ = io.WriteString(w, "data: "+chunk+"\r\n\r\n")
+		}
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{APIKey: "key", BaseURL: srv.URL + "/", DefaultModel: "gemini-2.5-flash"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var deltas, reasoning strings.Builder
+	var calls []chat.ToolCall
+	var done *chat.Usage
+	res, err := p.Chat(context.Background(), &chat.Request{
+		Messages: []chat.Message{chat.User("look up go")},
+		Options: chat.Options{OnStream: func(ev chat.StreamEvent) error {
+			deltas.WriteString(ev.Delta)
+			reasoning.WriteString(ev.ReasoningDelta)
+			if ev.ToolCall != nil {
+				calls = append(calls, *ev.ToolCall)
+			}
+			if ev.Done {
+				done = ev.Usage
+			}
+			return nil
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deltas.String() != "Let me check." || reasoning.String() != "thinking" || res.Text != "Let me check." || res.ReasoningText != "thinking" {
+		t.Fatalf("unexpected text %q reasoning %q result %#v", deltas.String(), reasoning.String(), res)
+	}
+	if len(calls) != 1 || len(res.ToolCalls) != 1 || res.ToolCalls[0].ID == "" || res.ToolCalls[0].Function.Arguments != `{"q":"go"}` {
+		t.Fatalf("unexpected tool calls: %#v %#v", calls, res.ToolCalls)
+	}
+	if res.FinishReason != chat.FinishReasonToolCalls {
+		t.Fatalf("expected tool_calls finish reason, got %q", res.FinishReason)
+	}
+	if done == nil || done.InputTokens != 3 || done.OutputTokens != 8 || res.Usage != *done {
+		t.Fatalf("unexpected usage: %#v %#v", done, res.Usage)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -3,6 +3,7 @@ package openai
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	openai "github.com/openai/openai-go/v3"
@@ -17,12 +18,17 @@ type Config struct {
 	BaseURL      string
 	DefaultModel string
 	Debug        bool
+	// OmitStore leaves the store field out of NoStore requests, for
+	// OpenAI-compatible backends that do not document it. Such requests get
+	// a warning instead.
+	OmitStore bool
 }
 
 type Provider struct {
 	client       openai.Client
 	defaultModel string
 	debug        bool
+	omitStore    bool
 }
 
 func New(cfg Config) (*Provider, error) {
@@ -38,31 +44,66 @@ func New(cfg Config) (*Provider, error) {
 		client:       openai.NewClient(opts...),
 		defaultModel: cfg.DefaultModel,
 		debug:        cfg.Debug,
+		omitStore:    cfg.OmitStore,
 	}, nil
 }
 
 func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
 	debugFn := req.Options.DebugFn
+	debug := p.debug && !req.Options.NoStore
 	params, err := buildParams(req, p.defaultModel)
 	if err != nil {
 		return nil, err
 	}
-	diag.LogJSON(p.debug, debugFn, "openai.chat.request", params)
+	warnings := applyNoStore(&params, req, p.omitStore)
+	if req.Options.LeanResult {
+		defer oaicompat.ReleaseMessages(params.Messages)
+	}
+	diag.LogJSON(debug, debugFn, "openai.chat.request", params)
 
+	// gpt-oss served without a Harmony parser returns raw channels
+	harmony := oaicompat.HarmonyModel(params.Model)
+	var httpResp *http.Response
+	reqOpts := []option.RequestOption{option.WithResponseInto(&httpResp)}
+	if flags, _ := chat.ResolveBetas(chat.BetaProviderOpenAI, req.Options.Betas); len(flags) > 0 {
+		reqOpts = append(reqOpts, option.WithHeader("OpenAI-Beta", strings.Join(flags, ",")))
+	}
 	if req.Options.OnStream != nil {
-		return oaicompat.ChatStream(ctx, &p.client, params, req.Options.OnStream)
+		onStream := req.Options.OnStream
+		if harmony {
+			onStream = oaicompat.HarmonyStream(onStream)
+		}
+		res, err := oaicompat.ChatStream(ctx, &p.client, params, onStream, reqOpts...)
+		if err == nil {
+			if harmony {
+				oaicompat.ApplyHarmony(res)
+			}
+			if httpResp != nil {
+				res.RateLimit = chat.ParseRateLimit(httpResp.Header)
+			}
+			res.Warnings = append(warnings, res.Warnings...)
+		}
+		return res, err
 	}
 
-	resp, err := p.client.Chat.Completions.New(ctx, params)
+	resp, err := p.client.Chat.Completions.New(ctx, params, reqOpts...)
 	if err != nil {
 		return nil, err
 	}
 	if raw := resp.RawJSON(); raw != "" {
-		diag.LogText(p.debug, debugFn, "openai.chat.response", raw)
+		diag.LogText(debug, debugFn, "openai.chat.response", raw)
 	} else {
-		diag.LogJSON(p.debug, debugFn, "openai.chat.response", resp)
+		diag.LogJSON(debug, debugFn, "openai.chat.response", resp)
 	}
-	return toResult(resp), nil
+	res := toResult(resp)
+	if harmony {
+		oaicompat.ApplyHarmony(res)
+	}
+	if httpResp != nil {
+		res.RateLimit = chat.ParseRateLimit(httpResp.Header)
+	}
+	res.Warnings = append(warnings, res.Warnings...)
+	return res, nil
 }
 
 func buildParams(req *chat.Request, defaultModel string) (openai.ChatCompletionNewParams, error) {
@@ -73,93 +114,29 @@ func buildParams(req *chat.Request, defaultModel string) (openai.ChatCompletionN
 	if model == "" {
 		return openai.ChatCompletionNewParams{}, fmt.Errorf("model is required")
 	}
-
-	messages, err := oaicompat.ToMessages(req.Messages)
+	params, err := oaicompat.BuildParams(req, model)
 	if err != nil {
 		return openai.ChatCompletionNewParams{}, err
 	}
+	return params, nil
+}
 
-	params := openai.ChatCompletionNewParams{
-		Model:    openai.ChatModel(model),
-		Messages: messages,
-	}
-
-	if req.Options.Temperature != nil {
-		params.Temperature = openai.Float(*req.Options.Temperature)
-	}
-	if req.Options.TopP != nil {
-		params.TopP = openai.Float(*req.Options.TopP)
-	}
-	if req.Options.MaxTokens != nil {
-		maxTokens := int64(*req.Options.MaxTokens)
-		if useMaxCompletionTokens(model) {
-			params.MaxCompletionTokens = openai.Int(maxTokens)
-		} else {
-			params.MaxTokens = openai.Int(maxTokens)
-		}
-	}
-	if len(req.Options.Stop) > 0 {
-		params.Stop = openai.ChatCompletionNewParamsStopUnion{
-			OfStringArray: append([]string{}, req.Options.Stop...),
-		}
-	}
-	if req.Options.PresencePenalty != nil {
-		params.PresencePenalty = openai.Float(*req.Options.PresencePenalty)
-	}
-	if req.Options.FrequencyPenalty != nil {
-		params.FrequencyPenalty = openai.Float(*req.Options.FrequencyPenalty)
-	}
-	if req.Options.User != nil {
-		params.User = openai.String(*req.Options.User)
-	}
-
-	if len(req.Tools) > 0 {
-		tools, err := oaicompat.ToToolParams(req.Tools)
-		if err != nil {
-			return openai.ChatCompletionNewParams{}, err
-		}
-		params.Tools = tools
+// applyNoStore sends store: false for NoStore requests, or returns a
+// warning when the backend does not take the field.
+func applyNoStore(params *openai.ChatCompletionNewParams, req *chat.Request, omit bool) []string {
+	if !req.Options.NoStore {
+		return nil
 	}
-
-	if req.ToolChoice != nil {
-		params.ToolChoice = oaicompat.ToToolChoice(req.ToolChoice)
+	if omit {
+		return []string{"no_store: the backend has no per-request retention control, store was not sent"}
 	}
-
-	oaicompat.ApplyOptions(&params, req.Options.OpenAI)
-
-	return params, nil
+	params.Store = openai.Bool(false)
+	return nil
 }
 
 func toResult(resp *openai.ChatCompletion) *chat.Result {
 	if resp == nil {
 		return &chat.Result{Warnings: []string{"openai response is nil"}}
 	}
-	text := ""
-	var toolCalls []chat.ToolCall
-	for _, choice := range resp.Choices {
-		text += choice.Message.Content
-		if len(choice.Message.ToolCalls) > 0 && len(toolCalls) == 0 {
-			toolCalls = oaicompat.ToToolCalls(choice.Message.ToolCalls)
-		}
-	}
-
-	return &chat.Result{
-		Text:      text,
-		Model:     resp.Model,
-		ToolCalls: toolCalls,
-		Usage: chat.Usage{
-			InputTokens:  int(resp.Usage.PromptTokens),
-			OutputTokens: int(resp.Usage.CompletionTokens),
-			TotalTokens:  int(resp.Usage.TotalTokens),
-		},
-		Raw: resp,
-	}
-}
-
-func useMaxCompletionTokens(model string) bool {
-	model = strings.ToLower(model)
-	return strings.HasPrefix(model, "gpt") ||
-		strings.HasPrefix(model, "o1") ||
-		strings.HasPrefix(model, "o3") ||
-		strings.HasPrefix(model, "o4")
+	return oaicompat.ToResult(resp)
 }
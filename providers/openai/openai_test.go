@@ -1,8 +1,14 @@
 package openai
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/lyricat/goutils/structs"
 	openai "github.com/openai/openai-go/v3"
 	"github.com/quailyquaily/uniai/chat"
 )
@@ -92,6 +98,47 @@ func TestMaxCompletionTokensHeuristic(t *testing.T) {
 	}
 }
 
+func TestBuildParamsImages(t *testing.T) {
+	req := &chat.Request{
+		Model:    "gpt-4o",
+		Messages: []chat.Message{chat.UserWithImages("what is this?", chat.Image{URL: "https://example.com/cat.png", Detail: "low"})},
+	}
+	params, err := buildParams(req, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := json.Marshal(params.Messages[0])
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"content":[{"text":"what is this?","type":"text"},{"image_url":{"url":"https://example.com/cat.png","detail":"low"},"type":"image_url"}],"role":"user"}`
+	if string(data) != want {
+		t.Fatalf("unexpected message %s", data)
+	}
+}
+
+func TestNoStore(t *testing.T) {
+	req := &chat.Request{Model: "gpt-5", Messages: []chat.Message{chat.User("hello")}}
+	req.Options.OpenAI = structs.JSONMap{"store": true}
+	req.Options.NoStore = true
+	params, err := buildParams(req, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if warnings := applyNoStore(&params, req, false); len(warnings) != 0 || !params.Store.Valid() || params.Store.Value {
+		t.Fatalf("expected store=false, got %+v %v", params.Store, warnings)
+	}
+
+	req.Options.OpenAI = nil
+	params, err = buildParams(req, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if warnings := applyNoStore(&params, req, true); len(warnings) != 1 || params.Store.Valid() {
+		t.Fatalf("expected no store field and a warning, got %+v %v", params.Store, warnings)
+	}
+}
+
 func TestToolSchemaAddsArrayItems(t *testing.T) {
 	req := &chat.Request{
 		Model: "gpt-4.1-mini",
@@ -123,3 +170,84 @@ func TestToolSchemaAddsArrayItems(t *testing.T) {
 		t.Fatalf("expected items to be added for array type")
 	}
 }
+
+func TestToResultMessages(t *testing.T) {
+	var resp openai.ChatCompletion
+	body := `{"model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"","refusal":"I can't help with that."}}]}`
+	if err := resp.UnmarshalJSON([]byte(body)); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	res := toResult(&resp)
+	if len(res.Messages) != 1 || res.Messages[0].Role != chat.RoleAssistant || res.Messages[0].Refusal != "I can't help with that." {
+		t.Fatalf("unexpected messages %+v", res.Messages)
+	}
+}
+
+func TestToResultChoices(t *testing.T) {
+	var resp openai.ChatCompletion
+	body := `{"model":"gpt-4o","choices":[
+		{"index":0,"finish_reason":"content_filter","message":{"role":"assistant","content":"bl"}},
+		{"index":1,"finish_reason":"stop","message":{"role":"assistant","content":"second"}},
+		{"index":2,"finish_reason":"stop","message":{"role":"assistant","content":"third"}}]}`
+	if err := resp.UnmarshalJSON([]byte(body)); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	res := toResult(&resp)
+	if res.Text != "second" || res.Messages[0].Content != "second" || res.FinishReason != chat.FinishReasonStop {
+		t.Fatalf("expected the first unblocked choice, got %q %q", res.Text, res.FinishReason)
+	}
+	if len(res.Choices) != 3 || res.Choices[2].Text != "third" || res.Choices[0].FinishReason != chat.FinishReasonContentFilter {
+		t.Fatalf("unexpected choices %+v", res.Choices)
+	}
+	if len(res.ItemErrors) != 1 || res.ItemErrors[0].Index != 0 {
+		t.Fatalf("unexpected item errors %+v", res.ItemErrors)
+	}
+
+	body = `{"model":"gpt-4o","choices":[
+		{"index":0,"finish_reason":"content_filter","message":{"role":"assistant","content":"a"}},
+		{"index":1,"finish_reason":"content_filter","message":{"role":"assistant","content":"b"}}]}`
+	if err := resp.UnmarshalJSON([]byte(body)); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	res = toResult(&resp)
+	if res.Text != "a" || res.FinishReason != chat.FinishReasonContentFilter || len(res.ItemErrors) != 2 {
+		t.Fatalf("unexpected all-blocked result %q %q %+v", res.Text, res.FinishReason, res.ItemErrors)
+	}
+}
+
+func BenchmarkChat(b *testing.B) {
+	body := []byte(`{"id":"c1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":40,"completion_tokens":1,"total_tokens":41}}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{APIKey: "sk-test", BaseURL: srv.URL, DefaultModel: "gpt-4o"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	msgs := make([]chat.Message, 0, 20)
+	msgs = append(msgs, chat.System("You are terse."))
+	for i := 0; i < 9; i++ {
+		msgs = append(msgs, chat.User("question"), chat.Assistant("answer"))
+	}
+	msgs = append(msgs, chat.User("last question"))
+
+	for _, lean := range []bool{false, true} {
+		name := "default"
+		if lean {
+			name = "lean"
+		}
+		b.Run(name, func(b *testing.B) {
+			req := &chat.Request{Messages: msgs, Options: chat.Options{LeanResult: lean}}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := p.Chat(context.Background(), req); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
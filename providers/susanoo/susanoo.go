@@ -40,7 +40,9 @@ type taskResponse struct {
 	} `json:"data"`
 }
 
-type taskResultResponse struct {
+// TaskResult is the polled task result, available as chat.Result.Raw via
+// RawResponse.
+type TaskResult struct {
 	Data struct {
 		Result map[string]any `json:"result"`
 		Status int            `json:"status"`
@@ -54,9 +56,18 @@ type taskResultResponse struct {
 
 func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
 	debugFn := req.Options.DebugFn
+	debug := p.cfg.Debug && !req.Options.NoStore
 	if p.cfg.APIBase == "" || p.cfg.APIKey == "" {
 		return nil, fmt.Errorf("susanoo api base and api key are required")
 	}
+	for _, m := range req.Messages {
+		if len(m.Images) > 0 {
+			return nil, fmt.Errorf("susanoo provider does not support images")
+		}
+		if len(m.Videos) > 0 {
+			return nil, fmt.Errorf("susanoo provider does not support videos")
+		}
+	}
 
 	params := map[string]any{}
 	if req.Model != "" {
@@ -84,25 +95,30 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 		params["user"] = *req.Options.User
 	}
 	if len(req.Tools) > 0 {
-		params["tools"] = req.Tools
+		tools := make([]chat.Tool, len(req.Tools))
+		for i, t := range req.Tools {
+			t.Function.Examples = nil // emulation-only
+			tools[i] = t
+		}
+		params["tools"] = tools
 		if req.ToolChoice != nil {
 			params["tool_choice"] = req.ToolChoice
 		}
 	}
 
 	traceID, err := p.createTask(ctx, &taskRequest{
-		Messages: req.Messages,
+		Messages: wireMessages(req.Messages),
 		Params:   params,
-	}, debugFn)
+	}, debug, debugFn)
 	if err != nil {
 		return nil, err
 	}
 
-	result, err := p.pollResult(ctx, traceID, debugFn)
+	result, err := p.pollResult(ctx, traceID, debug, debugFn)
 	if err != nil {
 		return nil, err
 	}
-	diag.LogJSON(p.cfg.Debug, debugFn, "susanoo.chat.response", result)
+	diag.LogJSON(debug, debugFn, "susanoo.chat.response", result)
 
 	text := ""
 	if val, ok := result.Data.Result["response"]; ok {
@@ -112,7 +128,8 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 	}
 
 	return &chat.Result{
-		Text: text,
+		Text:     text,
+		Messages: chat.AssistantTurn(text, nil),
 		Usage: chat.Usage{
 			InputTokens:  result.Data.Usage.InputTokens,
 			OutputTokens: result.Data.Usage.OutputTokens,
@@ -122,12 +139,33 @@ func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, e
 	}, nil
 }
 
-func (p *Provider) createTask(ctx context.Context, task *taskRequest, debugFn func(string, string)) (string, error) {
+// wireMessages returns msgs without the tool call Metadata uniai adds.
+func wireMessages(msgs []chat.Message) []chat.Message {
+	out := msgs
+	copied := false
+	for i, m := range msgs {
+		if len(m.ToolCalls) == 0 {
+			continue
+		}
+		if !copied {
+			out, copied = append([]chat.Message(nil), msgs...), true
+		}
+		calls := make([]chat.ToolCall, len(m.ToolCalls))
+		for j, tc := range m.ToolCalls {
+			tc.Metadata = nil
+			calls[j] = tc
+		}
+		out[i].ToolCalls = calls
+	}
+	return out
+}
+
+func (p *Provider) createTask(ctx context.Context, task *taskRequest, debug bool, debugFn func(string, string)) (string, error) {
 	data, err := json.Marshal(task)
 	if err != nil {
 		return "", err
 	}
-	diag.LogText(p.cfg.Debug, debugFn, "susanoo.chat.request", string(data))
+	diag.LogText(debug, debugFn, "susanoo.chat.request", string(data))
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/tasks", p.cfg.APIBase), bytes.NewReader(data))
 	if err != nil {
 		return "", err
@@ -145,7 +183,7 @@ func (p *Provider) createTask(ctx context.Context, task *taskRequest, debugFn fu
 	if err != nil {
 		return "", err
 	}
-	diag.LogText(p.cfg.Debug, debugFn, "susanoo.chat.create_task.response", string(respData))
+	diag.LogText(debug, debugFn, "susanoo.chat.create_task.response", string(respData))
 	var out taskResponse
 	if err := json.Unmarshal(respData, &out); err != nil {
 		return "", err
@@ -156,9 +194,9 @@ func (p *Provider) createTask(ctx context.Context, task *taskRequest, debugFn fu
 	return out.Data.TraceID, nil
 }
 
-func (p *Provider) pollResult(ctx context.Context, traceID string, debugFn func(string, string)) (*taskResultResponse, error) {
+func (p *Provider) pollResult(ctx context.Context, traceID string, debug bool, debugFn func(string, string)) (*TaskResult, error) {
 	for {
-		result, err := p.fetchResult(ctx, traceID, debugFn)
+		result, err := p.fetchResult(ctx, traceID, debug, debugFn)
 		if err != nil {
 			return nil, err
 		}
@@ -175,7 +213,7 @@ func (p *Provider) pollResult(ctx context.Context, traceID string, debugFn func(
 	}
 }
 
-func (p *Provider) fetchResult(ctx context.Context, traceID string, debugFn func(string, string)) (*taskResultResponse, error) {
+func (p *Provider) fetchResult(ctx context.Context, traceID string, debug bool, debugFn func(string, string)) (*TaskResult, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/tasks/result?trace_id=%s", p.cfg.APIBase, traceID), nil)
 	if err != nil {
 		return nil, err
@@ -193,10 +231,19 @@ func (p *Provider) fetchResult(ctx context.Context, traceID string, debugFn func
 	if err != nil {
 		return nil, err
 	}
-	diag.LogText(p.cfg.Debug, debugFn, "susanoo.chat.fetch_result.response", string(respData))
-	var out taskResultResponse
+	diag.LogText(debug, debugFn, "susanoo.chat.fetch_result.response", string(respData))
+	var out TaskResult
 	if err := json.Unmarshal(respData, &out); err != nil {
 		return nil, err
 	}
 	return &out, nil
 }
+
+// RawResponse returns the provider response retained in res.Raw.
+func RawResponse(res *chat.Result) (*TaskResult, bool) {
+	if res == nil {
+		return nil, false
+	}
+	raw, ok := res.Raw.(*TaskResult)
+	return raw, ok
+}
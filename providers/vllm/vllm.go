@@ -0,0 +1,144 @@
+package vllm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lyricat/goutils/structs"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/internal/diag"
+	"github.com/quailyquaily/uniai/internal/oaicompat"
+)
+
+type Config struct {
+	APIKey       string
+	BaseURL      string
+	DefaultModel string
+	Debug        bool
+}
+
+type Provider struct {
+	client       openai.Client
+	defaultModel string
+	debug        bool
+}
+
+// New creates a provider for a vLLM OpenAI-compatible server. vLLM does not
+// require an API key unless the server was started with --api-key.
+func New(cfg Config) (*Provider, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("vllm base url is required")
+	}
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = "EMPTY"
+	}
+	return &Provider{
+		client:       openai.NewClient(option.WithAPIKey(apiKey), option.WithBaseURL(cfg.BaseURL)),
+		defaultModel: cfg.DefaultModel,
+		debug:        cfg.Debug,
+	}, nil
+}
+
+func (p *Provider) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	debugFn := req.Options.DebugFn
+	debug := p.debug && !req.Options.NoStore
+	params, err := buildParams(req, p.defaultModel)
+	if err != nil {
+		return nil, err
+	}
+	if req.Options.LeanResult {
+		defer oaicompat.ReleaseMessages(params.Messages)
+	}
+	diag.LogJSON(debug, debugFn, "vllm.chat.request", params)
+
+	// gpt-oss served without a Harmony parser returns raw channels
+	harmony := oaicompat.HarmonyModel(params.Model)
+	if req.Options.OnStream != nil {
+		onStream := req.Options.OnStream
+		if harmony {
+			onStream = oaicompat.HarmonyStream(onStream)
+		}
+		res, err := oaicompat.ChatStream(ctx, &p.client, params, onStream)
+		if err == nil && harmony {
+			oaicompat.ApplyHarmony(res)
+		}
+		return res, err
+	}
+
+	resp, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if raw := resp.RawJSON(); raw != "" {
+		diag.LogText(debug, debugFn, "vllm.chat.response", raw)
+	} else {
+		diag.LogJSON(debug, debugFn, "vllm.chat.response", resp)
+	}
+
+	res := oaicompat.ToResult(resp)
+	if harmony {
+		oaicompat.ApplyHarmony(res)
+	}
+	return res, nil
+}
+
+func buildParams(req *chat.Request, defaultModel string) (openai.ChatCompletionNewParams, error) {
+	model := req.Model
+	if model == "" {
+		model = defaultModel
+	}
+	// vLLM serves LoRA adapters under their own model name.
+	if adapter := strings.TrimSpace(req.Options.VLLM.GetString("lora_adapter")); adapter != "" {
+		model = adapter
+	}
+	if model == "" {
+		return openai.ChatCompletionNewParams{}, fmt.Errorf("model is required")
+	}
+
+	params, err := oaicompat.BuildParams(req, model)
+	if err != nil {
+		return openai.ChatCompletionNewParams{}, err
+	}
+	if extra := extraBody(req.Options.OpenAI, req.Options.VLLM); len(extra) > 0 {
+		params.SetExtraFields(extra)
+	}
+	return params, nil
+}
+
+// vllmExtraKeys are the vLLM sampling extensions passed through from
+// Options.VLLM as top-level request fields.
+var vllmExtraKeys = []string{
+	"guided_json",
+	"guided_choice",
+	"guided_regex",
+	"guided_grammar",
+	"guided_decoding_backend",
+	"best_of",
+	"use_beam_search",
+	"length_penalty",
+	"top_k",
+	"min_p",
+	"repetition_penalty",
+	"min_tokens",
+}
+
+// extraBody merges the OpenAI extra_body option (which carries the portable
+// grammar mapping) with the vLLM-specific options. vLLM options win.
+func extraBody(openaiOpts, vllmOpts structs.JSONMap) map[string]any {
+	extra := map[string]any{}
+	if raw, ok := openaiOpts["extra_body"]; ok {
+		for k, v := range oaicompat.ParseAnyMap(raw) {
+			extra[k] = v
+		}
+	}
+	for _, key := range vllmExtraKeys {
+		if v, ok := vllmOpts[key]; ok {
+			extra[key] = v
+		}
+	}
+	return extra
+}
@@ -0,0 +1,124 @@
+package vllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestBuildParamsVLLMExtensions(t *testing.T) {
+	req := &chat.Request{
+		Model:    "meta-llama/Llama-3.1-8B-Instruct",
+		Messages: []chat.Message{chat.User("hello")},
+		Options: chat.Options{
+			OpenAI: structs.JSONMap{
+				"extra_body": map[string]any{"guided_regex": "yes|no"},
+			},
+			VLLM: structs.JSONMap{
+				"lora_adapter":    "sql-lora",
+				"guided_choice":   []any{"yes", "no"},
+				"use_beam_search": true,
+				"best_of":         4,
+			},
+		},
+	}
+	params, err := buildParams(req, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(params.Model) != "sql-lora" {
+		t.Fatalf("expected lora adapter as model, got %s", params.Model)
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, want := range []string{`"guided_regex":"yes|no"`, `"guided_choice":["yes","no"]`, `"use_beam_search":true`, `"best_of":4`} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("missing %s in %s", want, data)
+		}
+	}
+}
+
+func TestBuildParamsSharedWithOpenAI(t *testing.T) {
+	maxTokens := 64
+	params, err := buildParams(&chat.Request{
+		Messages: []chat.Message{chat.User("hello")},
+		Options:  chat.Options{MaxTokens: &maxTokens},
+	}, "gpt-oss-20b")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params.MaxTokens.Valid() || params.MaxCompletionTokens.Value != 64 {
+		t.Fatalf("expected max_completion_tokens for gpt-oss, got %+v / %+v", params.MaxTokens, params.MaxCompletionTokens)
+	}
+}
+
+func TestHarmonyOutput(t *testing.T) {
+	raw := "<|channel|>analysis<|message|>Need weather.<|end|><|start|>assistant<|channel|>commentary to=functions.get_weather <|constrain|>json<|message|>{\"city\":\"Oslo\"}<|call|>"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		content, _ := json.Marshal(raw)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < len(raw); i += 7 {
+				delta, _ := json.Marshal(raw[i:min(i+7, len(raw))])
+				fmt.Fprintf(w, "data: {\"id\":\"1\",\"model\":\"gpt-oss-20b\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%s}}]}\n\n", delta)
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"1","model":"gpt-oss-20b","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%s}}]}`, content)
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{BaseURL: srv.URL, DefaultModel: "openai/gpt-oss-20b"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	check := func(res *chat.Result) {
+		t.Helper()
+		if res.Text != "" || res.ReasoningText != "Need weather." || res.FinishReason != chat.FinishReasonToolCalls {
+			t.Fatalf("unexpected result %+v", res)
+		}
+		if len(res.ToolCalls) != 1 || res.ToolCalls[0].Function.Name != "get_weather" || res.ToolCalls[0].Function.Arguments != `{"city":"Oslo"}` {
+			t.Fatalf("unexpected tool calls %+v", res.ToolCalls)
+		}
+	}
+
+	res, err := p.Chat(context.Background(), &chat.Request{Messages: []chat.Message{chat.User("weather in Oslo?")}})
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	check(res)
+
+	var deltas, reasoning strings.Builder
+	var streamed []chat.ToolCall
+	res, err = p.Chat(context.Background(), &chat.Request{
+		Messages: []chat.Message{chat.User("weather in Oslo?")},
+		Options: chat.Options{OnStream: func(ev chat.StreamEvent) error {
+			deltas.WriteString(ev.Delta)
+			reasoning.WriteString(ev.ReasoningDelta)
+			if ev.ToolCall != nil {
+				streamed = append(streamed, *ev.ToolCall)
+			}
+			return nil
+		}},
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	check(res)
+	if deltas.String() != "" || reasoning.String() != "Need weather." || len(streamed) != 1 {
+		t.Fatalf("unexpected stream %q / %q / %+v", deltas.String(), reasoning.String(), streamed)
+	}
+}
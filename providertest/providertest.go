@@ -0,0 +1,359 @@
+// Package providertest is a conformance suite for chat.Provider
+// implementations. It checks roles, tool calls, option mapping, error
+// handling and streaming the same way for every provider, in-tree or not:
+//
+//	func TestConformance(t *testing.T) {
+//		if os.Getenv("ACME_API_KEY") == "" {
+//			t.Skip("ACME_API_KEY not set")
+//		}
+//		p, _ := acme.New(acme.Config{APIKey: os.Getenv("ACME_API_KEY")})
+//		providertest.Run(t, providertest.Config{Provider: p, Model: "acme-small"})
+//	}
+//
+// The requests go to a real model, so checks are structural and the
+// prompts leave the model no room for interpretation. Capabilities a
+// provider lacks are listed in Config.Skip.
+package providertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Capabilities that Config.Skip can name.
+const (
+	Streaming = "streaming"
+	Tools     = "tools"
+	StopWords = "stop_words"
+	MaxTokens = "max_tokens"
+	// Usage is token counts in Result.Usage and on the final stream event.
+	Usage = "usage"
+	// UnknownModel is rejecting a model the backend does not serve.
+	UnknownModel = "unknown_model"
+)
+
+// Config describes the provider under test.
+type Config struct {
+	Provider chat.Provider
+	// Model is sent with every request; empty uses the provider's default.
+	Model string
+	// Skip names capabilities the provider does not support.
+	Skip []string
+	// Timeout bounds each call (default 1 minute).
+	Timeout time.Duration
+}
+
+func (cfg Config) supports(capability string) bool {
+	for _, s := range cfg.Skip {
+		if s == capability {
+			return false
+		}
+	}
+	return true
+}
+
+// Run runs the suite as subtests of t.
+func Run(t *testing.T, cfg Config) {
+	t.Helper()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Minute
+	}
+	s := &suite{cfg: cfg}
+	t.Run("roles", s.roles)
+	t.Run("tools", s.tools)
+	t.Run("max_tokens", s.maxTokens)
+	t.Run("stop_words", s.stopWords)
+	t.Run("errors", s.failures)
+	t.Run("streaming", s.streaming)
+	t.Run("streaming_tools", s.streamingTools)
+	t.Run("stream_abort", s.streamAbort)
+}
+
+type suite struct {
+	cfg Config
+}
+
+func (s *suite) require(t *testing.T, capabilities ...string) {
+	t.Helper()
+	for _, c := range capabilities {
+		if !s.cfg.supports(c) {
+			t.Skipf("provider does not support %s", c)
+		}
+	}
+}
+
+// chat builds a request from opts and sends it.
+func (s *suite) chat(t *testing.T, ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	t.Helper()
+	if s.cfg.Model != "" {
+		opts = append([]chat.Option{chat.WithModel(s.cfg.Model)}, opts...)
+	}
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	return s.cfg.Provider.Chat(ctx, req)
+}
+
+func (s *suite) mustChat(t *testing.T, opts ...chat.Option) *chat.Result {
+	t.Helper()
+	res, err := s.chat(t, context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if res == nil {
+		t.Fatal("chat returned no result and no error")
+	}
+	return res
+}
+
+// roles sends a system prompt and a multi-turn history.
+func (s *suite) roles(t *testing.T) {
+	res := s.mustChat(t, chat.WithMessages(
+		chat.System("Reply with the word the user sends, in lowercase, and nothing else."),
+		chat.User("Alpha"),
+		chat.Assistant("alpha"),
+		chat.User("Bravo"),
+	))
+	if !strings.Contains(strings.ToLower(res.Text), "bravo") {
+		t.Errorf("reply %q does not follow the conversation", res.Text)
+	}
+	if got := chat.NormalizeFinishReason(res.FinishReason); got != chat.FinishReasonStop {
+		t.Errorf("finish reason %q normalizes to %q, want %q", res.FinishReason, got, chat.FinishReasonStop)
+	}
+	if len(res.ToolCalls) > 0 {
+		t.Errorf("unexpected tool calls without tools: %+v", res.ToolCalls)
+	}
+	if s.cfg.supports(Usage) {
+		checkUsage(t, res.Usage)
+	}
+}
+
+var weatherTool = chat.FunctionTool("get_weather", "Get the current weather for a city.",
+	[]byte(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`))
+
+func weatherOptions() []chat.Option {
+	return []chat.Option{
+		chat.WithMessages(chat.User("What is the weather in Paris right now?")),
+		chat.WithTools([]chat.Tool{weatherTool}),
+		chat.WithToolChoice(chat.ToolChoiceFunction("get_weather")),
+	}
+}
+
+// tools forces a tool call and sends its result back.
+func (s *suite) tools(t *testing.T) {
+	s.require(t, Tools)
+	res := s.mustChat(t, weatherOptions()...)
+	call := checkWeatherCall(t, res.ToolCalls)
+	if got := chat.NormalizeFinishReason(res.FinishReason); got != chat.FinishReasonToolCalls {
+		t.Errorf("finish reason %q normalizes to %q, want %q", res.FinishReason, got, chat.FinishReasonToolCalls)
+	}
+
+	msgs := append([]chat.Message{chat.User("What is the weather in Paris right now?")}, chat.AssistantTurn(res.Text, res.ToolCalls)...)
+	msgs = append(msgs, chat.ToolResult(call.ID, `{"city":"Paris","temperature_c":21,"sky":"clear"}`))
+	final := s.mustChat(t, chat.WithMessages(msgs...), chat.WithTools([]chat.Tool{weatherTool}))
+	if !strings.Contains(final.Text, "21") {
+		t.Errorf("reply %q does not use the tool result", final.Text)
+	}
+}
+
+func checkWeatherCall(t *testing.T, calls []chat.ToolCall) chat.ToolCall {
+	t.Helper()
+	if len(calls) == 0 {
+		t.Fatal("no tool call for a forced tool choice")
+	}
+	call := calls[0]
+	if call.Function.Name != "get_weather" {
+		t.Errorf("tool call name %q, want get_weather", call.Function.Name)
+	}
+	if call.ID == "" {
+		t.Error("tool call has no ID")
+	}
+	var args map[string]any
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+		t.Fatalf("tool call arguments %q are not a JSON object: %v", call.Function.Arguments, err)
+	}
+	if city, _ := args["city"].(string); !strings.Contains(strings.ToLower(city), "paris") {
+		t.Errorf("tool call arguments %s lack the city", call.Function.Arguments)
+	}
+	return call
+}
+
+// maxTokens checks that max_tokens is mapped and reported as a length
+// finish.
+func (s *suite) maxTokens(t *testing.T) {
+	s.require(t, MaxTokens)
+	res := s.mustChat(t,
+		chat.WithMessages(chat.User("Count from 1 to 500, separated by spaces.")),
+		chat.WithMaxTokens(16),
+	)
+	if got := chat.NormalizeFinishReason(res.FinishReason); got != chat.FinishReasonLength {
+		t.Errorf("finish reason %q normalizes to %q, want %q", res.FinishReason, got, chat.FinishReasonLength)
+	}
+	if s.cfg.supports(Usage) && res.Usage.OutputTokens > 16 {
+		t.Errorf("%d output tokens for max_tokens 16", res.Usage.OutputTokens)
+	}
+}
+
+func (s *suite) stopWords(t *testing.T) {
+	s.require(t, StopWords)
+	res := s.mustChat(t,
+		chat.WithMessages(chat.User("Count from 1 to 10, separated by spaces, and nothing else.")),
+		chat.WithStopWords(" 6"),
+	)
+	if !strings.Contains(res.Text, "5") || strings.Contains(res.Text, "7") {
+		t.Errorf("reply %q does not stop at the stop word", res.Text)
+	}
+}
+
+func (s *suite) failures(t *testing.T) {
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		res, err := s.chat(t, ctx, chat.WithMessages(chat.User("Hi")))
+		if err == nil {
+			t.Fatalf("no error for a canceled context, got %+v", res)
+		}
+		if class := chat.ClassifyError(err); class != chat.ErrorClassCanceled {
+			t.Errorf("error %v classifies as %q, want %q", err, class, chat.ErrorClassCanceled)
+		}
+	})
+	t.Run("unknown_model", func(t *testing.T) {
+		s.require(t, UnknownModel)
+		res, err := s.chat(t, context.Background(), chat.WithMessages(chat.User("Hi")), chat.WithModel("providertest-no-such-model"))
+		if err == nil {
+			t.Fatalf("no error for an unknown model, got %+v", res)
+		}
+		if class := chat.ClassifyError(err); class == chat.ErrorClassCanceled || class == chat.ErrorClassTimeout {
+			t.Errorf("error %v classifies as %q", err, class)
+		}
+	})
+}
+
+// recorder collects stream events and checks their order.
+type recorder struct {
+	events []chat.StreamEvent
+}
+
+func (r *recorder) onStream(ev chat.StreamEvent) error {
+	r.events = append(r.events, ev)
+	return nil
+}
+
+func (r *recorder) check(t *testing.T, res *chat.Result, usage bool) {
+	t.Helper()
+	var text strings.Builder
+	done := 0
+	for i, ev := range r.events {
+		text.WriteString(ev.Delta)
+		if ev.Done {
+			done++
+			if i != len(r.events)-1 {
+				t.Errorf("Done event %d of %d is not the last", i+1, len(r.events))
+			}
+			if usage {
+				if ev.Usage == nil {
+					t.Error("Done event has no usage")
+				} else {
+					checkUsage(t, *ev.Usage)
+				}
+			}
+		}
+	}
+	if done != 1 {
+		t.Errorf("%d Done events, want 1", done)
+	}
+	if text.String() != res.Text {
+		t.Errorf("streamed text %q differs from Result.Text %q", text.String(), res.Text)
+	}
+}
+
+func (s *suite) streaming(t *testing.T) {
+	s.require(t, Streaming)
+	var rec recorder
+	res := s.mustChat(t,
+		chat.WithMessages(chat.User("Write the days of the week, separated by commas.")),
+		chat.WithOnStream(rec.onStream),
+	)
+	rec.check(t, res, s.cfg.supports(Usage))
+	deltas := 0
+	for _, ev := range rec.events {
+		if ev.Delta != "" {
+			deltas++
+		}
+	}
+	if deltas < 2 {
+		t.Errorf("reply arrived in %d deltas; the provider does not seem to stream", deltas)
+	}
+}
+
+func (s *suite) streamingTools(t *testing.T) {
+	s.require(t, Streaming, Tools)
+	var rec recorder
+	res := s.mustChat(t, append(weatherOptions(), chat.WithOnStream(rec.onStream))...)
+	rec.check(t, res, s.cfg.supports(Usage))
+	checkWeatherCall(t, res.ToolCalls)
+
+	var acc chat.ToolCallAccumulator
+	var complete []chat.ToolCall
+	for _, ev := range rec.events {
+		if ev.ToolCallDelta != nil {
+			acc.Add(*ev.ToolCallDelta)
+		}
+		if ev.ToolCall != nil {
+			complete = append(complete, *ev.ToolCall)
+		}
+	}
+	if len(complete) != len(res.ToolCalls) {
+		t.Fatalf("%d ToolCall events for %d tool calls", len(complete), len(res.ToolCalls))
+	}
+	deltas := acc.Calls()
+	for i, call := range res.ToolCalls {
+		if complete[i].ID != call.ID || complete[i].Function != call.Function {
+			t.Errorf("ToolCall event %+v differs from Result.ToolCalls[%d] %+v", complete[i], i, call)
+		}
+		if i < len(deltas) && deltas[i].Function.Arguments != call.Function.Arguments {
+			t.Errorf("tool call deltas assemble to %q, want %q", deltas[i].Function.Arguments, call.Function.Arguments)
+		}
+	}
+}
+
+var errAbort = errors.New("providertest: stream aborted")
+
+// streamAbort checks that an error returned by OnStream stops the stream
+// and is returned by Chat.
+func (s *suite) streamAbort(t *testing.T) {
+	s.require(t, Streaming)
+	calls := 0
+	_, err := s.chat(t, context.Background(),
+		chat.WithMessages(chat.User("Write the days of the week, separated by commas.")),
+		chat.WithOnStream(func(ev chat.StreamEvent) error {
+			calls++
+			return errAbort
+		}),
+	)
+	if !errors.Is(err, errAbort) {
+		t.Errorf("Chat returned %v, want the error returned by OnStream", err)
+	}
+	if calls != 1 {
+		t.Errorf("OnStream called %d times after returning an error", calls)
+	}
+}
+
+func checkUsage(t *testing.T, u chat.Usage) {
+	t.Helper()
+	if u.InputTokens <= 0 || u.OutputTokens <= 0 {
+		t.Errorf("usage %+v lacks token counts", u)
+	}
+	if u.TotalTokens != 0 && u.TotalTokens < u.InputTokens+u.OutputTokens {
+		t.Errorf("usage %+v: total is below input plus output", u)
+	}
+}
@@ -0,0 +1,203 @@
+package providertest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+	"github.com/quailyquaily/uniai/providers/openai"
+)
+
+// reference is a provider that answers the suite's prompts the way a
+// conforming model would.
+type reference struct{}
+
+func (reference) Chat(ctx context.Context, req *chat.Request) (*chat.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if req.Model == "providertest-no-such-model" {
+		return nil, fmt.Errorf("reference: status 404: model not found")
+	}
+	last := req.Messages[len(req.Messages)-1]
+	res := &chat.Result{FinishReason: "end_turn"}
+	var words []string
+	switch {
+	case req.ToolChoice != nil && req.ToolChoice.Mode == "function":
+		res.ToolCalls = []chat.ToolCall{{ID: "toolu_1", Type: "function", Function: chat.ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}}}
+		res.FinishReason = "tool_use"
+	case last.Role == chat.RoleTool:
+		words = []string{"It", "is", "21°C", "and", "clear."}
+	case strings.HasPrefix(last.Content, "Count from 1 to "):
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.Fields(last.Content)[4], ","))
+		for i := 1; i <= n; i++ {
+			words = append(words, strconv.Itoa(i))
+		}
+	case strings.HasPrefix(last.Content, "Write the days"):
+		words = []string{"Monday,", "Tuesday,", "Wednesday,", "Thursday,", "Friday,", "Saturday,", "Sunday"}
+	default:
+		words = []string{strings.ToLower(last.Content)}
+	}
+	text := strings.Join(words, " ")
+	for _, stop := range req.Options.Stop {
+		if i := strings.Index(text, stop); i >= 0 {
+			text = text[:i]
+		}
+	}
+	if max := req.Options.MaxTokens; max != nil && len(words) > *max {
+		text = strings.Join(words[:*max], " ")
+		res.FinishReason = "max_tokens"
+	}
+	res.Text = text
+	res.Usage = chat.Usage{InputTokens: len(req.Messages), OutputTokens: max(1, min(len(words), 16))}
+	res.Usage.TotalTokens = res.Usage.InputTokens + res.Usage.OutputTokens
+
+	if emit := req.Options.OnStream; emit != nil {
+		for i, w := range strings.SplitAfter(text, " ") {
+			if w == "" {
+				continue
+			}
+			if err := emit(chat.StreamEvent{Delta: w}); err != nil {
+				return nil, fmt.Errorf("stream chunk %d: %w", i, err)
+			}
+		}
+		for i, call := range res.ToolCalls {
+			if err := emit(chat.StreamEvent{ToolCallDelta: &chat.ToolCallDelta{Index: i, ID: call.ID, Name: call.Function.Name, ArgsChunk: call.Function.Arguments}}); err != nil {
+				return nil, err
+			}
+			if err := emit(chat.StreamEvent{ToolCall: &res.ToolCalls[i]}); err != nil {
+				return nil, err
+			}
+		}
+		if err := emit(chat.StreamEvent{Done: true, Usage: &res.Usage}); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func TestReferenceProviderConforms(t *testing.T) {
+	Run(t, Config{Provider: reference{}, Model: "reference-1"})
+}
+
+func TestFakeProviderConforms(t *testing.T) {
+	usage := func(out int) *chat.Usage {
+		return &chat.Usage{InputTokens: 12, OutputTokens: out, TotalTokens: 12 + out}
+	}
+	weather := fake.Response{
+		Chunks:       []fake.Chunk{{ToolCall: &chat.ToolCall{ID: "call_1", Type: "function", Function: chat.ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}}}},
+		FinishReason: chat.FinishReasonToolCalls,
+		Usage:        usage(9),
+	}
+	days := fake.Text("Monday, Tuesday, Wednesday, Thursday, Friday, Saturday, Sunday", 9, 0)
+	days.Usage = usage(14)
+	// the replies in the order the suite asks for them
+	responses := []fake.Response{
+		{Chunks: []fake.Chunk{{Text: "bravo"}}, FinishReason: chat.FinishReasonStop, Usage: usage(1)},
+		weather,
+		{Chunks: []fake.Chunk{{Text: "It is 21°C and clear in Paris."}}, FinishReason: chat.FinishReasonStop, Usage: usage(10)},
+		{Chunks: []fake.Chunk{{Text: "1 2 3 4 5 6 7 8"}}, FinishReason: chat.FinishReasonLength, Usage: usage(16)},
+		{Chunks: []fake.Chunk{{Text: "1 2 3 4 5"}}, FinishReason: chat.FinishReasonStop, Usage: usage(9)},
+		days,
+		weather,
+		days,
+	}
+	Run(t, Config{Provider: fake.New(fake.Config{Responses: responses}), Skip: []string{UnknownModel}})
+}
+
+// openAIRecordings are replies of the Chat Completions API to the suite's
+// requests, keyed by the kind of request.
+var openAIRecordings = map[string]string{
+	"roles":       `{"id":"chatcmpl-1","object":"chat.completion","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"message":{"role":"assistant","content":"bravo"},"finish_reason":"stop"}],"usage":{"prompt_tokens":31,"completion_tokens":2,"total_tokens":33}}`,
+	"tool_call":   `{"id":"chatcmpl-2","object":"chat.completion","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_Kq1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":64,"completion_tokens":15,"total_tokens":79}}`,
+	"tool_result": `{"id":"chatcmpl-3","object":"chat.completion","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"message":{"role":"assistant","content":"It is currently 21°C with clear skies in Paris."},"finish_reason":"stop"}],"usage":{"prompt_tokens":98,"completion_tokens":13,"total_tokens":111}}`,
+	"max_tokens":  `{"id":"chatcmpl-4","object":"chat.completion","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"message":{"role":"assistant","content":"1 2 3 4 5 6 7 8 9"},"finish_reason":"length"}],"usage":{"prompt_tokens":20,"completion_tokens":16,"total_tokens":36}}`,
+	"stop":        `{"id":"chatcmpl-5","object":"chat.completion","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"message":{"role":"assistant","content":"1 2 3 4 5"},"finish_reason":"stop"}],"usage":{"prompt_tokens":22,"completion_tokens":9,"total_tokens":31}}`,
+	"not_found":   `{"error":{"message":"The model 'providertest-no-such-model' does not exist or you do not have access to it.","type":"invalid_request_error","param":null,"code":"model_not_found"}}`,
+	"stream": `data: {"id":"chatcmpl-6","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-6","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"delta":{"content":"Monday, Tuesday,"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-6","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"delta":{"content":" Wednesday, Thursday,"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-6","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"delta":{"content":" Friday, Saturday, Sunday"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-6","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: {"id":"chatcmpl-6","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[],"usage":{"prompt_tokens":17,"completion_tokens":14,"total_tokens":31}}
+
+data: [DONE]
+
+`,
+	"stream_tool_call": `data: {"id":"chatcmpl-7","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"delta":{"role":"assistant","content":null,"tool_calls":[{"index":0,"id":"call_Zp2","type":"function","function":{"name":"get_weather","arguments":""}}]},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-7","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-7","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-7","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}
+
+data: {"id":"chatcmpl-7","object":"chat.completion.chunk","created":1760000000,"model":"gpt-4.1-mini","choices":[],"usage":{"prompt_tokens":64,"completion_tokens":15,"total_tokens":79}}
+
+data: [DONE]
+
+`,
+}
+
+// openAIRecording picks the recording that answers the request body.
+func openAIRecording(body map[string]any) (status int, recording string) {
+	msgs, _ := body["messages"].([]any)
+	last, _ := msgs[len(msgs)-1].(map[string]any)
+	stream, _ := body["stream"].(bool)
+	switch {
+	case body["model"] == "providertest-no-such-model":
+		return http.StatusNotFound, "not_found"
+	case stream && body["tool_choice"] != nil:
+		return http.StatusOK, "stream_tool_call"
+	case stream:
+		return http.StatusOK, "stream"
+	case body["tool_choice"] != nil:
+		return http.StatusOK, "tool_call"
+	case last["role"] == "tool":
+		return http.StatusOK, "tool_result"
+	case body["max_completion_tokens"] != nil || body["max_tokens"] != nil:
+		return http.StatusOK, "max_tokens"
+	case body["stop"] != nil:
+		return http.StatusOK, "stop"
+	}
+	return http.StatusOK, "roles"
+}
+
+func TestOpenAIProviderConforms(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected request %s: %v", r.URL.Path, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		status, name := openAIRecording(body)
+		if strings.HasPrefix(name, "stream") {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, openAIRecordings[name])
+	}))
+	defer srv.Close()
+
+	p, err := openai.New(openai.Config{APIKey: "test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Run(t, Config{Provider: p, Model: "gpt-4.1-mini"})
+}
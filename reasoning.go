@@ -0,0 +1,43 @@
+package uniai
+
+import (
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// applyReasoningSeparation implements req.Options.SeparateReasoning: stream
+// deltas are split into answer and reasoning as they arrive, and the
+// returned function moves the reasoning in the final reply text to
+// ReasoningText. Streams of models that only close the think block, see
+// chat.PreopensThink, start as reasoning.
+func applyReasoningSeparation(model string, req *chat.Request) (*chat.Request, func(*chat.Result)) {
+	if !req.Options.SeparateReasoning {
+		return req, func(*chat.Result) {}
+	}
+	out := *req
+	if onStream := req.Options.OnStream; onStream != nil {
+		splitter := chat.ReasoningSplitter{Open: chat.PreopensThink(model)}
+		out.Options.OnStream = func(ev chat.StreamEvent) error {
+			answer, reasoning := splitter.Write(ev.Delta)
+			if ev.Done {
+				a, r := splitter.Flush()
+				answer, reasoning = answer+a, reasoning+r
+			}
+			ev.Delta = answer
+			ev.ReasoningDelta += reasoning
+			if ev == (chat.StreamEvent{}) {
+				return nil
+			}
+			return onStream(ev)
+		}
+	}
+	return &out, func(resp *chat.Result) {
+		answer, reasoning := chat.SplitReasoning(resp.Text)
+		if reasoning == "" {
+			return
+		}
+		setReplyText(resp, answer)
+		resp.ReasoningText = strings.TrimSpace(resp.ReasoningText + "\n" + reasoning)
+	}
+}
@@ -0,0 +1,67 @@
+package uniai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestSeparateReasoning(t *testing.T) {
+	client := New(Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{
+		fake.Text("<think>the user wants a number</think>42", 3, 0),
+	}}))
+
+	var answer, reasoning strings.Builder
+	resp, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User("pick a number")),
+		WithSeparateReasoning(),
+		WithOnStream(func(ev chat.StreamEvent) error {
+			answer.WriteString(ev.Delta)
+			reasoning.WriteString(ev.ReasoningDelta)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Text != "42" || resp.ReasoningText != "the user wants a number" || resp.Messages[0].Content != "42" {
+		t.Fatalf("unexpected result %+v", resp)
+	}
+	if answer.String() != "42" || reasoning.String() != "the user wants a number" {
+		t.Fatalf("unexpected stream %q / %q", answer.String(), reasoning.String())
+	}
+}
+
+func TestSeparateReasoningPreopened(t *testing.T) {
+	client := New(Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{
+		fake.Text("add them</think>4", 2, 0),
+	}}))
+
+	var answer, reasoning strings.Builder
+	resp, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithModel("deepseek-r1-distill-qwen-32b"),
+		WithMessages(User("2+2?")),
+		WithSeparateReasoning(),
+		WithOnStream(func(ev chat.StreamEvent) error {
+			answer.WriteString(ev.Delta)
+			reasoning.WriteString(ev.ReasoningDelta)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Text != "4" || resp.ReasoningText != "add them" {
+		t.Fatalf("unexpected result %+v", resp)
+	}
+	if answer.String() != "4" || reasoning.String() != "add them" {
+		t.Fatalf("unexpected stream %q / %q", answer.String(), reasoning.String())
+	}
+}
@@ -0,0 +1,376 @@
+// Package redact replaces personal data in chat requests with placeholders
+// such as [EMAIL_1] before they reach a provider, and puts the original
+// values back into the response. The placeholder mapping is built per call
+// and kept only in memory.
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Chatter is the subset of uniai.Client a Redactor needs.
+type Chatter interface {
+	Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error)
+}
+
+// Match is a span of text a Detector found, as byte offsets.
+type Match struct {
+	Start, End int
+	// Kind names the placeholder, e.g. "EMAIL".
+	Kind string
+}
+
+// Detector finds personal data in text.
+type Detector interface {
+	Detect(text string) []Match
+}
+
+type DetectorFunc func(text string) []Match
+
+func (f DetectorFunc) Detect(text string) []Match { return f(text) }
+
+// Pattern detects every match of Regexp as Kind.
+type Pattern struct {
+	Kind   string
+	Regexp *regexp.Regexp
+}
+
+func (p Pattern) Detect(text string) []Match {
+	var out []Match
+	for _, loc := range p.Regexp.FindAllStringIndex(text, -1) {
+		out = append(out, Match{Start: loc[0], End: loc[1], Kind: p.Kind})
+	}
+	return out
+}
+
+// Built-in detectors.
+var (
+	Email      = Pattern{Kind: "EMAIL", Regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)}
+	Phone      = Pattern{Kind: "PHONE", Regexp: regexp.MustCompile(`\+?\d{1,3}?[ .-]?\(?\d{3}\)?[ .-]?\d{3}[ .-]?\d{4}\b`)}
+	SSN        = Pattern{Kind: "SSN", Regexp: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)}
+	CreditCard = Pattern{Kind: "CARD", Regexp: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)}
+	IPAddress  = Pattern{Kind: "IP", Regexp: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)}
+)
+
+// DefaultDetectors are used when a Redactor has none configured.
+func DefaultDetectors() []Detector {
+	return []Detector{Email, SSN, CreditCard, Phone, IPAddress}
+}
+
+// Names detects the given names, such as the users and contacts an
+// application knows about, as whole words and ignoring case. Names cannot
+// be found reliably by pattern, so there is no built-in name detector.
+func Names(names ...string) Detector {
+	var quoted []string
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			quoted = append(quoted, regexp.QuoteMeta(n))
+		}
+	}
+	if len(quoted) == 0 {
+		return DetectorFunc(func(string) []Match { return nil })
+	}
+	// longest first, so "Ann Lee" wins over "Ann"
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return Pattern{Kind: "NAME", Regexp: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)}
+}
+
+// Redactor wraps a Chatter, redacting message contents and tool call
+// arguments on the way out and restoring the result's text, tool calls,
+// messages and stream deltas on the way back. Raw provider responses are
+// left as received.
+type Redactor struct {
+	Client Chatter
+	// Detectors default to DefaultDetectors.
+	Detectors []Detector
+}
+
+func New(client Chatter, detectors ...Detector) *Redactor {
+	return &Redactor{Client: client, Detectors: detectors}
+}
+
+// Chat redacts the request, sends it and restores the response.
+func (r *Redactor) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	detectors := r.Detectors
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+	m := NewMapping(detectors...)
+	msgs := make([]chat.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		msg.Content = m.Redact(msg.Content)
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]chat.ToolCall, len(msg.ToolCalls))
+			for j, tc := range msg.ToolCalls {
+				tc.Function.Arguments = m.RedactJSON(tc.Function.Arguments)
+				calls[j] = tc
+			}
+			msg.ToolCalls = calls
+		}
+		msgs[i] = msg
+	}
+
+	callOpts := append(append([]chat.Option{}, opts...), chat.WithReplaceMessages(msgs...))
+	var tokens *restorer
+	if onToken := req.Options.OnToken; onToken != nil {
+		tokens = &restorer{m: m}
+		callOpts = append(callOpts, chat.WithOnToken(func(delta string) {
+			if s := tokens.feed(delta); s != "" {
+				onToken(s)
+			}
+		}))
+	}
+	if onEvent := req.Options.OnEvent; onEvent != nil {
+		events := &restorer{m: m}
+		callOpts = append(callOpts, chat.WithOnEvent(func(ev chat.StreamEvent) {
+			onEvent(events.event(ev))
+		}))
+	}
+	if onStream := req.Options.OnStream; onStream != nil {
+		stream := &restorer{m: m}
+		callOpts = append(callOpts, chat.WithOnStream(func(ev chat.StreamEvent) error {
+			return onStream(stream.event(ev))
+		}))
+	}
+
+	resp, err := r.Client.Chat(ctx, callOpts...)
+	if tokens != nil {
+		if s := tokens.flush(); s != "" {
+			req.Options.OnToken(s)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := *resp
+	out.Text = m.Restore(resp.Text)
+	out.ReasoningText = m.Restore(resp.ReasoningText)
+	out.ToolCalls = m.restoreCalls(resp.ToolCalls)
+	if resp.Messages != nil {
+		out.Messages = make([]chat.Message, len(resp.Messages))
+		for i, msg := range resp.Messages {
+			msg.Content = m.Restore(msg.Content)
+			msg.Refusal = m.Restore(msg.Refusal)
+			msg.ToolCalls = m.restoreCalls(msg.ToolCalls)
+			out.Messages[i] = msg
+		}
+	}
+	return &out, nil
+}
+
+// Mapping assigns placeholders to detected values and restores them. The
+// same value always gets the same placeholder. It is safe for concurrent
+// use.
+type Mapping struct {
+	detectors []Detector
+
+	mu      sync.Mutex
+	values  map[string]string // placeholder to value
+	holders map[string]string // kind and value to placeholder
+	counts  map[string]int
+}
+
+func NewMapping(detectors ...Detector) *Mapping {
+	return &Mapping{
+		detectors: detectors,
+		values:    map[string]string{},
+		holders:   map[string]string{},
+		counts:    map[string]int{},
+	}
+}
+
+// Redact replaces everything the detectors find in text with placeholders.
+// Overlapping matches are resolved in favour of the earliest, then the
+// longest.
+func (m *Mapping) Redact(text string) string {
+	return Replace(text, m.detectors, m.placeholder)
+}
+
+// RedactJSON redacts the string values of a JSON document, leaving its
+// structure intact. Text that is not valid JSON is redacted as plain text.
+func (m *Mapping) RedactJSON(text string) string {
+	return MapJSON(text, m.Redact)
+}
+
+// Replace replaces everything the detectors find in text with what repl
+// returns for it. Overlapping matches are resolved in favour of the
+// earliest, then the longest.
+func Replace(text string, detectors []Detector, repl func(kind, value string) string) string {
+	if text == "" {
+		return text
+	}
+	var matches []Match
+	for _, d := range detectors {
+		matches = append(matches, d.Detect(text)...)
+	}
+	if len(matches) == 0 {
+		return text
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+	var b strings.Builder
+	pos := 0
+	for _, match := range matches {
+		if match.Start < pos || match.End <= match.Start || match.End > len(text) {
+			continue
+		}
+		b.WriteString(text[pos:match.Start])
+		b.WriteString(repl(match.Kind, text[match.Start:match.End]))
+		pos = match.End
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
+
+// MapJSON applies f to the string values of a JSON document, leaving its
+// structure intact. Text that is not valid JSON is passed to f whole.
+func MapJSON(text string, f func(string) string) string {
+	var v any
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return f(text)
+	}
+	data, err := json.Marshal(mapValue(v, f))
+	if err != nil {
+		return f(text)
+	}
+	return string(data)
+}
+
+func mapValue(v any, f func(string) string) any {
+	switch val := v.(type) {
+	case string:
+		return f(val)
+	case []any:
+		for i := range val {
+			val[i] = mapValue(val[i], f)
+		}
+	case map[string]any:
+		for k := range val {
+			val[k] = mapValue(val[k], f)
+		}
+	}
+	return v
+}
+
+var placeholderPattern = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_\d+\]`)
+
+// maxPlaceholder bounds the length of a placeholder, for stream buffering.
+const maxPlaceholder = 40
+
+// Restore replaces known placeholders in text with their values. Unknown
+// placeholders are left alone.
+func (m *Mapping) Restore(text string) string {
+	if !strings.Contains(text, "[") {
+		return text
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return placeholderPattern.ReplaceAllStringFunc(text, func(p string) string {
+		if v, ok := m.values[p]; ok {
+			return v
+		}
+		return p
+	})
+}
+
+func (m *Mapping) restoreCalls(calls []chat.ToolCall) []chat.ToolCall {
+	if len(calls) == 0 {
+		return calls
+	}
+	out := make([]chat.ToolCall, len(calls))
+	for i, tc := range calls {
+		tc.Function.Arguments = m.restoreJSON(tc.Function.Arguments)
+		out[i] = tc
+	}
+	return out
+}
+
+// restoreJSON restores placeholders inside JSON strings, escaping the
+// values as needed.
+func (m *Mapping) restoreJSON(text string) string {
+	if !strings.Contains(text, "[") {
+		return text
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return placeholderPattern.ReplaceAllStringFunc(text, func(p string) string {
+		v, ok := m.values[p]
+		if !ok {
+			return p
+		}
+		quoted, _ := json.Marshal(v)
+		return string(quoted[1 : len(quoted)-1])
+	})
+}
+
+func (m *Mapping) placeholder(kind, value string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := kind + "\x00" + value
+	if p, ok := m.holders[key]; ok {
+		return p
+	}
+	m.counts[kind]++
+	p := "[" + kind + "_" + strconv.Itoa(m.counts[kind]) + "]"
+	m.holders[key] = p
+	m.values[p] = value
+	return p
+}
+
+// restorer restores placeholders in streamed text, holding back a trailing
+// "[" until it is known whether a placeholder follows. Tool call deltas
+// are passed through; complete tool calls are restored.
+type restorer struct {
+	m       *Mapping
+	pending string
+}
+
+func (r *restorer) feed(delta string) string {
+	text := r.pending + delta
+	r.pending = ""
+	if i := strings.LastIndexByte(text, '['); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i < maxPlaceholder {
+		text, r.pending = text[:i], text[i:]
+	}
+	return r.m.Restore(text)
+}
+
+func (r *restorer) flush() string {
+	text := r.pending
+	r.pending = ""
+	return r.m.Restore(text)
+}
+
+func (r *restorer) event(ev chat.StreamEvent) chat.StreamEvent {
+	if ev.Delta != "" {
+		ev.Delta = r.feed(ev.Delta)
+	}
+	if ev.Done {
+		ev.Delta += r.flush()
+	}
+	if ev.ReasoningDelta != "" {
+		ev.ReasoningDelta = r.m.Restore(ev.ReasoningDelta)
+	}
+	if ev.ToolCall != nil {
+		calls := r.m.restoreCalls([]chat.ToolCall{*ev.ToolCall})
+		ev.ToolCall = &calls[0]
+	}
+	return ev
+}
@@ -0,0 +1,62 @@
+package redact
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+type echoClient struct {
+	seen []chat.Message
+}
+
+// Chat streams the last user message back in small chunks and calls a tool
+// with it.
+func (e *echoClient) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	e.seen = req.Messages
+	text := "You said: " + req.Messages[len(req.Messages)-1].Content
+	if req.Options.OnToken != nil {
+		for i := 0; i < len(text); i += 3 {
+			req.Options.OnToken(text[i:min(i+3, len(text))])
+		}
+	}
+	call := chat.ToolCall{ID: "1", Type: "function", Function: chat.ToolCallFunction{Name: "mail", Arguments: `{"to":"[EMAIL_1]"}`}}
+	return &chat.Result{Text: text, ToolCalls: []chat.ToolCall{call}}, nil
+}
+
+func TestRedactor(t *testing.T) {
+	client := &echoClient{}
+	r := New(client, Email, Names("Ann Lee"))
+	var streamed strings.Builder
+	resp, err := r.Chat(context.Background(),
+		chat.WithMessages(chat.User(`Mail ann@example.com, signed Ann Lee`)),
+		chat.WithOnToken(func(delta string) { streamed.WriteString(delta) }),
+	)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if sent := client.seen[0].Content; sent != "Mail [EMAIL_1], signed [NAME_1]" {
+		t.Fatalf("not redacted: %q", sent)
+	}
+	want := "You said: Mail ann@example.com, signed Ann Lee"
+	if resp.Text != want || streamed.String() != want {
+		t.Fatalf("not restored: %q / %q", resp.Text, streamed.String())
+	}
+	if args := resp.ToolCalls[0].Function.Arguments; args != `{"to":"ann@example.com"}` {
+		t.Fatalf("tool call not restored: %s", args)
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	m := NewMapping(Email)
+	got := m.RedactJSON(`{"id":12345678901234567890,"cc":["a@b.io"]}`)
+	if got != `{"cc":["[EMAIL_1]"],"id":12345678901234567890}` {
+		t.Fatalf("unexpected %s", got)
+	}
+}
@@ -0,0 +1,165 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+const (
+	DefaultMinScore = 7
+
+	defaultJudgePrompt = "You grade answers. Given a conversation and a candidate reply to its last message, " +
+		"rate how correct, complete and helpful the reply is from 1 (useless) to 10 (excellent). " +
+		"Respond with the number only."
+)
+
+// Cascade sends a request to the cheapest tier first and escalates to the
+// next tier only when the answer fails validation or the call fails. When
+// every tier fails validation, the last answer is returned with Passed
+// false.
+type Cascade struct {
+	Client Chatter
+	// Tiers are ordered from cheapest to strongest.
+	Tiers []Target
+	// Validate, if set, accepts or rejects an answer. It takes precedence
+	// over Judge.
+	Validate func(ctx context.Context, req *chat.Request, resp *chat.Result) (bool, error)
+	// Judge, if set, is asked to score each answer from 1 to 10 with
+	// JudgePrompt; answers scoring below MinScore (default DefaultMinScore)
+	// are rejected. With neither Validate nor Judge every answer passes.
+	Judge       *Target
+	JudgePrompt string
+	MinScore    int
+}
+
+// CascadeResult is the outcome of Cascade.Run.
+type CascadeResult struct {
+	*chat.Result
+	// Tier is the index in Tiers of the tier that served the request.
+	Tier   int
+	Target Target
+	// Passed is false when no tier's answer was accepted and the last one
+	// is returned anyway.
+	Passed bool
+	// Scores holds the judge score of each judged answer.
+	Scores []int
+}
+
+// Chat runs the cascade and returns the served answer. Usage, cost and
+// attempts cover every tier and judge call.
+func (c *Cascade) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	res, err := c.Run(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return res.Result, nil
+}
+
+func (c *Cascade) Run(ctx context.Context, opts ...chat.Option) (*CascadeResult, error) {
+	if len(c.Tiers) == 0 {
+		return nil, fmt.Errorf("cascade has no tiers")
+	}
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out   CascadeResult
+		spent []*chat.Result
+		errs  []error
+	)
+	for i, tier := range c.Tiers {
+		resp, err := send(ctx, c.Client, tier, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tier %d (%s): %w", i, tier, err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		spent = append(spent, resp)
+		out.Result, out.Tier, out.Target = resp, i, tier
+		ok, err := c.accept(ctx, req, resp, &out, &spent)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tier %d (%s) validation: %w", i, tier, err))
+		}
+		if out.Passed = ok; ok {
+			break
+		}
+	}
+	if out.Result == nil {
+		return nil, errors.Join(errs...)
+	}
+	merged := *out.Result
+	merged.Attempts = nil
+	merged.Usage, merged.Cost = chat.Usage{}, 0
+	for _, r := range spent {
+		merged.Usage.Add(r.Usage)
+		merged.Cost += r.Cost
+		merged.Attempts = append(merged.Attempts, r.Attempts...)
+	}
+	if out.Tier > 0 {
+		merged.Warnings = append(append([]string{}, merged.Warnings...), fmt.Sprintf("cascade: escalated to tier %d (%s)", out.Tier, out.Target))
+	}
+	out.Result = &merged
+	return &out, nil
+}
+
+// accept validates or judges resp. Judge calls are added to spent.
+func (c *Cascade) accept(ctx context.Context, req *chat.Request, resp *chat.Result, out *CascadeResult, spent *[]*chat.Result) (bool, error) {
+	if c.Validate != nil {
+		return c.Validate(ctx, req, resp)
+	}
+	if c.Judge == nil {
+		return true, nil
+	}
+	prompt := c.JudgePrompt
+	if prompt == "" {
+		prompt = defaultJudgePrompt
+	}
+	var transcript strings.Builder
+	for _, m := range req.Messages {
+		if m.Role == chat.RoleSystem {
+			continue
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", m.Role, m.Content)
+	}
+	fmt.Fprintf(&transcript, "\nCandidate reply:\n%s", resp.Text)
+	judged, err := send(ctx, c.Client, *c.Judge, append([]chat.Option{
+		chat.WithMessages(chat.System(prompt), chat.User(transcript.String())),
+	}, inherit(req)...))
+	if err != nil {
+		return false, err
+	}
+	*spent = append(*spent, judged)
+	score, err := parseScore(judged.Text)
+	if err != nil {
+		return false, err
+	}
+	out.Scores = append(out.Scores, score)
+	minScore := c.MinScore
+	if minScore <= 0 {
+		minScore = DefaultMinScore
+	}
+	return score >= minScore, nil
+}
+
+var scorePattern = regexp.MustCompile(`\d+`)
+
+func parseScore(text string) (int, error) {
+	m := scorePattern.FindString(text)
+	if m == "" {
+		return 0, fmt.Errorf("judge reply has no score: %q", text)
+	}
+	score, err := strconv.Atoi(m)
+	if err != nil || score < 1 || score > 10 {
+		return 0, fmt.Errorf("judge score out of range: %q", text)
+	}
+	return score, nil
+}
@@ -0,0 +1,75 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+type scriptedClient struct {
+	replies  map[string]string // provider to reply
+	calls    []string
+	requests []*chat.Request
+}
+
+func (s *scriptedClient) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.calls = append(s.calls, req.Provider)
+	s.requests = append(s.requests, req)
+	return &chat.Result{Text: s.replies[req.Provider], Usage: chat.Usage{TotalTokens: 10}}, nil
+}
+
+func TestCascadeJudgeEscalates(t *testing.T) {
+	client := &scriptedClient{replies: map[string]string{"cheap": "dunno", "strong": "42", "judge": "3"}}
+	c := &Cascade{
+		Client: client,
+		Tiers:  []Target{{Provider: "cheap"}, {Provider: "strong"}},
+		Judge:  &Target{Provider: "judge"},
+	}
+	res, err := c.Run(context.Background(), chat.WithMessages(chat.User("meaning of life?")))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if res.Tier != 1 || res.Text != "42" || res.Passed {
+		t.Fatalf("unexpected result tier=%d text=%q passed=%v", res.Tier, res.Text, res.Passed)
+	}
+	if got := strings.Join(client.calls, ","); got != "cheap,judge,strong,judge" {
+		t.Fatalf("unexpected calls %s", got)
+	}
+	if res.Usage.TotalTokens != 40 || len(res.Scores) != 2 {
+		t.Fatalf("unexpected usage %+v scores %v", res.Usage, res.Scores)
+	}
+}
+
+func TestCascadeJudgeInheritsPrivacy(t *testing.T) {
+	client := &scriptedClient{replies: map[string]string{"cheap": "42", "judge": "9"}}
+	c := &Cascade{Client: client, Tiers: []Target{{Provider: "cheap"}}, Judge: &Target{Provider: "judge"}}
+	_, err := c.Run(context.Background(), chat.WithMessages(chat.User("meaning of life?")), chat.WithNoStore(), chat.WithRequirements(chat.TagEU))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	judge := client.requests[1]
+	if judge.Provider != "judge" || !judge.Options.NoStore || len(judge.Options.Requirements) != 1 {
+		t.Fatalf("judge call lost NoStore or requirements: %+v", judge.Options)
+	}
+}
+
+func TestCascadeValidateAccepts(t *testing.T) {
+	client := &scriptedClient{replies: map[string]string{"cheap": `{"ok":true}`}}
+	c := &Cascade{
+		Client: client,
+		Tiers:  []Target{{Provider: "cheap"}, {Provider: "strong"}},
+		Validate: func(ctx context.Context, req *chat.Request, resp *chat.Result) (bool, error) {
+			return strings.HasPrefix(resp.Text, "{"), nil
+		},
+	}
+	res, err := c.Run(context.Background(), chat.WithMessages(chat.User("json please")))
+	if err != nil || res.Tier != 0 || !res.Passed || len(client.calls) != 1 {
+		t.Fatalf("unexpected result %+v, %v", res, err)
+	}
+}
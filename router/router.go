@@ -0,0 +1,277 @@
+// Package router spreads chat requests over several provider/model
+// targets. It tracks the latency of each target and, when the context has
+// a deadline, skips targets that are unlikely to answer in time.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Chatter is the subset of uniai.Client the router needs.
+type Chatter interface {
+	Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error)
+}
+
+// Target is a provider and, optionally, a model to send requests to.
+type Target struct {
+	Provider string
+	Model    string
+}
+
+func (t Target) String() string {
+	if t.Model == "" {
+		return t.Provider
+	}
+	return t.Provider + "/" + t.Model
+}
+
+// ErrDeadline is returned when no target's p95 latency fits in the time
+// left before the context deadline.
+var ErrDeadline = errors.New("no target can answer before the deadline")
+
+const (
+	DefaultWindow     = 100
+	DefaultMinSamples = 5
+	DefaultTight      = 2.0
+)
+
+// Router sends each request to the first target, in order of preference,
+// that fits the deadline, and falls back to the next one on error.
+type Router struct {
+	Client  Chatter
+	Targets []Target
+	// Window is the number of recent latencies kept per target (default
+	// DefaultWindow).
+	Window int
+	// MinSamples is the number of latencies needed before a target's p95
+	// is trusted (default DefaultMinSamples). Targets with fewer samples
+	// are always eligible.
+	MinSamples int
+	// Tight marks the deadline as tight when less than Tight times the
+	// preferred target's p95 remains (default DefaultTight). Eligible
+	// targets are then tried fastest first.
+	Tight float64
+	// Tags lists the compliance tags of each target, keyed by provider
+	// name or by Target.String(). Targets lacking a requirement set with
+	// chat.WithRequirements are skipped. The tags are passed on with
+	// chat.WithProviderTags, so the client checks the provider it finally
+	// calls without needing Config.ProviderTags of its own.
+	Tags map[string][]string
+
+	mu        sync.Mutex
+	latencies map[Target][]time.Duration
+}
+
+func New(client Chatter, targets ...Target) *Router {
+	return &Router{Client: client, Targets: targets}
+}
+
+// Chat sends the request to the chosen target. Options selecting the
+// provider or model are overridden by the target. The attempts, usage and
+// cost of targets that failed are included in the result, and the
+// attempts in the error when all of them fail.
+func (r *Router) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	if len(r.Targets) == 0 {
+		return nil, fmt.Errorf("router has no targets")
+	}
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Options.Requirements) > 0 && len(r.Tags) > 0 {
+		// the client checks the requirements again against the provider it
+		// ends up calling, which a client policy may have changed
+		opts = append(append([]chat.Option{}, opts...), chat.WithProviderTags(r.Tags))
+	}
+	var errs []error
+	var failed []chat.AttemptInfo
+	compliant := 0
+	for _, target := range r.order(ctx) {
+		if len(r.missing(target, req.Options.Requirements)) > 0 {
+			continue
+		}
+		compliant++
+		if !r.fits(ctx, target) {
+			continue
+		}
+		start := time.Now()
+		resp, err := send(ctx, r.Client, target, opts)
+		if err == nil {
+			r.Observe(target, time.Since(start))
+			return withFailedAttempts(resp, failed), nil
+		}
+		failed = append(failed, failedAttempts(target, err, time.Since(start))...)
+		errs = append(errs, fmt.Errorf("%s: %w", target, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if compliant == 0 {
+		return nil, fmt.Errorf("%w: no target has %s", chat.ErrNonCompliant, strings.Join(req.Options.Requirements, ", "))
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%w (%s left)", ErrDeadline, r.remaining(ctx).Round(time.Millisecond))
+	}
+	return nil, &chat.AttemptsError{Err: errors.Join(errs...), Attempts: failed}
+}
+
+// failedAttempts returns the attempts recorded in err from a call to
+// target, or a single failed attempt when the client records none.
+func failedAttempts(target Target, err error, d time.Duration) []chat.AttemptInfo {
+	if attempts, ok := chat.AttemptsFromError(err); ok {
+		return attempts
+	}
+	return []chat.AttemptInfo{{
+		Provider:   target.Provider,
+		Model:      target.Model,
+		Status:     chat.AttemptError,
+		ErrorClass: chat.ClassifyError(err),
+		Error:      err.Error(),
+		Duration:   d,
+	}}
+}
+
+// withFailedAttempts returns resp with the attempts of the targets tried
+// before it prepended, and their usage and cost added to its totals.
+func withFailedAttempts(resp *chat.Result, failed []chat.AttemptInfo) *chat.Result {
+	if len(failed) == 0 {
+		return resp
+	}
+	out := *resp
+	out.Attempts = append(append([]chat.AttemptInfo{}, failed...), resp.Attempts...)
+	for _, a := range failed {
+		out.Usage.Add(a.Usage)
+		out.Cost += a.Cost
+	}
+	return &out
+}
+
+// send calls client with the provider and model of target appended to
+// opts, so they take precedence.
+func send(ctx context.Context, client Chatter, target Target, opts []chat.Option) (*chat.Result, error) {
+	callOpts := append(append([]chat.Option{}, opts...), chat.WithProvider(target.Provider))
+	if target.Model != "" {
+		callOpts = append(callOpts, chat.WithModel(target.Model))
+	}
+	return client.Chat(ctx, callOpts...)
+}
+
+// inherit returns the options of req that must hold for any call made on
+// its behalf, such as a judge or classifier call that sees its messages:
+// zero retention and the compliance requirements with their tags.
+func inherit(req *chat.Request) []chat.Option {
+	var opts []chat.Option
+	if req.Options.NoStore {
+		opts = append(opts, chat.WithNoStore())
+	}
+	if len(req.Options.Requirements) > 0 {
+		opts = append(opts, chat.WithRequirements(req.Options.Requirements...))
+	}
+	if req.Options.ProviderTags != nil {
+		opts = append(opts, chat.WithProviderTags(req.Options.ProviderTags))
+	}
+	return opts
+}
+
+// missing returns the requirements target's tags do not satisfy.
+func (r *Router) missing(target Target, requirements []string) []string {
+	if len(requirements) == 0 {
+		return nil
+	}
+	tags := r.Tags[target.Provider]
+	if target.Model != "" {
+		tags = append(append([]string(nil), tags...), r.Tags[target.String()]...)
+	}
+	return chat.MissingRequirements(tags, requirements)
+}
+
+// order returns the targets in the order they should be tried.
+func (r *Router) order(ctx context.Context) []Target {
+	out := append([]Target{}, r.Targets...)
+	if _, ok := ctx.Deadline(); !ok {
+		return out
+	}
+	first, ok := r.P95(out[0])
+	if !ok || float64(r.remaining(ctx)) >= r.tight()*float64(first) {
+		return out
+	}
+	// little time left: fastest known first, unknown targets last
+	sort.SliceStable(out, func(i, j int) bool {
+		pi, oki := r.P95(out[i])
+		pj, okj := r.P95(out[j])
+		if oki != okj {
+			return oki
+		}
+		return oki && pi < pj
+	})
+	return out
+}
+
+// fits reports whether target is expected to answer before the deadline.
+func (r *Router) fits(ctx context.Context, target Target) bool {
+	if _, ok := ctx.Deadline(); !ok {
+		return true
+	}
+	p95, ok := r.P95(target)
+	return !ok || p95 <= r.remaining(ctx)
+}
+
+func (r *Router) remaining(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	return time.Until(deadline)
+}
+
+// Observe records the latency of a successful call to target. Chat calls
+// it automatically; it is exported to seed the router at startup.
+func (r *Router) Observe(target Target, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.latencies == nil {
+		r.latencies = map[Target][]time.Duration{}
+	}
+	window := r.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	samples := append(r.latencies[target], d)
+	if len(samples) > window {
+		samples = samples[len(samples)-window:]
+	}
+	r.latencies[target] = samples
+}
+
+// P95 returns the 95th percentile of the recent latencies of target, and
+// false until MinSamples have been observed.
+func (r *Router) P95(target Target) (time.Duration, bool) {
+	r.mu.Lock()
+	samples := append([]time.Duration{}, r.latencies[target]...)
+	r.mu.Unlock()
+	minSamples := r.MinSamples
+	if minSamples <= 0 {
+		minSamples = DefaultMinSamples
+	}
+	if len(samples) < minSamples {
+		return 0, false
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	idx := (len(samples)*95+99)/100 - 1
+	return samples[idx], true
+}
+
+func (r *Router) tight() float64 {
+	if r.Tight <= 0 {
+		return DefaultTight
+	}
+	return r.Tight
+}
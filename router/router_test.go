@@ -0,0 +1,168 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/policy"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+type stubClient struct {
+	calls []string
+	fail  map[string]bool
+}
+
+func (s *stubClient) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.calls = append(s.calls, req.Provider)
+	if s.fail[req.Provider] {
+		return nil, errors.New("status 503: unavailable")
+	}
+	return &chat.Result{Text: req.Provider, Model: req.Model}, nil
+}
+
+func seed(r *Router, target Target, d time.Duration) {
+	for i := 0; i < DefaultMinSamples; i++ {
+		r.Observe(target, d)
+	}
+}
+
+func TestRouterDeadline(t *testing.T) {
+	slow, fast := Target{Provider: "slow"}, Target{Provider: "fast"}
+	client := &stubClient{}
+	r := New(client, slow, fast)
+	seed(r, slow, 10*time.Second)
+	seed(r, fast, 500*time.Millisecond)
+
+	resp, err := r.Chat(context.Background(), chat.WithMessages(chat.User("hi")))
+	if err != nil || resp.Text != "slow" {
+		t.Fatalf("without deadline expected preferred target, got %v, %v", resp, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	resp, err = r.Chat(ctx, chat.WithMessages(chat.User("hi")))
+	if err != nil || resp.Text != "fast" {
+		t.Fatalf("expected fast target, got %v, %v", resp, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := r.Chat(ctx, chat.WithMessages(chat.User("hi"))); !errors.Is(err, ErrDeadline) {
+		t.Fatalf("expected ErrDeadline, got %v", err)
+	}
+}
+
+func TestRouterFallback(t *testing.T) {
+	client := &stubClient{fail: map[string]bool{"a": true}}
+	r := New(client, Target{Provider: "a"}, Target{Provider: "b", Model: "m"})
+	resp, err := r.Chat(context.Background(), chat.WithMessages(chat.User("hi")))
+	if err != nil || resp.Text != "b" || resp.Model != "m" {
+		t.Fatalf("unexpected result %v, %v", resp, err)
+	}
+	if len(client.calls) != 2 {
+		t.Fatalf("unexpected calls %v", client.calls)
+	}
+}
+
+func TestRouterFallbackKeepsAttempts(t *testing.T) {
+	client := uniai.New(uniai.Config{PriceFunc: func(provider, model string, u chat.Usage) float64 { return float64(u.TotalTokens) }})
+	client.RegisterProvider("bad", fake.New(fake.Config{Responses: []fake.Response{{Err: errors.New("status 503: unavailable")}}}))
+	client.RegisterProvider("good", fake.New(fake.Config{Responses: []fake.Response{fake.Text("ok", 0, 0)}}))
+
+	r := New(client, Target{Provider: "bad"}, Target{Provider: "good"})
+	resp, err := r.Chat(context.Background(), chat.WithMessages(chat.User("hi")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Attempts) != 2 || resp.Attempts[0].Provider != "bad" || resp.Attempts[0].Status != chat.AttemptError || resp.Attempts[1].Provider != "good" {
+		t.Fatalf("unexpected attempts: %+v", resp.Attempts)
+	}
+
+	// usage spent by a failed target counts towards the total
+	spent := chatterFunc(func(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+		req, _ := chat.BuildRequest(opts...)
+		if req.Provider == "a" {
+			return nil, &chat.AttemptsError{Err: errors.New("status 500: boom"), Attempts: []chat.AttemptInfo{{Provider: "a", Status: chat.AttemptOK, Usage: chat.Usage{TotalTokens: 5}, Cost: 1}}}
+		}
+		return &chat.Result{Usage: chat.Usage{TotalTokens: 2}, Cost: 0.5}, nil
+	})
+	resp, err = New(spent, Target{Provider: "a"}, Target{Provider: "b"}).Chat(context.Background(), chat.WithMessages(chat.User("hi")))
+	if err != nil || resp.Usage.TotalTokens != 7 || resp.Cost != 1.5 {
+		t.Fatalf("unexpected totals: %+v, %v", resp, err)
+	}
+
+	r = New(client, Target{Provider: "bad"}, Target{Provider: "bad", Model: "other"})
+	_, err = r.Chat(context.Background(), chat.WithMessages(chat.User("hi")))
+	if attempts, ok := chat.AttemptsFromError(err); !ok || len(attempts) != 2 {
+		t.Fatalf("expected both failed attempts on the error, got %v", err)
+	}
+}
+
+type chatterFunc func(ctx context.Context, opts ...chat.Option) (*chat.Result, error)
+
+func (f chatterFunc) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	return f(ctx, opts...)
+}
+
+func TestP95(t *testing.T) {
+	r := New(nil)
+	target := Target{Provider: "p"}
+	for i := 1; i <= 100; i++ {
+		r.Observe(target, time.Duration(i)*time.Millisecond)
+	}
+	if p95, ok := r.P95(target); !ok || p95 != 95*time.Millisecond {
+		t.Fatalf("unexpected p95 %v", p95)
+	}
+}
+
+func TestRouterRequirements(t *testing.T) {
+	us, eu := Target{Provider: "openai"}, Target{Provider: "azure", Model: "gpt-eu"}
+	r := New(&stubClient{}, us, eu)
+	r.Tags = map[string][]string{"azure": {chat.TagNoTraining}, "azure/gpt-eu": {chat.TagEU}}
+
+	resp, err := r.Chat(context.Background(), chat.WithMessages(chat.User("hi")), chat.WithRequirements(chat.TagEU, chat.TagNoTraining))
+	if err != nil || resp.Text != "azure" {
+		t.Fatalf("expected EU target, got %v, %v", resp, err)
+	}
+	if _, err := r.Chat(context.Background(), chat.WithMessages(chat.User("hi")), chat.WithRequirements(chat.TagHIPAA)); !errors.Is(err, chat.ErrNonCompliant) {
+		t.Fatalf("expected ErrNonCompliant, got %v", err)
+	}
+}
+
+func TestRouterRequirementsWithClient(t *testing.T) {
+	client := uniai.New(uniai.Config{})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{fake.Text("ok", 0, 0)}}))
+	r := New(client, Target{Provider: "fake"})
+	r.Tags = map[string][]string{"fake": {chat.TagEU}}
+
+	resp, err := r.Chat(context.Background(), chat.WithMessages(chat.User("hi")), chat.WithRequirements(chat.TagEU))
+	if err != nil || resp.Text != "ok" {
+		t.Fatalf("expected the tagged target to answer, got %v, %v", resp, err)
+	}
+}
+
+func TestRouterRequirementsAfterClientPolicy(t *testing.T) {
+	p, err := policy.Parse([]byte(`{"rules":[{"name":"reroute","match":{"providers":["fake"]},"action":"route","provider":"other"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := uniai.New(uniai.Config{Policy: p})
+	client.RegisterProvider("fake", fake.New(fake.Config{Responses: []fake.Response{fake.Text("ok", 0, 0)}}))
+	client.RegisterProvider("other", fake.New(fake.Config{Responses: []fake.Response{fake.Text("leaked", 0, 0)}}))
+	r := New(client, Target{Provider: "fake"})
+	r.Tags = map[string][]string{"fake": {chat.TagEU}}
+
+	_, err = r.Chat(context.Background(), chat.WithMessages(chat.User("hi")), chat.WithRequirements(chat.TagEU))
+	if !errors.Is(err, chat.ErrNonCompliant) {
+		t.Fatalf("expected the rerouted request to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/tokens"
+)
+
+// Task labels produced by the built-in classifiers.
+const (
+	TaskCode        = "code"
+	TaskCreative    = "creative"
+	TaskExtraction  = "extraction"
+	TaskLongContext = "long_context"
+	TaskGeneral     = "general"
+)
+
+// Classifier assigns a task label to a request.
+type Classifier interface {
+	Classify(ctx context.Context, req *chat.Request) (string, error)
+}
+
+// ResultClassifier is a Classifier that calls a model, and returns that
+// call's result so its usage and cost are billed with the routed reply.
+type ResultClassifier interface {
+	Classifier
+	ClassifyResult(ctx context.Context, req *chat.Request) (string, *chat.Result, error)
+}
+
+type ClassifierFunc func(ctx context.Context, req *chat.Request) (string, error)
+
+func (f ClassifierFunc) Classify(ctx context.Context, req *chat.Request) (string, error) {
+	return f(ctx, req)
+}
+
+// DefaultLongContextTokens is the estimated prompt size from which
+// HeuristicClassifier labels a request TaskLongContext.
+const DefaultLongContextTokens = 32000
+
+var (
+	codePattern       = regexp.MustCompile("(?i)```|\\b(func|def|class|import|return|const|SELECT|stack trace|compile|refactor|bug|regex|function)\\b")
+	extractionPattern = regexp.MustCompile(`(?i)\b(extract|parse|fields?|json|csv|table|entities|classify)\b`)
+	creativePattern   = regexp.MustCompile(`(?i)\b(story|poem|haiku|lyrics|slogan|fiction|novel|creative|imagine|brainstorm)\b`)
+)
+
+// HeuristicClassifier labels requests from keywords and prompt size,
+// without a model call.
+type HeuristicClassifier struct {
+	// LongContextTokens defaults to DefaultLongContextTokens.
+	LongContextTokens int
+}
+
+func (h HeuristicClassifier) Classify(ctx context.Context, req *chat.Request) (string, error) {
+	limit := h.LongContextTokens
+	if limit <= 0 {
+		limit = DefaultLongContextTokens
+	}
+	total := 0
+	for _, m := range req.Messages {
+		total += tokens.Estimate(m.Content)
+	}
+	if total >= limit {
+		return TaskLongContext, nil
+	}
+	text := lastUserText(req)
+	switch {
+	case codePattern.MatchString(text):
+		return TaskCode, nil
+	case extractionPattern.MatchString(text):
+		return TaskExtraction, nil
+	case creativePattern.MatchString(text):
+		return TaskCreative, nil
+	}
+	return TaskGeneral, nil
+}
+
+// ModelClassifier asks a small model to pick one of Labels for the last
+// user message.
+type ModelClassifier struct {
+	Client Chatter
+	Target Target
+	// Labels default to the built-in task labels.
+	Labels []string
+}
+
+func (m ModelClassifier) Classify(ctx context.Context, req *chat.Request) (string, error) {
+	task, _, err := m.ClassifyResult(ctx, req)
+	return task, err
+}
+
+// ClassifyResult classifies req and also returns the classification call.
+func (m ModelClassifier) ClassifyResult(ctx context.Context, req *chat.Request) (string, *chat.Result, error) {
+	labels := m.Labels
+	if len(labels) == 0 {
+		labels = []string{TaskCode, TaskCreative, TaskExtraction, TaskLongContext, TaskGeneral}
+	}
+	prompt := "Classify the task in the user's message. Answer with exactly one of these labels and nothing else: " +
+		strings.Join(labels, ", ") + "."
+	resp, err := send(ctx, m.Client, m.Target, append([]chat.Option{
+		chat.WithMessages(chat.System(prompt), chat.User(lastUserText(req))),
+		chat.WithMaxTokens(10),
+	}, inherit(req)...))
+	if err != nil {
+		return "", nil, err
+	}
+	answer := strings.ToLower(strings.TrimSpace(resp.Text))
+	for _, label := range labels {
+		if strings.Contains(answer, strings.ToLower(label)) {
+			return label, resp, nil
+		}
+	}
+	return "", resp, fmt.Errorf("classifier reply matches no label: %q", resp.Text)
+}
+
+func lastUserText(req *chat.Request) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == chat.RoleUser {
+			return req.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// DefaultTaskCacheSize bounds the classifications a TaskRouter caches.
+const DefaultTaskCacheSize = 1024
+
+// TaskRouter classifies each request and sends it to the target configured
+// for its task. Classifications are cached by chat.Request.Hash.
+type TaskRouter struct {
+	Client Chatter
+	// Classifier defaults to HeuristicClassifier.
+	Classifier Classifier
+	Routes     map[string]Target
+	// Default serves tasks without a route and requests whose
+	// classification failed.
+	Default Target
+	// CacheSize defaults to DefaultTaskCacheSize.
+	CacheSize int
+
+	mu    sync.Mutex
+	cache map[string]string
+	order []string
+}
+
+// Chat classifies the request and sends it to the task's target. The
+// usage, cost and attempts of a ResultClassifier's call are added to the
+// result. A failed classification falls back to Default, or is returned
+// when there is none.
+func (t *TaskRouter) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	target := t.Default
+	task, classification, err := t.task(ctx, req)
+	if err != nil && target.Provider == "" {
+		return nil, fmt.Errorf("classify task: %w", err)
+	}
+	if err == nil {
+		if routed, ok := t.Routes[task]; ok {
+			target = routed
+		}
+	}
+	if target.Provider == "" {
+		return nil, fmt.Errorf("no target for task %q", task)
+	}
+	resp, sendErr := send(ctx, t.Client, target, opts)
+	if sendErr != nil {
+		return nil, sendErr
+	}
+	if classification != nil {
+		return withClassification(resp, classification), nil
+	}
+	if attempts, ok := chat.AttemptsFromError(err); ok {
+		return withFailedAttempts(resp, attempts), nil
+	}
+	return resp, nil
+}
+
+// Task returns the cached or freshly computed task label of req.
+func (t *TaskRouter) Task(ctx context.Context, req *chat.Request) (string, error) {
+	task, _, err := t.task(ctx, req)
+	return task, err
+}
+
+// task is Task, also returning the call a ResultClassifier made, which is
+// nil for cached labels.
+func (t *TaskRouter) task(ctx context.Context, req *chat.Request) (string, *chat.Result, error) {
+	key := req.Hash()
+	t.mu.Lock()
+	task, ok := t.cache[key]
+	t.mu.Unlock()
+	if ok {
+		return task, nil, nil
+	}
+	classifier := t.Classifier
+	if classifier == nil {
+		classifier = HeuristicClassifier{}
+	}
+	var resp *chat.Result
+	var err error
+	if rc, ok := classifier.(ResultClassifier); ok {
+		task, resp, err = rc.ClassifyResult(ctx, req)
+	} else {
+		task, err = classifier.Classify(ctx, req)
+	}
+	if err != nil {
+		return "", resp, err
+	}
+	t.remember(key, task)
+	return task, resp, nil
+}
+
+// withClassification returns resp with the usage, cost and attempts of the
+// classification call added.
+func withClassification(resp, classification *chat.Result) *chat.Result {
+	out := *resp
+	out.Attempts = append(append([]chat.AttemptInfo{}, classification.Attempts...), resp.Attempts...)
+	out.Usage.Add(classification.Usage)
+	out.Cost += classification.Cost
+	return &out
+}
+
+// remember caches task, evicting the oldest entry when full.
+func (t *TaskRouter) remember(key, task string) {
+	size := t.CacheSize
+	if size <= 0 {
+		size = DefaultTaskCacheSize
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cache == nil {
+		t.cache = map[string]string{}
+	}
+	if _, ok := t.cache[key]; !ok {
+		t.order = append(t.order, key)
+	}
+	t.cache[key] = task
+	for len(t.order) > size {
+		delete(t.cache, t.order[0])
+		t.order = t.order[1:]
+	}
+}
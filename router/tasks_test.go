@@ -0,0 +1,106 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestHeuristicClassifier(t *testing.T) {
+	cases := map[string]string{
+		"Why does this func panic?":           TaskCode,
+		"Extract the names as JSON":           TaskExtraction,
+		"Write a poem about autumn":           TaskCreative,
+		"What is the capital of France?":      TaskGeneral,
+		strings.Repeat("lorem ipsum ", 15000): TaskLongContext,
+	}
+	for text, want := range cases {
+		got, _ := HeuristicClassifier{}.Classify(context.Background(), &chat.Request{Messages: []chat.Message{chat.User(text)}})
+		if got != want {
+			t.Errorf("classify(%.30q) = %s, want %s", text, got, want)
+		}
+	}
+}
+
+func TestTaskRouterCachesClassification(t *testing.T) {
+	client := &stubClient{}
+	classified := 0
+	r := &TaskRouter{
+		Client: client,
+		Classifier: ClassifierFunc(func(ctx context.Context, req *chat.Request) (string, error) {
+			classified++
+			return TaskCode, nil
+		}),
+		Routes:  map[string]Target{TaskCode: {Provider: "coder"}},
+		Default: Target{Provider: "general"},
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := r.Chat(context.Background(), chat.WithMessages(chat.User("fix my bug")))
+		if err != nil || resp.Text != "coder" {
+			t.Fatalf("unexpected result %v, %v", resp, err)
+		}
+	}
+	if classified != 1 {
+		t.Fatalf("expected one classification, got %d", classified)
+	}
+}
+
+type classifierClient struct{}
+
+func (classifierClient) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	usage := chat.Usage{InputTokens: 20, OutputTokens: 1, TotalTokens: 21}
+	return &chat.Result{
+		Text:     "code",
+		Usage:    usage,
+		Cost:     0.01,
+		Attempts: []chat.AttemptInfo{{Provider: "small", Status: chat.AttemptOK, Usage: usage, Cost: 0.01}},
+	}, nil
+}
+
+func TestTaskRouterBillsClassification(t *testing.T) {
+	r := &TaskRouter{
+		Client:     &stubClient{},
+		Classifier: ModelClassifier{Client: classifierClient{}, Target: Target{Provider: "small"}},
+		Routes:     map[string]Target{TaskCode: {Provider: "coder"}},
+	}
+	resp, err := r.Chat(context.Background(), chat.WithMessages(chat.User("fix my bug")))
+	if err != nil || resp.Text != "coder" {
+		t.Fatalf("unexpected result %v, %v", resp, err)
+	}
+	if resp.Usage.InputTokens != 20 || resp.Cost != 0.01 || len(resp.Attempts) != 1 || resp.Attempts[0].Provider != "small" {
+		t.Fatalf("classification not billed: %+v", resp)
+	}
+}
+
+func TestModelClassifierInheritsPrivacy(t *testing.T) {
+	client := &scriptedClient{replies: map[string]string{"small": TaskCode}}
+	req, err := chat.BuildRequest(chat.WithMessages(chat.User("fix my bug")), chat.WithNoStore(), chat.WithRequirements(chat.TagEU))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (ModelClassifier{Client: client, Target: Target{Provider: "small"}}).Classify(context.Background(), req); err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if opts := client.requests[0].Options; !opts.NoStore || len(opts.Requirements) != 1 {
+		t.Fatalf("classifier call lost NoStore or requirements: %+v", opts)
+	}
+}
+
+func TestTaskRouterClassifierError(t *testing.T) {
+	failure := errors.New("classifier down")
+	r := &TaskRouter{
+		Client:     &stubClient{},
+		Classifier: ClassifierFunc(func(ctx context.Context, req *chat.Request) (string, error) { return "", failure }),
+		Routes:     map[string]Target{TaskCode: {Provider: "coder"}},
+	}
+	if _, err := r.Chat(context.Background(), chat.WithMessages(chat.User("hi"))); !errors.Is(err, failure) {
+		t.Fatalf("expected the classifier error, got %v", err)
+	}
+	r.Default = Target{Provider: "general"}
+	if resp, err := r.Chat(context.Background(), chat.WithMessages(chat.User("hi"))); err != nil || resp.Text != "general" {
+		t.Fatalf("expected the default target, got %v, %v", resp, err)
+	}
+}
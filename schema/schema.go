@@ -0,0 +1,470 @@
+// Package schema rewrites JSON Schemas into the dialect a provider accepts
+// for tool parameters and structured outputs, since most providers support
+// only part of JSON Schema:
+//
+//	out, warnings := schema.Normalize(s, schema.OpenAIStrict)
+//
+// OpenAI strict mode requires every property and closed objects, so
+// optional properties become required and nullable, additionalProperties
+// is set to false and unsupported keywords are removed. Gemini takes an
+// OpenAPI subset without references, so $ref is inlined, type unions become
+// nullable or anyOf, const becomes enum and other keywords are removed.
+// Rewrites that change what the schema accepts are reported as warnings,
+// each prefixed with the location in the schema, such as
+// "$.properties.tags". The input is never modified.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the subset of JSON Schema a provider accepts.
+type Dialect string
+
+const (
+	// OpenAIStrict is accepted by OpenAI tools and response formats with
+	// strict set.
+	OpenAIStrict Dialect = "openai_strict"
+	// Gemini is accepted by Gemini function declarations and response
+	// schemas.
+	Gemini Dialect = "gemini"
+)
+
+// openAIUnsupported are keywords OpenAI strict mode rejects.
+var openAIUnsupported = []string{
+	"not", "if", "then", "else", "dependentRequired", "dependentSchemas",
+	"patternProperties", "propertyNames", "unevaluatedProperties", "unevaluatedItems",
+	"minProperties", "maxProperties", "contains", "minContains", "maxContains",
+	"uniqueItems", "default", "examples",
+}
+
+// geminiSupported are the keywords Gemini accepts.
+var geminiSupported = []string{
+	"type", "format", "title", "description", "nullable", "enum", "properties",
+	"required", "propertyOrdering", "items", "minItems", "maxItems", "minimum",
+	"maximum", "minLength", "maxLength", "pattern", "anyOf", "example", "default",
+}
+
+// annotations are removed without a warning since they do not constrain
+// values.
+var annotations = []string{"$schema", "$id", "$comment", "$anchor", "examples", "deprecated", "readOnly", "writeOnly"}
+
+// Normalize returns a copy of s rewritten for d, and a warning for each
+// rewrite that changes what the schema accepts.
+func Normalize(s map[string]any, d Dialect) (map[string]any, []string) {
+	n := &normalizer{dialect: d}
+	root, _ := clone(s).(map[string]any)
+	switch d {
+	case Gemini:
+		root = n.inline(root, root, "$", nil).(map[string]any)
+		delete(root, "$defs")
+		delete(root, "definitions")
+		root = n.gemini(root, "$")
+	case OpenAIStrict:
+		if defs, ok := root["definitions"]; ok {
+			// strict mode resolves references into $defs only
+			if _, ok := root["$defs"]; !ok {
+				root["$defs"] = defs
+				delete(root, "definitions")
+				renameRefs(root, "#/definitions/", "#/$defs/")
+			}
+		}
+		n.root = root
+		if t, _ := root["type"].(string); t != "object" {
+			n.warn("$", "the root of a strict schema must be an object")
+		}
+		root = n.openAI(root, "$")
+	default:
+		n.warn("$", fmt.Sprintf("unknown dialect %q; left unchanged", d))
+	}
+	return root, n.warnings
+}
+
+// NormalizeJSON is Normalize for an encoded schema.
+func NormalizeJSON(data []byte, d Dialect) ([]byte, []string, error) {
+	var s map[string]any
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, nil, fmt.Errorf("decode schema: %w", err)
+	}
+	out, warnings := Normalize(s, d)
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode schema: %w", err)
+	}
+	return data, warnings, nil
+}
+
+// Inline returns a copy of s with local references ("#/$defs/item",
+// "#/definitions/item") replaced by the schemas they point to, and the
+// $defs and definitions removed. A recursive reference cannot be inlined
+// and becomes an unconstrained object, with a warning.
+func Inline(s map[string]any) (map[string]any, []string) {
+	n := &normalizer{}
+	root, _ := clone(s).(map[string]any)
+	out := n.inline(root, root, "$", nil).(map[string]any)
+	delete(out, "$defs")
+	delete(out, "definitions")
+	return out, n.warnings
+}
+
+type normalizer struct {
+	dialect  Dialect
+	root     map[string]any
+	warnings []string
+}
+
+func (n *normalizer) warn(path, msg string) {
+	w := path + ": " + msg
+	if !slices.Contains(n.warnings, w) {
+		n.warnings = append(n.warnings, w)
+	}
+}
+
+// inline resolves the local references in v against root. stack holds the
+// references being inlined, to detect recursion.
+func (n *normalizer) inline(root map[string]any, v any, path string, stack []string) any {
+	switch node := v.(type) {
+	case map[string]any:
+		if ref, ok := node["$ref"].(string); ok {
+			target, err := resolve(root, ref)
+			switch {
+			case err != nil:
+				n.warn(path, err.Error()+"; replaced with an unconstrained schema")
+				target = map[string]any{}
+			case slices.Contains(stack, ref):
+				n.warn(path, fmt.Sprintf("recursive $ref %q cannot be inlined; replaced with an unconstrained object", ref))
+				target = map[string]any{"type": "object"}
+			default:
+				target = n.inline(root, clone(target), path, append(stack, ref)).(map[string]any)
+			}
+			// keywords next to $ref, such as a description, take precedence
+			for k, val := range node {
+				if k != "$ref" {
+					target[k] = n.inline(root, val, path, stack)
+				}
+			}
+			return target
+		}
+		for k, val := range node {
+			if k == "$defs" || k == "definitions" {
+				continue
+			}
+			node[k] = n.inline(root, val, childPath(path, k), stack)
+		}
+		return node
+	case []any:
+		for i, val := range node {
+			node[i] = n.inline(root, val, path+"["+strconv.Itoa(i)+"]", stack)
+		}
+		return node
+	}
+	return v
+}
+
+// resolve returns the schema a local JSON pointer reference points to.
+func resolve(root map[string]any, ref string) (map[string]any, error) {
+	if ref == "#" {
+		return root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("$ref %q is not a local reference", ref)
+	}
+	var cur any = root
+	for _, tok := range strings.Split(ref[2:], "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		switch c := cur.(type) {
+		case map[string]any:
+			cur = c[tok]
+		case []any:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("$ref %q does not resolve", ref)
+			}
+			cur = c[i]
+		default:
+			cur = nil
+		}
+		if cur == nil {
+			return nil, fmt.Errorf("$ref %q does not resolve", ref)
+		}
+	}
+	target, ok := cur.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("$ref %q does not point to a schema", ref)
+	}
+	return target, nil
+}
+
+func (n *normalizer) openAI(node map[string]any, path string) map[string]any {
+	n.combinators(node, path)
+	for _, k := range annotations {
+		delete(node, k)
+	}
+	for _, k := range openAIUnsupported {
+		if _, ok := node[k]; ok {
+			n.warn(path, fmt.Sprintf("%s is not supported in strict mode; removed", k))
+			delete(node, k)
+		}
+	}
+	if props, ok := node["properties"].(map[string]any); ok || hasType(node, "object") {
+		if ap, ok := node["additionalProperties"]; ok && ap != false {
+			n.warn(path, "additionalProperties must be false in strict mode; set to false")
+		}
+		node["additionalProperties"] = false
+		if props == nil {
+			props = map[string]any{}
+			node["properties"] = props
+		}
+		required := stringSet(node["required"])
+		all := make([]any, 0, len(props))
+		for _, name := range sortedKeys(props) {
+			sub, _ := props[name].(map[string]any)
+			if sub == nil {
+				sub = map[string]any{}
+			}
+			sub = n.openAI(sub, childPath(path, "properties."+name))
+			if !required[name] {
+				n.warn(childPath(path, "properties."+name), "optional properties are not supported in strict mode; made required and nullable")
+				sub = nullable(sub)
+			}
+			props[name] = sub
+			all = append(all, name)
+		}
+		node["required"] = all
+	}
+	n.children(node, path, n.openAI)
+	return node
+}
+
+func (n *normalizer) gemini(node map[string]any, path string) map[string]any {
+	n.combinators(node, path)
+	if c, ok := node["const"]; ok {
+		node["enum"] = []any{c}
+		delete(node, "const")
+	}
+	if types, ok := node["type"].([]any); ok {
+		var kinds []any
+		for _, t := range types {
+			if t == "null" {
+				node["nullable"] = true
+			} else {
+				kinds = append(kinds, t)
+			}
+		}
+		switch len(kinds) {
+		case 0:
+			delete(node, "type")
+		case 1:
+			node["type"] = kinds[0]
+		default:
+			delete(node, "type")
+			alts := make([]any, 0, len(kinds))
+			for _, t := range kinds {
+				alts = append(alts, map[string]any{"type": t})
+			}
+			node["anyOf"] = alts
+		}
+	}
+	for _, k := range append(annotations, "$defs", "definitions") {
+		delete(node, k)
+	}
+	for _, k := range sortedKeys(node) {
+		if !slices.Contains(geminiSupported, k) {
+			n.warn(path, fmt.Sprintf("%s is not supported by gemini; removed", k))
+			delete(node, k)
+		}
+	}
+	if props, ok := node["properties"].(map[string]any); ok {
+		for _, name := range sortedKeys(props) {
+			if sub, ok := props[name].(map[string]any); ok {
+				props[name] = n.gemini(sub, childPath(path, "properties."+name))
+			}
+		}
+	}
+	n.children(node, path, n.gemini)
+	return node
+}
+
+// combinators rewrites oneOf as anyOf and merges allOf into node, which
+// neither dialect supports.
+func (n *normalizer) combinators(node map[string]any, path string) {
+	if one, ok := node["oneOf"]; ok {
+		if _, ok := node["anyOf"]; !ok {
+			n.warn(path, "oneOf is not supported; rewritten as anyOf, which also accepts values matching several alternatives")
+			node["anyOf"] = one
+			delete(node, "oneOf")
+		}
+	}
+	all, ok := node["allOf"].([]any)
+	if !ok {
+		return
+	}
+	delete(node, "allOf")
+	for _, item := range all {
+		sub, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if ref, ok := sub["$ref"].(string); ok && n.root != nil {
+			if target, err := resolve(n.root, ref); err == nil {
+				sub = clone(target).(map[string]any)
+			}
+		}
+		for k, v := range sub {
+			switch k {
+			case "properties":
+				props, _ := node["properties"].(map[string]any)
+				if props == nil {
+					props = map[string]any{}
+					node["properties"] = props
+				}
+				sp, _ := v.(map[string]any)
+				for name, p := range sp {
+					if _, ok := props[name]; ok {
+						n.warn(path, fmt.Sprintf("allOf constrains property %q more than once; the first definition is kept", name))
+						continue
+					}
+					props[name] = p
+				}
+			case "required":
+				req, _ := node["required"].([]any)
+				for _, name := range asSlice(v) {
+					if !slices.Contains(req, name) {
+						req = append(req, name)
+					}
+				}
+				node["required"] = req
+			default:
+				if _, ok := node[k]; ok {
+					n.warn(path, fmt.Sprintf("allOf sets %s more than once; the first value is kept", k))
+					continue
+				}
+				node[k] = v
+			}
+		}
+	}
+}
+
+// children normalizes the subschemas of node other than its properties.
+func (n *normalizer) children(node map[string]any, path string, f func(map[string]any, string) map[string]any) {
+	if items, ok := node["items"].(map[string]any); ok {
+		node["items"] = f(items, childPath(path, "items"))
+	}
+	if alts, ok := node["anyOf"].([]any); ok {
+		for i, alt := range alts {
+			if sub, ok := alt.(map[string]any); ok {
+				alts[i] = f(sub, path+".anyOf["+strconv.Itoa(i)+"]")
+			}
+		}
+	}
+	if defs, ok := node["$defs"].(map[string]any); ok {
+		for _, name := range sortedKeys(defs) {
+			if sub, ok := defs[name].(map[string]any); ok {
+				defs[name] = f(sub, childPath(path, "$defs."+name))
+			}
+		}
+	}
+}
+
+// nullable makes s accept null as well.
+func nullable(s map[string]any) map[string]any {
+	switch t := s["type"].(type) {
+	case string:
+		if t != "null" {
+			s["type"] = []any{t, "null"}
+		}
+		return s
+	case []any:
+		if !slices.Contains(t, any("null")) {
+			s["type"] = append(t, "null")
+		}
+		return s
+	}
+	if alts, ok := s["anyOf"].([]any); ok && len(s) == 1 {
+		s["anyOf"] = append(alts, map[string]any{"type": "null"})
+		return s
+	}
+	return map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+}
+
+func hasType(node map[string]any, want string) bool {
+	switch t := node["type"].(type) {
+	case string:
+		return t == want
+	case []any:
+		return slices.Contains(t, any(want))
+	}
+	return false
+}
+
+func renameRefs(v any, from, to string) {
+	switch node := v.(type) {
+	case map[string]any:
+		if ref, ok := node["$ref"].(string); ok && strings.HasPrefix(ref, from) {
+			node["$ref"] = to + strings.TrimPrefix(ref, from)
+		}
+		for _, val := range node {
+			renameRefs(val, from, to)
+		}
+	case []any:
+		for _, val := range node {
+			renameRefs(val, from, to)
+		}
+	}
+}
+
+func clone(v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(node))
+		for k, val := range node {
+			out[k] = clone(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(node))
+		for i, val := range node {
+			out[i] = clone(val)
+		}
+		return out
+	case []string:
+		out := make([]any, len(node))
+		for i, val := range node {
+			out[i] = val
+		}
+		return out
+	}
+	return v
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func stringSet(v any) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range asSlice(v) {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func childPath(path, key string) string {
+	return path + "." + key
+}
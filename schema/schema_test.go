@@ -0,0 +1,165 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, s string) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return out
+}
+
+func hasWarning(warnings []string, substr string) bool {
+	for _, w := range warnings {
+		if strings.Contains(w, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNormalizeOpenAIStrict(t *testing.T) {
+	in := decode(t, `{
+		"type": "object",
+		"properties": {
+			"city": {"type": "string"},
+			"unit": {"type": "string", "enum": ["c", "f"], "default": "c"},
+			"tags": {"type": "array", "items": {"$ref": "#/definitions/tag"}, "uniqueItems": true}
+		},
+		"required": ["city"],
+		"definitions": {"tag": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}}
+	}`)
+	orig := decode(t, mustJSON(t, in))
+	out, warnings := Normalize(in, OpenAIStrict)
+	if !reflect.DeepEqual(in, orig) {
+		t.Fatalf("input was modified")
+	}
+	want := decode(t, `{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"city": {"type": "string"},
+			"unit": {"type": ["string", "null"], "enum": ["c", "f"]},
+			"tags": {"type": ["array", "null"], "items": {"$ref": "#/$defs/tag"}}
+		},
+		"required": ["city", "tags", "unit"],
+		"$defs": {"tag": {"type": "object", "additionalProperties": false, "properties": {"name": {"type": "string"}}, "required": ["name"]}}
+	}`)
+	if got := decode(t, mustJSON(t, out)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected schema:\n%s", mustJSON(t, out))
+	}
+	for _, w := range []string{
+		"$.properties.unit: optional properties",
+		"$.properties.unit: default is not supported",
+		"$.properties.tags: uniqueItems is not supported",
+	} {
+		if !hasWarning(warnings, w) {
+			t.Fatalf("missing warning %q in %v", w, warnings)
+		}
+	}
+	if hasWarning(warnings, "$.properties.city") {
+		t.Fatalf("unexpected warning for a required property: %v", warnings)
+	}
+}
+
+func TestNormalizeGemini(t *testing.T) {
+	in := decode(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"kind": {"const": "order"},
+			"note": {"type": ["string", "null"]},
+			"id": {"type": ["string", "integer"]},
+			"item": {"$ref": "#/$defs/item", "description": "the item"},
+			"shape": {"oneOf": [{"type": "string"}, {"type": "number"}]}
+		},
+		"$defs": {
+			"item": {"type": "object", "properties": {"sku": {"type": "string"}, "parts": {"type": "array", "items": {"$ref": "#/$defs/item"}}}}
+		}
+	}`)
+	out, warnings := Normalize(in, Gemini)
+	props := out["properties"].(map[string]any)
+	if kind := props["kind"].(map[string]any); !reflect.DeepEqual(kind["enum"], []any{"order"}) {
+		t.Fatalf("const not rewritten: %v", kind)
+	}
+	if note := props["note"].(map[string]any); note["type"] != "string" || note["nullable"] != true {
+		t.Fatalf("nullable not rewritten: %v", note)
+	}
+	if id := props["id"].(map[string]any); len(id["anyOf"].([]any)) != 2 || id["type"] != nil {
+		t.Fatalf("type union not rewritten: %v", id)
+	}
+	item := props["item"].(map[string]any)
+	if item["description"] != "the item" || item["type"] != "object" {
+		t.Fatalf("$ref not inlined: %v", item)
+	}
+	parts := item["properties"].(map[string]any)["parts"].(map[string]any)
+	if items := parts["items"].(map[string]any); items["type"] != "object" || items["properties"] != nil {
+		t.Fatalf("recursive $ref not cut: %v", items)
+	}
+	if _, ok := props["shape"].(map[string]any)["anyOf"]; !ok {
+		t.Fatalf("oneOf not rewritten")
+	}
+	if _, ok := out["$defs"]; ok {
+		t.Fatalf("$defs not removed")
+	}
+	for _, w := range []string{
+		"$: additionalProperties is not supported by gemini",
+		"recursive $ref",
+		"$.properties.shape: oneOf",
+	} {
+		if !hasWarning(warnings, w) {
+			t.Fatalf("missing warning %q in %v", w, warnings)
+		}
+	}
+	if hasWarning(warnings, "$schema") {
+		t.Fatalf("annotations should be removed silently: %v", warnings)
+	}
+}
+
+func TestNormalizeAllOf(t *testing.T) {
+	in := decode(t, `{
+		"type": "object",
+		"allOf": [
+			{"$ref": "#/$defs/base"},
+			{"properties": {"b": {"type": "integer"}}, "required": ["b"]}
+		],
+		"$defs": {"base": {"properties": {"a": {"type": "string"}}, "required": ["a"]}}
+	}`)
+	out, warnings := Normalize(in, OpenAIStrict)
+	props := out["properties"].(map[string]any)
+	if props["a"] == nil || props["b"] == nil {
+		t.Fatalf("allOf not merged: %v", out)
+	}
+	if !reflect.DeepEqual(out["required"], []any{"a", "b"}) || len(warnings) != 0 {
+		t.Fatalf("unexpected required %v or warnings %v", out["required"], warnings)
+	}
+}
+
+func TestInline(t *testing.T) {
+	in := decode(t, `{"type": "object", "properties": {"a": {"$ref": "#/definitions/x"}, "b": {"$ref": "https://example.com/s.json"}}, "definitions": {"x": {"type": "string"}}}`)
+	out, warnings := Inline(in)
+	props := out["properties"].(map[string]any)
+	if props["a"].(map[string]any)["type"] != "string" || out["definitions"] != nil {
+		t.Fatalf("unexpected schema: %v", out)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "not a local reference") {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,104 @@
+package uniai
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/schema"
+)
+
+// applySchemaDialect rewrites the tool parameter schemas and the
+// json_schema response format of req into the dialect providerName
+// accepts: OpenAI strict mode for strict tools and formats on OpenAI, and
+// the Gemini subset on gemini. Lossy rewrites are reported as warnings.
+func applySchemaDialect(providerName string, req *chat.Request) (*chat.Request, []string) {
+	var out *chat.Request
+	var warnings []string
+	copyReq := func() {
+		if out == nil {
+			r := *req
+			out = &r
+		}
+	}
+	var tools []chat.Tool
+	for i, tool := range req.Tools {
+		d, ok := schemaDialect(providerName, tool.Function.Strict != nil && *tool.Function.Strict)
+		if !ok || len(tool.Function.ParametersJSONSchema) == 0 {
+			continue
+		}
+		data, ws, err := schema.NormalizeJSON(tool.Function.ParametersJSONSchema, d)
+		if err != nil {
+			continue
+		}
+		for _, w := range ws {
+			warnings = append(warnings, fmt.Sprintf("tool %s schema %s", tool.Function.Name, w))
+		}
+		copyReq()
+		if tools == nil {
+			tools = append([]chat.Tool{}, req.Tools...)
+			out.Tools = tools
+		}
+		tools[i].Function.ParametersJSONSchema = data
+	}
+
+	format, _ := jsonMap(req.Options.OpenAI["response_format"])
+	if t, _ := format["type"].(string); t == "json_schema" {
+		js, _ := jsonMap(format["json_schema"])
+		s, _ := jsonMap(js["schema"])
+		strict, _ := js["strict"].(bool)
+		if d, ok := schemaDialect(providerName, strict); ok && s != nil {
+			normalized, ws := schema.Normalize(s, d)
+			for _, w := range ws {
+				warnings = append(warnings, "response_format schema "+w)
+			}
+			nextJS := map[string]any{}
+			for k, v := range js {
+				nextJS[k] = v
+			}
+			nextJS["schema"] = normalized
+			nextFormat := map[string]any{}
+			for k, v := range format {
+				nextFormat[k] = v
+			}
+			nextFormat["json_schema"] = nextJS
+			copyReq()
+			opts := cloneJSONMap(req.Options.OpenAI)
+			opts["response_format"] = nextFormat
+			out.Options.OpenAI = opts
+		}
+	}
+	if out == nil {
+		return req, nil
+	}
+	return out, warnings
+}
+
+// schemaDialect returns the dialect schemas sent to providerName are
+// rewritten into, if any.
+func schemaDialect(providerName string, strict bool) (schema.Dialect, bool) {
+	switch providerName {
+	case "openai", "openai_custom", "azure":
+		return schema.OpenAIStrict, strict
+	case "gemini":
+		return schema.Gemini, true
+	}
+	return "", false
+}
+
+// jsonMap returns v as a map when it is a JSON object.
+func jsonMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case structs.JSONMap:
+		return map[string]any(m), true
+	case json.RawMessage:
+		var out map[string]any
+		if json.Unmarshal(m, &out) == nil {
+			return out, true
+		}
+	}
+	return nil, false
+}
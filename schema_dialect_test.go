@@ -0,0 +1,63 @@
+package uniai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestApplySchemaDialect(t *testing.T) {
+	strict := true
+	params := []byte(`{"type":"object","properties":{"city":{"type":"string"},"unit":{"type":"string"}},"required":["city"]}`)
+	loose := chat.FunctionTool("loose", "", params)
+	tool := chat.FunctionTool("weather", "", params)
+	tool.Function.Strict = &strict
+	opts := structs.NewJSONMap()
+	opts["response_format"] = map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   "answer",
+			"schema": map[string]any{"type": "object", "properties": map[string]any{"n": map[string]any{"type": []any{"integer", "null"}}}},
+		},
+	}
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("hi")),
+		chat.WithTools([]chat.Tool{loose, tool}),
+		chat.WithOpenAIOptions(opts),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, warnings := applySchemaDialect("openai", req)
+	if string(out.Tools[0].Function.ParametersJSONSchema) != string(params) {
+		t.Fatalf("non-strict tool was rewritten: %s", out.Tools[0].Function.ParametersJSONSchema)
+	}
+	var s map[string]any
+	if err := json.Unmarshal(out.Tools[1].Function.ParametersJSONSchema, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s["additionalProperties"] != false || len(s["required"].([]any)) != 2 {
+		t.Fatalf("strict tool not rewritten: %v", s)
+	}
+	if string(req.Tools[1].Function.ParametersJSONSchema) != string(params) {
+		t.Fatalf("request was modified")
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "tool weather schema $.properties.unit") {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+
+	out, _ = applySchemaDialect("gemini", req)
+	format := out.Options.OpenAI["response_format"].(map[string]any)
+	n := format["json_schema"].(map[string]any)["schema"].(map[string]any)["properties"].(map[string]any)["n"].(map[string]any)
+	if n["type"] != "integer" || n["nullable"] != true {
+		t.Fatalf("response format not rewritten for gemini: %v", n)
+	}
+
+	if out, warnings = applySchemaDialect("anthropic", req); out != req || warnings != nil {
+		t.Fatalf("anthropic request should be unchanged")
+	}
+}
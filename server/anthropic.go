@@ -0,0 +1,466 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+// Chatter is the subset of uniai.Client the provider-neutral frontends
+// need.
+type Chatter interface {
+	Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error)
+}
+
+// Messages serves POST /v1/messages in the Anthropic Messages format,
+// including its server-sent event stream, on top of a uniai client, so
+// Claude-native clients can use the gateway with any provider. Messages
+// carry text, images, tool use and tool results; document blocks are
+// rejected.
+type Messages struct {
+	Client Chatter
+	// Options are applied to every request before the converted ones, e.g.
+	// to pick a provider.
+	Options []chat.Option
+}
+
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	MaxTokens     int                `json:"max_tokens"`
+	System        json.RawMessage    `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Tools         []struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		InputSchema json.RawMessage `json:"input_schema,omitempty"`
+	} `json:"tools,omitempty"`
+	ToolChoice *struct {
+		Type string `json:"type"`
+		Name string `json:"name,omitempty"`
+	} `json:"tool_choice,omitempty"`
+	Metadata *struct {
+		UserID string `json:"user_id,omitempty"`
+	} `json:"metadata,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+	// Source is the content of image blocks.
+	Source *anthropicSource `json:"source,omitempty"`
+}
+
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	ID           string           `json:"id"`
+	Type         string           `json:"type"`
+	Role         string           `json:"role"`
+	Model        string           `json:"model"`
+	Content      []anthropicBlock `json:"content"`
+	StopReason   *string          `json:"stop_reason"`
+	StopSequence *string          `json:"stop_sequence"`
+	Usage        anthropicUsage   `json:"usage"`
+}
+
+func (h *Messages) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAnthropicError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	body, err := httputil.ReadBody(r.Body)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	var req anthropicRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	opts, err := anthropicOptions(&req)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	opts = append(append([]chat.Option{}, h.Options...), opts...)
+	id := newMessageID("msg_")
+	if req.Stream {
+		h.stream(w, r, &req, id, opts)
+		return
+	}
+	resp, err := h.Client.Chat(r.Context(), opts...)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadGateway, "api_error", err.Error())
+		return
+	}
+	RecordUsage(r.Context(), req.Model, resp.Usage)
+	out := anthropicResponse{
+		ID:    id,
+		Type:  "message",
+		Role:  "assistant",
+		Model: req.Model,
+		Usage: anthropicUsage{InputTokens: resp.Usage.InputTokens, OutputTokens: resp.Usage.OutputTokens},
+	}
+	if resp.Text != "" {
+		out.Content = append(out.Content, anthropicBlock{Type: "text", Text: resp.Text})
+	}
+	for _, call := range resp.ToolCalls {
+		out.Content = append(out.Content, toolUseBlock(call))
+	}
+	if out.Content == nil {
+		out.Content = []anthropicBlock{}
+	}
+	stop := stopReason(resp)
+	out.StopReason = &stop
+	writeJSON(w, http.StatusOK, out)
+}
+
+// stream relays the reply as Anthropic server-sent events. Headers are sent
+// with the first event, so errors before it still get a JSON error.
+func (h *Messages) stream(w http.ResponseWriter, r *http.Request, req *anthropicRequest, id string, opts []chat.Option) {
+	flusher, _ := w.(http.Flusher)
+	started := false
+	index := -1
+	textOpen := false
+	send := func(event string, data any) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			if err := writeEvent(w, "message_start", map[string]any{
+				"type": "message_start",
+				"message": anthropicResponse{
+					ID: id, Type: "message", Role: "assistant", Model: req.Model, Content: []anthropicBlock{},
+				},
+			}); err != nil {
+				return err
+			}
+		}
+		if err := writeEvent(w, event, data); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	closeBlock := func() error {
+		if index < 0 || !textOpen {
+			return nil
+		}
+		textOpen = false
+		return send("content_block_stop", map[string]any{"type": "content_block_stop", "index": index})
+	}
+	streamed := false
+	onEvent := func(ev chat.StreamEvent) error {
+		switch {
+		case ev.Delta != "":
+			streamed = true
+			if !textOpen {
+				index++
+				textOpen = true
+				if err := send("content_block_start", map[string]any{
+					"type": "content_block_start", "index": index,
+					"content_block": map[string]any{"type": "text", "text": ""},
+				}); err != nil {
+					return err
+				}
+			}
+			return send("content_block_delta", map[string]any{
+				"type": "content_block_delta", "index": index,
+				"delta": map[string]any{"type": "text_delta", "text": ev.Delta},
+			})
+		case ev.ToolCall != nil:
+			streamed = true
+			if err := closeBlock(); err != nil {
+				return err
+			}
+			index++
+			block := toolUseBlock(*ev.ToolCall)
+			input := block.Input
+			block.Input = json.RawMessage("{}")
+			for _, e := range []struct {
+				name string
+				data any
+			}{
+				{"content_block_start", map[string]any{"type": "content_block_start", "index": index, "content_block": block}},
+				{"content_block_delta", map[string]any{"type": "content_block_delta", "index": index, "delta": map[string]any{"type": "input_json_delta", "partial_json": string(input)}}},
+				{"content_block_stop", map[string]any{"type": "content_block_stop", "index": index}},
+			} {
+				if err := send(e.name, e.data); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	resp, err := h.Client.Chat(r.Context(), append(opts, chat.WithOnStream(onEvent))...)
+	if err != nil {
+		if !started {
+			writeAnthropicError(w, http.StatusBadGateway, "api_error", err.Error())
+			return
+		}
+		_ = send("error", map[string]any{"type": "error", "error": map[string]string{"type": "api_error", "message": err.Error()}})
+		return
+	}
+	RecordUsage(r.Context(), req.Model, resp.Usage)
+	if !streamed {
+		// the provider answered without streaming
+		events := []chat.StreamEvent{{Delta: resp.Text}}
+		for _, call := range resp.ToolCalls {
+			events = append(events, chat.StreamEvent{ToolCall: &call})
+		}
+		for _, ev := range events {
+			if err := onEvent(ev); err != nil {
+				return
+			}
+		}
+	}
+	if err := closeBlock(); err != nil {
+		return
+	}
+	_ = send("message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": stopReason(resp), "stop_sequence": nil},
+		"usage": anthropicUsage{InputTokens: resp.Usage.InputTokens, OutputTokens: resp.Usage.OutputTokens},
+	})
+	_ = send("message_stop", map[string]any{"type": "message_stop"})
+}
+
+// writeEvent writes one server-sent event.
+func writeEvent(w http.ResponseWriter, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+func anthropicOptions(req *anthropicRequest) ([]chat.Option, error) {
+	if len(req.Messages) == 0 {
+		return nil, fmt.Errorf("messages are required")
+	}
+	var msgs []chat.Message
+	if len(req.System) > 0 {
+		system, err := anthropicText(req.System)
+		if err != nil {
+			return nil, fmt.Errorf("system: %w", err)
+		}
+		if system != "" {
+			msgs = append(msgs, chat.System(system))
+		}
+	}
+	for i, m := range req.Messages {
+		converted, err := anthropicMessages(m)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		msgs = append(msgs, converted...)
+	}
+	opts := []chat.Option{chat.WithMessages(msgs...)}
+	if req.Model != "" {
+		opts = append(opts, chat.WithModel(req.Model))
+	}
+	if req.MaxTokens > 0 {
+		opts = append(opts, chat.WithMaxTokens(req.MaxTokens))
+	}
+	if req.Temperature != nil {
+		opts = append(opts, chat.WithTemperature(*req.Temperature))
+	}
+	if req.TopP != nil {
+		opts = append(opts, chat.WithTopP(*req.TopP))
+	}
+	if len(req.StopSequences) > 0 {
+		opts = append(opts, chat.WithStopWords(req.StopSequences...))
+	}
+	if req.Metadata != nil && req.Metadata.UserID != "" {
+		opts = append(opts, chat.WithUser(req.Metadata.UserID))
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]chat.Tool, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			tools = append(tools, chat.FunctionTool(t.Name, t.Description, t.InputSchema))
+		}
+		opts = append(opts, chat.WithTools(tools))
+	}
+	if tc := req.ToolChoice; tc != nil {
+		switch tc.Type {
+		case "auto":
+			opts = append(opts, chat.WithToolChoice(chat.ToolChoiceAuto()))
+		case "any":
+			opts = append(opts, chat.WithToolChoice(chat.ToolChoiceRequired()))
+		case "none":
+			opts = append(opts, chat.WithToolChoice(chat.ToolChoiceNone()))
+		case "tool":
+			opts = append(opts, chat.WithToolChoice(chat.ToolChoiceFunction(tc.Name)))
+		default:
+			return nil, fmt.Errorf("unsupported tool_choice %q", tc.Type)
+		}
+	}
+	return opts, nil
+}
+
+// anthropicMessages converts one message. A user message with tool results
+// becomes one tool message per result, followed by its text, if any.
+func anthropicMessages(m anthropicMessage) ([]chat.Message, error) {
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return []chat.Message{{Role: m.Role, Content: text}}, nil
+	}
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(m.Content, &blocks); err != nil {
+		return nil, fmt.Errorf("content must be a string or an array of blocks")
+	}
+	var out []chat.Message
+	var texts []string
+	var calls []chat.ToolCall
+	var images []chat.Image
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			texts = append(texts, b.Text)
+		case "image":
+			img, err := anthropicImage(m.Role, b.Source)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, img)
+		case "tool_use":
+			args := string(b.Input)
+			if args == "" {
+				args = "{}"
+			}
+			calls = append(calls, chat.ToolCall{ID: b.ID, Type: "function", Function: chat.ToolCallFunction{Name: b.Name, Arguments: args}})
+		case "tool_result":
+			content, err := anthropicText(b.Content)
+			if err != nil {
+				return nil, fmt.Errorf("tool_result: %w", err)
+			}
+			if b.IsError {
+				content = "Error: " + content
+			}
+			out = append(out, chat.ToolResult(b.ToolUseID, content))
+		case "thinking", "redacted_thinking":
+			// earlier reasoning is not replayed to other providers
+		default:
+			return nil, fmt.Errorf("unsupported content block %q", b.Type)
+		}
+	}
+	if len(texts) > 0 || len(calls) > 0 || len(images) > 0 {
+		out = append(out, chat.Message{Role: m.Role, Content: strings.Join(texts, "\n"), ToolCalls: calls, Images: images})
+	}
+	return out, nil
+}
+
+// anthropicImage converts the source of an image block.
+func anthropicImage(role string, src *anthropicSource) (chat.Image, error) {
+	if role != chat.RoleUser {
+		return chat.Image{}, fmt.Errorf("image blocks are only supported in user messages")
+	}
+	switch {
+	case src == nil:
+		return chat.Image{}, fmt.Errorf("image block without a source")
+	case src.Type == "base64" && src.MediaType != "" && src.Data != "":
+		return chat.Image{URL: "data:" + src.MediaType + ";base64," + src.Data}, nil
+	case src.Type == "url" && src.URL != "":
+		return chat.Image{URL: src.URL}, nil
+	}
+	return chat.Image{}, fmt.Errorf("unsupported image source %q", src.Type)
+}
+
+// anthropicText reads a string or an array of text blocks.
+func anthropicText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return "", fmt.Errorf("content must be a string or an array of text blocks")
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Type != "text" {
+			return "", fmt.Errorf("unsupported content block %q", b.Type)
+		}
+		parts = append(parts, b.Text)
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+func toolUseBlock(call chat.ToolCall) anthropicBlock {
+	id := call.ID
+	if id == "" {
+		id = newMessageID("toolu_")
+	}
+	input := json.RawMessage(call.Function.Arguments)
+	if !json.Valid(input) {
+		input = json.RawMessage("{}")
+	}
+	return anthropicBlock{Type: "tool_use", ID: id, Name: call.Function.Name, Input: input}
+}
+
+func stopReason(resp *chat.Result) string {
+	if len(resp.ToolCalls) > 0 {
+		return "tool_use"
+	}
+	switch chat.NormalizeFinishReason(resp.FinishReason) {
+	case chat.FinishReasonLength:
+		return "max_tokens"
+	case chat.FinishReasonContentFilter:
+		return "refusal"
+	}
+	return "end_turn"
+}
+
+// writeAnthropicError writes an error in the Anthropic error envelope.
+func writeAnthropicError(w http.ResponseWriter, status int, typ, message string) {
+	writeJSON(w, status, map[string]any{
+		"type":  "error",
+		"error": map[string]string{"type": typ, "message": message},
+	})
+}
+
+func newMessageID(prefix string) string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return prefix + hex.EncodeToString(b[:])
+}
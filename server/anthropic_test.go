@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// stubChatter replies with a fixed result, streaming it when asked.
+type stubChatter struct {
+	result *chat.Result
+	req    *chat.Request
+}
+
+func (s *stubChatter) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.req = req
+	if fn := req.Options.OnStream; fn != nil {
+		for _, word := range strings.SplitAfter(s.result.Text, " ") {
+			if err := fn(chat.StreamEvent{Delta: word}); err != nil {
+				return nil, err
+			}
+		}
+		for _, call := range s.result.ToolCalls {
+			if err := fn(chat.StreamEvent{ToolCall: &call}); err != nil {
+				return nil, err
+			}
+		}
+		if err := fn(chat.StreamEvent{Done: true, Usage: &s.result.Usage}); err != nil {
+			return nil, err
+		}
+	}
+	return s.result, nil
+}
+
+const anthropicBody = `{"model":"any","max_tokens":100,"system":"Be brief.","messages":[
+  {"role":"user","content":"Weather in Paris?"},
+  {"role":"assistant","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_1","name":"weather","input":{"city":"Paris"}}]},
+  {"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"18C"}]}
+],"tools":[{"name":"weather","input_schema":{"type":"object"}}]%s}`
+
+func TestMessages(t *testing.T) {
+	stub := &stubChatter{result: &chat.Result{
+		Text:      "It is 18C.",
+		ToolCalls: []chat.ToolCall{{ID: "call_2", Type: "function", Function: chat.ToolCallFunction{Name: "weather", Arguments: `{"city":"Lyon"}`}}},
+		Usage:     chat.Usage{InputTokens: 30, OutputTokens: 5, TotalTokens: 35},
+	}}
+	h := &Messages{Client: stub}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(strings.Replace(anthropicBody, "%s", "", 1))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	msgs := stub.req.Messages
+	if len(msgs) != 4 || msgs[0].Role != chat.RoleSystem || len(msgs[2].ToolCalls) != 1 || msgs[3].ToolCallID != "toolu_1" || msgs[3].Content != "18C" {
+		t.Fatalf("converted messages: %+v", msgs)
+	}
+	var resp anthropicResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Content) != 2 || resp.Content[1].Type != "tool_use" || *resp.StopReason != "tool_use" || resp.Usage.OutputTokens != 5 {
+		t.Fatalf("response: %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(strings.Replace(anthropicBody, "%s", `,"stream":true`, 1))))
+	var events []string
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, name)
+		}
+	}
+	want := "message_start content_block_start content_block_delta content_block_delta content_block_delta content_block_stop " +
+		"content_block_start content_block_delta content_block_stop message_delta message_stop"
+	if got := strings.Join(events, " "); got != want {
+		t.Fatalf("events:\n got %s\nwant %s", got, want)
+	}
+	if !strings.Contains(rec.Body.String(), `"partial_json":"{\"city\":\"Lyon\"}"`) {
+		t.Fatalf("tool input not streamed: %s", rec.Body)
+	}
+}
+
+func TestMessagesImages(t *testing.T) {
+	stub := &stubChatter{result: &chat.Result{Text: "A cat."}}
+	h := &Messages{Client: stub}
+	body := `{"model":"any","max_tokens":100,"messages":[{"role":"user","content":[
+  {"type":"image","source":{"type":"base64","media_type":"image/png","data":"cG5n"}},
+  {"type":"image","source":{"type":"url","url":"https://example.com/cat.jpg"}},
+  {"type":"text","text":"What are these?"}
+]}]}`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	msg := stub.req.Messages[0]
+	if msg.Content != "What are these?" || len(msg.Images) != 2 ||
+		msg.Images[0].URL != "data:image/png;base64,cG5n" || msg.Images[1].URL != "https://example.com/cat.jpg" {
+		t.Fatalf("converted message: %+v", msg)
+	}
+}
@@ -0,0 +1,162 @@
+// Package server provides HTTP handlers for running uniai-based gateways.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai"
+)
+
+// Pinger checks provider reachability. *uniai.Client implements it.
+type Pinger interface {
+	Ping(ctx context.Context, provider string) (time.Duration, error)
+}
+
+var _ Pinger = (*uniai.Client)(nil)
+
+// BreakerReporter exposes the circuit-breaker state of each provider, e.g.
+// "closed", "open" or "half_open". Providers with an open breaker are
+// reported as not ready without being pinged. *uniai.Client implements it
+// when its Config.Breaker is set.
+type BreakerReporter interface {
+	BreakerState(provider string) string
+}
+
+var _ BreakerReporter = (*uniai.Client)(nil)
+
+// Health serves /healthz (liveness) and /readyz (provider readiness).
+type Health struct {
+	Pinger    Pinger
+	Providers []string
+	Breakers  BreakerReporter
+	// Timeout bounds each provider check (default 5s).
+	Timeout time.Duration
+	// CacheTTL reuses the last readiness result so probes do not hit the
+	// providers on every request (default 10s).
+	CacheTTL time.Duration
+
+	mu      sync.Mutex
+	checked time.Time
+	last    ReadyReport
+}
+
+// ProviderStatus is the readiness of one provider.
+type ProviderStatus struct {
+	Ready bool `json:"ready"`
+	// Unchecked is set for providers the Pinger cannot check, such as
+	// providers without a model list. They count as ready.
+	Unchecked bool   `json:"unchecked,omitempty"`
+	LatencyMS int64  `json:"latency_ms,omitempty"`
+	Breaker   string `json:"breaker,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReadyReport is the body of /readyz.
+type ReadyReport struct {
+	Status    string                    `json:"status"`
+	Providers map[string]ProviderStatus `json:"providers"`
+}
+
+// Register mounts the handlers on mux.
+func (h *Health) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", h.Healthz)
+	mux.HandleFunc("/readyz", h.Readyz)
+}
+
+// Healthz reports that the process is up. It never calls providers.
+func (h *Health) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz reports 200 when every provider is reachable and 503 otherwise.
+func (h *Health) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// Check runs (or reuses a cached) readiness check of all providers. The
+// pings do not stop when ctx is canceled, because their result is shared
+// with later callers through the cache.
+func (h *Health) Check(ctx context.Context) ReadyReport {
+	ttl := h.CacheTTL
+	if ttl == 0 {
+		ttl = 10 * time.Second
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.checked.IsZero() && time.Since(h.checked) < ttl {
+		return h.last
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx = context.WithoutCancel(ctx)
+	report := ReadyReport{Status: "ok", Providers: map[string]ProviderStatus{}}
+	var (
+		wg  sync.WaitGroup
+		rmu sync.Mutex
+	)
+	for _, provider := range h.Providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			st := h.checkProvider(ctx, provider, timeout)
+			rmu.Lock()
+			report.Providers[provider] = st
+			if !st.Ready {
+				report.Status = "unavailable"
+			}
+			rmu.Unlock()
+		}()
+	}
+	wg.Wait()
+	h.checked = time.Now()
+	h.last = report
+	return report
+}
+
+func (h *Health) checkProvider(ctx context.Context, provider string, timeout time.Duration) ProviderStatus {
+	var st ProviderStatus
+	if h.Breakers != nil {
+		st.Breaker = h.Breakers.BreakerState(provider)
+		if st.Breaker == "open" {
+			st.Error = "circuit breaker open"
+			return st
+		}
+	}
+	if h.Pinger == nil {
+		st.Error = "no pinger configured"
+		return st
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	latency, err := h.Pinger.Ping(ctx, provider)
+	if errors.Is(err, uniai.ErrModelListUnsupported) {
+		st.Ready, st.Unchecked = true, true
+		return st
+	}
+	st.LatencyMS = latency.Milliseconds()
+	if err != nil {
+		st.Error = err.Error()
+		return st
+	}
+	st.Ready = true
+	return st
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai"
+)
+
+type fakePinger map[string]error
+
+func (f fakePinger) Ping(ctx context.Context, provider string) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return time.Millisecond, f[provider]
+}
+
+type fakeBreakers map[string]string
+
+func (f fakeBreakers) BreakerState(provider string) string { return f[provider] }
+
+func TestReadyz(t *testing.T) {
+	h := &Health{
+		Pinger:    fakePinger{"openai": nil, "anthropic": errors.New("unauthorized")},
+		Providers: []string{"openai", "anthropic", "azure"},
+		Breakers:  fakeBreakers{"openai": "closed", "azure": "open"},
+	}
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	var report ReadyReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !report.Providers["openai"].Ready || report.Providers["anthropic"].Ready || report.Providers["azure"].Breaker != "open" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from healthz, got %d", rec.Code)
+	}
+}
+
+func TestCheckIgnoresCallerCancel(t *testing.T) {
+	h := &Health{Pinger: fakePinger{"openai": nil}, Providers: []string{"openai"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := h.Check(ctx); report.Status != "ok" {
+		t.Fatalf("canceled probe reported %+v", report)
+	}
+	if report := h.Check(context.Background()); report.Status != "ok" {
+		t.Fatalf("cached report %+v", report)
+	}
+}
+
+func TestCheckUncheckableProvider(t *testing.T) {
+	h := &Health{
+		Pinger:    fakePinger{"house": fmt.Errorf("%w for provider house", uniai.ErrModelListUnsupported)},
+		Providers: []string{"house"},
+	}
+	report := h.Check(context.Background())
+	if st := report.Providers["house"]; report.Status != "ok" || !st.Ready || !st.Unchecked {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
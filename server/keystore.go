@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/encryption"
+)
+
+// FileKeyStore is a persistent KeyStore keeping keys and usage in one JSON
+// file, rewritten after every change. Reservations are held in memory
+// only; they belong to requests in flight and do not outlive the process.
+type FileKeyStore struct {
+	// Sealer, if set, encrypts the file, which holds the keys. Set it
+	// before the first call; a file written without it cannot be read with
+	// it, and vice versa.
+	Sealer *encryption.Sealer
+
+	path string
+	// mu orders loads and writes; mem guards the state itself.
+	mu     sync.Mutex
+	loaded bool
+	mem    *MemoryKeyStore
+}
+
+type keyFile struct {
+	Keys  []APIKey            `json:"keys"`
+	Usage map[string]KeyUsage `json:"usage,omitempty"`
+}
+
+// NewFileKeyStore returns a store backed by path. The file is created on
+// the first change.
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return &FileKeyStore{path: path, mem: NewMemoryKeyStore()}, nil
+}
+
+// PutKey adds or replaces a key.
+func (s *FileKeyStore) PutKey(ctx context.Context, key APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+	s.mem.PutKey(key)
+	return s.save(ctx)
+}
+
+func (s *FileKeyStore) GetKey(ctx context.Context, key string) (*APIKey, error) {
+	if err := s.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+	return s.mem.GetKey(ctx, key)
+}
+
+func (s *FileKeyStore) AddUsage(ctx context.Context, key, model string, usage chat.Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+	s.mem.AddUsage(ctx, key, model, usage)
+	return s.save(ctx)
+}
+
+func (s *FileKeyStore) Usage(ctx context.Context, key string) (KeyUsage, error) {
+	if err := s.ensureLoaded(ctx); err != nil {
+		return KeyUsage{}, err
+	}
+	return s.mem.Usage(ctx, key)
+}
+
+func (s *FileKeyStore) Reserve(ctx context.Context, key string, tokens, budget int64) error {
+	if err := s.ensureLoaded(ctx); err != nil {
+		return err
+	}
+	return s.mem.Reserve(ctx, key, tokens, budget)
+}
+
+func (s *FileKeyStore) Release(ctx context.Context, key string, tokens int64) error {
+	return s.mem.Release(ctx, key, tokens)
+}
+
+func (s *FileKeyStore) ensureLoaded(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(ctx)
+}
+
+// load reads the file once. s.mu must be held.
+func (s *FileKeyStore) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.Sealer != nil {
+		if data, err = s.Sealer.Open(ctx, data, []byte(filepath.Base(s.path))); err != nil {
+			return err
+		}
+	}
+	var f keyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	s.mem.mu.Lock()
+	for _, k := range f.Keys {
+		s.mem.keys[k.Key] = k
+	}
+	for k, u := range f.Usage {
+		s.mem.usage[k] = u
+	}
+	s.mem.mu.Unlock()
+	s.loaded = true
+	return nil
+}
+
+// save writes the file atomically. s.mu must be held.
+func (s *FileKeyStore) save(ctx context.Context) error {
+	s.mem.mu.RLock()
+	f := keyFile{Keys: make([]APIKey, 0, len(s.mem.keys)), Usage: map[string]KeyUsage{}}
+	for _, k := range s.mem.keys {
+		f.Keys = append(f.Keys, k)
+	}
+	for k, u := range s.mem.usage {
+		f.Usage[k] = u
+	}
+	s.mem.mu.RUnlock()
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if s.Sealer != nil {
+		if data, err = s.Sealer.Seal(ctx, data, []byte(filepath.Base(s.path))); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
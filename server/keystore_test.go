@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/encryption"
+)
+
+func TestFileKeyStorePersists(t *testing.T) {
+	ctx := context.Background()
+	sealer, err := encryption.New(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store, err := NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Sealer = sealer
+	if err := store.PutKey(ctx, APIKey{Key: "sk-secret", Tenant: "acme", TokenBudget: 100}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.AddUsage(ctx, "sk-secret", "gpt-4o", chat.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15}); err != nil {
+		t.Fatalf("add usage: %v", err)
+	}
+	if err := store.Reserve(ctx, "sk-secret", 90, 100); err == nil {
+		t.Fatalf("expected reservation over budget to fail")
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("sk-secret")) {
+		t.Fatalf("key stored in plain text")
+	}
+
+	reopened, err := NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.Sealer = sealer
+	key, err := reopened.GetKey(ctx, "sk-secret")
+	if err != nil || key.Tenant != "acme" || key.TokenBudget != 100 {
+		t.Fatalf("unexpected key %+v, %v", key, err)
+	}
+	if u, err := reopened.Usage(ctx, "sk-secret"); err != nil || u.Requests != 1 || u.TotalTokens != 15 {
+		t.Fatalf("unexpected usage %+v, %v", u, err)
+	}
+	if _, err := reopened.GetKey(ctx, "sk-other"); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+}
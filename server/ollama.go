@@ -0,0 +1,333 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/quailyquaily/uniai"
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+// ModelLister lists the models of a provider. *uniai.Client implements it.
+type ModelLister interface {
+	ListModels(ctx context.Context, provider string) ([]uniai.ModelInfo, error)
+}
+
+var _ ModelLister = (*uniai.Client)(nil)
+
+// OllamaVersion is the Ollama version reported by /api/version. Some
+// clients check it before using newer features such as tools.
+const OllamaVersion = "0.9.0"
+
+// Ollama serves the Ollama /api/chat, /api/tags and /api/version endpoints
+// on top of a uniai client, so desktop tools that only speak to Ollama can
+// use cloud providers. /api/chat streams newline-delimited JSON unless the
+// request sets "stream": false, like Ollama itself.
+type Ollama struct {
+	Client Chatter
+	// Options are applied to every request before the converted ones, e.g.
+	// to pick a provider.
+	Options []chat.Option
+	// Models are listed by /api/tags. Without them, the models of
+	// Providers are listed through Lister.
+	Models    []string
+	Lister    ModelLister
+	Providers []string
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []struct {
+		Function struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description,omitempty"`
+			Parameters  json.RawMessage `json:"parameters,omitempty"`
+		} `json:"function"`
+	} `json:"tools,omitempty"`
+	// Format is "json" or a JSON schema.
+	Format  json.RawMessage `json:"format,omitempty"`
+	Stream  *bool           `json:"stream,omitempty"`
+	Options struct {
+		Temperature *float64 `json:"temperature,omitempty"`
+		TopP        *float64 `json:"top_p,omitempty"`
+		NumPredict  int      `json:"num_predict,omitempty"`
+		Stop        []string `json:"stop,omitempty"`
+	} `json:"options"`
+}
+
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       time.Time     `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason,omitempty"`
+	TotalDuration   int64         `json:"total_duration,omitempty"`
+	PromptEvalCount int           `json:"prompt_eval_count,omitempty"`
+	EvalCount       int           `json:"eval_count,omitempty"`
+}
+
+// Register mounts the handlers on mux.
+func (h *Ollama) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/chat", h.Chat)
+	mux.HandleFunc("/api/tags", h.Tags)
+	mux.HandleFunc("/api/version", h.Version)
+}
+
+// Chat serves POST /api/chat.
+func (h *Ollama) Chat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOllamaError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	body, err := httputil.ReadBody(r.Body)
+	if err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var req ollamaChatRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts, err := ollamaOptions(&req)
+	if err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts = append(append([]chat.Option{}, h.Options...), opts...)
+	start := time.Now()
+	chunk := func(msg ollamaMessage) ollamaChatResponse {
+		msg.Role = chat.RoleAssistant
+		return ollamaChatResponse{Model: req.Model, CreatedAt: time.Now().UTC(), Message: msg}
+	}
+
+	if req.Stream != nil && !*req.Stream {
+		resp, err := h.Client.Chat(r.Context(), opts...)
+		if err != nil {
+			writeOllamaError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		RecordUsage(r.Context(), req.Model, resp.Usage)
+		out := chunk(ollamaMessage{Content: resp.Text, ToolCalls: toOllamaToolCalls(resp.ToolCalls)})
+		finishOllama(&out, resp, start)
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	enc := json.NewEncoder(w)
+	send := func(v ollamaChatResponse) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	streamed := false
+	opts = append(opts, chat.WithOnStream(func(ev chat.StreamEvent) error {
+		switch {
+		case ev.Delta != "":
+			streamed = true
+			return send(chunk(ollamaMessage{Content: ev.Delta}))
+		case ev.ToolCall != nil:
+			streamed = true
+			return send(chunk(ollamaMessage{ToolCalls: toOllamaToolCalls([]chat.ToolCall{*ev.ToolCall})}))
+		}
+		return nil
+	}))
+	resp, err := h.Client.Chat(r.Context(), opts...)
+	if err != nil {
+		if !started {
+			writeOllamaError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		_ = enc.Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	RecordUsage(r.Context(), req.Model, resp.Usage)
+	last := chunk(ollamaMessage{})
+	if !streamed {
+		// the provider answered without streaming
+		last.Message.Content = resp.Text
+		last.Message.ToolCalls = toOllamaToolCalls(resp.ToolCalls)
+	}
+	finishOllama(&last, resp, start)
+	_ = send(last)
+}
+
+// Tags serves GET /api/tags.
+func (h *Ollama) Tags(w http.ResponseWriter, r *http.Request) {
+	names := h.Models
+	if len(names) == 0 && h.Lister != nil {
+		for _, provider := range h.Providers {
+			models, err := h.Lister.ListModels(r.Context(), provider)
+			if err != nil {
+				writeOllamaError(w, http.StatusBadGateway, fmt.Sprintf("list %s models: %v", provider, err))
+				return
+			}
+			for _, m := range models {
+				names = append(names, m.ID)
+			}
+		}
+	}
+	type tag struct {
+		Name       string            `json:"name"`
+		Model      string            `json:"model"`
+		ModifiedAt time.Time         `json:"modified_at"`
+		Size       int64             `json:"size"`
+		Digest     string            `json:"digest"`
+		Details    map[string]string `json:"details"`
+	}
+	tags := make([]tag, 0, len(names))
+	for _, name := range names {
+		sum := sha256.Sum256([]byte(name))
+		tags = append(tags, tag{Name: name, Model: name, Digest: hex.EncodeToString(sum[:]), Details: map[string]string{"format": "remote"}})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"models": tags})
+}
+
+// Version serves GET /api/version.
+func (h *Ollama) Version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"version": OllamaVersion})
+}
+
+func ollamaOptions(req *ollamaChatRequest) ([]chat.Option, error) {
+	if len(req.Messages) == 0 {
+		return nil, fmt.Errorf("messages are required")
+	}
+	// Ollama tool calls have no IDs; results refer to calls in order.
+	var pending []string
+	next := 0
+	msgs := make([]chat.Message, 0, len(req.Messages))
+	for i, m := range req.Messages {
+		var images []chat.Image
+		for _, img := range m.Images {
+			if m.Role != chat.RoleUser {
+				return nil, fmt.Errorf("messages[%d]: images are only supported in user messages", i)
+			}
+			data, err := base64.StdEncoding.DecodeString(img)
+			if err != nil {
+				return nil, fmt.Errorf("messages[%d]: invalid base64 image", i)
+			}
+			images = append(images, chat.Image{URL: "data:" + http.DetectContentType(data) + ";base64," + img})
+		}
+		switch m.Role {
+		case chat.RoleTool:
+			id := ""
+			if len(pending) > 0 {
+				id, pending = pending[0], pending[1:]
+			}
+			msgs = append(msgs, chat.ToolResult(id, m.Content))
+		default:
+			msg := chat.Message{Role: m.Role, Content: m.Content, Images: images}
+			pending = pending[:0]
+			for _, tc := range m.ToolCalls {
+				next++
+				id := "call_" + strconv.Itoa(next)
+				pending = append(pending, id)
+				args := string(tc.Function.Arguments)
+				if args == "" || args == "null" {
+					args = "{}"
+				}
+				msg.ToolCalls = append(msg.ToolCalls, chat.ToolCall{ID: id, Type: "function", Function: chat.ToolCallFunction{Name: tc.Function.Name, Arguments: args}})
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	opts := []chat.Option{chat.WithMessages(msgs...)}
+	if req.Model != "" {
+		opts = append(opts, chat.WithModel(req.Model))
+	}
+	o := req.Options
+	if o.Temperature != nil {
+		opts = append(opts, chat.WithTemperature(*o.Temperature))
+	}
+	if o.TopP != nil {
+		opts = append(opts, chat.WithTopP(*o.TopP))
+	}
+	if o.NumPredict > 0 {
+		opts = append(opts, chat.WithMaxTokens(o.NumPredict))
+	}
+	if len(o.Stop) > 0 {
+		opts = append(opts, chat.WithStopWords(o.Stop...))
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]chat.Tool, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			tools = append(tools, chat.FunctionTool(t.Function.Name, t.Function.Description, t.Function.Parameters))
+		}
+		opts = append(opts, chat.WithTools(tools))
+	}
+	if len(req.Format) > 0 && string(req.Format) != "null" && string(req.Format) != `""` {
+		schema := string(req.Format)
+		if schema == `"json"` {
+			schema = `{"type":"object"}`
+		} else if req.Format[0] != '{' {
+			return nil, fmt.Errorf("format must be \"json\" or a JSON schema")
+		}
+		opts = append(opts, chat.WithGrammar(chat.Grammar{Syntax: chat.GrammarJSONSchema, Value: schema}))
+	}
+	return opts, nil
+}
+
+func toOllamaToolCalls(calls []chat.ToolCall) []ollamaToolCall {
+	out := make([]ollamaToolCall, 0, len(calls))
+	for _, c := range calls {
+		var tc ollamaToolCall
+		tc.Function.Name = c.Function.Name
+		tc.Function.Arguments = json.RawMessage(c.Function.Arguments)
+		if !json.Valid(tc.Function.Arguments) {
+			tc.Function.Arguments = json.RawMessage("{}")
+		}
+		out = append(out, tc)
+	}
+	return out
+}
+
+func finishOllama(out *ollamaChatResponse, resp *chat.Result, start time.Time) {
+	out.Done = true
+	out.DoneReason = "stop"
+	if chat.NormalizeFinishReason(resp.FinishReason) == chat.FinishReasonLength {
+		out.DoneReason = "length"
+	}
+	out.TotalDuration = time.Since(start).Nanoseconds()
+	out.PromptEvalCount = resp.Usage.InputTokens
+	out.EvalCount = resp.Usage.OutputTokens
+}
+
+// writeOllamaError writes an error the way Ollama does.
+func writeOllamaError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestOllama(t *testing.T) {
+	stub := &stubChatter{result: &chat.Result{Text: "Hello there.", Usage: chat.Usage{InputTokens: 9, OutputTokens: 3}}}
+	mux := http.NewServeMux()
+	(&Ollama{Client: stub, Models: []string{"gpt-5-mini"}}).Register(mux)
+
+	body := `{"model":"gpt-5-mini","stream":false,"messages":[
+  {"role":"user","content":"Weather?"},
+  {"role":"assistant","content":"","tool_calls":[{"function":{"name":"weather","arguments":{"city":"Paris"}}}]},
+  {"role":"tool","content":"18C","tool_name":"weather"}
+],"format":"json","options":{"num_predict":50}}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(body)))
+	var resp ollamaChatResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || !resp.Done || resp.Message.Content != "Hello there." || resp.EvalCount != 3 {
+		t.Fatalf("chat: %d %s", rec.Code, rec.Body)
+	}
+	msgs := stub.req.Messages
+	if len(msgs) != 3 || msgs[1].ToolCalls[0].ID == "" || msgs[2].ToolCallID != msgs[1].ToolCalls[0].ID {
+		t.Fatalf("tool results not matched to calls: %+v", msgs)
+	}
+	if stub.req.Options.Grammar == nil || *stub.req.Options.MaxTokens != 50 {
+		t.Fatalf("options not converted: %+v", stub.req.Options)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"model":"gpt-5-mini","messages":[{"role":"user","content":"hi"}]}`)))
+	var text strings.Builder
+	var last ollamaChatResponse
+	sc := bufio.NewScanner(rec.Body)
+	lines := 0
+	for sc.Scan() {
+		lines++
+		if err := json.Unmarshal(sc.Bytes(), &last); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		text.WriteString(last.Message.Content)
+	}
+	if lines != 3 || !last.Done || text.String() != "Hello there." {
+		t.Fatalf("stream: %d lines, %q, last %+v", lines, text.String(), last)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tags", nil))
+	if !strings.Contains(rec.Body.String(), `"name":"gpt-5-mini"`) {
+		t.Fatalf("tags: %s", rec.Body)
+	}
+}
+
+func TestOllamaImages(t *testing.T) {
+	stub := &stubChatter{result: &chat.Result{Text: "A cat."}}
+	mux := http.NewServeMux()
+	(&Ollama{Client: stub, Models: []string{"llava"}}).Register(mux)
+
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n"))
+	body := `{"model":"llava","stream":false,"messages":[{"role":"user","content":"What is this?","images":["` + png + `"]}]}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if images := stub.req.Messages[0].Images; len(images) != 1 || images[0].URL != "data:image/png;base64,"+png {
+		t.Fatalf("converted images: %+v", images)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/quailyquaily/uniai/chat"
+	uniaiopenai "github.com/quailyquaily/uniai/chat/openai"
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+// ChatCompletions serves POST /v1/chat/completions in the OpenAI format on
+// top of a uniai client, so existing OpenAI SDKs can use the gateway.
+// Streaming requests are rejected.
+type ChatCompletions struct {
+	Client *uniaiopenai.Client
+}
+
+func (h *ChatCompletions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	body, err := httputil.ReadBody(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	var probe struct {
+		Stream bool `json:"stream"`
+	}
+	_ = json.Unmarshal(body, &probe)
+	if probe.Stream {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "streaming is not supported")
+		return
+	}
+	var params openai.ChatCompletionNewParams
+	if err := json.Unmarshal(body, &params); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	resp, err := h.Client.CreateChatCompletion(r.Context(), params)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "api_error", err.Error())
+		return
+	}
+	RecordUsage(r.Context(), string(params.Model), chat.Usage{
+		InputTokens:  int(resp.Usage.PromptTokens),
+		OutputTokens: int(resp.Usage.CompletionTokens),
+		TotalTokens:  int(resp.Usage.TotalTokens),
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writeError writes an error in the OpenAI error envelope.
+func writeError(w http.ResponseWriter, status int, typ, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]string{"type": typ, "message": message},
+	})
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/quailyquaily/uniai/signature"
+)
+
+// Signatures rejects requests that are not signed with one of Secrets, for
+// gateways reached across a trust boundary. Clients sign with
+// signature.Transport. Put it in front of Tenants so unsigned requests are
+// rejected before key lookups.
+type Signatures struct {
+	// Secrets are tried in turn, so a secret can be rotated by listing the
+	// new one next to the old.
+	Secrets [][]byte
+	// Tolerance bounds the age of signatures (default
+	// signature.DefaultTolerance).
+	Tolerance time.Duration
+}
+
+func (s *Signatures) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := signature.VerifyRequest(r, s.Tolerance, s.Secrets...); err != nil {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", err.Error())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/internal/httputil"
+	"github.com/quailyquaily/uniai/tokens"
+	"github.com/quailyquaily/uniai/usage"
+)
+
+var (
+	// ErrKeyNotFound is returned by a KeyStore for unknown keys.
+	ErrKeyNotFound = errors.New("api key not found")
+	// ErrBudgetExhausted is returned by KeyStore.Reserve when a request
+	// does not fit in the key's remaining token budget.
+	ErrBudgetExhausted = errors.New("token budget exhausted")
+)
+
+// APIKey is a tenant-scoped virtual key for the gateway.
+type APIKey struct {
+	Key    string `json:"key"`
+	Tenant string `json:"tenant"`
+	// AllowedModels restricts the models the key may request. Entries ending
+	// in "*" match by prefix; an empty list allows every model.
+	AllowedModels     []string `json:"allowed_models,omitempty"`
+	RequestsPerMinute int      `json:"requests_per_minute,omitempty"`
+	// TokenBudget caps the total tokens the key may consume; 0 is unlimited.
+	TokenBudget int64 `json:"token_budget,omitempty"`
+	Disabled    bool  `json:"disabled,omitempty"`
+}
+
+// AllowsModel reports whether the key may use model.
+func (k *APIKey) AllowsModel(model string) bool {
+	if len(k.AllowedModels) == 0 {
+		return true
+	}
+	for _, allowed := range k.AllowedModels {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok && strings.HasPrefix(model, prefix) {
+			return true
+		}
+		if allowed == model {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyUsage is the usage attributed to a key.
+type KeyUsage struct {
+	Requests     int64 `json:"requests"`
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	TotalTokens  int64 `json:"total_tokens"`
+}
+
+// KeyStore persists keys and their usage. MemoryKeyStore and FileKeyStore
+// are the built-in backends; others, such as SQLite or Redis, implement the
+// same interface.
+type KeyStore interface {
+	GetKey(ctx context.Context, key string) (*APIKey, error)
+	AddUsage(ctx context.Context, key, model string, usage chat.Usage) error
+	Usage(ctx context.Context, key string) (KeyUsage, error)
+	// Reserve holds tokens for a request in flight. It fails with
+	// ErrBudgetExhausted, reserving nothing, when the key's total tokens
+	// plus its reservations plus tokens exceed budget. The check and the
+	// reservation must be atomic.
+	Reserve(ctx context.Context, key string, tokens, budget int64) error
+	// Release returns tokens held by Reserve.
+	Release(ctx context.Context, key string, tokens int64) error
+}
+
+// MemoryKeyStore is an in-process KeyStore.
+type MemoryKeyStore struct {
+	mu       sync.RWMutex
+	keys     map[string]APIKey
+	usage    map[string]KeyUsage
+	reserved map[string]int64
+}
+
+func NewMemoryKeyStore(keys ...APIKey) *MemoryKeyStore {
+	s := &MemoryKeyStore{keys: map[string]APIKey{}, usage: map[string]KeyUsage{}, reserved: map[string]int64{}}
+	for _, k := range keys {
+		s.keys[k.Key] = k
+	}
+	return s
+}
+
+// PutKey adds or replaces a key.
+func (s *MemoryKeyStore) PutKey(key APIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.Key] = key
+}
+
+func (s *MemoryKeyStore) GetKey(ctx context.Context, key string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return &k, nil
+}
+
+func (s *MemoryKeyStore) AddUsage(ctx context.Context, key, model string, usage chat.Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.usage[key]
+	u.Requests++
+	u.InputTokens += int64(usage.InputTokens)
+	u.OutputTokens += int64(usage.OutputTokens)
+	u.TotalTokens += int64(usage.TotalTokens)
+	s.usage[key] = u
+	return nil
+}
+
+func (s *MemoryKeyStore) Usage(ctx context.Context, key string) (KeyUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.usage[key], nil
+}
+
+func (s *MemoryKeyStore) Reserve(ctx context.Context, key string, tokens, budget int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.usage[key].TotalTokens+s.reserved[key]+tokens > budget {
+		return ErrBudgetExhausted
+	}
+	s.reserved[key] += tokens
+	return nil
+}
+
+func (s *MemoryKeyStore) Release(ctx context.Context, key string, tokens int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reserved[key] -= tokens; s.reserved[key] <= 0 {
+		delete(s.reserved, key)
+	}
+	return nil
+}
+
+// Tenants authenticates requests by virtual key and enforces the key's model
+// allow-list, rate limit and token budget. Handlers report usage with
+// RecordUsage, which is attributed to the key.
+//
+// Requests with a body must name an allowed model when the key has an
+// allow-list. Under a token budget, each request reserves its estimated
+// input plus its max_tokens until it completes, so concurrent requests
+// cannot overrun the budget together.
+type Tenants struct {
+	Store KeyStore
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+type tenantContextKey struct{}
+
+type tenantContext struct {
+	key   *APIKey
+	store KeyStore
+}
+
+// TenantFromContext returns the key that authenticated the request.
+func TenantFromContext(ctx context.Context) (*APIKey, bool) {
+	tc, ok := ctx.Value(tenantContextKey{}).(*tenantContext)
+	if !ok {
+		return nil, false
+	}
+	return tc.key, true
+}
+
+// RecordUsage attributes usage to the key that authenticated the request.
+// It is a no-op outside the Tenants middleware.
+func RecordUsage(ctx context.Context, model string, usage chat.Usage) {
+	tc, ok := ctx.Value(tenantContextKey{}).(*tenantContext)
+	if !ok {
+		return
+	}
+	_ = tc.store.AddUsage(context.WithoutCancel(ctx), tc.key.Key, model, usage)
+}
+
+func (t *Tenants) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := bearerToken(r)
+		if raw == "" {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", "missing api key")
+			return
+		}
+		key, err := t.Store.GetKey(r.Context(), raw)
+		if err != nil || key.Disabled {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid api key")
+			return
+		}
+		var body []byte
+		if r.Body != nil && (len(key.AllowedModels) > 0 || key.TokenBudget > 0) {
+			body, err = httputil.ReadBody(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		var probe struct {
+			Model               string `json:"model"`
+			MaxTokens           int64  `json:"max_tokens"`
+			MaxCompletionTokens int64  `json:"max_completion_tokens"`
+		}
+		_ = json.Unmarshal(body, &probe)
+		if len(key.AllowedModels) > 0 && len(body) > 0 {
+			if probe.Model == "" {
+				writeError(w, http.StatusForbidden, "permission_error", "model is required for this key")
+				return
+			}
+			if !key.AllowsModel(probe.Model) {
+				writeError(w, http.StatusForbidden, "permission_error", "model not allowed for this key")
+				return
+			}
+		}
+		if !t.allow(key) {
+			writeError(w, http.StatusTooManyRequests, "rate_limit_error", "rate limit exceeded")
+			return
+		}
+		if key.TokenBudget > 0 {
+			reserve := int64(tokens.Estimate(string(body))) + max(probe.MaxTokens, probe.MaxCompletionTokens)
+			if err := t.Store.Reserve(r.Context(), key.Key, reserve, key.TokenBudget); err != nil {
+				if errors.Is(err, ErrBudgetExhausted) {
+					writeError(w, http.StatusTooManyRequests, "insufficient_quota", "token budget exhausted")
+				} else {
+					writeError(w, http.StatusInternalServerError, "api_error", err.Error())
+				}
+				return
+			}
+			defer t.Store.Release(context.WithoutCancel(r.Context()), key.Key, reserve)
+		}
+		ctx := context.WithValue(r.Context(), tenantContextKey{}, &tenantContext{key: key, store: t.Store})
+		ctx = usage.WithTenant(ctx, key.Tenant)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// allow applies a fixed one-minute window per key.
+func (t *Tenants) allow(key *APIKey) bool {
+	if key.RequestsPerMinute <= 0 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.windows == nil {
+		t.windows = map[string]*rateWindow{}
+	}
+	now := time.Now()
+	w := t.windows[key.Key]
+	if w == nil || now.Sub(w.start) >= time.Minute {
+		w = &rateWindow{start: now}
+		t.windows[key.Key] = w
+	}
+	if w.count >= key.RequestsPerMinute {
+		return false
+	}
+	w.count++
+	return true
+}
+
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get("x-api-key"); key != "" {
+		// Anthropic clients
+		return strings.TrimSpace(key)
+	}
+	return strings.TrimSpace(r.Header.Get("api-key"))
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestTenantsMiddleware(t *testing.T) {
+	store := NewMemoryKeyStore(APIKey{
+		Key:               "sk-a",
+		Tenant:            "acme",
+		AllowedModels:     []string{"gpt-4o*"},
+		RequestsPerMinute: 2,
+		TokenBudget:       100,
+	})
+	tenants := &Tenants{Store: store}
+	handler := tenants.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := TenantFromContext(r.Context()); !ok || key.Tenant != "acme" {
+			t.Fatalf("tenant missing from context")
+		}
+		RecordUsage(r.Context(), "gpt-4o-mini", chat.Usage{TotalTokens: 60})
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(key, model string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"`+model+`"}`))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := do("", "gpt-4o"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", code)
+	}
+	if code := do("sk-a", "claude-3"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for disallowed model, got %d", code)
+	}
+	if code := do("sk-a", ""); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for missing model, got %d", code)
+	}
+	// rejected models do not count against the rate limit
+	for range 2 {
+		if code := do("sk-a", "gpt-4o-mini"); code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", code)
+		}
+	}
+	if code := do("sk-a", "gpt-4o-mini"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 from rate limit, got %d", code)
+	}
+	usage, _ := store.Usage(context.Background(), "sk-a")
+	if usage.TotalTokens != 120 || usage.Requests != 2 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestTenantsBudgetReservation(t *testing.T) {
+	store := NewMemoryKeyStore(APIKey{Key: "sk-a", Tenant: "acme", TokenBudget: 100})
+	tenants := &Tenants{Store: store}
+	inFlight := make(chan struct{})
+	finish := make(chan struct{})
+	handler := tenants.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight <- struct{}{}
+		<-finish
+		RecordUsage(r.Context(), "gpt-4o", chat.Usage{TotalTokens: 50})
+	}))
+	do := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","max_tokens":60}`))
+		req.Header.Set("Authorization", "Bearer sk-a")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	first := make(chan int)
+	go func() { first <- do() }()
+	<-inFlight
+	// the first request holds 60+ tokens of the budget while in flight
+	if code := do(); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 while the budget is reserved, got %d", code)
+	}
+	close(finish)
+	if code := <-first; code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if err := store.Reserve(context.Background(), "sk-a", 50, 100); err != nil {
+		t.Fatalf("reservation not released: %v", err)
+	}
+}
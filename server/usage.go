@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/usage"
+)
+
+// UsageReport serves the aggregated usage of Aggregator as JSON. Query
+// parameters: from and to (YYYY-MM-DD, to exclusive), tenant, and group_by
+// (comma-separated provider, model, tenant, day). It performs no
+// authentication; mount it behind an admin-only route.
+type UsageReport struct {
+	Aggregator *usage.Aggregator
+}
+
+func (h *UsageReport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	query := usage.Query{Tenant: q.Get("tenant")}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid "+p.name+" date")
+			return
+		}
+		*p.dst = t
+	}
+	if g := q.Get("group_by"); g != "" {
+		for _, dim := range strings.Split(g, ",") {
+			dim = strings.TrimSpace(dim)
+			switch dim {
+			case usage.ByProvider, usage.ByModel, usage.ByTenant, usage.ByDay:
+				query.GroupBy = append(query.GroupBy, dim)
+			default:
+				writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid group_by "+dim)
+				return
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"data": h.Aggregator.Report(query)})
+}
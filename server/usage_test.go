@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/usage"
+)
+
+func TestUsageReport(t *testing.T) {
+	agg := usage.NewAggregator(nil)
+	agg.RecordUsage(usage.WithTenant(context.Background(), "acme"), "openai", "gpt-4o", chat.Usage{TotalTokens: 10})
+	agg.RecordUsage(usage.WithTenant(context.Background(), "beta"), "openai", "gpt-4o", chat.Usage{TotalTokens: 5})
+	h := &UsageReport{Aggregator: agg}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/usage?group_by=tenant", nil))
+	var out struct {
+		Data []usage.Row `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out.Data) != 2 || out.Data[0].Tenant != "acme" || out.Data[0].TotalTokens != 10 {
+		t.Fatalf("unexpected report: %+v", out.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/usage?group_by=region", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,252 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/embedding"
+	"github.com/quailyquaily/uniai/tokens"
+	"github.com/quailyquaily/uniai/vecmath"
+)
+
+// Embedder is the subset of uniai.Client a Memory needs.
+type Embedder interface {
+	Embedding(ctx context.Context, opts ...embedding.Option) (*embedding.Result, error)
+}
+
+// MemoryEntry is one remembered text.
+type MemoryEntry struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// NodeID is the session node the entry was taken from, if any.
+	NodeID    string    `json:"node_id,omitempty"`
+	Vector    []float32 `json:"vector"`
+	CreatedAt time.Time `json:"created_at"`
+	// Score is the cosine similarity to the query, set by Recall.
+	Score float64 `json:"score,omitempty"`
+}
+
+const (
+	// DefaultMemoryTokens is used when MemoryConfig.MaxTokens is not set.
+	DefaultMemoryTokens = 1000
+	// DefaultMemoryTopK is used when MemoryConfig.TopK is not set.
+	DefaultMemoryTopK = 8
+	// DefaultMemoryPrompt is used when MemoryConfig.Prompt is not set.
+	DefaultMemoryPrompt = "Relevant notes from earlier conversations:"
+)
+
+// MemoryConfig configures a Memory.
+type MemoryConfig struct {
+	Embedder Embedder
+	// Options are applied to every embedding call, e.g. to pick the model.
+	Options []embedding.Option
+	// MaxTokens caps the estimated tokens of the memories injected into one
+	// request (default 1000).
+	MaxTokens int
+	// TopK is the number of candidates considered per request (default 8).
+	TopK int
+	// MinScore drops memories less similar to the query than this.
+	MinScore float64
+	// Prompt heads the system message listing the memories.
+	Prompt string
+}
+
+// Memory is a long-term store for a Session. Each turn is embedded and
+// kept, and the turns most similar to a new user message are added to the
+// request as a system message, as far as MaxTokens allows. Turns already
+// on the active branch are not repeated. Memory is safe for concurrent use
+// and may be shared by several sessions.
+type Memory struct {
+	cfg MemoryConfig
+
+	mu      sync.Mutex
+	entries []*MemoryEntry
+	nextID  int
+}
+
+func NewMemory(cfg MemoryConfig) *Memory {
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = DefaultMemoryTokens
+	}
+	if cfg.TopK <= 0 {
+		cfg.TopK = DefaultMemoryTopK
+	}
+	if cfg.Prompt == "" {
+		cfg.Prompt = DefaultMemoryPrompt
+	}
+	return &Memory{cfg: cfg}
+}
+
+// Add embeds text and stores it.
+func (m *Memory) Add(ctx context.Context, text string) (MemoryEntry, error) {
+	return m.add(ctx, text, "")
+}
+
+func (m *Memory) add(ctx context.Context, text, nodeID string) (MemoryEntry, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return MemoryEntry{}, fmt.Errorf("memory text is empty")
+	}
+	vec, err := m.embed(ctx, text)
+	if err != nil {
+		return MemoryEntry{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	e := &MemoryEntry{
+		ID:        "m" + strconv.Itoa(m.nextID),
+		Text:      text,
+		NodeID:    nodeID,
+		Vector:    vec,
+		CreatedAt: time.Now(),
+	}
+	m.entries = append(m.entries, e)
+	return *e, nil
+}
+
+// Import stores entries as they are, for example ones saved from Entries.
+// Entries without a vector are embedded first.
+func (m *Memory) Import(ctx context.Context, entries ...MemoryEntry) error {
+	for _, e := range entries {
+		if len(e.Vector) == 0 {
+			vec, err := m.embed(ctx, e.Text)
+			if err != nil {
+				return err
+			}
+			e.Vector = vec
+		}
+		m.mu.Lock()
+		m.nextID++
+		if e.ID == "" {
+			e.ID = "m" + strconv.Itoa(m.nextID)
+		}
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now()
+		}
+		e.Score = 0
+		m.entries = append(m.entries, &e)
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// Forget removes the entry with id.
+func (m *Memory) Forget(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, e := range m.entries {
+		if e.ID == id {
+			m.entries = slices.Delete(m.entries, i, i+1)
+			return nil
+		}
+	}
+	return fmt.Errorf("memory %s not found", id)
+}
+
+// Clear removes every entry.
+func (m *Memory) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = nil
+}
+
+// Entries returns copies of the stored entries, oldest first.
+func (m *Memory) Entries() []MemoryEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]MemoryEntry, len(m.entries))
+	for i, e := range m.entries {
+		out[i] = *e
+	}
+	return out
+}
+
+// Recall returns the entries most similar to query, best first, that fit
+// in MaxTokens.
+func (m *Memory) Recall(ctx context.Context, query string) ([]MemoryEntry, error) {
+	return m.recall(ctx, query, nil)
+}
+
+func (m *Memory) recall(ctx context.Context, query string, skipNodes map[string]bool) ([]MemoryEntry, error) {
+	m.mu.Lock()
+	var candidates []*MemoryEntry
+	for _, e := range m.entries {
+		if e.NodeID == "" || !skipNodes[e.NodeID] {
+			candidates = append(candidates, e)
+		}
+	}
+	m.mu.Unlock()
+	if len(candidates) == 0 || strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	vec, err := m.embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(candidates))
+	for i, e := range candidates {
+		vectors[i] = e.Vector
+	}
+	var out []MemoryEntry
+	budget := m.cfg.MaxTokens
+	for _, match := range vecmath.TopK(vec, vectors, m.cfg.TopK) {
+		if match.Score < m.cfg.MinScore {
+			break
+		}
+		e := *candidates[match.Index]
+		n := tokens.Estimate(e.Text)
+		if n > budget {
+			continue
+		}
+		budget -= n
+		e.Score = match.Score
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func (m *Memory) embed(ctx context.Context, text string) ([]float32, error) {
+	if m.cfg.Embedder == nil {
+		return nil, fmt.Errorf("memory has no embedder")
+	}
+	opts := append(slices.Clone(m.cfg.Options), embedding.WithInputs(embedding.Input{Text: text}))
+	res, err := m.cfg.Embedder.Embedding(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("embed memory: %w", err)
+	}
+	if res == nil || len(res.Data) == 0 {
+		return nil, fmt.Errorf("embed memory: no embedding returned")
+	}
+	return vecmath.DecodeBase64(res.Data[0].Embedding)
+}
+
+// inject adds the recalled entries to msgs as a system message after the
+// leading system messages.
+func (m *Memory) inject(msgs []chat.Message, entries []MemoryEntry) []chat.Message {
+	if len(entries) == 0 {
+		return msgs
+	}
+	var b strings.Builder
+	b.WriteString(m.cfg.Prompt)
+	for _, e := range entries {
+		b.WriteString("\n- ")
+		b.WriteString(strings.ReplaceAll(e.Text, "\n", "\n  "))
+	}
+	at := 0
+	for at < len(msgs) && msgs[at].Role == chat.RoleSystem {
+		at++
+	}
+	return slices.Insert(slices.Clone(msgs), at, chat.System(b.String()))
+}
+
+// turnText is how a user message and its reply are remembered.
+func turnText(user, assistant string) string {
+	return "User: " + strings.TrimSpace(user) + "\nAssistant: " + strings.TrimSpace(assistant)
+}
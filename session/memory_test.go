@@ -0,0 +1,104 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/embedding"
+)
+
+// keywordEmbedder embeds a text as counts of a few keywords.
+type keywordEmbedder struct{ words []string }
+
+func (e keywordEmbedder) Embedding(ctx context.Context, opts ...embedding.Option) (*embedding.Result, error) {
+	req := embedding.BuildRequest(opts...)
+	res := &embedding.Result{}
+	for i, in := range req.Input {
+		buf := make([]byte, 4*len(e.words))
+		for j, w := range e.words {
+			n := float32(strings.Count(strings.ToLower(in.Text), w))
+			binary.LittleEndian.PutUint32(buf[4*j:], math.Float32bits(n))
+		}
+		res.Data = append(res.Data, embedding.Item{Embedding: base64.StdEncoding.EncodeToString(buf), Index: i})
+	}
+	return res, nil
+}
+
+type recordingChatter struct{ requests []*chat.Request }
+
+func (c *recordingChatter) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.requests = append(c.requests, req)
+	return &chat.Result{Text: "noted"}, nil
+}
+
+func TestSessionMemory(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory(MemoryConfig{Embedder: keywordEmbedder{words: []string{"cat", "invoice", "weather"}}, MinScore: 0.5})
+	if _, err := mem.Add(ctx, "The user's cat is called Miso."); err != nil {
+		t.Fatal(err)
+	}
+	weather, err := mem.Add(ctx, "The user dislikes rainy weather.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chatter := &recordingChatter{}
+	s := New(chatter)
+	s.Memory = mem
+	if _, err := s.Send(ctx, "The invoice is due Friday."); err != nil {
+		t.Fatal(err)
+	}
+	if got := chatter.requests[0].Messages; len(got) != 1 {
+		t.Fatalf("no memory should match, got %+v", got)
+	}
+	if len(mem.Entries()) != 3 {
+		t.Fatalf("the turn should be remembered: %+v", mem.Entries())
+	}
+
+	// a new conversation recalls the cat and the invoice turn
+	s.Checkout("")
+	if _, err := s.Send(ctx, "What is my cat's name, and when is the invoice due?"); err != nil {
+		t.Fatal(err)
+	}
+	got := chatter.requests[1].Messages
+	if len(got) != 2 || got[0].Role != chat.RoleSystem || !strings.Contains(got[0].Content, "Miso") ||
+		!strings.Contains(got[0].Content, "User: The invoice is due Friday.") || strings.Contains(got[0].Content, "weather") {
+		t.Fatalf("unexpected messages %+v", got)
+	}
+
+	// turns on the active branch are not repeated
+	if _, err := s.Send(ctx, "And the invoice amount?"); err != nil {
+		t.Fatal(err)
+	}
+	if got := chatter.requests[2].Messages; strings.Contains(got[0].Content, "my cat's name") {
+		t.Fatalf("active branch turn was injected: %+v", got[0])
+	}
+
+	if err := mem.Forget(weather.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.Forget(weather.ID); err == nil {
+		t.Fatal("forgetting twice should fail")
+	}
+}
+
+func TestSessionMemorySkipsNoStore(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory(MemoryConfig{Embedder: keywordEmbedder{words: []string{"cat"}}})
+	s := New(&recordingChatter{})
+	s.Memory = mem
+	if _, err := s.Send(ctx, "My cat is called Miso.", chat.WithNoStore()); err != nil {
+		t.Fatal(err)
+	}
+	if entries := mem.Entries(); len(entries) != 0 {
+		t.Fatalf("no_store turn was remembered: %+v", entries)
+	}
+}
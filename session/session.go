@@ -0,0 +1,301 @@
+// Package session keeps a conversation as a tree of messages so chat UIs can
+// edit earlier turns, regenerate replies and switch between the resulting
+// branches. The active branch is the path from the root to the head node.
+package session
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai/attachment"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Chatter is the subset of uniai.Client a session needs.
+type Chatter interface {
+	Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error)
+}
+
+// Node is one message in the conversation tree.
+type Node struct {
+	ID        string       `json:"id"`
+	ParentID  string       `json:"parent_id,omitempty"`
+	Message   chat.Message `json:"message"`
+	Children  []string     `json:"children,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Branch describes a leaf of the tree.
+type Branch struct {
+	HeadID string `json:"head_id"`
+	Length int    `json:"length"`
+	Active bool   `json:"active"`
+}
+
+// Session is safe for concurrent use, but model calls are not serialized:
+// two concurrent Sends extend the same head into sibling branches.
+type Session struct {
+	Client Chatter
+	// Options are applied to every model call before per-call options.
+	Options []chat.Option
+	// Memory, when set, stores each turn and adds the most relevant past
+	// turns to new requests. Turns sent with chat.WithNoStore are not
+	// stored. See Memory.
+	Memory *Memory
+	// Attachments, when set, keeps the inline images of messages sent with
+	// SendMessage and Edit: the tree holds attachment URLs (see
+	// attachment.Offload), which are resolved again for each model call.
+	Attachments attachment.Store
+
+	mu     sync.Mutex
+	nodes  map[string]*Node
+	roots  []string
+	head   string
+	nextID int
+}
+
+func New(client Chatter, opts ...chat.Option) *Session {
+	return &Session{Client: client, Options: opts, nodes: map[string]*Node{}}
+}
+
+// Append adds msg after the head and makes it the new head.
+func (s *Session) Append(msg chat.Message) *Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appendLocked(s.head, msg)
+}
+
+func (s *Session) appendLocked(parentID string, msg chat.Message) *Node {
+	if s.nodes == nil {
+		s.nodes = map[string]*Node{}
+	}
+	s.nextID++
+	n := &Node{
+		ID:        strconv.Itoa(s.nextID),
+		ParentID:  parentID,
+		Message:   msg,
+		CreatedAt: time.Now(),
+	}
+	s.nodes[n.ID] = n
+	if parent := s.nodes[parentID]; parent != nil {
+		parent.Children = append(parent.Children, n.ID)
+	} else {
+		s.roots = append(s.roots, n.ID)
+	}
+	s.head = n.ID
+	return n
+}
+
+// Head returns the ID of the last message on the active branch.
+func (s *Session) Head() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.head
+}
+
+// Node returns a copy of the node with id.
+func (s *Session) Node(id string) (Node, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.nodes[id]
+	if !ok {
+		return Node{}, false
+	}
+	cp := *n
+	cp.Children = append([]string(nil), n.Children...)
+	return cp, true
+}
+
+// Messages returns the active branch.
+func (s *Session) Messages() []chat.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pathLocked(s.head)
+}
+
+func (s *Session) pathLocked(id string) []chat.Message {
+	var msgs []chat.Message
+	for n := s.nodes[id]; n != nil; n = s.nodes[n.ParentID] {
+		msgs = append(msgs, n.Message)
+	}
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs
+}
+
+// Checkout makes id the head. Checking out an inner node forks: the next
+// appended message starts a new branch there. An empty id checks out the
+// empty conversation.
+func (s *Session) Checkout(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id != "" && s.nodes[id] == nil {
+		return fmt.Errorf("session node %s not found", id)
+	}
+	s.head = id
+	return nil
+}
+
+// Siblings returns the IDs of the alternatives to id, including id itself,
+// in creation order.
+func (s *Session) Siblings(id string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.nodes[id]
+	if n == nil {
+		return nil
+	}
+	if parent := s.nodes[n.ParentID]; parent != nil {
+		return append([]string(nil), parent.Children...)
+	}
+	return append([]string(nil), s.roots...)
+}
+
+// Branches returns every leaf of the tree.
+func (s *Session) Branches() []Branch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Branch
+	var walk func(ids []string, depth int)
+	walk = func(ids []string, depth int) {
+		for _, id := range ids {
+			n := s.nodes[id]
+			if len(n.Children) == 0 {
+				out = append(out, Branch{HeadID: id, Length: depth + 1, Active: id == s.head})
+				continue
+			}
+			walk(n.Children, depth+1)
+		}
+	}
+	walk(s.roots, 0)
+	return out
+}
+
+// Send appends a user message, asks the model for a reply on the active
+// branch and appends it. If the call fails, the session is left unchanged.
+func (s *Session) Send(ctx context.Context, text string, opts ...chat.Option) (*chat.Result, error) {
+	return s.SendMessage(ctx, chat.User(text), opts...)
+}
+
+// SendMessage is Send for a message with more than text, such as images.
+func (s *Session) SendMessage(ctx context.Context, msg chat.Message, opts ...chat.Option) (*chat.Result, error) {
+	s.mu.Lock()
+	parent := s.head
+	s.mu.Unlock()
+	return s.reply(ctx, parent, &msg, opts)
+}
+
+// Edit replaces the user message id with text on a new branch and asks for
+// a fresh reply. The original branch is kept, and the new one is only added
+// if the call succeeds.
+func (s *Session) Edit(ctx context.Context, id, text string, opts ...chat.Option) (*chat.Result, error) {
+	s.mu.Lock()
+	n := s.nodes[id]
+	if n == nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("session node %s not found", id)
+	}
+	if n.Message.Role != chat.RoleUser {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("session node %s is a %s message, not user", id, n.Message.Role)
+	}
+	msg := n.Message
+	msg.Content = text
+	parent := n.ParentID
+	s.mu.Unlock()
+	return s.reply(ctx, parent, &msg, opts)
+}
+
+// Regenerate asks for another reply to the message before the head, which
+// must be an assistant message, using opts on top of the session options.
+// The new reply becomes a sibling of the old one. If the call fails, the
+// head is left on the old reply.
+func (s *Session) Regenerate(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	s.mu.Lock()
+	n := s.nodes[s.head]
+	if n == nil || n.Message.Role != chat.RoleAssistant {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("regenerate requires an assistant message at the head")
+	}
+	parent := n.ParentID
+	s.mu.Unlock()
+	return s.reply(ctx, parent, nil, opts)
+}
+
+// reply asks for a reply to the path to parent followed by msg, if set, and
+// appends both once the call succeeds.
+func (s *Session) reply(ctx context.Context, parent string, msg *chat.Message, opts []chat.Option) (*chat.Result, error) {
+	s.mu.Lock()
+	msgs := s.pathLocked(parent)
+	onPath := map[string]bool{}
+	for n := s.nodes[parent]; n != nil; n = s.nodes[n.ParentID] {
+		onPath[n.ID] = true
+	}
+	s.mu.Unlock()
+	if msg != nil {
+		if s.Attachments != nil {
+			stored, err := attachment.Offload(ctx, s.Attachments, []chat.Message{*msg})
+			if err != nil {
+				return nil, err
+			}
+			msg = &stored[0]
+		}
+		msgs = append(msgs, *msg)
+	}
+
+	var warnings []string
+	var query string
+	if len(msgs) > 0 && msgs[len(msgs)-1].Role == chat.RoleUser {
+		query = msgs[len(msgs)-1].Content
+	}
+	if s.Memory != nil && query != "" {
+		recalled, err := s.Memory.recall(ctx, query, onPath)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("memory recall failed: %v", err))
+		}
+		msgs = s.Memory.inject(msgs, recalled)
+	}
+
+	if s.Attachments != nil {
+		resolved, err := attachment.Resolve(ctx, s.Attachments, msgs)
+		if err != nil {
+			return nil, err
+		}
+		msgs = resolved
+	}
+	callOpts := append(append([]chat.Option{}, s.Options...), opts...)
+	callOpts = append(callOpts, chat.WithReplaceMessages(msgs...))
+	resp, err := s.Client.Chat(ctx, callOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if msg != nil {
+		parent = s.appendLocked(parent, *msg).ID
+	}
+	n := s.appendLocked(parent, chat.Message{
+		Role:      chat.RoleAssistant,
+		Content:   resp.Text,
+		ToolCalls: resp.ToolCalls,
+	})
+	s.mu.Unlock()
+	if s.Memory != nil && query != "" && resp.Text != "" && !noStore(callOpts) {
+		if _, err := s.Memory.add(ctx, turnText(query, resp.Text), n.ID); err != nil {
+			warnings = append(warnings, fmt.Sprintf("memory store failed: %v", err))
+		}
+	}
+	resp.Warnings = append(resp.Warnings, warnings...)
+	return resp, nil
+}
+
+// noStore reports whether opts ask providers not to retain the request,
+// in which case the turn is not remembered either.
+func noStore(opts []chat.Option) bool {
+	req, err := chat.BuildRequest(opts...)
+	return err == nil && req.Options.NoStore
+}
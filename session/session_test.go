@@ -0,0 +1,125 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/quailyquaily/uniai/attachment"
+	"github.com/quailyquaily/uniai/chat"
+)
+
+type countingChatter struct{ calls int }
+
+func (c *countingChatter) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	req, err := chat.BuildRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.calls++
+	last := req.Messages[len(req.Messages)-1].Content
+	return &chat.Result{Text: fmt.Sprintf("reply %d to %s", c.calls, last)}, nil
+}
+
+func TestSessionBranching(t *testing.T) {
+	s := New(&countingChatter{})
+	ctx := context.Background()
+	if _, err := s.Send(ctx, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	first := s.Head()
+	if _, err := s.Regenerate(ctx, chat.WithTemperature(1)); err != nil {
+		t.Fatal(err)
+	}
+	if siblings := s.Siblings(s.Head()); len(siblings) != 2 || siblings[0] != first {
+		t.Fatalf("regenerated reply should be a sibling: %v", siblings)
+	}
+	if got := s.Messages(); len(got) != 2 || got[1].Content != "reply 2 to hello" {
+		t.Fatalf("unexpected active branch: %+v", got)
+	}
+
+	reply, _ := s.Node(first)
+	if _, err := s.Edit(ctx, reply.ParentID, "hi there"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Messages(); got[0].Content != "hi there" || got[1].Content != "reply 3 to hi there" {
+		t.Fatalf("unexpected edited branch: %+v", got)
+	}
+	if branches := s.Branches(); len(branches) != 3 {
+		t.Fatalf("expected 3 branches, got %+v", branches)
+	}
+
+	if err := s.Checkout(first); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Messages(); got[1].Content != "reply 1 to hello" {
+		t.Fatalf("checkout did not restore branch: %+v", got)
+	}
+	if _, err := s.Edit(ctx, first, "x"); err == nil {
+		t.Fatalf("editing an assistant message should fail")
+	}
+}
+
+type failingChatter struct{}
+
+func (failingChatter) Chat(ctx context.Context, opts ...chat.Option) (*chat.Result, error) {
+	return nil, fmt.Errorf("unavailable")
+}
+
+func TestSessionFailedCallKeepsHead(t *testing.T) {
+	c := &countingChatter{}
+	s := New(c)
+	ctx := context.Background()
+	if _, err := s.Send(ctx, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	head := s.Head()
+	reply, _ := s.Node(head)
+
+	s.Client = failingChatter{}
+	if _, err := s.Send(ctx, "again"); err == nil {
+		t.Fatalf("expected send error")
+	}
+	if _, err := s.Edit(ctx, reply.ParentID, "hi there"); err == nil {
+		t.Fatalf("expected edit error")
+	}
+	if _, err := s.Regenerate(ctx); err == nil {
+		t.Fatalf("expected regenerate error")
+	}
+	if s.Head() != head || len(s.Branches()) != 1 || len(s.Messages()) != 2 {
+		t.Fatalf("failed calls changed the session: head %s, branches %+v", s.Head(), s.Branches())
+	}
+
+	s.Client = c
+	if _, err := s.Send(ctx, "again"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Messages(); len(got) != 4 || got[2].Content != "again" || got[3].Content != "reply 2 to again" {
+		t.Fatalf("unexpected branch after retry: %+v", got)
+	}
+}
+
+func TestSessionAttachments(t *testing.T) {
+	c := &recordingChatter{}
+	s := New(c)
+	s.Attachments = attachment.NewMemoryStore()
+	ctx := context.Background()
+	img := chat.ImageData("image/png", []byte("png"))
+	if _, err := s.SendMessage(ctx, chat.UserWithImages("what is this?", img)); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.requests[len(c.requests)-1].Messages[0].Images; len(got) != 1 || got[0] != img {
+		t.Fatalf("model did not get the image: %+v", c.requests)
+	}
+	stored := s.Messages()[0].Images[0].URL
+	if stored != "attachment:"+attachment.Digest([]byte("png")) {
+		t.Fatalf("session kept %q, want an attachment URL", stored)
+	}
+
+	if _, err := s.Send(ctx, "and now?"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.requests[len(c.requests)-1].Messages[0].Images; len(got) != 1 || got[0] != img {
+		t.Fatalf("earlier image not resolved: %+v", c.requests[len(c.requests)-1].Messages[0])
+	}
+}
@@ -0,0 +1,148 @@
+// Package signature signs and verifies messages with HMAC-SHA256 so uniai
+// gateways, their clients and webhook receivers can authenticate each other
+// across trust boundaries. A signature is sent in one header:
+//
+//	X-Uniai-Signature: t=1735689600,v1=5257a869e7ecebeda32affa62cdca3fa51cad7e77a0e56ff536d0ce8e108d8bd
+//
+// where v1 is the hex HMAC of the Unix timestamp, a ".", and the payload.
+// Webhooks sign their body; HTTP requests sign "METHOD path?query\n"
+// followed by the body, so a request cannot be replayed against another
+// endpoint. Signatures older than the tolerance are rejected.
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/internal/httputil"
+)
+
+// Header carries the signature.
+const Header = "X-Uniai-Signature"
+
+// DefaultTolerance is the maximum age of a signature when none is given.
+const DefaultTolerance = 5 * time.Minute
+
+// ErrInvalid is returned (wrapped) for missing, malformed, stale or
+// mismatched signatures.
+var ErrInvalid = errors.New("invalid signature")
+
+// Sign returns the header value signing payload at time t.
+func Sign(secret []byte, t time.Time, payload []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, payload)
+}
+
+// Verify checks a header value against payload. Any of secrets may match,
+// so secrets can be rotated by verifying with the old and new one for a
+// while. tolerance bounds the age of the signature, and its skew into the
+// future (default DefaultTolerance).
+func Verify(header string, payload []byte, tolerance time.Duration, secrets ...[]byte) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalid)
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalid)
+	}
+	for _, secret := range secrets {
+		want := mac(secret, ts, payload)
+		for _, sig := range sigs {
+			if hmac.Equal([]byte(sig), []byte(want)) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: no matching signature", ErrInvalid)
+}
+
+func mac(secret []byte, ts string, payload []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RequestPayload is what HTTP request signatures cover.
+func RequestPayload(method, requestURI string, body []byte) []byte {
+	return append([]byte(method+" "+requestURI+"\n"), body...)
+}
+
+// SignRequest signs r with its body, which it reads and restores.
+func SignRequest(r *http.Request, secret []byte) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(Header, Sign(secret, time.Now(), RequestPayload(r.Method, r.URL.RequestURI(), body)))
+	return nil
+}
+
+// VerifyRequest checks the signature of an incoming request. It reads the
+// body and restores it for the next handler.
+func VerifyRequest(r *http.Request, tolerance time.Duration, secrets ...[]byte) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	return Verify(r.Header.Get(Header), RequestPayload(r.Method, r.URL.RequestURI(), body), tolerance, secrets...)
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := httputil.ReadBody(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// Transport signs every request it sends, for clients of a gateway that
+// verifies signatures:
+//
+//	http.Client{Transport: &signature.Transport{Secret: secret}}
+type Transport struct {
+	Secret []byte
+	// Base sends the signed requests (default http.DefaultTransport).
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	if err := SignRequest(r, t.Secret); err != nil {
+		return nil, err
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r)
+}
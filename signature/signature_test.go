@@ -0,0 +1,63 @@
+package signature
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret, old := []byte("new-secret"), []byte("old-secret")
+	body := []byte(`{"event":"job.completed"}`)
+	header := Sign(secret, time.Now(), body)
+	if err := Verify(header, body, 0, old, secret); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	for name, err := range map[string]error{
+		"tampered":  Verify(header, []byte(`{"event":"job.failed"}`), 0, secret),
+		"secret":    Verify(header, body, 0, old),
+		"stale":     Verify(Sign(secret, time.Now().Add(-time.Hour), body), body, 0, secret),
+		"malformed": Verify("v1=abc", body, 0, secret),
+	} {
+		if !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	secret := []byte("s3cret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyRequest(r, 0, secret); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{Secret: secret}}
+	resp, err := client.Post(srv.URL+"/v1/chat/completions?x=1", "application/json", strings.NewReader(`{"model":"m"}`))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != `{"model":"m"}` {
+		t.Fatalf("signed request: %d %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unsigned request: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,99 @@
+package uniai
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+const sourcesPrompt = "Answer using the numbered sources below. After each sentence that uses a source, cite it by number in square brackets, such as [1] or [1][3]. Do not cite sources that are not listed.\n"
+
+// maxSnippet is the length in bytes of the source excerpt kept in a
+// citation.
+const maxSnippet = 300
+
+var citationMarker = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// applySources implements req.Options.Sources: the sources are numbered in a
+// system message, and the returned function turns the [n] markers of the
+// reply into Result.Citations.
+func applySources(req *chat.Request) (*chat.Request, func(*chat.Result)) {
+	sources := req.Options.Sources
+	if len(sources) == 0 {
+		return req, func(*chat.Result) {}
+	}
+	var b strings.Builder
+	b.WriteString(sourcesPrompt)
+	for i, s := range sources {
+		fmt.Fprintf(&b, "\n[%d]", i+1)
+		if s.Title != "" {
+			b.WriteString(" " + s.Title)
+		}
+		if s.URL != "" {
+			b.WriteString(" (" + s.URL + ")")
+		}
+		b.WriteString("\n" + strings.TrimSpace(s.Text) + "\n")
+	}
+	out := *req
+	out.Messages = append([]chat.Message{chat.System(b.String())}, req.Messages...)
+	return &out, func(resp *chat.Result) {
+		resp.Citations = append(resp.Citations, sourceCitations(resp.Text, sources)...)
+	}
+}
+
+// sourceCitations returns a citation for each [n] marker in text. Its span
+// is the sentence before the marker.
+func sourceCitations(text string, sources []chat.Source) []chat.Citation {
+	var out []chat.Citation
+	var span *chat.Span
+	prev := 0
+	for _, m := range citationMarker.FindAllStringSubmatchIndex(text, -1) {
+		// adjacent markers, as in [1][2], cite the same sentence
+		if span == nil || strings.TrimSpace(text[prev:m[0]]) != "" {
+			span = sentenceBefore(text, prev, m[0])
+		}
+		prev = m[1]
+		for _, n := range strings.Split(text[m[2]:m[3]], ",") {
+			i, err := strconv.Atoi(strings.TrimSpace(n))
+			if err != nil || i < 1 || i > len(sources) {
+				continue
+			}
+			s := sources[i-1]
+			out = chat.AddCitation(out, chat.Citation{
+				URL:     s.URL,
+				Title:   s.Title,
+				Snippet: snippet(s.Text),
+				Span:    span,
+			})
+		}
+	}
+	return out
+}
+
+// sentenceBefore returns the span of the sentence that ends at end, not
+// reaching back past from.
+func sentenceBefore(text string, from, end int) *chat.Span {
+	head := strings.TrimRight(text[from:end], " \t")
+	start := from
+	if i := strings.LastIndexAny(strings.TrimRight(head, ".!?"), ".!?\n"); i >= 0 {
+		start = from + i + 1
+	}
+	start += len(head[start-from:]) - len(strings.TrimLeft(head[start-from:], " \t\n"))
+	return &chat.Span{Start: start, End: from + len(head)}
+}
+
+func snippet(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= maxSnippet {
+		return text
+	}
+	cut := maxSnippet
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
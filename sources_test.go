@@ -0,0 +1,30 @@
+package uniai
+
+import (
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestSourceCitations(t *testing.T) {
+	sources := []chat.Source{
+		{URL: "https://example.com/a", Title: "A", Text: "Paris is the capital of France."},
+		{URL: "https://example.com/b", Title: "B", Text: "France is in Europe."},
+	}
+	text := "Paris is the capital [1]. It lies in Europe.[1][2] Nothing else [7]."
+	got := sourceCitations(text, sources)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 citations, got %#v", got)
+	}
+	if s := got[0].Span; text[s.Start:s.End] != "Paris is the capital" {
+		t.Fatalf("unexpected first span %q", text[s.Start:s.End])
+	}
+	for _, c := range got[1:] {
+		if s := c.Span; text[s.Start:s.End] != "It lies in Europe." {
+			t.Fatalf("unexpected span %q for %s", text[s.Start:s.End], c.Title)
+		}
+	}
+	if got[2].URL != "https://example.com/b" || got[2].Snippet != sources[1].Text {
+		t.Fatalf("unexpected citation %#v", got[2])
+	}
+}
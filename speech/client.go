@@ -0,0 +1,267 @@
+// Package speech transcribes audio. Streaming transcription runs over the
+// WebSocket APIs of Deepgram and OpenAI (realtime transcription) and
+// reports interim and final results through OnStream, as chat streaming
+// does.
+package speech
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+const DefaultDeepgramAPIBase = "https://api.deepgram.com"
+
+type Config struct {
+	OpenAIAPIKey  string
+	OpenAIAPIBase string
+
+	DeepgramAPIKey  string
+	DeepgramAPIBase string
+}
+
+type Client struct {
+	cfg Config
+}
+
+func New(cfg Config) *Client {
+	return &Client{cfg: cfg}
+}
+
+// Stream sends audio to the provider as it is read and returns the full
+// transcript once audio is exhausted and the provider has finished. Pass a
+// reader that blocks between chunks, such as a pipe from a microphone, to
+// transcribe live audio; cancel ctx to stop early.
+func (c *Client) Stream(ctx context.Context, audio io.Reader, opts ...Option) (*Result, error) {
+	req := BuildRequest(opts...)
+	provider := req.Provider
+	if provider == "" {
+		provider = pickProviderByModel(req.Model)
+	}
+	if req.Encoding == "" {
+		req.Encoding = EncodingLinear16
+	}
+	if req.Channels <= 0 {
+		req.Channels = 1
+	}
+	switch provider {
+	case "deepgram":
+		return c.streamDeepgram(ctx, req, audio)
+	case "openai":
+		return c.streamOpenAI(ctx, req, audio)
+	case "":
+		return nil, fmt.Errorf("provider not set")
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", provider)
+	}
+}
+
+func pickProviderByModel(model string) string {
+	model = strings.ToLower(model)
+	switch {
+	case strings.HasPrefix(model, "nova"), strings.HasPrefix(model, "enhanced"), strings.HasPrefix(model, "base"):
+		return "deepgram"
+	case strings.HasPrefix(model, "gpt-"), strings.HasPrefix(model, "whisper"):
+		return "openai"
+	}
+	return ""
+}
+
+// websocketURL turns an http(s) API base into a ws(s) URL for path.
+func websocketURL(base, path string, query url.Values) (string, error) {
+	u, err := url.Parse(strings.TrimRight(base, "/") + path)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+	if len(query) > 0 {
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+func dial(ctx context.Context, rawURL string, header http.Header) (*websocket.Conn, error) {
+	cfg, err := websocket.NewConfig(rawURL, "https://"+hostOf(rawURL))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Header = header
+	ws, err := cfg.DialContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	return ws, nil
+}
+
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Host
+	}
+	return "localhost"
+}
+
+// stream runs one transcription: send writes audio to ws until it returns,
+// while receive handles incoming messages until it reports done. Canceling
+// ctx or a failure on either side closes the connection.
+type stream struct {
+	ws     *websocket.Conn
+	req    *Request
+	result *Result
+
+	once sync.Once
+	mu   sync.Mutex
+	err  error
+}
+
+func newStream(ws *websocket.Conn, req *Request, provider, model string) *stream {
+	return &stream{ws: ws, req: req, result: &Result{Provider: provider, Model: model}}
+}
+
+func (s *stream) close() {
+	s.once.Do(func() { s.ws.Close() })
+}
+
+// fail records the first error and closes the connection, which ends the
+// other side too.
+func (s *stream) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.close()
+}
+
+func (s *stream) run(ctx context.Context, send func() error, receive func(msg []byte) (done bool, err error)) (*Result, error) {
+	defer s.close()
+	stop := context.AfterFunc(ctx, s.close)
+	defer stop()
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		if err := send(); err != nil {
+			s.fail(err)
+		}
+	}()
+	if err := s.receive(receive); err != nil {
+		s.fail(err)
+	}
+	s.close()
+	<-sent
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if err := s.emit(StreamEvent{Done: true}); err != nil {
+		return nil, err
+	}
+	return s.result, nil
+}
+
+func (s *stream) receive(receive func(msg []byte) (bool, error)) error {
+	for {
+		var msg []byte
+		if err := websocket.Message.Receive(s.ws, &msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("receive: %w", err)
+		}
+		done, err := receive(msg)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// emit passes ev to OnStream and adds final text to the result.
+func (s *stream) emit(ev StreamEvent) error {
+	if ev.Final && ev.Text != "" {
+		if s.result.Text != "" {
+			s.result.Text += " "
+		}
+		s.result.Text += ev.Text
+		s.result.Segments = append(s.result.Segments, segments(ev)...)
+	}
+	if ev.End > s.result.Duration {
+		s.result.Duration = ev.End
+	}
+	switch {
+	case ev.Activity != "":
+		if !s.req.VoiceActivity {
+			return nil
+		}
+	case !ev.Final && !ev.Done && !s.req.Interim:
+		return nil
+	}
+	if s.req.OnStream == nil {
+		return nil
+	}
+	return s.req.OnStream(ev)
+}
+
+// segments splits a final utterance at speaker changes.
+func segments(ev StreamEvent) []Segment {
+	if len(ev.Words) == 0 {
+		return []Segment{{Start: ev.Start, End: ev.End, Text: ev.Text}}
+	}
+	var out []Segment
+	for i, w := range ev.Words {
+		if i == 0 || w.Speaker != ev.Words[i-1].Speaker {
+			out = append(out, Segment{Speaker: w.Speaker, Start: w.Start})
+		}
+		seg := &out[len(out)-1]
+		seg.End = w.End
+		seg.Words = append(seg.Words, w)
+	}
+	if len(out) == 1 {
+		// keep the provider's formatting of the whole utterance
+		out[0].Text = ev.Text
+		return out
+	}
+	for i := range out {
+		texts := make([]string, len(out[i].Words))
+		for j, w := range out[i].Words {
+			texts[j] = w.Text
+		}
+		out[i].Text = strings.Join(texts, " ")
+	}
+	return out
+}
+
+// chunks reads audio in pieces of about 100 ms and passes them to fn.
+func chunks(audio io.Reader, req *Request, fn func([]byte) error) error {
+	bytesPerSample := 2
+	if req.Encoding == EncodingMulaw {
+		bytesPerSample = 1
+	}
+	buf := make([]byte, max(req.SampleRate*req.Channels*bytesPerSample/10, 512))
+	for {
+		n, err := audio.Read(buf)
+		if n > 0 {
+			if err := fn(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read audio: %w", err)
+		}
+	}
+}
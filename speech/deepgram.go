@@ -0,0 +1,154 @@
+package speech
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+type deepgramMessage struct {
+	Type     string  `json:"type"`
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+	IsFinal  bool    `json:"is_final"`
+	Channel  struct {
+		Alternatives []struct {
+			Transcript string         `json:"transcript"`
+			Words      []deepgramWord `json:"words"`
+		} `json:"alternatives"`
+	} `json:"channel"`
+	// Timestamp is set on SpeechStarted, LastWordEnd on UtteranceEnd.
+	Timestamp   float64 `json:"timestamp"`
+	LastWordEnd float64 `json:"last_word_end"`
+	Description string  `json:"description"`
+	Message     string  `json:"message"`
+}
+
+type deepgramWord struct {
+	Word           string  `json:"word"`
+	PunctuatedWord string  `json:"punctuated_word"`
+	Start          float64 `json:"start"`
+	End            float64 `json:"end"`
+	Confidence     float64 `json:"confidence"`
+	Speaker        *int    `json:"speaker"`
+}
+
+// streamDeepgram uses Deepgram's live transcription API: audio goes out as
+// binary frames, and a CloseStream message asks for the remaining results
+// before the server closes the connection.
+func (c *Client) streamDeepgram(ctx context.Context, req *Request, audio io.Reader) (*Result, error) {
+	model := req.Model
+	if model == "" {
+		model = "nova-3"
+	}
+	if req.SampleRate <= 0 {
+		req.SampleRate = 16000
+	}
+	query := url.Values{
+		"model":           {model},
+		"encoding":        {req.Encoding},
+		"sample_rate":     {strconv.Itoa(req.SampleRate)},
+		"channels":        {strconv.Itoa(req.Channels)},
+		"interim_results": {strconv.FormatBool(req.Interim)},
+		"punctuate":       {"true"},
+		"smart_format":    {"true"},
+	}
+	if req.Language != "" {
+		query.Set("language", req.Language)
+	}
+	if req.Diarize {
+		query.Set("diarize", "true")
+	}
+	if req.VoiceActivity {
+		// utterance ends are only reported with interim results; emit
+		// drops those unless they were asked for
+		query.Set("vad_events", "true")
+		query.Set("interim_results", "true")
+		query.Set("utterance_end_ms", "1000")
+	}
+	for k, v := range req.Options.Deepgram {
+		query.Set(k, fmt.Sprint(v))
+	}
+	base := c.cfg.DeepgramAPIBase
+	if base == "" {
+		base = DefaultDeepgramAPIBase
+	}
+	rawURL, err := websocketURL(base, "/v1/listen", query)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := dial(ctx, rawURL, http.Header{"Authorization": {"Token " + c.cfg.DeepgramAPIKey}})
+	if err != nil {
+		return nil, fmt.Errorf("deepgram: %w", err)
+	}
+	s := newStream(ws, req, "deepgram", model)
+	return s.run(ctx, func() error {
+		err := chunks(audio, req, func(b []byte) error {
+			return websocket.Message.Send(ws, b)
+		})
+		if err != nil {
+			return err
+		}
+		return websocket.Message.Send(ws, `{"type":"CloseStream"}`)
+	}, func(data []byte) (bool, error) {
+		var msg deepgramMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return false, fmt.Errorf("deepgram: decode message: %w", err)
+		}
+		switch msg.Type {
+		case "Results":
+			if len(msg.Channel.Alternatives) == 0 {
+				return false, nil
+			}
+			alt := msg.Channel.Alternatives[0]
+			text := strings.TrimSpace(alt.Transcript)
+			if text == "" {
+				return false, nil
+			}
+			var words []Word
+			for _, w := range alt.Words {
+				word := Word{
+					Text:       cmp.Or(w.PunctuatedWord, w.Word),
+					Start:      seconds(w.Start),
+					End:        seconds(w.End),
+					Confidence: w.Confidence,
+				}
+				if w.Speaker != nil {
+					word.Speaker = "speaker_" + strconv.Itoa(*w.Speaker)
+				}
+				words = append(words, word)
+			}
+			return false, s.emit(StreamEvent{
+				Text:  text,
+				Final: msg.IsFinal,
+				Start: seconds(msg.Start),
+				End:   seconds(msg.Start + msg.Duration),
+				Words: words,
+			})
+		case "SpeechStarted":
+			return false, s.emit(StreamEvent{Activity: ActivitySpeechStarted, Start: seconds(msg.Timestamp)})
+		case "UtteranceEnd":
+			return false, s.emit(StreamEvent{Activity: ActivitySpeechStopped, Start: seconds(msg.LastWordEnd)})
+		case "Metadata":
+			if d := seconds(msg.Duration); d > s.result.Duration {
+				s.result.Duration = d
+			}
+		case "Error":
+			return false, fmt.Errorf("deepgram: %s", strings.TrimSpace(msg.Description+" "+msg.Message))
+		}
+		return false, nil
+	})
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,179 @@
+package speech
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const defaultOpenAIAPIBase = "https://api.openai.com/v1"
+
+type openAIEvent struct {
+	Type         string `json:"type"`
+	ItemID       string `json:"item_id"`
+	Delta        string `json:"delta"`
+	Transcript   string `json:"transcript"`
+	AudioStartMS int64  `json:"audio_start_ms"`
+	AudioEndMS   int64  `json:"audio_end_ms"`
+	Error        *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// openAIItem is an utterance of a realtime transcription session.
+type openAIItem struct {
+	text       string
+	start, end time.Duration
+}
+
+// streamOpenAI uses OpenAI realtime transcription. Server-side voice
+// activity detection splits the audio into utterances (items), each of
+// which is transcribed as it ends; the final commit flushes the last one.
+func (c *Client) streamOpenAI(ctx context.Context, req *Request, audio io.Reader) (*Result, error) {
+	model := req.Model
+	if model == "" {
+		model = "gpt-4o-transcribe"
+	}
+	format := "pcm16"
+	switch req.Encoding {
+	case EncodingLinear16:
+		if req.SampleRate <= 0 {
+			req.SampleRate = 24000
+		}
+		if req.SampleRate != 24000 || req.Channels != 1 {
+			return nil, fmt.Errorf("openai: linear16 audio must be 24 kHz mono")
+		}
+	case EncodingMulaw:
+		format = "g711_ulaw"
+		if req.SampleRate <= 0 {
+			req.SampleRate = 8000
+		}
+	default:
+		return nil, fmt.Errorf("openai: unsupported encoding %s", req.Encoding)
+	}
+	transcription := map[string]any{"model": model}
+	if req.Language != "" {
+		transcription["language"] = req.Language
+	}
+	session := map[string]any{
+		"input_audio_format":        format,
+		"input_audio_transcription": transcription,
+		"turn_detection":            map[string]any{"type": "server_vad"},
+	}
+	maps.Copy(session, req.Options.OpenAI)
+
+	base := c.cfg.OpenAIAPIBase
+	if base == "" {
+		base = defaultOpenAIAPIBase
+	}
+	rawURL, err := websocketURL(base, "/realtime?intent=transcription", nil)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := dial(ctx, rawURL, http.Header{
+		"Authorization": {"Bearer " + c.cfg.OpenAIAPIKey},
+		"OpenAI-Beta":   {"realtime=v1"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+	s := newStream(ws, req, "openai", model)
+
+	// every speech_stopped is followed by the committed event of voice
+	// activity detection; any other committed event answers the final
+	// commit
+	var ending atomic.Bool
+	flushed, vadCommits := false, 0
+	items := map[string]*openAIItem{}
+	pending := map[string]bool{}
+	item := func(id string) *openAIItem {
+		if items[id] == nil {
+			items[id] = &openAIItem{}
+		}
+		return items[id]
+	}
+	return s.run(ctx, func() error {
+		err := websocket.JSON.Send(ws, map[string]any{"type": "transcription_session.update", "session": session})
+		if err != nil {
+			return err
+		}
+		err = chunks(audio, req, func(b []byte) error {
+			return websocket.JSON.Send(ws, map[string]any{
+				"type":  "input_audio_buffer.append",
+				"audio": base64.StdEncoding.EncodeToString(b),
+			})
+		})
+		if err != nil {
+			return err
+		}
+		ending.Store(true)
+		return websocket.JSON.Send(ws, map[string]any{"type": "input_audio_buffer.commit"})
+	}, func(data []byte) (bool, error) {
+		var ev openAIEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return false, fmt.Errorf("openai: decode event: %w", err)
+		}
+		switch ev.Type {
+		case "input_audio_buffer.speech_started":
+			it := item(ev.ItemID)
+			it.start = time.Duration(ev.AudioStartMS) * time.Millisecond
+			if err := s.emit(StreamEvent{Activity: ActivitySpeechStarted, Start: it.start}); err != nil {
+				return false, err
+			}
+		case "input_audio_buffer.speech_stopped":
+			it := item(ev.ItemID)
+			it.end = time.Duration(ev.AudioEndMS) * time.Millisecond
+			vadCommits++
+			if err := s.emit(StreamEvent{Activity: ActivitySpeechStopped, Start: it.end}); err != nil {
+				return false, err
+			}
+		case "input_audio_buffer.committed":
+			pending[ev.ItemID] = true
+			if vadCommits > 0 {
+				vadCommits--
+			} else if ending.Load() {
+				flushed = true
+			}
+		case "conversation.item.input_audio_transcription.delta":
+			it := item(ev.ItemID)
+			it.text += ev.Delta
+			err := s.emit(StreamEvent{Text: strings.TrimSpace(it.text), Delta: ev.Delta, Start: it.start, End: it.end})
+			if err != nil {
+				return false, err
+			}
+		case "conversation.item.input_audio_transcription.completed":
+			it := item(ev.ItemID)
+			delete(pending, ev.ItemID)
+			text := strings.TrimSpace(ev.Transcript)
+			if text != "" {
+				if err := s.emit(StreamEvent{Text: text, Final: true, Start: it.start, End: it.end}); err != nil {
+					return false, err
+				}
+			}
+		case "conversation.item.input_audio_transcription.failed":
+			msg := "transcription failed"
+			if ev.Error != nil {
+				msg = ev.Error.Message
+			}
+			return false, fmt.Errorf("openai: %s", msg)
+		case "error":
+			if ev.Error != nil && ev.Error.Code == "input_audio_buffer_commit_empty" && ending.Load() {
+				// nothing was left after the last voice activity commit
+				flushed = true
+			} else if ev.Error != nil {
+				return false, fmt.Errorf("openai: %s", ev.Error.Message)
+			}
+		}
+		return flushed && len(pending) == 0, nil
+	})
+}
@@ -0,0 +1,132 @@
+package speech
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestStreamDeepgram(t *testing.T) {
+	var query, auth string
+	received := 0
+	srv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		query, auth = ws.Request().URL.RawQuery, ws.Request().Header.Get("Authorization")
+		send := func(text string, final bool, start float64, words ...map[string]any) {
+			websocket.JSON.Send(ws, map[string]any{
+				"type": "Results", "is_final": final, "start": start, "duration": 1.0,
+				"channel": map[string]any{"alternatives": []map[string]any{{"transcript": text, "words": words}}},
+			})
+		}
+		word := func(text string, start float64, speaker int) map[string]any {
+			return map[string]any{"word": strings.ToLower(text), "punctuated_word": text, "start": start, "end": start + 0.4, "speaker": speaker}
+		}
+		for {
+			var msg []byte
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+			if string(msg) == `{"type":"CloseStream"}` {
+				break
+			}
+			received += len(msg)
+		}
+		websocket.JSON.Send(ws, map[string]any{"type": "SpeechStarted", "timestamp": 0.1})
+		send("hello", false, 0)
+		send("Hello world", true, 0, word("Hello", 0.1, 0), word("world", 0.5, 1))
+		send("again", true, 1, word("again", 1.1, 1))
+		websocket.JSON.Send(ws, map[string]any{"type": "Metadata", "duration": 2.5})
+	}))
+	defer srv.Close()
+
+	c := New(Config{DeepgramAPIKey: "k", DeepgramAPIBase: srv.URL})
+	var events []StreamEvent
+	res, err := c.Stream(context.Background(), strings.NewReader(strings.Repeat("\x00", 10000)),
+		Transcribe("nova-3"),
+		WithLanguage("en"),
+		WithInterimResults(true),
+		WithDiarization(true),
+		WithVoiceActivity(true),
+		WithOnStream(func(ev StreamEvent) error {
+			events = append(events, ev)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Text != "Hello world again" || res.Provider != "deepgram" || res.Duration != 2500*time.Millisecond {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(events) != 5 || events[0].Activity != ActivitySpeechStarted || events[1].Final || !events[2].Final || events[3].Start != time.Second || !events[4].Done {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	segs := res.Segments
+	if len(segs) != 3 || segs[0].Speaker != "speaker_0" || segs[0].Text != "Hello" || segs[1].Text != "world" || segs[1].Start != 500*time.Millisecond {
+		t.Fatalf("unexpected segments %+v", segs)
+	}
+	turns := MergeTurns(segs)
+	if len(turns) != 2 || turns[1].Text != "world again" || turns[1].End != 1500*time.Millisecond || len(turns[1].Words) != 2 {
+		t.Fatalf("unexpected turns %+v", turns)
+	}
+	if speakers := res.Speakers(); len(speakers) != 2 || speakers[1] != "speaker_1" {
+		t.Fatalf("unexpected speakers %v", speakers)
+	}
+	if received != 10000 || auth != "Token k" || !strings.Contains(query, "diarize=true") || !strings.Contains(query, "language=en") {
+		t.Fatalf("server got %d bytes, auth %q, query %q", received, auth, query)
+	}
+}
+
+func TestStreamOpenAI(t *testing.T) {
+	var session map[string]any
+	srv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var ev map[string]any
+			if err := websocket.JSON.Receive(ws, &ev); err != nil {
+				return
+			}
+			switch ev["type"] {
+			case "transcription_session.update":
+				session = ev["session"].(map[string]any)
+				// voice activity detection ends an utterance mid-stream
+				websocket.JSON.Send(ws, map[string]any{"type": "input_audio_buffer.speech_started", "item_id": "i1", "audio_start_ms": 100})
+				websocket.JSON.Send(ws, map[string]any{"type": "input_audio_buffer.speech_stopped", "item_id": "i1", "audio_end_ms": 900})
+				websocket.JSON.Send(ws, map[string]any{"type": "input_audio_buffer.committed", "item_id": "i1"})
+				websocket.JSON.Send(ws, map[string]any{"type": "conversation.item.input_audio_transcription.delta", "item_id": "i1", "delta": "Hi"})
+				websocket.JSON.Send(ws, map[string]any{"type": "conversation.item.input_audio_transcription.completed", "item_id": "i1", "transcript": "Hi there."})
+			case "input_audio_buffer.commit":
+				websocket.JSON.Send(ws, map[string]any{"type": "input_audio_buffer.committed", "item_id": "i2"})
+				websocket.JSON.Send(ws, map[string]any{"type": "conversation.item.input_audio_transcription.completed", "item_id": "i2", "transcript": "Bye."})
+			}
+		}
+	}))
+	defer srv.Close()
+
+	c := New(Config{OpenAIAPIKey: "k", OpenAIAPIBase: srv.URL + "/v1"})
+	var finals []StreamEvent
+	res, err := c.Stream(context.Background(), strings.NewReader(strings.Repeat("\x00", 9600)),
+		Transcribe("gpt-4o-transcribe"),
+		WithOnStream(func(ev StreamEvent) error {
+			finals = append(finals, ev)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Text != "Hi there. Bye." || len(finals) != 3 || finals[0].Start != 100*time.Millisecond || finals[0].End != 900*time.Millisecond {
+		t.Fatalf("unexpected result %+v, events %+v", res, finals)
+	}
+	encoded, _ := json.Marshal(session)
+	if !strings.Contains(string(encoded), `"input_audio_format":"pcm16"`) || !strings.Contains(string(encoded), `"model":"gpt-4o-transcribe"`) {
+		t.Fatalf("unexpected session %s", encoded)
+	}
+
+	if _, err := c.Stream(context.Background(), strings.NewReader(""), Transcribe("gpt-4o-transcribe"), WithAudioFormat(EncodingLinear16, 16000, 1)); err == nil {
+		t.Fatal("expected an error for 16 kHz audio")
+	}
+}
@@ -0,0 +1,199 @@
+package speech
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lyricat/goutils/structs"
+)
+
+// Audio encodings for streamed input: raw samples without a container.
+const (
+	// EncodingLinear16 is 16-bit signed little-endian PCM.
+	EncodingLinear16 = "linear16"
+	// EncodingMulaw is 8-bit G.711 mu-law, as used by telephony.
+	EncodingMulaw = "mulaw"
+)
+
+type Options struct {
+	// Deepgram options are added to the listen query string.
+	Deepgram structs.JSONMap `json:"deepgram_options,omitempty"`
+	// OpenAI options are merged into the transcription session.
+	OpenAI structs.JSONMap `json:"openai_options,omitempty"`
+}
+
+type Request struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	// Language is a BCP 47 code such as "en"; empty lets the provider
+	// detect it where supported.
+	Language string `json:"language,omitempty"`
+	// Encoding, SampleRate and Channels describe the audio. They default to
+	// mono linear16 at 16 kHz for deepgram and 24 kHz for openai, which
+	// only accepts mono linear16 at 24 kHz or mulaw at 8 kHz.
+	Encoding   string `json:"encoding,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+	// Interim asks for interim results: hypotheses for the current
+	// utterance that later events revise.
+	Interim bool `json:"interim,omitempty"`
+	// Diarize asks for speaker labels on words and segments (deepgram).
+	Diarize bool `json:"diarize,omitempty"`
+	// VoiceActivity asks for events when speech starts and stops.
+	VoiceActivity bool    `json:"voice_activity,omitempty"`
+	Options       Options `json:"options,omitempty"`
+
+	OnStream OnStreamFunc `json:"-"`
+}
+
+// OnStreamFunc is called for each transcription event.
+// Returning a non-nil error cancels the stream.
+type OnStreamFunc func(event StreamEvent) error
+
+// StreamEvent is one update of a streaming transcription.
+type StreamEvent struct {
+	// Text is the transcript of the current utterance: the latest
+	// hypothesis for interim events, the settled text for final ones.
+	Text string `json:"text,omitempty"`
+	// Delta is the text added since the previous event of the same
+	// utterance, for providers that stream text incrementally (openai).
+	Delta string `json:"delta,omitempty"`
+	// Final is set once the utterance will not change any more.
+	Final bool `json:"final,omitempty"`
+	// Start and End place the utterance in the audio, when reported.
+	Start time.Duration `json:"start,omitempty"`
+	End   time.Duration `json:"end,omitempty"`
+	// Words are the words of the utterance with their timing, for
+	// providers that report them (deepgram).
+	Words []Word `json:"words,omitempty"`
+	// Activity is set on voice activity events, which carry no text; Start
+	// is the time of the change when known.
+	Activity string `json:"activity,omitempty"`
+	Done     bool   `json:"done,omitempty"`
+}
+
+// Voice activity values of StreamEvent.Activity.
+const (
+	ActivitySpeechStarted = "speech_started"
+	ActivitySpeechStopped = "speech_stopped"
+)
+
+// Word is one transcribed word.
+type Word struct {
+	Text       string        `json:"text"`
+	Start      time.Duration `json:"start"`
+	End        time.Duration `json:"end"`
+	Confidence float64       `json:"confidence,omitempty"`
+	// Speaker labels the voice with diarization, as "speaker_0",
+	// "speaker_1" and so on.
+	Speaker string `json:"speaker,omitempty"`
+}
+
+// Segment is a stretch of speech by one speaker. A final utterance gives
+// one segment, or one per speaker turn when it is diarized.
+type Segment struct {
+	Speaker string        `json:"speaker,omitempty"`
+	Start   time.Duration `json:"start"`
+	End     time.Duration `json:"end"`
+	Text    string        `json:"text"`
+	Words   []Word        `json:"words,omitempty"`
+}
+
+// Result is the complete transcript of a stream.
+type Result struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+	// Text joins the final utterances with spaces.
+	Text string `json:"text"`
+	// Duration is the length of the transcribed audio, when reported.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Segments are the final utterances in order, split at speaker turns.
+	Segments []Segment `json:"segments,omitempty"`
+}
+
+// Speakers returns the distinct speakers of the segments in order of first
+// appearance.
+func (r *Result) Speakers() []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, seg := range r.Segments {
+		if seg.Speaker != "" && !seen[seg.Speaker] {
+			seen[seg.Speaker] = true
+			out = append(out, seg.Speaker)
+		}
+	}
+	return out
+}
+
+// MergeTurns joins consecutive segments of the same speaker, which gives
+// one segment per turn of a conversation.
+func MergeTurns(segments []Segment) []Segment {
+	var out []Segment
+	for _, seg := range segments {
+		if n := len(out); n > 0 && out[n-1].Speaker == seg.Speaker {
+			last := &out[n-1]
+			last.End = max(last.End, seg.End)
+			last.Text = strings.TrimSpace(last.Text + " " + seg.Text)
+			last.Words = append(slices.Clip(last.Words), seg.Words...)
+			continue
+		}
+		out = append(out, seg)
+	}
+	return out
+}
+
+type Option func(*Request)
+
+func BuildRequest(opts ...Option) *Request {
+	req := &Request{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(req)
+		}
+	}
+	return req
+}
+
+func Transcribe(model string) Option {
+	return func(r *Request) { r.Model = model }
+}
+
+func WithProvider(provider string) Option {
+	return func(r *Request) { r.Provider = provider }
+}
+
+func WithLanguage(language string) Option {
+	return func(r *Request) { r.Language = language }
+}
+
+// WithAudioFormat describes the streamed audio.
+func WithAudioFormat(encoding string, sampleRate, channels int) Option {
+	return func(r *Request) {
+		r.Encoding = encoding
+		r.SampleRate = sampleRate
+		r.Channels = channels
+	}
+}
+
+func WithInterimResults(interim bool) Option {
+	return func(r *Request) { r.Interim = interim }
+}
+
+// WithDiarization asks for speaker labels.
+func WithDiarization(diarize bool) Option {
+	return func(r *Request) { r.Diarize = diarize }
+}
+
+// WithVoiceActivity asks for speech started and stopped events.
+func WithVoiceActivity(enabled bool) Option {
+	return func(r *Request) { r.VoiceActivity = enabled }
+}
+
+func WithOptions(opts Options) Option {
+	return func(r *Request) { r.Options = opts }
+}
+
+func WithOnStream(fn OnStreamFunc) Option {
+	return func(r *Request) { r.OnStream = fn }
+}
@@ -0,0 +1,128 @@
+package uniai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// StructuredStream incrementally decodes a streamed JSON object into T.
+// Each time a top-level field of the object completes, the object decoded so
+// far is passed to the partial callback, so callers can render structured
+// results progressively. Text before the opening brace (commentary, code
+// fences) is ignored.
+type StructuredStream[T any] struct {
+	onPartial func(partial T) error
+
+	buf      strings.Builder
+	scanned  int
+	start    int
+	depth    int
+	inString bool
+	escape   bool
+	done     bool
+}
+
+// NewStructuredStream returns a decoder that calls onPartial whenever another
+// top-level field has been received. onPartial may be nil.
+func NewStructuredStream[T any](onPartial func(partial T) error) *StructuredStream[T] {
+	return &StructuredStream[T]{onPartial: onPartial, start: -1}
+}
+
+// OnStream feeds text deltas from a chat stream into the decoder. It can be
+// passed directly to WithOnStream.
+func (s *StructuredStream[T]) OnStream(ev chat.StreamEvent) error {
+	if ev.Delta == "" {
+		return nil
+	}
+	return s.Write(ev.Delta)
+}
+
+// Write appends a chunk of streamed text and reports any newly completed fields.
+func (s *StructuredStream[T]) Write(delta string) error {
+	s.buf.WriteString(delta)
+	if s.done {
+		return nil
+	}
+	text := s.buf.String()
+	for ; s.scanned < len(text); s.scanned++ {
+		ch := text[s.scanned]
+		if s.start < 0 {
+			if ch == '{' {
+				s.start = s.scanned
+				s.depth = 1
+			}
+			continue
+		}
+		if s.inString {
+			switch {
+			case s.escape:
+				s.escape = false
+			case ch == '\\':
+				s.escape = true
+			case ch == '"':
+				s.inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			s.inString = true
+		case '{', '[':
+			s.depth++
+		case '}', ']':
+			s.depth--
+			if s.depth == 0 {
+				s.done = true
+				s.scanned++
+				return s.emit(text[s.start:s.scanned])
+			}
+		case ',':
+			if s.depth == 1 {
+				if err := s.emit(text[s.start:s.scanned] + "}"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *StructuredStream[T]) emit(candidate string) error {
+	if s.onPartial == nil {
+		return nil
+	}
+	var partial T
+	if err := json.Unmarshal([]byte(candidate), &partial); err != nil {
+		// A field that does not fit T yet; wait for more input.
+		return nil
+	}
+	return s.onPartial(partial)
+}
+
+// Text returns the raw text received so far.
+func (s *StructuredStream[T]) Text() string {
+	return s.buf.String()
+}
+
+// Result decodes the complete object. If the stream ended before the object
+// was closed, a repair is attempted before decoding.
+func (s *StructuredStream[T]) Result() (T, error) {
+	var out T
+	text := s.buf.String()
+	if s.start < 0 {
+		return out, fmt.Errorf("no JSON object in stream")
+	}
+	payload := text[s.start:]
+	if s.done {
+		payload = text[s.start:s.scanned]
+	} else {
+		payload = attemptJSONRepair(payload)
+	}
+	if err := json.Unmarshal([]byte(payload), &out); err != nil {
+		return out, fmt.Errorf("invalid structured output: %w", err)
+	}
+	return out, nil
+}
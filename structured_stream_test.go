@@ -0,0 +1,54 @@
+package uniai
+
+import (
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestStructuredStreamPartials(t *testing.T) {
+	type recipe struct {
+		Title string   `json:"title"`
+		Steps []string `json:"steps"`
+		Time  int      `json:"time"`
+	}
+	var partials []recipe
+	s := NewStructuredStream(func(r recipe) error {
+		partials = append(partials, r)
+		return nil
+	})
+	chunks := []string{"```json\n{\"title\":\"Te", "a, hot\",\"steps\":[\"boil\",", "\"steep\"],", "\"time\":5}\n```"}
+	for _, c := range chunks {
+		if err := s.OnStream(chat.StreamEvent{Delta: c}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(partials) != 3 {
+		t.Fatalf("expected 3 partial objects, got %d: %+v", len(partials), partials)
+	}
+	if partials[0].Title != "Tea, hot" || partials[0].Steps != nil {
+		t.Fatalf("unexpected first partial: %+v", partials[0])
+	}
+	if len(partials[1].Steps) != 2 || partials[1].Time != 0 {
+		t.Fatalf("unexpected second partial: %+v", partials[1])
+	}
+	final, err := s.Result()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if final.Time != 5 {
+		t.Fatalf("unexpected final object: %+v", final)
+	}
+}
+
+func TestStructuredStreamResultRepairsTruncatedObject(t *testing.T) {
+	s := NewStructuredStream[map[string]any](nil)
+	_ = s.Write(`{"a":1,"b":"x`)
+	out, err := s.Result()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out["b"] != "x" {
+		t.Fatalf("unexpected result: %v", out)
+	}
+}
@@ -0,0 +1,65 @@
+package uniai
+
+import (
+	"context"
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// SystemPrompt is text the client adds to the system prompt of every Chat
+// call, such as an organization-wide safety preamble. Requests opt out with
+// chat.WithoutSystemPrompt.
+type SystemPrompt struct {
+	// Prefix goes before the request's own system prompt, Suffix after it.
+	Prefix string
+	Suffix string
+	// Dynamic, if set, returns text added after Suffix on each call, for
+	// values that change such as the current date or the caller's locale.
+	Dynamic func(ctx context.Context) string
+}
+
+// systemPrompt returns the system prompt configured for providerName.
+func (c *Client) systemPrompt(providerName string) *SystemPrompt {
+	if sp, ok := c.cfg.ProviderSystemPrompts[providerName]; ok {
+		return &sp
+	}
+	return c.cfg.SystemPrompt
+}
+
+// injectSystemPrompt merges the configured system prompt into the first
+// system message of req, adding one when there is none.
+func (c *Client) injectSystemPrompt(ctx context.Context, providerName string, req *chat.Request) *chat.Request {
+	sp := c.systemPrompt(providerName)
+	if sp == nil || req.Options.NoSystemPrompt {
+		return req
+	}
+	var dynamic string
+	if sp.Dynamic != nil {
+		dynamic = sp.Dynamic(ctx)
+	}
+	if sp.Prefix == "" && sp.Suffix == "" && dynamic == "" {
+		return req
+	}
+	out := *req
+	out.Messages = append([]chat.Message{}, req.Messages...)
+	hasOwn := len(out.Messages) > 0 && out.Messages[0].Role == chat.RoleSystem
+	own := ""
+	if hasOwn {
+		own = out.Messages[0].Content
+	}
+	var parts []string
+	for _, p := range []string{sp.Prefix, own, sp.Suffix, dynamic} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	content := strings.Join(parts, "\n\n")
+	if hasOwn {
+		out.Messages[0].Content = content
+	} else {
+		out.Messages = append([]chat.Message{chat.System(content)}, out.Messages...)
+	}
+	req.Options.Dump.Decide("system_prompt", "added the client system prompt for %s", providerName)
+	return &out
+}
@@ -0,0 +1,44 @@
+package uniai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
+)
+
+func TestSystemPromptInjection(t *testing.T) {
+	p := fake.New(fake.Config{Responses: []fake.Response{fake.Text("ok", 1, 0), fake.Text("ok", 1, 0), fake.Text("ok", 1, 0)}})
+	client := New(Config{SystemPrompt: &SystemPrompt{
+		Prefix:  "Be safe.",
+		Suffix:  "Answer in French.",
+		Dynamic: func(ctx context.Context) string { return "Today is 2025-03-01." },
+	}})
+	client.RegisterProvider("fake", p)
+	ctx := context.Background()
+
+	if _, err := client.Chat(ctx, WithProvider("fake"), WithMessages(System("You are a poet."), User("hi"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Chat(ctx, WithProvider("fake"), WithMessages(User("hi"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Chat(ctx, WithProvider("fake"), WithMessages(User("hi")), WithoutSystemPrompt()); err != nil {
+		t.Fatal(err)
+	}
+	reqs := p.Requests()
+	want := []string{
+		"Be safe.\n\nYou are a poet.\n\nAnswer in French.\n\nToday is 2025-03-01.",
+		"Be safe.\n\nAnswer in French.\n\nToday is 2025-03-01.",
+	}
+	for i, w := range want {
+		msgs := reqs[i].Messages
+		if len(msgs) != 2 || msgs[0].Role != chat.RoleSystem || msgs[0].Content != w {
+			t.Fatalf("request %d: %+v", i, msgs)
+		}
+	}
+	if msgs := reqs[2].Messages; len(msgs) != 1 || msgs[0].Role != chat.RoleUser {
+		t.Fatalf("opted-out request: %+v", msgs)
+	}
+}
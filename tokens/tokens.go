@@ -0,0 +1,78 @@
+// Package tokens maps models to tokenizers. uniai does not ship vocabulary
+// files; callers register an Encoder (for example a tiktoken binding) for the
+// model families they use, and features that need token IDs look it up here.
+package tokens
+
+import (
+	"strings"
+	"sync"
+)
+
+// Encoder converts text into model token IDs.
+type Encoder interface {
+	Encode(text string) ([]int, error)
+}
+
+// EncoderFunc adapts a function to the Encoder interface.
+type EncoderFunc func(text string) ([]int, error)
+
+func (f EncoderFunc) Encode(text string) ([]int, error) { return f(text) }
+
+var (
+	mu       sync.RWMutex
+	encoders = map[string]Encoder{}
+)
+
+// Register associates enc with every model whose name starts with
+// modelPrefix (case-insensitive). Registering a nil encoder removes it.
+func Register(modelPrefix string, enc Encoder) {
+	key := strings.ToLower(strings.TrimSpace(modelPrefix))
+	mu.Lock()
+	defer mu.Unlock()
+	if enc == nil {
+		delete(encoders, key)
+		return
+	}
+	encoders[key] = enc
+}
+
+// ForModel returns the encoder registered with the longest prefix of model,
+// or nil when none matches.
+func ForModel(model string) Encoder {
+	model = strings.ToLower(strings.TrimSpace(model))
+	mu.RLock()
+	defer mu.RUnlock()
+	var (
+		best    Encoder
+		bestLen = -1
+	)
+	for prefix, enc := range encoders {
+		if strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = enc, len(prefix)
+		}
+	}
+	return best
+}
+
+// Count returns the number of tokens in text for model. Without a
+// registered encoder it falls back to Estimate and reports exact=false.
+func Count(model, text string) (n int, exact bool, err error) {
+	enc := ForModel(model)
+	if enc == nil {
+		return Estimate(text), false, nil
+	}
+	ids, err := enc.Encode(text)
+	if err != nil {
+		return 0, false, err
+	}
+	return len(ids), true, nil
+}
+
+// Estimate approximates the token count of text at four bytes per token,
+// which is close for English with BPE tokenizers.
+func Estimate(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (len(text) + 3) / 4
+}
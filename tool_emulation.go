@@ -1,10 +1,11 @@
 package uniai
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -19,38 +20,52 @@ func (c *Client) chatWithToolEmulation(ctx context.Context, providerName string,
 		return c.chatOnce(ctx, providerName, req)
 	}
 	debugFn := req.Options.DebugFn
-	diag.LogJSON(c.cfg.Debug, debugFn, "tool_emulation.start", map[string]any{
+	debug := c.cfg.Debug && !req.Options.NoStore
+	diag.LogJSON(debug, debugFn, "tool_emulation.start", map[string]any{
 		"provider": providerName,
 		"tools":    len(req.Tools),
 		"mode":     req.Options.ToolsEmulationMode,
 	})
 
-	decisionReq, err := buildToolDecisionRequest(req)
+	// a forced function only needs its arguments, not a decision
+	var (
+		decisionReq *chat.Request
+		parse       = parseToolDecision
+		err         error
+	)
+	if tool, ok := forcedFunctionTool(req); ok {
+		decisionReq, err = buildToolArgumentsRequest(req, tool, req.Options.ToolsEmulationConfidence)
+		parse = func(text string) ([]ToolDecision, error) {
+			return parseToolArguments(tool.Function.Name, text)
+		}
+	} else {
+		decisionReq, err = buildToolDecisionRequest(req)
+	}
 	if err != nil {
 		return nil, err
 	}
-	diag.LogJSON(c.cfg.Debug, debugFn, "tool_emulation.decision_request", decisionReq)
+	diag.LogJSON(debug, debugFn, "tool_emulation.decision_request", decisionReq)
 	decisionResp, err := c.chatOnce(ctx, providerName, decisionReq)
 	if err != nil {
 		return nil, err
 	}
-	diag.LogText(c.cfg.Debug, debugFn, "tool_emulation.decision_response", decisionResp.Text)
+	diag.LogText(debug, debugFn, "tool_emulation.decision_response", decisionResp.Text)
 
-	toolCalls, err := parseToolDecision(decisionResp.Text)
+	toolCalls, err := parse(decisionResp.Text)
 	if err != nil {
 		return nil, err
 	}
 	filteredCalls, dropped := filterUnknownTools(req.Tools, toolCalls)
-	diag.LogJSON(c.cfg.Debug, debugFn, "tool_emulation.parsed_calls", map[string]any{
+	diag.LogJSON(debug, debugFn, "tool_emulation.parsed_calls", map[string]any{
 		"calls":   filteredCalls,
 		"dropped": dropped,
 	})
 	if len(filteredCalls) == 0 {
 		if req.ToolChoice != nil && (req.ToolChoice.Mode == "required" || req.ToolChoice.Mode == "function") {
-			diag.LogText(c.cfg.Debug, debugFn, "tool_emulation.no_calls", "tool_choice requires a tool but none was produced")
+			diag.LogText(debug, debugFn, "tool_emulation.no_calls", "tool_choice requires a tool but none was produced")
 			return nil, fmt.Errorf("tool emulation expected a tool call but got null")
 		}
-		diag.LogText(c.cfg.Debug, debugFn, "tool_emulation.fallback", "no tool calls produced; returning final response")
+		diag.LogText(debug, debugFn, "tool_emulation.fallback", "no tool calls produced; returning final response")
 		finalReq := buildFinalRequest(req)
 		resp, err := c.chatOnce(ctx, providerName, finalReq)
 		if resp != nil {
@@ -65,6 +80,9 @@ func (c *Client) chatWithToolEmulation(ctx context.Context, providerName string,
 	if err := enforceToolChoice(req.ToolChoice, filteredCalls); err != nil {
 		return nil, err
 	}
+	if p := req.Options.ParallelToolCalls; p != nil && !*p && len(filteredCalls) > 1 {
+		filteredCalls = filteredCalls[:1]
+	}
 
 	calls := make([]chat.ToolCall, 0, len(filteredCalls))
 	for i, call := range filteredCalls {
@@ -76,9 +94,10 @@ func (c *Client) chatWithToolEmulation(ctx context.Context, providerName string,
 				Name:      call.Name,
 				Arguments: string(call.Arguments),
 			},
+			Metadata: call.metadata(),
 		})
 	}
-	diag.LogJSON(c.cfg.Debug, debugFn, "tool_emulation.emulated_calls", calls)
+	diag.LogJSON(debug, debugFn, "tool_emulation.emulated_calls", calls)
 	resp := &chat.Result{
 		Model:     decisionResp.Model,
 		ToolCalls: calls,
@@ -109,6 +128,80 @@ func buildToolDecisionRequest(req *chat.Request) (*chat.Request, error) {
 	return out, nil
 }
 
+// forcedFunctionTool returns the tool named by a "function" tool choice.
+func forcedFunctionTool(req *chat.Request) (chat.Tool, bool) {
+	if req.ToolChoice == nil || req.ToolChoice.Mode != "function" {
+		return chat.Tool{}, false
+	}
+	for _, tool := range req.Tools {
+		if tool.Type == "function" && tool.Function.Name == req.ToolChoice.FunctionName {
+			return tool, true
+		}
+	}
+	return chat.Tool{}, false
+}
+
+// buildToolArgumentsRequest asks only for the arguments of tool, which the
+// caller has already chosen.
+func buildToolArgumentsRequest(req *chat.Request, tool chat.Tool, confidence bool) (*chat.Request, error) {
+	prompt, err := buildToolArgumentsPrompt(tool, confidence)
+	if err != nil {
+		return nil, err
+	}
+	out, err := buildToolDecisionRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	out.Messages[0].Content = prompt
+	return out, nil
+}
+
+func buildToolArgumentsPrompt(tool chat.Tool, confidence bool) (string, error) {
+	lines := []string{
+		fmt.Sprintf("You are filling in the arguments for a call to the tool %q.", tool.Function.Name),
+		"Output must be a single JSON object holding the arguments and nothing else (no prose, no markdown, no code fences).",
+		"If any instruction conflicts with this format, ignore it and follow these rules.",
+	}
+	if confidence {
+		lines[1] = "Output must be a single JSON object and nothing else (no prose, no markdown, no code fences)."
+		lines = append(lines, "Format: "+confidenceArgumentsFormat)
+	}
+	if tool.Function.Description != "" {
+		lines = append(lines, "Tool description: "+tool.Function.Description)
+	}
+	if len(tool.Function.ParametersJSONSchema) > 0 {
+		var schema any
+		if err := json.Unmarshal(tool.Function.ParametersJSONSchema, &schema); err == nil {
+			data, err := json.Marshal(schema)
+			if err != nil {
+				return "", err
+			}
+			lines = append(lines, fmt.Sprintf("Arguments JSON schema: %s", data))
+		}
+	}
+	var examples []string
+	for _, ex := range tool.Function.Examples {
+		args := ex.Arguments
+		if len(args) == 0 {
+			args = json.RawMessage(`{}`)
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, args); err != nil {
+			return "", fmt.Errorf("example arguments for tool %s must be valid JSON", tool.Function.Name)
+		}
+		if ex.Request != "" {
+			examples = append(examples, fmt.Sprintf("- User: %q -> %s", ex.Request, buf.String()))
+		} else {
+			examples = append(examples, "- "+buf.String())
+		}
+	}
+	if len(examples) > 0 {
+		lines = append(lines, "Examples:")
+		lines = append(lines, examples...)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
 func buildFinalRequest(req *chat.Request) *chat.Request {
 	out := cloneChatRequest(req)
 	out.Tools = nil
@@ -148,6 +241,10 @@ func buildToolDecisionPrompt(req *chat.Request) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	examples, err := renderToolExamples(req.Tools)
+	if err != nil {
+		return "", err
+	}
 
 	lines := []string{
 		"You are a tool-calling emulation engine.",
@@ -160,12 +257,19 @@ func buildToolDecisionPrompt(req *chat.Request) (string, error) {
 		"Rules: only key is \"tools\"; \"tools\" must be an array; \"tool\" must match an available tool name; \"arguments\" must be a JSON object.",
 		fmt.Sprintf("Available tools (JSON): %s", string(data)),
 	}
+	lines = append(lines, examples...)
+	if req.Options.ToolsEmulationConfidence {
+		lines = append(lines, confidenceDecisionRule)
+	}
+	if p := req.Options.ParallelToolCalls; p != nil && !*p {
+		lines = append(lines, "Call at most one tool: tools[] must have no more than one entry.")
+	}
 	if req.ToolChoice != nil {
 		switch req.ToolChoice.Mode {
 		case "none":
 			lines = append(lines, "Tool choice: none. You MUST return {\"tools\":[]}.")
 		case "required":
-			lines = append(lines, "Tool choice: required. You MUST return at least one tool in tools[].")
+			lines = append(lines, "Tool choice: required. You MUST call at least one tool: tools[] must not be empty.")
 		case "function":
 			if req.ToolChoice.FunctionName != "" {
 				lines = append(lines, fmt.Sprintf("Tool choice: function. You MUST return exactly one tool named %q.", req.ToolChoice.FunctionName))
@@ -175,6 +279,46 @@ func buildToolDecisionPrompt(req *chat.Request) (string, error) {
 	return strings.Join(lines, "\n"), nil
 }
 
+const (
+	confidenceDecisionRule    = "Also give each tool entry a \"confidence\" (a number from 0 to 1 for how sure you are the call is right) and a \"reasoning\" (one short sentence): {\"tool\":\"<name>\",\"arguments\":{...},\"confidence\":0.9,\"reasoning\":\"...\"}"
+	confidenceArgumentsFormat = "{\"arguments\":{...},\"confidence\":<number from 0 to 1 for how sure you are the arguments are right>,\"reasoning\":\"<one short sentence>\"}"
+)
+
+// renderToolExamples formats ToolFunction.Examples as request/decision
+// pairs in the exact output format.
+func renderToolExamples(tools []chat.Tool) ([]string, error) {
+	var lines []string
+	for _, tool := range tools {
+		if tool.Type != "function" {
+			continue
+		}
+		for _, ex := range tool.Function.Examples {
+			args := ex.Arguments
+			if len(args) == 0 {
+				args = json.RawMessage(`{}`)
+			}
+			if !json.Valid(args) {
+				return nil, fmt.Errorf("example arguments for tool %s must be valid JSON", tool.Function.Name)
+			}
+			decision, err := json.Marshal(struct {
+				Tools []ToolDecision `json:"tools"`
+			}{[]ToolDecision{{Name: tool.Function.Name, Arguments: args}}})
+			if err != nil {
+				return nil, err
+			}
+			if ex.Request != "" {
+				lines = append(lines, fmt.Sprintf("- User: %q -> %s", ex.Request, decision))
+			} else {
+				lines = append(lines, "- "+string(decision))
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return append([]string{"Examples:"}, lines...), nil
+}
+
 func filterNonSystemMessages(messages []chat.Message) []chat.Message {
 	if len(messages) == 0 {
 		return messages
@@ -190,75 +334,257 @@ func filterNonSystemMessages(messages []chat.Message) []chat.Message {
 	return out
 }
 
-type emulatedToolCall struct {
-	Name      string
-	Arguments json.RawMessage
+// ToolDecision is one tool call parsed from an emulated tool decision.
+type ToolDecision struct {
+	Name      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	// Confidence and Reasoning are set when the model reports them; see
+	// chat.WithToolsEmulationConfidence.
+	Confidence *float64 `json:"confidence,omitempty"`
+	Reasoning  string   `json:"reasoning,omitempty"`
+}
+
+func (d ToolDecision) metadata() map[string]any {
+	if d.Confidence == nil && d.Reasoning == "" {
+		return nil
+	}
+	meta := map[string]any{}
+	if d.Confidence != nil {
+		meta[chat.MetadataConfidence] = *d.Confidence
+	}
+	if d.Reasoning != "" {
+		meta[chat.MetadataReasoning] = d.Reasoning
+	}
+	return meta
+}
+
+// ParseToolDecision extracts the tool calls from a model reply to the tool
+// emulation prompt. It tolerates leading commentary, multiple fenced code
+// blocks, JSON-encoded strings, truncated output, top-level arrays, "name"
+// or "parameters" in place of "tool" and "arguments", and arguments passed
+// as a JSON string. It returns no calls and no error when the reply
+// declines to call a tool or contains no JSON, and an error when the reply
+// contains a malformed decision.
+func ParseToolDecision(text string) ([]ToolDecision, error) {
+	return parseToolDecision(text)
 }
 
-func parseToolDecision(text string) ([]emulatedToolCall, error) {
-	cleaned := stripNonJSONLines(text)
-	if strings.TrimSpace(cleaned) == "" {
+func parseToolDecision(text string) ([]ToolDecision, error) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
 		return nil, nil
 	}
-	candidates, err := collectJSONCandidates(cleaned)
+	candidates, err := collectJSONCandidates(trimmed)
 	if err != nil {
 		return nil, err
 	}
-	var fallback []byte
+	var (
+		firstErr error
+		sawJSON  bool
+	)
 	for _, candidate := range candidates {
-		payload := strings.TrimSpace(candidate)
-		if payload == "" {
+		payload, ok := candidateJSON(candidate)
+		if !ok {
 			continue
 		}
-		if unquoted := unquoteJSON(payload); unquoted != "" {
-			payload = unquoted
-		}
-		if !json.Valid([]byte(payload)) {
-			repaired := attemptJSONRepair(payload)
-			if repaired == "" || !json.Valid([]byte(repaired)) {
-				continue
-			}
-			payload = repaired
-		}
-		if fallback == nil {
-			fallback = []byte(payload)
-		}
+		sawJSON = true
 		calls, ok, err := parseToolDecisionPayload([]byte(payload))
 		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
 			continue
 		}
 		if ok {
 			return calls, nil
 		}
 	}
-	if fallback != nil {
+	if firstErr != nil {
+		return nil, fmt.Errorf("invalid tool decision: %w", firstErr)
+	}
+	if sawJSON || !strings.Contains(trimmed, "{") {
 		return nil, nil
 	}
-	return nil, fmt.Errorf("invalid tool decision JSON: %q", strings.TrimSpace(text))
+	return nil, fmt.Errorf("invalid tool decision JSON: %q", trimmed)
 }
 
-func parseToolsArray(raw json.RawMessage) ([]emulatedToolCall, error) {
-	trimmed := strings.TrimSpace(string(raw))
-	if trimmed == "null" {
+// parseToolArguments reads the reply to the argument-only prompt. Besides
+// a bare arguments object it accepts a full decision for the named tool.
+func parseToolArguments(name, text string) ([]ToolDecision, error) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
 		return nil, nil
 	}
-	var items []struct {
-		Tool      json.RawMessage `json:"tool"`
-		Arguments json.RawMessage `json:"arguments"`
+	candidates, err := collectJSONCandidates(trimmed)
+	if err != nil {
+		return nil, err
 	}
-	if err := json.Unmarshal(raw, &items); err != nil {
-		return nil, fmt.Errorf("tools must be an array: %w", err)
+	for _, candidate := range candidates {
+		payload, ok := candidateJSON(candidate)
+		if !ok {
+			continue
+		}
+		if calls, shaped, err := parseToolDecisionPayload([]byte(payload)); err == nil && shaped {
+			// enforceToolChoice rejects a call to any other tool
+			return calls, nil
+		}
+		if strings.HasPrefix(payload, "{") {
+			item := decisionItem{Arguments: json.RawMessage(payload)}
+			if wrapped, ok := confidenceWrapper(payload); ok {
+				item = wrapped
+			}
+			call, _, err := parseSingleTool(json.RawMessage(strconv.Quote(name)), item.Arguments)
+			if err != nil {
+				return nil, err
+			}
+			item.annotate(&call)
+			return []ToolDecision{call}, nil
+		}
 	}
-	if len(items) == 0 {
+	return nil, fmt.Errorf("invalid tool arguments JSON: %q", trimmed)
+}
+
+// confidenceWrapper reports whether payload is the confidenceArgumentsFormat
+// wrapper rather than the arguments themselves: an "arguments" object next
+// to "confidence" or "reasoning" and no other keys.
+func confidenceWrapper(payload string) (decisionItem, bool) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal([]byte(payload), &fields) != nil {
+		return decisionItem{}, false
+	}
+	args, ok := fields["arguments"]
+	if !ok || len(fields) < 2 || len(fields) > 3 {
+		return decisionItem{}, false
+	}
+	item := decisionItem{Arguments: args}
+	for key, value := range fields {
+		switch key {
+		case "arguments":
+		case "confidence":
+			item.Confidence = value
+		case "reasoning":
+			item.Reasoning = value
+		default:
+			return decisionItem{}, false
+		}
+	}
+	return item, true
+}
+
+// candidateJSON unquotes and repairs a candidate, reporting whether the
+// result is valid JSON.
+func candidateJSON(candidate string) (string, bool) {
+	payload := strings.TrimSpace(candidate)
+	if payload == "" {
+		return "", false
+	}
+	if unquoted := unquoteJSON(payload); unquoted != "" {
+		payload = unquoted
+	}
+	if !json.Valid([]byte(payload)) {
+		repaired := attemptJSONRepair(payload)
+		if repaired == "" || !json.Valid([]byte(repaired)) {
+			return "", false
+		}
+		payload = repaired
+	}
+	return payload, true
+}
+
+// decisionItem is the shape of a single call. Name and Parameters are
+// aliases some models use for Tool and Arguments.
+type decisionItem struct {
+	Tool       json.RawMessage `json:"tool"`
+	Name       json.RawMessage `json:"name"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Parameters json.RawMessage `json:"parameters"`
+	Confidence json.RawMessage `json:"confidence"`
+	Reasoning  json.RawMessage `json:"reasoning"`
+}
+
+// call resolves the aliases. shaped is false when the object does not look
+// like a tool call at all; a shaped item with an empty or null tool yields a
+// call with no Name.
+func (d decisionItem) call() (call ToolDecision, shaped bool, err error) {
+	args := d.Arguments
+	if len(args) == 0 {
+		args = d.Parameters
+	}
+	tool := d.Tool
+	if len(tool) == 0 {
+		// "name" alone is too common in ordinary JSON to count as a call
+		if len(d.Name) == 0 || len(args) == 0 {
+			return ToolDecision{}, false, nil
+		}
+		tool = d.Name
+	}
+	call, _, err = parseSingleTool(tool, args)
+	if err == nil && call.Name != "" {
+		d.annotate(&call)
+	}
+	return call, true, err
+}
+
+// annotate copies a well-formed confidence and reasoning onto call.
+// Malformed values are ignored rather than failing the call.
+func (d decisionItem) annotate(call *ToolDecision) {
+	call.Confidence = parseConfidence(d.Confidence)
+	var reasoning string
+	if json.Unmarshal(d.Reasoning, &reasoning) == nil {
+		call.Reasoning = strings.TrimSpace(reasoning)
+	}
+}
+
+// parseConfidence accepts a number or numeric string in [0, 1], or a
+// percentage in (1, 100].
+func parseConfidence(raw json.RawMessage) *float64 {
+	var v any
+	if len(raw) == 0 || json.Unmarshal(raw, &v) != nil {
+		return nil
+	}
+	var f float64
+	switch x := v.(type) {
+	case float64:
+		f = x
+	case string:
+		s := strings.TrimSpace(x)
+		percent := strings.HasSuffix(s, "%")
+		parsed, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return nil
+		}
+		f = parsed
+		if percent {
+			f /= 100
+		}
+	default:
+		return nil
+	}
+	if f > 1 && f <= 100 {
+		f /= 100
+	}
+	if f < 0 || f > 1 {
+		return nil
+	}
+	return &f
+}
+
+func parseToolsArray(raw json.RawMessage) ([]ToolDecision, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "null" {
 		return nil, nil
 	}
-	out := make([]emulatedToolCall, 0, len(items))
+	var items []decisionItem
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("tools must be an array of objects: %w", err)
+	}
+	out := make([]ToolDecision, 0, len(items))
 	for _, item := range items {
-		call, ok, err := parseSingleTool(item.Tool, item.Arguments)
+		call, _, err := item.call()
 		if err != nil {
 			return nil, err
 		}
-		if ok {
+		if call.Name != "" {
 			out = append(out, call)
 		}
 	}
@@ -268,30 +594,39 @@ func parseToolsArray(raw json.RawMessage) ([]emulatedToolCall, error) {
 	return out, nil
 }
 
-func parseSingleTool(toolRaw json.RawMessage, argsRaw json.RawMessage) (emulatedToolCall, bool, error) {
+func parseSingleTool(toolRaw json.RawMessage, argsRaw json.RawMessage) (ToolDecision, bool, error) {
 	if len(toolRaw) == 0 {
-		return emulatedToolCall{}, false, nil
+		return ToolDecision{}, false, nil
 	}
 	raw := strings.TrimSpace(string(toolRaw))
 	if raw == "null" || raw == `""` {
-		return emulatedToolCall{}, false, nil
+		return ToolDecision{}, false, nil
 	}
 	var toolName string
 	if err := json.Unmarshal(toolRaw, &toolName); err != nil {
-		return emulatedToolCall{}, false, fmt.Errorf("tool must be string or null: %w", err)
+		return ToolDecision{}, false, fmt.Errorf("tool must be string or null: %w", err)
 	}
 	toolName = strings.TrimSpace(toolName)
 	if toolName == "" {
-		return emulatedToolCall{}, false, nil
+		return ToolDecision{}, false, nil
 	}
-	args := argsRaw
-	if len(args) == 0 {
+	args := json.RawMessage(bytes.TrimSpace(argsRaw))
+	if len(args) == 0 || string(args) == "null" {
 		args = json.RawMessage(`{}`)
 	}
 	if !json.Valid(args) {
-		return emulatedToolCall{}, false, fmt.Errorf("tool arguments must be valid JSON")
+		return ToolDecision{}, false, fmt.Errorf("tool arguments must be valid JSON")
+	}
+	// some models send the arguments JSON-encoded, as in the OpenAI API
+	if args[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(args, &encoded); err == nil {
+			if inner := strings.TrimSpace(encoded); strings.HasPrefix(inner, "{") && json.Valid([]byte(inner)) {
+				args = json.RawMessage(inner)
+			}
+		}
 	}
-	return emulatedToolCall{Name: toolName, Arguments: args}, true, nil
+	return ToolDecision{Name: toolName, Arguments: args}, true, nil
 }
 
 func collectJSONCandidates(text string) ([]string, error) {
@@ -300,25 +635,81 @@ func collectJSONCandidates(text string) ([]string, error) {
 		return nil, fmt.Errorf("empty tool decision")
 	}
 	candidates := []string{trimmed}
-	if strings.Contains(trimmed, "```") {
-		parts := strings.Split(trimmed, "```")
-		for i := 1; i < len(parts); i += 2 {
-			block := strings.TrimSpace(parts[i])
-			block = strings.TrimPrefix(block, "json")
-			block = strings.TrimSpace(block)
-			if block != "" {
-				candidates = append(candidates, block)
-			}
-		}
-	}
+	blocks := fencedBlocks(trimmed)
+	candidates = append(candidates, blocks...)
 	candidates = append(candidates, findJSONSnippets(trimmed)...)
 	if unquoted := unquoteJSON(trimmed); unquoted != "" {
 		candidates = append(candidates, unquoted)
 		candidates = append(candidates, findJSONSnippets(unquoted)...)
 	}
+	// a truncated object or array at the end of the reply is only reachable by
+	// repairing from its opening bracket
+	if i := firstUnclosed(trimmed); i > 0 {
+		candidates = append(candidates, trimmed[i:])
+	}
 	return candidates, nil
 }
 
+// fencedBlocks returns the contents of ``` code fences, without the
+// language tag.
+func fencedBlocks(text string) []string {
+	if !strings.Contains(text, "```") {
+		return nil
+	}
+	var blocks []string
+	parts := strings.Split(text, "```")
+	for i := 1; i < len(parts); i += 2 {
+		block := parts[i]
+		if nl := strings.IndexByte(block, '\n'); nl >= 0 {
+			tag := strings.TrimSpace(block[:nl])
+			if tag != "" && !strings.ContainsAny(tag, "{[\"") {
+				block = block[nl+1:]
+			}
+		} else {
+			block = strings.TrimPrefix(strings.TrimSpace(block), "json")
+		}
+		if block = strings.TrimSpace(block); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// firstUnclosed returns the index of the first '{' or '[' that is never
+// closed, or -1. Brackets inside strings are ignored.
+func firstUnclosed(text string) int {
+	var stack []int
+	inString, escape := false, false
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if inString {
+			switch {
+			case escape:
+				escape = false
+			case ch == '\\':
+				escape = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = len(stack) > 0
+		case '{', '[':
+			stack = append(stack, i)
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	if len(stack) == 0 {
+		return -1
+	}
+	return stack[0]
+}
+
 func stripNonJSONLines(input string) string {
 	lines := strings.Split(input, "\n")
 	out := make([]string, 0, len(lines))
@@ -413,77 +804,111 @@ func findJSONSnippets(text string) []string {
 	return snippets
 }
 
-func parseToolDecisionPayload(payload []byte) ([]emulatedToolCall, bool, error) {
+func parseToolDecisionPayload(payload []byte) ([]ToolDecision, bool, error) {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) > 0 && payload[0] == '[' {
+		// a bare array of calls counts only if its first element looks
+		// like one
+		var items []json.RawMessage
+		if err := json.Unmarshal(payload, &items); err != nil || len(items) == 0 {
+			return nil, false, nil
+		}
+		var first decisionItem
+		if err := json.Unmarshal(items[0], &first); err != nil {
+			return nil, false, nil
+		}
+		if _, ok, _ := first.call(); !ok {
+			return nil, false, nil
+		}
+		calls, err := parseToolsArray(payload)
+		return calls, true, err
+	}
 	var decision struct {
-		Tools     json.RawMessage `json:"tools"`
-		Tool      json.RawMessage `json:"tool"`
-		Arguments json.RawMessage `json:"arguments"`
+		decisionItem
+		Tools json.RawMessage `json:"tools"`
 	}
 	if err := json.Unmarshal(payload, &decision); err != nil {
-		return nil, false, err
-	}
-	if len(decision.Tools) == 0 && len(decision.Tool) == 0 {
 		return nil, false, nil
 	}
 	if len(decision.Tools) > 0 {
 		calls, err := parseToolsArray(decision.Tools)
 		return calls, true, err
 	}
-	call, ok, err := parseSingleTool(decision.Tool, decision.Arguments)
-	if err != nil {
-		return nil, true, err
+	call, shaped, err := decision.call()
+	if !shaped {
+		return nil, false, nil
 	}
-	if !ok {
-		return nil, true, nil
+	if err != nil || call.Name == "" {
+		return nil, true, err
 	}
-	return []emulatedToolCall{call}, true, nil
+	return []ToolDecision{call}, true, nil
 }
 
-var trailingCommaRe = regexp.MustCompile(`,\s*([}\]])`)
-
+// attemptJSONRepair drops trailing commas, closes an unterminated string
+// and closes open objects and arrays in order. Characters inside strings are
+// left alone. It returns "" when the input has no JSON structure or closes
+// a bracket it never opened.
 func attemptJSONRepair(input string) string {
 	trimmed := strings.TrimSpace(input)
-	if trimmed == "" {
-		return ""
-	}
-	if !strings.ContainsAny(trimmed, "{[") {
+	if trimmed == "" || !strings.ContainsAny(trimmed, "{[") {
 		return ""
 	}
-	repaired := trailingCommaRe.ReplaceAllString(trimmed, "$1")
-
-	inString := false
-	escaped := false
-	for i := 0; i < len(repaired); i++ {
-		ch := repaired[i]
-		if escaped {
-			escaped = false
-			continue
-		}
-		if ch == '\\' {
-			escaped = true
+	var (
+		out      strings.Builder
+		stack    []byte
+		inString bool
+		escape   bool
+	)
+	out.Grow(len(trimmed) + 4)
+	for i := 0; i < len(trimmed); i++ {
+		ch := trimmed[i]
+		if inString {
+			out.WriteByte(ch)
+			switch {
+			case escape:
+				escape = false
+			case ch == '\\':
+				escape = true
+			case ch == '"':
+				inString = false
+			}
 			continue
 		}
-		if ch == '"' {
-			inString = !inString
+		switch ch {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, ch)
+		case '}', ']':
+			if len(stack) == 0 || (stack[len(stack)-1] == '{') != (ch == '}') {
+				return ""
+			}
+			stack = stack[:len(stack)-1]
+		case ',':
+			rest := strings.TrimLeftFunc(trimmed[i+1:], unicode.IsSpace)
+			if rest == "" || rest[0] == '}' || rest[0] == ']' {
+				continue
+			}
 		}
+		out.WriteByte(ch)
 	}
-	if inString {
-		repaired += `"`
+	if escape {
+		// drop a dangling backslash so the closing quote is not escaped
+		s := out.String()
+		out.Reset()
+		out.WriteString(s[:len(s)-1])
 	}
-
-	openBraces := strings.Count(repaired, "{")
-	closeBraces := strings.Count(repaired, "}")
-	for i := closeBraces; i < openBraces; i++ {
-		repaired += "}"
+	if inString {
+		out.WriteByte('"')
 	}
-
-	openBrackets := strings.Count(repaired, "[")
-	closeBrackets := strings.Count(repaired, "]")
-	for i := closeBrackets; i < openBrackets; i++ {
-		repaired += "]"
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			out.WriteByte('}')
+		} else {
+			out.WriteByte(']')
+		}
 	}
-
-	return repaired
+	return out.String()
 }
 
 func scanJSONSubstring(data []byte, start int) string {
@@ -545,11 +970,11 @@ func toolExists(tools []chat.Tool, name string) bool {
 	return false
 }
 
-func filterUnknownTools(tools []chat.Tool, calls []emulatedToolCall) ([]emulatedToolCall, int) {
+func filterUnknownTools(tools []chat.Tool, calls []ToolDecision) ([]ToolDecision, int) {
 	if len(calls) == 0 {
 		return nil, 0
 	}
-	filtered := make([]emulatedToolCall, 0, len(calls))
+	filtered := make([]ToolDecision, 0, len(calls))
 	dropped := 0
 	for _, call := range calls {
 		if toolExists(tools, call.Name) {
@@ -561,7 +986,7 @@ func filterUnknownTools(tools []chat.Tool, calls []emulatedToolCall) ([]emulated
 	return filtered, dropped
 }
 
-func enforceToolChoice(choice *chat.ToolChoice, calls []emulatedToolCall) error {
+func enforceToolChoice(choice *chat.ToolChoice, calls []ToolDecision) error {
 	if choice == nil {
 		return nil
 	}
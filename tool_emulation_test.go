@@ -1,11 +1,14 @@
 package uniai
 
 import (
+	"context"
 	"encoding/json"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/quailyquaily/uniai/chat"
+	"github.com/quailyquaily/uniai/providers/fake"
 )
 
 func TestParseToolDecisionSingle(t *testing.T) {
@@ -82,7 +85,7 @@ func TestBuildToolDecisionPrompt(t *testing.T) {
 }
 
 func TestEnforceToolChoice(t *testing.T) {
-	err := enforceToolChoice(&chat.ToolChoice{Mode: "none"}, []emulatedToolCall{{Name: "a"}})
+	err := enforceToolChoice(&chat.ToolChoice{Mode: "none"}, []ToolDecision{{Name: "a"}})
 	if err == nil {
 		t.Fatalf("expected error for tool_choice none")
 	}
@@ -90,7 +93,7 @@ func TestEnforceToolChoice(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected error for tool_choice required with no calls")
 	}
-	err = enforceToolChoice(&chat.ToolChoice{Mode: "function", FunctionName: "a"}, []emulatedToolCall{{Name: "b"}})
+	err = enforceToolChoice(&chat.ToolChoice{Mode: "function", FunctionName: "a"}, []ToolDecision{{Name: "b"}})
 	if err == nil {
 		t.Fatalf("expected error for tool_choice function mismatch")
 	}
@@ -111,3 +114,180 @@ func assertArgs(t *testing.T, raw json.RawMessage, expected map[string]any) {
 		}
 	}
 }
+
+type toolDecisionCase struct {
+	Name   string `json:"name"`
+	Output string `json:"output"`
+	Calls  []struct {
+		Tool      string         `json:"tool"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"calls"`
+	Error bool `json:"error"`
+}
+
+func loadToolDecisionCorpus(tb testing.TB) []toolDecisionCase {
+	tb.Helper()
+	data, err := os.ReadFile("testdata/tool_decisions.json")
+	if err != nil {
+		tb.Fatalf("read corpus: %v", err)
+	}
+	var cases []toolDecisionCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		tb.Fatalf("parse corpus: %v", err)
+	}
+	return cases
+}
+
+func TestParseToolDecisionCorpus(t *testing.T) {
+	for _, tc := range loadToolDecisionCorpus(t) {
+		t.Run(tc.Name, func(t *testing.T) {
+			calls, err := ParseToolDecision(tc.Output)
+			if tc.Error {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(calls) != len(tc.Calls) {
+				t.Fatalf("expected %d calls, got %+v", len(tc.Calls), calls)
+			}
+			for i, want := range tc.Calls {
+				if calls[i].Name != want.Tool {
+					t.Fatalf("call %d: expected %s, got %s", i, want.Tool, calls[i].Name)
+				}
+				assertArgs(t, calls[i].Arguments, want.Arguments)
+			}
+		})
+	}
+}
+
+func FuzzParseToolDecision(f *testing.F) {
+	for _, tc := range loadToolDecisionCorpus(f) {
+		f.Add(tc.Output)
+	}
+	f.Fuzz(func(t *testing.T, text string) {
+		calls, err := ParseToolDecision(text)
+		if err != nil {
+			return
+		}
+		for _, call := range calls {
+			if call.Name == "" {
+				t.Fatalf("call without a name from %q", text)
+			}
+			if !json.Valid(call.Arguments) {
+				t.Fatalf("invalid arguments %q from %q", call.Arguments, text)
+			}
+		}
+	})
+}
+
+func TestBuildToolDecisionPromptExamples(t *testing.T) {
+	tool := FunctionTool("get_weather", "Get weather", []byte(`{"type":"object"}`))
+	tool.Function.Examples = []chat.ToolExample{
+		{Request: "Is it raining in Oslo?", Arguments: json.RawMessage(`{"city":"Oslo"}`)},
+	}
+	prompt, err := buildToolDecisionPrompt(&chat.Request{Tools: []chat.Tool{tool}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `- User: "Is it raining in Oslo?" -> {"tools":[{"tool":"get_weather","arguments":{"city":"Oslo"}}]}`
+	if !strings.Contains(prompt, "Examples:\n"+want) {
+		t.Fatalf("prompt missing rendered example:\n%s", prompt)
+	}
+
+	tool.Function.Examples[0].Arguments = json.RawMessage(`{bad`)
+	if _, err := buildToolDecisionPrompt(&chat.Request{Tools: []chat.Tool{tool}}); err == nil {
+		t.Fatalf("expected error for invalid example arguments")
+	}
+}
+
+func TestToolEmulationFunctionChoice(t *testing.T) {
+	provider := fake.New(fake.Config{
+		Responses: []fake.Response{fake.Text("```json\n{\"city\": \"Oslo\",}\n```", 0, 0)},
+	})
+	client := New(Config{})
+	client.RegisterProvider("fake", provider)
+
+	resp, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User("Is it raining in Oslo?")),
+		WithTools([]Tool{
+			FunctionTool("get_weather", "Get weather", []byte(`{"type":"object","properties":{"city":{"type":"string"}}}`)),
+			FunctionTool("get_time", "Get time", nil),
+		}),
+		WithToolChoice(ToolChoiceFunction("get_weather")),
+		WithToolsEmulationMode(ToolsEmulationForce),
+	)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Function.Name != "get_weather" || resp.ToolCalls[0].Function.Arguments != `{"city": "Oslo"}` {
+		t.Fatalf("unexpected tool calls %+v", resp.ToolCalls)
+	}
+	if len(resp.Messages) != 1 || len(resp.Messages[0].ToolCalls) != 1 {
+		t.Fatalf("assistant turn not reconstructed: %+v", resp.Messages)
+	}
+	prompt := provider.Requests()[0].Messages[0].Content
+	if !strings.Contains(prompt, `arguments for a call to the tool "get_weather"`) || strings.Contains(prompt, "get_time") {
+		t.Fatalf("unexpected prompt:\n%s", prompt)
+	}
+}
+
+func TestParseToolArguments(t *testing.T) {
+	calls, err := parseToolArguments("get_weather", `{"tools":[{"tool":"get_weather","arguments":{"city":"Oslo"}}]}`)
+	if err != nil || len(calls) != 1 || string(calls[0].Arguments) != `{"city":"Oslo"}` {
+		t.Fatalf("unexpected decision %+v, %v", calls, err)
+	}
+	if _, err := parseToolArguments("get_weather", "I cannot help with that."); err == nil {
+		t.Fatalf("expected error without arguments")
+	}
+}
+
+func TestToolEmulationConfidence(t *testing.T) {
+	provider := fake.New(fake.Config{
+		Responses: []fake.Response{
+			fake.Text(`{"tools":[{"tool":"get_weather","arguments":{"city":"Oslo"},"confidence":"85%","reasoning":"asks about weather"}]}`, 0, 0),
+			fake.Text(`{"arguments":{"city":"Oslo"},"confidence":0.4}`, 0, 0),
+		},
+	})
+	client := New(Config{})
+	client.RegisterProvider("fake", provider)
+	tools := []Tool{FunctionTool("get_weather", "Get weather", []byte(`{"type":"object"}`))}
+
+	resp, err := client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User("Is it raining in Oslo?")),
+		WithTools(tools),
+		WithToolsEmulationMode(ToolsEmulationForce),
+		WithToolsEmulationConfidence(),
+	)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	call := resp.ToolCalls[0]
+	if c, ok := call.Confidence(); !ok || c != 0.85 || call.Metadata[MetadataReasoning] != "asks about weather" {
+		t.Fatalf("unexpected metadata %+v", call.Metadata)
+	}
+	if !strings.Contains(provider.Requests()[0].Messages[0].Content, `"confidence"`) {
+		t.Fatalf("decision prompt does not ask for confidence")
+	}
+
+	resp, err = client.Chat(context.Background(),
+		WithProvider("fake"),
+		WithMessages(User("Is it raining in Oslo?")),
+		WithTools(tools),
+		WithToolChoice(ToolChoiceFunction("get_weather")),
+		WithToolsEmulationMode(ToolsEmulationForce),
+		WithToolsEmulationConfidence(),
+	)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	call = resp.ToolCalls[0]
+	if c, ok := call.Confidence(); !ok || c != 0.4 || call.Function.Arguments != `{"city":"Oslo"}` {
+		t.Fatalf("unexpected call %+v", call)
+	}
+}
@@ -0,0 +1,78 @@
+package uniai
+
+import (
+	"strings"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+type registeredTool struct {
+	tool chat.Tool
+	tags []string
+}
+
+// RegisterTool adds tool to the client's tool registry. Requests select
+// registered tools by tag with chat.WithToolTags. Tags can be namespaced
+// with slashes: selecting "crm" also matches "crm/read" and "crm/write".
+// Registering a tool name again replaces the earlier tool.
+func (c *Client) RegisterTool(tool chat.Tool, tags ...string) {
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+	entry := registeredTool{tool: tool, tags: append([]string(nil), tags...)}
+	for i := range c.tools {
+		if c.tools[i].tool.Function.Name == tool.Function.Name {
+			c.tools[i] = entry
+			return
+		}
+	}
+	c.tools = append(c.tools, entry)
+}
+
+// UnregisterTool removes the named tool from the registry.
+func (c *Client) UnregisterTool(name string) {
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+	for i := range c.tools {
+		if c.tools[i].tool.Function.Name == name {
+			c.tools = append(c.tools[:i], c.tools[i+1:]...)
+			return
+		}
+	}
+}
+
+// RegisteredTools returns the registered tools that carry any of tags, in
+// registration order. Without tags it returns every registered tool.
+func (c *Client) RegisteredTools(tags ...string) []chat.Tool {
+	c.toolsMu.RLock()
+	defer c.toolsMu.RUnlock()
+	var out []chat.Tool
+	for _, t := range c.tools {
+		if len(tags) == 0 || matchToolTags(t.tags, tags) {
+			out = append(out, t.tool)
+		}
+	}
+	return out
+}
+
+// withRegisteredTools adds the registered tools selected by tags to tools.
+// A tool already in tools keeps the request's definition.
+func (c *Client) withRegisteredTools(tools []chat.Tool, tags []string) []chat.Tool {
+	out := append([]chat.Tool(nil), tools...)
+	for _, t := range c.RegisteredTools(tags...) {
+		if !toolExists(out, t.Function.Name) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func matchToolTags(have, want []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if h == w || strings.HasPrefix(h, w+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package std
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/quailyquaily/uniai/agent"
+)
+
+// Calculator returns the calculator tool. It evaluates arithmetic with
+// + - * / % ^ and parentheses.
+func Calculator() agent.Tool {
+	return agent.Tool{
+		Name:        "calculator",
+		Description: "Evaluate an arithmetic expression with + - * / % ^ and parentheses.",
+		Parameters:  []byte(`{"type":"object","properties":{"expression":{"type":"string","description":"For example (2 + 3) * 4.5"}},"required":["expression"]}`),
+		Run: func(ctx context.Context, args string) (string, error) {
+			var in struct {
+				Expression string `json:"expression"`
+			}
+			if err := decodeArgs(args, &in); err != nil {
+				return "", err
+			}
+			v, err := Eval(in.Expression)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		},
+	}
+}
+
+// Eval evaluates an arithmetic expression.
+func Eval(expr string) (float64, error) {
+	p := &exprParser{s: expr}
+	v, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	p.space()
+	if p.pos < len(p.s) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.s[p.pos:], p.pos)
+	}
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return v, nil
+}
+
+type exprParser struct {
+	s   string
+	pos int
+}
+
+func (p *exprParser) space() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space byte, or 0 at the end.
+func (p *exprParser) peek() byte {
+	p.space()
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *exprParser) sum() (float64, error) {
+	v, err := p.product()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			r, err := p.product()
+			if err != nil {
+				return 0, err
+			}
+			v += r
+		case '-':
+			p.pos++
+			r, err := p.product()
+			if err != nil {
+				return 0, err
+			}
+			v -= r
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *exprParser) product() (float64, error) {
+	v, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return v, nil
+		}
+		p.pos++
+		r, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			v *= r
+		case '/':
+			if r == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			v /= r
+		case '%':
+			if r == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			v = math.Mod(v, r)
+		}
+	}
+}
+
+// unary applies signs to a power, so -2^2 is -4.
+func (p *exprParser) unary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		v, err := p.unary()
+		return -v, err
+	case '+':
+		p.pos++
+		return p.unary()
+	}
+	return p.power()
+}
+
+// power is right-associative: 2^3^2 is 2^9.
+func (p *exprParser) power() (float64, error) {
+	v, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != '^' {
+		return v, nil
+	}
+	p.pos++
+	r, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(v, r), nil
+}
+
+func (p *exprParser) primary() (float64, error) {
+	switch p.peek() {
+	case '(':
+		p.pos++
+		v, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return v, nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte("0123456789.eE", p.s[p.pos]) >= 0 {
+		// allow the sign of an exponent, as in 1e-3
+		if (p.s[p.pos] == 'e' || p.s[p.pos] == 'E') && p.pos+1 < len(p.s) && (p.s[p.pos+1] == '-' || p.s[p.pos+1] == '+') {
+			p.pos++
+		}
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos >= len(p.s) {
+			return 0, fmt.Errorf("unexpected end of expression")
+		}
+		return 0, fmt.Errorf("unexpected %q at position %d", p.s[p.pos], p.pos)
+	}
+	v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.s[start:p.pos])
+	}
+	return v, nil
+}
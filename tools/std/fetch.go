@@ -0,0 +1,98 @@
+package std
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/agent"
+)
+
+// FetchConfig configures HTTPFetch.
+type FetchConfig struct {
+	// AllowedHosts are the hosts that may be fetched, with their
+	// subdomains. Requests to other hosts, including redirects, fail.
+	AllowedHosts []string
+	// MaxBytes caps the returned body (default DefaultMaxBytes).
+	MaxBytes int
+	// Client defaults to a client with a 30 second timeout.
+	Client *http.Client
+}
+
+// HTTPFetch returns the http_fetch tool. It GETs a URL on one of the
+// allowed hosts and returns the status and body.
+func HTTPFetch(cfg FetchConfig) agent.Tool {
+	checked := allowlistClient(cfg.Client, cfg.AllowedHosts)
+	return agent.Tool{
+		Name:        "http_fetch",
+		Description: "Fetch a web page or API response with HTTP GET. Only these hosts are allowed: " + strings.Join(cfg.AllowedHosts, ", ") + ".",
+		Parameters:  []byte(`{"type":"object","properties":{"url":{"type":"string"}},"required":["url"]}`),
+		Run: func(ctx context.Context, args string) (string, error) {
+			var in struct {
+				URL string `json:"url"`
+			}
+			if err := decodeArgs(args, &in); err != nil {
+				return "", err
+			}
+			u, err := url.Parse(in.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return "", fmt.Errorf("invalid url %q", in.URL)
+			}
+			if !hostAllowed(u, cfg.AllowedHosts) {
+				return "", fmt.Errorf("host %s is not allowed", u.Hostname())
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+			if err != nil {
+				return "", err
+			}
+			resp, err := checked.Do(req)
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			maxBytes := cfg.MaxBytes
+			if maxBytes <= 0 {
+				maxBytes = DefaultMaxBytes
+			}
+			body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("status %d\n\n%s", resp.StatusCode, truncate(string(body), maxBytes)), nil
+		},
+	}
+}
+
+// allowlistClient returns a copy of client (default: 30 second timeout)
+// that also checks redirects against the allowed hosts.
+func allowlistClient(client *http.Client, allowed []string) *http.Client {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	checked := *client
+	checked.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !hostAllowed(req.URL, allowed) {
+			return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("too many redirects")
+		}
+		return nil
+	}
+	return &checked
+}
+
+func hostAllowed(u *url.URL, allowed []string) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, a := range allowed {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a != "" && (host == a || strings.HasSuffix(host, "."+a)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package std
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/quailyquaily/uniai/agent"
+)
+
+// ReadFile returns the read_file tool. It reads files below root, which
+// the model cannot escape with ".." or symbolic links.
+func ReadFile(root string, maxBytes int) agent.Tool {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return agent.Tool{
+		Name:        "read_file",
+		Description: "Read a text file. Paths are relative to the workspace root.",
+		Parameters:  []byte(`{"type":"object","properties":{"path":{"type":"string"}},"required":["path"]}`),
+		Run: func(ctx context.Context, args string) (string, error) {
+			var in struct {
+				Path string `json:"path"`
+			}
+			if err := decodeArgs(args, &in); err != nil {
+				return "", err
+			}
+			dir, err := os.OpenRoot(root)
+			if err != nil {
+				return "", err
+			}
+			defer dir.Close()
+			f, err := dir.Open(filepath.Clean(in.Path))
+			if err != nil {
+				return "", fmt.Errorf("cannot read %q: %w", in.Path, err)
+			}
+			defer f.Close()
+			data, err := io.ReadAll(io.LimitReader(f, int64(maxBytes)+1))
+			if err != nil {
+				return "", err
+			}
+			return truncate(string(data), maxBytes), nil
+		},
+	}
+}
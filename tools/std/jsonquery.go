@@ -0,0 +1,80 @@
+package std
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/quailyquaily/uniai/agent"
+)
+
+// JSONQuery returns the json_query tool. It selects a value from a JSON
+// document by a path such as "items[0].name".
+func JSONQuery() agent.Tool {
+	return agent.Tool{
+		Name:        "json_query",
+		Description: "Select a value from a JSON document by path, such as items[0].name. An empty path returns the whole document.",
+		Parameters:  []byte(`{"type":"object","properties":{"json":{"type":"string"},"path":{"type":"string"}},"required":["json","path"]}`),
+		Run: func(ctx context.Context, args string) (string, error) {
+			var in struct {
+				JSON string `json:"json"`
+				Path string `json:"path"`
+			}
+			if err := decodeArgs(args, &in); err != nil {
+				return "", err
+			}
+			var doc any
+			if err := json.Unmarshal([]byte(in.JSON), &doc); err != nil {
+				return "", fmt.Errorf("invalid json: %w", err)
+			}
+			v, err := Query(doc, in.Path)
+			if err != nil {
+				return "", err
+			}
+			out, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			return string(out), nil
+		},
+	}
+}
+
+// Query returns the value at path in a decoded JSON document. Path
+// segments are object keys separated by dots, and [n] array indexes.
+func Query(doc any, path string) (any, error) {
+	cur := doc
+	for _, seg := range splitPath(path) {
+		if arr, ok := cur.([]any); ok {
+			if i, err := strconv.Atoi(seg); err == nil {
+				if i < 0 || i >= len(arr) {
+					return nil, fmt.Errorf("index %d out of range", i)
+				}
+				cur = arr[i]
+				continue
+			}
+		}
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot select %q from %T", seg, cur)
+		}
+		if cur, ok = obj[seg]; !ok {
+			return nil, fmt.Errorf("key %q not found", seg)
+		}
+	}
+	return cur, nil
+}
+
+// splitPath splits "a.b[0].c" into "a", "b", "0", "c".
+func splitPath(path string) []string {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(strings.TrimSpace(path))
+	var out []string
+	for _, seg := range strings.Split(path, ".") {
+		if seg != "" {
+			out = append(out, seg)
+		}
+	}
+	return out
+}
@@ -0,0 +1,251 @@
+package std
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// boilerplateClass matches class and id values of page chrome.
+	boilerplateClass = regexp.MustCompile(`(?i)comment|sidebar|footer|masthead|nav|menu|share|social|cookie|banner|advert|\bads?\b|promo|related|breadcrumb|popup|modal|subscribe|newsletter`)
+	// contentClass matches class and id values that usually hold the text.
+	contentClass = regexp.MustCompile(`(?i)article|content|main|post|entry|story|body|text`)
+)
+
+// skipped are elements that never hold readable text.
+var skipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Button: true, atom.Iframe: true, atom.Svg: true,
+	atom.Select: true, atom.Dialog: true,
+}
+
+// blocks are elements rendered on their own lines.
+var blocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Pre: true, atom.Blockquote: true,
+	atom.Table: true, atom.Tr: true, atom.Br: true, atom.Hr: true, atom.Figure: true,
+	atom.Figcaption: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+}
+
+// ExtractText returns the title and the readable text of an HTML page.
+// Navigation, headers, footers, scripts and similar page chrome are
+// removed, and the element holding most of the paragraph text is kept.
+// Headings are marked with "#" and list items with "-".
+func ExtractText(r io.Reader) (title, text string, err error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", "", err
+	}
+	if t := findFirst(doc, atom.Title); t != nil {
+		title = collapseSpace(nodeText(t))
+	}
+	prune(doc)
+	root := mainContent(doc)
+	var b strings.Builder
+	render(&b, root)
+	return title, tidyLines(b.String()), nil
+}
+
+// prune removes page chrome from the tree.
+func prune(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isBoilerplate(c)) {
+			n.RemoveChild(c)
+		} else {
+			prune(c)
+		}
+		c = next
+	}
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if skipped[n.DataAtom] || attr(n, "aria-hidden") == "true" || hasAttr(n, "hidden") {
+		return true
+	}
+	switch attr(n, "role") {
+	case "navigation", "banner", "contentinfo", "complementary", "dialog":
+		return true
+	}
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Html || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return boilerplateClass.MatchString(names) && !contentClass.MatchString(names)
+}
+
+// mainContent returns the <article> or <main> element with the most text,
+// or else the element whose paragraphs hold the most text, or else <body>.
+func mainContent(doc *html.Node) *html.Node {
+	var best *html.Node
+	bestLen := 0
+	walk(doc, func(n *html.Node) {
+		if n.DataAtom == atom.Article || n.DataAtom == atom.Main || attr(n, "role") == "main" {
+			if l := len(collapseSpace(nodeText(n))); l > bestLen {
+				best, bestLen = n, l
+			}
+		}
+	})
+	if best != nil {
+		return best
+	}
+	scores := map[*html.Node]float64{}
+	walk(doc, func(n *html.Node) {
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Blockquote {
+			return
+		}
+		text := collapseSpace(nodeText(n))
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		score *= 1 - linkDensity(n, len(text))
+		if p := n.Parent; p != nil {
+			scores[p] += score
+			if gp := p.Parent; gp != nil {
+				scores[gp] += score / 2
+			}
+		}
+	})
+	bestScore := 0.0
+	for n, s := range scores {
+		names := attr(n, "class") + " " + attr(n, "id")
+		if contentClass.MatchString(names) {
+			s *= 1.25
+		}
+		if s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	if best != nil {
+		return best
+	}
+	if body := findFirst(doc, atom.Body); body != nil {
+		return body
+	}
+	return doc
+}
+
+// linkDensity is the share of n's text that is link text.
+func linkDensity(n *html.Node, textLen int) float64 {
+	if textLen == 0 {
+		return 0
+	}
+	links := 0
+	walk(n, func(c *html.Node) {
+		if c.DataAtom == atom.A {
+			links += len(collapseSpace(nodeText(c)))
+		}
+	})
+	return min(float64(links)/float64(textLen), 1)
+}
+
+func render(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if n.DataAtom == atom.Pre {
+			b.WriteString("\n" + nodeText(n) + "\n")
+			return
+		}
+		if blocks[n.DataAtom] {
+			b.WriteString("\n")
+		}
+		switch n.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			b.WriteString(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		case atom.Li:
+			b.WriteString("\n- ")
+		case atom.Td, atom.Th:
+			b.WriteString(" ")
+		case atom.Img:
+			if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+				b.WriteString("[" + alt + "]")
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		render(b, c)
+	}
+	if n.Type == html.ElementNode && blocks[n.DataAtom] {
+		b.WriteString("\n")
+	}
+}
+
+// tidyLines collapses the spaces within lines and runs of blank lines.
+func tidyLines(s string) string {
+	var out []string
+	blank := true
+	for _, line := range strings.Split(s, "\n") {
+		line = collapseSpace(line)
+		if line == "" {
+			if !blank {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		out = append(out, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	})
+	return b.String()
+}
+
+func walk(n *html.Node, fn func(*html.Node)) {
+	fn(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if f := findFirst(c, a); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,280 @@
+package std
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/agent"
+)
+
+// DefaultMaxArtifactBytes is the default size of /output in the sandbox.
+const DefaultMaxArtifactBytes = 16 << 20
+
+// SandboxConfig configures CodeInterpreter. The code runs in a throwaway
+// Docker container without network access, with a read-only file system
+// except for /tmp and /output. The image must provide sleep, which keeps
+// the container up while the code runs, and tar when ArtifactDir is set.
+type SandboxConfig struct {
+	// Image is the container image (default "python:3.12-alpine").
+	Image string
+	// Command reads the code from stdin (default python -).
+	Command []string
+	// Language names the language in the tool description (default "Python").
+	Language string
+	// Timeout limits a run, including container start (default 30s).
+	Timeout time.Duration
+	// Memory is the docker memory limit (default "256m").
+	Memory string
+	// CPUs is the docker CPU limit (default "1").
+	CPUs string
+	// ArtifactDir, if set, receives the files the code writes to /output,
+	// one subdirectory per run. Without it /output is discarded.
+	ArtifactDir string
+	// MaxArtifactBytes is the size of /output, so it caps what a run can
+	// write there (default DefaultMaxArtifactBytes).
+	MaxArtifactBytes int64
+	// MaxBytes caps stdout and stderr each (default DefaultMaxBytes).
+	MaxBytes int
+	// Docker is the docker binary (default "docker").
+	Docker string
+}
+
+// CodeInterpreter returns the run_code tool. It runs model-written code in
+// a Docker container with CPU, memory, process and time limits, and returns
+// the exit code, stdout, stderr and the files written to /output. The tool
+// executes arbitrary code, so it is never part of Tools unless
+// Config.Sandbox is set.
+func CodeInterpreter(cfg SandboxConfig) agent.Tool {
+	if cfg.Image == "" {
+		cfg.Image = "python:3.12-alpine"
+	}
+	if len(cfg.Command) == 0 {
+		cfg.Command = []string{"python", "-"}
+	}
+	if cfg.Language == "" {
+		cfg.Language = "Python"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.Memory == "" {
+		cfg.Memory = "256m"
+	}
+	if cfg.CPUs == "" {
+		cfg.CPUs = "1"
+	}
+	if cfg.Docker == "" {
+		cfg.Docker = "docker"
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.MaxArtifactBytes <= 0 {
+		cfg.MaxArtifactBytes = DefaultMaxArtifactBytes
+	}
+	return agent.Tool{
+		Name:        "run_code",
+		Description: "Run a " + cfg.Language + " program in an isolated sandbox without network access and return its output. Files written to /output are kept as artifacts.",
+		Parameters:  []byte(`{"type":"object","properties":{"code":{"type":"string"}},"required":["code"]}`),
+		Run: func(ctx context.Context, args string) (string, error) {
+			var in struct {
+				Code string `json:"code"`
+			}
+			if err := decodeArgs(args, &in); err != nil {
+				return "", err
+			}
+			return runSandbox(ctx, cfg, in.Code)
+		},
+	}
+}
+
+func runSandbox(ctx context.Context, cfg SandboxConfig, code string) (string, error) {
+	var id [6]byte
+	rand.Read(id[:])
+	name := "uniai-sandbox-" + hex.EncodeToString(id[:])
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	// The container idles while the code runs in it through docker exec,
+	// so /output can be a tmpfs of capped size that is copied out once the
+	// code is done.
+	runArgs := []string{
+		"run", "-d", "--rm", "--name", name,
+		"--network", "none",
+		"--memory", cfg.Memory, "--memory-swap", cfg.Memory,
+		"--cpus", cfg.CPUs,
+		"--pids-limit", "64",
+		"--read-only", "--tmpfs", "/tmp",
+		"--tmpfs", "/output:size=" + strconv.FormatInt(cfg.MaxArtifactBytes, 10),
+		"--security-opt", "no-new-privileges",
+		"--workdir", "/tmp",
+		"--entrypoint", "sleep",
+		cfg.Image, strconv.Itoa(int(cfg.Timeout/time.Second) + 1),
+	}
+	if out, err := exec.CommandContext(ctx, cfg.Docker, runArgs...).CombinedOutput(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("code did not finish within %s", cfg.Timeout)
+		}
+		return "", fmt.Errorf("sandbox: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	// killing the docker client does not stop the container
+	defer exec.Command(cfg.Docker, "rm", "-f", name).Run()
+
+	cmd := exec.CommandContext(ctx, cfg.Docker, append([]string{"exec", "-i", name}, cfg.Command...)...)
+	cmd.Stdin = strings.NewReader(code)
+	// one byte over the cap is kept so truncate marks the output
+	stdout, stderr := &cappedBuffer{max: cfg.MaxBytes + 1}, &cappedBuffer{max: cfg.MaxBytes + 1}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	err := cmd.Run()
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return "", fmt.Errorf("code did not finish within %s", cfg.Timeout)
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case err != nil:
+		return "", fmt.Errorf("sandbox: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "exit code %d\n", exitCode)
+	if stdout.Len() > 0 {
+		b.WriteString("\nstdout:\n" + truncate(strings.TrimRight(stdout.String(), "\n"), cfg.MaxBytes) + "\n")
+	}
+	if stderr.Len() > 0 {
+		b.WriteString("\nstderr:\n" + truncate(strings.TrimRight(stderr.String(), "\n"), cfg.MaxBytes) + "\n")
+	}
+	if cfg.ArtifactDir != "" {
+		if err := os.MkdirAll(cfg.ArtifactDir, 0o755); err != nil {
+			return "", err
+		}
+		dir, err := os.MkdirTemp(cfg.ArtifactDir, "run-")
+		if err != nil {
+			return "", err
+		}
+		outDir, err := filepath.Abs(dir)
+		if err != nil {
+			return "", err
+		}
+		if err := copyArtifacts(ctx, cfg, name, outDir); err != nil {
+			return "", err
+		}
+		artifacts, err := listArtifacts(outDir)
+		if err != nil {
+			return "", err
+		}
+		if len(artifacts) > 0 {
+			b.WriteString("\nartifacts:\n" + strings.Join(artifacts, "\n") + "\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the
+// rest, so a chatty program cannot exhaust memory.
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	if room := c.max - c.Len(); room > 0 {
+		c.Buffer.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// copyArtifacts extracts /output of the container into dir, read as a tar
+// stream. Only regular files and directories are kept, and no more than
+// MaxArtifactBytes.
+func copyArtifacts(ctx context.Context, cfg SandboxConfig, name, dir string) error {
+	cmd := exec.CommandContext(ctx, cfg.Docker, "exec", name, "tar", "-C", "/output", "-cf", "-", ".")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+	extractErr := extractArtifacts(tar.NewReader(out), dir, cfg.MaxArtifactBytes)
+	io.Copy(io.Discard, out)
+	if err := cmd.Wait(); err != nil && extractErr == nil {
+		return fmt.Errorf("sandbox: copying artifacts: %w", err)
+	}
+	return extractErr
+}
+
+func extractArtifacts(tr *tar.Reader, dir string, limit int64) error {
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("sandbox: reading artifacts: %w", err)
+		}
+		rel := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if rel == "." || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		target := filepath.Join(dir, rel)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if total += hdr.Size; total > limit {
+				return fmt.Errorf("sandbox: artifacts exceed %d bytes", limit)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+			if err != nil {
+				return err
+			}
+			_, err = io.CopyN(f, tr, hdr.Size)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// listArtifacts describes the files below dir as "- path (n bytes)".
+func listArtifacts(dir string) ([]string, error) {
+	var out []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		out = append(out, fmt.Sprintf("- %s (%d bytes)", path, info.Size()))
+		return nil
+	})
+	return out, err
+}
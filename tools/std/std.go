@@ -0,0 +1,79 @@
+// Package std provides ready-made tools for agent.Runner: an HTTP fetcher
+// restricted to allowed hosts, a web page reader, a calculator, the current
+// time, a JSON query, a file reader confined to a root directory and an
+// opt-in code sandbox.
+//
+//	runner.Tools = append(runner.Tools, std.Tools(std.Config{
+//		AllowedHosts: []string{"api.example.com"},
+//		Root:         "./docs",
+//	})...)
+package std
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/quailyquaily/uniai/agent"
+)
+
+// DefaultMaxBytes caps the output of the fetch and file tools.
+const DefaultMaxBytes = 64 << 10
+
+// Config selects and configures the tools returned by Tools.
+type Config struct {
+	// AllowedHosts enables the http_fetch tool for these hosts and their
+	// subdomains.
+	AllowedHosts []string
+	// Root enables the read_file tool for files below this directory.
+	Root string
+	// MaxBytes caps the output of http_fetch and read_file
+	// (default DefaultMaxBytes).
+	MaxBytes int
+	// WebFetch enables the web_fetch tool, which returns the readable text
+	// of web pages.
+	WebFetch *WebFetchConfig
+	// Sandbox enables the run_code tool, which executes model-written code
+	// in a Docker container. Leave nil unless you mean to allow that.
+	Sandbox *SandboxConfig
+}
+
+// Tools returns the calculator, current_time and json_query tools, plus
+// http_fetch, web_fetch, read_file and run_code when cfg enables them.
+func Tools(cfg Config) []agent.Tool {
+	tools := []agent.Tool{Calculator(), CurrentTime(), JSONQuery()}
+	if len(cfg.AllowedHosts) > 0 {
+		tools = append(tools, HTTPFetch(FetchConfig{AllowedHosts: cfg.AllowedHosts, MaxBytes: cfg.MaxBytes}))
+	}
+	if cfg.WebFetch != nil {
+		tools = append(tools, WebFetch(*cfg.WebFetch))
+	}
+	if cfg.Root != "" {
+		tools = append(tools, ReadFile(cfg.Root, cfg.MaxBytes))
+	}
+	if cfg.Sandbox != nil {
+		tools = append(tools, CodeInterpreter(*cfg.Sandbox))
+	}
+	return tools
+}
+
+// decodeArgs unmarshals the JSON arguments of a tool call into v.
+func decodeArgs(args string, v any) error {
+	if args == "" {
+		args = "{}"
+	}
+	if err := json.Unmarshal([]byte(args), v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// truncate cuts s to maxBytes, marking the cut.
+func truncate(s string, maxBytes int) string {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if len(s) <= maxBytes {
+		return s
+	}
+	return s[:maxBytes] + "\n[truncated]"
+}
@@ -0,0 +1,167 @@
+package std
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCalculator(t *testing.T) {
+	for expr, want := range map[string]float64{
+		"1 + 2 * 3":     7,
+		"(1 + 2) * 3":   9,
+		"-2 ^ 2":        -4,
+		"2 ^ 3 ^ 2":     512,
+		"7 % 4 + 1e-1":  3.1,
+		"10 / 4 - -0.5": 3,
+	} {
+		got, err := Eval(expr)
+		if err != nil || got != want {
+			t.Errorf("Eval(%q) = %v, %v; want %v", expr, got, err, want)
+		}
+	}
+	for _, expr := range []string{"1 +", "2 / 0", "(1", "1 2", "abs(1)"} {
+		if _, err := Eval(expr); err == nil {
+			t.Errorf("Eval(%q): expected error", expr)
+		}
+	}
+}
+
+func TestJSONQuery(t *testing.T) {
+	out, err := JSONQuery().Run(context.Background(), `{"json":"{\"items\":[{\"name\":\"a\"},{\"name\":\"b\"}]}","path":"items[1].name"}`)
+	if err != nil || out != `"b"` {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+}
+
+func TestReadFileStaysInRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool := ReadFile(root, 0)
+	if out, err := tool.Run(context.Background(), `{"path":"notes.txt"}`); err != nil || out != "hello" {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+	if _, err := tool.Run(context.Background(), `{"path":"../../etc/passwd"}`); err == nil {
+		t.Fatalf("expected an error for a path outside the root")
+	}
+}
+
+func TestHTTPFetchAllowlist(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	}))
+	defer srv.Close()
+
+	tool := HTTPFetch(FetchConfig{AllowedHosts: []string{"127.0.0.1"}})
+	out, err := tool.Run(context.Background(), `{"url":"`+srv.URL+`"}`)
+	if err != nil || !strings.HasSuffix(out, "pong") {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+	denied := HTTPFetch(FetchConfig{AllowedHosts: []string{"example.com"}})
+	if _, err := denied.Run(context.Background(), `{"url":"`+srv.URL+`"}`); err == nil {
+		t.Fatalf("expected the host to be rejected")
+	}
+}
+
+func TestCodeInterpreter(t *testing.T) {
+	// a stand-in for docker that records the arguments of run, runs the
+	// code of exec with sh and serves out/ as the container's /output
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	if err := os.MkdirAll(filepath.Join(out, "plots"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "plots", "a.txt"), []byte("plot"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	docker := filepath.Join(dir, "docker")
+	script := `#!/bin/sh
+case "$1" in
+run) echo "$@" > ` + filepath.Join(dir, "args") + ` ;;
+exec) [ "$3" = tar ] && exec tar -C ` + out + ` -cf - . ; sh ;;
+esac
+`
+	if err := os.WriteFile(docker, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	artifacts := filepath.Join(dir, "artifacts")
+	tool := CodeInterpreter(SandboxConfig{Docker: docker, Command: []string{"sh"}, MaxBytes: 10, ArtifactDir: artifacts})
+	res, err := tool.Run(context.Background(), `{"code":"echo hi; echo oops >&2; yes | head -c 100000 >&2; exit 3"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(res, "exit code 3\n\nstdout:\nhi\n\nstderr:\noops\ny\ny\ny\n[truncated]\n") || !strings.Contains(res, "plots/a.txt") {
+		t.Fatalf("unexpected output %q", res)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	for _, want := range []string{"--network none", "--memory 256m", "--read-only", "--tmpfs /output:size=16777216", "python:3.12-alpine 31"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("docker arguments %q lack %q", args, want)
+		}
+	}
+
+	// a run whose artifacts are larger than the cap fails
+	if err := os.WriteFile(filepath.Join(out, "big"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool = CodeInterpreter(SandboxConfig{Docker: docker, Command: []string{"sh"}, ArtifactDir: artifacts, MaxArtifactBytes: 1024})
+	if _, err := tool.Run(context.Background(), `{"code":"true"}`); err == nil || !strings.Contains(err.Error(), "artifacts exceed") {
+		t.Fatalf("expected artifact size error, got %v", err)
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	page := `<html><head><title>Tides</title><script>var x = 1;</script></head><body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<div class="sidebar">Popular posts, trending, more links to click</div>
+<div class="post-content">
+<h2>How tides work</h2>
+<p>Tides are caused by the gravity of the moon and, to a lesser extent, the sun.</p>
+<ul><li>High tide</li><li>Low tide</li></ul>
+<p>Most coasts see two high tides a day, roughly twelve hours apart.</p>
+</div>
+<footer>Copyright, all rights reserved, contact us</footer>
+</body></html>`
+	title, text, err := ExtractText(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## How tides work\n\nTides are caused by the gravity of the moon and, to a lesser extent, the sun.\n\n- High tide\n- Low tide\n\nMost coasts see two high tides a day, roughly twelve hours apart."
+	if title != "Tides" || text != want {
+		t.Fatalf("unexpected extraction %q:\n%s", title, text)
+	}
+}
+
+func TestWebFetchCachesAndTruncates(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("word ", 100)))
+	}))
+	defer srv.Close()
+
+	tool := WebFetch(WebFetchConfig{AllowedHosts: []string{"127.0.0.1"}, MaxTokens: 10})
+	for range 2 {
+		out, err := tool.Run(context.Background(), `{"url":"`+srv.URL+`/page#top"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(out, "[truncated]") || len(out) > 60 {
+			t.Fatalf("unexpected result %q", out)
+		}
+	}
+	if hits != 1 {
+		t.Fatalf("expected one request, got %d", hits)
+	}
+
+	if _, err := WebFetch(WebFetchConfig{}).Run(context.Background(), `{"url":"`+srv.URL+`"}`); err == nil {
+		t.Fatalf("expected loopback addresses to be refused without an allowlist")
+	}
+}
@@ -0,0 +1,37 @@
+package std
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/quailyquaily/uniai/agent"
+)
+
+// CurrentTime returns the current_time tool. It reports the time in the
+// requested IANA time zone, or UTC.
+func CurrentTime() agent.Tool {
+	return agent.Tool{
+		Name:        "current_time",
+		Description: "Get the current date and time.",
+		Parameters:  []byte(`{"type":"object","properties":{"timezone":{"type":"string","description":"IANA time zone, such as Europe/Paris. Defaults to UTC."}}}`),
+		Run: func(ctx context.Context, args string) (string, error) {
+			var in struct {
+				Timezone string `json:"timezone"`
+			}
+			if err := decodeArgs(args, &in); err != nil {
+				return "", err
+			}
+			loc := time.UTC
+			if in.Timezone != "" {
+				l, err := time.LoadLocation(in.Timezone)
+				if err != nil {
+					return "", fmt.Errorf("unknown time zone %q", in.Timezone)
+				}
+				loc = l
+			}
+			now := time.Now().In(loc)
+			return now.Format(time.RFC3339) + " (" + now.Weekday().String() + ")", nil
+		},
+	}
+}
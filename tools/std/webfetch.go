@@ -0,0 +1,236 @@
+package std
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/quailyquaily/uniai/agent"
+	"github.com/quailyquaily/uniai/tokens"
+)
+
+// Web fetch defaults.
+const (
+	DefaultWebFetchTokens   = 4000
+	DefaultWebFetchCacheTTL = 15 * time.Minute
+	defaultWebFetchCache    = 128
+	maxPageBytes            = 5 << 20
+)
+
+// WebFetchConfig configures WebFetch.
+type WebFetchConfig struct {
+	// AllowedHosts limits fetching to these hosts and their subdomains.
+	// When empty any host may be fetched, except loopback, private and
+	// link-local addresses.
+	AllowedHosts []string
+	// MaxTokens is the token budget of the returned text, estimated with
+	// tokens.Estimate (default DefaultWebFetchTokens).
+	MaxTokens int
+	// CacheTTL is how long extracted pages are reused
+	// (default DefaultWebFetchCacheTTL; negative disables the cache).
+	CacheTTL time.Duration
+	// CacheSize is the number of cached pages (default 128).
+	CacheSize int
+	// Client defaults to a client with a 30 second timeout.
+	Client *http.Client
+}
+
+// WebFetch returns the web_fetch tool. It downloads a page, extracts its
+// readable text with ExtractText, and cuts it to the token budget. Pages
+// are cached by URL.
+func WebFetch(cfg WebFetchConfig) agent.Tool {
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = DefaultWebFetchTokens
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = DefaultWebFetchCacheTTL
+	}
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = defaultWebFetchCache
+	}
+	f := &webFetcher{cfg: cfg, cache: map[string]cachedPage{}}
+	if len(cfg.AllowedHosts) > 0 {
+		f.client = allowlistClient(cfg.Client, cfg.AllowedHosts)
+	} else if cfg.Client != nil {
+		f.client = cfg.Client
+	} else {
+		f.client = publicClient()
+	}
+	desc := "Fetch a web page and return its title and main text, without navigation and other page chrome."
+	if len(cfg.AllowedHosts) > 0 {
+		desc += " Only these hosts are allowed: " + strings.Join(cfg.AllowedHosts, ", ") + "."
+	}
+	return agent.Tool{
+		Name:        "web_fetch",
+		Description: desc,
+		Parameters:  []byte(`{"type":"object","properties":{"url":{"type":"string"}},"required":["url"]}`),
+		Run: func(ctx context.Context, args string) (string, error) {
+			var in struct {
+				URL string `json:"url"`
+			}
+			if err := decodeArgs(args, &in); err != nil {
+				return "", err
+			}
+			return f.fetch(ctx, in.URL)
+		},
+	}
+}
+
+type cachedPage struct {
+	text    string
+	expires time.Time
+}
+
+type webFetcher struct {
+	cfg    WebFetchConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedPage
+}
+
+func (f *webFetcher) fetch(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid url %q", rawURL)
+	}
+	if len(f.cfg.AllowedHosts) > 0 && !hostAllowed(u, f.cfg.AllowedHosts) {
+		return "", fmt.Errorf("host %s is not allowed", u.Hostname())
+	}
+	u.Fragment = ""
+	key := u.String()
+	if text, ok := f.cached(key); ok {
+		return text, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("fetch %s: status %d", key, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml" || (mediaType == "" && looksLikeHTML(body)):
+		title, content, err := ExtractText(bytes.NewReader(body))
+		if err != nil {
+			return "", fmt.Errorf("parse %s: %w", key, err)
+		}
+		if title != "" {
+			text = "# " + title + "\n\n"
+		}
+		text += content
+	case strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml"):
+		text = string(body)
+	default:
+		return "", fmt.Errorf("fetch %s: unsupported content type %q", key, mediaType)
+	}
+	text = fitTokens(text, f.cfg.MaxTokens)
+	f.store(key, text)
+	return text, nil
+}
+
+func (f *webFetcher) cached(key string) (string, bool) {
+	if f.cfg.CacheTTL < 0 {
+		return "", false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.cache[key]
+	if !ok || time.Now().After(p.expires) {
+		return "", false
+	}
+	return p.text, true
+}
+
+func (f *webFetcher) store(key, text string) {
+	if f.cfg.CacheTTL < 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	if len(f.cache) >= f.cfg.CacheSize {
+		// drop expired pages, then the one expiring first
+		oldest := ""
+		for k, p := range f.cache {
+			if now.After(p.expires) {
+				delete(f.cache, k)
+			} else if oldest == "" || p.expires.Before(f.cache[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(f.cache) >= f.cfg.CacheSize && oldest != "" {
+			delete(f.cache, oldest)
+		}
+	}
+	f.cache[key] = cachedPage{text: text, expires: now.Add(f.cfg.CacheTTL)}
+}
+
+// fitTokens cuts text to about maxTokens tokens, at a paragraph or line
+// break when one is near the end.
+func fitTokens(text string, maxTokens int) string {
+	if tokens.Estimate(text) <= maxTokens {
+		return text
+	}
+	cut := maxTokens * 4
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	head := text[:cut]
+	if i := strings.LastIndex(head, "\n"); i > cut*3/4 {
+		head = head[:i]
+	}
+	return strings.TrimRight(head, " \n") + "\n[truncated]"
+}
+
+func looksLikeHTML(body []byte) bool {
+	head := strings.ToLower(string(body[:min(len(body), 512)]))
+	return strings.Contains(head, "<html") || strings.Contains(head, "<!doctype html")
+}
+
+// publicClient returns a client that refuses to connect to loopback,
+// private and link-local addresses, including after redirects.
+func publicClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+				return fmt.Errorf("address %s is not public", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 30 * time.Second, Transport: transport}
+}
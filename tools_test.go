@@ -0,0 +1,39 @@
+package uniai
+
+import (
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestToolRegistry(t *testing.T) {
+	client := New(Config{})
+	client.RegisterTool(chat.FunctionTool("get_weather", "", nil), "public")
+	client.RegisterTool(chat.FunctionTool("read_customer", "", nil), "crm/read")
+	client.RegisterTool(chat.FunctionTool("delete_customer", "", nil), "crm/write")
+
+	names := func(tools []chat.Tool) []string {
+		var out []string
+		for _, tool := range tools {
+			out = append(out, tool.Function.Name)
+		}
+		return out
+	}
+	if got := names(client.RegisteredTools("crm")); len(got) != 2 {
+		t.Fatalf("expected the crm namespace, got %v", got)
+	}
+	if got := names(client.RegisteredTools("crm/read", "public")); len(got) != 2 || got[0] != "get_weather" {
+		t.Fatalf("unexpected selection %v", got)
+	}
+
+	own := chat.FunctionTool("get_weather", "the request's own definition", nil)
+	tools := client.withRegisteredTools([]chat.Tool{own}, []string{"public", "crm/read"})
+	if got := names(tools); len(got) != 2 || tools[0].Function.Description != own.Function.Description {
+		t.Fatalf("unexpected request tools %v", got)
+	}
+
+	client.UnregisterTool("read_customer")
+	if got := client.RegisteredTools("crm"); len(got) != 1 {
+		t.Fatalf("expected one crm tool after unregistering, got %v", names(got))
+	}
+}
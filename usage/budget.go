@@ -0,0 +1,103 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Limit caps the usage of a tenant per budget period. Zero fields are
+// unlimited.
+type Limit struct {
+	Tokens int64   `json:"tokens,omitempty"`
+	Cost   float64 `json:"cost,omitempty"`
+}
+
+// Budget is a Recorder that tracks the usage of each tenant in the current
+// period and reports how much of its limit is spent. Periods are aligned to
+// UTC, so the default of 24 hours resets at midnight UTC.
+type Budget struct {
+	// Limit applies to tenants without an entry in Tenants.
+	Limit   Limit
+	Tenants map[string]Limit
+	// Period defaults to 24 hours.
+	Period time.Duration
+	// Price computes the cost of each call; Cost limits need it.
+	Price PriceFunc
+
+	mu     sync.Mutex
+	window time.Time
+	spent  map[string]Row
+	now    func() time.Time
+}
+
+func NewBudget(limit Limit, price PriceFunc) *Budget {
+	return &Budget{Limit: limit, Price: price, now: time.Now}
+}
+
+func (b *Budget) RecordUsage(ctx context.Context, provider, model string, u chat.Usage) {
+	cost := 0.0
+	if b.Price != nil {
+		cost = b.Price(provider, model, u)
+	}
+	tenant := TenantFrom(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll()
+	row := b.spent[tenant]
+	row.Tenant = tenant
+	row.Requests++
+	row.InputTokens += int64(u.InputTokens)
+	row.OutputTokens += int64(u.OutputTokens)
+	row.TotalTokens += int64(u.TotalTokens)
+	row.Cost += cost
+	b.spent[tenant] = row
+}
+
+// Spent returns the usage of tenant in the current period.
+func (b *Budget) Spent(tenant string) Row {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll()
+	row := b.spent[tenant]
+	row.Tenant = tenant
+	return row
+}
+
+// Used returns the fraction of its limit that the tenant of ctx has spent
+// in the current period: the larger of the token and cost fractions, 0
+// without limits, and 1 or more once the budget is exhausted.
+func (b *Budget) Used(ctx context.Context) float64 {
+	tenant := TenantFrom(ctx)
+	limit, ok := b.Tenants[tenant]
+	if !ok {
+		limit = b.Limit
+	}
+	spent := b.Spent(tenant)
+	used := 0.0
+	if limit.Tokens > 0 {
+		used = float64(spent.TotalTokens) / float64(limit.Tokens)
+	}
+	if limit.Cost > 0 {
+		used = max(used, spent.Cost/limit.Cost)
+	}
+	return used
+}
+
+// roll starts a new period when the current one is over. b.mu must be held.
+func (b *Budget) roll() {
+	if b.now == nil {
+		b.now = time.Now
+	}
+	period := b.Period
+	if period <= 0 {
+		period = 24 * time.Hour
+	}
+	window := b.now().UTC().Truncate(period)
+	if b.spent == nil || !window.Equal(b.window) {
+		b.window = window
+		b.spent = map[string]Row{}
+	}
+}
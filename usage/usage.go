@@ -0,0 +1,175 @@
+// Package usage aggregates token usage and cost by provider, model, tenant
+// and day.
+package usage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// Recorder receives the usage of every completed call.
+type Recorder interface {
+	RecordUsage(ctx context.Context, provider, model string, usage chat.Usage)
+}
+
+type tenantKey struct{}
+
+// WithTenant tags ctx so usage recorded for calls made with it is attributed
+// to tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant set by WithTenant, or "".
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// PriceFunc returns the cost of usage for provider and model.
+type PriceFunc func(provider, model string, usage chat.Usage) float64
+
+const (
+	ByProvider = "provider"
+	ByModel    = "model"
+	ByTenant   = "tenant"
+	ByDay      = "day"
+)
+
+// Row is one aggregated group. Fields not grouped by are empty.
+type Row struct {
+	Day          string  `json:"day,omitempty"`
+	Provider     string  `json:"provider,omitempty"`
+	Model        string  `json:"model,omitempty"`
+	Tenant       string  `json:"tenant,omitempty"`
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	Cost         float64 `json:"cost,omitempty"`
+}
+
+// Query filters and groups a report. Zero values match everything; From is
+// inclusive and To exclusive, compared by UTC day.
+type Query struct {
+	From    time.Time
+	To      time.Time
+	Tenant  string
+	GroupBy []string
+}
+
+// Aggregator is an in-memory Recorder that keeps daily rollups.
+type Aggregator struct {
+	// Price, if set, computes the cost of each call.
+	Price PriceFunc
+
+	mu   sync.Mutex
+	rows map[Row]*Row
+	now  func() time.Time
+}
+
+func NewAggregator(price PriceFunc) *Aggregator {
+	return &Aggregator{Price: price, rows: map[Row]*Row{}, now: time.Now}
+}
+
+func (a *Aggregator) RecordUsage(ctx context.Context, provider, model string, u chat.Usage) {
+	key := Row{
+		Day:      a.now().UTC().Format(time.DateOnly),
+		Provider: provider,
+		Model:    model,
+		Tenant:   TenantFrom(ctx),
+	}
+	cost := 0.0
+	if a.Price != nil {
+		cost = a.Price(provider, model, u)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	row := a.rows[key]
+	if row == nil {
+		r := key
+		row = &r
+		a.rows[key] = row
+	}
+	row.Requests++
+	row.InputTokens += int64(u.InputTokens)
+	row.OutputTokens += int64(u.OutputTokens)
+	row.TotalTokens += int64(u.TotalTokens)
+	row.Cost += cost
+}
+
+// Report returns the rows matching q grouped by q.GroupBy, sorted by group.
+// Without GroupBy a single total row is returned.
+func (a *Aggregator) Report(q Query) []Row {
+	group := map[string]bool{}
+	for _, g := range q.GroupBy {
+		group[strings.ToLower(strings.TrimSpace(g))] = true
+	}
+	from, to := "", ""
+	if !q.From.IsZero() {
+		from = q.From.UTC().Format(time.DateOnly)
+	}
+	if !q.To.IsZero() {
+		to = q.To.UTC().Format(time.DateOnly)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := map[Row]*Row{}
+	for key, row := range a.rows {
+		if (from != "" && key.Day < from) || (to != "" && key.Day >= to) {
+			continue
+		}
+		if q.Tenant != "" && key.Tenant != q.Tenant {
+			continue
+		}
+		var g Row
+		if group[ByDay] {
+			g.Day = key.Day
+		}
+		if group[ByProvider] {
+			g.Provider = key.Provider
+		}
+		if group[ByModel] {
+			g.Model = key.Model
+		}
+		if group[ByTenant] {
+			g.Tenant = key.Tenant
+		}
+		agg := out[g]
+		if agg == nil {
+			r := g
+			agg = &r
+			out[g] = agg
+		}
+		agg.Requests += row.Requests
+		agg.InputTokens += row.InputTokens
+		agg.OutputTokens += row.OutputTokens
+		agg.TotalTokens += row.TotalTokens
+		agg.Cost += row.Cost
+	}
+
+	rows := make([]Row, 0, len(out))
+	for _, r := range out {
+		rows = append(rows, *r)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Model < b.Model
+	})
+	return rows
+}
@@ -0,0 +1,36 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestAggregatorReport(t *testing.T) {
+	agg := NewAggregator(func(provider, model string, u chat.Usage) float64 {
+		return float64(u.TotalTokens) / 1000
+	})
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	agg.now = func() time.Time { return day }
+
+	acme := WithTenant(context.Background(), "acme")
+	agg.RecordUsage(acme, "openai", "gpt-4o", chat.Usage{InputTokens: 100, OutputTokens: 900, TotalTokens: 1000})
+	agg.RecordUsage(acme, "anthropic", "claude", chat.Usage{TotalTokens: 2000})
+	agg.now = func() time.Time { return day.AddDate(0, 0, 1) }
+	agg.RecordUsage(context.Background(), "openai", "gpt-4o", chat.Usage{TotalTokens: 500})
+
+	total := agg.Report(Query{})
+	if len(total) != 1 || total[0].TotalTokens != 3500 || total[0].Requests != 3 || total[0].Cost != 3.5 {
+		t.Fatalf("unexpected total: %+v", total)
+	}
+	byProvider := agg.Report(Query{GroupBy: []string{ByProvider}, Tenant: "acme"})
+	if len(byProvider) != 2 || byProvider[0].Provider != "anthropic" || byProvider[1].TotalTokens != 1000 {
+		t.Fatalf("unexpected provider report: %+v", byProvider)
+	}
+	firstDay := agg.Report(Query{GroupBy: []string{ByDay}, To: day.AddDate(0, 0, 1)})
+	if len(firstDay) != 1 || firstDay[0].Day != "2025-03-01" || firstDay[0].TotalTokens != 3000 {
+		t.Fatalf("unexpected day report: %+v", firstDay)
+	}
+}
@@ -0,0 +1,165 @@
+// Package vecmath provides the basic vector operations needed to work with
+// embeddings: similarity, normalization, top-k selection and MMR
+// diversification.
+package vecmath
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// DecodeBase64 decodes a base64 embedding of little-endian float32 values, the
+// format returned in embedding.Item.Embedding.
+func DecodeBase64(s string) ([]float32, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid embedding length %d", len(data))
+	}
+	out := make([]float32, len(data)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return out, nil
+}
+
+// Dot returns the dot product of a and b. Extra elements of the longer vector
+// are ignored.
+func Dot(a, b []float32) float64 {
+	n := min(len(a), len(b))
+	var sum float64
+	for i := 0; i < n; i++ {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// Norm returns the Euclidean length of v.
+func Norm(v []float32) float64 {
+	return math.Sqrt(Dot(v, v))
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if either is zero.
+func Cosine(a, b []float32) float64 {
+	na, nb := Norm(a), Norm(b)
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return Dot(a, b) / (na * nb)
+}
+
+// Normalize returns a unit-length copy of v. A zero vector is returned as is.
+func Normalize(v []float32) []float32 {
+	out := make([]float32, len(v))
+	n := Norm(v)
+	if n == 0 {
+		copy(out, v)
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(float64(x) / n)
+	}
+	return out
+}
+
+// Match is a scored candidate returned by TopK and MMR.
+type Match struct {
+	Index int
+	Score float64
+}
+
+type matchHeap []Match
+
+func (h matchHeap) Len() int           { return len(h) }
+func (h matchHeap) Less(i, j int) bool { return h[i].Score < h[j].Score }
+func (h matchHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *matchHeap) Push(x any)        { *h = append(*h, x.(Match)) }
+func (h *matchHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// TopK returns the k candidates most similar to query by cosine similarity,
+// best first. It keeps a size-k min-heap, so it runs in O(n log k).
+func TopK(query []float32, candidates [][]float32, k int) []Match {
+	if k <= 0 {
+		return nil
+	}
+	h := make(matchHeap, 0, k)
+	for i, c := range candidates {
+		score := Cosine(query, c)
+		if h.Len() < k {
+			heap.Push(&h, Match{Index: i, Score: score})
+		} else if score > h[0].Score {
+			h[0] = Match{Index: i, Score: score}
+			heap.Fix(&h, 0)
+		}
+	}
+	out := make([]Match, h.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(&h).(Match)
+	}
+	return out
+}
+
+// MMR selects k candidates by maximal marginal relevance: each pick maximizes
+// lambda*sim(query, c) - (1-lambda)*max sim(c, picked). lambda = 1 is plain
+// relevance ranking; lower values favor diversity. Scores in the result are
+// the query similarities. Candidates whose score is NaN, e.g. vectors with
+// NaN components, are never picked, so fewer than k may be returned.
+func MMR(query []float32, candidates [][]float32, k int, lambda float64) []Match {
+	if k > len(candidates) {
+		k = len(candidates)
+	}
+	if k <= 0 {
+		return nil
+	}
+	relevance := make([]float64, len(candidates))
+	for i, c := range candidates {
+		relevance[i] = Cosine(query, c)
+	}
+	// maxSim[i] is the highest similarity of candidate i to any picked one.
+	maxSim := make([]float64, len(candidates))
+	for i := range maxSim {
+		maxSim[i] = math.Inf(-1)
+	}
+	picked := make([]bool, len(candidates))
+	out := make([]Match, 0, k)
+	for len(out) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range candidates {
+			if picked[i] {
+				continue
+			}
+			penalty := 0.0
+			if len(out) > 0 {
+				penalty = maxSim[i]
+			}
+			score := lambda*relevance[i] - (1-lambda)*penalty
+			if math.IsNaN(score) {
+				continue
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		picked[best] = true
+		out = append(out, Match{Index: best, Score: relevance[best]})
+		for i := range candidates {
+			if !picked[i] {
+				maxSim[i] = math.Max(maxSim[i], Cosine(candidates[i], candidates[best]))
+			}
+		}
+	}
+	return out
+}
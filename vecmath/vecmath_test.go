@@ -0,0 +1,47 @@
+package vecmath
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestTopKAndMMR(t *testing.T) {
+	query := []float32{1, 0}
+	candidates := [][]float32{
+		{0, 1},
+		{1, 0.1},
+		{1, 0.11},
+		{0.7, 0.7},
+	}
+	top := TopK(query, candidates, 2)
+	if len(top) != 2 || top[0].Index != 1 || top[1].Index != 2 {
+		t.Fatalf("unexpected top-k: %+v", top)
+	}
+	diverse := MMR(query, candidates, 2, 0.3)
+	if len(diverse) != 2 || diverse[0].Index != 1 || diverse[1].Index == 2 {
+		t.Fatalf("expected MMR to skip the near-duplicate: %+v", diverse)
+	}
+}
+
+func TestMMRSkipsNaN(t *testing.T) {
+	nan := float32(math.NaN())
+	got := MMR([]float32{1, 0}, [][]float32{{nan, 1}, {1, 0}, {1, nan}}, 3, 0.5)
+	if len(got) != 1 || got[0].Index != 1 {
+		t.Fatalf("expected only the valid candidate: %+v", got)
+	}
+}
+
+func TestDecodeBase64(t *testing.T) {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint32(buf, math.Float32bits(0.5))
+	binary.LittleEndian.PutUint32(buf[4:], math.Float32bits(-2))
+	v, err := DecodeBase64(base64.StdEncoding.EncodeToString(buf))
+	if err != nil || len(v) != 2 || v[0] != 0.5 || v[1] != -2 {
+		t.Fatalf("unexpected decode: %v %v", v, err)
+	}
+	if n := Normalize([]float32{3, 4}); math.Abs(float64(n[0])-0.6) > 1e-6 {
+		t.Fatalf("unexpected normalize: %v", n)
+	}
+}
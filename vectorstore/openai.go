@@ -0,0 +1,203 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quailyquaily/uniai/files"
+)
+
+const defaultOpenAIAPIBase = "https://api.openai.com/v1"
+
+// idAttribute holds the record ID among the attributes of a vector store
+// file.
+const idAttribute = "uniai_id"
+
+type OpenAIConfig struct {
+	APIKey  string
+	APIBase string
+	// VectorStoreID is the store to use, e.g. "vs_abc123".
+	VectorStoreID string
+	// ChunkTokens and ChunkOverlap set the static chunking strategy of
+	// uploaded files; zero uses OpenAI's default (800 and 400).
+	ChunkTokens  int
+	ChunkOverlap int
+}
+
+// OpenAI stores records in an OpenAI vector store. OpenAI embeds and
+// chunks the content itself: each record becomes a file holding Text, with
+// Metadata as its attributes, and queries search by Query.Text. Vectors
+// are ignored. Files are indexed asynchronously, so a record may take a
+// moment to show up in results. Delete lists the files of the store to
+// find the records, which is slow on large stores.
+type OpenAI struct {
+	cfg   OpenAIConfig
+	files *files.Client
+}
+
+var (
+	_ Store         = (*OpenAI)(nil)
+	_ Fingerprinter = (*OpenAI)(nil)
+)
+
+func NewOpenAI(cfg OpenAIConfig) *OpenAI {
+	return &OpenAI{
+		cfg:   cfg,
+		files: files.New(files.Config{Provider: "openai", OpenAIAPIKey: cfg.APIKey, OpenAIAPIBase: cfg.APIBase}),
+	}
+}
+
+func (o *OpenAI) Upsert(ctx context.Context, records ...Record) error {
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		if r.ID == "" || r.Text == "" {
+			return fmt.Errorf("openai vector store records need an id and text")
+		}
+		ids = append(ids, r.ID)
+	}
+	if err := o.Delete(ctx, ids...); err != nil {
+		return err
+	}
+	for _, r := range records {
+		f, err := o.files.Upload(ctx, r.ID+".txt", []byte(r.Text), files.WithPurpose("assistants"), files.WithMIMEType("text/plain"))
+		if err != nil {
+			return err
+		}
+		attributes := map[string]any{}
+		for k, v := range r.Metadata {
+			attributes[k] = v
+		}
+		attributes[idAttribute] = r.ID
+		body := map[string]any{"file_id": f.ID, "attributes": attributes}
+		if o.cfg.ChunkTokens > 0 {
+			body["chunking_strategy"] = map[string]any{
+				"type": "static",
+				"static": map[string]any{
+					"max_chunk_size_tokens": o.cfg.ChunkTokens,
+					"chunk_overlap_tokens":  o.cfg.ChunkOverlap,
+				},
+			}
+		}
+		if err := o.do(ctx, http.MethodPost, "/files", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fingerprint reports the chunking settings, so Sync re-uploads files
+// when they change.
+func (o *OpenAI) Fingerprint() string {
+	return fmt.Sprintf("openai:%d:%d", o.cfg.ChunkTokens, o.cfg.ChunkOverlap)
+}
+
+func (o *OpenAI) Query(ctx context.Context, q Query) ([]Match, error) {
+	if q.Text == "" {
+		return nil, fmt.Errorf("openai vector stores search by text; set Query.Text")
+	}
+	body := map[string]any{"query": q.Text, "max_num_results": min(topK(q), 50)}
+	var filters []map[string]any
+	for k, v := range q.Filter {
+		filters = append(filters, map[string]any{"type": "eq", "key": k, "value": v})
+	}
+	switch len(filters) {
+	case 0:
+	case 1:
+		body["filters"] = filters[0]
+	default:
+		body["filters"] = map[string]any{"type": "and", "filters": filters}
+	}
+	var out struct {
+		Data []struct {
+			FileID     string         `json:"file_id"`
+			Score      float64        `json:"score"`
+			Attributes map[string]any `json:"attributes"`
+			Content    []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"data"`
+	}
+	if err := o.do(ctx, http.MethodPost, "/search", body, &out); err != nil {
+		return nil, err
+	}
+	matches := make([]Match, len(out.Data))
+	for i, d := range out.Data {
+		id, _ := d.Attributes[idAttribute].(string)
+		if id == "" {
+			id = d.FileID
+		}
+		delete(d.Attributes, idAttribute)
+		var texts []string
+		for _, c := range d.Content {
+			if c.Type == "text" {
+				texts = append(texts, c.Text)
+			}
+		}
+		matches[i] = Match{Record: Record{ID: id, Text: strings.Join(texts, "\n"), Metadata: d.Attributes}, Score: d.Score}
+	}
+	return matches, nil
+}
+
+func (o *OpenAI) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	remove := map[string]bool{}
+	for _, id := range ids {
+		remove[id] = true
+	}
+	var fileIDs []string
+	query := url.Values{"limit": {"100"}}
+	for {
+		var page struct {
+			Data []struct {
+				ID         string         `json:"id"`
+				Attributes map[string]any `json:"attributes"`
+			} `json:"data"`
+			LastID  string `json:"last_id"`
+			HasMore bool   `json:"has_more"`
+		}
+		if err := o.do(ctx, http.MethodGet, "/files?"+query.Encode(), nil, &page); err != nil {
+			return err
+		}
+		for _, f := range page.Data {
+			if id, _ := f.Attributes[idAttribute].(string); remove[id] {
+				fileIDs = append(fileIDs, f.ID)
+			}
+		}
+		if !page.HasMore || page.LastID == "" {
+			break
+		}
+		query.Set("after", page.LastID)
+	}
+	for _, id := range fileIDs {
+		if err := o.do(ctx, http.MethodDelete, "/files/"+url.PathEscape(id), nil, nil); err != nil {
+			return err
+		}
+		if err := o.files.Delete(ctx, id, files.WithProvider("openai")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do calls path below the vector store.
+func (o *OpenAI) do(ctx context.Context, method, path string, body, out any) error {
+	if o.cfg.APIKey == "" || o.cfg.VectorStoreID == "" {
+		return fmt.Errorf("openai api key and vector store id are required")
+	}
+	base := o.cfg.APIBase
+	if base == "" {
+		base = defaultOpenAIAPIBase
+	}
+	endpoint := strings.TrimRight(base, "/") + "/vector_stores/" + url.PathEscape(o.cfg.VectorStoreID) + path
+	header := http.Header{
+		"Authorization": {"Bearer " + o.cfg.APIKey},
+		"Openai-Beta":   {"assistants=v2"},
+	}
+	return doJSON(ctx, "openai vector store", method, endpoint, header, body, out)
+}
@@ -0,0 +1,118 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type PineconeConfig struct {
+	APIKey string
+	// Host is the data plane host of the index, as shown in the console,
+	// e.g. "https://docs-abc123.svc.us-east-1.pinecone.io".
+	Host      string
+	Namespace string
+}
+
+// Pinecone stores records in a Pinecone index. Text is kept in the
+// metadata field "text".
+type Pinecone struct {
+	cfg PineconeConfig
+}
+
+var _ Store = (*Pinecone)(nil)
+
+func NewPinecone(cfg PineconeConfig) *Pinecone {
+	return &Pinecone{cfg: cfg}
+}
+
+type pineconeVector struct {
+	ID       string         `json:"id"`
+	Values   []float32      `json:"values,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+func (p *Pinecone) Upsert(ctx context.Context, records ...Record) error {
+	vectors := make([]pineconeVector, len(records))
+	for i, r := range records {
+		if len(r.Vector) == 0 {
+			return fmt.Errorf("record %s has no vector", r.ID)
+		}
+		metadata := map[string]any{}
+		for k, v := range r.Metadata {
+			metadata[k] = v
+		}
+		if r.Text != "" {
+			metadata["text"] = r.Text
+		}
+		vectors[i] = pineconeVector{ID: r.ID, Values: r.Vector, Metadata: metadata}
+	}
+	// Pinecone accepts at most 1000 vectors per request
+	for start := 0; start < len(vectors); start += 1000 {
+		end := min(start+1000, len(vectors))
+		body := map[string]any{"vectors": vectors[start:end], "namespace": p.cfg.Namespace}
+		if err := p.do(ctx, "/vectors/upsert", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Pinecone) Query(ctx context.Context, q Query) ([]Match, error) {
+	if len(q.Vector) == 0 {
+		return nil, fmt.Errorf("query vector is required")
+	}
+	body := map[string]any{
+		"vector":          q.Vector,
+		"topK":            topK(q),
+		"includeMetadata": true,
+		"namespace":       p.cfg.Namespace,
+	}
+	if len(q.Filter) > 0 {
+		filter := map[string]any{}
+		for k, v := range q.Filter {
+			filter[k] = map[string]any{"$eq": v}
+		}
+		body["filter"] = filter
+	}
+	var out struct {
+		Matches []struct {
+			ID       string         `json:"id"`
+			Score    float64        `json:"score"`
+			Metadata map[string]any `json:"metadata"`
+		} `json:"matches"`
+	}
+	if err := p.do(ctx, "/query", body, &out); err != nil {
+		return nil, err
+	}
+	matches := make([]Match, len(out.Matches))
+	for i, m := range out.Matches {
+		text, _ := m.Metadata["text"].(string)
+		delete(m.Metadata, "text")
+		matches[i] = Match{Record: Record{ID: m.ID, Text: text, Metadata: m.Metadata}, Score: m.Score}
+	}
+	return matches, nil
+}
+
+func (p *Pinecone) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return p.do(ctx, "/vectors/delete", map[string]any{"ids": ids, "namespace": p.cfg.Namespace}, nil)
+}
+
+func (p *Pinecone) do(ctx context.Context, path string, body, out any) error {
+	if p.cfg.APIKey == "" || p.cfg.Host == "" {
+		return fmt.Errorf("pinecone api key and index host are required")
+	}
+	host := strings.TrimRight(p.cfg.Host, "/")
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	header := http.Header{
+		"Api-Key":                {p.cfg.APIKey},
+		"X-Pinecone-Api-Version": {"2024-07"},
+	}
+	return doJSON(ctx, "pinecone", http.MethodPost, host+path, header, body, out)
+}
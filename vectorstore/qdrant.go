@@ -0,0 +1,117 @@
+package vectorstore
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type QdrantConfig struct {
+	// URL of the Qdrant server, e.g. "http://localhost:6333".
+	URL        string
+	APIKey     string
+	Collection string
+}
+
+// Qdrant stores records as points of a Qdrant collection. Qdrant only
+// accepts integers and UUIDs as point IDs, so each record ID is mapped to
+// a name-based UUID and kept in the payload field "id"; Text is kept in
+// "text".
+type Qdrant struct {
+	cfg QdrantConfig
+}
+
+var _ Store = (*Qdrant)(nil)
+
+func NewQdrant(cfg QdrantConfig) *Qdrant {
+	return &Qdrant{cfg: cfg}
+}
+
+func (q *Qdrant) Upsert(ctx context.Context, records ...Record) error {
+	points := make([]map[string]any, len(records))
+	for i, r := range records {
+		if len(r.Vector) == 0 {
+			return fmt.Errorf("record %s has no vector", r.ID)
+		}
+		payload := map[string]any{}
+		for k, v := range r.Metadata {
+			payload[k] = v
+		}
+		payload["id"] = r.ID
+		if r.Text != "" {
+			payload["text"] = r.Text
+		}
+		points[i] = map[string]any{"id": pointID(r.ID), "vector": r.Vector, "payload": payload}
+	}
+	return q.do(ctx, http.MethodPut, "/points?wait=true", map[string]any{"points": points}, nil)
+}
+
+func (q *Qdrant) Query(ctx context.Context, query Query) ([]Match, error) {
+	if len(query.Vector) == 0 {
+		return nil, fmt.Errorf("query vector is required")
+	}
+	body := map[string]any{
+		"vector":       query.Vector,
+		"limit":        topK(query),
+		"with_payload": true,
+	}
+	if len(query.Filter) > 0 {
+		var must []map[string]any
+		for k, v := range query.Filter {
+			must = append(must, map[string]any{"key": k, "match": map[string]any{"value": v}})
+		}
+		body["filter"] = map[string]any{"must": must}
+	}
+	var out struct {
+		Result []struct {
+			Score   float64        `json:"score"`
+			Payload map[string]any `json:"payload"`
+		} `json:"result"`
+	}
+	if err := q.do(ctx, http.MethodPost, "/points/search", body, &out); err != nil {
+		return nil, err
+	}
+	matches := make([]Match, len(out.Result))
+	for i, p := range out.Result {
+		id, _ := p.Payload["id"].(string)
+		text, _ := p.Payload["text"].(string)
+		delete(p.Payload, "id")
+		delete(p.Payload, "text")
+		matches[i] = Match{Record: Record{ID: id, Text: text, Metadata: p.Payload}, Score: p.Score}
+	}
+	return matches, nil
+}
+
+func (q *Qdrant) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	points := make([]string, len(ids))
+	for i, id := range ids {
+		points[i] = pointID(id)
+	}
+	return q.do(ctx, http.MethodPost, "/points/delete?wait=true", map[string]any{"points": points}, nil)
+}
+
+func (q *Qdrant) do(ctx context.Context, method, path string, body, out any) error {
+	if q.cfg.URL == "" || q.cfg.Collection == "" {
+		return fmt.Errorf("qdrant url and collection are required")
+	}
+	endpoint := strings.TrimRight(q.cfg.URL, "/") + "/collections/" + url.PathEscape(q.cfg.Collection) + path
+	header := http.Header{}
+	if q.cfg.APIKey != "" {
+		header.Set("api-key", q.cfg.APIKey)
+	}
+	return doJSON(ctx, "qdrant", method, endpoint, header, body, out)
+}
+
+// pointID derives a version 5 style UUID from a record ID.
+func pointID(id string) string {
+	sum := sha1.Sum([]byte("uniai:" + id))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
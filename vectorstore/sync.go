@@ -0,0 +1,294 @@
+package vectorstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/quailyquaily/uniai/embedding"
+	"github.com/quailyquaily/uniai/ingest"
+	"github.com/quailyquaily/uniai/vecmath"
+)
+
+// DefaultManifest is the file, in the synced directory, where Sync records
+// what it uploaded.
+const DefaultManifest = ".uniai-sync.json"
+
+// Embedder computes embeddings; *uniai.Client implements it.
+type Embedder interface {
+	Embedding(ctx context.Context, opts ...embedding.Option) (*embedding.Result, error)
+}
+
+// Fingerprinter is implemented by stores whose own settings, such as how
+// they chunk uploaded files, shape the records Sync creates. Sync records
+// the fingerprint in its manifest and re-syncs every file when it changes.
+type Fingerprinter interface {
+	Fingerprint() string
+}
+
+// SyncConfig configures Sync.
+type SyncConfig struct {
+	// Embedder computes the vectors of chunks. Leave it nil for stores that
+	// embed text themselves (openai): each file is then sent whole and the
+	// store chunks it, as set in OpenAIConfig.
+	Embedder Embedder
+	// EmbeddingOptions select the embedding model; the chunk texts are
+	// added as inputs.
+	EmbeddingOptions []embedding.Option
+	// Chunk splits files before embedding. Ignored without an Embedder.
+	Chunk ingest.ChunkConfig
+	// OCR extracts the text of images and PDFs; without it they are
+	// skipped.
+	OCR ingest.OCR
+	// Extensions limits the files synced, e.g. []string{".md", ".txt"}.
+	// Empty syncs every file ingest can read.
+	Extensions []string
+	// Manifest is the path of the sync state (default DefaultManifest in
+	// the directory).
+	Manifest string
+}
+
+// SyncResult lists the files Sync changed, by path relative to the
+// directory.
+type SyncResult struct {
+	Added     []string `json:"added,omitempty"`
+	Updated   []string `json:"updated,omitempty"`
+	Deleted   []string `json:"deleted,omitempty"`
+	Unchanged int      `json:"unchanged"`
+	// Skipped maps files that could not be read or ingested to the reason.
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+type manifest struct {
+	// Settings fingerprints the chunking settings, local and of the store;
+	// a change re-syncs every file.
+	Settings string                   `json:"settings"`
+	Files    map[string]manifestEntry `json:"files"`
+}
+
+type manifestEntry struct {
+	Hash string   `json:"hash"`
+	IDs  []string `json:"ids"`
+}
+
+// Sync makes store mirror the files below dir. Files are compared with
+// the previous sync by SHA-256, so only new and changed files are uploaded,
+// and the records of changed and removed files are deleted. Hidden files
+// and directories are ignored. Record IDs are the file path, plus "#n" for
+// each chunk when files are chunked locally; the metadata holds "path", and
+// "chunk" and "page" for chunks.
+//
+// The manifest is written even when Sync fails, so a later call resumes
+// where it stopped.
+func Sync(ctx context.Context, store Store, dir string, cfg SyncConfig) (res *SyncResult, err error) {
+	manifestPath := cfg.Manifest
+	if manifestPath == "" {
+		manifestPath = filepath.Join(dir, DefaultManifest)
+	}
+	state, err := readManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	settings := "whole"
+	if cfg.Embedder != nil {
+		settings = fmt.Sprintf("chunk:%d:%d", cfg.Chunk.MaxTokens, cfg.Chunk.Overlap)
+	}
+	if f, ok := store.(Fingerprinter); ok {
+		settings += "|" + f.Fingerprint()
+	}
+	if state.Settings != settings {
+		// make every file look changed
+		for path, entry := range state.Files {
+			entry.Hash = ""
+			state.Files[path] = entry
+		}
+		state.Settings = settings
+	}
+	defer func() {
+		if werr := writeManifest(manifestPath, state); werr != nil && err == nil {
+			err = werr
+		}
+	}()
+
+	res = &SyncResult{Skipped: map[string]string{}}
+	seen := map[string]bool{}
+	ingester := ingest.New(ingest.Config{OCR: cfg.OCR})
+	walkErr := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() || !syncedExtension(path, cfg.Extensions) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			res.Skipped[rel] = err.Error()
+			return nil
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		old, exists := state.Files[rel]
+		if exists && old.Hash == hash {
+			res.Unchanged++
+			return nil
+		}
+		doc, err := ingester.File(ctx, rel, data)
+		if err != nil {
+			res.Skipped[rel] = err.Error()
+			return nil
+		}
+		records, err := syncRecords(ctx, rel, doc, cfg)
+		if err != nil {
+			return err
+		}
+		if exists {
+			if err := store.Delete(ctx, old.IDs...); err != nil {
+				return fmt.Errorf("delete %s: %w", rel, err)
+			}
+			delete(state.Files, rel)
+		}
+		if err := store.Upsert(ctx, records...); err != nil {
+			return fmt.Errorf("upload %s: %w", rel, err)
+		}
+		ids := make([]string, len(records))
+		for i, r := range records {
+			ids[i] = r.ID
+		}
+		state.Files[rel] = manifestEntry{Hash: hash, IDs: ids}
+		if exists {
+			res.Updated = append(res.Updated, rel)
+		} else {
+			res.Added = append(res.Added, rel)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return res, walkErr
+	}
+
+	var removed []string
+	for rel := range state.Files {
+		if !seen[rel] {
+			removed = append(removed, rel)
+		}
+	}
+	slices.Sort(removed)
+	for _, rel := range removed {
+		if err := store.Delete(ctx, state.Files[rel].IDs...); err != nil {
+			return res, fmt.Errorf("delete %s: %w", rel, err)
+		}
+		delete(state.Files, rel)
+		res.Deleted = append(res.Deleted, rel)
+	}
+	return res, nil
+}
+
+// syncRecords turns a document into records: one per chunk with an
+// embedder, or the whole text otherwise.
+func syncRecords(ctx context.Context, rel string, doc *ingest.Document, cfg SyncConfig) ([]Record, error) {
+	if cfg.Embedder == nil {
+		return []Record{{ID: rel, Text: doc.Text(), Metadata: map[string]any{"path": rel}}}, nil
+	}
+	chunks := doc.Chunks(cfg.Chunk)
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	inputs := make([]embedding.Input, len(chunks))
+	for i, c := range chunks {
+		inputs[i] = embedding.Input{Text: c.Text}
+	}
+	opts := append(slices.Clone(cfg.EmbeddingOptions), embedding.WithInputs(inputs...))
+	out, err := cfg.Embedder.Embedding(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("embed %s: %w", rel, err)
+	}
+	if out == nil || len(out.Data) != len(chunks) {
+		return nil, fmt.Errorf("embed %s: expected %d embeddings", rel, len(chunks))
+	}
+	records := make([]Record, len(chunks))
+	for _, item := range out.Data {
+		if item.Index < 0 || item.Index >= len(chunks) {
+			return nil, fmt.Errorf("embed %s: embedding index %d out of range", rel, item.Index)
+		}
+		vec, err := vecmath.DecodeBase64(item.Embedding)
+		if err != nil {
+			return nil, fmt.Errorf("embed %s: %w", rel, err)
+		}
+		c := chunks[item.Index]
+		records[item.Index] = Record{
+			ID:       rel + "#" + strconv.Itoa(c.Index),
+			Vector:   vec,
+			Text:     c.Text,
+			Metadata: map[string]any{"path": rel, "chunk": c.Index, "page": c.Page},
+		}
+	}
+	return records, nil
+}
+
+func syncedExtension(path string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range extensions {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func readManifest(path string) (*manifest, error) {
+	state := &manifest{Files: map[string]manifestEntry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("read sync manifest %s: %w", path, err)
+	}
+	if state.Files == nil {
+		state.Files = map[string]manifestEntry{}
+	}
+	return state, nil
+}
+
+// writeManifest replaces the manifest atomically.
+func writeManifest(path string, state *manifest) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,103 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/quailyquaily/uniai/embedding"
+	"github.com/quailyquaily/uniai/ingest"
+)
+
+// keywordEmbedder embeds a text as counts of a few keywords.
+type keywordEmbedder struct{ words []string }
+
+func (e keywordEmbedder) Embedding(ctx context.Context, opts ...embedding.Option) (*embedding.Result, error) {
+	req := embedding.BuildRequest(opts...)
+	res := &embedding.Result{}
+	for i, in := range req.Input {
+		buf := make([]byte, 4*len(e.words))
+		for j, w := range e.words {
+			n := float32(strings.Count(strings.ToLower(in.Text), w)) + 0.01
+			binary.LittleEndian.PutUint32(buf[4*j:], math.Float32bits(n))
+		}
+		res.Data = append(res.Data, embedding.Item{Embedding: base64.StdEncoding.EncodeToString(buf), Index: i})
+	}
+	return res, nil
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	write := func(name, text string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("cats.md", "Cats purr. Cats sleep a lot.")
+	write("guides/dogs.txt", "Dogs bark.")
+	write(".hidden.md", "ignored")
+	write("logo.png", "\x89PNG\r\n\x1a\n")
+
+	store := NewMemory()
+	cfg := SyncConfig{Embedder: keywordEmbedder{words: []string{"cat", "dog"}}, Chunk: ingest.ChunkConfig{MaxTokens: 4}}
+	res, err := Sync(ctx, store, dir, cfg)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(res.Added) != 2 || res.Skipped["logo.png"] == "" || store.Len() != 3 {
+		t.Fatalf("unexpected first sync %+v, %d records", res, store.Len())
+	}
+
+	res, err = Sync(ctx, store, dir, cfg)
+	if err != nil || res.Unchanged != 2 || len(res.Added)+len(res.Updated) != 0 {
+		t.Fatalf("unexpected second sync %+v: %v", res, err)
+	}
+
+	write("guides/dogs.txt", "Dogs bark loudly.")
+	os.Remove(filepath.Join(dir, "cats.md"))
+	res, err = Sync(ctx, store, dir, cfg)
+	if err != nil || len(res.Updated) != 1 || len(res.Deleted) != 1 || res.Deleted[0] != "cats.md" {
+		t.Fatalf("unexpected third sync %+v: %v", res, err)
+	}
+	got, _ := store.Query(ctx, Query{Vector: []float32{0, 1}})
+	if store.Len() != 1 || got[0].Text != "Dogs bark loudly." || got[0].Metadata["path"] != "guides/dogs.txt" {
+		t.Fatalf("unexpected store contents: %+v", got)
+	}
+}
+
+// chunkingStore is a Memory with store-side chunking settings.
+type chunkingStore struct {
+	*Memory
+	chunkTokens int
+}
+
+func (s *chunkingStore) Fingerprint() string { return strconv.Itoa(s.chunkTokens) }
+
+func TestSyncStoreFingerprint(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cats.md"), []byte("Cats purr."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := &chunkingStore{Memory: NewMemory(), chunkTokens: 800}
+	cfg := SyncConfig{Embedder: keywordEmbedder{words: []string{"cat"}}}
+	if _, err := Sync(ctx, store, dir, cfg); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if res, err := Sync(ctx, store, dir, cfg); err != nil || res.Unchanged != 1 {
+		t.Fatalf("unexpected second sync %+v: %v", res, err)
+	}
+	store.chunkTokens = 200
+	if res, err := Sync(ctx, store, dir, cfg); err != nil || len(res.Updated) != 1 {
+		t.Fatalf("expected a re-sync after the store settings changed, got %+v: %v", res, err)
+	}
+}
@@ -0,0 +1,221 @@
+// Package vectorstore stores embedded records and finds those nearest to a
+// query. Store is implemented in memory by Memory and by adapters for
+// managed services: OpenAI Vector Stores, Pinecone and Qdrant.
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/quailyquaily/uniai/internal/httputil"
+	"github.com/quailyquaily/uniai/vecmath"
+)
+
+// Record is a stored item.
+type Record struct {
+	ID     string    `json:"id"`
+	Vector []float32 `json:"vector,omitempty"`
+	// Text is the content the vector was computed from. Stores that embed
+	// on their side (openai) index Text and ignore Vector.
+	Text     string         `json:"text,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// Query selects the records nearest to Vector, or to Text for stores that
+// search by text.
+type Query struct {
+	Vector []float32
+	Text   string
+	// TopK is the number of matches (default DefaultTopK).
+	TopK int
+	// Filter keeps records whose metadata equals every given value.
+	Filter map[string]any
+}
+
+// Match is a record found by a query. Score is the similarity reported by
+// the store, higher is closer; Vector is not filled in.
+type Match struct {
+	Record
+	Score float64 `json:"score"`
+}
+
+const DefaultTopK = 8
+
+// Store is a vector index.
+type Store interface {
+	// Upsert adds records, replacing those with the same ID.
+	Upsert(ctx context.Context, records ...Record) error
+	// Query returns the best matches, best first.
+	Query(ctx context.Context, q Query) ([]Match, error)
+	// Delete removes records by ID; unknown IDs are ignored.
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// Memory is an in-process Store ranked by cosine similarity.
+type Memory struct {
+	mu      sync.RWMutex
+	records map[string]Record
+	order   []string
+}
+
+var _ Store = (*Memory)(nil)
+
+func NewMemory() *Memory {
+	return &Memory{records: map[string]Record{}}
+}
+
+func (m *Memory) Upsert(ctx context.Context, records ...Record) error {
+	for _, r := range records {
+		if r.ID == "" {
+			return fmt.Errorf("record id is required")
+		}
+		if len(r.Vector) == 0 {
+			return fmt.Errorf("record %s has no vector", r.ID)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range records {
+		if _, ok := m.records[r.ID]; !ok {
+			m.order = append(m.order, r.ID)
+		}
+		m.records[r.ID] = r
+	}
+	return nil
+}
+
+func (m *Memory) Query(ctx context.Context, q Query) ([]Match, error) {
+	if len(q.Vector) == 0 {
+		return nil, fmt.Errorf("query vector is required")
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var (
+		candidates []Record
+		vectors    [][]float32
+	)
+	for _, id := range m.order {
+		r := m.records[id]
+		if matchesFilter(r.Metadata, q.Filter) {
+			candidates = append(candidates, r)
+			vectors = append(vectors, r.Vector)
+		}
+	}
+	var out []Match
+	for _, match := range vecmath.TopK(q.Vector, vectors, topK(q)) {
+		r := candidates[match.Index]
+		r.Vector = nil
+		out = append(out, Match{Record: r, Score: match.Score})
+	}
+	return out, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.records, id)
+	}
+	order := m.order[:0]
+	for _, id := range m.order {
+		if _, ok := m.records[id]; ok {
+			order = append(order, id)
+		}
+	}
+	m.order = order
+	return nil
+}
+
+// Len returns the number of stored records.
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.records)
+}
+
+func topK(q Query) int {
+	if q.TopK > 0 {
+		return q.TopK
+	}
+	return DefaultTopK
+}
+
+func matchesFilter(metadata, filter map[string]any) bool {
+	for k, want := range filter {
+		got, ok := metadata[k]
+		if !ok || !equalValue(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalValue compares metadata values, treating numbers of any type as
+// equal when their values are, since stores return JSON numbers.
+func equalValue(a, b any) bool {
+	fa, aNum := number(a)
+	fb, bNum := number(b)
+	if aNum && bNum {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func number(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// doJSON sends body as JSON and decodes the response into out. name labels
+// errors.
+func doJSON(ctx context.Context, name, method, endpoint string, header http.Header, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := httputil.ReadBody(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s request failed with status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
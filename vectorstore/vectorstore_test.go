@@ -0,0 +1,96 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	err := m.Upsert(ctx,
+		Record{ID: "a", Vector: []float32{1, 0}, Text: "apples", Metadata: map[string]any{"lang": "en", "year": 2024}},
+		Record{ID: "b", Vector: []float32{0.9, 0.1}, Text: "pommes", Metadata: map[string]any{"lang": "fr"}},
+		Record{ID: "c", Vector: []float32{0, 1}, Text: "cars", Metadata: map[string]any{"lang": "en"}},
+	)
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	got, err := m.Query(ctx, Query{Vector: []float32{1, 0}, TopK: 2})
+	if err != nil || len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" || got[0].Vector != nil {
+		t.Fatalf("unexpected matches %+v: %v", got, err)
+	}
+	got, _ = m.Query(ctx, Query{Vector: []float32{1, 0}, Filter: map[string]any{"lang": "en", "year": 2024.0}})
+	if len(got) != 1 || got[0].ID != "a" {
+		t.Fatalf("unexpected filtered matches: %+v", got)
+	}
+	if err := m.Delete(ctx, "a", "missing"); err != nil || m.Len() != 2 {
+		t.Fatalf("delete: %v, len %d", err, m.Len())
+	}
+}
+
+func TestQdrant(t *testing.T) {
+	var upserted map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "k" {
+			t.Errorf("missing api key")
+		}
+		switch r.URL.Path {
+		case "/collections/docs/points":
+			json.NewDecoder(r.Body).Decode(&upserted)
+			w.Write([]byte(`{"status":"ok"}`))
+		case "/collections/docs/points/search":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["filter"] == nil || body["limit"] != 3.0 {
+				t.Errorf("unexpected search: %v", body)
+			}
+			w.Write([]byte(`{"result":[{"id":"x","score":0.9,"payload":{"id":"doc-1","text":"hello","lang":"en"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	q := NewQdrant(QdrantConfig{URL: srv.URL, APIKey: "k", Collection: "docs"})
+	if err := q.Upsert(ctx, Record{ID: "doc-1", Vector: []float32{1, 0}, Text: "hello", Metadata: map[string]any{"lang": "en"}}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	point := upserted["points"].([]any)[0].(map[string]any)
+	if point["id"] != pointID("doc-1") || len(pointID("doc-1")) != 36 {
+		t.Fatalf("unexpected point: %v", point)
+	}
+	got, err := q.Query(ctx, Query{Vector: []float32{1, 0}, TopK: 3, Filter: map[string]any{"lang": "en"}})
+	if err != nil || len(got) != 1 || got[0].ID != "doc-1" || got[0].Text != "hello" || got[0].Metadata["lang"] != "en" {
+		t.Fatalf("unexpected matches %+v: %v", got, err)
+	}
+}
+
+func TestOpenAIQuery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/vector_stores/vs_1/search" {
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["query"] != "refunds" || body["filters"].(map[string]any)["type"] != "eq" {
+			t.Errorf("unexpected search: %v", body)
+		}
+		w.Write([]byte(`{"data":[{"file_id":"file_1","score":0.8,"attributes":{"uniai_id":"faq-3","lang":"en"},"content":[{"type":"text","text":"Refunds take 5 days."}]}]}`))
+	}))
+	defer srv.Close()
+
+	o := NewOpenAI(OpenAIConfig{APIKey: "k", APIBase: srv.URL, VectorStoreID: "vs_1"})
+	got, err := o.Query(context.Background(), Query{Text: "refunds", Filter: map[string]any{"lang": "en"}})
+	if err != nil || len(got) != 1 || got[0].ID != "faq-3" || got[0].Text != "Refunds take 5 days." {
+		t.Fatalf("unexpected matches %+v: %v", got, err)
+	}
+	if _, err := o.Query(context.Background(), Query{Vector: []float32{1}}); err == nil {
+		t.Fatalf("expected error without query text")
+	}
+}
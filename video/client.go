@@ -0,0 +1,200 @@
+package video
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/quailyquaily/uniai/internal/httputil"
+	"github.com/quailyquaily/uniai/internal/providers/luma"
+	"github.com/quailyquaily/uniai/internal/providers/openai"
+	"github.com/quailyquaily/uniai/internal/providers/runway"
+	"github.com/quailyquaily/uniai/job"
+)
+
+type Config struct {
+	OpenAIAPIKey  string
+	OpenAIAPIBase string
+
+	RunwayAPIKey  string
+	RunwayAPIBase string
+	LumaAPIKey    string
+	LumaAPIBase   string
+
+	// PollInterval is the delay between status checks in Wait; it
+	// defaults to job.DefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// Client submits video generation jobs and tracks them until their videos
+// can be downloaded.
+type Client struct {
+	cfg Config
+}
+
+func New(cfg Config) *Client {
+	return &Client{cfg: cfg}
+}
+
+// Submit starts a video generation job and returns it without waiting.
+func (c *Client) Submit(ctx context.Context, opts ...Option) (*Job, error) {
+	req := BuildRequest(opts...)
+	provider := req.Provider
+	if provider == "" {
+		provider = pickProviderByModel(req.Model)
+	}
+
+	var (
+		respData []byte
+		err      error
+	)
+	switch provider {
+	case "openai":
+		if req.ImageURL != "" && len(req.Image) == 0 {
+			return nil, fmt.Errorf("openai video input must be image data, not a url")
+		}
+		respData, err = openai.CreateVideo(ctx, c.cfg.OpenAIAPIKey, c.cfg.OpenAIAPIBase, req.Model, req.Prompt, req.Image, req.Options.OpenAI)
+	case "runway":
+		image := req.ImageURL
+		if image == "" && len(req.Image) > 0 {
+			image = "data:" + http.DetectContentType(req.Image) + ";base64," + base64.StdEncoding.EncodeToString(req.Image)
+		}
+		respData, err = runway.CreateVideo(ctx, c.cfg.RunwayAPIKey, c.cfg.RunwayAPIBase, req.Model, req.Prompt, image, req.Options.Runway)
+	case "luma":
+		if len(req.Image) > 0 && req.ImageURL == "" {
+			return nil, fmt.Errorf("luma video input must be an image url")
+		}
+		respData, err = luma.CreateVideo(ctx, c.cfg.LumaAPIKey, c.cfg.LumaAPIBase, req.Model, req.Prompt, req.ImageURL, req.Options.Luma)
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", provider)
+	}
+	j, err := decodeJob(provider, respData, err)
+	if err != nil {
+		return nil, err
+	}
+	if j.Model == "" {
+		j.Model = req.Model
+	}
+	return j, nil
+}
+
+// Get returns the current state of a job.
+func (c *Client) Get(ctx context.Context, provider, id string) (*Job, error) {
+	var (
+		respData []byte
+		err      error
+	)
+	switch provider {
+	case "openai":
+		respData, err = openai.GetVideo(ctx, c.cfg.OpenAIAPIKey, c.cfg.OpenAIAPIBase, id)
+	case "runway":
+		respData, err = runway.GetVideo(ctx, c.cfg.RunwayAPIKey, c.cfg.RunwayAPIBase, id)
+	case "luma":
+		respData, err = luma.GetVideo(ctx, c.cfg.LumaAPIKey, c.cfg.LumaAPIBase, id)
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", provider)
+	}
+	return decodeJob(provider, respData, err)
+}
+
+// Wait polls a job until it succeeds, fails or is canceled. A failed or
+// canceled job is returned with an error.
+func (c *Client) Wait(ctx context.Context, j *Job) (*Job, error) {
+	if j.Status.Done() {
+		return j, jobError(j)
+	}
+	out, err := job.Wait(ctx, c.cfg.PollInterval, func(ctx context.Context) (*Job, job.Status, error) {
+		cur, err := c.Get(ctx, j.Provider, j.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		return cur, cur.Status, nil
+	})
+	if err != nil {
+		return out, err
+	}
+	if out.Model == "" {
+		out.Model = j.Model
+	}
+	return out, jobError(out)
+}
+
+// Cancel cancels a running job or deletes a finished one and its result.
+func (c *Client) Cancel(ctx context.Context, provider, id string) error {
+	switch provider {
+	case "openai":
+		return openai.DeleteVideo(ctx, c.cfg.OpenAIAPIKey, c.cfg.OpenAIAPIBase, id)
+	case "runway":
+		return runway.DeleteVideo(ctx, c.cfg.RunwayAPIKey, c.cfg.RunwayAPIBase, id)
+	case "luma":
+		return luma.DeleteVideo(ctx, c.cfg.LumaAPIKey, c.cfg.LumaAPIBase, id)
+	default:
+		return fmt.Errorf("unknown provider: %s", provider)
+	}
+}
+
+// Download returns the video of a succeeded job. Provider-hosted URLs
+// expire, so download soon after the job completes.
+func (c *Client) Download(ctx context.Context, j *Job) ([]byte, error) {
+	if j.Status != job.StatusSucceeded {
+		return nil, fmt.Errorf("video job %s is %s", j.ID, j.Status)
+	}
+	if j.Provider == "openai" {
+		return openai.DownloadVideo(ctx, c.cfg.OpenAIAPIKey, c.cfg.OpenAIAPIBase, j.ID)
+	}
+	if len(j.URLs) == 0 {
+		return nil, fmt.Errorf("video job %s has no result url", j.ID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.URLs[0], nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httputil.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("video download failed with status %d", resp.StatusCode)
+	}
+	return httputil.ReadBody(resp.Body)
+}
+
+func decodeJob(provider string, respData []byte, err error) (*Job, error) {
+	if err != nil {
+		return nil, err
+	}
+	var out Job
+	if err := json.Unmarshal(respData, &out); err != nil {
+		return nil, err
+	}
+	out.Provider = provider
+	return &out, nil
+}
+
+func jobError(j *Job) error {
+	switch j.Status {
+	case job.StatusFailed:
+		if j.Error != "" {
+			return fmt.Errorf("video job %s failed: %s", j.ID, j.Error)
+		}
+		return fmt.Errorf("video job %s failed", j.ID)
+	case job.StatusCanceled:
+		return fmt.Errorf("video job %s was canceled", j.ID)
+	}
+	return nil
+}
+
+func pickProviderByModel(model string) string {
+	switch {
+	case strings.HasPrefix(model, "gen") || strings.HasPrefix(model, "veo"):
+		return "runway"
+	case strings.HasPrefix(model, "ray"):
+		return "luma"
+	}
+	return "openai"
+}
@@ -0,0 +1,72 @@
+package video
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/job"
+)
+
+func TestRunwayJob(t *testing.T) {
+	polls := 0
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/out.mp4" && r.Header.Get("X-Runway-Version") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/image_to_video":
+			w.Write([]byte(`{"id":"t1"}`))
+		case "/v1/tasks/t1":
+			polls++
+			if polls < 2 {
+				w.Write([]byte(`{"id":"t1","status":"RUNNING","progress":0.4}`))
+				return
+			}
+			w.Write([]byte(`{"id":"t1","status":"SUCCEEDED","output":["` + srv.URL + `/out.mp4"]}`))
+		case "/out.mp4":
+			w.Write([]byte("mp4"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(Config{RunwayAPIKey: "k", RunwayAPIBase: srv.URL, PollInterval: time.Millisecond})
+	ctx := context.Background()
+	j, err := c.Submit(ctx, Video("gen4_turbo", "waves"), WithImageURL("https://example.com/a.png"))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if j.Provider != "runway" || j.Status != job.StatusQueued {
+		t.Fatalf("unexpected job %+v", j)
+	}
+	j, err = c.Wait(ctx, j)
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if j.Status != job.StatusSucceeded || j.Progress != 100 || j.Model != "gen4_turbo" || polls != 2 {
+		t.Fatalf("unexpected job %+v after %d polls", j, polls)
+	}
+	data, err := c.Download(ctx, j)
+	if err != nil || string(data) != "mp4" {
+		t.Fatalf("download: %q, %v", data, err)
+	}
+}
+
+func TestWaitFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"g1","state":"failed","failure_reason":"moderation"}`))
+	}))
+	defer srv.Close()
+
+	c := New(Config{LumaAPIKey: "k", LumaAPIBase: srv.URL})
+	j, err := c.Wait(context.Background(), &Job{ID: "g1", Provider: "luma", Status: job.StatusQueued})
+	if err == nil || j.Status != job.StatusFailed {
+		t.Fatalf("expected failure, got %+v, %v", j, err)
+	}
+}
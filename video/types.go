@@ -0,0 +1,74 @@
+package video
+
+import (
+	"github.com/lyricat/goutils/structs"
+	"github.com/quailyquaily/uniai/job"
+)
+
+type Options struct {
+	OpenAI structs.JSONMap `json:"openai_options,omitempty"`
+	Runway structs.JSONMap `json:"runway_options,omitempty"`
+	Luma   structs.JSONMap `json:"luma_options,omitempty"`
+}
+
+type Request struct {
+	Provider string  `json:"provider,omitempty"`
+	Model    string  `json:"model,omitempty"`
+	Prompt   string  `json:"prompt,omitempty"`
+	Options  Options `json:"options,omitempty"`
+
+	// Image and ImageURL set the first frame of an image-to-video job.
+	// openai needs Image, luma needs ImageURL, and runway takes either.
+	Image    []byte `json:"image,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// Job is a video generation job.
+type Job struct {
+	ID       string     `json:"id"`
+	Provider string     `json:"provider"`
+	Model    string     `json:"model,omitempty"`
+	Status   job.Status `json:"status"`
+	// Progress is the completion percentage, when the provider reports it.
+	Progress  int    `json:"progress"`
+	Error     string `json:"error,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	// URLs are the result videos of providers that host them (runway,
+	// luma). openai results are fetched with Client.Download.
+	URLs []string `json:"urls,omitempty"`
+}
+
+type Option func(*Request)
+
+func BuildRequest(opts ...Option) *Request {
+	req := &Request{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(req)
+		}
+	}
+	return req
+}
+
+func Video(model, prompt string) Option {
+	return func(r *Request) {
+		r.Model = model
+		r.Prompt = prompt
+	}
+}
+
+func WithProvider(provider string) Option {
+	return func(r *Request) { r.Provider = provider }
+}
+
+func WithImage(data []byte) Option {
+	return func(r *Request) { r.Image = data }
+}
+
+func WithImageURL(url string) Option {
+	return func(r *Request) { r.ImageURL = url }
+}
+
+func WithOptions(opts Options) Option {
+	return func(r *Request) { r.Options = opts }
+}
@@ -0,0 +1,179 @@
+// Package webhook notifies an HTTP endpoint when asynchronous work
+// finishes, as an alternative to polling. Events are POSTed as JSON,
+// signed with the signature package when a secret is set, and retried with
+// backoff until the endpoint answers 2xx.
+//
+//	hook := webhook.New(webhook.Config{URL: "https://example.com/hooks/uniai", Secret: secret})
+//	q := jobs.New(jobs.Config{Client: client, Webhook: hook})
+//
+// Receivers verify deliveries with signature.Verify on the request body
+// and should deduplicate by the X-Uniai-Delivery header, since a delivery
+// whose response was lost is sent again.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/quailyquaily/uniai/internal/httputil"
+	"github.com/quailyquaily/uniai/signature"
+)
+
+// Event types.
+const (
+	TypeJob      = "job.finished"
+	TypeFineTune = "fine_tuning.finished"
+	TypeVideo    = "video.finished"
+)
+
+// Headers set on every delivery besides signature.Header.
+const (
+	HeaderEvent    = "X-Uniai-Event"
+	HeaderDelivery = "X-Uniai-Delivery"
+)
+
+// DefaultMaxAttempts is used when Config.MaxAttempts is not set.
+const DefaultMaxAttempts = 5
+
+// Event is the body of a delivery.
+type Event struct {
+	// ID identifies the delivery and stays the same across retries.
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Subject is the ID of the job the event is about.
+	Subject   string    `json:"subject"`
+	CreatedAt time.Time `json:"created_at"`
+	// Error is set when waiting for the job failed; Data then holds the
+	// last state seen, if any.
+	Error string `json:"error,omitempty"`
+	// Data is the finished job, such as a *jobs.Job or *finetune.Job.
+	Data any `json:"data,omitempty"`
+}
+
+type Config struct {
+	URL string
+	// Secret, if set, signs each delivery.
+	Secret []byte
+	// MaxAttempts is the number of delivery attempts (default 5).
+	MaxAttempts int
+	// Backoff returns the delay before the next attempt after the given
+	// number of failed ones (default 1s, 2s, 4s... up to 1 minute).
+	Backoff func(attempts int) time.Duration
+	// HTTPClient sends the deliveries (default a client with a two minute
+	// timeout).
+	HTTPClient *http.Client
+	// OnError, if set, receives events that could not be delivered by
+	// senders running in the background, such as the jobs queue.
+	OnError func(Event, error)
+}
+
+// Sender delivers events to one endpoint.
+type Sender struct {
+	cfg Config
+}
+
+func New(cfg Config) *Sender {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = func(attempts int) time.Duration {
+			return min(time.Second<<min(attempts-1, 10), time.Minute)
+		}
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = httputil.DefaultClient
+	}
+	return &Sender{cfg: cfg}
+}
+
+// Send delivers ev, filling in its ID and CreatedAt when empty. Network
+// errors, 408, 429 and 5xx responses are retried; other responses fail at
+// once.
+func (s *Sender) Send(ctx context.Context, ev Event) error {
+	if ev.ID == "" {
+		ev.ID = newID()
+	}
+	if ev.CreatedAt.IsZero() {
+		ev.CreatedAt = time.Now().UTC()
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("encode webhook event: %w", err)
+	}
+	for attempt := 1; ; attempt++ {
+		retry, err := s.deliver(ctx, ev, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.cfg.MaxAttempts {
+			return fmt.Errorf("webhook delivery %s failed after %d attempts: %w", ev.ID, attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.cfg.Backoff(attempt)):
+		}
+	}
+}
+
+// SendAsync delivers ev in the background, reporting failures to
+// Config.OnError.
+func (s *Sender) SendAsync(ev Event) {
+	go func() {
+		if err := s.Send(context.Background(), ev); err != nil && s.cfg.OnError != nil {
+			s.cfg.OnError(ev, err)
+		}
+	}()
+}
+
+func (s *Sender) deliver(ctx context.Context, ev Event, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, ev.Type)
+	req.Header.Set(HeaderDelivery, ev.ID)
+	if len(s.cfg.Secret) > 0 {
+		req.Header.Set(signature.Header, signature.Sign(s.cfg.Secret, time.Now(), body))
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	data, _ := httputil.ReadBody(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook endpoint returned status %d: %s", resp.StatusCode, data)
+}
+
+// Watch waits for a job with wait, such as a call to video.Client.Wait or
+// finetune.Client.WaitJob, and sends the outcome as an event of type typ
+// about subject. Run it in a goroutine to be notified instead of polling.
+func Watch[T any](ctx context.Context, s *Sender, typ, subject string, wait func(ctx context.Context) (T, error)) error {
+	v, err := wait(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	ev := Event{Type: typ, Subject: subject, Data: v}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	return s.Send(ctx, ev)
+}
+
+func newID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "evt_" + hex.EncodeToString(b[:])
+}
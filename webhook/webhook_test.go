@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quailyquaily/uniai/signature"
+)
+
+func TestSendRetriesAndSigns(t *testing.T) {
+	secret := []byte("hook-secret")
+	var calls atomic.Int32
+	var deliveries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := signature.Verify(r.Header.Get(signature.Header), body, 0, secret); err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		deliveries = append(deliveries, r.Header.Get(HeaderDelivery))
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var ev Event
+		if err := json.Unmarshal(body, &ev); err != nil || ev.Type != TypeFineTune || ev.Subject != "ftjob-1" {
+			t.Errorf("event: %s, %v", body, err)
+		}
+	}))
+	defer srv.Close()
+
+	s := New(Config{URL: srv.URL, Secret: secret, Backoff: func(int) time.Duration { return time.Millisecond }})
+	err := Watch(context.Background(), s, TypeFineTune, "ftjob-1", func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"status": "succeeded"}, nil
+	})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if calls.Load() != 2 || deliveries[0] == "" || deliveries[0] != deliveries[1] {
+		t.Fatalf("expected a retried delivery with a stable id, got %v", deliveries)
+	}
+}
+
+func TestSendDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer srv.Close()
+
+	done := make(chan error, 1)
+	s := New(Config{URL: srv.URL, Backoff: func(int) time.Duration { return time.Millisecond }, OnError: func(ev Event, err error) { done <- err }})
+	s.SendAsync(Event{Type: TypeJob, Subject: "job-1", Data: errors.New("x")})
+	select {
+	case err := <-done:
+		if !strings.Contains(err.Error(), "status 410") || calls.Load() != 1 {
+			t.Fatalf("expected one failed attempt, got %d: %v", calls.Load(), err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("OnError not called")
+	}
+}
@@ -0,0 +1,55 @@
+package uniai
+
+import (
+	"fmt"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+// applyWebSearch maps req.Options.WebSearch onto the OpenAI-compatible
+// providers that search natively. Anthropic adds its web search tool in the
+// provider, and gemini grounds in Google Search through its native API.
+// Settings a provider cannot honour are reported as warnings.
+func applyWebSearch(providerName string, req *chat.Request) (*chat.Request, []string) {
+	ws := req.Options.WebSearch
+	if ws == nil {
+		return req, nil
+	}
+	if providerName == "perplexity" {
+		// Perplexity searches unless told not to
+		fields := map[string]any{}
+		if !ws.Enabled {
+			fields["disable_search"] = true
+			return withExtraBody(req, fields), nil
+		}
+		var warnings []string
+		if len(ws.AllowedDomains) > 0 {
+			fields["search_domain_filter"] = append([]string{}, ws.AllowedDomains...)
+		}
+		if ws.MaxResults > 0 {
+			warnings = append(warnings, "web search max_results is not supported by perplexity; ignored")
+		}
+		return withExtraBody(req, fields), warnings
+	}
+	if !ws.Enabled {
+		return req, nil
+	}
+	switch providerName {
+	case "anthropic":
+		return req, nil
+	case "openai", "openai_custom":
+		var warnings []string
+		if ws.MaxResults > 0 || len(ws.AllowedDomains) > 0 {
+			warnings = append(warnings, "web search max_results and allowed_domains are not supported by openai chat completions; ignored")
+		}
+		return withExtraBody(req, map[string]any{"web_search_options": map[string]any{}}), warnings
+	case "gemini":
+		// grounded requests go to the native API, which adds the
+		// googleSearch tool
+		if ws.MaxResults > 0 || len(ws.AllowedDomains) > 0 {
+			return req, []string{"web search max_results and allowed_domains are not supported by gemini grounding; ignored"}
+		}
+		return req, nil
+	}
+	return req, []string{fmt.Sprintf("web search is not supported by %s; ignored", providerName)}
+}
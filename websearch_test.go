@@ -0,0 +1,33 @@
+package uniai
+
+import (
+	"testing"
+
+	"github.com/quailyquaily/uniai/chat"
+)
+
+func TestApplyWebSearch(t *testing.T) {
+	req, err := chat.BuildRequest(
+		chat.WithMessages(chat.User("news?")),
+		chat.WithWebSearch(chat.WebSearch{AllowedDomains: []string{"example.com"}}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, warnings := applyWebSearch("perplexity", req)
+	extra, _ := out.Options.OpenAI["extra_body"].(map[string]any)
+	if domains, _ := extra["search_domain_filter"].([]string); len(domains) != 1 || len(warnings) != 0 {
+		t.Fatalf("unexpected perplexity mapping: %#v %v", extra, warnings)
+	}
+	out, warnings = applyWebSearch("openai", req)
+	extra, _ = out.Options.OpenAI["extra_body"].(map[string]any)
+	if _, ok := extra["web_search_options"]; !ok || len(warnings) != 1 {
+		t.Fatalf("unexpected openai mapping: %#v %v", extra, warnings)
+	}
+	if _, warnings = applyWebSearch("gemini", req); len(warnings) != 1 {
+		t.Fatalf("expected ignored allowed_domains warning on gemini, got %v", warnings)
+	}
+	if _, warnings = applyWebSearch("bedrock", req); len(warnings) != 1 {
+		t.Fatalf("expected unsupported warning, got %v", warnings)
+	}
+}